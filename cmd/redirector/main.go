@@ -0,0 +1,118 @@
+// cmd/redirector/main.go
+package main
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"url-shortener/internal/bootstrap"
+	"url-shortener/internal/config"
+	"url-shortener/internal/handler"
+	"url-shortener/internal/scheduler"
+	"url-shortener/internal/service"
+	customLogger "url-shortener/pkg/logger"
+)
+
+// main serves only the resolve path: short links, their confirm pages, /health and /metrics
+// Links are created, managed and migrated by cmd/server; this binary never writes the schema.
+func main() {
+	// Simple health check for Docker - just make HTTP request to existing server
+	if len(os.Args) > 1 && os.Args[1] == "healthcheck" {
+		os.Exit(bootstrap.Healthcheck())
+	}
+
+	cfg, runtime, appLogger := bootstrap.Init("URL Shortener Redirector")
+
+	// REDIRECTOR_DB_USER may be a role that can only read; REDIRECTOR_READ_ONLY then turns click counting off
+	db, err := bootstrap.OpenDatabase(cfg, bootstrap.RedirectorRole(cfg), appLogger)
+	if err != nil {
+		appLogger.Fatal("Failed to initialize database", "error", err)
+	}
+
+	// Migrations are the server's job, a redirector only refuses to run against an older schema
+	if err := bootstrap.CheckSchema(db); err != nil {
+		appLogger.Fatal("Failed to prepare database schema", "error", err)
+	}
+
+	// Initialize Redis cache; nil runs without one
+	redisCache := bootstrap.OpenCache(cfg, appLogger)
+
+	// With DB_DRIVER=mongo the links are read from MongoDB, whose indexes the server creates
+	links, err := bootstrap.OpenLinkStore(cfg, false, appLogger)
+	if err != nil {
+		appLogger.Fatal("Failed to initialize link store", "error", err)
+	}
+
+	urlRepo := bootstrap.URLRepository(db, links, redisCache, cfg, appLogger)
+	serviceOpts := bootstrap.ResolveOptions(db, cfg, runtime, appLogger)
+	if cfg.RedirectorReadOnly {
+		serviceOpts = append(serviceOpts, service.WithoutClickCounting())
+	}
+	urlService := service.NewURLService(urlRepo, redisCache, cfg, appLogger, serviceOpts...)
+
+	// Pool stats are the only background job; expiry, rollups and the relay run in the server
+	jobs := scheduler.New(appLogger)
+	if job, ok := bootstrap.PoolStatsJob(db, cfg); ok {
+		jobs.Add(job)
+	}
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	jobs.Start(jobsCtx)
+
+	urlHandler := handler.NewURLHandler(urlService, cfg, appLogger)
+	staticHandler := handler.NewStaticHandler(cfg, appLogger)
+	reloadHandler := handler.NewReloadHandler(runtime, appLogger)
+
+	srv := bootstrap.NewHTTPServer(cfg, setupRouter(urlHandler, staticHandler, cfg, runtime, appLogger))
+	bootstrap.Serve(srv, appLogger)
+
+	// The hottest links are loaded into the cache while the first requests are already served
+	bootstrap.WarmCache(jobsCtx, urlService, cfg, appLogger)
+
+	// SIGHUP reloads the rate limits, log level and bot lists, as in the server
+	bootstrap.ReloadOnSIGHUP(reloadHandler)
+	bootstrap.WaitForShutdown()
+
+	appLogger.Info("Shutting down redirector...")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := srv.Shutdown(ctx); err != nil {
+		appLogger.Error("Server forced to shutdown", "error", err)
+	}
+	stopJobs()
+	jobs.Wait()
+
+	// Persist clicks still queued from cache hits before the connections go away
+	if err := urlService.Close(ctx); err != nil {
+		appLogger.Error("Pending clicks lost during shutdown", "error", err)
+	}
+	bootstrap.CloseCache(redisCache, appLogger)
+
+	appLogger.Info("Redirector exited successfully")
+}
+
+// setupRouter registers the redirect routes on the shared base router
+// There is no API, no CORS and no key lookup: nothing here needs a caller's identity.
+func setupRouter(urlHandler *handler.URLHandler, staticHandler *handler.StaticHandler, cfg *config.Config, runtime *config.Runtime, log *customLogger.Logger) *gin.Engine {
+	quiet := handler.NewQuietPaths(cfg.QuietPaths)
+	router := bootstrap.NewRouter("url-shortener-redirector", cfg, quiet, log)
+	limiter := handler.NewRuntimeRateLimiter(runtime).SkipQuietPaths(quiet).GroupIPv6(cfg.RateLimitIPv6Prefix)
+
+	// Answered here so browsers and crawlers don't cost a short code lookup each
+	router.GET("/favicon.ico", staticHandler.Favicon)
+	router.GET("/robots.txt", staticHandler.RobotsTxt)
+
+	// Same limit and deadline as the server's redirects
+	redirects := router.Group("", handler.BaseURLMiddleware(cfg), handler.RedirectTenantMiddleware(cfg), limiter.RuntimeRedirectMiddleware(), handler.TimeoutMiddleware(cfg.RedirectTimeout))
+	{
+		redirects.GET("/:shortCode", urlHandler.RedirectURL)
+		redirects.GET("/:shortCode/*path", urlHandler.RedirectPath) // Multi-segment aliases, /continue, the second hop from the interstitial page, and /stats, the public stats page
+	}
+
+	router.NoRoute(handler.BaseURLMiddleware(cfg), urlHandler.NoRoute)
+	return router
+}
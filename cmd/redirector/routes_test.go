@@ -0,0 +1,48 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/config"
+	"url-shortener/internal/handler"
+	customLogger "url-shortener/pkg/logger"
+)
+
+// TestRedirectorRoutes pins the redirector to the resolve path, so an API route can't slip in with the shared wiring
+func TestRedirectorRoutes(t *testing.T) {
+	cfg, err := config.LoadFrom(nil)
+	require.NoError(t, err)
+	cfg.EnableMetrics = true
+
+	log := customLogger.NewLogger(customLogger.Options{})
+	router := setupRouter(handler.NewURLHandler(nil, cfg, log), handler.NewStaticHandler(cfg, log), cfg, config.NewRuntime(cfg), log)
+
+	var routes []string
+	for _, route := range router.Routes() {
+		routes = append(routes, route.Method+" "+route.Path)
+	}
+	sort.Strings(routes)
+	assert.Equal(t, []string{
+		"GET /:shortCode",
+		"GET /:shortCode/*path",
+		"GET /favicon.ico",
+		"GET /health",
+		"GET /metrics",
+		"GET /robots.txt",
+	}, routes)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/api/v1/shorten", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code, "the API is only served by cmd/server")
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/health", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"service":"url-shortener-redirector"`)
+}
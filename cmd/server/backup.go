@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/joho/godotenv"
+
+	"url-shortener/internal/backup"
+	"url-shortener/internal/bootstrap"
+	"url-shortener/internal/config"
+	postgresRepo "url-shortener/internal/repository/postgres"
+)
+
+const (
+	backupUsage  = "usage: server backup-critical [create|list|verify [NAME]]"
+	restoreUsage = `usage: server restore-critical [--policy-file PATH] [NAME]
+
+Migrates the schema, loads api_keys from the backup and writes its policy settings, the domain lists,
+tenant domains, rate limits and quotas among them, to PATH (NAME.policy.yaml by default) for CONFIG_FILE.
+Per-link state such as deactivated links and the interstitial and confirm flags is NOT restored;
+it is part of the urls rows and comes back with the regular database backups.`
+)
+
+// runBackupCritical implements `server backup-critical` and returns the exit code
+// create writes a backup now, as the scheduled job does; list and verify only read the store.
+func runBackupCritical(args []string) int {
+	command := "create"
+	if len(args) > 0 {
+		command = args[0]
+	}
+	name := ""
+	switch {
+	case command == "verify" && len(args) <= 2:
+		if len(args) == 2 {
+			name = args[1]
+		}
+	case (command == "create" || command == "list") && len(args) <= 1:
+	default:
+		fmt.Fprintln(os.Stderr, backupUsage)
+		return 2
+	}
+
+	cfg, ok := loadBackupConfig()
+	if !ok {
+		return 1
+	}
+	store, err := backup.NewStore(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to initialize backup store:", err)
+		return 1
+	}
+
+	// Only create reads the database
+	backups := backup.New(store, nil, nil, cfg.BackupKey, cfg.BackupRetention)
+	if command == "create" {
+		db, err := bootstrap.OpenDatabase(cfg, bootstrap.ServerRole(cfg), bootstrap.NewLogger(cfg))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to initialize database:", err)
+			return 1
+		}
+		backups = backup.New(store, postgresRepo.NewAPIKeyRepository(db), func() *config.Config { return cfg }, cfg.BackupKey, cfg.BackupRetention)
+	}
+
+	ctx := context.Background()
+	switch command {
+	case "create":
+		manifest, err := backups.Create(ctx)
+		if manifest != nil {
+			printManifest(os.Stdout, "Wrote", manifest)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	case "list":
+		names, err := backups.List(ctx)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		for _, name := range names {
+			fmt.Fprintln(os.Stdout, name)
+		}
+	case "verify":
+		manifest, err := backups.Verify(ctx, name)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		printManifest(os.Stdout, "Verified", manifest)
+	}
+	return 0
+}
+
+// runRestoreCritical implements `server restore-critical [--policy-file PATH] [NAME]` and returns the exit code
+// The schema is migrated first, so it can be pointed at an empty database; the newest backup is used without NAME.
+func runRestoreCritical(args []string) int {
+	if len(args) == 1 && (args[0] == "-h" || args[0] == "--help") {
+		fmt.Fprintln(os.Stdout, restoreUsage)
+		return 0
+	}
+	policyPath := ""
+	if len(args) >= 2 && args[0] == "--policy-file" {
+		policyPath, args = args[1], args[2:]
+	}
+	if len(args) > 1 || (len(args) == 1 && strings.HasPrefix(args[0], "-")) {
+		fmt.Fprintln(os.Stderr, restoreUsage)
+		return 2
+	}
+	name := ""
+	if len(args) == 1 {
+		name = args[0]
+	}
+
+	cfg, ok := loadBackupConfig()
+	if !ok {
+		return 1
+	}
+	store, err := backup.NewStore(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to initialize backup store:", err)
+		return 1
+	}
+	db, err := bootstrap.OpenDatabase(cfg, bootstrap.ServerRole(cfg), bootstrap.NewLogger(cfg))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to initialize database:", err)
+		return 1
+	}
+	migrator, err := bootstrap.NewMigrator(db)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	ctx := context.Background()
+	backups := backup.New(store, postgresRepo.NewAPIKeyRepository(db), nil, cfg.BackupKey, cfg.BackupRetention)
+
+	// The backup is picked and checked first, so the policy file is named after it and claimed before anything is written
+	manifest, err := backups.Verify(ctx, name)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if policyPath == "" {
+		policyPath = manifest.Name + ".policy.yaml"
+	}
+	policyFile, err := os.OpenFile(policyPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to create the policy file:", err)
+		return 1
+	}
+	defer policyFile.Close()
+
+	if _, err := migrator.Up(ctx); err != nil {
+		os.Remove(policyPath)
+		fmt.Fprintln(os.Stderr, "Failed to migrate the schema:", err)
+		return 1
+	}
+	manifest, policy, err := backups.Restore(ctx, manifest.Name)
+	if err != nil {
+		os.Remove(policyPath)
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if err := policy.WriteYAML(policyFile, manifest.Name); err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to write the policy file:", err)
+		return 1
+	}
+	printManifest(os.Stdout, "Restored", manifest)
+	fmt.Fprintf(os.Stdout, "  policy: %s (start the server with CONFIG_FILE=%s)\n", policyPath, policyPath)
+	return 0
+}
+
+// loadBackupConfig reads the server's configuration and checks a backup store is set up
+func loadBackupConfig() (*config.Config, bool) {
+	_ = godotenv.Load()
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to load configuration:", err)
+		return nil, false
+	}
+	if cfg.BackupStore == config.BackupStoreNone {
+		fmt.Fprintln(os.Stderr, "BACKUP_STORE is not set")
+		return nil, false
+	}
+	return cfg, true
+}
+
+// printManifest reports what a backup holds on out
+func printManifest(out io.Writer, verb string, manifest *backup.Manifest) {
+	fmt.Fprintf(out, "%s %s (%s)\n", verb, manifest.Name, manifest.CreatedAt.Format("2006-01-02 15:04:05 MST"))
+	for _, table := range manifest.Tables {
+		fmt.Fprintf(out, "  %s: %d rows\n", table.Name, table.Rows)
+	}
+	tenants := make([]string, len(manifest.Tenants))
+	for i, tenant := range manifest.Tenants {
+		if tenant == "" {
+			tenant = "(default)"
+		}
+		tenants[i] = tenant
+	}
+	fmt.Fprintf(out, "  tenants: %s\n", strings.Join(tenants, ", "))
+}
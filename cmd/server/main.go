@@ -4,109 +4,288 @@ package main
 import (
 	"context"
 	"fmt"
-	"log"
-	"net/http"
+	"net"
 	"os"
-	"os/signal"
-	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/joho/godotenv"
-	"gorm.io/driver/postgres"
-	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
+	"google.golang.org/grpc"
 
+	"url-shortener/internal/apikey"
+	"url-shortener/internal/archive"
+	"url-shortener/internal/backup"
+	"url-shortener/internal/bootstrap"
 	"url-shortener/internal/cache"
 	"url-shortener/internal/config"
+	"url-shortener/internal/grpcserver"
 	"url-shortener/internal/handler"
+	"url-shortener/internal/linkcheck"
+	"url-shortener/internal/metadata"
+	"url-shortener/internal/notify"
+	"url-shortener/internal/outbox"
 	postgresRepo "url-shortener/internal/repository/postgres"
+	"url-shortener/internal/scheduler"
 	"url-shortener/internal/service"
+	"url-shortener/internal/unshorten"
 	customLogger "url-shortener/pkg/logger"
 )
 
-// gormWriter wraps our custom logger to implement gorm's logger.Writer interface
-type gormWriter struct {
-	logger *customLogger.Logger
-}
-
-// Printf implements the logger.Writer interface
-func (w *gormWriter) Printf(format string, args ...interface{}) {
-	w.logger.Info(fmt.Sprintf(format, args...))
-}
-
 func main() {
 	// Simple health check for Docker - just make HTTP request to existing server
 	if len(os.Args) > 1 && os.Args[1] == "healthcheck" {
-		resp, err := http.Get("http://localhost:8081/health")
-		if err != nil || resp.StatusCode != http.StatusOK {
-			os.Exit(1)
-		}
-		os.Exit(0)
+		os.Exit(bootstrap.Healthcheck())
 	}
 
-	// Load environment variables from .env file (development only)
-	if err := godotenv.Load(); err != nil {
-		log.Println("Warning: .env file not found, using environment variables")
+	// Schema migrations can be applied, reverted and listed without starting the server
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrate(os.Args[2:]))
 	}
-
-	// Initialize structured logger
-	appLogger := customLogger.NewLogger()
-	appLogger.Info("Starting URL Shortener Service")
-
-	// Load application configuration 
-	cfg, err := config.LoadConfig()
-	if err != nil {
-		appLogger.Fatal("Failed to load configuration", "error", err)
+	// Critical tables are backed up and restored for recovery drills the same way
+	if len(os.Args) > 1 && os.Args[1] == "backup-critical" {
+		os.Exit(runBackupCritical(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "restore-critical" {
+		os.Exit(runRestoreCritical(os.Args[2:]))
 	}
 
+	// Configuration, logger and the reloadable settings are wired the same way in cmd/redirector
+	cfg, runtime, appLogger := bootstrap.Init("URL Shortener Service")
+
 	// Initialize database connection
-	db, err := initDatabase(cfg, appLogger)
+	db, err := bootstrap.OpenDatabase(cfg, bootstrap.ServerRole(cfg), appLogger)
 	if err != nil {
 		appLogger.Fatal("Failed to initialize database", "error", err)
 	}
 
-	// Initialize Redis cache
-	redisCache, err := cache.NewRedisCache(cfg.RedisAddr, cfg.RedisPassword, cfg.RedisDB)
+	// A fresh database gets its tables here instead of failing every request
+	if err := bootstrap.PrepareSchema(db, cfg, appLogger); err != nil {
+		appLogger.Fatal("Failed to prepare database schema", "error", err)
+	}
+
+	// Initialize Redis cache; nil runs without one
+	redisCache := bootstrap.OpenCache(cfg, appLogger)
+
+	// With DB_DRIVER=mongo the links are kept in MongoDB, the server creates its indexes
+	links, err := bootstrap.OpenLinkStore(cfg, true, appLogger)
 	if err != nil {
-		appLogger.Warn("Failed to initialize Redis cache, continuing without cache", "error", err)
-		redisCache = nil // Continue without cache
+		appLogger.Fatal("Failed to initialize link store", "error", err)
 	}
 
 	// Initialize repository layer
-	urlRepo := postgresRepo.NewURLRepository(db)
+	urlRepo := bootstrap.URLRepository(db, links, redisCache, cfg, appLogger)
+	auditRepo := postgresRepo.NewAuditRepository(db)
+	clickRepo := postgresRepo.NewClickRepository(db)
+	apiKeyRepo := postgresRepo.NewAPIKeyRepository(db)
+	outboxRepo := postgresRepo.NewOutboxRepository(db)
+
+	serviceOpts := append(bootstrap.ResolveOptions(db, cfg, runtime, appLogger),
+		service.WithAuditRepository(auditRepo),
+	)
+
+	// Metadata enrichment makes outbound requests, so it is opt-in
+	if cfg.EnableMetadataFetch {
+		serviceOpts = append(serviceOpts, service.WithMetadataFetcher(metadata.NewHTTPFetcher(cfg.MetadataFetchTimeout)))
+	}
+
+	// Resolving links on other shorteners also makes outbound requests; without it they are refused
+	if cfg.ResolveShortenerChains {
+		serviceOpts = append(serviceOpts, service.WithChainResolver(unshorten.NewHTTPResolver(cfg.ChainResolveTimeout)))
+	}
+
+	// Archival also fetches destinations; snapshots are evidence for takedown disputes
+	var snapshots archive.SnapshotStore
+	if cfg.SnapshotStore != config.SnapshotStoreNone {
+		store, err := archive.NewStore(cfg)
+		if err != nil {
+			appLogger.Fatal("Failed to initialize snapshot store", "error", err, "store", cfg.SnapshotStore)
+		}
+		snapshots = store
+		fetcher := archive.NewHTTPFetcher(cfg.SnapshotFetchTimeout, cfg.SnapshotMaxBytes)
+		serviceOpts = append(serviceOpts, service.WithSnapshots(fetcher, snapshots))
+	}
+
+	// Lifecycle events are written to the outbox in the mutation's transaction (see ResolveOptions) and relayed by a job
+	var relay *outbox.Relay
+	if cfg.EventsDriver != config.EventsDriverNone {
+		publisher, err := outbox.NewPublisher(cfg)
+		if err != nil {
+			appLogger.Fatal("Failed to initialize event publisher", "error", err, "driver", cfg.EventsDriver)
+		}
+		relay = outbox.NewRelay(outboxRepo, publisher, cfg.EventsBatchSize, appLogger)
+	}
 
 	// Initialize service layer with dependency injection
-	urlService := service.NewURLService(urlRepo, redisCache, cfg, appLogger)
+	urlService := service.NewURLService(urlRepo, redisCache, cfg, appLogger, serviceOpts...)
+
+	// Background maintenance shares one scheduler; every job is safe to run on several instances
+	jobs := scheduler.New(appLogger)
+	jobs.Add(scheduler.Job{
+		Name:     "cleanup_expired",
+		Interval: cfg.CleanupInterval,
+		Run: func(ctx context.Context) error {
+			deactivated, err := urlRepo.DeleteExpired(ctx)
+			if err == nil && deactivated > 0 {
+				appLogger.Info("Deactivated expired URLs", "count", deactivated)
+			}
+			return err
+		},
+	})
+	jobs.Add(scheduler.Job{
+		Name:     "rollup_clicks",
+		Interval: cfg.StatsRollupInterval,
+		Run: func(ctx context.Context) error {
+			// The day before yesterday is redone to pick up clicks queued across midnight
+			now := time.Now().UTC()
+			for _, day := range []time.Time{now.AddDate(0, 0, -2), now.AddDate(0, 0, -1)} {
+				if _, err := clickRepo.RollupDay(ctx, day); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+	if cfg.DeletedRetention > 0 {
+		jobs.Add(scheduler.Job{
+			Name:     "purge_deleted",
+			Interval: cfg.CleanupInterval,
+			Run: func(ctx context.Context) error {
+				erased, err := urlService.PurgeDeleted(ctx, time.Now().Add(-cfg.DeletedRetention))
+				if erased > 0 {
+					appLogger.Info("Erased deleted URLs past retention", "count", erased)
+				}
+				return err
+			},
+		})
+	}
+	if snapshots != nil && cfg.SnapshotRetention > 0 {
+		jobs.Add(scheduler.Job{
+			Name:     "purge_snapshots",
+			Interval: cfg.CleanupInterval,
+			Run: func(ctx context.Context) error {
+				purged, err := snapshots.DeleteOlderThan(ctx, time.Now().Add(-cfg.SnapshotRetention))
+				if purged > 0 {
+					appLogger.Info("Purged destination snapshots", "count", purged)
+				}
+				return err
+			},
+		})
+	}
+	// API keys and the policy settings are backed up encrypted, so a drill has them before the bulk data
+	if cfg.BackupStore != config.BackupStoreNone {
+		backupStore, err := backup.NewStore(cfg)
+		if err != nil {
+			appLogger.Fatal("Failed to initialize backup store", "error", err)
+		}
+		backups := backup.New(backupStore, apiKeyRepo, runtime.Config, cfg.BackupKey, cfg.BackupRetention)
+		jobs.Add(scheduler.Job{
+			Name:     "backup_critical",
+			Interval: cfg.BackupInterval,
+			Run: func(ctx context.Context) error {
+				manifest, err := backups.Create(ctx)
+				if manifest != nil {
+					appLogger.Info("Backed up critical tables", "name", manifest.Name, "tenants", len(manifest.Tenants))
+				}
+				return err
+			},
+		})
+	}
+	if job, ok := bootstrap.PoolStatsJob(db, cfg); ok {
+		jobs.Add(job)
+	}
+	if relay != nil {
+		jobs.Add(scheduler.Job{
+			Name:     "relay_events",
+			Interval: cfg.EventsPollInterval,
+			Run:      relay.Run,
+		})
+		jobs.Add(scheduler.Job{
+			Name:     "prune_events",
+			Interval: time.Hour,
+			Run: func(ctx context.Context) error {
+				_, err := outboxRepo.DeletePublished(ctx, time.Now().Add(-cfg.EventsRetention))
+				return err
+			},
+		})
+	}
+	// Owners are warned before their links expire; the lock keeps instances from scanning together
+	var expiryJob *notify.ExpiryJob
+	if cfg.ExpiryWebhookURL != "" {
+		locker, _ := redisCache.(cache.Locker)
+		expiryJob = notify.NewExpiryJob(urlRepo, notify.NewWebhookNotifier(cfg.ExpiryWebhookURL, 10*time.Second), locker, cfg.BaseURL, cfg.ExpiryNotifyWindow, appLogger)
+		jobs.Add(scheduler.Job{
+			Name:     "notify_expiring",
+			Interval: cfg.ExpiryNotifyInterval,
+			Run:      expiryJob.Run,
+		})
+	}
+	// Destinations are checked for rot; another opt-in source of outbound requests
+	var linkMonitor *linkcheck.Monitor
+	if cfg.EnableLinkCheck {
+		var notifier notify.BrokenNotifier
+		if cfg.LinkCheckWebhookURL != "" {
+			notifier = notify.NewWebhookNotifier(cfg.LinkCheckWebhookURL, 10*time.Second)
+		}
+		locker, _ := redisCache.(cache.Locker)
+		linkMonitor = linkcheck.NewMonitor(urlRepo, linkcheck.NewHTTPChecker(cfg.LinkCheckTimeout), notifier, locker, linkcheck.Config{
+			RecheckAfter: cfg.LinkCheckRecheckAfter,
+			Concurrency:  cfg.LinkCheckConcurrency,
+			HostRate:     float64(time.Second) / float64(cfg.LinkCheckHostInterval),
+			BaseURL:      cfg.BaseURL,
+		}, appLogger)
+		jobs.Add(scheduler.Job{
+			Name:     "check_links",
+			Interval: cfg.LinkCheckInterval,
+			Run:      linkMonitor.Run,
+		})
+	}
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	jobs.Start(jobsCtx)
 
-	// Initialize HTTP handler
-	urlHandler := handler.NewURLHandler(urlService, appLogger)
+	// Issued API keys are cached in memory; API_KEY keeps working as the bootstrap key
+	apiKeys := apikey.NewStore(apiKeyRepo, cfg.APIKeyCacheTTL, appLogger)
+	if err := apiKeys.Refresh(context.Background()); err != nil {
+		appLogger.Warn("Failed to load API keys, retrying on first use", "error", err)
+	}
+
+	// Initialize HTTP handlers
+	urlHandler := handler.NewURLHandler(urlService, cfg, appLogger)
+	apiKeyHandler := handler.NewAPIKeyHandler(apiKeys, appLogger)
+	staticHandler := handler.NewStaticHandler(cfg, appLogger)
+	docsHandler := handler.NewDocsHandler(cfg, appLogger)
+	reloadHandler := handler.NewReloadHandler(runtime, appLogger)
 
 	// Setup HTTP router with middleware
-	router := setupRouter(urlHandler, cfg, appLogger)
-
-	// Create HTTP server with timeouts
-	srv := &http.Server{
-		Addr:           fmt.Sprintf(":%s", cfg.ServerPort),
-		Handler:        router,
-		ReadTimeout:    15 * time.Second,
-		WriteTimeout:   15 * time.Second,
-		IdleTimeout:    60 * time.Second,
-		MaxHeaderBytes: 1 << 20, // 1 MB
-	}
-
-	// Start server in a goroutine for graceful shutdown
-	go func() {
-		appLogger.Info("Server starting", "port", cfg.ServerPort)
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			appLogger.Fatal("Failed to start server", "error", err)
+	router := setupRouter(urlHandler, apiKeyHandler, staticHandler, docsHandler, reloadHandler, apiKeys, cfg, runtime, appLogger)
+
+	// Create HTTP server with timeouts and start it in a goroutine for graceful shutdown
+	srv := bootstrap.NewHTTPServer(cfg, router)
+	bootstrap.Serve(srv, appLogger)
+
+	// The hottest links are loaded into the cache while the first requests are already served
+	bootstrap.WarmCache(jobsCtx, urlService, cfg, appLogger)
+
+	// Start gRPC server on its own port, sharing the same service layer
+	var grpcSrv *grpc.Server
+	if cfg.EnableGRPC {
+		lis, err := net.Listen("tcp", fmt.Sprintf(":%s", cfg.GRPCPort))
+		if err != nil {
+			appLogger.Fatal("Failed to listen for gRPC", "error", err, "port", cfg.GRPCPort)
 		}
-	}()
+		
+		grpcSrv = grpcserver.NewGRPCServer(urlService, apiKeys, cfg, appLogger)
+		go func() {
+			appLogger.Info("gRPC server starting", "port", cfg.GRPCPort)
+			if err := grpcserver.Serve(grpcSrv, lis); err != nil {
+				appLogger.Fatal("Failed to start gRPC server", "error", err)
+			}
+		}()
+	}
+
+	// SIGHUP reloads the configuration; requests in flight finish with the settings they started with
+	bootstrap.ReloadOnSIGHUP(reloadHandler)
 
 	// Wait for interrupt signal for graceful shutdown
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
+	bootstrap.WaitForShutdown()
 
 	appLogger.Info("Shutting down server...")
 
@@ -118,123 +297,125 @@ func main() {
 		appLogger.Error("Server forced to shutdown", "error", err)
 	}
 
-	// Close Redis connection
-	if redisCache != nil {
-		if err := redisCache.Close(); err != nil {
-			appLogger.Error("Error closing Redis connection", "error", err)
-		}
+	// Drain in-flight gRPC calls within the same deadline
+	if grpcSrv != nil {
+		grpcserver.Shutdown(ctx, grpcSrv)
 	}
 
-	appLogger.Info("Server exited successfully")
-}
-
-// initDatabase initializes the PostgreSQL database connection with connection pooling
-func initDatabase(cfg *config.Config, log *customLogger.Logger) (*gorm.DB, error) {
-	writer := &gormWriter{logger: log}
-	
-	gormLogger := logger.New(
-		writer, // Use our custom writer
-		logger.Config{
-			SlowThreshold:             time.Second,
-			LogLevel:                  logger.Warn,
-			IgnoreRecordNotFoundError: true,
-			Colorful:                  false,
-		},
-	)
-
-	// Connect to PostgreSQL with retry logic
-	var db *gorm.DB
-	var err error
-	
-	maxRetries := 5
-	for i := 0; i < maxRetries; i++ {
-		dsn := fmt.Sprintf(
-			"host=%s user=%s password=%s dbname=%s port=%s sslmode=%s TimeZone=UTC",
-			cfg.DBHost, cfg.DBUser, cfg.DBPassword, cfg.DBName, cfg.DBPort, cfg.DBSSLMode,
-		)
-
-		db, err = gorm.Open(postgres.Open(dsn), &gorm.Config{
-			Logger:                 gormLogger,
-			SkipDefaultTransaction: true,
-			PrepareStmt:            true,
-		})
-		
-		if err == nil {
-			break
-		}
-		
-		log.Warn("Failed to connect to database, retrying...", "attempt", i+1, "error", err)
-		time.Sleep(5 * time.Second)
+	// Stop maintenance jobs; a run in progress finishes first
+	// Events written after the relay stopped stay in the outbox and go out after the next start
+	stopJobs()
+	jobs.Wait()
+	if relay != nil {
+		relay.Close()
 	}
-	
-	if err != nil {
-		return nil, fmt.Errorf("failed to connect to database after %d attempts: %w", maxRetries, err)
+	if expiryJob != nil {
+		expiryJob.Close()
 	}
-
-	// Get underlying SQL DB for connection pool configuration
-	sqlDB, err := db.DB()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get database instance: %w", err)
+	if linkMonitor != nil {
+		linkMonitor.Close()
 	}
 
-	// Configure connection pool for optimal performance
-	sqlDB.SetMaxIdleConns(10)
-	sqlDB.SetMaxOpenConns(100)
-	sqlDB.SetConnMaxLifetime(time.Hour)
-
-	// Verify database connection
-	if err := sqlDB.Ping(); err != nil {
-		return nil, fmt.Errorf("failed to ping database: %w", err)
+	// Persist clicks still queued from cache hits before the connections go away
+	if err := urlService.Close(ctx); err != nil {
+		appLogger.Error("Pending clicks lost during shutdown", "error", err)
 	}
 
-	log.Info("Database connection established successfully")
-	return db, nil
+	// Close Redis connection
+	bootstrap.CloseCache(redisCache, appLogger)
+
+	appLogger.Info("Server exited successfully")
 }
 
 // setupRouter configures the Gin router with middleware and routes
-func setupRouter(urlHandler *handler.URLHandler, cfg *config.Config, log *customLogger.Logger) *gin.Engine {
-	// Set Gin mode based on environment
-	if cfg.Environment == "production" {
-		gin.SetMode(gin.ReleaseMode)
-	}
-
-	router := gin.New()
-
-	// Apply global middleware
-	router.Use(gin.Recovery()) // Panic recovery
-	router.Use(handler.LoggerMiddleware(log))
-	router.Use(handler.CORSMiddleware(cfg))
-	router.Use(handler.SecurityHeadersMiddleware())
-	router.Use(handler.RateLimitMiddleware(cfg.RateLimitPerMinute))
-
-	// Health check endpoint (no authentication required)
-	router.GET("/health", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{
-			"status":  "healthy",
-			"service": "url-shortener",
-			"version": "1.0.0",
-		})
-	})
+func setupRouter(urlHandler *handler.URLHandler, apiKeyHandler *handler.APIKeyHandler, staticHandler *handler.StaticHandler, docsHandler *handler.DocsHandler, reloadHandler *handler.ReloadHandler, apiKeys *apikey.Store, cfg *config.Config, runtime *config.Runtime, log *customLogger.Logger) *gin.Engine {
+	// Middleware every request goes through, /health and /metrics are shared with cmd/redirector
+	quiet := handler.NewQuietPaths(cfg.QuietPaths)
+	router := bootstrap.NewRouter("url-shortener", cfg, quiet, log)
+
+	// Redirects, API reads and API writes have their own limits, kept in one shared store and read per request
+	limiter := handler.NewRuntimeRateLimiter(runtime).SkipQuietPaths(quiet).GroupIPv6(cfg.RateLimitIPv6Prefix)
+
+	router.GET("/favicon.ico", staticHandler.Favicon)
+
+	// Middleware of the API, the redirects and the 404 handler
+	appChain := []gin.HandlerFunc{
+		handler.CORSMiddleware(cfg, runtime),
+		handler.BaseURLMiddleware(cfg),
+		handler.APIKeyIdentityMiddleware(cfg, apiKeys), // Identify keys first so they are limited separately from their IP
+	}
+	app := router.Group("/", appChain...)
 
 	// API v1 routes
-	v1 := router.Group("/api/v1")
+	v1 := app.Group("/api/v1")
+	v1.Use(limiter.RuntimeMethodMiddleware()) // GETs and writes are limited separately
+	v1.Use(handler.BodyLimitMiddleware(cfg.MaxRequestBodyBytes)) // Refuse oversized bodies before they are buffered
+	if cfg.EnableCompression {
+		v1.Use(handler.CompressionMiddleware(cfg.CompressionMinBytes)) // Gzip large responses; redirects stay outside /api/v1
+	}
+	v1.Use(handler.TenantMiddleware(cfg)) // Only the links of the key's tenant, or X-Tenant-ID's for admins, are visible
+	{
+		// Long-running endpoints have no deadline, they stop when the client goes away
+		v1.GET("/export", handler.AuthMiddleware(cfg, apiKeys), urlHandler.ExportURLs) // Export URLs as CSV/JSON (auth required)
+		v1.POST("/admin/cache/flush", handler.AdminAuthMiddleware(cfg), urlHandler.FlushCache) // Drop the cache namespace (admin)
+	}
+
+	api := v1.Group("", handler.TimeoutMiddleware(cfg.RequestTimeout)) // 504 when a handler outlives REQUEST_TIMEOUT_SECONDS
 	{
 		// URL shortening endpoints
-		v1.POST("/shorten", urlHandler.ShortenURL)        // Create short URL
-		v1.GET("/urls/:shortCode", urlHandler.GetURLInfo) // Get URL details
-		v1.DELETE("/urls/:shortCode", urlHandler.DeleteURL) // Delete URL (optional auth)
-		v1.GET("/urls/:shortCode/stats", urlHandler.GetStats) // Get click statistics
+		api.POST("/shorten", urlHandler.ShortenURL) // Create short URL (identified keys get their own quota)
+		api.GET("/shorten", handler.ShortenLinkAuthMiddleware(cfg, apiKeys), urlHandler.ShortenLink) // Create from a bookmarklet or share target (?key= or signed ?ts=&sig=)
+		api.GET("/urls", handler.AuthMiddleware(cfg, apiKeys), urlHandler.ListURLs) // Active links, newest first; ?broken=true for broken ones (auth required)
+		api.GET("/urls/expiring", handler.AuthMiddleware(cfg, apiKeys), urlHandler.ListExpiring) // Links expiring within ?days (auth required)
+		api.GET("/urls/:shortCode", urlHandler.GetURLInfo) // Get URL details
+		api.POST("/urls/:shortCode/clone", urlHandler.CloneURL) // New link with the settings of an existing one
+		api.PATCH("/urls/:shortCode", handler.ManagementAuthMiddleware(cfg, apiKeys), urlHandler.UpdateURL) // Update URL settings (admin or management token; an API key for links without a token)
+		api.POST("/urls/:shortCode/extend", handler.ManagementAuthMiddleware(cfg, apiKeys), urlHandler.ExtendURL) // Push out the expiry (admin or management token; an API key for links without a token)
+		api.DELETE("/urls/:shortCode", handler.ManagementAuthMiddleware(cfg, apiKeys), urlHandler.DeleteURL) // Delete URL (admin or management token; an API key for links without a token)
+		api.GET("/urls/:shortCode/stats", urlHandler.GetStats) // Get click statistics
+		api.POST("/urls/:shortCode/stats/reset", handler.ManagementAuthMiddleware(cfg, apiKeys), urlHandler.ResetStats) // Zero the click statistics (admin or management token; an API key for links without a token)
+		api.GET("/urls/:shortCode/stats/timeseries", urlHandler.GetClickTimeSeries) // Clicks per hour, day or week
+		api.GET("/urls/:shortCode/stats/heatmap", urlHandler.GetClickHeatmap) // Clicks per weekday and hour of day
+		api.POST("/urls/:shortCode/hit", handler.RedirectTenantMiddleware(cfg), urlHandler.RegisterHit) // Count a click without redirecting (apps opening the destination)
+		api.POST("/conversions", urlHandler.RecordConversion) // Redeem an ush_click receipt as a conversion of its link
+		api.GET("/urls/:shortCode/pixel.gif", handler.RedirectTenantMiddleware(cfg), urlHandler.TrackingPixel) // Transparent GIF that counts a click, e.g. for email opens
+		api.PUT("/urls/:shortCode/deactivate", handler.AdminAuthMiddleware(cfg), urlHandler.DeactivateURL) // Disable link (admin)
+		api.PUT("/urls/:shortCode/activate", handler.AdminAuthMiddleware(cfg), urlHandler.ActivateURL)     // Re-enable link (admin)
+		api.POST("/urls/bulk-delete", handler.AdminAuthMiddleware(cfg), urlHandler.BulkDeactivate) // Deactivate links by code or filter (admin)
+		api.GET("/stats/summary", handler.AuthMiddleware(cfg, apiKeys), urlHandler.GetSummary) // Global dashboard numbers (auth required)
+		api.GET("/admin/urls", handler.AdminAuthMiddleware(cfg), urlHandler.ListByCreatorIP) // Links created from ?creator_ip, newest first (admin)
+		api.GET("/admin/urls/search", handler.AdminAuthMiddleware(cfg), urlHandler.SearchURLs) // Links pointing at ?host, optionally ?path_prefix (admin)
+		api.GET("/admin/urls/:shortCode", handler.AdminAuthMiddleware(cfg), urlHandler.GetAdminURLInfo) // URL details with creator metadata (admin)
+		api.POST("/admin/urls/:shortCode/metadata", handler.AdminAuthMiddleware(cfg), urlHandler.RefreshMetadata) // Re-fetch title and favicon (admin)
+		api.GET("/urls/:shortCode/snapshot", handler.AdminAuthMiddleware(cfg), urlHandler.GetSnapshot) // Destination as archived at creation (admin)
+		api.GET("/admin/api-keys", handler.AdminAuthMiddleware(cfg), apiKeyHandler.ListKeys)         // List issued keys (admin)
+		api.POST("/admin/api-keys", handler.AdminAuthMiddleware(cfg), apiKeyHandler.CreateKey)       // Issue a key, secret returned once (admin)
+		api.DELETE("/admin/api-keys/:id", handler.AdminAuthMiddleware(cfg), apiKeyHandler.RevokeKey) // Revoke a key (admin)
+		api.POST("/admin/reload", handler.AdminAuthMiddleware(cfg), reloadHandler.Reload) // Re-read the reloadable settings, like SIGHUP (admin)
+
+		// API description; cmd/server/routes_test.go fails when a route is missing from it
+		api.GET("/openapi.json", docsHandler.OpenAPISpec) // OpenAPI 3 spec
+		api.GET("/errors", docsHandler.ErrorCatalog) // Every error value with its statuses
+		if cfg.EnableAPIDocs {
+			api.GET("/docs", docsHandler.SwaggerUI) // Swagger UI for the spec
+		}
 	}
 
-	// Short URL redirection (public endpoint)
-	router.GET("/:shortCode", urlHandler.RedirectURL)
+	// Crawlers fetch robots.txt before the short code catch-all; favicon.ico is registered with the probes
+	app.GET("/robots.txt", staticHandler.RobotsTxt)
+	if cfg.EnableWebUI {
+		app.GET("/", urlHandler.ShortenForm) // Form posting to /api/v1/shorten; the catch-all never matches the bare root
+	}
 
-	// 404 handler
-	router.NoRoute(func(c *gin.Context) {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": "endpoint not found",
-		})
-	})
+	// Short URL redirection (public endpoint), on a tighter deadline than the API
+	redirects := app.Group("", handler.RedirectTenantMiddleware(cfg), limiter.RuntimeRedirectMiddleware(), handler.TimeoutMiddleware(cfg.RedirectTimeout))
+	{
+		redirects.GET("/:shortCode", urlHandler.RedirectURL)
+		redirects.GET("/:shortCode/*path", urlHandler.RedirectPath) // Multi-segment aliases, /continue, the second hop from the interstitial page, and /stats, the public stats page
+	}
+
+	// 404 handler, HTML for browsers and JSON for API clients; CORS preflights of API routes end up here too
+	router.NoRoute(append(appChain, urlHandler.NoRoute)...)
 
 	return router
 }
\ No newline at end of file
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/joho/godotenv"
+
+	"url-shortener/internal/bootstrap"
+	"url-shortener/internal/config"
+	"url-shortener/internal/migrations"
+)
+
+const migrateUsage = "usage: server migrate up|down|status"
+
+// runMigrate implements `server migrate up|down|status` and returns the exit code
+// It connects with the same settings as the server, so it works wherever the server would start.
+func runMigrate(args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+	command := args[0]
+	if command != "up" && command != "down" && command != "status" {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	_ = godotenv.Load()
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to load configuration:", err)
+		return 1
+	}
+	appLogger := bootstrap.NewLogger(cfg)
+	db, err := bootstrap.OpenDatabase(cfg, bootstrap.ServerRole(cfg), appLogger)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to initialize database:", err)
+		return 1
+	}
+	migrator, err := bootstrap.NewMigrator(db)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	if err := migrate(context.Background(), migrator, command, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// migrate runs one migrate command against migrator and reports the outcome on out
+func migrate(ctx context.Context, migrator *migrations.Migrator, command string, out io.Writer) error {
+	switch command {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Fprintln(out, "Schema is up to date")
+		}
+		for _, version := range applied {
+			fmt.Fprintf(out, "Applied %04d\n", version)
+		}
+	case "down":
+		version, err := migrator.Down(ctx)
+		if errors.Is(err, migrations.ErrNothingToRollBack) {
+			fmt.Fprintln(out, "No migration to roll back")
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Rolled back %04d\n", version)
+	case "status":
+		status, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, s := range status {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\n", s.Version, s.Name, applied)
+		}
+		return w.Flush()
+	default:
+		return errors.New(migrateUsage)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/config"
+	"url-shortener/internal/handler"
+	customLogger "url-shortener/pkg/logger"
+)
+
+// newQuietTestRouter builds the server's router with a budget of two API reads per minute
+func newQuietTestRouter(t *testing.T, quietPaths []string) *gin.Engine {
+	cfg, err := config.LoadFrom(nil)
+	require.NoError(t, err)
+	cfg.EnableMetrics = true
+	cfg.RateLimitAPIReads = 2
+	cfg.QuietPaths = quietPaths
+
+	log := customLogger.NewLogger(customLogger.Options{})
+	runtime := config.NewRuntime(cfg)
+	return setupRouter(
+		handler.NewURLHandler(nil, cfg, log),
+		handler.NewAPIKeyHandler(nil, log),
+		handler.NewStaticHandler(cfg, log),
+		handler.NewDocsHandler(cfg, log),
+		handler.NewReloadHandler(runtime, log),
+		nil, cfg, runtime, log,
+	)
+}
+
+// get sends a GET from the address every probe in these tests comes from
+func get(router *gin.Engine, path string) int {
+	req := httptest.NewRequest("GET", path, nil)
+	req.RemoteAddr = "10.0.0.2:4312"
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestQuietPaths_HealthFloodKeepsRateBudget(t *testing.T) {
+	router := newQuietTestRouter(t, []string{"/health", "/metrics", "/favicon.ico"})
+
+	for i := 0; i < 100; i++ {
+		require.Equal(t, http.StatusOK, get(router, "/health"))
+		require.Equal(t, http.StatusOK, get(router, "/metrics"))
+	}
+
+	assert.Equal(t, http.StatusOK, get(router, "/api/v1/openapi.json"), "the probes took no tokens")
+	assert.Equal(t, http.StatusOK, get(router, "/api/v1/openapi.json"))
+	assert.Equal(t, http.StatusTooManyRequests, get(router, "/api/v1/openapi.json"), "the budget still applies to the API")
+}
+
+func TestQuietPaths_ConfiguredPathSkipsLimiter(t *testing.T) {
+	router := newQuietTestRouter(t, []string{"/api/v1/errors"})
+
+	for i := 0; i < 10; i++ {
+		require.Equal(t, http.StatusOK, get(router, "/api/v1/errors"), "a quiet API path is never limited")
+	}
+	assert.Equal(t, http.StatusOK, get(router, "/api/v1/openapi.json"))
+	assert.Equal(t, http.StatusOK, get(router, "/api/v1/openapi.json"))
+	assert.Equal(t, http.StatusTooManyRequests, get(router, "/api/v1/openapi.json"))
+}
+
+func TestQuietPaths_PreflightStillAnswered(t *testing.T) {
+	router := newQuietTestRouter(t, nil)
+
+	req := httptest.NewRequest("OPTIONS", "/api/v1/shorten", nil)
+	req.Header.Set("Origin", "http://localhost:3000")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNoContent, w.Code, "CORS moved off the global chain but still covers unmatched preflights")
+	assert.Equal(t, "http://localhost:3000", w.Header().Get("Access-Control-Allow-Origin"))
+}
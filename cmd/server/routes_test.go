@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/config"
+	"url-shortener/internal/handler"
+	customLogger "url-shortener/pkg/logger"
+)
+
+// ginParam matches a gin path parameter such as :shortCode, or a catch-all such as *path
+var ginParam = regexp.MustCompile(`[:*]([A-Za-z]+)`)
+
+// TestOpenAPISpecMatchesRoutes walks the router's route table, so a route added without documenting it
+// (or a documented route that was removed) fails here instead of surprising client teams
+func TestOpenAPISpecMatchesRoutes(t *testing.T) {
+	cfg, err := config.LoadFrom(nil)
+	require.NoError(t, err)
+	// Optional routes are switched on so they are checked too
+	cfg.EnableMetrics = true
+	cfg.EnableAPIDocs = true
+	cfg.EnableWebUI = true
+
+	log := customLogger.NewLogger(customLogger.Options{})
+	runtime := config.NewRuntime(cfg)
+	router := setupRouter(
+		handler.NewURLHandler(nil, cfg, log),
+		handler.NewAPIKeyHandler(nil, log),
+		handler.NewStaticHandler(cfg, log),
+		handler.NewDocsHandler(cfg, log),
+		handler.NewReloadHandler(runtime, log),
+		nil, cfg, runtime, log,
+	)
+
+	routed := map[string]bool{}
+	for _, route := range router.Routes() {
+		routed[route.Method+" "+ginParam.ReplaceAllString(route.Path, "{$1}")] = true
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/openapi.json", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var spec struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &spec))
+
+	documented := map[string]bool{}
+	for path, item := range spec.Paths {
+		for method := range item {
+			if method != "parameters" {
+				documented[strings.ToUpper(method)+" "+path] = true
+			}
+		}
+	}
+
+	assert.Empty(t, missing(routed, documented), "routes missing from internal/handler/openapi.json")
+	assert.Empty(t, missing(documented, routed), "documented routes that aren't registered")
+}
+
+// missing lists the keys of want that got lacks, sorted
+func missing(want, got map[string]bool) []string {
+	var keys []string
+	for key := range want {
+		if !got[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// TestCompressionCoversOnlyTheAPI checks gzip is wired into /api/v1 and nowhere else
+func TestCompressionCoversOnlyTheAPI(t *testing.T) {
+	router := newQuietTestRouter(t, nil)
+
+	req := httptest.NewRequest("GET", "/api/v1/openapi.json", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+	assert.Contains(t, w.Header().Values("Vary"), "Accept-Encoding")
+
+	req = httptest.NewRequest("GET", "/robots.txt", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+	assert.Empty(t, w.Header().Get("Vary"), "the middleware doesn't run outside /api/v1")
+}
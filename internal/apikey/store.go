@@ -0,0 +1,188 @@
+// Package apikey issues, revokes and validates API keys stored in the api_keys table
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/repository"
+	"url-shortener/pkg/logger"
+)
+
+// keyPrefix marks issued keys so they are easy to spot in leaked config or logs
+const keyPrefix = "usk_"
+
+// Hash returns the hex SHA-256 of a key as stored in api_keys.key_hash
+func Hash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// Fingerprint returns the short key id used in audit logs and RATE_LIMIT_TIERS
+func Fingerprint(key string) string {
+	return Hash(key)[:8]
+}
+
+// Store validates keys against an in-memory copy of the active keys
+// The copy is reloaded at most once per ttl, so a revocation on another instance takes effect within ttl
+type Store struct {
+	repo   repository.APIKeyRepository
+	ttl    time.Duration
+	logger *logger.Logger
+
+	mu       sync.RWMutex
+	keys     map[string]domain.APIKey // Active keys by hash
+	loadedAt time.Time
+
+	refreshMu sync.Mutex // Lets one request reload while the others keep using the old copy
+}
+
+// NewStore creates a store that reloads active keys from repo every ttl
+func NewStore(repo repository.APIKeyRepository, ttl time.Duration, log *logger.Logger) *Store {
+	return &Store{repo: repo, ttl: ttl, logger: log, keys: make(map[string]domain.APIKey)}
+}
+
+// Validate returns the active key matching key
+// A nil store accepts nothing, so callers without a key table only use the env keys
+func (s *Store) Validate(ctx context.Context, key string) (*domain.APIKey, bool) {
+	if s == nil || key == "" {
+		return nil, false
+	}
+
+	if s.stale() {
+		s.refreshIfStale(ctx)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	found, ok := s.keys[Hash(key)]
+	if !ok {
+		return nil, false
+	}
+	return &found, true
+}
+
+// Refresh reloads the active keys now
+func (s *Store) Refresh(ctx context.Context) error {
+	keys, err := s.repo.ListActive(ctx)
+	if err != nil {
+		return err
+	}
+
+	byHash := make(map[string]domain.APIKey, len(keys))
+	for _, key := range keys {
+		byHash[key.KeyHash] = key
+	}
+
+	s.mu.Lock()
+	s.keys = byHash
+	s.loadedAt = time.Now()
+	s.mu.Unlock()
+	return nil
+}
+
+// Create issues a new key and returns it together with its secret
+func (s *Store) Create(ctx context.Context, req *domain.CreateAPIKeyRequest) (*domain.CreateAPIKeyResponse, error) {
+	label := strings.TrimSpace(req.Label)
+	if label == "" {
+		return nil, domain.NewValidationError("label is required")
+	}
+	if req.Tier < -1 {
+		return nil, domain.NewValidationError("tier must be a requests-per-minute limit, 0 for the default or -1 for unlimited")
+	}
+	if req.DefaultExpiryDays < domain.ExpiryNever || req.MaxExpiryDays < domain.ExpiryNever {
+		return nil, domain.NewValidationError("default_expiry_days and max_expiry_days must be a number of days, 0 for the global setting or -1 for never")
+	}
+	// A cap the key's own default breaks would refuse nothing yet make the key look bounded
+	if req.MaxExpiryDays > 0 && (req.DefaultExpiryDays == domain.ExpiryNever || req.DefaultExpiryDays > req.MaxExpiryDays) {
+		return nil, domain.NewValidationError("default_expiry_days must be within max_expiry_days")
+	}
+	if req.TenantID != domain.DefaultTenant && !domain.IsValidTenantID(req.TenantID) {
+		return nil, domain.NewValidationError("tenant_id must be lowercase letters, digits, - and _, starting with a letter or digit")
+	}
+
+	secret, err := generateKey()
+	if err != nil {
+		return nil, domain.NewInternalError(err)
+	}
+
+	key := &domain.APIKey{
+		KeyHash:     Hash(secret),
+		Fingerprint: Fingerprint(secret),
+		Label:       label,
+		Tier:        req.Tier,
+		TenantID:    req.TenantID,
+		DefaultExpiryDays: req.DefaultExpiryDays,
+		MaxExpiryDays:     req.MaxExpiryDays,
+	}
+	if err := s.repo.Create(ctx, key); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.keys[key.KeyHash] = *key
+	s.mu.Unlock()
+
+	return &domain.CreateAPIKeyResponse{APIKey: *key, Key: secret}, nil
+}
+
+// Revoke revokes a key; it stops working here immediately and on other instances within ttl
+func (s *Store) Revoke(ctx context.Context, id uint) (*domain.APIKey, error) {
+	key, err := s.repo.Revoke(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	delete(s.keys, key.KeyHash)
+	s.mu.Unlock()
+
+	return key, nil
+}
+
+// List returns all keys, revoked ones included
+func (s *Store) List(ctx context.Context) ([]domain.APIKey, error) {
+	return s.repo.List(ctx)
+}
+
+// stale reports whether the in-memory copy is older than ttl
+func (s *Store) stale() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return time.Since(s.loadedAt) >= s.ttl
+}
+
+// refreshIfStale reloads the keys unless another request already did
+// A failed reload keeps the previous copy and is retried after ttl instead of on every request
+func (s *Store) refreshIfStale(ctx context.Context) {
+	s.refreshMu.Lock()
+	defer s.refreshMu.Unlock()
+
+	if !s.stale() {
+		return
+	}
+
+	if err := s.Refresh(ctx); err != nil {
+		s.logger.Warn("Failed to reload API keys, using the previous set", "error", err)
+		s.mu.Lock()
+		s.loadedAt = time.Now()
+		s.mu.Unlock()
+	}
+}
+
+// generateKey returns a new random secret with the usk_ prefix
+func generateKey() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return keyPrefix + base64.RawURLEncoding.EncodeToString(buf), nil
+}
@@ -0,0 +1,177 @@
+// Package archive preserves what a destination looked like when it was shortened
+// Snapshots are evidence for takedown disputes, so they are stored as fetched and never rewritten
+package archive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"url-shortener/internal/config"
+	"url-shortener/internal/netguard"
+)
+
+const (
+	// DefaultTimeout bounds a whole capture including redirects
+	DefaultTimeout = 10 * time.Second
+
+	// DefaultMaxBytes is how much of a destination is stored when no limit is configured
+	DefaultMaxBytes = 512 << 10
+
+	// maxRedirects stops captures that bounce between hosts
+	maxRedirects = 5
+)
+
+// ErrSnapshotNotFound is returned when no snapshot is stored for a short code
+var ErrSnapshotNotFound = errors.New("snapshot not found")
+
+// Snapshot is the response a destination gave when its link was created
+type Snapshot struct {
+	ShortCode   string
+	SourceURL   string // Final URL after redirects
+	StatusCode  int
+	ContentType string
+	Content     []byte
+	Truncated   bool // Content was cut off at the size limit
+	FetchedAt   time.Time
+}
+
+// SnapshotStore persists snapshots keyed by short code
+// Implementations must be safe for concurrent use
+type SnapshotStore interface {
+	// Save stores a snapshot, replacing an earlier one for the same short code
+	Save(ctx context.Context, snapshot *Snapshot) error
+
+	// Get returns the snapshot of a short code, or ErrSnapshotNotFound
+	Get(ctx context.Context, shortCode string) (*Snapshot, error)
+
+	// Delete removes the snapshot of a short code; deleting one that isn't stored is not an error
+	Delete(ctx context.Context, shortCode string) error
+
+	// DeleteOlderThan removes snapshots fetched before cutoff and returns how many were removed
+	DeleteOlderThan(ctx context.Context, cutoff time.Time) (int64, error)
+}
+
+// NewStore builds the store selected by SNAPSHOT_STORE
+func NewStore(cfg *config.Config) (SnapshotStore, error) {
+	switch cfg.SnapshotStore {
+	case config.SnapshotStoreFilesystem:
+		return NewFileStore(cfg.SnapshotDir)
+	case config.SnapshotStoreS3:
+		return NewS3Store(S3Config{
+			Endpoint:  cfg.SnapshotS3Endpoint,
+			Bucket:    cfg.SnapshotS3Bucket,
+			Region:    cfg.SnapshotS3Region,
+			AccessKey: cfg.SnapshotS3AccessKey,
+			SecretKey: cfg.SnapshotS3SecretKey,
+			Prefix:    cfg.SnapshotS3Prefix,
+			PathStyle: cfg.SnapshotS3PathStyle,
+		}, &http.Client{Timeout: 30 * time.Second})
+	default:
+		return nil, fmt.Errorf("unsupported snapshot store %q", cfg.SnapshotStore)
+	}
+}
+
+// Fetcher captures a destination
+type Fetcher interface {
+	Capture(ctx context.Context, rawURL string) (*Snapshot, error)
+}
+
+// HTTPFetcher downloads destinations over HTTP with an SSRF guard
+// Every connection, including those made for redirects, is checked after DNS resolution
+type HTTPFetcher struct {
+	client   *http.Client
+	maxBytes int64
+}
+
+// NewHTTPFetcher creates a fetcher that stores at most maxBytes of each destination
+func NewHTTPFetcher(timeout time.Duration, maxBytes int64) *HTTPFetcher {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: netguard.Control,
+	}
+
+	return NewHTTPFetcherWithTransport(&http.Transport{
+		DialContext:         dialer.DialContext,
+		TLSHandshakeTimeout: timeout,
+		MaxIdleConns:        10,
+		IdleConnTimeout:     30 * time.Second,
+	}, timeout, maxBytes)
+}
+
+// NewHTTPFetcherWithTransport creates a fetcher on a custom transport, e.g. a stub in tests
+// The transport is responsible for its own address checks
+func NewHTTPFetcherWithTransport(transport http.RoundTripper, timeout time.Duration, maxBytes int64) *HTTPFetcher {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxBytes
+	}
+
+	return &HTTPFetcher{
+		maxBytes: maxBytes,
+		client: &http.Client{
+			Timeout:   timeout,
+			Transport: transport,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) >= maxRedirects {
+					return fmt.Errorf("stopped after %d redirects", maxRedirects)
+				}
+				if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+					return fmt.Errorf("redirect to unsupported scheme %q", req.URL.Scheme)
+				}
+				return nil
+			},
+		},
+	}
+}
+
+// Capture downloads rawURL and returns its response, cut off after the size limit
+// Error statuses are captured too; a 404 at shortening time is part of the record
+func (f *HTTPFetcher) Capture(ctx context.Context, rawURL string) (*Snapshot, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,*/*;q=0.8")
+	req.Header.Set("User-Agent", "url-shortener-archive/1.0")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// One extra byte tells a page of exactly maxBytes apart from a longer one
+	content, err := io.ReadAll(io.LimitReader(resp.Body, f.maxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	truncated := int64(len(content)) > f.maxBytes
+	if truncated {
+		content = content[:f.maxBytes]
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = http.DetectContentType(content)
+	}
+
+	return &Snapshot{
+		SourceURL:   resp.Request.URL.String(),
+		StatusCode:  resp.StatusCode,
+		ContentType: contentType,
+		Content:     content,
+		Truncated:   truncated,
+		FetchedAt:   time.Now().UTC(),
+	}, nil
+}
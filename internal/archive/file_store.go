@@ -0,0 +1,186 @@
+package archive
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"url-shortener/pkg/validator"
+)
+
+// FileStore keeps each snapshot as two files: <code>.body with the content and <code>.json describing it
+type FileStore struct {
+	dir string
+}
+
+// fileMeta is the <code>.json file written next to the content
+type fileMeta struct {
+	SourceURL   string    `json:"source_url"`
+	StatusCode  int       `json:"status_code"`
+	ContentType string    `json:"content_type"`
+	Truncated   bool      `json:"truncated,omitempty"`
+	FetchedAt   time.Time `json:"fetched_at"`
+}
+
+// NewFileStore creates the store, creating dir if it doesn't exist
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("create snapshot directory: %w", err)
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+// Save writes the content first and the description last, each through a rename
+// A crash in between leaves a body without a description, which Get treats as missing
+func (s *FileStore) Save(_ context.Context, snapshot *Snapshot) error {
+	bodyPath, metaPath, err := s.paths(snapshot.ShortCode)
+	if err != nil {
+		return err
+	}
+
+	meta, err := json.Marshal(fileMeta{
+		SourceURL:   snapshot.SourceURL,
+		StatusCode:  snapshot.StatusCode,
+		ContentType: snapshot.ContentType,
+		Truncated:   snapshot.Truncated,
+		FetchedAt:   snapshot.FetchedAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := writeFileAtomic(bodyPath, snapshot.Content); err != nil {
+		return err
+	}
+	return writeFileAtomic(metaPath, meta)
+}
+
+// Get reads a snapshot back
+func (s *FileStore) Get(_ context.Context, shortCode string) (*Snapshot, error) {
+	bodyPath, metaPath, err := s.paths(shortCode)
+	if err != nil {
+		return nil, ErrSnapshotNotFound
+	}
+
+	meta, err := readMeta(metaPath)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := os.ReadFile(bodyPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrSnapshotNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &Snapshot{
+		ShortCode:   shortCode,
+		SourceURL:   meta.SourceURL,
+		StatusCode:  meta.StatusCode,
+		ContentType: meta.ContentType,
+		Content:     content,
+		Truncated:   meta.Truncated,
+		FetchedAt:   meta.FetchedAt,
+	}, nil
+}
+
+// Delete removes both files of a snapshot, description first like DeleteOlderThan
+func (s *FileStore) Delete(_ context.Context, shortCode string) error {
+	bodyPath, metaPath, err := s.paths(shortCode)
+	if err != nil {
+		return nil
+	}
+
+	for _, path := range []string{metaPath, bodyPath} {
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteOlderThan removes snapshots whose fetched_at is before cutoff
+// Descriptions that can't be read are skipped rather than deleted, so nothing is purged by accident
+func (s *FileStore) DeleteOlderThan(ctx context.Context, cutoff time.Time) (int64, error) {
+	metaPaths, err := filepath.Glob(filepath.Join(s.dir, "*.json"))
+	if err != nil {
+		return 0, err
+	}
+
+	var deleted int64
+	for _, metaPath := range metaPaths {
+		if err := ctx.Err(); err != nil {
+			return deleted, err
+		}
+
+		meta, err := readMeta(metaPath)
+		if err != nil || !meta.FetchedAt.Before(cutoff) {
+			continue
+		}
+
+		// The description goes first, so an interrupted purge never leaves it pointing at nothing
+		if err := os.Remove(metaPath); err != nil {
+			return deleted, err
+		}
+		bodyPath := strings.TrimSuffix(metaPath, ".json") + ".body"
+		if err := os.Remove(bodyPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return deleted, err
+		}
+		deleted++
+	}
+	return deleted, nil
+}
+
+// paths returns the content and description files of a short code
+// Codes are checked against the short code alphabet so they can never escape the directory; the segments of
+// a multi-segment code are joined with dots, which no code contains, to keep every file in the directory itself
+func (s *FileStore) paths(shortCode string) (string, string, error) {
+	if !validator.ValidateShortCodePath(shortCode) {
+		return "", "", fmt.Errorf("invalid short code %q", shortCode)
+	}
+	base := filepath.Join(s.dir, strings.ReplaceAll(shortCode, "/", "."))
+	return base + ".body", base + ".json", nil
+}
+
+// readMeta loads a description file
+func readMeta(path string) (*fileMeta, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrSnapshotNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var meta fileMeta
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	return &meta, nil
+}
+
+// writeFileAtomic writes data to a temporary file and renames it over path
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".snapshot-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
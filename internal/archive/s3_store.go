@@ -0,0 +1,351 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// emptyPayloadHash is the SHA-256 of an empty body, signed on GET, HEAD and DELETE
+	emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+	amzDateFormat = "20060102T150405Z"
+)
+
+// S3Config addresses a bucket on an S3-compatible service
+type S3Config struct {
+	Endpoint  string // e.g. https://s3.eu-west-1.amazonaws.com or http://minio:9000
+	Bucket    string
+	Region    string
+	AccessKey string
+	SecretKey string
+	Prefix    string // Prepended to every object key
+	PathStyle bool   // Bucket in the path instead of the host name
+}
+
+// S3Store keeps each snapshot as one object, with its description in x-amz-meta headers
+// Requests are signed with Signature Version 4, which AWS, MinIO, R2 and Ceph all accept
+type S3Store struct {
+	cfg      S3Config
+	endpoint *url.URL
+	client   *http.Client
+}
+
+// NewS3Store creates a store on the bucket described by cfg
+func NewS3Store(cfg S3Config, client *http.Client) (*S3Store, error) {
+	endpoint, err := url.Parse(strings.TrimSuffix(cfg.Endpoint, "/"))
+	if err != nil || endpoint.Host == "" || (endpoint.Scheme != "http" && endpoint.Scheme != "https") {
+		return nil, fmt.Errorf("invalid S3 endpoint %q", cfg.Endpoint)
+	}
+	if cfg.Bucket == "" {
+		return nil, fmt.Errorf("S3 bucket is required")
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return &S3Store{cfg: cfg, endpoint: endpoint, client: client}, nil
+}
+
+// Save uploads the content with its description as object metadata
+func (s *S3Store) Save(ctx context.Context, snapshot *Snapshot) error {
+	header := http.Header{}
+	header.Set("Content-Type", snapshot.ContentType)
+	header.Set("X-Amz-Meta-Source-Url", url.QueryEscape(snapshot.SourceURL))
+	header.Set("X-Amz-Meta-Status-Code", strconv.Itoa(snapshot.StatusCode))
+	header.Set("X-Amz-Meta-Fetched-At", snapshot.FetchedAt.UTC().Format(time.RFC3339Nano))
+	header.Set("X-Amz-Meta-Truncated", strconv.FormatBool(snapshot.Truncated))
+
+	resp, err := s.do(ctx, http.MethodPut, s.key(snapshot.ShortCode), nil, header, snapshot.Content)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return checkResponse(resp)
+}
+
+// Get downloads a snapshot, ErrSnapshotNotFound when the object doesn't exist
+func (s *S3Store) Get(ctx context.Context, shortCode string) (*Snapshot, error) {
+	resp, err := s.do(ctx, http.MethodGet, s.key(shortCode), nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrSnapshotNotFound
+	}
+	if err := checkResponse(resp); err != nil {
+		return nil, err
+	}
+
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &Snapshot{
+		ShortCode:   shortCode,
+		ContentType: resp.Header.Get("Content-Type"),
+		Content:     content,
+	}
+	snapshot.SourceURL, _ = url.QueryUnescape(resp.Header.Get("X-Amz-Meta-Source-Url"))
+	snapshot.StatusCode, _ = strconv.Atoi(resp.Header.Get("X-Amz-Meta-Status-Code"))
+	snapshot.Truncated, _ = strconv.ParseBool(resp.Header.Get("X-Amz-Meta-Truncated"))
+	snapshot.FetchedAt, _ = time.Parse(time.RFC3339Nano, resp.Header.Get("X-Amz-Meta-Fetched-At"))
+	return snapshot, nil
+}
+
+// listBucketResult is the part of a ListObjectsV2 response the purge needs
+type listBucketResult struct {
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+	Contents              []struct {
+		Key          string    `xml:"Key"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+}
+
+// Delete removes the object of a short code
+func (s *S3Store) Delete(ctx context.Context, shortCode string) error {
+	return s.delete(ctx, s.key(shortCode))
+}
+
+// DeleteOlderThan removes objects under the prefix last written before cutoff
+// An object is written once when its link is created, so LastModified is the fetch time
+func (s *S3Store) DeleteOlderThan(ctx context.Context, cutoff time.Time) (int64, error) {
+	var deleted int64
+	token := ""
+
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {s.cfg.Prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+
+		page, err := s.list(ctx, query)
+		if err != nil {
+			return deleted, err
+		}
+
+		for _, object := range page.Contents {
+			if !object.LastModified.Before(cutoff) {
+				continue
+			}
+			if err := s.delete(ctx, object.Key); err != nil {
+				return deleted, err
+			}
+			deleted++
+		}
+
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			return deleted, nil
+		}
+		token = page.NextContinuationToken
+	}
+}
+
+// list fetches one page of object keys
+func (s *S3Store) list(ctx context.Context, query url.Values) (*listBucketResult, error) {
+	resp, err := s.do(ctx, http.MethodGet, "", query, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if err := checkResponse(resp); err != nil {
+		return nil, err
+	}
+
+	var page listBucketResult
+	if err := xml.NewDecoder(resp.Body).Decode(&page); err != nil {
+		return nil, fmt.Errorf("decode S3 listing: %w", err)
+	}
+	return &page, nil
+}
+
+// delete removes one object; S3 answers 204 whether or not it existed
+func (s *S3Store) delete(ctx context.Context, key string) error {
+	resp, err := s.do(ctx, http.MethodDelete, key, nil, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return checkResponse(resp)
+}
+
+// key returns the object key of a short code
+func (s *S3Store) key(shortCode string) string {
+	return s.cfg.Prefix + shortCode
+}
+
+// do sends a signed request for key, or for the bucket itself when key is empty
+func (s *S3Store) do(ctx context.Context, method, key string, query url.Values, header http.Header, body []byte) (*http.Response, error) {
+	target := *s.endpoint
+	path := strings.TrimSuffix(target.Path, "/")
+	if s.cfg.PathStyle {
+		path += "/" + s.cfg.Bucket
+	} else {
+		target.Host = s.cfg.Bucket + "." + target.Host
+	}
+	target.Path = path + "/" + key
+	target.RawPath = ""
+
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.URL.RawQuery = query.Encode()
+	for name, values := range header {
+		req.Header[name] = values
+	}
+
+	payloadHash := emptyPayloadHash
+	if len(body) > 0 {
+		sum := sha256.Sum256(body)
+		payloadHash = hex.EncodeToString(sum[:])
+	}
+	SignS3Request(req, payloadHash, s.cfg.Region, s.cfg.AccessKey, s.cfg.SecretKey, time.Now())
+
+	return s.client.Do(req)
+}
+
+// checkResponse turns a non-2xx answer into an error carrying the start of S3's error document
+func checkResponse(resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return fmt.Errorf("S3 %s %s: %s: %s", resp.Request.Method, resp.Request.URL.Path, resp.Status, strings.TrimSpace(string(detail)))
+}
+
+// SignS3Request signs req for the S3 service with Signature Version 4
+// Every header already on the request is signed along with Host; headers the
+// transport adds later, like User-Agent, are not. The query and path are
+// rewritten to their canonical encoding so what is sent matches what was signed.
+func SignS3Request(req *http.Request, payloadHash, region, accessKey, secretKey string, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format(amzDateFormat)
+	date := amzDate[:8]
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	req.Header.Del("Authorization")
+
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+
+	// Canonical headers: lower-case names, trimmed values, sorted by name
+	headers := map[string]string{"host": host}
+	for name, values := range req.Header {
+		trimmed := make([]string, len(values))
+		for i, value := range values {
+			trimmed[i] = strings.Join(strings.Fields(value), " ")
+		}
+		headers[strings.ToLower(name)] = strings.Join(trimmed, ",")
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalURI := s3EscapePath(req.URL.Path)
+	req.URL.RawPath = canonicalURI
+	canonicalQuery := s3CanonicalQuery(req.URL.Query())
+	req.URL.RawQuery = canonicalQuery
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		canonicalURI,
+		canonicalQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + region + "/s3/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+secretKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKey, scope, signedHeaders, signature))
+}
+
+// hmacSHA256 is one step of the signing key derivation
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// s3CanonicalQuery encodes query sorted by key and value, with empty values kept as key=
+func s3CanonicalQuery(query url.Values) string {
+	pairs := make([]string, 0, len(query))
+	for key, values := range query {
+		for _, value := range values {
+			pairs = append(pairs, s3Escape(key)+"="+s3Escape(value))
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "&")
+}
+
+// s3EscapePath encodes a path segment by segment, keeping the slashes
+func s3EscapePath(path string) string {
+	if path == "" {
+		return "/"
+	}
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = s3Escape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// s3Escape percent-encodes everything except the RFC 3986 unreserved characters
+// url.QueryEscape would turn spaces into +, which does not match the signature S3 computes
+func s3Escape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if ('A' <= c && c <= 'Z') || ('a' <= c && c <= 'z') || ('0' <= c && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
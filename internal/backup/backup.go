@@ -0,0 +1,418 @@
+// Package backup writes encrypted archives of the tables the control plane needs, and restores them
+// The urls table is left to the regular database backups: it is large, while API keys are small and a
+// recovery drill must get authentication working before the bulk data is back. Tenants have no table of
+// their own; they are the tenant_id of their keys and links, so restoring api_keys brings them back too.
+// The policy settings held in the configuration, such as the domain lists and tenant domains, are archived
+// alongside and restored as a configuration file. Per-link state like deactivation or the interstitial and
+// confirm flags is a column of the urls rows, so it is not part of these backups.
+package backup
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"url-shortener/internal/config"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/repository"
+)
+
+const (
+	// formatVersion is written into every archive and manifest; restores refuse other versions
+	formatVersion = 1
+
+	namePrefix     = "critical-"
+	nameLayout     = "20060102T150405.000Z"
+	archiveSuffix  = ".bak"
+	manifestSuffix = ".manifest.json"
+	saltSize       = 16
+)
+
+// magic starts every archive, so a wrong file is told apart from a wrong key
+var magic = []byte("USHBAK1\n")
+
+var (
+	// ErrNotFound is returned when the store holds no backup of the requested name, or none at all
+	ErrNotFound = errors.New("backup not found")
+
+	// ErrCorrupt is returned when a backup fails verification: a changed file, a wrong key or a manifest that doesn't match
+	ErrCorrupt = errors.New("backup failed verification")
+)
+
+// Store keeps backup files by name
+// Implementations must be safe for concurrent use
+type Store interface {
+	// Put writes a file, replacing one of the same name
+	Put(ctx context.Context, name string, data []byte) error
+
+	// Get reads a file, or returns ErrNotFound
+	Get(ctx context.Context, name string) ([]byte, error)
+
+	// List returns the names of every stored file in any order
+	List(ctx context.Context) ([]string, error)
+
+	// Delete removes a file; deleting one that isn't stored is not an error
+	Delete(ctx context.Context, name string) error
+}
+
+// Manifest describes one backup and is stored in the clear next to its archive
+// A drill can check what a backup holds and that the archive is intact without the key.
+type Manifest struct {
+	Name          string          `json:"name"`
+	Format        int             `json:"format"`
+	CreatedAt     time.Time       `json:"created_at"`
+	ArchiveSHA256 string          `json:"archive_sha256"` // Of the encrypted archive as stored
+	Tables        []TableManifest `json:"tables"`
+	PolicySHA256  string          `json:"policy_sha256"` // Of the policy as archived, before encryption
+	Tenants       []string        `json:"tenants"`       // Tenants with keys or a domain; "" is the default tenant
+}
+
+// TableManifest is the row count and checksum of one archived table
+type TableManifest struct {
+	Name   string `json:"name"`
+	Rows   int    `json:"rows"`
+	SHA256 string `json:"sha256"` // Of the table's rows as archived, before encryption
+}
+
+// contents is what an archive holds once decrypted
+// Tables are kept as raw JSON, so the checksums are computed over exactly the bytes restored.
+type contents struct {
+	Format    int             `json:"format"`
+	CreatedAt time.Time       `json:"created_at"`
+	APIKeys   json.RawMessage `json:"api_keys"`
+	Policy    json.RawMessage `json:"policy"`
+}
+
+// apiKeyRecord is an archived api_keys row
+// domain.APIKey leaves the hash out of its JSON; a restore needs it, or no key would work again.
+type apiKeyRecord struct {
+	ID                uint       `json:"id"`
+	KeyHash           string     `json:"key_hash"`
+	Fingerprint       string     `json:"fingerprint"`
+	Label             string     `json:"label"`
+	Tier              int        `json:"tier"`
+	TenantID          string     `json:"tenant_id"`
+	DefaultExpiryDays int        `json:"default_expiry_days"`
+	MaxExpiryDays     int        `json:"max_expiry_days"`
+	CreatedAt         time.Time  `json:"created_at"`
+	RevokedAt         *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Backups writes critical-table backups to a store and restores them
+type Backups struct {
+	store     Store
+	keys      repository.APIKeyRepository
+	settings  func() *config.Config
+	secret    string
+	retention int
+	now       func() time.Time
+}
+
+// New creates the backups of keys and the policy of settings in store, encrypted with secret
+// settings is called for every backup, so one taken after a reload has the reloaded lists; it may be nil
+// when no backup is created.
+// The retention newest backups are kept; a retention below 1 keeps every backup.
+func New(store Store, keys repository.APIKeyRepository, settings func() *config.Config, secret string, retention int) *Backups {
+	return &Backups{store: store, keys: keys, settings: settings, secret: secret, retention: retention, now: time.Now}
+}
+
+// Create writes a new backup and then deletes the oldest ones beyond the retention
+// The archive is written before its manifest, so every listed backup is complete.
+func (b *Backups) Create(ctx context.Context) (*Manifest, error) {
+	// Step 1: Read the tables
+	keys, err := b.keys.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("read api_keys: %w", err)
+	}
+	records := make([]apiKeyRecord, len(keys))
+	tenants := map[string]bool{}
+	for i, key := range keys {
+		records[i] = newAPIKeyRecord(&key)
+		tenants[key.TenantID] = true
+	}
+	table, err := json.Marshal(records)
+	if err != nil {
+		return nil, err
+	}
+	policy := PolicyOf(b.settings())
+	for tenant := range policy.TenantDomains {
+		tenants[tenant] = true
+	}
+	archivedPolicy, err := json.Marshal(policy)
+	if err != nil {
+		return nil, err
+	}
+
+	// Step 2: Encrypt them and describe the result
+	createdAt := b.now().UTC()
+	plaintext, err := json.Marshal(contents{Format: formatVersion, CreatedAt: createdAt, APIKeys: table, Policy: archivedPolicy})
+	if err != nil {
+		return nil, err
+	}
+	archive, err := seal(b.secret, plaintext)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &Manifest{
+		Name:          namePrefix + createdAt.Format(nameLayout),
+		Format:        formatVersion,
+		CreatedAt:     createdAt,
+		ArchiveSHA256: checksum(archive),
+		Tables:        []TableManifest{{Name: "api_keys", Rows: len(records), SHA256: checksum(table)}},
+		PolicySHA256:  checksum(archivedPolicy),
+		Tenants:       make([]string, 0, len(tenants)),
+	}
+	for tenant := range tenants {
+		manifest.Tenants = append(manifest.Tenants, tenant)
+	}
+	sort.Strings(manifest.Tenants)
+	encoded, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	// Step 3: Store both, then prune; a failed prune leaves an extra backup, not a missing one
+	if err := b.store.Put(ctx, manifest.Name+archiveSuffix, archive); err != nil {
+		return nil, fmt.Errorf("store archive: %w", err)
+	}
+	if err := b.store.Put(ctx, manifest.Name+manifestSuffix, encoded); err != nil {
+		return nil, fmt.Errorf("store manifest: %w", err)
+	}
+	if err := b.prune(ctx); err != nil {
+		return manifest, fmt.Errorf("prune old backups: %w", err)
+	}
+	return manifest, nil
+}
+
+// List returns the names of the stored backups, newest first
+func (b *Backups) List(ctx context.Context) ([]string, error) {
+	files, err := b.store.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, file := range files {
+		if strings.HasPrefix(file, namePrefix) && strings.HasSuffix(file, manifestSuffix) {
+			names = append(names, strings.TrimSuffix(file, manifestSuffix))
+		}
+	}
+	// The names embed their creation time in a fixed layout, so they sort by age
+	sort.Sort(sort.Reverse(sort.StringSlice(names)))
+	return names, nil
+}
+
+// Verify reads a backup, the newest when name is empty, and checks it against its manifest without restoring it
+func (b *Backups) Verify(ctx context.Context, name string) (*Manifest, error) {
+	manifest, _, err := b.load(ctx, name)
+	return manifest, err
+}
+
+// Restore verifies a backup, the newest when name is empty, loads its tables into the database and returns its policy
+// The tables must be empty, as in a freshly migrated database; nothing is restored otherwise.
+// The policy isn't applied here: the caller writes it out for the configuration the drill starts with.
+func (b *Backups) Restore(ctx context.Context, name string) (*Manifest, *Policy, error) {
+	manifest, archived, err := b.load(ctx, name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var records []apiKeyRecord
+	if err := json.Unmarshal(archived.APIKeys, &records); err != nil {
+		return nil, nil, fmt.Errorf("%w: api_keys: %v", ErrCorrupt, err)
+	}
+	var policy Policy
+	if err := json.Unmarshal(archived.Policy, &policy); err != nil {
+		return nil, nil, fmt.Errorf("%w: policy: %v", ErrCorrupt, err)
+	}
+	keys := make([]domain.APIKey, len(records))
+	for i, record := range records {
+		keys[i] = record.apiKey()
+	}
+	if err := b.keys.Restore(ctx, keys); err != nil {
+		return nil, nil, fmt.Errorf("restore api_keys: %w", err)
+	}
+	return manifest, &policy, nil
+}
+
+// load fetches a backup and its manifest and checks one against the other
+func (b *Backups) load(ctx context.Context, name string) (*Manifest, *contents, error) {
+	if name == "" {
+		names, err := b.List(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(names) == 0 {
+			return nil, nil, ErrNotFound
+		}
+		name = names[0]
+	}
+
+	encoded, err := b.store.Get(ctx, name+manifestSuffix)
+	if err != nil {
+		return nil, nil, err
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(encoded, &manifest); err != nil {
+		return nil, nil, fmt.Errorf("%w: manifest: %v", ErrCorrupt, err)
+	}
+	if manifest.Format != formatVersion || manifest.Name != name {
+		return nil, nil, fmt.Errorf("%w: manifest is for %q in format %d", ErrCorrupt, manifest.Name, manifest.Format)
+	}
+
+	archive, err := b.store.Get(ctx, name+archiveSuffix)
+	if err != nil {
+		return nil, nil, err
+	}
+	if checksum(archive) != manifest.ArchiveSHA256 {
+		return nil, nil, fmt.Errorf("%w: archive checksum doesn't match the manifest", ErrCorrupt)
+	}
+	plaintext, err := open(b.secret, archive)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var archived contents
+	if err := json.Unmarshal(plaintext, &archived); err != nil {
+		return nil, nil, fmt.Errorf("%w: archive: %v", ErrCorrupt, err)
+	}
+	if archived.Format != formatVersion || !archived.CreatedAt.Equal(manifest.CreatedAt) {
+		return nil, nil, fmt.Errorf("%w: archive doesn't belong to the manifest", ErrCorrupt)
+	}
+	for _, table := range manifest.Tables {
+		if table.Name != "api_keys" {
+			return nil, nil, fmt.Errorf("%w: unknown table %q", ErrCorrupt, table.Name)
+		}
+		var rows []json.RawMessage
+		if err := json.Unmarshal(archived.APIKeys, &rows); err != nil || len(rows) != table.Rows || checksum(archived.APIKeys) != table.SHA256 {
+			return nil, nil, fmt.Errorf("%w: %s doesn't match the manifest", ErrCorrupt, table.Name)
+		}
+	}
+	if checksum(archived.Policy) != manifest.PolicySHA256 {
+		return nil, nil, fmt.Errorf("%w: policy doesn't match the manifest", ErrCorrupt)
+	}
+	return &manifest, &archived, nil
+}
+
+// prune deletes the backups beyond the retention, manifest first so a half-deleted one is never listed
+func (b *Backups) prune(ctx context.Context) error {
+	if b.retention < 1 {
+		return nil
+	}
+	names, err := b.List(ctx)
+	if err != nil {
+		return err
+	}
+	for _, name := range names[min(len(names), b.retention):] {
+		if err := b.store.Delete(ctx, name+manifestSuffix); err != nil {
+			return err
+		}
+		if err := b.store.Delete(ctx, name+archiveSuffix); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// seal encrypts plaintext with AES-256-GCM under a key derived from secret and a random salt
+// The archive is magic, salt, nonce and the sealed data; the magic is authenticated along with it.
+func seal(secret string, plaintext []byte) ([]byte, error) {
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(secret, salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, 0, len(magic)+len(salt)+len(nonce)+len(plaintext)+aead.Overhead())
+	out = append(append(append(out, magic...), salt...), nonce...)
+	return aead.Seal(out, nonce, plaintext, magic), nil
+}
+
+// open reverses seal; a wrong secret and a changed archive both fail the authentication
+func open(secret string, archive []byte) ([]byte, error) {
+	if !bytes.HasPrefix(archive, magic) {
+		return nil, fmt.Errorf("%w: not a backup archive", ErrCorrupt)
+	}
+	rest := archive[len(magic):]
+	if len(rest) < saltSize {
+		return nil, fmt.Errorf("%w: archive is truncated", ErrCorrupt)
+	}
+	aead, err := newAEAD(secret, rest[:saltSize])
+	if err != nil {
+		return nil, err
+	}
+	rest = rest[saltSize:]
+	if len(rest) < aead.NonceSize() {
+		return nil, fmt.Errorf("%w: archive is truncated", ErrCorrupt)
+	}
+
+	plaintext, err := aead.Open(nil, rest[:aead.NonceSize()], rest[aead.NonceSize():], magic)
+	if err != nil {
+		return nil, fmt.Errorf("%w: wrong BACKUP_KEY or damaged archive", ErrCorrupt)
+	}
+	return plaintext, nil
+}
+
+// newAEAD derives the archive key with SHA-256 over salt and secret
+// BACKUP_KEY is required to be long, so it isn't stretched like a password would be.
+func newAEAD(secret string, salt []byte) (cipher.AEAD, error) {
+	key := sha256.Sum256(append(append([]byte{}, salt...), secret...))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// checksum is the hex SHA-256 recorded in manifests
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func newAPIKeyRecord(key *domain.APIKey) apiKeyRecord {
+	return apiKeyRecord{
+		ID:                key.ID,
+		KeyHash:           key.KeyHash,
+		Fingerprint:       key.Fingerprint,
+		Label:             key.Label,
+		Tier:              key.Tier,
+		TenantID:          key.TenantID,
+		DefaultExpiryDays: key.DefaultExpiryDays,
+		MaxExpiryDays:     key.MaxExpiryDays,
+		CreatedAt:         key.CreatedAt,
+		RevokedAt:         key.RevokedAt,
+	}
+}
+
+func (r apiKeyRecord) apiKey() domain.APIKey {
+	return domain.APIKey{
+		ID:                r.ID,
+		KeyHash:           r.KeyHash,
+		Fingerprint:       r.Fingerprint,
+		Label:             r.Label,
+		Tier:              r.Tier,
+		TenantID:          r.TenantID,
+		DefaultExpiryDays: r.DefaultExpiryDays,
+		MaxExpiryDays:     r.MaxExpiryDays,
+		CreatedAt:         r.CreatedAt,
+		RevokedAt:         r.RevokedAt,
+	}
+}
@@ -0,0 +1,92 @@
+package backup
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+
+	"url-shortener/internal/config"
+)
+
+// Policy is the part of the configuration deciding who may create and manage which links
+// It holds no secrets: API_KEY, ADMIN_API_KEY and the HMAC keys come from the drill's own environment.
+// The yaml keys are those of CONFIG_FILE, so a restored policy is loaded like any configuration file.
+type Policy struct {
+	EnableAuthentication       bool              `json:"enable_authentication" yaml:"enable_authentication"`
+	RequireManagementToken     bool              `json:"require_management_token" yaml:"require_management_token"`
+	TenantScopedCodes          bool              `json:"tenant_scoped_codes" yaml:"tenant_scoped_codes"`
+	TenantDomains              map[string]string `json:"tenant_domains" yaml:"tenant_domains"`
+	AllowedDomains             []string          `json:"allowed_domains" yaml:"allowed_domains"`
+	AllowedURLSchemes          []string          `json:"allowed_url_schemes" yaml:"allowed_url_schemes"`
+	StripTrackingParams        []string          `json:"strip_tracking_params" yaml:"strip_tracking_params"`
+	ShortenerDomains           []string          `json:"shortener_domains" yaml:"shortener_domains"`
+	ResolveShortenerChains     bool              `json:"resolve_shortener_chains" yaml:"resolve_shortener_chains"`
+	Hooks                      []string          `json:"hooks" yaml:"hooks"`
+	HookAllowedHosts           []string          `json:"hook_allowed_hosts" yaml:"hook_allowed_hosts"`
+	BotUserAgents              []string          `json:"bot_user_agents" yaml:"bot_user_agents"`
+	CORSAllowedOrigins         []string          `json:"cors_allowed_origins" yaml:"cors_allowed_origins"`
+	RateLimitPerMinute         int               `json:"rate_limit_per_minute" yaml:"rate_limit_per_minute"`
+	RateLimitRedirects         int               `json:"rate_limit_redirects" yaml:"rate_limit_redirects"`
+	RateLimitAPIReads          int               `json:"rate_limit_api_reads" yaml:"rate_limit_api_reads"`
+	RateLimitAPIWrites         int               `json:"rate_limit_api_writes" yaml:"rate_limit_api_writes"`
+	RateLimitTiers             map[string]string `json:"rate_limit_tiers" yaml:"rate_limit_tiers"` // As written in CONFIG_FILE, "unlimited" included
+	MaxURLsPerDayPerIP         int               `json:"max_urls_per_day_per_ip" yaml:"max_urls_per_day_per_ip"`
+	MaxURLsPerDayPerKey        int               `json:"max_urls_per_day_per_key" yaml:"max_urls_per_day_per_key"`
+	MaxURLsPerDayPerTenant     int               `json:"max_urls_per_day_per_tenant" yaml:"max_urls_per_day_per_tenant"`
+	MaxExpiryDays              int               `json:"max_expiry_days" yaml:"max_expiry_days"`
+	InterstitialAll            bool              `json:"interstitial_all" yaml:"interstitial_all"`
+	InterstitialNewLinkMinutes int               `json:"interstitial_new_link_minutes" yaml:"interstitial_new_link_minutes"`
+}
+
+// PolicyOf copies the policy settings out of cfg
+func PolicyOf(cfg *config.Config) Policy {
+	tiers := make(map[string]string, len(cfg.RateLimitTiers))
+	for id, perMinute := range cfg.RateLimitTiers {
+		tiers[id] = strconv.Itoa(perMinute)
+		if perMinute == config.RateLimitUnlimited {
+			tiers[id] = "unlimited"
+		}
+	}
+	return Policy{
+		EnableAuthentication:       cfg.EnableAuthentication,
+		RequireManagementToken:     cfg.RequireManagementToken,
+		TenantScopedCodes:          cfg.TenantScopedCodes,
+		TenantDomains:              cfg.TenantDomains,
+		AllowedDomains:             cfg.AllowedDomains,
+		AllowedURLSchemes:          cfg.AllowedURLSchemes,
+		StripTrackingParams:        cfg.StripTrackingParams,
+		ShortenerDomains:           cfg.ShortenerDomains,
+		ResolveShortenerChains:     cfg.ResolveShortenerChains,
+		Hooks:                      cfg.Hooks,
+		HookAllowedHosts:           cfg.HookAllowedHosts,
+		BotUserAgents:              cfg.BotUserAgents,
+		CORSAllowedOrigins:         cfg.CORSAllowedOrigins,
+		RateLimitPerMinute:         cfg.RateLimitPerMinute,
+		RateLimitRedirects:         cfg.RateLimitRedirects,
+		RateLimitAPIReads:          cfg.RateLimitAPIReads,
+		RateLimitAPIWrites:         cfg.RateLimitAPIWrites,
+		RateLimitTiers:             tiers,
+		MaxURLsPerDayPerIP:         cfg.MaxURLsPerDayPerIP,
+		MaxURLsPerDayPerKey:        cfg.MaxURLsPerDayPerKey,
+		MaxURLsPerDayPerTenant:     cfg.MaxURLsPerDayPerTenant,
+		MaxExpiryDays:              cfg.MaxExpiryDays,
+		InterstitialAll:            cfg.InterstitialAll,
+		InterstitialNewLinkMinutes: cfg.InterstitialNewLinkMinutes,
+	}
+}
+
+// WriteYAML writes the policy as a configuration file for CONFIG_FILE
+// Every key is written, empty lists too, so the drill runs with exactly the archived rules rather than the defaults.
+func (p *Policy) WriteYAML(w io.Writer, name string) error {
+	if _, err := fmt.Fprintf(w, "# Policy settings restored from backup %s\n", name); err != nil {
+		return err
+	}
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(p); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
@@ -0,0 +1,123 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"url-shortener/internal/config"
+)
+
+// NewStore builds the store selected by BACKUP_STORE
+func NewStore(cfg *config.Config) (Store, error) {
+	switch cfg.BackupStore {
+	case config.BackupStoreFilesystem:
+		return NewFileStore(cfg.BackupDir)
+	case config.BackupStoreS3:
+		return NewS3Store(S3Config{
+			Endpoint:  cfg.BackupS3Endpoint,
+			Bucket:    cfg.BackupS3Bucket,
+			Region:    cfg.BackupS3Region,
+			AccessKey: cfg.BackupS3AccessKey,
+			SecretKey: cfg.BackupS3SecretKey,
+			Prefix:    cfg.BackupS3Prefix,
+			PathStyle: cfg.BackupS3PathStyle,
+		}, &http.Client{Timeout: 30 * time.Second})
+	default:
+		return nil, fmt.Errorf("unsupported backup store %q", cfg.BackupStore)
+	}
+}
+
+// FileStore keeps every backup file directly under one directory
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore creates the store, creating dir if it doesn't exist
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("create backup directory: %w", err)
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+// Put writes the file through a rename, so a crash never leaves half a backup under its name
+func (s *FileStore) Put(_ context.Context, name string, data []byte) error {
+	path, err := s.path(name)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(s.dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Get reads a file back
+func (s *FileStore) Get(_ context.Context, name string) ([]byte, error) {
+	path, err := s.path(name)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
+
+// List returns the files in the directory, leaving out temporary ones
+func (s *FileStore) List(_ context.Context) ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && !strings.HasPrefix(entry.Name(), ".") {
+			names = append(names, entry.Name())
+		}
+	}
+	return names, nil
+}
+
+// Delete removes a file
+func (s *FileStore) Delete(_ context.Context, name string) error {
+	path, err := s.path(name)
+	if err != nil {
+		return nil
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// path maps a name to its file, refusing names that would leave the directory
+func (s *FileStore) path(name string) (string, error) {
+	if name == "" || strings.HasPrefix(name, ".") || strings.ContainsAny(name, `/\`) {
+		return "", fmt.Errorf("invalid backup file name %q", name)
+	}
+	return filepath.Join(s.dir, name), nil
+}
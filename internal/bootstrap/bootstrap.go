@@ -0,0 +1,68 @@
+// Package bootstrap holds the wiring shared by the binaries under cmd/: configuration, the database,
+// the cache, the router's common middleware and the HTTP server's lifecycle.
+package bootstrap
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/joho/godotenv"
+
+	"url-shortener/internal/config"
+	customLogger "url-shortener/pkg/logger"
+)
+
+// healthcheckURL is probed by the healthcheck subcommand of the Docker images
+const healthcheckURL = "http://localhost:8081/health"
+
+// Init loads .env and the configuration and sets up the logger; a broken configuration is fatal
+// The log level follows reloads of the returned runtime.
+func Init(name string) (*config.Config, *config.Runtime, *customLogger.Logger) {
+	// Load environment variables from .env file (development only)
+	if err := godotenv.Load(); err != nil {
+		log.Println("Warning: .env file not found, using environment variables")
+	}
+
+	// Load application configuration; until it is, failures go to a logger with the defaults
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		customLogger.NewLogger(customLogger.Options{}).Fatal("Failed to load configuration", "error", err)
+	}
+
+	// Initialize structured logger
+	appLogger := NewLogger(cfg)
+	appLogger.Info("Starting " + name)
+
+	// Rate limits, CORS origins, log level and the domain and bot lists can be reloaded with SIGHUP
+	runtime := config.NewRuntime(cfg)
+	runtime.OnReload(func(settings *config.RuntimeConfig) {
+		appLogger.SetLevel(settings.LogLevel)
+	})
+
+	return cfg, runtime, appLogger
+}
+
+// NewLogger returns a logger writing to stdout, and to the rotating LOG_FILE if one is set
+func NewLogger(cfg *config.Config) *customLogger.Logger {
+	return customLogger.NewLogger(customLogger.Options{
+		Level:       cfg.LogLevel,
+		Format:      cfg.LogFormat,
+		Development: cfg.IsDevelopment(),
+		File: customLogger.FileOptions{
+			Path:       cfg.LogFile,
+			MaxSizeMB:  cfg.LogMaxSizeMB,
+			MaxBackups: cfg.LogMaxBackups,
+			MaxAgeDays: cfg.LogMaxAgeDays,
+			Compress:   cfg.LogCompress,
+		},
+	})
+}
+
+// Healthcheck requests /health of the server running in the same container and returns the exit code
+func Healthcheck() int {
+	resp, err := http.Get(healthcheckURL)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,71 @@
+package bootstrap
+
+import (
+	"context"
+	"time"
+
+	"url-shortener/internal/cache"
+	"url-shortener/internal/config"
+	"url-shortener/internal/metrics"
+	"url-shortener/internal/service"
+	customLogger "url-shortener/pkg/logger"
+)
+
+// OpenCache connects to Redis behind the circuit breaker, or returns nil to run without a cache
+func OpenCache(cfg *config.Config, log *customLogger.Logger) cache.Cache {
+	redisCache, err := cache.NewRedisCacheWith(cache.RedisOptions{
+		Mode:          cfg.RedisMode,
+		Addr:          cfg.RedisAddr,
+		SentinelAddrs: cfg.RedisSentinelAddrs,
+		MasterName:    cfg.RedisMasterName,
+		ClusterAddrs:  cfg.RedisClusterAddrs,
+		Password:      cfg.RedisPassword,
+		DB:            cfg.RedisDB,
+	}, cfg.CacheNamespace)
+	if err != nil {
+		log.Warn("Failed to initialize Redis cache, continuing without cache", "error", err)
+		return nil
+	}
+
+	// Fail fast if Redis dies later instead of paying a read timeout on every request
+	return cache.NewCircuitBreaker(redisCache, cache.BreakerConfig{
+		FailureThreshold: cfg.CacheBreakerThreshold,
+		Cooldown:         cfg.CacheBreakerCooldown,
+		OnStateChange: func(from, to cache.BreakerState) {
+			log.Warn("Cache circuit breaker state changed", "from", from.String(), "to", to.String())
+			metrics.CacheBreakerState.Set(float64(to))
+			metrics.CacheBreakerTransitions.WithLabelValues(to.String()).Inc()
+		},
+	})
+}
+
+// CloseCache closes the Redis connection, if there is one
+func CloseCache(redisCache cache.Cache, log *customLogger.Logger) {
+	if redisCache == nil {
+		return
+	}
+	if err := redisCache.Close(); err != nil {
+		log.Error("Error closing Redis connection", "error", err)
+	}
+}
+
+// WarmCache loads the CACHE_WARM_LINKS most clicked links into the cache in the background
+// Requests are served meanwhile; the warm-up gives up after CACHE_WARM_TIMEOUT_SECONDS or when ctx is cancelled.
+func WarmCache(ctx context.Context, svc service.URLService, cfg *config.Config, log *customLogger.Logger) {
+	if cfg.CacheWarmLinks <= 0 {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(ctx, cfg.CacheWarmTimeout)
+		defer cancel()
+
+		start := time.Now()
+		warmed, err := svc.WarmCache(ctx, cfg.CacheWarmLinks)
+		if err != nil {
+			log.Warn("Cache warm-up stopped early", "error", err, "warmed", warmed, "duration", time.Since(start))
+			return
+		}
+		log.Info("Cache warmed", "warmed", warmed, "duration", time.Since(start))
+	}()
+}
@@ -0,0 +1,252 @@
+package bootstrap
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"url-shortener/internal/cache"
+	"url-shortener/internal/config"
+	"url-shortener/internal/migrations"
+	"url-shortener/internal/repository"
+	mongoRepo "url-shortener/internal/repository/mongo"
+	postgresRepo "url-shortener/internal/repository/postgres"
+	"url-shortener/internal/scheduler"
+	customLogger "url-shortener/pkg/logger"
+)
+
+// Role is the database user a binary connects as
+type Role struct {
+	User     string
+	Password string
+	ReadOnly bool // Sessions refuse writes, so a missing check in the code can't turn into one
+}
+
+// ServerRole is the DB_USER role of the API server, which owns the schema
+func ServerRole(cfg *config.Config) Role {
+	return Role{User: cfg.DBUser, Password: cfg.DBPassword}
+}
+
+// RedirectorRole is the role of cmd/redirector: REDIRECTOR_DB_USER when set, otherwise the server's
+func RedirectorRole(cfg *config.Config) Role {
+	role := ServerRole(cfg)
+	if cfg.RedirectorDBUser != "" {
+		role.User, role.Password = cfg.RedirectorDBUser, cfg.RedirectorDBPassword
+	}
+	role.ReadOnly = cfg.RedirectorReadOnly
+	return role
+}
+
+// DSN is the connection string of the primary database for role
+func (r Role) DSN(cfg *config.Config) string {
+	dsn := fmt.Sprintf(
+		"host=%s user=%s password=%s dbname=%s port=%s sslmode=%s TimeZone=UTC",
+		cfg.DBHost, r.User, r.Password, cfg.DBName, cfg.DBPort, cfg.DBSSLMode,
+	)
+	if r.ReadOnly {
+		dsn += " default_transaction_read_only=on"
+	}
+	return dsn
+}
+
+// OpenDatabase initializes the PostgreSQL database connection with connection pooling
+func OpenDatabase(cfg *config.Config, role Role, log *customLogger.Logger) (*gorm.DB, error) {
+	// Slow queries are logged as literal-free digests with the request that ran them
+	gormLogger := postgresRepo.NewQueryLogger(log, cfg.DBSlowQueryThreshold)
+
+	// Connect to PostgreSQL with retry logic
+	var db *gorm.DB
+	var err error
+
+	maxRetries := 5
+	for i := 0; i < maxRetries; i++ {
+		db, err = gorm.Open(postgres.Open(role.DSN(cfg)), &gorm.Config{
+			Logger:                 gormLogger,
+			SkipDefaultTransaction: true,
+			PrepareStmt:            true,
+		})
+
+		if err == nil {
+			break
+		}
+
+		log.Warn("Failed to connect to database, retrying...", "attempt", i+1, "error", err)
+		time.Sleep(5 * time.Second)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database after %d attempts: %w", maxRetries, err)
+	}
+
+	// Get underlying SQL DB for connection pool configuration
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database instance: %w", err)
+	}
+
+	// Size the pool for the database in front of us; behind PgBouncer keep it small with short lifetimes
+	configurePool(sqlDB, cfg)
+
+	// Verify database connection
+	if err := sqlDB.Ping(); err != nil {
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	log.Info("Database connection established successfully",
+		"max_open_conns", cfg.DBMaxOpenConns,
+		"max_idle_conns", cfg.DBMaxIdleConns,
+		"read_only", role.ReadOnly,
+	)
+
+	return db, nil
+}
+
+// configurePool applies the DB_* pool settings to a connection pool
+func configurePool(sqlDB *sql.DB, cfg *config.Config) {
+	sqlDB.SetMaxIdleConns(cfg.DBMaxIdleConns)
+	sqlDB.SetMaxOpenConns(cfg.DBMaxOpenConns)
+	sqlDB.SetConnMaxLifetime(cfg.DBConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(cfg.DBConnMaxIdleTime)
+}
+
+// openReplicas connects to every DB_REPLICA_DSNS entry with the primary's pool settings
+// An unreachable replica is left out rather than failing startup, since the primary can serve its reads
+func openReplicas(cfg *config.Config, log *customLogger.Logger) []repository.URLRepository {
+	gormLogger := postgresRepo.NewQueryLogger(log, cfg.DBSlowQueryThreshold)
+
+	var replicas []repository.URLRepository
+	for i, dsn := range cfg.DBReplicaDSNs {
+		db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+			Logger:                 gormLogger,
+			SkipDefaultTransaction: true,
+			PrepareStmt:            true,
+		})
+		if err != nil {
+			log.Warn("Failed to connect to read replica, skipping it", "replica", i, "error", err)
+			continue
+		}
+		sqlDB, err := db.DB()
+		if err != nil {
+			log.Warn("Failed to get read replica instance, skipping it", "replica", i, "error", err)
+			continue
+		}
+		configurePool(sqlDB, cfg)
+		replicas = append(replicas, postgresRepo.NewURLRepository(db))
+	}
+
+	log.Info("Read replicas connected", "connected", len(replicas), "configured", len(cfg.DBReplicaDSNs))
+	return replicas
+}
+
+// OpenLinkStore connects to MONGO_URI when DB_DRIVER=mongo keeps the links there, and returns nil otherwise
+// The server passes createIndexes, as it runs the migrations, so a fresh database gets its indexes on startup.
+func OpenLinkStore(cfg *config.Config, createIndexes bool, log *customLogger.Logger) (*mongo.Database, error) {
+	if cfg.DBDriver != config.DBDriverMongo {
+		return nil, nil
+	}
+
+	client, err := mongo.Connect(options.Client().ApplyURI(cfg.MongoURI).SetServerSelectionTimeout(30 * time.Second))
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure MongoDB client: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx, nil); err != nil {
+		return nil, fmt.Errorf("failed to ping MongoDB: %w", err)
+	}
+
+	db := client.Database(cfg.MongoDatabase)
+	if createIndexes {
+		if err := mongoRepo.EnsureIndexes(ctx, db); err != nil {
+			return nil, fmt.Errorf("failed to create MongoDB indexes: %w", err)
+		}
+	}
+
+	log.Info("MongoDB connection established successfully", "database", cfg.MongoDatabase)
+	return db, nil
+}
+
+// URLRepository is the link repository on db, reading through DB_REPLICA_DSNS when any are configured
+// A code written moments ago is re-read from the primary, using the cache to know which codes are fresh.
+// Queries on the primary are retried DB_RETRIES times after transient failures; replicas fall back to it.
+// With a links database from OpenLinkStore the links are kept in MongoDB, whose driver retries on its own,
+// and their click events, audit entries and outbox events stay in db.
+func URLRepository(db *gorm.DB, links *mongo.Database, linkCache cache.Cache, cfg *config.Config, log *customLogger.Logger) repository.URLRepository {
+	if links != nil {
+		return mongoRepo.NewURLRepository(links, postgresRepo.NewLinkRecords(db))
+	}
+	retries := postgresRepo.RetryPolicy{Attempts: cfg.DBRetries, Backoff: cfg.DBRetryBackoff}
+	urlRepo := postgresRepo.NewRetryingURLRepository(postgresRepo.NewURLRepository(db), retries, log)
+	if len(cfg.DBReplicaDSNs) == 0 {
+		return urlRepo
+	}
+	return postgresRepo.NewReplicaURLRepository(urlRepo, openReplicas(cfg, log), linkCache, cfg.DBReplicaFallbackWindow, log)
+}
+
+// NewMigrator builds the migrator on the connection pool under db
+// gorm prepares statements, which Postgres refuses for the multi-statement migration files, so
+// the migrator talks to the pool directly.
+func NewMigrator(db *gorm.DB) (*migrations.Migrator, error) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database instance: %w", err)
+	}
+	return migrations.New(sqlDB)
+}
+
+// PrepareSchema applies pending migrations when AUTO_MIGRATE is on, and otherwise checks that none are pending
+func PrepareSchema(db *gorm.DB, cfg *config.Config, log *customLogger.Logger) error {
+	if !cfg.AutoMigrate {
+		return CheckSchema(db)
+	}
+
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	applied, err := migrator.Up(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to migrate database: %w", err)
+	}
+	if len(applied) > 0 {
+		log.Info("Database schema migrated", "applied", applied, "version", migrator.Latest(), "duration", time.Since(start))
+	}
+	return nil
+}
+
+// CheckSchema fails while any migration is pending; it only reads, so it works for a read-only role
+func CheckSchema(db *gorm.DB) error {
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		return err
+	}
+	if err := migrator.Check(context.Background()); err != nil {
+		return fmt.Errorf("%w: run ./server migrate up or start with AUTO_MIGRATE=true", err)
+	}
+	return nil
+}
+
+// PoolStatsJob exports the primary pool's usage to /metrics; there is none without ENABLE_METRICS
+func PoolStatsJob(db *gorm.DB, cfg *config.Config) (scheduler.Job, bool) {
+	if !cfg.EnableMetrics {
+		return scheduler.Job{}, false
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return scheduler.Job{}, false
+	}
+	return scheduler.Job{
+		Name:     "db_pool_stats",
+		Interval: cfg.DBStatsInterval,
+		Run:      postgresRepo.NewPoolStatsReporter(sqlDB).Run,
+	}, true
+}
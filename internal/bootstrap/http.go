@@ -0,0 +1,100 @@
+package bootstrap
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"url-shortener/internal/config"
+	"url-shortener/internal/handler"
+	"url-shortener/internal/metrics"
+	customLogger "url-shortener/pkg/logger"
+)
+
+// NewRouter builds a router with the middleware every request goes through, probes included,
+// and registers /health and, with ENABLE_METRICS, /metrics
+// Probes and scrapes stop at this middleware: no CORS, base URL, key lookup or rate limit.
+func NewRouter(service string, cfg *config.Config, quiet handler.QuietPaths, log *customLogger.Logger) *gin.Engine {
+	// Set Gin mode based on environment
+	if cfg.Environment == "production" {
+		gin.SetMode(gin.ReleaseMode)
+	}
+
+	router := gin.New()
+	// X-Forwarded-For is believed only from TRUSTED_PROXIES; without any, the peer address is the client
+	var proxies []string
+	if len(cfg.TrustedProxies) > 0 {
+		proxies = cfg.TrustedProxies
+	}
+	if err := router.SetTrustedProxies(proxies); err != nil {
+		log.Fatal("Invalid TRUSTED_PROXIES", "error", err)
+	}
+	router.UseRawPath = true // The API addresses multi-segment codes with escaped slashes, e.g. /api/v1/urls/promo%2Fsummer
+	router.Use(gin.Recovery()) // Panic recovery
+	router.Use(handler.RequestIDMiddleware()) // Tag the request before anything logs about it
+	router.Use(handler.ErrorFormatMiddleware(cfg)) // problem+json for every error with PROBLEM_DETAILS
+	router.Use(handler.LoggerMiddleware(log, quiet)) // Quiet paths are logged at debug level
+	router.Use(handler.SecurityHeadersMiddleware())
+
+	// Health check endpoint (no authentication required)
+	router.GET("/health", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  "healthy",
+			"service": service,
+			"version": "1.0.0",
+		})
+	})
+
+	// Prometheus metrics (expose only on internal networks)
+	if cfg.EnableMetrics {
+		router.GET("/metrics", metrics.Handler())
+	}
+	return router
+}
+
+// NewHTTPServer serves handler on SERVER_PORT with the read, write and idle timeouts of every binary
+func NewHTTPServer(cfg *config.Config, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:           fmt.Sprintf(":%s", cfg.ServerPort),
+		Handler:        handler,
+		ReadTimeout:    15 * time.Second,
+		WriteTimeout:   15 * time.Second,
+		IdleTimeout:    60 * time.Second,
+		MaxHeaderBytes: 1 << 20, // 1 MB
+	}
+}
+
+// Serve starts srv in a goroutine; failing to listen is fatal
+func Serve(srv *http.Server, log *customLogger.Logger) {
+	go func() {
+		log.Info("Server starting", "port", strings.TrimPrefix(srv.Addr, ":"))
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatal("Failed to start server", "error", err)
+		}
+	}()
+}
+
+// ReloadOnSIGHUP reloads the configuration on every SIGHUP
+// Requests in flight finish with the settings they started with.
+func ReloadOnSIGHUP(reloads *handler.ReloadHandler) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	go func() {
+		for range signals {
+			reloads.ReloadConfig()
+		}
+	}()
+}
+
+// WaitForShutdown blocks until SIGINT or SIGTERM
+func WaitForShutdown() {
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+}
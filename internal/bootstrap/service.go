@@ -0,0 +1,46 @@
+package bootstrap
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+
+	"url-shortener/internal/config"
+	"url-shortener/internal/geo"
+	"url-shortener/internal/hooks"
+	postgresRepo "url-shortener/internal/repository/postgres"
+	"url-shortener/internal/service"
+	customLogger "url-shortener/pkg/logger"
+)
+
+// ResolveOptions are the service options redirects depend on, shared by the server and the redirector
+// Click events go to the outbox in the click's transaction; the server's relay publishes them.
+func ResolveOptions(db *gorm.DB, cfg *config.Config, runtime *config.Runtime, log *customLogger.Logger) []service.Option {
+	opts := []service.Option{
+		service.WithRuntimeConfig(runtime),
+		service.WithClickRepository(postgresRepo.NewClickRepository(db)),
+	}
+
+	// Load the GeoIP table for country rules; a broken table must not silently disable them
+	if cfg.GeoIPCIDRFile != "" {
+		geoResolver, err := geo.LoadCIDRFile(cfg.GeoIPCIDRFile)
+		if err != nil {
+			log.Fatal("Failed to load GeoIP table", "error", err, "path", cfg.GeoIPCIDRFile)
+		}
+		opts = append(opts, service.WithGeoResolver(geoResolver))
+	}
+
+	if cfg.EventsDriver != config.EventsDriverNone {
+		opts = append(opts, service.WithOutbox(postgresRepo.NewOutboxRepository(db), postgresRepo.NewTransactor(db)))
+	}
+
+	// Hooks compiled into the build only run when HOOKS names them; a name the build lacks is a typo
+	if len(cfg.Hooks) > 0 {
+		built, err := hooks.Build(cfg)
+		if err != nil {
+			log.Fatal("Failed to initialize hooks", "error", err, "available", strings.Join(hooks.Registered(), ","))
+		}
+		opts = append(opts, service.WithHooks(built))
+	}
+	return opts
+}
@@ -0,0 +1,400 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned instead of calling the backend while the breaker is open
+var ErrCircuitOpen = errors.New("cache circuit breaker is open")
+
+// BreakerState is the state of a circuit breaker
+type BreakerState int
+
+// Breaker states
+const (
+	StateClosed BreakerState = iota
+	StateHalfOpen
+	StateOpen
+)
+
+// String returns the state name used in logs and metrics
+func (s BreakerState) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateHalfOpen:
+		return "half_open"
+	case StateOpen:
+		return "open"
+	default:
+		return "unknown"
+	}
+}
+
+// BreakerConfig tunes the circuit breaker
+type BreakerConfig struct {
+	FailureThreshold  int           // Consecutive failures that open the breaker
+	Cooldown          time.Duration // How long the breaker stays open before a probe is allowed
+	MaxPendingDeletes int           // Missed deletes kept for replay once the backend is back
+
+	// OnStateChange is called after every transition, outside the breaker lock
+	OnStateChange func(from, to BreakerState)
+
+	// Now overrides the clock in tests; nil uses time.Now
+	Now func() time.Time
+}
+
+// breakerCache decorates a Cache with a circuit breaker
+// While open, calls fail fast instead of waiting for backend timeouts, so callers
+// fall back to the database immediately. Deletes that can't reach the backend are
+// remembered and replayed before anything else once it answers again, so a link
+// changed during an outage isn't served stale from the cache afterwards.
+type breakerCache struct {
+	next Cache
+	cfg  BreakerConfig
+
+	mu       sync.Mutex
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	probing  bool                // A half-open probe is in flight
+	pending  map[string]struct{} // Keys whose delete was missed, replayed on recovery
+
+	replayMu sync.Mutex // Serializes replays, so no call passes while one is in flight
+}
+
+// NewCircuitBreaker wraps next with a circuit breaker
+// Zero config values default to 5 failures, a 30 second cooldown and 10000 pending deletes
+func NewCircuitBreaker(next Cache, cfg BreakerConfig) Cache {
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = 5
+	}
+	if cfg.Cooldown <= 0 {
+		cfg.Cooldown = 30 * time.Second
+	}
+	if cfg.MaxPendingDeletes <= 0 {
+		cfg.MaxPendingDeletes = 10000
+	}
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+	return &breakerCache{next: next, cfg: cfg, pending: make(map[string]struct{})}
+}
+
+// Set stores a value; writes while open are skipped silently
+func (b *breakerCache) Set(ctx context.Context, key string, value string, ttl time.Duration) error {
+	if !b.pass(ctx) {
+		return nil
+	}
+	err := b.next.Set(ctx, key, value, ttl)
+	b.record(err)
+	return err
+}
+
+// Get retrieves a value or fails fast while open
+func (b *breakerCache) Get(ctx context.Context, key string) (string, error) {
+	if !b.pass(ctx) {
+		return "", ErrCircuitOpen
+	}
+	value, err := b.next.Get(ctx, key)
+	b.record(err)
+	return value, err
+}
+
+// Delete removes a key or fails fast while open
+// A missed delete is queued for replay; the error is still surfaced so callers know
+// the invalidation hasn't happened yet
+func (b *breakerCache) Delete(ctx context.Context, key string) error {
+	if !b.pass(ctx) {
+		b.queueDeletes([]string{key})
+		return ErrCircuitOpen
+	}
+	err := b.next.Delete(ctx, key)
+	b.record(err)
+	if err != nil && !errors.Is(err, context.Canceled) {
+		b.queueDeletes([]string{key})
+	}
+	return err
+}
+
+// Exists checks a key or fails fast while open
+func (b *breakerCache) Exists(ctx context.Context, key string) (bool, error) {
+	if !b.pass(ctx) {
+		return false, ErrCircuitOpen
+	}
+	exists, err := b.next.Exists(ctx, key)
+	b.record(err)
+	return exists, err
+}
+
+// IncrBy forwards to the wrapped cache when it supports counters, failing fast while open
+func (b *breakerCache) IncrBy(ctx context.Context, key string, delta int64, expireAt time.Time) (int64, error) {
+	counter, ok := b.next.(Counter)
+	if !ok {
+		return 0, ErrCounterUnsupported
+	}
+	if !b.pass(ctx) {
+		return 0, ErrCircuitOpen
+	}
+	value, err := counter.IncrBy(ctx, key, delta, expireAt)
+	b.record(err)
+	return value, err
+}
+
+// SetMultiple forwards to the wrapped cache when it supports batch sets, failing fast while open
+func (b *breakerCache) SetMultiple(ctx context.Context, items map[string]string, ttl time.Duration) error {
+	setter, ok := b.next.(BatchSetter)
+	if !ok {
+		return ErrBatchSetUnsupported
+	}
+	if !b.pass(ctx) {
+		return ErrCircuitOpen
+	}
+	err := setter.SetMultiple(ctx, items, ttl)
+	b.record(err)
+	return err
+}
+
+// DeleteMultiple forwards to the wrapped cache when it supports batch deletes, failing fast while open
+// Missed keys are queued for replay like Delete's
+func (b *breakerCache) DeleteMultiple(ctx context.Context, keys []string) error {
+	deleter, ok := b.next.(BatchDeleter)
+	if !ok {
+		return ErrBatchDeleteUnsupported
+	}
+	if !b.pass(ctx) {
+		b.queueDeletes(keys)
+		return ErrCircuitOpen
+	}
+	err := deleter.DeleteMultiple(ctx, keys)
+	b.record(err)
+	if err != nil && !errors.Is(err, context.Canceled) {
+		b.queueDeletes(keys)
+	}
+	return err
+}
+
+// SetIfAbsent forwards to the wrapped cache when it supports conditional sets, failing fast while open
+func (b *breakerCache) SetIfAbsent(ctx context.Context, key, value string, ttl time.Duration) (bool, error) {
+	claimer, ok := b.next.(Claimer)
+	if !ok {
+		return false, ErrClaimUnsupported
+	}
+	if !b.pass(ctx) {
+		return false, ErrCircuitOpen
+	}
+	stored, err := claimer.SetIfAbsent(ctx, key, value, ttl)
+	b.record(err)
+	return stored, err
+}
+
+// TryLock forwards to the wrapped cache when it supports locks, failing fast while open
+func (b *breakerCache) TryLock(ctx context.Context, key string, ttl time.Duration) (func(), bool, error) {
+	locker, ok := b.next.(Locker)
+	if !ok {
+		return nil, false, ErrLockUnsupported
+	}
+	if !b.pass(ctx) {
+		return nil, false, ErrCircuitOpen
+	}
+	release, acquired, err := locker.TryLock(ctx, key, ttl)
+	b.record(err)
+	return release, acquired, err
+}
+
+// ResolveAndCount forwards to the wrapped cache when it supports one-trip redirects, failing fast while open
+func (b *breakerCache) ResolveAndCount(ctx context.Context, req ResolveRequest) (Resolution, error) {
+	resolver, ok := b.next.(Resolver)
+	if !ok {
+		return Resolution{}, ErrResolveUnsupported
+	}
+	if !b.pass(ctx) {
+		return Resolution{}, ErrCircuitOpen
+	}
+	res, err := resolver.ResolveAndCount(ctx, req)
+	b.record(err)
+	return res, err
+}
+
+// CountVisit forwards to the wrapped cache when it supports one-trip redirects, skipped while open like Set
+func (b *breakerCache) CountVisit(ctx context.Context, counters VisitCounters) error {
+	resolver, ok := b.next.(Resolver)
+	if !ok {
+		return ErrResolveUnsupported
+	}
+	if !b.pass(ctx) {
+		return nil
+	}
+	err := resolver.CountVisit(ctx, counters)
+	b.record(err)
+	return err
+}
+
+// VisitCounts forwards to the wrapped cache when it supports one-trip redirects, failing fast while open
+func (b *breakerCache) VisitCounts(ctx context.Context, counters VisitCounters) (int64, int64, error) {
+	resolver, ok := b.next.(Resolver)
+	if !ok {
+		return 0, 0, ErrResolveUnsupported
+	}
+	if !b.pass(ctx) {
+		return 0, 0, ErrCircuitOpen
+	}
+	visits, visitors, err := resolver.VisitCounts(ctx, counters)
+	b.record(err)
+	return visits, visitors, err
+}
+
+// FlushNamespace forwards to the wrapped cache when it supports flushing
+// A flush is an emergency tool, so it fails fast like any other call while open
+func (b *breakerCache) FlushNamespace(ctx context.Context, keysPerSecond int) (int64, error) {
+	flusher, ok := b.next.(Flusher)
+	if !ok {
+		return 0, ErrFlushUnsupported
+	}
+	if !b.pass(ctx) {
+		return 0, ErrCircuitOpen
+	}
+	deleted, err := flusher.FlushNamespace(ctx, keysPerSecond)
+	b.record(err)
+	return deleted, err
+}
+
+// Close closes the underlying cache
+func (b *breakerCache) Close() error {
+	return b.next.Close()
+}
+
+// pass reports whether a call may reach the backend, replaying missed deletes first
+// A failed replay counts as the call's failure, which then fails fast as if the breaker were open
+func (b *breakerCache) pass(ctx context.Context) bool {
+	if !b.allow() {
+		return false
+	}
+	if err := b.replay(ctx); err != nil {
+		b.record(err)
+		return false
+	}
+	return true
+}
+
+// queueDeletes queues keys whose delete didn't reach the backend
+// Beyond MaxPendingDeletes further keys are dropped and left to their TTL.
+func (b *breakerCache) queueDeletes(keys []string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, key := range keys {
+		if len(b.pending) >= b.cfg.MaxPendingDeletes {
+			return
+		}
+		b.pending[key] = struct{}{}
+	}
+}
+
+// replay deletes the queued keys before a call is let through
+// Calls arriving meanwhile wait for it, so none of them can read a key it is about to delete.
+func (b *breakerCache) replay(ctx context.Context) error {
+	b.mu.Lock()
+	queued := len(b.pending)
+	b.mu.Unlock()
+	if queued == 0 {
+		return nil
+	}
+
+	b.replayMu.Lock()
+	defer b.replayMu.Unlock()
+
+	b.mu.Lock()
+	keys := make([]string, 0, len(b.pending))
+	for key := range b.pending {
+		keys = append(keys, key)
+	}
+	b.mu.Unlock()
+	if len(keys) == 0 {
+		return nil // Another call replayed them while this one waited
+	}
+
+	if deleter, ok := b.next.(BatchDeleter); ok {
+		if err := deleter.DeleteMultiple(ctx, keys); err != nil {
+			return err
+		}
+	} else {
+		for _, key := range keys {
+			if err := b.next.Delete(ctx, key); err != nil {
+				return err
+			}
+		}
+	}
+
+	b.mu.Lock()
+	for _, key := range keys {
+		delete(b.pending, key)
+	}
+	b.mu.Unlock()
+	return nil
+}
+
+// allow reports whether a call may reach the backend
+// After the cooldown a single probe is let through in the half-open state
+func (b *breakerCache) allow() bool {
+	b.mu.Lock()
+	from := b.state
+	allowed := true
+
+	switch b.state {
+	case StateOpen:
+		if b.cfg.Now().Sub(b.openedAt) < b.cfg.Cooldown {
+			allowed = false
+		} else {
+			b.state = StateHalfOpen
+			b.probing = true
+		}
+	case StateHalfOpen:
+		if b.probing {
+			allowed = false
+		} else {
+			b.probing = true
+		}
+	}
+
+	to := b.state
+	b.mu.Unlock()
+
+	b.notify(from, to)
+	return allowed
+}
+
+// record updates the breaker with the outcome of a backend call
+func (b *breakerCache) record(err error) {
+	b.mu.Lock()
+	from := b.state
+	b.probing = false
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		// A caller giving up is not a sign of an unhealthy backend
+	case err == nil:
+		b.failures = 0
+		b.state = StateClosed
+	default:
+		b.failures++
+		if b.state == StateHalfOpen || b.failures >= b.cfg.FailureThreshold {
+			b.state = StateOpen
+			b.openedAt = b.cfg.Now()
+		}
+	}
+
+	to := b.state
+	b.mu.Unlock()
+
+	b.notify(from, to)
+}
+
+// notify reports a state change to the listener, outside the lock
+func (b *breakerCache) notify(from, to BreakerState) {
+	if from != to && b.cfg.OnStateChange != nil {
+		b.cfg.OnStateChange(from, to)
+	}
+}
@@ -2,6 +2,7 @@ package cache
 
 import (
 	"context"
+	"errors"
 	"time"
 )
 
@@ -22,4 +23,77 @@ type Cache interface {
 	
 	// Close closes the cache connection
 	Close() error
-}
\ No newline at end of file
+}
+
+// Flusher is implemented by caches that can drop their whole keyspace
+// It is optional so in-memory and test caches don't have to support it
+type Flusher interface {
+	// FlushNamespace deletes all keys under the current namespace, at most keysPerSecond at a time
+	// Returns the number of keys deleted, also when the flush stops early with an error
+	FlushNamespace(ctx context.Context, keysPerSecond int) (int64, error)
+}
+
+// Counter is implemented by caches that support atomic counters
+type Counter interface {
+	// IncrBy atomically adds delta to key, sets it to expire at expireAt and returns the new value
+	IncrBy(ctx context.Context, key string, delta int64, expireAt time.Time) (int64, error)
+}
+
+// Locker is implemented by caches that can hand out short-lived locks shared between instances
+type Locker interface {
+	// TryLock takes key for at most ttl without waiting; ok is false when another holder has it
+	// release frees the lock early and leaves alone a lock that expired and was taken over
+	TryLock(ctx context.Context, key string, ttl time.Duration) (release func(), ok bool, err error)
+}
+
+// Claimer is implemented by caches that can set a key only when it is missing, atomically
+type Claimer interface {
+	// SetIfAbsent stores value under key for ttl unless key exists; ok reports whether it was stored
+	SetIfAbsent(ctx context.Context, key, value string, ttl time.Duration) (ok bool, err error)
+}
+
+// BatchSetter is implemented by caches that can store many keys in one round trip
+type BatchSetter interface {
+	// SetMultiple stores every key in items with the same ttl
+	SetMultiple(ctx context.Context, items map[string]string, ttl time.Duration) error
+}
+
+// BatchDeleter is implemented by caches that can delete many keys in one round trip
+type BatchDeleter interface {
+	// DeleteMultiple removes every key in keys; missing keys are not an error
+	DeleteMultiple(ctx context.Context, keys []string) error
+}
+
+// Resolver is implemented by caches that can answer a redirect and count the visit in one round trip
+type Resolver interface {
+	// ResolveAndCount reads req.Key and, on a miss, whether req.InactiveKey exists
+	// A hit on an entry that isn't marked inactive also counts the visit under req.Counters
+	ResolveAndCount(ctx context.Context, req ResolveRequest) (Resolution, error)
+
+	// CountVisit counts a visit resolved without ResolveAndCount, e.g. from the database
+	CountVisit(ctx context.Context, counters VisitCounters) error
+
+	// VisitCounts returns the visits and the approximate number of distinct visitors counted under counters
+	VisitCounts(ctx context.Context, counters VisitCounters) (visits, visitors int64, err error)
+}
+
+// ErrFlushUnsupported is returned when the configured cache cannot be flushed
+var ErrFlushUnsupported = errors.New("cache does not support flushing")
+
+// ErrCounterUnsupported is returned when the configured cache has no atomic counters
+var ErrCounterUnsupported = errors.New("cache does not support counters")
+
+// ErrLockUnsupported is returned when the configured cache cannot hold locks
+var ErrLockUnsupported = errors.New("cache does not support locks")
+
+// ErrClaimUnsupported is returned when the configured cache cannot set keys only when missing
+var ErrClaimUnsupported = errors.New("cache does not support conditional sets")
+
+// ErrBatchDeleteUnsupported is returned when the configured cache can only delete keys one at a time
+var ErrBatchDeleteUnsupported = errors.New("cache does not support batch deletes")
+
+// ErrBatchSetUnsupported is returned when the configured cache cannot store keys in batches
+var ErrBatchSetUnsupported = errors.New("cache does not support batch sets")
+
+// ErrResolveUnsupported is returned when the configured cache can't resolve redirects in one round trip
+var ErrResolveUnsupported = errors.New("cache does not support resolving redirects")
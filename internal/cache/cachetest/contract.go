@@ -0,0 +1,135 @@
+// Package cachetest holds the contract every cache.Cache must honor and an in-memory cache for unit tests
+// An implementation's own test calls RunCache with a factory handing out empty caches.
+package cachetest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/cache"
+)
+
+// CacheFactory returns a cache with no keys in it
+// It is called once per subtest, so state never leaks from one check into the next
+type CacheFactory func(t *testing.T) cache.Cache
+
+// shortTTL is long enough to read a key back and short enough to wait for its expiry
+const shortTTL = 100 * time.Millisecond
+
+// RunCache checks the documented semantics of the Cache methods
+func RunCache(t *testing.T, newCache CacheFactory) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, c cache.Cache)
+	}{
+		{"MissIsEmptyNotError", testMissIsEmptyNotError},
+		{"SetGet", testSetGet},
+		{"Overwrite", testOverwrite},
+		{"Delete", testDelete},
+		{"Exists", testExists},
+		{"TTLExpiry", testTTLExpiry},
+		{"ZeroTTLKeepsKey", testZeroTTLKeepsKey},
+		{"KeysAreExact", testKeysAreExact},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newCache(t))
+		})
+	}
+}
+
+func testMissIsEmptyNotError(t *testing.T, c cache.Cache) {
+	value, err := c.Get(context.Background(), "missing")
+
+	require.NoError(t, err, "a miss is not an error")
+	assert.Empty(t, value)
+}
+
+func testSetGet(t *testing.T, c cache.Cache) {
+	ctx := context.Background()
+	require.NoError(t, c.Set(ctx, "url:abc123", `{"original_url":"https://example.com"}`, time.Minute))
+
+	value, err := c.Get(ctx, "url:abc123")
+	require.NoError(t, err)
+	assert.Equal(t, `{"original_url":"https://example.com"}`, value)
+}
+
+func testOverwrite(t *testing.T, c cache.Cache) {
+	ctx := context.Background()
+	require.NoError(t, c.Set(ctx, "key", "first", time.Minute))
+	require.NoError(t, c.Set(ctx, "key", "second", time.Minute))
+
+	value, err := c.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, "second", value)
+}
+
+func testDelete(t *testing.T, c cache.Cache) {
+	ctx := context.Background()
+	require.NoError(t, c.Set(ctx, "key", "value", time.Minute))
+
+	require.NoError(t, c.Delete(ctx, "key"))
+	value, err := c.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.Empty(t, value)
+
+	assert.NoError(t, c.Delete(ctx, "key"), "deleting a missing key is not an error")
+}
+
+func testExists(t *testing.T, c cache.Cache) {
+	ctx := context.Background()
+	exists, err := c.Exists(ctx, "key")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	require.NoError(t, c.Set(ctx, "key", "value", time.Minute))
+	exists, err = c.Exists(ctx, "key")
+	require.NoError(t, err)
+	assert.True(t, exists)
+}
+
+func testTTLExpiry(t *testing.T, c cache.Cache) {
+	ctx := context.Background()
+	require.NoError(t, c.Set(ctx, "key", "value", shortTTL))
+
+	value, err := c.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, "value", value)
+
+	time.Sleep(3 * shortTTL)
+
+	value, err = c.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.Empty(t, value, "expired keys read as a miss")
+	exists, err := c.Exists(ctx, "key")
+	require.NoError(t, err)
+	assert.False(t, exists, "expired keys don't exist")
+}
+
+func testZeroTTLKeepsKey(t *testing.T, c cache.Cache) {
+	ctx := context.Background()
+	require.NoError(t, c.Set(ctx, "key", "value", 0))
+
+	time.Sleep(2 * shortTTL)
+
+	value, err := c.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, "value", value, "a zero TTL never expires")
+}
+
+func testKeysAreExact(t *testing.T, c cache.Cache) {
+	ctx := context.Background()
+	require.NoError(t, c.Set(ctx, "url:abc", "short", time.Minute))
+	require.NoError(t, c.Set(ctx, "url:abc123", "long", time.Minute))
+
+	require.NoError(t, c.Delete(ctx, "url:abc"))
+
+	value, err := c.Get(ctx, "url:abc123")
+	require.NoError(t, err)
+	assert.Equal(t, "long", value, "keys are not matched by prefix")
+}
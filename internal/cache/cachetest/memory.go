@@ -0,0 +1,123 @@
+package cachetest
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryCache is a map-backed cache.Cache for unit tests that need real cache behavior rather than a mock
+// Keys expire like they do in Redis. It implements none of the optional interfaces, such as
+// cache.Counter, so tests can wrap it to add exactly the ones they need.
+type MemoryCache struct {
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+}
+
+// memoryEntry is one stored key
+type memoryEntry struct {
+	value     string
+	ttl       time.Duration // As passed to Set, 0 for no expiry
+	expiresAt time.Time     // Zero for no expiry
+}
+
+// expired reports whether the entry is past its TTL at now
+func (e memoryEntry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && !now.Before(e.expiresAt)
+}
+
+// NewMemoryCache returns an empty cache
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{entries: map[string]memoryEntry{}}
+}
+
+// Set stores value under key; a ttl of zero or less never expires
+func (c *MemoryCache) Set(_ context.Context, key, value string, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := memoryEntry{value: value, ttl: ttl}
+	if ttl > 0 {
+		entry.expiresAt = time.Now().Add(ttl)
+	}
+	c.entries[key] = entry
+	return nil
+}
+
+// Get returns the value of key, or "" without an error on a miss
+func (c *MemoryCache) Get(_ context.Context, key string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.live(key)
+	if !ok {
+		return "", nil
+	}
+	return entry.value, nil
+}
+
+// Delete removes key; missing keys are not an error
+func (c *MemoryCache) Delete(_ context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, key)
+	return nil
+}
+
+// Exists reports whether key is stored and not expired
+func (c *MemoryCache) Exists(_ context.Context, key string) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, ok := c.live(key)
+	return ok, nil
+}
+
+// Close does nothing, the cache holds no connections
+func (c *MemoryCache) Close() error {
+	return nil
+}
+
+// Keys returns the live keys starting with prefix, sorted
+func (c *MemoryCache) Keys(prefix string) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var keys []string
+	for key := range c.entries {
+		if _, ok := c.live(key); ok && strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// TTL returns the ttl key was last set with, 0 when it is missing or never expires
+func (c *MemoryCache) TTL(key string) time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.live(key)
+	if !ok {
+		return 0
+	}
+	return entry.ttl
+}
+
+// live returns the entry of key unless it is missing or expired; expired entries are dropped
+// The caller holds mu
+func (c *MemoryCache) live(key string) (memoryEntry, bool) {
+	entry, ok := c.entries[key]
+	if !ok {
+		return memoryEntry{}, false
+	}
+	if entry.expired(time.Now()) {
+		delete(c.entries, key)
+		return memoryEntry{}, false
+	}
+	return entry, true
+}
@@ -0,0 +1,57 @@
+package cache
+
+import (
+	"encoding/json"
+	"time"
+
+	"url-shortener/internal/domain"
+)
+
+// DedupEntry is what the dedup cache stores for a destination
+// It holds every field of the create response, so a repeat request needs no database read
+type DedupEntry struct {
+	ShortCode   string     `json:"code"`
+	OriginalURL string     `json:"url"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+}
+
+// NewDedupEntry builds the cached form of a shareable link
+func NewDedupEntry(url *domain.URL) DedupEntry {
+	return DedupEntry{
+		ShortCode:   url.ShortCode,
+		OriginalURL: url.OriginalURL,
+		CreatedAt:   url.CreatedAt,
+		ExpiresAt:   url.ExpiresAt,
+	}
+}
+
+// URL returns the link the entry describes, with only the cached fields set
+func (e DedupEntry) URL() *domain.URL {
+	return &domain.URL{
+		ShortCode:   e.ShortCode,
+		OriginalURL: e.OriginalURL,
+		CreatedAt:   e.CreatedAt,
+		ExpiresAt:   e.ExpiresAt,
+		IsActive:    true,
+	}
+}
+
+// Encode serializes the entry as JSON
+func (e DedupEntry) Encode() string {
+	data, err := json.Marshal(e)
+	if err != nil {
+		// Cannot happen for these types; an empty value reads back as a miss
+		return ""
+	}
+	return string(data)
+}
+
+// DecodeDedupEntry parses a cached value, reporting false for anything unusable
+func DecodeDedupEntry(value string) (DedupEntry, bool) {
+	var entry DedupEntry
+	if err := json.Unmarshal([]byte(value), &entry); err != nil || entry.ShortCode == "" {
+		return DedupEntry{}, false
+	}
+	return entry, true
+}
@@ -0,0 +1,128 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// KeyVersion is part of every key and must be bumped whenever cached values change
+// incompatibly, so a rollout starts from an empty keyspace instead of misreading old entries
+const KeyVersion = "v2"
+
+// DefaultNamespace is used when CACHE_NAMESPACE is not configured
+const DefaultNamespace = "urlshortener"
+
+// KeyPrefix returns the prefix applied to every key, e.g. "urlshortener:v2:"
+func KeyPrefix(namespace string) string {
+	if namespace == "" {
+		namespace = DefaultNamespace
+	}
+	return namespace + ":" + KeyVersion + ":"
+}
+
+// TenantKey puts key in a tenant's part of the keyspace; the default tenant keeps the plain keys
+func TenantKey(tenant, key string) string {
+	if tenant == "" {
+		return key
+	}
+	return "tenant:" + tenant + ":" + key
+}
+
+// LinkKey is the key holding the encoded LinkEntry for a short code
+func LinkKey(shortCode string) string {
+	return shortCode
+}
+
+// InactiveKey is the negative-cache key for a recently deactivated or deleted link
+func InactiveKey(shortCode string) string {
+	return "inactive:" + shortCode
+}
+
+// DedupKey is the key holding the DedupEntry of the link already created for a destination fingerprint
+// The fingerprint is hashed since destinations can be far longer than a sensible key
+func DedupKey(fingerprint string) string {
+	sum := sha256.Sum256([]byte(fingerprint))
+	return "dedup:" + hex.EncodeToString(sum[:16])
+}
+
+// DedupRefKey points from a short code back to its DedupKey, so invalidating the link can drop both
+func DedupRefKey(shortCode string) string {
+	return "dedup-ref:" + shortCode
+}
+
+// SummaryKey is the key holding a cached stats summary for one window
+func SummaryKey(days, limit int) string {
+	return fmt.Sprintf("stats:summary:%d:%d", days, limit)
+}
+
+// TimeSeriesKey is the key holding the settled buckets of a click time series
+// generation tells the link apart from earlier ones that had the same code
+func TimeSeriesKey(shortCode, generation, granularity string, from, to time.Time) string {
+	return fmt.Sprintf("stats:series:%s:%s:%s:%d:%d", shortCode, generation, granularity, from.Unix(), to.Unix())
+}
+
+// HeatmapKey is the key holding the click heat map of a range that has ended
+// generation is the link's, as for TimeSeriesKey
+func HeatmapKey(shortCode, generation, timeZone string, from, to time.Time) string {
+	return fmt.Sprintf("stats:heatmap:%s:%s:%s:%d:%d", shortCode, generation, timeZone, from.Unix(), to.Unix())
+}
+
+// QuotaKey is the daily creation counter for one scope ("ip", "key" or "tenant") and UTC day
+func QuotaKey(scope, id string, day time.Time) string {
+	return fmt.Sprintf("quota:%s:%s:%s", scope, id, day.UTC().Format("20060102"))
+}
+
+// RecentWriteKey marks a short code written moments ago, whose row replicas may not have yet
+func RecentWriteKey(shortCode string) string {
+	return "recent:" + shortCode
+}
+
+// RecentDestinationKey marks a destination linked moments ago; it is hashed like DedupKey
+func RecentDestinationKey(originalURL string) string {
+	sum := sha256.Sum256([]byte(originalURL))
+	return "recent-dest:" + hex.EncodeToString(sum[:16])
+}
+
+// ClickDedupKey marks a visitor's click on a short code within the dedup window
+func ClickDedupKey(shortCode, ip, userAgent string) string {
+	return "click-dedup:" + shortCode + ":" + VisitorID(ip, userAgent)
+}
+
+// ClickReceiptKey marks a click receipt as redeemed until it expires
+// Receipt nonces are random, so unlike click dedup keys it needs neither the code nor the tenant
+func ClickReceiptKey(nonce string) string {
+	return "click-receipt:" + nonce
+}
+
+// VisitorID identifies a visitor by IP and User-Agent
+// Both are hashed, which keeps keys short and out of plain sight
+func VisitorID(ip, userAgent string) string {
+	sum := sha256.Sum256([]byte(ip + "\n" + userAgent))
+	return hex.EncodeToString(sum[:16])
+}
+
+// VisitsKey counts the visits of a short code on one UTC day
+func VisitsKey(shortCode string, day time.Time) string {
+	return "visits:" + shortCode + ":" + day.UTC().Format("20060102")
+}
+
+// VisitorsKey is the HyperLogLog of a short code's visitors on one UTC day
+func VisitorsKey(shortCode string, day time.Time) string {
+	return "visitors:" + shortCode + ":" + day.UTC().Format("20060102")
+}
+
+// LockKey is the key of the lock a background job holds while it runs
+func LockKey(job string) string {
+	return "lock:" + job
+}
+
+// globEscaper escapes the characters SCAN MATCH treats as wildcards
+var globEscaper = strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`, `]`, `\]`)
+
+// matchPrefix builds a SCAN MATCH pattern for every key under prefix
+func matchPrefix(prefix string) string {
+	return globEscaper.Replace(prefix) + "*"
+}
@@ -0,0 +1,102 @@
+package cache
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"url-shortener/internal/domain"
+)
+
+// linkEntryVersion is written into JSON entries so future readers can tell formats apart
+const linkEntryVersion = 2
+
+// LinkEntry is what the redirect cache stores for a short code
+// Links without rules are encoded as the plain destination; links with rules as JSON
+// The entry carries the link's state so a hit answers exactly like the database would: 404 when
+// inactive, 410 once expired, however long the entry itself lingers
+type LinkEntry struct {
+	Version  int                 `json:"v"`
+	URL      string              `json:"url"`
+	Targets  []domain.Target     `json:"targets,omitempty"`
+	Variants []domain.Variant    `json:"variants,omitempty"`
+	Sticky   bool                `json:"sticky,omitempty"`
+	Bundle   []domain.BundleItem `json:"bundle,omitempty"` // Landing page members; URL is then the page itself
+	ForwardQuery bool            `json:"forward_query,omitempty"`
+	AttachClickID bool           `json:"attach_click_id,omitempty"` // Hits get a fresh ush_click receipt each
+	ReferrerPolicy domain.ReferrerPolicy `json:"referrer_policy,omitempty"`
+	App       *domain.AppLink        `json:"app,omitempty"` // Deep link for mobile visitors, fallbacks with UTM parameters applied
+	ExpiresAt *time.Time             `json:"expires_at,omitempty"`
+	Inactive  bool                   `json:"inactive,omitempty"` // Absent in older entries, which were only written for active links
+	ConfirmPrefetch bool             `json:"confirm_prefetch,omitempty"` // Link prefetchers get the confirm page instead of the redirect
+	TenantID  string                 `json:"tenant,omitempty"` // Owner of the link, whose clicks a hit records
+	FreshUntil *time.Time            `json:"fresh_until,omitempty"` // Past this a hit is served stale and refreshed, see CACHE_STALE_TTL_SECONDS
+}
+
+// NewLinkEntry builds the cached form of a link with UTM parameters already applied
+func NewLinkEntry(url *domain.URL) LinkEntry {
+	entry := LinkEntry{Version: linkEntryVersion, URL: url.Destination(), Sticky: url.StickyVariants, Bundle: url.Bundle, ForwardQuery: url.ForwardQuery, AttachClickID: url.AttachClickID, ReferrerPolicy: url.ReferrerPolicy, ExpiresAt: url.ExpiresAt, Inactive: !url.IsActive, ConfirmPrefetch: url.ConfirmBeforeRedirect, TenantID: url.TenantID}
+	if url.HasDeepLink() {
+		app := url.AppLink()
+		entry.App = &app
+	}
+	for _, target := range url.Targets {
+		target.URL = url.UTM.AppendTo(target.URL)
+		entry.Targets = append(entry.Targets, target)
+	}
+	for _, variant := range url.Variants {
+		variant.URL = url.UTM.AppendTo(variant.URL)
+		entry.Variants = append(entry.Variants, variant)
+	}
+	return entry
+}
+
+// Conditional reports whether the destination depends on the visitor
+// Deep links do, since only iOS and Android visitors are sent into the app
+func (e LinkEntry) Conditional() bool {
+	return len(e.Targets) > 0 || len(e.Variants) > 0 || e.App != nil
+}
+
+// Expired reports whether the link had expired by now
+func (e LinkEntry) Expired(now time.Time) bool {
+	return e.ExpiresAt != nil && now.After(*e.ExpiresAt)
+}
+
+// Stale reports whether the entry outlived its soft TTL by now; entries without one never go stale
+func (e LinkEntry) Stale(now time.Time) bool {
+	return e.FreshUntil != nil && now.After(*e.FreshUntil)
+}
+
+// Encode serializes the entry, keeping plain links as a bare URL string
+// Bundles are always JSON; as a bare URL they would read back as a redirect to themselves
+// So are links forwarding the query, attaching click IDs, setting a referrer policy, confirming prefetches,
+// expiring, inactive or owned by a tenant, and entries with a soft TTL, since a bare URL would lose the setting
+func (e LinkEntry) Encode() string {
+	if !e.Conditional() && len(e.Bundle) == 0 && !e.ForwardQuery && !e.AttachClickID && e.ReferrerPolicy == domain.ReferrerPolicyNone &&
+		e.ExpiresAt == nil && !e.Inactive && !e.ConfirmPrefetch && e.TenantID == domain.DefaultTenant && e.FreshUntil == nil {
+		return e.URL
+	}
+
+	e.Version = linkEntryVersion
+	data, err := json.Marshal(e)
+	if err != nil {
+		// Cannot happen for these types; fall back to a value that still redirects
+		return e.URL
+	}
+	return string(data)
+}
+
+// DecodeLinkEntry parses a cached value
+// Destinations are http(s) URLs, so anything not starting with a brace is a plain URL.
+// Unknown JSON fields are ignored, so entries written by newer releases still decode.
+func DecodeLinkEntry(value string) (LinkEntry, bool) {
+	if !strings.HasPrefix(value, "{") {
+		return LinkEntry{URL: value}, value != ""
+	}
+
+	var entry LinkEntry
+	if err := json.Unmarshal([]byte(value), &entry); err != nil || entry.URL == "" {
+		return LinkEntry{}, false
+	}
+	return entry, true
+}
@@ -2,40 +2,133 @@ package cache
 
 import (
 	"context"
+	"crypto/rand"
+	_ "embed"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"sync/atomic"
 	"time"
 	
 	"github.com/redis/go-redis/v9"
+	"golang.org/x/time/rate"
 )
 
+// flushScanCount is the SCAN COUNT hint and the largest UNLINK batch used by FlushNamespace
+const flushScanCount = 500
+
+// Redis deployments NewRedisCacheWith can connect to
+const (
+	RedisModeSingle   = "single"   // One server at Addr
+	RedisModeSentinel = "sentinel" // The master Sentinel reports for MasterName, followed across failovers
+	RedisModeCluster  = "cluster"  // A Redis Cluster reached through the seed nodes in ClusterAddrs
+)
+
+// RedisOptions says how to reach Redis; the fields a mode doesn't use are ignored
+type RedisOptions struct {
+	Mode          string   // One of the RedisMode constants; empty means RedisModeSingle
+	Addr          string   // Server address in single mode
+	SentinelAddrs []string // Sentinel addresses in sentinel mode
+	MasterName    string   // Name the Sentinels monitor the master under
+	ClusterAddrs  []string // Seed node addresses in cluster mode
+	Password      string
+	DB            int // Database number; a cluster only has database 0
+}
+
+// validate reports options that can't work before any connection is attempted
+func (o RedisOptions) validate() error {
+	switch o.Mode {
+	case "", RedisModeSingle:
+		if o.Addr == "" {
+			return errors.New("redis single mode needs an address")
+		}
+	case RedisModeSentinel:
+		if len(o.SentinelAddrs) == 0 {
+			return errors.New("redis sentinel mode needs at least one sentinel address")
+		}
+		if o.MasterName == "" {
+			return errors.New("redis sentinel mode needs the master name")
+		}
+	case RedisModeCluster:
+		if len(o.ClusterAddrs) == 0 {
+			return errors.New("redis cluster mode needs at least one node address")
+		}
+		if o.DB != 0 {
+			return fmt.Errorf("redis cluster mode only supports database 0, got %d", o.DB)
+		}
+	default:
+		return fmt.Errorf("unknown redis mode %q, want single, sentinel or cluster", o.Mode)
+	}
+	return nil
+}
+
 // redisCache implements the Cache interface using Redis
 type redisCache struct {
-	client *redis.Client
+	client  redis.UniversalClient
+	cluster bool   // Keys may live on different nodes, so no command or transaction may span several
+	prefix  string // Namespace and key version, see KeyPrefix
 }
 
-// NewRedisCache creates a new Redis cache client
+// NewRedisCache creates a new Redis cache client whose keys live under the given namespace
 // Returns error if connection fails
-func NewRedisCache(addr, password string, db int) (Cache, error) {
-	client := redis.NewClient(&redis.Options{
-		Addr:         addr,
-		Password:     password,
-		DB:           db,
-		DialTimeout:  5 * time.Second,
-		ReadTimeout:  3 * time.Second,
-		WriteTimeout: 3 * time.Second,
-		PoolSize:     10, // Connection pool size
-		MinIdleConns: 5,  // Minimum idle connections
-	})
-	
+func NewRedisCache(addr, password string, db int, namespace string) (Cache, error) {
+	return NewRedisCacheWith(RedisOptions{Mode: RedisModeSingle, Addr: addr, Password: password, DB: db}, namespace)
+}
+
+// NewRedisCacheWith creates a Redis cache for a single server, a Sentinel-managed master or a cluster
+// Returns error if the options are incomplete or connection fails
+func NewRedisCacheWith(opts RedisOptions, namespace string) (Cache, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+
+	var client redis.UniversalClient
+	switch opts.Mode {
+	case RedisModeSentinel:
+		client = redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:    opts.MasterName,
+			SentinelAddrs: opts.SentinelAddrs,
+			Password:      opts.Password,
+			DB:            opts.DB,
+			DialTimeout:   5 * time.Second,
+			ReadTimeout:   3 * time.Second,
+			WriteTimeout:  3 * time.Second,
+			PoolSize:      10,
+			MinIdleConns:  5,
+		})
+	case RedisModeCluster:
+		client = redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:        opts.ClusterAddrs,
+			Password:     opts.Password,
+			DialTimeout:  5 * time.Second,
+			ReadTimeout:  3 * time.Second,
+			WriteTimeout: 3 * time.Second,
+			PoolSize:     10, // Per node
+			MinIdleConns: 5,
+		})
+	default:
+		client = redis.NewClient(&redis.Options{
+			Addr:         opts.Addr,
+			Password:     opts.Password,
+			DB:           opts.DB,
+			DialTimeout:  5 * time.Second,
+			ReadTimeout:  3 * time.Second,
+			WriteTimeout: 3 * time.Second,
+			PoolSize:     10, // Connection pool size
+			MinIdleConns: 5,  // Minimum idle connections
+		})
+	}
+
 	// Test connection
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
-	
+
 	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
 		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
 	}
-	
-	return &redisCache{client: client}, nil
+
+	return &redisCache{client: client, cluster: opts.Mode == RedisModeCluster, prefix: KeyPrefix(namespace)}, nil
 }
 
 // Set stores a key-value pair in Redis with TTL
@@ -98,12 +191,237 @@ func (c *redisCache) Close() error {
 	return c.client.Close()
 }
 
-// prefixKey adds a namespace prefix to avoid key collisions
+// prefixKey adds the versioned namespace prefix to avoid key collisions
 func (c *redisCache) prefixKey(key string) string {
-	return fmt.Sprintf("urlshortener:%s", key)
+	return c.prefix + key
+}
+
+// IncrBy adds delta to a counter and sets its absolute expiry in one MULTI/EXEC transaction
+func (c *redisCache) IncrBy(ctx context.Context, key string, delta int64, expireAt time.Time) (int64, error) {
+	prefixedKey := c.prefixKey(key)
+	
+	var incr *redis.IntCmd
+	_, err := c.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		incr = pipe.IncrBy(ctx, prefixedKey, delta)
+		pipe.ExpireAt(ctx, prefixedKey, expireAt)
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("redis incrby failed: %w", err)
+	}
+	
+	return incr.Val(), nil
+}
+
+// releaseLockScript deletes a lock only while it still holds the caller's token
+var releaseLockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+// TryLock takes a lock with SET NX and a random token, so release never frees another holder's lock
+func (c *redisCache) TryLock(ctx context.Context, key string, ttl time.Duration) (func(), bool, error) {
+	prefixedKey := c.prefixKey(key)
+	
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, false, fmt.Errorf("lock token: %w", err)
+	}
+	token := hex.EncodeToString(raw)
+	
+	acquired, err := c.client.SetNX(ctx, prefixedKey, token, ttl).Result()
+	if err != nil {
+		return nil, false, fmt.Errorf("redis setnx failed: %w", err)
+	}
+	if !acquired {
+		return nil, false, nil
+	}
+	
+	release := func() {
+		// The lock expires on its own if this fails, so the error is not worth surfacing
+		releaseCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 3*time.Second)
+		defer cancel()
+		releaseLockScript.Run(releaseCtx, c.client, []string{prefixedKey}, token)
+	}
+	return release, true, nil
+}
+
+// SetIfAbsent stores value with SET NX, so of concurrent callers exactly one gets ok
+func (c *redisCache) SetIfAbsent(ctx context.Context, key, value string, ttl time.Duration) (bool, error) {
+	stored, err := c.client.SetNX(ctx, c.prefixKey(key), value, ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("redis setnx failed: %w", err)
+	}
+	return stored, nil
+}
+
+// resolveAndCountSource is the script behind ResolveAndCount, kept in its own file so it can be read and linted as Lua
+//
+//go:embed scripts/resolve_and_count.lua
+var resolveAndCountSource string
+
+// resolveAndCountScript runs by EVALSHA, so after the first call only the hash is sent
+var resolveAndCountScript = redis.NewScript(resolveAndCountSource)
+
+// ResolveAndCount reads the link entry, the negative cache on a miss and counts a hit in one script call
+// A redirect served from the cache thereby costs one round trip instead of a GET followed by the counter updates
+// A cluster can't run it, since the four keys hash to different slots; callers then fall back to separate calls
+func (c *redisCache) ResolveAndCount(ctx context.Context, req ResolveRequest) (Resolution, error) {
+	if c.cluster {
+		return Resolution{}, ErrResolveUnsupported
+	}
+	keys := []string{
+		c.prefixKey(req.Key),
+		c.prefixKey(req.InactiveKey),
+		c.prefixKey(req.Counters.VisitsKey),
+		c.prefixKey(req.Counters.VisitorsKey),
+	}
+	reply, err := resolveAndCountScript.Run(ctx, c.client, keys, req.Counters.Visitor, req.Counters.ExpireAt.Unix()).Slice()
+	if err != nil {
+		return Resolution{}, fmt.Errorf("redis resolve failed: %w", err)
+	}
+	if len(reply) != 3 {
+		return Resolution{}, fmt.Errorf("redis resolve failed: unexpected reply of %d values", len(reply))
+	}
+	
+	var res Resolution
+	res.Value, _ = reply[0].(string) // nil on a miss
+	inactive, _ := reply[1].(int64)
+	res.Inactive = inactive == 1
+	res.Visits, _ = reply[2].(int64)
+	return res, nil
+}
+
+// CountVisit updates both counters in one MULTI/EXEC transaction
+// In a cluster the two keys may sit in different slots, so they are updated in a plain pipeline instead
+func (c *redisCache) CountVisit(ctx context.Context, counters VisitCounters) error {
+	visitsKey, visitorsKey := c.prefixKey(counters.VisitsKey), c.prefixKey(counters.VisitorsKey)
+	
+	pipelined := c.client.TxPipelined
+	if c.cluster {
+		pipelined = c.client.Pipelined
+	}
+	_, err := pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Incr(ctx, visitsKey)
+		pipe.ExpireAt(ctx, visitsKey, counters.ExpireAt)
+		pipe.PFAdd(ctx, visitorsKey, counters.Visitor)
+		pipe.ExpireAt(ctx, visitorsKey, counters.ExpireAt)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("redis count visit failed: %w", err)
+	}
+	return nil
+}
+
+// VisitCounts reads the visit counter and the visitor HyperLogLog in one pipeline
+// Counters that don't exist yet read as zero
+func (c *redisCache) VisitCounts(ctx context.Context, counters VisitCounters) (int64, int64, error) {
+	pipe := c.client.Pipeline()
+	visits := pipe.Get(ctx, c.prefixKey(counters.VisitsKey))
+	visitors := pipe.PFCount(ctx, c.prefixKey(counters.VisitorsKey))
+	
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return 0, 0, fmt.Errorf("redis visit counts failed: %w", err)
+	}
+	
+	n, err := visits.Int64()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return 0, 0, fmt.Errorf("redis visit counts failed: %w", err)
+	}
+	return n, visitors.Val(), nil
+}
+
+// FlushNamespace deletes every key under the current namespace and version
+// Keys are found with SCAN and removed with UNLINK at no more than keysPerSecond, so an
+// emergency flush of a large keyspace never blocks Redis for other clients
+// In a cluster every master is scanned, concurrently, sharing the one rate limit
+func (c *redisCache) FlushNamespace(ctx context.Context, keysPerSecond int) (int64, error) {
+	if keysPerSecond <= 0 {
+		keysPerSecond = flushScanCount
+	}
+	burst := flushScanCount
+	if keysPerSecond < burst {
+		burst = keysPerSecond
+	}
+	limiter := rate.NewLimiter(rate.Limit(keysPerSecond), burst)
+	
+	var deleted atomic.Int64
+	flush := func(ctx context.Context, node redis.Cmdable) error {
+		return c.flushNode(ctx, node, limiter, burst, &deleted)
+	}
+	
+	var err error
+	if cluster, ok := c.client.(*redis.ClusterClient); ok {
+		err = cluster.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error {
+			return flush(ctx, node)
+		})
+	} else {
+		err = flush(ctx, c.client)
+	}
+	return deleted.Load(), err
+}
+
+// flushNode scans one server for the namespace's keys and unlinks them in chunks the limiter grants
+func (c *redisCache) flushNode(ctx context.Context, node redis.Cmdable, limiter *rate.Limiter, burst int, deleted *atomic.Int64) error {
+	var cursor uint64
+	for {
+		keys, next, err := node.Scan(ctx, cursor, matchPrefix(c.prefix), flushScanCount).Result()
+		if err != nil {
+			return fmt.Errorf("redis scan failed: %w", err)
+		}
+		
+		// SCAN may return more keys than the hint, so unlink in chunks the limiter can grant
+		for len(keys) > 0 {
+			n := len(keys)
+			if n > burst {
+				n = burst
+			}
+			if err := limiter.WaitN(ctx, n); err != nil {
+				return err
+			}
+			
+			removed, err := c.unlink(ctx, keys[:n])
+			if err != nil {
+				return fmt.Errorf("redis unlink failed: %w", err)
+			}
+			deleted.Add(removed)
+			keys = keys[n:]
+		}
+		
+		cursor = next
+		if cursor == 0 {
+			return nil
+		}
+	}
+}
+
+// unlink removes keys with one UNLINK, or in a cluster one UNLINK per key in a pipeline, since a
+// multi-key command must not span slots
+func (c *redisCache) unlink(ctx context.Context, keys []string) (int64, error) {
+	if !c.cluster {
+		return c.client.Unlink(ctx, keys...).Result()
+	}
+	
+	cmds, err := c.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, key := range keys {
+			pipe.Unlink(ctx, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	var removed int64
+	for _, cmd := range cmds {
+		removed += cmd.(*redis.IntCmd).Val()
+	}
+	return removed, nil
 }
 
 // Batch operations for performance optimization
+// They use plain pipelines of single-key commands, which a cluster client splits by node, never MULTI or multi-key commands
 
 // SetMultiple stores multiple key-value pairs in a single pipeline
 // More efficient than multiple Set calls
@@ -123,6 +441,26 @@ func (c *redisCache) SetMultiple(ctx context.Context, items map[string]string, t
 	return nil
 }
 
+// DeleteMultiple removes multiple keys in a single pipeline
+func (c *redisCache) DeleteMultiple(ctx context.Context, keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	
+	pipe := c.client.Pipeline()
+	
+	for _, key := range keys {
+		pipe.Del(ctx, c.prefixKey(key))
+	}
+	
+	_, err := pipe.Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("redis pipeline failed: %w", err)
+	}
+	
+	return nil
+}
+
 // GetMultiple retrieves multiple values in a single pipeline
 func (c *redisCache) GetMultiple(ctx context.Context, keys []string) (map[string]string, error) {
 	if len(keys) == 0 {
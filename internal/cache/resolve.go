@@ -0,0 +1,30 @@
+package cache
+
+import "time"
+
+// VisitCounters names the counters one visit of a link is counted in
+type VisitCounters struct {
+	VisitsKey   string    // Incremented once per visit
+	VisitorsKey string    // HyperLogLog of the visitor ids, for distinct visitors
+	Visitor     string    // Visitor id, see VisitorID
+	ExpireAt    time.Time // When both counters are dropped
+}
+
+// ResolveRequest is what Resolver.ResolveAndCount reads and counts for one redirect
+type ResolveRequest struct {
+	Key         string // LinkKey of the code
+	InactiveKey string // InactiveKey of the code, read only on a miss
+	Counters    VisitCounters
+}
+
+// Resolution is the answer of Resolver.ResolveAndCount
+type Resolution struct {
+	Value    string // Encoded LinkEntry, empty on a miss
+	Inactive bool   // On a miss, whether the negative cache holds the code
+	Visits   int64  // Visits counted so far including this one, 0 when the visit wasn't counted
+}
+
+// Counted reports whether the visit was counted along with the read
+func (r Resolution) Counted() bool {
+	return r.Visits > 0
+}
@@ -0,0 +1,22 @@
+-- Answers a redirect from the cache and counts the visit in one round trip
+-- KEYS[1] link entry, KEYS[2] negative-cache entry, KEYS[3] visit counter, KEYS[4] visitor HyperLogLog
+-- ARGV[1] visitor id, ARGV[2] unix time both counters expire at
+-- Returns {entry or nil, 1 when the negative cache holds the code, visits counted so far or 0}
+local entry = redis.call("GET", KEYS[1])
+if not entry then
+	return {false, redis.call("EXISTS", KEYS[2]), 0}
+end
+
+-- Deactivated links are answered with a 404 and not counted
+if string.sub(entry, 1, 1) == "{" then
+	local ok, decoded = pcall(cjson.decode, entry)
+	if ok and type(decoded) == "table" and decoded.inactive == true then
+		return {entry, 0, 0}
+	end
+end
+
+local visits = redis.call("INCR", KEYS[3])
+redis.call("EXPIREAT", KEYS[3], ARGV[2])
+redis.call("PFADD", KEYS[4], ARGV[1])
+redis.call("EXPIREAT", KEYS[4], ARGV[2])
+return {entry, 0, visits}
@@ -0,0 +1,36 @@
+// Package clientip normalizes client addresses so one client is always counted under one key
+package clientip
+
+import (
+	"net/netip"
+	"strings"
+)
+
+// Canonical returns ip in its canonical text form
+// IPv4-mapped IPv6 addresses (::ffff:192.0.2.1) become plain IPv4, zones are dropped and IPv6
+// is written in its shortest lowercase form. Anything that doesn't parse is returned trimmed but as is.
+func Canonical(ip string) string {
+	ip = strings.TrimSpace(ip)
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ip
+	}
+	return addr.Unmap().WithZone("").String()
+}
+
+// Network returns the canonical ip with IPv6 addresses masked to their first ipv6Prefix bits
+// A single IPv6 client usually holds a whole /64, so keying it by the full address lets it rotate
+// through fresh ones. The result is the network address itself, e.g. 2001:db8:1:2::, so it still
+// parses as an IP. IPv4 addresses and prefixes outside 1-127 keep the full address.
+func Network(ip string, ipv6Prefix int) string {
+	ip = Canonical(ip)
+	addr, err := netip.ParseAddr(ip)
+	if err != nil || !addr.Is6() || ipv6Prefix <= 0 || ipv6Prefix >= 128 {
+		return ip
+	}
+	prefix, err := addr.Prefix(ipv6Prefix)
+	if err != nil {
+		return ip
+	}
+	return prefix.Addr().String()
+}
@@ -2,78 +2,660 @@ package config
 
 import (
 	"fmt"
+	"net"
+	"net/url"
 	"os"
 	"strconv"
+	"strings"
 	"time"
+
+	"url-shortener/internal/domain"
+	"url-shortener/pkg/validator"
+)
+
+// RateLimitUnlimited marks a rate limit tier that is never throttled
+const RateLimitUnlimited = -1
+
+// Short code strategies selectable with SHORTCODE_STRATEGY
+const (
+	ShortCodeStrategyRandom = "random" // Random base62 codes, checked for collisions before insert
+	ShortCodeStrategyHash   = "hash"   // Codes derived from the destination, identical across replicas
+)
+
+// How short URLs in responses get their scheme and host, selectable with BASE_URL_MODE
+const (
+	BaseURLModeStatic  = "static"  // Always BASE_URL
+	BaseURLModeRequest = "request" // The request's scheme and host, when the host is in ALLOWED_DOMAINS
+)
+
+// Redis deployments selectable with REDIS_MODE
+const (
+	RedisModeSingle   = "single"   // One server at REDIS_ADDR
+	RedisModeSentinel = "sentinel" // The master REDIS_SENTINEL_ADDRS report for REDIS_MASTER_NAME, followed across failovers
+	RedisModeCluster  = "cluster"  // A Redis Cluster reached through REDIS_CLUSTER_ADDRS
+)
+
+// Precedence of query parameters forwarded to the destination, selectable with FORWARD_QUERY_PRECEDENCE
+const (
+	ForwardQueryDestinationWins = "destination" // The destination's own parameters are kept on conflict
+	ForwardQueryIncomingWins    = "incoming"    // Parameters on the short link replace the destination's
+)
+
+// Log formats selectable with LOG_FORMAT
+const (
+	LogFormatJSON    = "json"
+	LogFormatConsole = "console"
+)
+
+// Log levels selectable with LOG_LEVEL
+const (
+	LogLevelDebug = "debug"
+	LogLevelInfo  = "info"
+	LogLevelWarn  = "warn"
+	LogLevelError = "error"
+)
+
+// DefaultShortenerDomains are the hosts of other URL shorteners refused as destinations by default
+const DefaultShortenerDomains = "bit.ly,bitly.com,tinyurl.com,t.co,goo.gl,ow.ly,is.gd,buff.ly,rebrand.ly,cutt.ly,tiny.cc,shorturl.at,rb.gy"
+
+// DefaultAllowedURLSchemes are the schemes destinations may use unless ALLOWED_URL_SCHEMES says otherwise
+const DefaultAllowedURLSchemes = "http,https"
+
+// forbiddenURLSchemes run code or embed content in the browser and can't be allowed
+var forbiddenURLSchemes = map[string]bool{"javascript": true, "data": true, "vbscript": true, "file": true}
+
+// DefaultBotUserAgents are User-Agent substrings of crawlers whose hits are not counted as clicks
+const DefaultBotUserAgents = "bot,crawler,spider,slurp,facebookexternalhit,embedly,quora link preview,bitlybot,whatsapp,preview"
+
+// DefaultPrefetchUserAgents are User-Agent substrings of chat apps that fetch links to render a preview
+const DefaultPrefetchUserAgents = "slackbot,whatsapp,facebookexternalhit"
+
+// How clicks from bots are counted, selectable with BOT_CLICKS
+const (
+	BotClicksSeparate = "separate" // Counted in bot_clicks, apart from click_count
+	BotClicksIgnore   = "ignore"   // Not counted at all
+)
+
+// Where destination snapshots are stored, selectable with SNAPSHOT_STORE
+const (
+	SnapshotStoreNone       = ""           // Archival disabled, no snapshots are fetched
+	SnapshotStoreFilesystem = "filesystem" // Files under SNAPSHOT_DIR
+	SnapshotStoreS3         = "s3"         // Objects in an S3-compatible bucket
+)
+
+// Where critical-table backups are written, selectable with BACKUP_STORE
+const (
+	BackupStoreNone       = ""           // No scheduled backups; `server backup-critical` refuses to run
+	BackupStoreFilesystem = "filesystem" // Files under BACKUP_DIR
+	BackupStoreS3         = "s3"         // Objects in an S3-compatible bucket
+)
+
+// MinBackupKeyLength is the shortest BACKUP_KEY accepted; the key is used as is, without stretching
+const MinBackupKeyLength = 32
+
+// Where the links are kept, selectable with DB_DRIVER; every other table stays in Postgres
+const (
+	DBDriverPostgres = "postgres" // The urls table next to the others
+	DBDriverMongo    = "mongo"    // The urls collection of MONGO_DATABASE at MONGO_URI
+)
+
+// Message brokers the event outbox can relay to, selectable with EVENTS_DRIVER
+const (
+	EventsDriverNone  = ""      // Outbox disabled, no events are written
+	EventsDriverKafka = "kafka" // Kafka through a Confluent-compatible REST proxy
+	EventsDriverNATS  = "nats"  // NATS JetStream publish, acked per event
 )
 
 // Config holds all application configurations
 // All sensitive values are loaded from .env
 type Config struct {
 	// Server Configuration
-	Environment string
-	ServerPort  string
+	Environment string `yaml:"environment"`
+	ServerPort  string `yaml:"server_port"`
+	EnableGRPC  bool `yaml:"enable_grpc"`   // Start the gRPC API alongside HTTP
+	EnableMetrics bool `yaml:"enable_metrics"` // Serve Prometheus metrics at /metrics
+	EnableAPIDocs bool `yaml:"enable_api_docs"` // Serve Swagger UI for the OpenAPI spec at /api/v1/docs
+	EnableWebUI bool `yaml:"enable_web_ui"` // Serve a form for shortening links at /
+	ShortenLinkSecret  string `yaml:"shorten_link_secret"`            // HMAC key of signed GET /api/v1/shorten links (empty = API keys only)
+	ShortenLinkMaxSkew time.Duration `yaml:"shorten_link_max_skew"` // How far a signed link's timestamp may be from the server's clock
+	GRPCPort    string `yaml:"grpc_port"` // Port for the gRPC API
+	LogLevel    string `yaml:"log_level"` // debug, info, warn or error; reloadable
+	LogFormat   string `yaml:"log_format"` // json, or console for reading logs in a terminal
+	LogFile       string `yaml:"log_file"`        // Rotated file logs are also written to (empty = stdout only)
+	LogMaxSizeMB  int    `yaml:"log_max_size_mb"`  // Size the log file is rotated at
+	LogMaxBackups int    `yaml:"log_max_backups"`  // Rotated log files kept (0 = all)
+	LogMaxAgeDays int    `yaml:"log_max_age_days"` // Rotated log files older than this are removed (0 = never)
+	LogCompress   bool   `yaml:"log_compress"`     // Gzip rotated log files
+	QuietPaths  []string `yaml:"quiet_paths"` // Paths exempt from rate limits and only logged at debug level, e.g. health probes
+	CORSAllowedOrigins []string `yaml:"cors_allowed_origins"` // Origins browsers may call the API from outside development; reloadable
 
 	// DB configuration
-	DBHost     string
-	DBPort     string
-	DBUser     string
-	DBPassword string
-	DBName     string
-	DBSSLMode  string
+	DBDriver   string `yaml:"db_driver"` // postgres, or mongo to keep the links in MongoDB
+	MongoURI   string `yaml:"mongo_uri"` // Connection string of the MongoDB deployment with DB_DRIVER=mongo
+	MongoDatabase string `yaml:"mongo_database"` // Database holding the urls and counters collections
+	DBHost     string `yaml:"db_host"`
+	DBPort     string `yaml:"db_port"`
+	DBUser     string `yaml:"db_user"`
+	DBPassword string `yaml:"db_password"`
+	DBName     string `yaml:"db_name"`
+	DBSSLMode  string `yaml:"db_ssl_mode"`
+	DBMaxIdleConns     int `yaml:"db_max_idle_conns"`           // Connections kept open while idle
+	DBMaxOpenConns     int `yaml:"db_max_open_conns"`           // Upper bound on open connections (0 = unlimited)
+	DBConnMaxLifetime  time.Duration `yaml:"db_conn_max_lifetime"` // Connections older than this are closed and replaced (0 = forever)
+	DBConnMaxIdleTime  time.Duration `yaml:"db_conn_max_idle_time"` // Idle connections older than this are closed (0 = forever)
+	DBSlowQueryThreshold time.Duration `yaml:"db_slow_query_threshold"` // Queries taking longer are logged at warn (0 = never)
+	DBStatsInterval    time.Duration `yaml:"db_stats_interval"` // How often pool stats are exported to /metrics (0 = never)
+	DBReplicaDSNs      []string `yaml:"db_replica_dsns"`   // Read replicas for link lookups and stats; empty reads from the primary
+	DBReplicaFallbackWindow time.Duration `yaml:"db_replica_fallback_window"` // A code written this recently is re-read from the primary when a replica can't find it
+	DBRetries          int `yaml:"db_retries"`            // Retries of a link query after a transient failure such as a failover (0 = none)
+	DBRetryBackoff     time.Duration `yaml:"db_retry_backoff"` // Wait before the first retry, doubled for the next and jittered
+	AutoMigrate        bool `yaml:"auto_migrate"`          // Create and update the tables on startup; off requires the schema to exist
+	RedirectorDBUser     string `yaml:"redirector_db_user"`     // Role cmd/redirector connects as; empty uses DBUser
+	RedirectorDBPassword string `yaml:"redirector_db_password"` // Password of RedirectorDBUser
+	RedirectorReadOnly   bool `yaml:"redirector_read_only"`     // cmd/redirector opens read-only sessions and counts no clicks
 
 	// Redis configuration
-	RedisAddr     string
-	RedisPassword string
-	RedisDB       int
-	CacheTTL      time.Duration
+	RedisMode     string `yaml:"redis_mode"` // single, sentinel or cluster
+	RedisAddr     string `yaml:"redis_addr"`
+	RedisSentinelAddrs []string `yaml:"redis_sentinel_addrs"` // Sentinels asked for the master in sentinel mode
+	RedisMasterName    string `yaml:"redis_master_name"`      // Name the Sentinels monitor the master under
+	RedisClusterAddrs  []string `yaml:"redis_cluster_addrs"`  // Seed nodes in cluster mode
+	RedisPassword string `yaml:"redis_password"`
+	RedisDB       int `yaml:"redis_db"`
+	CacheTTL      time.Duration `yaml:"cache_ttl"`
+	CacheStaleTTL time.Duration `yaml:"cache_stale_ttl"` // How long past CacheTTL a link entry is still served while it is refreshed (0 = off)
+	CacheWarmLinks   int `yaml:"cache_warm_links"`              // Most clicked links loaded into the cache at startup (0 = off)
+	CacheWarmTimeout time.Duration `yaml:"cache_warm_timeout"` // Time budget of the startup warm-up, which runs alongside serving
+	NegativeCacheTTL time.Duration `yaml:"negative_cache_ttl"` // How long deactivated links are remembered as missing
+	CacheBreakerThreshold int `yaml:"cache_breaker_threshold"`           // Consecutive Redis failures before the cache is bypassed
+	CacheBreakerCooldown  time.Duration `yaml:"cache_breaker_cooldown"` // How long the cache is bypassed before probing again
+	SummaryCacheTTL       time.Duration `yaml:"summary_cache_ttl"` // How long the global stats summary is cached (0 = no caching)
+	DedupCacheTTL         time.Duration `yaml:"dedup_cache_ttl"` // How long the destination → short code lookup for dedup is cached (0 = no caching)
+	CacheNamespace        string `yaml:"cache_namespace"`        // Key prefix shared by all instances of one deployment
+	CacheFlushRate        int `yaml:"cache_flush_rate"`           // Keys deleted per second by the admin cache flush
+	ClickQueueSize        int `yaml:"click_queue_size"`           // Clicks from cache hits buffered for the background writer
+	CacheWriteQueueSize   int `yaml:"cache_write_queue_size"`     // Cache entries buffered for the background writer; the oldest is dropped when full
 
 	// Application settings
-	BaseURL              string // Base URL for generating short links
-	ShortCodeLength      int    // Length of generated short codes
-	RateLimitPerMinute   int    // Rate limit per IP address
-	URLExpirationDays    int    // Days before URLs expire (0 = never)
-	EnableAuthentication bool   // Enable API key authentication
-	APIKey               string // API key for protected endpoints	
+	BaseURL              string `yaml:"base_url"` // Base URL for generating short links
+	BaseURLMode          string `yaml:"base_url_mode"` // static uses BaseURL; request builds short links from the request's host
+	AllowedDomains       []string `yaml:"allowed_domains"` // Hosts short links may be built from in request mode; others get BaseURL
+	TrustedProxies       []string `yaml:"trusted_proxies"` // IPs or CIDRs whose X-Forwarded-For, X-Forwarded-Proto and X-Forwarded-Host are believed
+	ShortCodeLength      int `yaml:"short_code_length"`    // Length of generated short codes
+	MaxCustomAliasLength int `yaml:"max_custom_alias_length"` // Longest custom alias accepted, slashes included; at most the short_code column size
+	ShortCodeStrategy    string `yaml:"short_code_strategy"` // How generated codes are chosen: random or hash
+	ShortCodeExcludeAmbiguous bool `yaml:"short_code_exclude_ambiguous"` // Leave 0, O, 1, l and I out of random codes
+	CaseInsensitiveCodes bool `yaml:"case_insensitive_codes"` // Redirect a code that doesn't exist to the one link matching it ignoring case
+	AllowedURLSchemes    []string `yaml:"allowed_url_schemes"` // Schemes destinations may use; ftp, mailto and tel are opt-in
+	LegacyNormalization  bool `yaml:"legacy_normalization"`   // Normalize URLs as before default-port, escape and IDN handling, to keep dedup stable
+	StripTrackingParams  []string `yaml:"strip_tracking_params"` // Query parameters removed from destinations, "utm_*" matches a prefix
+	ShortenerDomains     []string `yaml:"shortener_domains"` // Hosts of other shorteners; their links are refused or resolved, subdomains included
+	ResolveShortenerChains bool `yaml:"resolve_shortener_chains"`   // Follow links on ShortenerDomains to their final destination instead of refusing them
+	ChainResolveTimeout  time.Duration `yaml:"chain_resolve_timeout"` // Upper bound for resolving one chain, all hops included
+	Hooks                []string `yaml:"hooks"` // Names of hooks compiled into the build to run around creates, redirects and deletes, in order
+	HookAllowedHosts     []string `yaml:"hook_allowed_hosts"` // Destination hosts the host_allowlist hook accepts, subdomains included
+	ForwardQueryDefault  bool `yaml:"forward_query_default"`   // Forward query parameters for links that don't set forward_query
+	ForwardQueryPrecedence string `yaml:"forward_query_precedence"` // Which side wins when forwarded and destination parameters share a key
+	LinkStateHeaders     bool `yaml:"link_state_headers"`     // Send X-URL-Expires-At and X-URL-Active on redirects and link info
+	RateLimitPerMinute   int `yaml:"rate_limit_per_minute"`    // Rate limit per IP address or API key
+	RateLimitRedirects   int `yaml:"rate_limit_redirects"`    // Redirects per minute per IP or API key
+	RateLimitAPIReads    int `yaml:"rate_limit_api_reads"`    // API reads (GET) per minute; 0 uses RateLimitPerMinute
+	RateLimitAPIWrites   int `yaml:"rate_limit_api_writes"`   // API writes (POST, PATCH, ...) per minute; 0 uses RateLimitPerMinute
+	RateLimitIPv6Prefix  int `yaml:"rate_limit_ipv6_prefix"`  // IPv6 clients share limits, quotas and click analytics per prefix of this length (128 = per address)
+	MaxRequestBodyBytes  int64 `yaml:"max_request_body_bytes"`  // Largest request body accepted by the API, larger ones get 413
+	EnableCompression    bool `yaml:"enable_compression"`    // Gzip API responses for clients sending Accept-Encoding: gzip
+	CompressionMinBytes  int `yaml:"compression_min_bytes"`   // Smaller API responses are sent uncompressed
+	RequestTimeout       time.Duration `yaml:"request_timeout"` // Deadline for API handlers before they are answered with 504 (0 = none)
+	RedirectTimeout      time.Duration `yaml:"redirect_timeout"` // Deadline for redirects before they are answered with 504 (0 = none)
+	RateLimitTiers       map[string]int `yaml:"rate_limit_tiers"` // Requests per minute by API key fingerprint, RateLimitUnlimited for no limit
+	URLExpirationDays    int `yaml:"url_expiration_days"`    // Days before URLs expire (0 = never)
+	MaxExpiryDays        int `yaml:"max_expiry_days"`    // Furthest ahead a requested expiry may be (0 = no limit)
+	EnableAuthentication bool `yaml:"enable_authentication"`   // Enable API key authentication
+	APIKey               string `yaml:"api_key"` // API key for protected endpoints	
+	AdminAPIKey          string `yaml:"admin_api_key"` // API key for admin endpoints (admin API disabled if empty)
+	APIKeyCacheTTL       time.Duration `yaml:"api_key_cache_ttl"` // How long issued keys are cached; bounds how late a revocation takes effect
+	RequireManagementToken bool `yaml:"require_management_token"` // Links without a management token can only be edited or deleted by an admin
+	MaxURLsPerDayPerIP   int `yaml:"max_urls_per_day_per_ip"`    // Daily creation quota per client IP (0 = unlimited)
+	MaxURLsPerDayPerKey  int `yaml:"max_urls_per_day_per_key"`    // Daily creation quota per API key (0 = unlimited)
+	MaxURLsPerDayPerTenant int `yaml:"max_urls_per_day_per_tenant"` // Daily creation quota shared by all keys of a tenant (0 = unlimited)
+	TenantScopedCodes    bool `yaml:"tenant_scoped_codes"`   // Codes are unique per tenant and domain instead of globally; redirects find the tenant by host
+	TenantDomains        map[string]string `yaml:"tenant_domains"` // Host serving each tenant's links, by tenant ID
+	EnableMetadataFetch  bool `yaml:"enable_metadata_fetch"`   // Fetch title and favicon of new links' destinations (outbound requests)
+	MetadataFetchTimeout time.Duration `yaml:"metadata_fetch_timeout"` // Upper bound for one metadata fetch
+	CleanupInterval      time.Duration `yaml:"cleanup_interval"` // How often expired links are deactivated (0 = never)
+	DeletedRetention     time.Duration `yaml:"deleted_retention"` // How long deleted links are kept before the cleanup job erases them (0 = forever)
+	StatsRollupInterval  time.Duration `yaml:"stats_rollup_interval"` // How often click events are rolled up into daily stats (0 = never)
+	LegacyURLInfo        bool `yaml:"legacy_url_info"`   // Deprecated: serve the raw model from GET /api/v1/urls/:shortCode for one more release
+	ProblemDetails       bool `yaml:"problem_details"`   // Answer every error as application/problem+json, not only when the client asks
+
+	// Interstitial and browser page settings
+	InterstitialAll            bool `yaml:"interstitial_all"`          // Show the interstitial for every link
+	InterstitialNewLinkMinutes int `yaml:"interstitial_new_link_minutes"`           // Show the interstitial for links younger than this (0 = off)
+	InterstitialSecret         string `yaml:"interstitial_secret"`        // HMAC key for continue tokens (random per process if empty)
+	InterstitialTokenTTL       time.Duration `yaml:"interstitial_token_ttl"` // How long a continue token stays valid
+	ClickIDSecret              string `yaml:"click_id_secret"`        // HMAC key for ush_click receipts (random per process if empty)
+	ClickIDTTL                 time.Duration `yaml:"click_id_ttl"` // How long a click receipt can be redeemed as a conversion
+	ClickIDMaxSkew             time.Duration `yaml:"click_id_max_skew"` // Grace past a receipt's expiry for clocks of other instances running behind
+	TemplateDir                string `yaml:"template_dir"`        // Directory with *.html files overriding the built-in pages
+	RobotsTxtFile              string `yaml:"robots_txt_file"`        // File served as /robots.txt instead of the built-in one
+	FaviconFile                string `yaml:"favicon_file"`        // Icon served as /favicon.ico (204 No Content if empty)
+	BotUserAgents              []string `yaml:"bot_user_agents"`      // Lowercase User-Agent substrings that mark a visitor as a bot
+	BotClicks                  string `yaml:"bot_clicks"`        // How bot clicks are counted: separate or ignore
+	PrefetchUserAgents         []string `yaml:"prefetch_user_agents"` // User-Agent substrings of link prefetchers shown the confirm page of confirm_before_redirect links
+	ClickDedupWindow           time.Duration `yaml:"click_dedup_window"` // Repeat clicks of one IP and User-Agent on a code within this are not counted (0 = off)
+
+	// GeoIP settings for country rules
+	GeoIPCIDRFile      string `yaml:"geoip_cidr_file"` // "network,country" table used to resolve visitor IPs
+	GeoIPCountryHeader string `yaml:"geoip_country_header"` // Trusted header carrying the visitor country, e.g. CF-IPCountry
+
+	// Lifecycle event stream settings
+	EventsDriver         string `yaml:"events_driver"`        // Broker the outbox relays to: kafka, nats or empty to disable
+	EventsTopic          string `yaml:"events_topic"`        // Kafka topic or NATS subject events are published to
+	EventsKafkaRESTURL   string `yaml:"events_kafka_rest_url"`        // Base URL of the Kafka REST proxy
+	EventsNATSURL        string `yaml:"events_nats_url"`        // nats://[user:pass@]host:port
+	EventsBatchSize      int `yaml:"events_batch_size"`           // Events published per relay round trip
+	EventsPollInterval   time.Duration `yaml:"events_poll_interval"` // How often the relay looks for new events
+	EventsClickSampling  int `yaml:"events_click_sampling"`           // One click event per N clicks, weighted N (1 = every click, 0 = none)
+	EventsRetention      time.Duration `yaml:"events_retention"` // How long published events stay in the outbox table
+
+	// Expiry notification settings
+	ExpiryWebhookURL     string `yaml:"expiry_webhook_url"`        // Receives a POST for every link about to expire (notifier disabled if empty)
+	ExpiryNotifyWindow   time.Duration `yaml:"expiry_notify_window"` // How long before expiry owners are warned
+	ExpiryNotifyInterval time.Duration `yaml:"expiry_notify_interval"` // How often expiring links are looked for; failed notices are retried then
+
+	// Link check settings
+	EnableLinkCheck       bool `yaml:"enable_link_check"`               // Periodically request destinations and flag broken links (outbound requests)
+	LinkCheckInterval     time.Duration `yaml:"link_check_interval"`     // How often due links are looked for
+	LinkCheckRecheckAfter time.Duration `yaml:"link_check_recheck_after"` // Links checked more recently are skipped
+	LinkCheckConcurrency  int `yaml:"link_check_concurrency"`            // Checks in flight at once
+	LinkCheckTimeout      time.Duration `yaml:"link_check_timeout"`      // Upper bound for one check including redirects
+	LinkCheckHostInterval time.Duration `yaml:"link_check_host_interval"` // Least time between two checks on one destination host
+	LinkCheckWebhookURL   string `yaml:"link_check_webhook_url"`         // Receives a url.broken POST when a link turns broken (none if empty)
+
+	// Destination snapshot (archival) settings
+	SnapshotStore        string `yaml:"snapshot_store"`        // Where snapshots are kept: filesystem, s3 or empty to disable
+	SnapshotMaxBytes     int64 `yaml:"snapshot_max_bytes"`         // Largest part of a destination that is stored, the rest is cut off
+	SnapshotFetchTimeout time.Duration `yaml:"snapshot_fetch_timeout"` // Upper bound for fetching one destination
+	SnapshotRetention    time.Duration `yaml:"snapshot_retention"` // How long snapshots are kept before the cleanup job purges them (0 = forever)
+	SnapshotDir          string `yaml:"snapshot_dir"`        // Directory of the filesystem store
+	SnapshotS3Endpoint   string `yaml:"snapshot_s3_endpoint"`        // Base URL of the S3 API, e.g. https://s3.eu-west-1.amazonaws.com
+	SnapshotS3Bucket     string `yaml:"snapshot_s3_bucket"`
+	SnapshotS3Region     string `yaml:"snapshot_s3_region"`
+	SnapshotS3AccessKey  string `yaml:"snapshot_s3_access_key"`
+	SnapshotS3SecretKey  string `yaml:"snapshot_s3_secret_key"`
+	SnapshotS3Prefix     string `yaml:"snapshot_s3_prefix"`        // Prepended to every object key
+	SnapshotS3PathStyle  bool `yaml:"snapshot_s3_path_style"`          // Address the bucket in the path (MinIO) instead of the host name
+
+	// Critical-table (disaster recovery) backup settings
+	BackupStore       string `yaml:"backup_store"`          // Where backups of api_keys and policy are written: filesystem, s3 or empty to disable
+	BackupKey         string `yaml:"backup_key"`            // Secret the archives are encrypted with; restores need the same one
+	BackupInterval    time.Duration `yaml:"backup_interval"` // How often the server writes a backup (0 = only by `server backup-critical`)
+	BackupRetention   int `yaml:"backup_retention"`         // Backups kept; older ones are deleted once a new one is written
+	BackupDir         string `yaml:"backup_dir"`            // Directory of the filesystem store
+	BackupS3Endpoint  string `yaml:"backup_s3_endpoint"`    // Base URL of the S3 API, e.g. https://s3.eu-west-1.amazonaws.com
+	BackupS3Bucket    string `yaml:"backup_s3_bucket"`
+	BackupS3Region    string `yaml:"backup_s3_region"`
+	BackupS3AccessKey string `yaml:"backup_s3_access_key"`
+	BackupS3SecretKey string `yaml:"backup_s3_secret_key"`
+	BackupS3Prefix    string `yaml:"backup_s3_prefix"`      // Prepended to every object key
+	BackupS3PathStyle bool `yaml:"backup_s3_path_style"`    // Address the bucket in the path (MinIO) instead of the host name
 }
 
-// LoadConfig loads configuration from environment variables
+// LoadConfig loads configuration from CONFIG_FILE, if set, and environment variables
 // Returns error if required environment variables are missing
 func LoadConfig() (*Config, error) {
-	cfg := &Config{
+	path := os.Getenv("CONFIG_FILE")
+	if path == "" {
+		return LoadFrom(nil)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("configuration file: %w", err)
+	}
+	defer file.Close()
+
+	cfg, err := LoadFrom(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// defaultConfig returns the settings used when neither the file nor the environment sets them
+func defaultConfig() *Config {
+	return &Config{
 		// Server defaults
-		Environment: getEnv("ENVIRONMENT", "development"),
-		ServerPort:  getEnv("SERVER_PORT", "8081"),
+		Environment:   "development",
+		ServerPort:    "8081",
+		EnableMetrics: true,
+		GRPCPort:      "9090",
+		LogLevel:      LogLevelInfo,
+		LogFormat:     LogFormatJSON,
+		LogMaxSizeMB:  100,
+		LogMaxBackups: 5,
+		QuietPaths:    []string{"/health", "/metrics", "/favicon.ico"},
 
-		// Database configuration (required)
-		DBHost:     getEnv("DB_HOST", "localhost"),
-		DBPort:     getEnv("DB_PORT", "5432"),
-		DBUser:     getEnv("DB_USER", "postgres"),
-		DBPassword: getEnv("DB_PASSWORD", ""),
-		DBName:     getEnv("DB_NAME", "urlshortener"),
-		DBSSLMode:  getEnv("DB_SSL_MODE", "disable"),
+		// Database configuration
+		DBDriver:             DBDriverPostgres,
+		MongoDatabase:        "urlshortener",
+		DBHost:               "localhost",
+		DBPort:               "5432",
+		DBUser:               "postgres",
+		DBName:               "urlshortener",
+		DBSSLMode:            "disable",
+		DBMaxIdleConns:       10,
+		DBMaxOpenConns:       100,
+		DBConnMaxLifetime:    time.Hour,
+		DBSlowQueryThreshold: time.Second,
+		DBStatsInterval:      15 * time.Second,
+		DBReplicaFallbackWindow: 10 * time.Second,
+		DBRetries:            2,
+		DBRetryBackoff:       100 * time.Millisecond,
+		AutoMigrate:          true,
 
 		// Redis configuration
-		RedisAddr:     getEnv("REDIS_ADDR", "localhost:6379"),
-		RedisPassword: getEnv("REDIS_PASSWORD", ""),
-		RedisDB:       getEnvAsInt("REDIS_DB", 0),
-		CacheTTL:      time.Duration(getEnvAsInt("CACHE_TTL_SECONDS", 3600)) * time.Second,
+		RedisMode:             RedisModeSingle,
+		RedisAddr:             "localhost:6379",
+		CacheTTL:              time.Hour,
+		NegativeCacheTTL:      time.Minute,
+		CacheWarmTimeout:      30 * time.Second,
+		CacheBreakerThreshold: 5,
+		CacheBreakerCooldown:  30 * time.Second,
+		SummaryCacheTTL:       time.Minute,
+		DedupCacheTTL:         time.Hour,
+		CacheNamespace:        "urlshortener",
+		CacheFlushRate:        1000,
+		ClickQueueSize:        1024,
+		CacheWriteQueueSize:   1024,
 
 		// Application settings
-		BaseURL:              getEnv("BASE_URL", "http://localhost:8081"),
-		ShortCodeLength:      getEnvAsInt("SHORT_CODE_LENGTH", 7),
-		RateLimitPerMinute:   getEnvAsInt("RATE_LIMIT_PER_MINUTE", 60),
-		URLExpirationDays:    getEnvAsInt("URL_EXPIRATION_DAYS", 0),
-		EnableAuthentication: getEnvAsBool("ENABLE_AUTHENTICATION", false),
-		APIKey:               getEnv("API_KEY", ""),
+		BaseURL:                "http://localhost:8081",
+		BaseURLMode:            BaseURLModeStatic,
+		ShortCodeLength:        7,
+		MaxCustomAliasLength:   validator.MaxShortCodePathLength,
+		ShortCodeStrategy:      ShortCodeStrategyRandom,
+		AllowedURLSchemes:      parseList(DefaultAllowedURLSchemes),
+		ShortenerDomains:       parseList(DefaultShortenerDomains),
+		ChainResolveTimeout:    5 * time.Second,
+		ForwardQueryPrecedence: ForwardQueryDestinationWins,
+		LinkStateHeaders:       true,
+		RateLimitPerMinute:     60,
+		RateLimitRedirects:     600,
+		RateLimitIPv6Prefix:    64,
+		MaxExpiryDays:          3650,
+		MaxRequestBodyBytes:    64 << 10,
+		EnableCompression:      true,
+		CompressionMinBytes:    1024,
+		RequestTimeout:         10 * time.Second,
+		RedirectTimeout:        3 * time.Second,
+		RateLimitTiers:         map[string]int{},
+		TenantDomains:          map[string]string{},
+		APIKeyCacheTTL:         time.Minute,
+		MetadataFetchTimeout:   5 * time.Second,
+		CleanupInterval:        time.Hour,
+		StatsRollupInterval:    time.Hour,
+
+		// Interstitial settings
+		InterstitialTokenTTL: 5 * time.Minute,
+		ClickIDTTL:           time.Hour,
+		ClickIDMaxSkew:       time.Minute,
+		ShortenLinkMaxSkew:   5 * time.Minute,
+		BotUserAgents:        parseList(DefaultBotUserAgents),
+		BotClicks:            BotClicksSeparate,
+		PrefetchUserAgents:   parseList(DefaultPrefetchUserAgents),
+
+		// Event stream settings
+		EventsDriver:        EventsDriverNone,
+		EventsTopic:         "url-shortener.events",
+		EventsKafkaRESTURL:  "http://localhost:8082",
+		EventsNATSURL:       "nats://localhost:4222",
+		EventsBatchSize:     100,
+		EventsPollInterval:  time.Second,
+		EventsClickSampling: 1,
+		EventsRetention:     24 * time.Hour,
+
+		// Expiry notification defaults
+		ExpiryNotifyWindow:   7 * 24 * time.Hour,
+		ExpiryNotifyInterval: 24 * time.Hour,
+
+		// Link check defaults
+		LinkCheckInterval:     time.Hour,
+		LinkCheckRecheckAfter: 24 * time.Hour,
+		LinkCheckConcurrency:  4,
+		LinkCheckTimeout:      10 * time.Second,
+		LinkCheckHostInterval: time.Second,
+
+		// Snapshot settings
+		SnapshotStore:        SnapshotStoreNone,
+		SnapshotMaxBytes:     512 << 10,
+		SnapshotFetchTimeout: 10 * time.Second,
+		SnapshotRetention:    365 * 24 * time.Hour,
+		SnapshotDir:          "./snapshots",
+		SnapshotS3Region:     "us-east-1",
+		SnapshotS3Prefix:     "snapshots/",
+
+		// Critical-table backup settings
+		BackupStore:     BackupStoreNone,
+		BackupInterval:  24 * time.Hour,
+		BackupRetention: 14,
+		BackupDir:       "./backups",
+		BackupS3Region:  "us-east-1",
+		BackupS3Prefix:  "backups/",
+	}
+}
+
+// applyEnv overrides cfg with every environment variable that is set
+// Variables keep the units in their names (_SECONDS, _MS, _KB), whatever the file used
+func applyEnv(cfg *Config) error {
+	// Server settings
+	cfg.Environment = getEnv("ENVIRONMENT", cfg.Environment)
+	cfg.ServerPort = getEnv("SERVER_PORT", cfg.ServerPort)
+	cfg.EnableGRPC = getEnvAsBool("ENABLE_GRPC", cfg.EnableGRPC)
+	cfg.EnableMetrics = getEnvAsBool("ENABLE_METRICS", cfg.EnableMetrics)
+	cfg.EnableAPIDocs = getEnvAsBool("ENABLE_API_DOCS", cfg.EnableAPIDocs)
+	cfg.EnableWebUI = getEnvAsBool("ENABLE_WEB_UI", cfg.EnableWebUI)
+	cfg.ShortenLinkSecret = getEnv("SHORTEN_LINK_SECRET", cfg.ShortenLinkSecret)
+	cfg.ShortenLinkMaxSkew = getEnvAsDurationIn("SHORTEN_LINK_MAX_SKEW_SECONDS", time.Second, cfg.ShortenLinkMaxSkew)
+	cfg.GRPCPort = getEnv("GRPC_PORT", cfg.GRPCPort)
+	cfg.LogLevel = strings.ToLower(getEnv("LOG_LEVEL", cfg.LogLevel))
+	cfg.LogFormat = strings.ToLower(getEnv("LOG_FORMAT", cfg.LogFormat))
+	cfg.LogFile = getEnv("LOG_FILE", cfg.LogFile)
+	cfg.LogMaxSizeMB = getEnvAsInt("LOG_MAX_SIZE_MB", cfg.LogMaxSizeMB)
+	cfg.LogMaxBackups = getEnvAsInt("LOG_MAX_BACKUPS", cfg.LogMaxBackups)
+	cfg.LogMaxAgeDays = getEnvAsInt("LOG_MAX_AGE_DAYS", cfg.LogMaxAgeDays)
+	cfg.LogCompress = getEnvAsBool("LOG_COMPRESS", cfg.LogCompress)
+	cfg.QuietPaths = getEnvAsRawList("QUIET_PATHS", cfg.QuietPaths)
+	cfg.CORSAllowedOrigins = getEnvAsList("CORS_ALLOWED_ORIGINS", cfg.CORSAllowedOrigins)
+
+	// Database configuration
+	cfg.DBDriver = getEnv("DB_DRIVER", cfg.DBDriver)
+	cfg.MongoURI = getEnv("MONGO_URI", cfg.MongoURI)
+	cfg.MongoDatabase = getEnv("MONGO_DATABASE", cfg.MongoDatabase)
+	cfg.DBHost = getEnv("DB_HOST", cfg.DBHost)
+	cfg.DBPort = getEnv("DB_PORT", cfg.DBPort)
+	cfg.DBUser = getEnv("DB_USER", cfg.DBUser)
+	cfg.DBPassword = getEnv("DB_PASSWORD", cfg.DBPassword)
+	cfg.DBName = getEnv("DB_NAME", cfg.DBName)
+	cfg.DBSSLMode = getEnv("DB_SSL_MODE", cfg.DBSSLMode)
+	cfg.DBMaxIdleConns = getEnvAsInt("DB_MAX_IDLE_CONNS", cfg.DBMaxIdleConns)
+	cfg.DBMaxOpenConns = getEnvAsInt("DB_MAX_OPEN_CONNS", cfg.DBMaxOpenConns)
+	cfg.DBConnMaxLifetime = getEnvAsDuration("DB_CONN_MAX_LIFETIME", cfg.DBConnMaxLifetime)
+	cfg.DBConnMaxIdleTime = getEnvAsDuration("DB_CONN_MAX_IDLE_TIME", cfg.DBConnMaxIdleTime)
+	cfg.DBSlowQueryThreshold = getEnvAsDurationIn("DB_SLOW_QUERY_MS", time.Millisecond, cfg.DBSlowQueryThreshold)
+	cfg.DBStatsInterval = getEnvAsDurationIn("DB_STATS_INTERVAL_SECONDS", time.Second, cfg.DBStatsInterval)
+	cfg.DBReplicaDSNs = getEnvAsRawList("DB_REPLICA_DSNS", cfg.DBReplicaDSNs)
+	cfg.DBReplicaFallbackWindow = getEnvAsDurationIn("DB_REPLICA_FALLBACK_SECONDS", time.Second, cfg.DBReplicaFallbackWindow)
+	cfg.DBRetries = getEnvAsInt("DB_RETRIES", cfg.DBRetries)
+	cfg.DBRetryBackoff = getEnvAsDurationIn("DB_RETRY_BACKOFF_MS", time.Millisecond, cfg.DBRetryBackoff)
+	cfg.AutoMigrate = getEnvAsBool("AUTO_MIGRATE", cfg.AutoMigrate)
+	cfg.RedirectorDBUser = getEnv("REDIRECTOR_DB_USER", cfg.RedirectorDBUser)
+	cfg.RedirectorDBPassword = getEnv("REDIRECTOR_DB_PASSWORD", cfg.RedirectorDBPassword)
+	cfg.RedirectorReadOnly = getEnvAsBool("REDIRECTOR_READ_ONLY", cfg.RedirectorReadOnly)
+
+	// Redis configuration
+	cfg.RedisMode = strings.ToLower(getEnv("REDIS_MODE", cfg.RedisMode))
+	cfg.RedisAddr = getEnv("REDIS_ADDR", cfg.RedisAddr)
+	cfg.RedisSentinelAddrs = getEnvAsRawList("REDIS_SENTINEL_ADDRS", cfg.RedisSentinelAddrs)
+	cfg.RedisMasterName = getEnv("REDIS_MASTER_NAME", cfg.RedisMasterName)
+	cfg.RedisClusterAddrs = getEnvAsRawList("REDIS_CLUSTER_ADDRS", cfg.RedisClusterAddrs)
+	cfg.RedisPassword = getEnv("REDIS_PASSWORD", cfg.RedisPassword)
+	cfg.RedisDB = getEnvAsInt("REDIS_DB", cfg.RedisDB)
+	cfg.CacheTTL = getEnvAsDurationIn("CACHE_TTL_SECONDS", time.Second, cfg.CacheTTL)
+	cfg.CacheStaleTTL = getEnvAsDurationIn("CACHE_STALE_TTL_SECONDS", time.Second, cfg.CacheStaleTTL)
+	cfg.CacheWarmLinks = getEnvAsInt("CACHE_WARM_LINKS", cfg.CacheWarmLinks)
+	cfg.CacheWarmTimeout = getEnvAsDurationIn("CACHE_WARM_TIMEOUT_SECONDS", time.Second, cfg.CacheWarmTimeout)
+	cfg.NegativeCacheTTL = getEnvAsDurationIn("NEGATIVE_CACHE_TTL_SECONDS", time.Second, cfg.NegativeCacheTTL)
+	cfg.CacheBreakerThreshold = getEnvAsInt("CACHE_BREAKER_THRESHOLD", cfg.CacheBreakerThreshold)
+	cfg.CacheBreakerCooldown = getEnvAsDurationIn("CACHE_BREAKER_COOLDOWN_SECONDS", time.Second, cfg.CacheBreakerCooldown)
+	cfg.SummaryCacheTTL = getEnvAsDurationIn("STATS_SUMMARY_CACHE_TTL_SECONDS", time.Second, cfg.SummaryCacheTTL)
+	cfg.DedupCacheTTL = getEnvAsDurationIn("DEDUP_CACHE_TTL_SECONDS", time.Second, cfg.DedupCacheTTL)
+	cfg.CacheNamespace = getEnv("CACHE_NAMESPACE", cfg.CacheNamespace)
+	cfg.CacheFlushRate = getEnvAsInt("CACHE_FLUSH_KEYS_PER_SECOND", cfg.CacheFlushRate)
+	cfg.ClickQueueSize = getEnvAsInt("CLICK_QUEUE_SIZE", cfg.ClickQueueSize)
+	cfg.CacheWriteQueueSize = getEnvAsInt("CACHE_WRITE_QUEUE_SIZE", cfg.CacheWriteQueueSize)
+
+	// Application settings
+	cfg.BaseURL = getEnv("BASE_URL", cfg.BaseURL)
+	cfg.BaseURLMode = getEnv("BASE_URL_MODE", cfg.BaseURLMode)
+	cfg.AllowedDomains = getEnvAsList("ALLOWED_DOMAINS", cfg.AllowedDomains)
+	cfg.TrustedProxies = getEnvAsList("TRUSTED_PROXIES", cfg.TrustedProxies)
+	cfg.ShortCodeLength = getEnvAsInt("SHORT_CODE_LENGTH", cfg.ShortCodeLength)
+	cfg.MaxCustomAliasLength = getEnvAsInt("MAX_CUSTOM_ALIAS_LENGTH", cfg.MaxCustomAliasLength)
+	cfg.ShortCodeStrategy = getEnv("SHORTCODE_STRATEGY", cfg.ShortCodeStrategy)
+	cfg.ShortCodeExcludeAmbiguous = getEnvAsBool("SHORTCODE_EXCLUDE_AMBIGUOUS", cfg.ShortCodeExcludeAmbiguous)
+	cfg.CaseInsensitiveCodes = getEnvAsBool("CASE_INSENSITIVE_CODES", cfg.CaseInsensitiveCodes)
+	cfg.AllowedURLSchemes = getEnvAsList("ALLOWED_URL_SCHEMES", cfg.AllowedURLSchemes)
+	cfg.LegacyNormalization = getEnvAsBool("LEGACY_URL_NORMALIZATION", cfg.LegacyNormalization)
+	cfg.StripTrackingParams = getEnvAsList("STRIP_TRACKING_PARAMS", cfg.StripTrackingParams)
+	cfg.ShortenerDomains = getEnvAsList("SHORTENER_DOMAINS", cfg.ShortenerDomains)
+	cfg.ResolveShortenerChains = getEnvAsBool("RESOLVE_SHORTENER_CHAINS", cfg.ResolveShortenerChains)
+	cfg.ChainResolveTimeout = getEnvAsDurationIn("CHAIN_RESOLVE_TIMEOUT_SECONDS", time.Second, cfg.ChainResolveTimeout)
+	cfg.Hooks = getEnvAsList("HOOKS", cfg.Hooks)
+	cfg.HookAllowedHosts = getEnvAsList("HOOK_ALLOWED_HOSTS", cfg.HookAllowedHosts)
+	cfg.ForwardQueryDefault = getEnvAsBool("FORWARD_QUERY_DEFAULT", cfg.ForwardQueryDefault)
+	cfg.ForwardQueryPrecedence = getEnv("FORWARD_QUERY_PRECEDENCE", cfg.ForwardQueryPrecedence)
+	cfg.LinkStateHeaders = getEnvAsBool("LINK_STATE_HEADERS", cfg.LinkStateHeaders)
+	cfg.RateLimitPerMinute = getEnvAsInt("RATE_LIMIT_PER_MINUTE", cfg.RateLimitPerMinute)
+	cfg.RateLimitRedirects = getEnvAsInt("RATE_LIMIT_REDIRECTS", cfg.RateLimitRedirects)
+	cfg.RateLimitAPIReads = getEnvAsInt("RATE_LIMIT_API_READS", cfg.RateLimitAPIReads)
+	cfg.RateLimitAPIWrites = getEnvAsInt("RATE_LIMIT_API_WRITES", cfg.RateLimitAPIWrites)
+	cfg.RateLimitIPv6Prefix = getEnvAsInt("RATE_LIMIT_IPV6_PREFIX", cfg.RateLimitIPv6Prefix)
+	cfg.MaxRequestBodyBytes = getEnvAsInt64In("MAX_REQUEST_BODY_BYTES", 1, cfg.MaxRequestBodyBytes)
+	cfg.EnableCompression = getEnvAsBool("ENABLE_COMPRESSION", cfg.EnableCompression)
+	cfg.CompressionMinBytes = getEnvAsInt("COMPRESSION_MIN_BYTES", cfg.CompressionMinBytes)
+	cfg.RequestTimeout = getEnvAsDurationIn("REQUEST_TIMEOUT_SECONDS", time.Second, cfg.RequestTimeout)
+	cfg.RedirectTimeout = getEnvAsDurationIn("REDIRECT_TIMEOUT_SECONDS", time.Second, cfg.RedirectTimeout)
+	cfg.URLExpirationDays = getEnvAsInt("URL_EXPIRATION_DAYS", cfg.URLExpirationDays)
+	cfg.MaxExpiryDays = getEnvAsInt("MAX_EXPIRY_DAYS", cfg.MaxExpiryDays)
+	cfg.EnableAuthentication = getEnvAsBool("ENABLE_AUTHENTICATION", cfg.EnableAuthentication)
+	cfg.APIKey = getEnv("API_KEY", cfg.APIKey)
+	cfg.AdminAPIKey = getEnv("ADMIN_API_KEY", cfg.AdminAPIKey)
+	cfg.APIKeyCacheTTL = getEnvAsDurationIn("API_KEY_CACHE_TTL_SECONDS", time.Second, cfg.APIKeyCacheTTL)
+	cfg.RequireManagementToken = getEnvAsBool("REQUIRE_MANAGEMENT_TOKEN", cfg.RequireManagementToken)
+	cfg.MaxURLsPerDayPerIP = getEnvAsInt("MAX_URLS_PER_DAY_PER_IP", cfg.MaxURLsPerDayPerIP)
+	cfg.MaxURLsPerDayPerKey = getEnvAsInt("MAX_URLS_PER_DAY_PER_KEY", cfg.MaxURLsPerDayPerKey)
+	cfg.MaxURLsPerDayPerTenant = getEnvAsInt("MAX_URLS_PER_DAY_PER_TENANT", cfg.MaxURLsPerDayPerTenant)
+	cfg.TenantScopedCodes = getEnvAsBool("TENANT_SCOPED_CODES", cfg.TenantScopedCodes)
+	cfg.EnableMetadataFetch = getEnvAsBool("ENABLE_METADATA_FETCH", cfg.EnableMetadataFetch)
+	cfg.MetadataFetchTimeout = getEnvAsDurationIn("METADATA_FETCH_TIMEOUT_SECONDS", time.Second, cfg.MetadataFetchTimeout)
+	cfg.CleanupInterval = getEnvAsDurationIn("CLEANUP_INTERVAL_MINUTES", time.Minute, cfg.CleanupInterval)
+	cfg.DeletedRetention = getEnvAsDurationIn("DELETED_RETENTION_DAYS", 24*time.Hour, cfg.DeletedRetention)
+	cfg.StatsRollupInterval = getEnvAsDurationIn("STATS_ROLLUP_INTERVAL_MINUTES", time.Minute, cfg.StatsRollupInterval)
+	cfg.LegacyURLInfo = getEnvAsBool("LEGACY_URL_INFO", cfg.LegacyURLInfo)
+	cfg.ProblemDetails = getEnvAsBool("PROBLEM_DETAILS", cfg.ProblemDetails)
+
+	// The tiers in the environment replace the file's as a whole
+	if raw := getEnv("RATE_LIMIT_TIERS", ""); raw != "" {
+		tiers, err := parseRateLimitTiers(raw)
+		if err != nil {
+			return fmt.Errorf("configuration validation failed: RATE_LIMIT_TIERS: %w", err)
+		}
+		cfg.RateLimitTiers = tiers
 	}
 
-	// Validate required configuration
-	if err := cfg.Validate(); err != nil {
-		return nil, fmt.Errorf("configuration validation failed: %w", err)
+	// Like the tiers, the tenant domains in the environment replace the file's
+	if raw := getEnv("TENANT_DOMAINS", ""); raw != "" {
+		domains, err := parseTenantDomains(raw)
+		if err != nil {
+			return fmt.Errorf("configuration validation failed: TENANT_DOMAINS: %w", err)
+		}
+		cfg.TenantDomains = domains
 	}
 
-	return cfg, nil
+	// Interstitial settings
+	cfg.InterstitialAll = getEnvAsBool("INTERSTITIAL_ALL", cfg.InterstitialAll)
+	cfg.InterstitialNewLinkMinutes = getEnvAsInt("INTERSTITIAL_NEW_LINK_MINUTES", cfg.InterstitialNewLinkMinutes)
+	cfg.InterstitialSecret = getEnv("INTERSTITIAL_SECRET", cfg.InterstitialSecret)
+	cfg.InterstitialTokenTTL = getEnvAsDurationIn("INTERSTITIAL_TOKEN_TTL_SECONDS", time.Second, cfg.InterstitialTokenTTL)
+	cfg.ClickIDSecret = getEnv("CLICK_ID_SECRET", cfg.ClickIDSecret)
+	cfg.ClickIDTTL = getEnvAsDurationIn("CLICK_ID_TTL_SECONDS", time.Second, cfg.ClickIDTTL)
+	cfg.ClickIDMaxSkew = getEnvAsDurationIn("CLICK_ID_MAX_SKEW_SECONDS", time.Second, cfg.ClickIDMaxSkew)
+	cfg.TemplateDir = getEnv("TEMPLATE_DIR", cfg.TemplateDir)
+	cfg.RobotsTxtFile = getEnv("ROBOTS_TXT_FILE", cfg.RobotsTxtFile)
+	cfg.FaviconFile = getEnv("FAVICON_FILE", cfg.FaviconFile)
+	cfg.BotUserAgents = getEnvAsList("BOT_USER_AGENTS", cfg.BotUserAgents)
+	cfg.BotClicks = getEnv("BOT_CLICKS", cfg.BotClicks)
+	cfg.PrefetchUserAgents = getEnvAsList("PREFETCH_USER_AGENTS", cfg.PrefetchUserAgents)
+	cfg.ClickDedupWindow = getEnvAsDuration("CLICK_DEDUP_WINDOW", cfg.ClickDedupWindow)
+
+	// GeoIP settings
+	cfg.GeoIPCIDRFile = getEnv("GEOIP_CIDR_FILE", cfg.GeoIPCIDRFile)
+	cfg.GeoIPCountryHeader = getEnv("GEOIP_COUNTRY_HEADER", cfg.GeoIPCountryHeader)
+
+	// Event stream settings
+	cfg.EventsDriver = getEnv("EVENTS_DRIVER", cfg.EventsDriver)
+	cfg.EventsTopic = getEnv("EVENTS_TOPIC", cfg.EventsTopic)
+	cfg.EventsKafkaRESTURL = getEnv("EVENTS_KAFKA_REST_URL", cfg.EventsKafkaRESTURL)
+	cfg.EventsNATSURL = getEnv("EVENTS_NATS_URL", cfg.EventsNATSURL)
+	cfg.EventsBatchSize = getEnvAsInt("EVENTS_BATCH_SIZE", cfg.EventsBatchSize)
+	cfg.EventsPollInterval = getEnvAsDurationIn("EVENTS_POLL_INTERVAL_MS", time.Millisecond, cfg.EventsPollInterval)
+	cfg.EventsClickSampling = getEnvAsInt("EVENTS_CLICK_SAMPLING", cfg.EventsClickSampling)
+	cfg.EventsRetention = getEnvAsDurationIn("EVENTS_RETENTION_HOURS", time.Hour, cfg.EventsRetention)
+
+	// Expiry notification settings
+	cfg.ExpiryWebhookURL = getEnv("EXPIRY_WEBHOOK_URL", cfg.ExpiryWebhookURL)
+	cfg.ExpiryNotifyWindow = getEnvAsDurationIn("EXPIRY_NOTIFY_DAYS", 24*time.Hour, cfg.ExpiryNotifyWindow)
+	cfg.ExpiryNotifyInterval = getEnvAsDurationIn("EXPIRY_NOTIFY_INTERVAL_HOURS", time.Hour, cfg.ExpiryNotifyInterval)
+
+	// Link check settings
+	cfg.EnableLinkCheck = getEnvAsBool("ENABLE_LINK_CHECK", cfg.EnableLinkCheck)
+	cfg.LinkCheckInterval = getEnvAsDuration("LINK_CHECK_INTERVAL", cfg.LinkCheckInterval)
+	cfg.LinkCheckRecheckAfter = getEnvAsDuration("LINK_CHECK_RECHECK_AFTER", cfg.LinkCheckRecheckAfter)
+	cfg.LinkCheckConcurrency = getEnvAsInt("LINK_CHECK_CONCURRENCY", cfg.LinkCheckConcurrency)
+	cfg.LinkCheckTimeout = getEnvAsDuration("LINK_CHECK_TIMEOUT", cfg.LinkCheckTimeout)
+	cfg.LinkCheckHostInterval = getEnvAsDuration("LINK_CHECK_HOST_INTERVAL", cfg.LinkCheckHostInterval)
+	cfg.LinkCheckWebhookURL = getEnv("LINK_CHECK_WEBHOOK_URL", cfg.LinkCheckWebhookURL)
+
+	// Snapshot settings
+	cfg.SnapshotStore = getEnv("SNAPSHOT_STORE", cfg.SnapshotStore)
+	cfg.SnapshotMaxBytes = getEnvAsInt64In("SNAPSHOT_MAX_KB", 1<<10, cfg.SnapshotMaxBytes)
+	cfg.SnapshotFetchTimeout = getEnvAsDurationIn("SNAPSHOT_FETCH_TIMEOUT_SECONDS", time.Second, cfg.SnapshotFetchTimeout)
+	cfg.SnapshotRetention = getEnvAsDurationIn("SNAPSHOT_RETENTION_DAYS", 24*time.Hour, cfg.SnapshotRetention)
+	cfg.SnapshotDir = getEnv("SNAPSHOT_DIR", cfg.SnapshotDir)
+	cfg.SnapshotS3Endpoint = getEnv("SNAPSHOT_S3_ENDPOINT", cfg.SnapshotS3Endpoint)
+	cfg.SnapshotS3Bucket = getEnv("SNAPSHOT_S3_BUCKET", cfg.SnapshotS3Bucket)
+	cfg.SnapshotS3Region = getEnv("SNAPSHOT_S3_REGION", cfg.SnapshotS3Region)
+	cfg.SnapshotS3AccessKey = getEnv("SNAPSHOT_S3_ACCESS_KEY", cfg.SnapshotS3AccessKey)
+	cfg.SnapshotS3SecretKey = getEnv("SNAPSHOT_S3_SECRET_KEY", cfg.SnapshotS3SecretKey)
+	cfg.SnapshotS3Prefix = getEnv("SNAPSHOT_S3_PREFIX", cfg.SnapshotS3Prefix)
+	cfg.SnapshotS3PathStyle = getEnvAsBool("SNAPSHOT_S3_PATH_STYLE", cfg.SnapshotS3PathStyle)
+
+	// Critical-table backup settings
+	cfg.BackupStore = getEnv("BACKUP_STORE", cfg.BackupStore)
+	cfg.BackupKey = getEnv("BACKUP_KEY", cfg.BackupKey)
+	cfg.BackupInterval = getEnvAsDurationIn("BACKUP_INTERVAL_HOURS", time.Hour, cfg.BackupInterval)
+	cfg.BackupRetention = getEnvAsInt("BACKUP_RETENTION", cfg.BackupRetention)
+	cfg.BackupDir = getEnv("BACKUP_DIR", cfg.BackupDir)
+	cfg.BackupS3Endpoint = getEnv("BACKUP_S3_ENDPOINT", cfg.BackupS3Endpoint)
+	cfg.BackupS3Bucket = getEnv("BACKUP_S3_BUCKET", cfg.BackupS3Bucket)
+	cfg.BackupS3Region = getEnv("BACKUP_S3_REGION", cfg.BackupS3Region)
+	cfg.BackupS3AccessKey = getEnv("BACKUP_S3_ACCESS_KEY", cfg.BackupS3AccessKey)
+	cfg.BackupS3SecretKey = getEnv("BACKUP_S3_SECRET_KEY", cfg.BackupS3SecretKey)
+	cfg.BackupS3Prefix = getEnv("BACKUP_S3_PREFIX", cfg.BackupS3Prefix)
+	cfg.BackupS3PathStyle = getEnvAsBool("BACKUP_S3_PATH_STYLE", cfg.BackupS3PathStyle)
+
+	return nil
 }
 
 // Validate checks if all required configuration is present and valid
@@ -83,24 +665,449 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("DB_PASSWORD is required in production")
 	}
 
+	if err := c.validateDBPool(); err != nil {
+		return err
+	}
+	if err := c.validateDBDriver(); err != nil {
+		return err
+	}
+
+	// A password alone would silently be used with DB_USER
+	if c.RedirectorDBPassword != "" && c.RedirectorDBUser == "" {
+		return fmt.Errorf("REDIRECTOR_DB_PASSWORD requires REDIRECTOR_DB_USER")
+	}
+	
 	// Validate short code length (must be between 4 and 12)
 	if c.ShortCodeLength < 4 || c.ShortCodeLength > 12 {
 		return fmt.Errorf("SHORT_CODE_LENGTH must be between 4 and 12, got %d", c.ShortCodeLength)
 	}
 
+	// Longer aliases would fail in the database instead of being refused up front
+	if c.MaxCustomAliasLength < 2 || c.MaxCustomAliasLength > validator.MaxShortCodePathLength {
+		return fmt.Errorf("MAX_CUSTOM_ALIAS_LENGTH must be between 2 and %d, the short_code column size, got %d", validator.MaxShortCodePathLength, c.MaxCustomAliasLength)
+	}
+
+	switch c.EventsDriver {
+	case EventsDriverNone, EventsDriverKafka, EventsDriverNATS:
+	default:
+		return fmt.Errorf("EVENTS_DRIVER must be %q, %q or empty, got %q", EventsDriverKafka, EventsDriverNATS, c.EventsDriver)
+	}
+	if c.EventsDriver != EventsDriverNone {
+		if c.EventsBatchSize <= 0 || c.EventsPollInterval <= 0 {
+			return fmt.Errorf("EVENTS_BATCH_SIZE and EVENTS_POLL_INTERVAL_MS must be positive")
+		}
+		if c.EventsClickSampling < 0 {
+			return fmt.Errorf("EVENTS_CLICK_SAMPLING cannot be negative, got %d", c.EventsClickSampling)
+		}
+	}
+
+	if c.ExpiryWebhookURL != "" {
+		if !strings.HasPrefix(c.ExpiryWebhookURL, "http://") && !strings.HasPrefix(c.ExpiryWebhookURL, "https://") {
+			return fmt.Errorf("EXPIRY_WEBHOOK_URL must be an http or https URL, got %q", c.ExpiryWebhookURL)
+		}
+		if c.ExpiryNotifyWindow <= 0 || c.ExpiryNotifyInterval <= 0 {
+			return fmt.Errorf("EXPIRY_NOTIFY_DAYS and EXPIRY_NOTIFY_INTERVAL_HOURS must be positive")
+		}
+	}
+
+	if c.EnableLinkCheck {
+		if c.LinkCheckInterval <= 0 || c.LinkCheckTimeout <= 0 || c.LinkCheckHostInterval <= 0 {
+			return fmt.Errorf("LINK_CHECK_INTERVAL, LINK_CHECK_TIMEOUT and LINK_CHECK_HOST_INTERVAL must be positive")
+		}
+		if c.LinkCheckRecheckAfter < 0 {
+			return fmt.Errorf("LINK_CHECK_RECHECK_AFTER cannot be negative, got %s", c.LinkCheckRecheckAfter)
+		}
+		if c.LinkCheckConcurrency < 1 {
+			return fmt.Errorf("LINK_CHECK_CONCURRENCY must be at least 1, got %d", c.LinkCheckConcurrency)
+		}
+		if c.LinkCheckWebhookURL != "" && !strings.HasPrefix(c.LinkCheckWebhookURL, "http://") && !strings.HasPrefix(c.LinkCheckWebhookURL, "https://") {
+			return fmt.Errorf("LINK_CHECK_WEBHOOK_URL must be an http or https URL, got %q", c.LinkCheckWebhookURL)
+		}
+	}
+
+	switch c.SnapshotStore {
+	case SnapshotStoreNone, SnapshotStoreFilesystem:
+	case SnapshotStoreS3:
+		if c.SnapshotS3Endpoint == "" || c.SnapshotS3Bucket == "" || c.SnapshotS3AccessKey == "" || c.SnapshotS3SecretKey == "" {
+			return fmt.Errorf("SNAPSHOT_STORE=s3 requires SNAPSHOT_S3_ENDPOINT, SNAPSHOT_S3_BUCKET and access keys")
+		}
+	default:
+		return fmt.Errorf("SNAPSHOT_STORE must be %q, %q or empty, got %q", SnapshotStoreFilesystem, SnapshotStoreS3, c.SnapshotStore)
+	}
+	if c.SnapshotStore != SnapshotStoreNone && (c.SnapshotMaxBytes <= 0 || c.SnapshotFetchTimeout <= 0 || c.SnapshotRetention < 0) {
+		return fmt.Errorf("SNAPSHOT_MAX_KB and SNAPSHOT_FETCH_TIMEOUT_SECONDS must be positive, SNAPSHOT_RETENTION_DAYS not negative")
+	}
+
+	switch c.BackupStore {
+	case BackupStoreNone, BackupStoreFilesystem:
+	case BackupStoreS3:
+		if c.BackupS3Endpoint == "" || c.BackupS3Bucket == "" || c.BackupS3AccessKey == "" || c.BackupS3SecretKey == "" {
+			return fmt.Errorf("BACKUP_STORE=s3 requires BACKUP_S3_ENDPOINT, BACKUP_S3_BUCKET and access keys")
+		}
+	default:
+		return fmt.Errorf("BACKUP_STORE must be %q, %q or empty, got %q", BackupStoreFilesystem, BackupStoreS3, c.BackupStore)
+	}
+	if c.BackupStore != BackupStoreNone {
+		if len(c.BackupKey) < MinBackupKeyLength {
+			return fmt.Errorf("BACKUP_STORE requires a BACKUP_KEY of at least %d characters", MinBackupKeyLength)
+		}
+		if c.BackupRetention < 1 || c.BackupInterval < 0 {
+			return fmt.Errorf("BACKUP_RETENTION must be at least 1 and BACKUP_INTERVAL_HOURS not negative")
+		}
+	}
+
+	if c.ShortCodeStrategy != ShortCodeStrategyRandom && c.ShortCodeStrategy != ShortCodeStrategyHash {
+		return fmt.Errorf("SHORTCODE_STRATEGY must be %q or %q, got %q", ShortCodeStrategyRandom, ShortCodeStrategyHash, c.ShortCodeStrategy)
+	}
+
+	if c.ForwardQueryPrecedence != ForwardQueryDestinationWins && c.ForwardQueryPrecedence != ForwardQueryIncomingWins {
+		return fmt.Errorf("FORWARD_QUERY_PRECEDENCE must be %q or %q, got %q", ForwardQueryDestinationWins, ForwardQueryIncomingWins, c.ForwardQueryPrecedence)
+	}
+
+	if c.BotClicks != BotClicksSeparate && c.BotClicks != BotClicksIgnore {
+		return fmt.Errorf("BOT_CLICKS must be %q or %q, got %q", BotClicksSeparate, BotClicksIgnore, c.BotClicks)
+	}
+
+	if c.ClickDedupWindow < 0 {
+		return fmt.Errorf("CLICK_DEDUP_WINDOW cannot be negative")
+	}
+
+	if c.DeletedRetention < 0 {
+		return fmt.Errorf("DELETED_RETENTION_DAYS cannot be negative")
+	}
+
+	if c.MaxExpiryDays < 0 {
+		return fmt.Errorf("MAX_EXPIRY_DAYS cannot be negative, got %d", c.MaxExpiryDays)
+	}
+
+	if c.RateLimitPerMinute <= 0 {
+		return fmt.Errorf("RATE_LIMIT_PER_MINUTE must be positive, got %d", c.RateLimitPerMinute)
+	}
+	if c.RateLimitRedirects <= 0 || c.RateLimitAPIReads < 0 || c.RateLimitAPIWrites < 0 {
+		return fmt.Errorf("RATE_LIMIT_REDIRECTS must be positive, RATE_LIMIT_API_READS and RATE_LIMIT_API_WRITES not negative")
+	}
+	if c.RateLimitIPv6Prefix < 1 || c.RateLimitIPv6Prefix > 128 {
+		return fmt.Errorf("RATE_LIMIT_IPV6_PREFIX must be between 1 and 128, got %d", c.RateLimitIPv6Prefix)
+	}
+
+	if c.ResolveShortenerChains && c.ChainResolveTimeout <= 0 {
+		return fmt.Errorf("CHAIN_RESOLVE_TIMEOUT_SECONDS must be positive, got %v", c.ChainResolveTimeout)
+	}
+
+	if c.MaxRequestBodyBytes <= 0 {
+		return fmt.Errorf("MAX_REQUEST_BODY_BYTES must be positive, got %d", c.MaxRequestBodyBytes)
+	}
+
+	if c.CompressionMinBytes < 0 {
+		return fmt.Errorf("COMPRESSION_MIN_BYTES must not be negative, got %d", c.CompressionMinBytes)
+	}
+
+	if c.CacheStaleTTL < 0 {
+		return fmt.Errorf("CACHE_STALE_TTL_SECONDS must not be negative, got %s", c.CacheStaleTTL)
+	}
+
+	if c.CacheWarmLinks < 0 {
+		return fmt.Errorf("CACHE_WARM_LINKS must not be negative, got %d", c.CacheWarmLinks)
+	}
+	if c.CacheWarmLinks > 0 && c.CacheWarmTimeout <= 0 {
+		return fmt.Errorf("CACHE_WARM_TIMEOUT_SECONDS must be positive when CACHE_WARM_LINKS is set, got %s", c.CacheWarmTimeout)
+	}
+
+	if c.RequestTimeout < 0 || c.RedirectTimeout < 0 {
+		return fmt.Errorf("REQUEST_TIMEOUT_SECONDS and REDIRECT_TIMEOUT_SECONDS must not be negative")
+	}
+
 	// Validate base URL
 	if c.BaseURL == "" {
 		return fmt.Errorf("BASE_URL is required")
 	}
 
-	// Validate API key if authentication is enabled
-	if c.EnableAuthentication && c.APIKey == "" {
-		return fmt.Errorf("API_KEY is required when ENABLE_AUTHENTICATION is true")
+	// Without an allow list, request mode would echo any Host header into short links
+	switch c.BaseURLMode {
+	case BaseURLModeStatic:
+	case BaseURLModeRequest:
+		if len(c.AllowedDomains) == 0 {
+			return fmt.Errorf("ALLOWED_DOMAINS is required when BASE_URL_MODE is %q", BaseURLModeRequest)
+		}
+	default:
+		return fmt.Errorf("BASE_URL_MODE must be %q or %q, got %q", BaseURLModeStatic, BaseURLModeRequest, c.BaseURLMode)
+	}
+
+	switch c.LogLevel {
+	case LogLevelDebug, LogLevelInfo, LogLevelWarn, LogLevelError:
+	default:
+		return fmt.Errorf("LOG_LEVEL must be %q, %q, %q or %q, got %q", LogLevelDebug, LogLevelInfo, LogLevelWarn, LogLevelError, c.LogLevel)
+	}
+	if c.LogFormat != LogFormatJSON && c.LogFormat != LogFormatConsole {
+		return fmt.Errorf("LOG_FORMAT must be %q or %q, got %q", LogFormatJSON, LogFormatConsole, c.LogFormat)
+	}
+	if c.LogMaxSizeMB <= 0 {
+		return fmt.Errorf("LOG_MAX_SIZE_MB must be positive, got %d", c.LogMaxSizeMB)
+	}
+	if c.LogMaxBackups < 0 || c.LogMaxAgeDays < 0 {
+		return fmt.Errorf("LOG_MAX_BACKUPS and LOG_MAX_AGE_DAYS must not be negative")
+	}
+
+	for _, path := range c.QuietPaths {
+		if !strings.HasPrefix(path, "/") {
+			return fmt.Errorf("QUIET_PATHS entries must be request paths starting with /, got %q", path)
+		}
 	}
 
+	// gRPC must not share the HTTP port
+	if c.EnableGRPC && c.GRPCPort == c.ServerPort {
+		return fmt.Errorf("GRPC_PORT must differ from SERVER_PORT, both are %s", c.ServerPort)
+	}
+
+	// Each Redis mode needs its own addresses
+	switch c.RedisMode {
+	case RedisModeSingle:
+	case RedisModeSentinel:
+		if len(c.RedisSentinelAddrs) == 0 || c.RedisMasterName == "" {
+			return fmt.Errorf("REDIS_SENTINEL_ADDRS and REDIS_MASTER_NAME are required when REDIS_MODE is %q", RedisModeSentinel)
+		}
+	case RedisModeCluster:
+		if len(c.RedisClusterAddrs) == 0 {
+			return fmt.Errorf("REDIS_CLUSTER_ADDRS is required when REDIS_MODE is %q", RedisModeCluster)
+		}
+		if c.RedisDB != 0 {
+			return fmt.Errorf("REDIS_DB must be 0 when REDIS_MODE is %q, got %d", RedisModeCluster, c.RedisDB)
+		}
+	default:
+		return fmt.Errorf("REDIS_MODE must be %q, %q or %q, got %q", RedisModeSingle, RedisModeSentinel, RedisModeCluster, c.RedisMode)
+	}
+
+	// The namespace ends up inside every Redis key
+	if strings.ContainsAny(c.CacheNamespace, ": \t\n") {
+		return fmt.Errorf("CACHE_NAMESPACE must not contain colons or whitespace, got %q", c.CacheNamespace)
+	}
+
+	// A zero window would refuse every signed shorten link
+	if c.ShortenLinkMaxSkew <= 0 {
+		return fmt.Errorf("SHORTEN_LINK_MAX_SKEW_SECONDS must be positive, got %s", c.ShortenLinkMaxSkew)
+	}
+
+	// Validate interstitial settings
+	if c.InterstitialNewLinkMinutes < 0 {
+		return fmt.Errorf("INTERSTITIAL_NEW_LINK_MINUTES cannot be negative, got %d", c.InterstitialNewLinkMinutes)
+	}
+
+	// Click receipts that expire at once could never be redeemed
+	if c.ClickIDTTL <= 0 {
+		return fmt.Errorf("CLICK_ID_TTL_SECONDS must be positive, got %s", c.ClickIDTTL)
+	}
+	if c.ClickIDMaxSkew < 0 {
+		return fmt.Errorf("CLICK_ID_MAX_SKEW_SECONDS cannot be negative, got %s", c.ClickIDMaxSkew)
+	}
+
+	// Some key must exist when authentication is enabled; with only ADMIN_API_KEY, keys are issued through the admin API
+	if c.EnableAuthentication && c.APIKey == "" && c.AdminAPIKey == "" {
+		return fmt.Errorf("API_KEY or ADMIN_API_KEY is required when ENABLE_AUTHENTICATION is true")
+	}
+	
+	if c.APIKeyCacheTTL <= 0 {
+		return fmt.Errorf("API_KEY_CACHE_TTL_SECONDS must be positive")
+	}
+
+	if err := c.validateLists(); err != nil {
+		return err
+	}
+
+	if err := c.validateTenants(); err != nil {
+		return err
+	}
+
+	return c.validateRateLimitTiers()
+}
+
+// validateLists checks the entries of the list settings, which a file can fill with anything
+func (c *Config) validateLists() error {
+	for _, domain := range c.ShortenerDomains {
+		if domain == "" || strings.ContainsAny(domain, "/: \t") {
+			return fmt.Errorf("SHORTENER_DOMAINS entries must be bare host names, got %q", domain)
+		}
+	}
+	for _, domain := range c.AllowedDomains {
+		if domain == "" || strings.ContainsAny(domain, "/: \t") {
+			return fmt.Errorf("ALLOWED_DOMAINS entries must be bare host names, got %q", domain)
+		}
+	}
+	for _, domain := range c.HookAllowedHosts {
+		if domain == "" || strings.ContainsAny(domain, "/: \t") {
+			return fmt.Errorf("HOOK_ALLOWED_HOSTS entries must be bare host names, got %q", domain)
+		}
+	}
+	for _, origin := range c.CORSAllowedOrigins {
+		if !validOrigin(origin) {
+			return fmt.Errorf("CORS_ALLOWED_ORIGINS entries must be origins such as https://app.example.com, got %q", origin)
+		}
+	}
+	for _, proxy := range c.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			return fmt.Errorf("TRUSTED_PROXIES entries must be IP addresses or CIDRs, got %q", proxy)
+		}
+	}
+	if len(c.AllowedURLSchemes) == 0 {
+		return fmt.Errorf("ALLOWED_URL_SCHEMES cannot be empty")
+	}
+	for _, scheme := range c.AllowedURLSchemes {
+		if !validScheme(scheme) {
+			return fmt.Errorf("ALLOWED_URL_SCHEMES entries must be URL schemes without \":\", got %q", scheme)
+		}
+		if forbiddenURLSchemes[strings.ToLower(scheme)] {
+			return fmt.Errorf("ALLOWED_URL_SCHEMES cannot contain %q", scheme)
+		}
+	}
+	for _, param := range c.StripTrackingParams {
+		if name := strings.TrimSuffix(param, "*"); name == "" || strings.Contains(name, "*") {
+			return fmt.Errorf("STRIP_TRACKING_PARAMS entries must be a parameter name, optionally ending in *, got %q", param)
+		}
+	}
+	for _, agent := range c.BotUserAgents {
+		if agent == "" {
+			return fmt.Errorf("BOT_USER_AGENTS entries cannot be empty")
+		}
+	}
+	for _, agent := range c.PrefetchUserAgents {
+		if agent == "" {
+			return fmt.Errorf("PREFETCH_USER_AGENTS entries cannot be empty")
+		}
+	}
+	return nil
+}
+
+// validOrigin reports whether s is an http or https origin: a scheme and host, optionally a port, nothing else
+func validOrigin(s string) bool {
+	u, err := url.Parse(s)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return false
+	}
+	return s == u.Scheme+"://"+u.Host
+}
+
+// validScheme reports whether s is an RFC 3986 scheme: a letter followed by letters, digits, "+", "-" or "."
+func validScheme(s string) bool {
+	for i, c := range s {
+		switch {
+		case 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z':
+		case i > 0 && ('0' <= c && c <= '9' || c == '+' || c == '-' || c == '.'):
+		default:
+			return false
+		}
+	}
+	return s != ""
+}
+
+// validateRateLimitTiers checks that every tier names a key and has a usable limit
+func (c *Config) validateRateLimitTiers() error {
+	for id, perMinute := range c.RateLimitTiers {
+		if strings.TrimSpace(id) == "" {
+			return fmt.Errorf("RATE_LIMIT_TIERS cannot contain an empty key ID")
+		}
+		if perMinute <= 0 && perMinute != RateLimitUnlimited {
+			return fmt.Errorf("RATE_LIMIT_TIERS limit for key %q must be positive or unlimited, got %d", id, perMinute)
+		}
+	}
+	return nil
+}
+
+// validateTenants checks the tenant quota and that TenantDomains maps valid tenants to distinct plain hosts
+func (c *Config) validateTenants() error {
+	if c.MaxURLsPerDayPerTenant < 0 {
+		return fmt.Errorf("MAX_URLS_PER_DAY_PER_TENANT cannot be negative, got %d", c.MaxURLsPerDayPerTenant)
+	}
+	tenants := make(map[string]string, len(c.TenantDomains))
+	for tenant, host := range c.TenantDomains {
+		if !domain.IsValidTenantID(tenant) {
+			return fmt.Errorf("TENANT_DOMAINS tenant IDs must be lowercase letters, digits, - and _, got %q", tenant)
+		}
+		if host == "" || strings.ContainsAny(host, "/: \t") {
+			return fmt.Errorf("TENANT_DOMAINS entries must map to bare host names, got %q for tenant %q", host, tenant)
+		}
+		if other, taken := tenants[strings.ToLower(host)]; taken {
+			return fmt.Errorf("TENANT_DOMAINS maps %q to both %q and %q", host, other, tenant)
+		}
+		tenants[strings.ToLower(host)] = tenant
+	}
 	return nil
 }
 
+// TenantForHost returns the tenant TenantDomains maps host to, the default tenant for other hosts
+func (c *Config) TenantForHost(host string) string {
+	for tenant, domainHost := range c.TenantDomains {
+		if strings.EqualFold(domainHost, host) {
+			return tenant
+		}
+	}
+	return ""
+}
+
+// validateDBPool checks the connection pool settings
+// More idle than open connections would be silently capped by database/sql, so it is refused instead
+func (c *Config) validateDBPool() error {
+	if c.DBMaxIdleConns < 0 || c.DBMaxOpenConns < 0 {
+		return fmt.Errorf("DB_MAX_IDLE_CONNS and DB_MAX_OPEN_CONNS cannot be negative")
+	}
+	if c.DBMaxOpenConns > 0 && c.DBMaxIdleConns > c.DBMaxOpenConns {
+		return fmt.Errorf("DB_MAX_IDLE_CONNS (%d) cannot exceed DB_MAX_OPEN_CONNS (%d)", c.DBMaxIdleConns, c.DBMaxOpenConns)
+	}
+	if c.DBConnMaxLifetime < 0 || c.DBConnMaxIdleTime < 0 {
+		return fmt.Errorf("DB_CONN_MAX_LIFETIME and DB_CONN_MAX_IDLE_TIME cannot be negative")
+	}
+	if c.DBSlowQueryThreshold < 0 || c.DBStatsInterval < 0 {
+		return fmt.Errorf("DB_SLOW_QUERY_MS and DB_STATS_INTERVAL_SECONDS cannot be negative")
+	}
+	if len(c.DBReplicaDSNs) > 0 && c.DBReplicaFallbackWindow <= 0 {
+		return fmt.Errorf("DB_REPLICA_FALLBACK_SECONDS must be positive when DB_REPLICA_DSNS is set")
+	}
+	if c.DBRetries < 0 || c.DBRetries > 5 {
+		return fmt.Errorf("DB_RETRIES must be between 0 and 5, got %d", c.DBRetries)
+	}
+	if c.DBRetries > 0 && c.DBRetryBackoff <= 0 {
+		return fmt.Errorf("DB_RETRY_BACKOFF_MS must be positive when DB_RETRIES is set")
+	}
+	return nil
+}
+
+// validateDBDriver checks DB_DRIVER and what keeping the links in MongoDB requires
+// Replicas and the outbox work on the Postgres urls table, so neither can be combined with mongo:
+// the outbox's events would no longer be written in the transaction of the change they describe.
+func (c *Config) validateDBDriver() error {
+	switch c.DBDriver {
+	case DBDriverPostgres:
+		return nil
+	case DBDriverMongo:
+	default:
+		return fmt.Errorf("DB_DRIVER must be %q or %q, got %q", DBDriverPostgres, DBDriverMongo, c.DBDriver)
+	}
+	if c.MongoURI == "" || c.MongoDatabase == "" {
+		return fmt.Errorf("MONGO_URI and MONGO_DATABASE are required when DB_DRIVER is %q", DBDriverMongo)
+	}
+	if len(c.DBReplicaDSNs) > 0 {
+		return fmt.Errorf("DB_REPLICA_DSNS cannot be used with DB_DRIVER=%s; set readPreference in MONGO_URI instead", DBDriverMongo)
+	}
+	if c.EventsDriver != EventsDriverNone {
+		return fmt.Errorf("EVENTS_DRIVER cannot be used with DB_DRIVER=%s", DBDriverMongo)
+	}
+	return nil
+}
+
+// APIReadLimit returns the per-minute limit of API reads
+func (c *Config) APIReadLimit() int {
+	if c.RateLimitAPIReads > 0 {
+		return c.RateLimitAPIReads
+	}
+	return c.RateLimitPerMinute
+}
+
+// APIWriteLimit returns the per-minute limit of API writes
+func (c *Config) APIWriteLimit() int {
+	if c.RateLimitAPIWrites > 0 {
+		return c.RateLimitAPIWrites
+	}
+	return c.RateLimitPerMinute
+}
+
 // IsDevelopment returns true if running in development mode
 func (c *Config) IsDevelopment() bool {
 	return c.Environment == "development"
@@ -111,6 +1118,65 @@ func (c *Config) IsProduction() bool {
 	return c.Environment == "production"
 }
 
+// parseRateLimitTiers parses "keyid:600,keyid2:unlimited" into requests per minute by key fingerprint
+func parseRateLimitTiers(value string) (map[string]int, error) {
+	tiers := make(map[string]int)
+	if strings.TrimSpace(value) == "" {
+		return tiers, nil
+	}
+	
+	for _, entry := range strings.Split(value, ",") {
+		id, limit, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		id = strings.TrimSpace(id)
+		limit = strings.TrimSpace(limit)
+		if !ok || id == "" || limit == "" {
+			return nil, fmt.Errorf("expected keyid:limit, got %q", entry)
+		}
+		if _, exists := tiers[id]; exists {
+			return nil, fmt.Errorf("duplicate tier for key %q", id)
+		}
+		
+		perMinute, err := parseTierLimit(id, limit)
+		if err != nil {
+			return nil, err
+		}
+		tiers[id] = perMinute
+	}
+	
+	return tiers, nil
+}
+
+// parseTenantDomains parses "acme:links.acme.com,globex:go.globex.com" into the host of each tenant
+func parseTenantDomains(value string) (map[string]string, error) {
+	domains := make(map[string]string)
+	for _, entry := range strings.Split(value, ",") {
+		tenant, host, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		tenant = strings.TrimSpace(tenant)
+		host = strings.TrimSpace(host)
+		if !ok || tenant == "" || host == "" {
+			return nil, fmt.Errorf("expected tenant:host, got %q", entry)
+		}
+		if _, exists := domains[tenant]; exists {
+			return nil, fmt.Errorf("duplicate domain for tenant %q", tenant)
+		}
+		domains[tenant] = host
+	}
+	return domains, nil
+}
+
+// parseTierLimit parses the requests per minute of one tier, a positive number or "unlimited"
+func parseTierLimit(id, limit string) (int, error) {
+	if strings.EqualFold(limit, "unlimited") {
+		return RateLimitUnlimited, nil
+	}
+	
+	perMinute, err := strconv.Atoi(limit)
+	if err != nil || perMinute <= 0 {
+		return 0, fmt.Errorf("limit for key %q must be a positive number or \"unlimited\", got %q", id, limit)
+	}
+	return perMinute, nil
+}
+
 // Helper functions for reading environment variables
 
 // getEnv reads an environment variable or returns a default value
@@ -149,4 +1215,80 @@ func getEnvAsBool(key string, defaultValue bool) bool {
 	}
 	
 	return value
-}
\ No newline at end of file
+}
+
+// getEnvAsDuration reads an environment variable as a Go duration such as "30m" or "1h"
+// A bare number is taken as seconds
+func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
+	valueStr := os.Getenv(key)
+	if valueStr == "" {
+		return defaultValue
+	}
+	
+	if seconds, err := strconv.Atoi(valueStr); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	value, err := time.ParseDuration(valueStr)
+	if err != nil {
+		return defaultValue
+	}
+	
+	return value
+}
+
+// getEnvAsDurationIn reads an environment variable as a whole number of unit, e.g. seconds
+func getEnvAsDurationIn(key string, unit, defaultValue time.Duration) time.Duration {
+	return time.Duration(getEnvAsInt64In(key, int64(unit), int64(defaultValue)))
+}
+
+// getEnvAsInt64In reads an environment variable as a whole number of unit, e.g. kilobytes, or returns default
+func getEnvAsInt64In(key string, unit, defaultValue int64) int64 {
+	valueStr := os.Getenv(key)
+	if valueStr == "" {
+		return defaultValue
+	}
+	
+	value, err := strconv.ParseInt(valueStr, 10, 64)
+	if err != nil {
+		return defaultValue
+	}
+	
+	return value * unit
+}
+
+// getEnvAsList reads a comma-separated environment variable as lowercase, trimmed entries
+// defaultValue only applies when the variable is unset, so setting it empty clears the list
+func getEnvAsList(key string, defaultValue []string) []string {
+	raw, ok := os.LookupEnv(key)
+	if !ok {
+		return defaultValue
+	}
+	return parseList(raw)
+}
+
+// getEnvAsRawList reads a comma-separated environment variable as trimmed entries, keeping their case
+// Used for values such as DSNs where case matters
+func getEnvAsRawList(key string, defaultValue []string) []string {
+	raw, ok := os.LookupEnv(key)
+	if !ok {
+		return defaultValue
+	}
+	var values []string
+	for _, entry := range strings.Split(raw, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			values = append(values, entry)
+		}
+	}
+	return values
+}
+
+// parseList splits a comma-separated list into lowercase, trimmed, non-empty entries
+func parseList(raw string) []string {
+	var values []string
+	for _, entry := range strings.Split(raw, ",") {
+		if entry = strings.ToLower(strings.TrimSpace(entry)); entry != "" {
+			values = append(values, entry)
+		}
+	}
+	return values
+}
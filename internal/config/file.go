@@ -0,0 +1,158 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LoadFrom loads configuration from the YAML in r, then applies environment variables on top
+// Every environment variable still overrides its key; a nil r leaves the defaults and the environment
+func LoadFrom(r io.Reader) (*Config, error) {
+	cfg := defaultConfig()
+	if r != nil {
+		if err := decodeYAML(r, cfg); err != nil {
+			return nil, fmt.Errorf("configuration file: %w", err)
+		}
+	}
+
+	if err := applyEnv(cfg); err != nil {
+		return nil, err
+	}
+
+	// Validate required configuration
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("configuration validation failed: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// decodeYAML sets the fields of cfg named by the keys of a YAML mapping
+// Keys are decoded one by one so a type mismatch or unknown key is reported by name
+func decodeYAML(r io.Reader, cfg *Config) error {
+	var doc yaml.Node
+	if err := yaml.NewDecoder(r).Decode(&doc); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		return err
+	}
+	if len(doc.Content) == 0 {
+		return nil
+	}
+
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: expected a mapping of settings", root.Line)
+	}
+
+	fields := yamlFields()
+	target := reflect.ValueOf(cfg).Elem()
+	seen := make(map[string]bool)
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+		index, ok := fields[key.Value]
+		if !ok {
+			return fmt.Errorf("line %d: unknown key %q", key.Line, key.Value)
+		}
+		if seen[key.Value] {
+			return fmt.Errorf("line %d: %s is set twice", key.Line, key.Value)
+		}
+		seen[key.Value] = true
+
+		if err := decodeField(key.Value, value, target.Field(index)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// yamlFields maps the yaml tag of every Config field to its index
+func yamlFields() map[string]int {
+	t := reflect.TypeOf(Config{})
+	fields := make(map[string]int, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		if tag := t.Field(i).Tag.Get("yaml"); tag != "" {
+			fields[tag] = i
+		}
+	}
+	return fields
+}
+
+// caseSensitiveLists are list keys whose entries keep their case, such as DSNs carrying passwords
+var caseSensitiveLists = map[string]bool{
+	"db_replica_dsns": true,
+}
+
+// decodeField decodes one value into field
+// Lists are lowercased like their environment variables, except those in caseSensitiveLists
+// Tiers accept "unlimited" like RATE_LIMIT_TIERS
+func decodeField(key string, value *yaml.Node, field reflect.Value) error {
+	if key == "rate_limit_tiers" {
+		var raw map[string]string
+		if err := value.Decode(&raw); err != nil {
+			return fmt.Errorf("line %d: %s must be a mapping of key IDs to a limit", value.Line, key)
+		}
+		tiers := make(map[string]int, len(raw))
+		for id, limit := range raw {
+			perMinute, err := parseTierLimit(id, strings.TrimSpace(limit))
+			if err != nil {
+				return fmt.Errorf("line %d: %s: %w", value.Line, key, err)
+			}
+			tiers[id] = perMinute
+		}
+		field.Set(reflect.ValueOf(tiers))
+		return nil
+	}
+
+	decoded := reflect.New(field.Type())
+	if err := value.Decode(decoded.Interface()); err != nil {
+		return fmt.Errorf("line %d: %s must be %s, got %s", value.Line, key, describeType(field.Type()), describeNode(value))
+	}
+
+	if list, ok := decoded.Elem().Interface().([]string); ok {
+		for i := range list {
+			list[i] = strings.TrimSpace(list[i])
+			if !caseSensitiveLists[key] {
+				list[i] = strings.ToLower(list[i])
+			}
+		}
+	}
+	field.Set(decoded.Elem())
+	return nil
+}
+
+// describeType names the YAML value expected for a field type in error messages
+func describeType(t reflect.Type) string {
+	if t == reflect.TypeOf(time.Duration(0)) {
+		return `a duration such as "30s" or "1h"`
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return "true or false"
+	case reflect.Int, reflect.Int64:
+		return "a whole number"
+	case reflect.Slice:
+		return "a list of strings"
+	default:
+		return "a string"
+	}
+}
+
+// describeNode shows the value found in error messages, or its kind when it isn't a scalar
+func describeNode(value *yaml.Node) string {
+	switch value.Kind {
+	case yaml.SequenceNode:
+		return "a list"
+	case yaml.MappingNode:
+		return "a mapping"
+	default:
+		return fmt.Sprintf("%q", value.Value)
+	}
+}
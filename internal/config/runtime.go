@@ -0,0 +1,156 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrRestartRequired is returned by a reload that changes settings which only take effect on startup
+var ErrRestartRequired = errors.New("settings changed that require a restart")
+
+// reloadable lists the yaml keys of the settings a reload may change; all others are restart-only
+var reloadable = map[string]bool{
+	"log_level":             true,
+	"cors_allowed_origins":  true,
+	"rate_limit_per_minute": true,
+	"rate_limit_redirects":  true,
+	"rate_limit_api_reads":  true,
+	"rate_limit_api_writes": true,
+	"rate_limit_tiers":      true,
+	"shortener_domains":     true,
+	"bot_user_agents":       true,
+}
+
+// RuntimeConfig holds the settings that can change while the server runs
+// A value is never modified after it is published, so readers can keep it for the rest of a request
+type RuntimeConfig struct {
+	LogLevel           string
+	CORSAllowedOrigins []string
+	RateLimitRedirects int            // Redirects per minute per IP or API key
+	RateLimitAPIReads  int            // API reads per minute, RATE_LIMIT_PER_MINUTE already applied
+	RateLimitAPIWrites int            // API writes per minute, RATE_LIMIT_PER_MINUTE already applied
+	RateLimitTiers     map[string]int // Requests per minute by API key fingerprint
+	ShortenerDomains   []string       // Hosts of other shorteners refused or resolved as destinations
+	BotUserAgents      []string       // User-Agent substrings whose hits are not counted as clicks
+}
+
+// newRuntimeConfig copies the reloadable settings out of cfg
+func newRuntimeConfig(cfg *Config) *RuntimeConfig {
+	return &RuntimeConfig{
+		LogLevel:           cfg.LogLevel,
+		CORSAllowedOrigins: cfg.CORSAllowedOrigins,
+		RateLimitRedirects: cfg.RateLimitRedirects,
+		RateLimitAPIReads:  cfg.APIReadLimit(),
+		RateLimitAPIWrites: cfg.APIWriteLimit(),
+		RateLimitTiers:     cfg.RateLimitTiers,
+		ShortenerDomains:   cfg.ShortenerDomains,
+		BotUserAgents:      cfg.BotUserAgents,
+	}
+}
+
+// AllowsOrigin reports whether origin is listed in CORSAllowedOrigins
+func (rc *RuntimeConfig) AllowsOrigin(origin string) bool {
+	origin = strings.ToLower(origin)
+	for _, allowed := range rc.CORSAllowedOrigins {
+		if allowed == origin {
+			return true
+		}
+	}
+	return false
+}
+
+// Runtime publishes the current RuntimeConfig to middleware and services, which read it per request
+// A reload swaps the whole value at once, so a request sees either the old or the new settings, never a mix.
+// Requests already running keep the value they loaded.
+type Runtime struct {
+	current atomic.Pointer[RuntimeConfig]
+
+	mu       sync.Mutex // Serializes reloads
+	base     *Config    // Settings in effect, restart-only ones as loaded at startup
+	load     func() (*Config, error)
+	onReload []func(*RuntimeConfig)
+}
+
+// NewRuntime publishes the reloadable part of cfg; Reload re-reads it with LoadConfig
+func NewRuntime(cfg *Config) *Runtime {
+	r := &Runtime{base: cfg, load: LoadConfig}
+	r.current.Store(newRuntimeConfig(cfg))
+	return r
+}
+
+// Load returns the settings in effect
+func (r *Runtime) Load() *RuntimeConfig {
+	return r.current.Load()
+}
+
+// Config returns the whole configuration in effect, reloaded settings included
+// The value must not be modified; a reload replaces it rather than changing it
+func (r *Runtime) Config() *Config {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.base
+}
+
+// OnReload registers fn to run after every successful reload, e.g. to change the log level
+func (r *Runtime) OnReload(fn func(*RuntimeConfig)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onReload = append(r.onReload, fn)
+}
+
+// Reload loads the configuration again, from CONFIG_FILE and the environment, and applies it
+// It returns the yaml keys of the settings that changed
+func (r *Runtime) Reload() ([]string, error) {
+	next, err := r.load()
+	if err != nil {
+		return nil, err
+	}
+	return r.Apply(next)
+}
+
+// Apply publishes the reloadable settings of next and returns the yaml keys of those that changed
+// If next differs in any restart-only setting nothing is applied and the error, wrapping
+// ErrRestartRequired, names those settings. Values are never part of the error, as some are secrets.
+func (r *Runtime) Apply(next *Config) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	changed, restartOnly := diffConfig(r.base, next)
+	if len(restartOnly) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrRestartRequired, strings.Join(restartOnly, ", "))
+	}
+	if len(changed) == 0 {
+		return nil, nil
+	}
+
+	r.base = next
+	published := newRuntimeConfig(next)
+	r.current.Store(published)
+	for _, fn := range r.onReload {
+		fn(published)
+	}
+	return changed, nil
+}
+
+// diffConfig compares two configurations field by field, splitting the yaml keys that differ
+// into reloadable and restart-only ones
+func diffConfig(old, next *Config) (changed, restartOnly []string) {
+	t := reflect.TypeOf(*old)
+	oldValue, nextValue := reflect.ValueOf(*old), reflect.ValueOf(*next)
+	for i := 0; i < t.NumField(); i++ {
+		if reflect.DeepEqual(oldValue.Field(i).Interface(), nextValue.Field(i).Interface()) {
+			continue
+		}
+		key := t.Field(i).Tag.Get("yaml")
+		if reloadable[key] {
+			changed = append(changed, key)
+		} else {
+			restartOnly = append(restartOnly, key)
+		}
+	}
+	return changed, restartOnly
+}
@@ -0,0 +1,49 @@
+package domain
+
+import (
+	"time"
+)
+
+// APIKey is an issued API key; only the SHA-256 of the secret is stored
+type APIKey struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	KeyHash     string     `gorm:"uniqueIndex;not null;size:64" json:"-"`
+	Fingerprint string     `gorm:"not null;size:8" json:"fingerprint"` // Key id used in audit logs and RATE_LIMIT_TIERS
+	Label       string     `gorm:"not null;size:100" json:"label"`
+	Tier        int        `gorm:"not null;default:0" json:"tier"`                         // Requests per minute, 0 = default limit, -1 = unlimited
+	TenantID    string     `gorm:"not null;size:64;default:''" json:"tenant_id,omitempty"` // Tenant whose links the key sees, empty for the default tenant
+	DefaultExpiryDays int  `gorm:"not null;default:0" json:"default_expiry_days,omitempty"` // Expiry of links created without one, 0 = URL_EXPIRATION_DAYS, -1 = never
+	MaxExpiryDays     int  `gorm:"not null;default:0" json:"max_expiry_days,omitempty"`     // Cap on requested expiries, 0 = MAX_EXPIRY_DAYS, -1 = no limit
+	CreatedAt   time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	RevokedAt   *time.Time `gorm:"index" json:"revoked_at,omitempty"`
+}
+
+// TableName specifies the table name for GORM
+func (APIKey) TableName() string {
+	return "api_keys"
+}
+
+// IsRevoked reports whether the key may no longer be used
+func (k *APIKey) IsRevoked() bool {
+	return k.RevokedAt != nil
+}
+
+// Expiry returns the key's expiry settings
+func (k *APIKey) Expiry() KeyExpiry {
+	return KeyExpiry{DefaultDays: k.DefaultExpiryDays, MaxDays: k.MaxExpiryDays}
+}
+
+// CreateAPIKeyRequest represents the request payload for issuing an API key
+type CreateAPIKeyRequest struct {
+	Label    string `json:"label" binding:"required,max=100"`
+	Tier     int    `json:"tier,omitempty"`                       // Requests per minute, 0 = default limit, -1 = unlimited
+	TenantID string `json:"tenant_id,omitempty" binding:"max=64"` // Lowercase letters, digits, - and _; empty for the default tenant
+	DefaultExpiryDays int `json:"default_expiry_days,omitempty"` // 0 = URL_EXPIRATION_DAYS, -1 = never
+	MaxExpiryDays     int `json:"max_expiry_days,omitempty"`     // 0 = MAX_EXPIRY_DAYS, -1 = no limit
+}
+
+// CreateAPIKeyResponse carries the secret of a new key, which is shown only once
+type CreateAPIKeyResponse struct {
+	APIKey
+	Key string `json:"key"`
+}
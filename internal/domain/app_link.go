@@ -0,0 +1,34 @@
+package domain
+
+// AppLink is where a link sends mobile visitors that may have its app installed
+// Fallbacks are left empty to use the link's regular destination
+type AppLink struct {
+	DeepLink        string `json:"deep_link"`                  // https universal/app link, or a custom scheme such as myapp://
+	IOSFallback     string `json:"ios_fallback,omitempty"`     // Opened on iOS when the app didn't take over
+	AndroidFallback string `json:"android_fallback,omitempty"` // Opened on Android when the app didn't take over
+}
+
+// AppLaunch tells the redirect handler to try a custom-scheme deep link from a page
+// The decision's OriginalURL is then the fallback the page falls back to
+type AppLaunch struct {
+	DeepLink string
+	Android  bool // Launch through an intent:// URL, which lets Chrome open the fallback itself
+}
+
+// HasDeepLink reports whether mobile visitors are sent into an app
+func (u *URL) HasDeepLink() bool {
+	return u.DeepLink != ""
+}
+
+// AppLink returns the link's deep link with UTM parameters applied to the fallbacks
+// The deep link itself is passed to the app unchanged
+func (u *URL) AppLink() AppLink {
+	link := AppLink{DeepLink: u.DeepLink}
+	if u.IOSFallbackURL != "" {
+		link.IOSFallback = u.UTM.AppendTo(u.IOSFallbackURL)
+	}
+	if u.AndroidFallbackURL != "" {
+		link.AndroidFallback = u.UTM.AppendTo(u.AndroidFallbackURL)
+	}
+	return link
+}
@@ -0,0 +1,39 @@
+package domain
+
+import (
+	"time"
+)
+
+// Audit actions recorded for administrative operations
+const (
+	AuditActionDeactivate = "url.deactivated"
+	AuditActionActivate   = "url.activated"
+	AuditActionCacheFlush = "cache.flushed"
+	AuditActionBulkDeactivate = "url.bulk_deactivated"
+	AuditActionErase      = "url.erased"     // Hard delete; the entry outlives the link's own audit trail
+	AuditActionStatsReset = "url.stats_reset" // Details hold the totals before the reset
+)
+
+// Actor identifies who performed an operation
+type Actor struct {
+	ID string // Stable identity, e.g. "admin:1a2b3c4d" or "anonymous"
+	IP string // Client address the request came from
+}
+
+// AuditEntry records an administrative action against a short URL
+// Actions that don't target a single link leave ShortCode empty
+type AuditEntry struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Action    string    `gorm:"not null;size:64;index" json:"action"`
+	TenantID  string    `gorm:"not null;size:64;default:''" json:"tenant_id,omitempty"` // Tenant of the link, or of the caller for entries without one
+	ShortCode string    `gorm:"not null;size:64;index" json:"short_code"`
+	ActorID   string    `gorm:"not null;size:64" json:"actor_id"`
+	ActorIP   string    `gorm:"size:45" json:"actor_ip"`
+	Details   string    `gorm:"type:text" json:"details,omitempty"`
+	CreatedAt time.Time `gorm:"autoCreateTime;index" json:"created_at"`
+}
+
+// TableName specifies the table name for GORM
+func (AuditEntry) TableName() string {
+	return "audit_logs"
+}
@@ -0,0 +1,28 @@
+package domain
+
+import (
+	"time"
+)
+
+// BulkDeactivateRequest selects links to deactivate, by short code or by filter but not both
+type BulkDeactivateRequest struct {
+	ShortCodes []string    `json:"short_codes"`
+	Filter     *BulkFilter `json:"filter"`
+	DryRun     bool        `json:"dry_run"` // Count the matches without deactivating them
+}
+
+// BulkFilter matches links by how and when they were created; at least one field must be set
+type BulkFilter struct {
+	CreatorIP       string     `json:"creator_ip"`
+	CreatedFrom     *time.Time `json:"created_from"`     // Inclusive
+	CreatedTo       *time.Time `json:"created_to"`       // Exclusive
+	DestinationHost string     `json:"destination_host"` // Host ignoring case and port, e.g. "spam.example", or "*.spam.example" with subdomains
+	PathPrefix      string     `json:"path_prefix"`      // Destination path starts with this, e.g. "/scam"; needs destination_host
+}
+
+// BulkDeactivateResponse reports the outcome of a bulk deactivation
+type BulkDeactivateResponse struct {
+	Affected int64    `json:"affected"`            // Links deactivated, or that would be on a dry run
+	NotFound []string `json:"not_found,omitempty"` // Requested short codes that don't exist
+	DryRun   bool     `json:"dry_run,omitempty"`
+}
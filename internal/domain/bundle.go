@@ -0,0 +1,35 @@
+package domain
+
+import (
+	"database/sql/driver"
+)
+
+// MaxBundleItems bounds how many links a single bundle page can list
+const MaxBundleItems = 20
+
+// MaxBundleTitleLength bounds the display title of a bundle member
+const MaxBundleTitleLength = 200
+
+// BundleItem is one entry on a bundle landing page
+// Each member gets its own short link so clicks on it are counted like any other redirect
+type BundleItem struct {
+	Title     string `json:"title"`
+	URL       string `json:"url"`
+	ShortCode string `json:"short_code,omitempty"` // Generated member link; ignored on input
+}
+
+// BundleItems is the ordered member list of a bundle, stored as JSONB
+type BundleItems []BundleItem
+
+// Value implements driver.Valuer so GORM writes the members as JSON
+func (b BundleItems) Value() (driver.Value, error) {
+	if len(b) == 0 {
+		return nil, nil
+	}
+	return jsonValue(b)
+}
+
+// Scan implements sql.Scanner for reading the JSON column back
+func (b *BundleItems) Scan(value interface{}) error {
+	return scanJSON(value, b)
+}
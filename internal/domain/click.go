@@ -0,0 +1,130 @@
+package domain
+
+import (
+	"time"
+)
+
+// ClickEvent records a single counted redirect
+type ClickEvent struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	TenantID  string    `gorm:"not null;size:64;default:''" json:"-"` // Tenant of the clicked link
+	ShortCode string    `gorm:"not null;size:64;index" json:"short_code"`
+	Target    string    `gorm:"not null;size:64" json:"target"` // Rule that selected the destination ("default" for the fallback)
+	Variant   *int      `json:"variant,omitempty"`              // A/B variant index, nil when the link has no split
+	ClickedAt time.Time `gorm:"not null;index" json:"clicked_at"`
+	IP        string    `gorm:"size:45" json:"-"`                 // Visitor address, only used for unique counts
+	Referrer  string    `gorm:"size:255" json:"referrer,omitempty"` // Host of the Referer header, empty for direct visits
+}
+
+// TableName specifies the table name for GORM
+func (ClickEvent) TableName() string {
+	return "click_events"
+}
+
+// DailyClickStats is one day of clicks for a short code
+// Completed days come from the url_stats_daily rollup, the current day from raw click events
+type DailyClickStats struct {
+	TenantID    string    `gorm:"primaryKey;size:64;default:''" json:"-"`
+	ShortCode   string    `gorm:"primaryKey;size:64" json:"-"`
+	Date        string    `gorm:"primaryKey;type:date" json:"date"` // UTC day as YYYY-MM-DD
+	Clicks      int64     `gorm:"not null;default:0" json:"clicks"`
+	UniqueIPs   int64     `gorm:"column:unique_ips;not null;default:0" json:"unique_ips"`
+	TopReferrer string    `gorm:"size:255" json:"top_referrer,omitempty"`
+	UpdatedAt   time.Time `json:"-"`
+}
+
+// TableName specifies the table name for GORM
+func (DailyClickStats) TableName() string {
+	return "url_stats_daily"
+}
+
+// Granularity is the bucket size of a click time series
+type Granularity string
+
+// Granularities accepted by GET /api/v1/urls/:shortCode/stats/timeseries
+const (
+	GranularityHour Granularity = "hour"
+	GranularityDay  Granularity = "day"
+	GranularityWeek Granularity = "week" // ISO weeks, starting on Monday
+)
+
+// Valid reports whether g is one of the supported granularities
+func (g Granularity) Valid() bool {
+	return g == GranularityHour || g == GranularityDay || g == GranularityWeek
+}
+
+// Truncate returns the start of the UTC bucket containing t
+func (g Granularity) Truncate(t time.Time) time.Time {
+	t = t.UTC()
+	switch g {
+	case GranularityHour:
+		return t.Truncate(time.Hour)
+	case GranularityWeek:
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	default:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	}
+}
+
+// Next returns the start of the bucket following the one that starts at start
+func (g Granularity) Next(start time.Time) time.Time {
+	switch g {
+	case GranularityHour:
+		return start.Add(time.Hour)
+	case GranularityWeek:
+		return start.AddDate(0, 0, 7)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
+
+// ClickBucket is the number of clicks in one time bucket of a series
+type ClickBucket struct {
+	BucketStart time.Time `json:"bucket_start"`
+	Clicks      int64     `json:"clicks"`
+}
+
+// TimeSeriesQuery selects a click time series; zero times fall back to a default range ending now
+type TimeSeriesQuery struct {
+	Granularity Granularity
+	From        time.Time
+	To          time.Time
+}
+
+// HeatmapCell is the number of clicks in one hour of one weekday, both in the heat map's time zone
+type HeatmapCell struct {
+	Weekday int // 0 is Sunday, like time.Weekday
+	Hour    int
+	Clicks  int64
+}
+
+// HeatmapQuery selects a click heat map; zero times fall back to a default range ending now
+type HeatmapQuery struct {
+	Location *time.Location // Time zone the weekdays and hours are counted in, nil for UTC
+	From     time.Time
+	To       time.Time
+}
+
+// ClickHeatmap is a link's clicks per weekday and hour of day, every cell present even without clicks
+type ClickHeatmap struct {
+	ShortCode string       `json:"short_code"`
+	TimeZone  string       `json:"tz"`
+	From      time.Time    `json:"from"`
+	To        time.Time    `json:"to"` // Exclusive
+	Total     int64        `json:"total"`
+	Clicks    [7][24]int64 `json:"clicks"` // Clicks[weekday][hour], weekday 0 is Sunday
+}
+
+// LoadTimeZone resolves an IANA time zone name such as "Europe/Berlin", UTC when name is empty
+// "Local" is refused since it depends on the server's configuration
+func LoadTimeZone(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil || name == "Local" {
+		return nil, NewFieldError("tz", "format", "tz must be an IANA time zone name such as Europe/Berlin")
+	}
+	return loc, nil
+}
@@ -0,0 +1,14 @@
+package domain
+
+// ClickIDParam is the query parameter redirects of links with attach_click_id add to the destination
+const ClickIDParam = "ush_click"
+
+// ConversionRequest redeems the click receipt a visitor arrived with
+type ConversionRequest struct {
+	ClickID string `json:"click_id" binding:"required"` // Value of the ush_click parameter
+}
+
+// ConversionResponse names the link a conversion was recorded for
+type ConversionResponse struct {
+	ShortCode string `json:"short_code"`
+}
@@ -0,0 +1,36 @@
+package domain
+
+// Limits on the creator metadata stored with a link; longer values are truncated
+const (
+	MaxCreatorUserAgentLength = 255
+	MaxCreatorOriginLength    = 255
+)
+
+// CreatorContext describes the request that created a link, kept for abuse investigations
+type CreatorContext struct {
+	IP        string
+	UserAgent string
+	Origin    string // Origin header, or the origin of the Referer when the client sent none
+}
+
+// CreatorInfo is the creator metadata of a link; only admins ever see it
+type CreatorInfo struct {
+	IP        string `json:"ip,omitempty"`
+	UserAgent string `json:"user_agent,omitempty"`
+	Origin    string `json:"origin,omitempty"`
+}
+
+// AdminURLInfoResponse is URLInfoResponse with the metadata of the request that created the link
+type AdminURLInfoResponse struct {
+	URLInfoResponse
+	Creator CreatorInfo `json:"creator"`
+}
+
+// CreatorLinksPage is one page of the links created from an IP address, newest first
+type CreatorLinksPage struct {
+	CreatorIP  string                  `json:"creator_ip"`
+	URLs       []*AdminURLInfoResponse `json:"urls"`
+	Limit      int                     `json:"limit"`
+	Offset     int                     `json:"offset"`
+	NextOffset *int                    `json:"next_offset,omitempty"` // Offset of the next page, nil on the last one
+}
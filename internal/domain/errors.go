@@ -25,19 +25,42 @@ var (
 	// ErrRateLimitExceeded is returned when rate limit is hit
 	ErrRateLimitExceeded = errors.New("rate limit exceeded")
 	
+	// ErrQuotaExceeded is returned when the daily creation quota for an IP or API key is used up
+	ErrQuotaExceeded = errors.New("daily quota exceeded")
+	
+	// ErrAPIKeyNotFound is returned when an API key id doesn't exist or was already revoked
+	ErrAPIKeyNotFound = errors.New("API key not found")
+	
 	// ErrDatabaseConnection is returned for database connectivity issues
 	ErrDatabaseConnection = errors.New("database connection error")
 	
+	// ErrInvalidClickID is returned for a click receipt that is malformed, wrongly signed or expired
+	ErrInvalidClickID = errors.New("invalid click ID")
+	
+	// ErrClickIDUsed is returned for a click receipt that was already redeemed
+	ErrClickIDUsed = errors.New("click ID already used")
+	
 	// ErrCacheUnavailable is returned when cache operations fail
 	ErrCacheUnavailable = errors.New("cache temporarily unavailable")
+	
+	// ErrDependencyUnavailable is returned when the database can't be reached or didn't answer in time
+	// The cause stays wrapped, see NewDependencyError
+	ErrDependencyUnavailable = errors.New("dependency unavailable")
 )
 
+// NewDependencyError marks err as an outage of a backing service rather than a bug
+// errors.Is matches both ErrDependencyUnavailable and the original cause
+func NewDependencyError(err error) error {
+	return fmt.Errorf("%w: %w", ErrDependencyUnavailable, err)
+}
+
 // AppError wraps errors with additional context for better debugging
 type AppError struct {
 	Err        error  // Original error
 	Message    string // User-friendly message
 	StatusCode int    // HTTP status code
 	Internal   bool   // Whether to log as internal error
+	Fields     []FieldError // Invalid request fields, sent as the errors array
 }
 
 // Error implements the error interface
@@ -83,6 +106,14 @@ func NewValidationError(message string) *AppError {
 	}
 }
 
+// NewFieldError creates a 400 validation error naming the request field that failed
+// field is the JSON name, e.g. "custom_alias" or "utm.source"; rule is a short, stable identifier clients can switch on
+func NewFieldError(field, rule, message string) *AppError {
+	err := NewValidationError(message)
+	err.Fields = []FieldError{{Field: field, Rule: rule, Message: message}}
+	return err
+}
+
 // NewInternalError creates a 500 internal server error
 func NewInternalError(err error) *AppError {
 	return &AppError{
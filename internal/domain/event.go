@@ -0,0 +1,46 @@
+package domain
+
+import (
+	"time"
+)
+
+// Link lifecycle event types written to the outbox
+const (
+	EventLinkCreated = "link.created"
+	EventLinkClicked = "link.clicked"
+	EventLinkDeleted = "link.deleted"
+)
+
+// EventLinkExpiring is sent to the expiry webhook, it never goes through the outbox
+const EventLinkExpiring = "link.expiring"
+
+// EventURLBroken is sent to the link check webhook when a destination turns broken; it never goes through the outbox
+const EventURLBroken = "url.broken"
+
+// OutboxEvent is a lifecycle event waiting in the events table to be relayed to the message broker
+// It is written in the same transaction as the change it describes, so events are never lost or invented
+type OutboxEvent struct {
+	ID          uint64     `gorm:"primaryKey" json:"id"`
+	Type        string     `gorm:"not null;size:32" json:"type"`
+	TenantID    string     `gorm:"not null;size:64;default:''" json:"tenant_id,omitempty"` // Tenant of the link, so consumers can route events per team
+	ShortCode   string     `gorm:"not null;size:64" json:"short_code"`                     // Message key, keeps one link's events in order
+	Payload     string     `gorm:"not null;type:jsonb" json:"payload"`
+	CreatedAt   time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	PublishedAt *time.Time `json:"published_at,omitempty"` // Set once the broker acknowledged the event
+}
+
+// TableName specifies the table name for GORM
+func (OutboxEvent) TableName() string {
+	return "events"
+}
+
+// LinkEventData is the payload of a lifecycle event
+// Fields that don't apply to an event type are omitted
+type LinkEventData struct {
+	OriginalURL string     `json:"original_url,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	Target      string     `json:"target,omitempty"`  // Rule that served a click
+	Variant     *int       `json:"variant,omitempty"` // A/B variant that served a click
+	Country     string     `json:"country,omitempty"`
+	Weight      int        `json:"weight,omitempty"` // Clicks this event stands for when clicks are sampled
+}
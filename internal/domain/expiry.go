@@ -0,0 +1,96 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ExpiryNever as expiry_days asks for a link that never expires; as a key's default_expiry_days
+// it makes never the default, and as its max_expiry_days it lifts the cap
+const ExpiryNever = -1
+
+// KeyExpiry is an API key's own expiry settings; 0 in either field falls back to the global setting
+type KeyExpiry struct {
+	DefaultDays int // Expiry of links created without one, ExpiryNever for never
+	MaxDays     int // Furthest ahead a requested expiry may be, ExpiryNever for no limit
+}
+
+// keyExpiryContextKey carries the expiry settings of the API key a request was made with
+type keyExpiryContextKey struct{}
+
+// ContextWithKeyExpiry attaches the expiry settings of the caller's API key
+func ContextWithKeyExpiry(ctx context.Context, expiry KeyExpiry) context.Context {
+	return context.WithValue(ctx, keyExpiryContextKey{}, expiry)
+}
+
+// KeyExpiryFromContext returns the settings set by ContextWithKeyExpiry, zero for anonymous callers
+func KeyExpiryFromContext(ctx context.Context) KeyExpiry {
+	expiry, _ := ctx.Value(keyExpiryContextKey{}).(KeyExpiry)
+	return expiry
+}
+
+// ExpiryPolicy decides when a new link expires
+type ExpiryPolicy struct {
+	DefaultDays int // Applied when the request sets no expiry, 0 = never
+	MaxDays     int // Furthest ahead an expiry may be requested, 0 = no limit
+}
+
+// NewExpiryPolicy combines the global URL_EXPIRATION_DAYS and MAX_EXPIRY_DAYS with a key's own settings
+func NewExpiryPolicy(defaultDays, maxDays int, key KeyExpiry) ExpiryPolicy {
+	policy := ExpiryPolicy{DefaultDays: max(defaultDays, 0), MaxDays: max(maxDays, 0)}
+	switch {
+	case key.DefaultDays == ExpiryNever:
+		policy.DefaultDays = 0
+	case key.DefaultDays > 0:
+		policy.DefaultDays = key.DefaultDays
+	}
+	switch {
+	case key.MaxDays == ExpiryNever:
+		policy.MaxDays = 0
+	case key.MaxDays > 0:
+		policy.MaxDays = key.MaxDays
+	}
+	return policy
+}
+
+// Expiry returns when a link requested with expiryDays or expiresAt expires, nil for never
+// expires_at is taken as given, to the second; expiry_days counts whole days from now, 0 applies the
+// default and ExpiryNever asks for no expiry, which a capped policy refuses. The default itself is the
+// operator's choice and isn't held to the cap.
+func (p ExpiryPolicy) Expiry(now time.Time, expiryDays int, expiresAt *time.Time) (*time.Time, error) {
+	if expiresAt != nil {
+		if expiryDays != 0 {
+			return nil, NewFieldError("expires_at", "excluded_with", "expires_at and expiry_days are mutually exclusive, send only one of them")
+		}
+		if !expiresAt.After(now) {
+			return nil, NewFieldError("expires_at", "future", fmt.Sprintf("expires_at must be in the future, got %s", expiresAt.UTC().Format(time.RFC3339)))
+		}
+		if p.MaxDays > 0 && expiresAt.After(now.AddDate(0, 0, p.MaxDays)) {
+			return nil, NewFieldError("expires_at", "max", fmt.Sprintf("expires_at must be at most %d days ahead", p.MaxDays))
+		}
+		expiry := expiresAt.UTC()
+		return &expiry, nil
+	}
+
+	days := expiryDays
+	switch {
+	case days == ExpiryNever:
+		if p.MaxDays > 0 {
+			return nil, NewFieldError("expiry_days", "max", fmt.Sprintf("links must expire within %d days, expiry_days -1 (never) is not allowed", p.MaxDays))
+		}
+		return nil, nil
+	case days < 0:
+		return nil, NewFieldError("expiry_days", "min", "expiry_days must be a number of days, 0 for the default or -1 for never")
+	case p.MaxDays > 0 && days > p.MaxDays:
+		return nil, NewFieldError("expiry_days", "max", fmt.Sprintf("expiry_days must be at most %d", p.MaxDays))
+	case days == 0:
+		days = p.DefaultDays
+	}
+	if days == 0 {
+		return nil, nil
+	}
+
+	expiry := now.AddDate(0, 0, days)
+	return &expiry, nil
+}
@@ -0,0 +1,18 @@
+package domain
+
+import "time"
+
+// PublicStats is what the public stats page of a link with public_stats shows anyone
+// It leaves out the destination, the creator and the referrers, so sharing it gives nothing else away.
+type PublicStats struct {
+	ShortCode   string              `json:"short_code"`
+	TotalClicks int64               `json:"total_clicks"`
+	CreatedAt   time.Time           `json:"created_at"`
+	Daily       []PublicDailyClicks `json:"daily"` // Clicks per UTC day, oldest first, today included
+}
+
+// PublicDailyClicks is one day of the public series, without the unique IPs and top referrer
+type PublicDailyClicks struct {
+	Date   string `json:"date"` // UTC day as YYYY-MM-DD
+	Clicks int64  `json:"clicks"`
+}
@@ -0,0 +1,18 @@
+package domain
+
+import (
+	"time"
+)
+
+// QuotaStatus describes the remaining daily creation quota after a create
+type QuotaStatus struct {
+	Limit     int64
+	Remaining int64
+	ResetAt   time.Time // Next midnight UTC
+}
+
+// NextQuotaReset returns the moment daily quotas reset, the next midnight UTC after now
+func NextQuotaReset(now time.Time) time.Time {
+	day := now.UTC()
+	return time.Date(day.Year(), day.Month(), day.Day()+1, 0, 0, 0, 0, time.UTC)
+}
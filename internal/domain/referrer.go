@@ -0,0 +1,77 @@
+package domain
+
+import (
+	"database/sql/driver"
+	"fmt"
+)
+
+// ReferrerPolicy controls what the destination learns about where a visitor came from
+// The empty policy sends no header, so the browser default applies
+type ReferrerPolicy string
+
+// Referrer policies a link can set; all but ReferrerPolicyBounce are sent as the Referrer-Policy header
+const (
+	ReferrerPolicyNone                        ReferrerPolicy = ""
+	ReferrerPolicyNoReferrer                  ReferrerPolicy = "no-referrer"
+	ReferrerPolicyNoReferrerWhenDowngrade     ReferrerPolicy = "no-referrer-when-downgrade"
+	ReferrerPolicyOrigin                      ReferrerPolicy = "origin"
+	ReferrerPolicyOriginWhenCrossOrigin       ReferrerPolicy = "origin-when-cross-origin"
+	ReferrerPolicySameOrigin                  ReferrerPolicy = "same-origin"
+	ReferrerPolicyStrictOrigin                ReferrerPolicy = "strict-origin"
+	ReferrerPolicyStrictOriginWhenCrossOrigin ReferrerPolicy = "strict-origin-when-cross-origin"
+	ReferrerPolicyUnsafeURL                   ReferrerPolicy = "unsafe-url"
+
+	// ReferrerPolicyBounce serves an HTML page that navigates with no-referrer instead of a Location header
+	// Some in-app browsers ignore Referrer-Policy on redirects but honour it on documents
+	ReferrerPolicyBounce ReferrerPolicy = "bounce"
+)
+
+// Validate rejects values outside the known policies
+func (p ReferrerPolicy) Validate() error {
+	switch p {
+	case ReferrerPolicyNone, ReferrerPolicyNoReferrer, ReferrerPolicyNoReferrerWhenDowngrade,
+		ReferrerPolicyOrigin, ReferrerPolicyOriginWhenCrossOrigin, ReferrerPolicySameOrigin,
+		ReferrerPolicyStrictOrigin, ReferrerPolicyStrictOriginWhenCrossOrigin, ReferrerPolicyUnsafeURL,
+		ReferrerPolicyBounce:
+		return nil
+	}
+	return fmt.Errorf("unknown referrer_policy %q", string(p))
+}
+
+// Header returns the Referrer-Policy header value, empty when none should be sent
+func (p ReferrerPolicy) Header() string {
+	if p == ReferrerPolicyBounce {
+		return string(ReferrerPolicyNoReferrer)
+	}
+	return string(p)
+}
+
+// Bounce reports whether the redirect is served as an HTML bounce page
+func (p ReferrerPolicy) Bounce() bool {
+	return p == ReferrerPolicyBounce
+}
+
+// Bounds of the per-link referrer counts kept on the urls row
+const (
+	// MaxReferrerCounts is how many referring hosts are counted by name
+	MaxReferrerCounts = 20
+
+	// OtherReferrers counts the hosts first seen after MaxReferrerCounts were already counted
+	OtherReferrers = "(other)"
+)
+
+// ReferrerCounts maps referring hosts to their clicks, stored as JSONB
+type ReferrerCounts map[string]int64
+
+// Value implements driver.Valuer so GORM writes the counts as JSON
+func (r ReferrerCounts) Value() (driver.Value, error) {
+	if len(r) == 0 {
+		return nil, nil
+	}
+	return jsonValue(r)
+}
+
+// Scan implements sql.Scanner for reading the JSON column back
+func (r *ReferrerCounts) Scan(value interface{}) error {
+	return scanJSON(value, r)
+}
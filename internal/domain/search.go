@@ -0,0 +1,14 @@
+package domain
+
+// URLSearchPage is one page of the admin search by destination, newest first
+type URLSearchPage struct {
+	Host       string                  `json:"host"` // As matched: lowercased, punycode, without port
+	Subdomains bool                    `json:"subdomains"`
+	PathPrefix string                  `json:"path_prefix,omitempty"`
+	Total      int64                   `json:"total"` // Matching links on all pages
+	URLs       []*AdminURLInfoResponse `json:"urls"`
+	ShortCodes []string                `json:"short_codes"` // Codes of this page, ready for the short_codes of POST /api/v1/urls/bulk-delete
+	Limit      int                     `json:"limit"`
+	Offset     int                     `json:"offset"`
+	NextOffset *int                    `json:"next_offset,omitempty"` // Offset of the next page, nil on the last one
+}
@@ -0,0 +1,28 @@
+package domain
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Snippets are a short link formatted for pasting into documents and tickets
+type Snippets struct {
+	Markdown string `json:"markdown"` // [label](short_url)
+	HTML     string `json:"html"`     // <a href="short_url">label</a>
+	Text     string `json:"text"`     // label: short_url
+}
+
+// LinkLabel is the text shown for a link: its title, else the destination's page title, else its host
+// Falls back to the destination as given when it has no host.
+func LinkLabel(title string, pageTitle *string, destination string) string {
+	if title = strings.TrimSpace(title); title != "" {
+		return title
+	}
+	if pageTitle != nil && strings.TrimSpace(*pageTitle) != "" {
+		return strings.TrimSpace(*pageTitle)
+	}
+	if parsed, err := url.Parse(destination); err == nil && parsed.Hostname() != "" {
+		return parsed.Hostname()
+	}
+	return destination
+}
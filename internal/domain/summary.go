@@ -0,0 +1,36 @@
+package domain
+
+import (
+	"time"
+)
+
+// SummaryWindow selects the period covered by the global statistics summary
+// The window always ends now and starts at midnight UTC, Days-1 days ago
+type SummaryWindow struct {
+	Days  int // Number of UTC days covered, including today
+	Limit int // Number of top links to return
+}
+
+// SummaryStats holds service-wide counters for the operator dashboard
+type SummaryStats struct {
+	TotalURLs    int64        `json:"total_urls"`
+	TotalClicks  int64        `json:"total_clicks"`
+	CreatedToday int64        `json:"created_today"`  // Links created since midnight UTC
+	Since        time.Time    `json:"since"`          // Start of the window
+	TopLinks     []TopLink    `json:"top_links"`      // Most clicked links within the window
+	CreatedByDay []DailyCount `json:"created_by_day"` // Links created per UTC day, oldest first, including empty days
+	GeneratedAt  time.Time    `json:"generated_at"`   // When the aggregates were computed; may lag behind while cached
+}
+
+// TopLink is a link ranked by the clicks it received within a window
+type TopLink struct {
+	ShortCode   string `json:"short_code"`
+	OriginalURL string `json:"original_url"`
+	Clicks      int64  `json:"clicks"`
+}
+
+// DailyCount is the number of items recorded on a single UTC day
+type DailyCount struct {
+	Date  string `json:"date"` // YYYY-MM-DD
+	Count int64  `json:"count"`
+}
@@ -0,0 +1,64 @@
+package domain
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// Target sends visitors matching its condition to an alternative destination
+// Exactly one of Platform or Country is set
+type Target struct {
+	Platform string `json:"platform,omitempty"` // ios, android, windows, macos or linux
+	Country  string `json:"country,omitempty"`  // ISO 3166-1 alpha-2 code, or EU
+	URL      string `json:"url"`
+}
+
+// Targets is the ordered rule set of a URL, stored as JSONB
+type Targets []Target
+
+// Value implements driver.Valuer so GORM writes the rules as JSON
+func (t Targets) Value() (driver.Value, error) {
+	if len(t) == 0 {
+		return nil, nil
+	}
+	return jsonValue(t)
+}
+
+// Scan implements sql.Scanner for reading the JSON column back
+func (t *Targets) Scan(value interface{}) error {
+	return scanJSON(value, t)
+}
+
+// jsonValue encodes v for a JSON/JSONB column
+func jsonValue(v interface{}) (driver.Value, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// scanJSON decodes a JSON/JSONB column into dst, leaving it untouched for NULL
+func scanJSON(value interface{}, dst interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("unsupported type %T for JSON column", value)
+	}
+	return json.Unmarshal(data, dst)
+}
+
+// Visitor describes the request being redirected, as seen by the rule engine
+type Visitor struct {
+	IP        string
+	UserAgent string
+	Country   string // Resolved from GeoIP; empty when unknown
+	Referrer  string // Raw Referer header, reduced to its host before it is stored
+}
@@ -0,0 +1,48 @@
+package domain
+
+import (
+	"context"
+	"regexp"
+)
+
+// DefaultTenant owns the links of callers without a tenant: the env keys, keys issued without one and anonymous callers
+const DefaultTenant = ""
+
+// MaxTenantIDLength is the size of the tenant_id columns
+const MaxTenantIDLength = 64
+
+// tenantIDPattern keeps tenant ids usable in cache keys and TENANT_DOMAINS entries
+var tenantIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// IsValidTenantID reports whether id can name a tenant; the default tenant has no id to validate
+func IsValidTenantID(id string) bool {
+	return len(id) <= MaxTenantIDLength && tenantIDPattern.MatchString(id)
+}
+
+// tenantContextKey carries the tenant whose links the repository queries run with ctx may see
+type tenantContextKey struct{}
+
+// ContextWithTenant limits the repository queries run with ctx to tenant's links and stamps new rows with it
+// Contexts without a tenant, such as the background jobs', see the links of every tenant
+func ContextWithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantContextKey{}, tenant)
+}
+
+// ContextWithoutTenant lifts the tenant of ctx, e.g. to check a short code against every tenant's links
+func ContextWithoutTenant(ctx context.Context) context.Context {
+	return context.WithValue(ctx, tenantContextKey{}, nil)
+}
+
+// TenantFromContext returns the tenant set by ContextWithTenant and whether ctx is limited to one
+func TenantFromContext(ctx context.Context) (string, bool) {
+	tenant, ok := ctx.Value(tenantContextKey{}).(string)
+	return tenant, ok
+}
+
+// CopyTenant limits ctx to the tenant of from, if from has one, for work detached from a request
+func CopyTenant(ctx, from context.Context) context.Context {
+	if tenant, ok := TenantFromContext(from); ok {
+		return ContextWithTenant(ctx, tenant)
+	}
+	return ctx
+}
@@ -8,16 +8,54 @@ import (
 // This is the core domain entity that models our business concept
 type URL struct {
 	ID           uint      `gorm:"primaryKey" json:"id"`
-	ShortCode    string    `gorm:"uniqueIndex;not null;size:12" json:"short_code"`
+	TenantID     string    `gorm:"not null;size:64;default:'';index" json:"tenant_id,omitempty"` // Set from the creator's API key, empty for the default tenant
+	CodeScope    string    `gorm:"not null;size:64;default:'';uniqueIndex:idx_urls_code_scope_short_code,priority:1" json:"-"` // Namespace the code is unique in: the tenant with TENANT_SCOPED_CODES, otherwise empty
+	ShortCode    string    `gorm:"not null;size:64;uniqueIndex:idx_urls_code_scope_short_code,priority:2" json:"short_code"`
 	OriginalURL  string    `gorm:"not null;type:text" json:"original_url"`
+	URLHash      string    `gorm:"size:64;index" json:"-"` // Hex SHA-256 of OriginalURL, set by the repository for the dedup lookup
+	DestinationHost string `gorm:"not null;size:255;default:'';index" json:"-"` // Lowercased host of OriginalURL without port, set by the repository for the admin search
+	SubmittedURL *string   `gorm:"type:text" json:"submitted_url,omitempty"` // Link on another shortener that OriginalURL was resolved from
+	Title        string    `gorm:"size:200" json:"title,omitempty"` // Owner's name for the link, plain text
+	Description  string    `gorm:"type:text" json:"description,omitempty"` // Owner's notes on the link, plain text
 	CreatedAt    time.Time `gorm:"autoCreateTime" json:"created_at"`
 	UpdatedAt    time.Time `gorm:"autoUpdateTime" json:"updated_at"`
 	ExpiresAt    *time.Time `gorm:"index" json:"expires_at,omitempty"` // Nullable for non-expiring URLs
 	ClickCount   int64     `gorm:"default:0" json:"click_count"`
+	BotClicks    int64     `gorm:"default:0" json:"bot_clicks"` // Redirects of crawlers, not included in ClickCount
+	PrefetchHits int64     `gorm:"default:0" json:"prefetch_hits"` // Link previews answered with the confirm page, not included in ClickCount
+	FilteredClicks int64   `gorm:"default:0" json:"filtered_clicks"` // Repeat clicks within CLICK_DEDUP_WINDOW, not included in ClickCount
+	Conversions  int64     `gorm:"not null;default:0" json:"conversions"` // Click receipts redeemed with POST /api/v1/conversions
 	LastAccessAt *time.Time `json:"last_access_at,omitempty"`
+	LastReferrer string    `gorm:"size:255" json:"-"` // Host of the latest click's Referer, reported by GetStats
+	ReferrerCounts ReferrerCounts `gorm:"type:jsonb" json:"-"` // Clicks per referring host, bounded by MaxReferrerCounts
+	StatsResetAt *time.Time `json:"-"` // Last stats reset, part of the key of cached click stats
 	CreatorIP    string    `gorm:"size:45" json:"-"` // IPv6 max length, not exposed in JSON
+	CreatorUserAgent string `gorm:"size:255" json:"-"` // User-Agent of the create call, truncated, not exposed in JSON
+	CreatorOrigin string   `gorm:"size:255" json:"-"` // Origin or Referer origin of the create call, not exposed in JSON
 	IsActive     bool      `gorm:"default:true;index" json:"is_active"`
 	CustomAlias  bool      `gorm:"default:false" json:"custom_alias"` // User-defined vs auto-generated
+	RequiresInterstitial bool `gorm:"default:false" json:"requires_interstitial"` // Show warning page before redirecting
+	ConfirmBeforeRedirect bool `gorm:"default:false" json:"confirm_before_redirect"` // Show the confirm page to link prefetchers instead of redirecting them
+	UTM          UTMParams `gorm:"embedded;embeddedPrefix:utm_" json:"utm"` // Appended to the destination on redirect
+	Targets      Targets   `gorm:"type:jsonb" json:"targets,omitempty"` // Conditional destinations, first match wins
+	Variants     Variants  `gorm:"type:jsonb" json:"variants,omitempty"` // Weighted A/B split of the default destination
+	StickyVariants bool    `gorm:"default:false" json:"sticky_variants"` // Same visitor always gets the same variant
+	ForwardQuery bool      `gorm:"default:false" json:"forward_query"` // Pass the short link's query string on to the destination
+	AttachClickID bool     `gorm:"not null;default:false" json:"attach_click_id"` // Append a signed ush_click receipt to the destination for conversion tracking
+	PublicStats  bool      `gorm:"not null;default:false" json:"public_stats"` // Anyone may view the click counts at /:shortCode/stats
+	ReferrerPolicy ReferrerPolicy `gorm:"size:32" json:"referrer_policy,omitempty"` // Referrer-Policy sent with the redirect
+	DeepLink     string    `gorm:"not null;type:text;default:''" json:"deep_link,omitempty"` // Opened instead of the destination on iOS and Android
+	IOSFallbackURL string  `gorm:"column:ios_fallback_url;not null;type:text;default:''" json:"ios_fallback_url,omitempty"` // Where iOS visitors go when the app isn't installed
+	AndroidFallbackURL string `gorm:"not null;type:text;default:''" json:"android_fallback_url,omitempty"` // Where Android visitors go when the app isn't installed
+	Bundle       BundleItems `gorm:"type:jsonb" json:"bundle,omitempty"` // Members listed on the landing page instead of redirecting
+	PageTitle    *string   `gorm:"type:text" json:"page_title"` // <title> of the destination, null until fetched or when the fetch failed
+	FaviconURL   *string   `gorm:"type:text" json:"favicon_url"` // Icon of the destination page
+	MetadataFetchedAt *time.Time `json:"metadata_fetched_at,omitempty"` // Last enrichment attempt, successful or not
+	ManagementTokenHash *string `gorm:"size:64" json:"-"` // SHA-256 of the creator's token, nil for links created before tokens
+	ExpiryNotifiedAt *time.Time `json:"expiry_notified_at,omitempty"` // When the owner was warned of the upcoming expiry, nil if not yet
+	LastCheckedAt *time.Time `json:"last_checked_at,omitempty"` // Last link check of the destination, nil if never checked
+	LastStatusCode *int    `json:"last_status_code,omitempty"` // Status the destination answered the last check with, nil when it didn't answer
+	IsBroken     bool      `gorm:"default:false" json:"is_broken"` // The destination answered 404 or 410, or its host stopped resolving
 }
 
 // TableName specifies the table name for GORM
@@ -33,6 +71,16 @@ func (u *URL) IsExpired() bool {
 	return time.Now().After(*u.ExpiresAt)
 }
 
+// IsBundle reports whether the link serves a landing page instead of redirecting
+func (u *URL) IsBundle() bool {
+	return len(u.Bundle) > 0
+}
+
+// Destination returns the URL visitors are redirected to, with UTM parameters applied
+func (u *URL) Destination() string {
+	return u.UTM.AppendTo(u.OriginalURL)
+}
+
 // IncrementClickCount safely increments the click counter
 // This should be called atomically in the repository layer
 func (u *URL) IncrementClickCount() {
@@ -41,23 +89,225 @@ func (u *URL) IncrementClickCount() {
 	u.LastAccessAt = &now
 }
 
+// Stats returns the statistics kept on the link itself, the way every repository's GetStats reports them
+// Click counts per variant, target and day come from click events and are filled in by the service
+func (u *URL) Stats() *URLStats {
+	stats := &URLStats{
+		ShortCode:      u.ShortCode,
+		OriginalURL:    u.OriginalURL,
+		TotalClicks:    u.ClickCount,
+		BotClicks:      u.BotClicks,
+		PrefetchHits:   u.PrefetchHits,
+		FilteredClicks: u.FilteredClicks,
+		Conversions:    u.Conversions,
+		CreatedAt:      u.CreatedAt,
+		UpdatedAt:      u.UpdatedAt,
+		LastAccessAt:   u.LastAccessAt,
+		ExpiresAt:      u.ExpiresAt,
+		IsActive:       u.IsActive,
+		LastReferrer:   u.LastReferrer,
+		ReferrerCounts: u.ReferrerCounts,
+	}
+
+	for i, variant := range u.Variants {
+		stats.Variants = append(stats.Variants, VariantStats{Index: i, URL: variant.URL, Weight: variant.Weight})
+	}
+
+	// Calculate days remaining if URL has expiration
+	// The check comes before rounding, which would turn the first day after expiry into 0 days left
+	if u.ExpiresAt != nil {
+		if until := time.Until(*u.ExpiresAt); until >= 0 {
+			remaining := int(until.Hours() / 24)
+			stats.DaysRemaining = &remaining
+		}
+	}
+
+	return stats
+}
+
 // URLStats represents aggregated statistics for a shortened URL
 type URLStats struct {
 	ShortCode     string    `json:"short_code"`
 	OriginalURL   string    `json:"original_url"`
 	TotalClicks   int64     `json:"total_clicks"`
+	BotClicks     int64     `json:"bot_clicks"` // Clicks by crawlers, not included in TotalClicks
+	PrefetchHits  int64     `json:"prefetch_hits"` // Prefetches shown the confirm page, not included in TotalClicks
+	FilteredClicks int64    `json:"filtered_clicks"` // Repeat clicks of one visitor within CLICK_DEDUP_WINDOW, not included in TotalClicks
+	Conversions   int64     `json:"conversions"` // Click receipts redeemed, see attach_click_id
+	ConversionRate float64  `json:"conversion_rate"` // Conversions per counted click, 0 before the first click
 	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"-"` // Versions conditional GETs, never serialized
 	LastAccessAt  *time.Time `json:"last_access_at,omitempty"`
 	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
 	IsActive      bool      `json:"is_active"`
 	DaysRemaining *int      `json:"days_remaining,omitempty"` // Calculated field
+	LastReferrer  string    `json:"last_referrer,omitempty"` // Host of the latest click's Referer
+	ReferrerCounts ReferrerCounts `json:"referrer_counts,omitempty"` // Clicks per referring host, later hosts pooled under "(other)"
+	ClicksByTarget map[string]int64 `json:"clicks_by_target,omitempty"` // Clicks per matched target rule
+	Variants      []VariantStats `json:"variants,omitempty"` // Clicks and ratio per A/B variant
+	Daily         []DailyClickStats `json:"daily,omitempty"` // Clicks per UTC day, oldest first, today included
+	Today         *VisitStats `json:"today,omitempty"` // Redirects counted by the cache today, absent without Redis
 }
 
+// VisitStats are the visits the redirect cache counted for a link on one UTC day
+// Unlike the click counts they include bots, prefetches and repeat clicks, and are kept for two days only
+type VisitStats struct {
+	Visits   int64 `json:"visits"`
+	Visitors int64 `json:"visitors"` // Distinct IP and User-Agent pairs, approximated by a HyperLogLog
+}
+
+// URLFilter narrows down which URLs are returned by bulk read operations
+// Zero values mean "no constraint"
+type URLFilter struct {
+	ShortCodes      []string   // Only these short codes
+	CreatorIP       string     // Only links created from this address
+	DestinationHost string     // Only links whose destination is on this host, ignoring the port
+	IncludeSubdomains bool     // DestinationHost also matches its subdomains, as "*.spam.example" does
+	PathPrefix      string     // Only links whose destination path starts with this, case-sensitive
+	CreatedFrom     *time.Time // Inclusive lower bound on created_at
+	CreatedTo       *time.Time // Exclusive upper bound on created_at
+	Search          string     // Only links whose short code, destination, title or description contain this, case-insensitive
+	Broken          *bool      // Only links whose last link check did, or didn't, find the destination broken
+}
+
+// IsEmpty reports whether the filter has no constraint and so matches every link
+func (f URLFilter) IsEmpty() bool {
+	return len(f.ShortCodes) == 0 && f.CreatorIP == "" && f.DestinationHost == "" && f.PathPrefix == "" &&
+		f.CreatedFrom == nil && f.CreatedTo == nil && f.Search == "" && f.Broken == nil
+}
+
+// LinkCheck is the outcome of one check of a link's destination
+type LinkCheck struct {
+	CheckedAt  time.Time
+	StatusCode *int // Nil when the destination didn't answer
+	Broken     bool
+}
+
+// URLPage is one page of a link listing, newest first
+type URLPage struct {
+	URLs       []*URLInfoResponse `json:"urls"`
+	Limit      int                `json:"limit"`
+	Offset     int                `json:"offset"`
+	NextOffset *int               `json:"next_offset,omitempty"` // Offset of the next page, nil on the last one
+}
+
+// ExportRecord represents a single row in a URL export
+type ExportRecord struct {
+	ShortCode    string     `json:"short_code"`
+	OriginalURL  string     `json:"original_url"`
+	CreatedAt    time.Time  `json:"created_at"`
+	ExpiresAt    *time.Time `json:"expires_at"`
+	ClickCount   int64      `json:"click_count"`
+	LastAccessAt *time.Time `json:"last_access_at"`
+	IsActive     bool       `json:"is_active"`
+	PageTitle    *string    `json:"page_title"`
+	FaviconURL   *string    `json:"favicon_url"`
+	Title        string     `json:"title"`
+	Description  string     `json:"description"`
+}
+
+// NewExportRecord builds an export row from a URL entity
+func NewExportRecord(u *URL) ExportRecord {
+	return ExportRecord{
+		ShortCode:    u.ShortCode,
+		OriginalURL:  u.OriginalURL,
+		CreatedAt:    u.CreatedAt,
+		ExpiresAt:    u.ExpiresAt,
+		ClickCount:   u.ClickCount,
+		LastAccessAt: u.LastAccessAt,
+		IsActive:     u.IsActive,
+		PageTitle:    u.PageTitle,
+		FaviconURL:   u.FaviconURL,
+		Title:        u.Title,
+		Description:  u.Description,
+	}
+}
+
+// Limits on the owner's notes, in characters after HTML is stripped
+const (
+	MaxTitleLength       = 200
+	MaxDescriptionLength = 1000
+)
+
 // CreateURLRequest represents the request payload for creating a short URL
 type CreateURLRequest struct {
-	URL         string `json:"url" binding:"required"`          // Original URL to shorten
+	URL         string `json:"url" binding:"required_without=Bundle"` // Original URL to shorten
 	CustomAlias string `json:"custom_alias,omitempty"`          // Optional custom short code
 	ExpiryDays  int    `json:"expiry_days,omitempty"`           // Optional expiration in days
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`         // Optional exact expiration (RFC3339), instead of expiry_days
+	UTM         *UTMParams `json:"utm,omitempty"`                // Optional UTM parameters added on redirect
+	Targets     []Target   `json:"targets,omitempty"`            // Optional platform/country-specific destinations
+	Variants    []Variant  `json:"variants,omitempty"`           // Optional weighted A/B split
+	StickyVariants bool    `json:"sticky_variants,omitempty"`    // Pick the variant from a hash of IP and User-Agent
+	ConfirmBeforeRedirect bool `json:"confirm_before_redirect,omitempty"` // Show link prefetchers the confirm page instead of redirecting
+	ForwardQuery *bool     `json:"forward_query,omitempty"`      // Pass incoming query parameters on; nil uses FORWARD_QUERY_DEFAULT
+	AttachClickID bool     `json:"attach_click_id,omitempty"`    // Append a signed ush_click receipt for POST /api/v1/conversions on redirect
+	PublicStats bool       `json:"public_stats,omitempty"`       // Serve the click counts to anyone at /:shortCode/stats
+	ReferrerPolicy ReferrerPolicy `json:"referrer_policy,omitempty"` // Optional Referrer-Policy, or "bounce" to scrub it with an HTML page
+	DeepLink    string       `json:"deep_link,omitempty"`        // Optional app link for mobile visitors, https or a custom scheme such as myapp://
+	IOSFallbackURL string    `json:"ios_fallback_url,omitempty"` // Optional page for iOS visitors without the app; needs deep_link
+	AndroidFallbackURL string `json:"android_fallback_url,omitempty"` // Optional page for Android visitors without the app; needs deep_link
+	Title       string       `json:"title,omitempty"`            // Optional name for the link; HTML is stripped
+	Description string       `json:"description,omitempty"`      // Optional notes on the link; HTML is stripped
+	Bundle      []BundleItem `json:"bundle,omitempty"`           // Create a landing page listing these links instead of a redirect
+	DryRun      bool         `json:"dry_run,omitempty"`          // Validate and report the outcome without saving anything
+	IncludeSnippets bool     `json:"include_snippets,omitempty"` // Add ready-to-paste snippets to the response, like ?include_snippets=true
+}
+
+// UpdateURLRequest represents a partial update of an existing short URL
+// Nil fields are left unchanged
+type UpdateURLRequest struct {
+	RequiresInterstitial *bool `json:"requires_interstitial,omitempty"`
+	ConfirmBeforeRedirect *bool `json:"confirm_before_redirect,omitempty"`
+	UTM                  *UTMParams `json:"utm,omitempty"` // Replaces all UTM parameters; empty fields clear them
+	Targets              *[]Target  `json:"targets,omitempty"` // Replaces the rule set; an empty list removes it
+	Variants             *[]Variant `json:"variants,omitempty"` // Replaces the A/B split; an empty list removes it
+	StickyVariants       *bool      `json:"sticky_variants,omitempty"`
+	ForwardQuery         *bool      `json:"forward_query,omitempty"`
+	AttachClickID        *bool      `json:"attach_click_id,omitempty"`
+	PublicStats          *bool      `json:"public_stats,omitempty"`
+	ReferrerPolicy       *ReferrerPolicy `json:"referrer_policy,omitempty"` // An empty string removes the policy
+	DeepLink             *string    `json:"deep_link,omitempty"` // An empty string removes the deep link and its fallbacks
+	IOSFallbackURL       *string    `json:"ios_fallback_url,omitempty"` // An empty string removes the fallback
+	AndroidFallbackURL   *string    `json:"android_fallback_url,omitempty"` // An empty string removes the fallback
+	Title                *string    `json:"title,omitempty"` // An empty string clears the title
+	Description          *string    `json:"description,omitempty"` // An empty string clears the description
+}
+
+// Where an expiry extension starts counting
+const (
+	ExtendFromExpiry = "expiry" // Add the days to the current expiry
+	ExtendFromNow    = "now"    // Set the expiry to now plus the days
+)
+
+// ExtendURLRequest pushes out the expiry of a link
+type ExtendURLRequest struct {
+	Days        int    `json:"days" binding:"required"`
+	From        string `json:"from,omitempty"`         // ExtendFromExpiry (default) or ExtendFromNow
+	AllowRevive bool   `json:"allow_revive,omitempty"` // Reactivate the link if it already expired
+}
+
+// CloneURLRequest creates a new link with the settings of an existing one
+// Empty fields keep the source's destination and give the clone a generated code
+type CloneURLRequest struct {
+	URL         string `json:"url,omitempty"`          // New destination instead of the source's
+	CustomAlias string `json:"custom_alias,omitempty"` // Optional custom short code for the clone
+}
+
+// RedirectDecision describes how a short link should be served to a visitor
+type RedirectDecision struct {
+	ShortCode    string
+	OriginalURL  string
+	Target       string // Rule that selected OriginalURL
+	Variant      *int   // Index of the A/B variant served, nil when the link has no split
+	Conditional  bool   // Destination depends on the visitor, so it must not be cached downstream
+	Interstitial bool   // Show the warning page instead of redirecting immediately
+	ForwardQuery bool   // Merge the request's query string into OriginalURL before redirecting
+	ClickID      string // Receipt added to OriginalURL as ush_click, after the forwarded query; empty for none
+	ReferrerPolicy ReferrerPolicy // Sent as Referrer-Policy; the bounce policy replaces the Location redirect
+	App          *AppLaunch   // Try this deep link from a page first; nil to redirect
+	Bundle       []BundleItem // Members to list on the landing page; empty for redirects
+	ExpiresAt    *time.Time   // When the link stops working, nil when it never expires
 }
 
 // CreateURLResponse represents the response after creating a short URL
@@ -67,6 +317,55 @@ type CreateURLResponse struct {
 	OriginalURL string    `json:"original_url"`
 	CreatedAt   time.Time `json:"created_at"`
 	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	Bundle      []BundleItem `json:"bundle,omitempty"` // Members of a bundle with their generated short codes
+	ManagementToken string `json:"management_token,omitempty"` // Lets the creator edit or delete the link; only returned once
+	ClonedFrom  string       `json:"cloned_from,omitempty"` // Short code whose settings the link was copied from
+	Deduplicated bool        `json:"deduplicated"` // The destination was already shortened and the existing link is returned
+	DryRun      bool         `json:"dry_run,omitempty"` // Nothing was saved; short_code is empty when it would be generated at random
+	Snippets    *Snippets    `json:"snippets,omitempty"` // Only with include_snippets
+	Label       string       `json:"-"` // Text the snippets show for the link, see LinkLabel
+	Quota       *QuotaStatus `json:"-"` // Tightest daily quota that applied, sent as response headers
+}
+
+// URLInfoResponse is the public view of a short link returned by GET /api/v1/urls/:shortCode
+// Fields are listed explicitly so new model columns never leak into the API by accident
+type URLInfoResponse struct {
+	ShortCode            string       `json:"short_code"`
+	ShortURL             string       `json:"short_url"`
+	OriginalURL          string       `json:"original_url"`
+	DisplayURL           string       `json:"display_url"` // original_url with an IDN host in Unicode, for display only
+	SubmittedURL         *string      `json:"submitted_url,omitempty"` // Shortener link original_url was resolved from
+	Title                string       `json:"title,omitempty"`
+	Description          string       `json:"description,omitempty"`
+	CreatedAt            time.Time    `json:"created_at"`
+	UpdatedAt            time.Time    `json:"-"` // Versions conditional GETs, never serialized
+	ExpiresAt            *time.Time   `json:"expires_at,omitempty"`
+	IsExpired            bool         `json:"is_expired"`
+	IsActive             bool         `json:"is_active"`
+	ClickCount           int64        `json:"click_count"`
+	LastAccessAt         *time.Time   `json:"last_access_at,omitempty"`
+	CustomAlias          bool         `json:"custom_alias"`
+	RequiresInterstitial bool         `json:"requires_interstitial"`
+	ConfirmBeforeRedirect bool        `json:"confirm_before_redirect"`
+	UTM                  UTMParams    `json:"utm"`
+	Targets              Targets      `json:"targets,omitempty"`
+	Variants             Variants     `json:"variants,omitempty"`
+	StickyVariants       bool         `json:"sticky_variants"`
+	ForwardQuery         bool         `json:"forward_query"`
+	AttachClickID        bool         `json:"attach_click_id"`
+	PublicStats          bool         `json:"public_stats"`
+	ReferrerPolicy       ReferrerPolicy `json:"referrer_policy,omitempty"`
+	DeepLink             string       `json:"deep_link,omitempty"`
+	IOSFallbackURL       string       `json:"ios_fallback_url,omitempty"`
+	AndroidFallbackURL   string       `json:"android_fallback_url,omitempty"`
+	Bundle               BundleItems  `json:"bundle,omitempty"`
+	PageTitle            *string      `json:"page_title"`
+	FaviconURL           *string      `json:"favicon_url"`
+	ExpiryNotifiedAt     *time.Time   `json:"expiry_notified_at,omitempty"` // When the expiry webhook was sent for this link
+	LastCheckedAt        *time.Time   `json:"last_checked_at,omitempty"` // Last link check of the destination
+	LastStatusCode       *int         `json:"last_status_code,omitempty"` // Status of the last check, omitted when the destination didn't answer
+	IsBroken             bool         `json:"is_broken"`
+	Snippets             *Snippets    `json:"snippets,omitempty"` // Only with include_snippets
 }
 
 // ErrorResponse represents a standard error response
@@ -74,6 +373,27 @@ type ErrorResponse struct {
 	Error   string `json:"error"`
 	Message string `json:"message,omitempty"`
 	Code    int    `json:"code"`
+	Suggestions []string `json:"suggestions,omitempty"` // Free aliases offered when the requested one is taken
+	Errors      []FieldError `json:"errors,omitempty"` // Every invalid field of the request body
+}
+
+// FieldError describes one invalid field of a request body
+type FieldError struct {
+	Field   string `json:"field"`   // JSON name, nested fields joined with dots such as utm.source
+	Rule    string `json:"rule"`    // The check that failed, e.g. required, max, format or unknown
+	Message string `json:"message"`
+}
+
+// ProblemDetails is the RFC 7807 form of an ErrorResponse, sent as application/problem+json
+type ProblemDetails struct {
+	Type     string `json:"type"`     // Entry of the error value in the error catalog
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance"` // Request path, with the request ID as fragment
+	Error    string `json:"error"`    // Extension member: the ErrorResponse error value, for clients switching formats
+	Suggestions []string `json:"suggestions,omitempty"`
+	Errors      []FieldError `json:"errors,omitempty"`
 }
 
 // HealthResponse represents health check response
@@ -0,0 +1,85 @@
+package domain
+
+import (
+	"net/url"
+	"strings"
+)
+
+// UTMParams holds the campaign parameters appended to a destination at redirect time
+// Empty fields are not added, and set fields override the same parameter in the destination
+type UTMParams struct {
+	Source   string `gorm:"size:255" json:"source,omitempty" binding:"max=255"`
+	Medium   string `gorm:"size:255" json:"medium,omitempty" binding:"max=255"`
+	Campaign string `gorm:"size:255" json:"campaign,omitempty" binding:"max=255"`
+	Term     string `gorm:"size:255" json:"term,omitempty" binding:"max=255"`
+	Content  string `gorm:"size:255" json:"content,omitempty" binding:"max=255"`
+}
+
+// IsZero reports whether no UTM parameter is set
+func (p UTMParams) IsZero() bool {
+	return p == UTMParams{}
+}
+
+// AppendTo returns destination with the UTM parameters added to its query string
+// Other query parameters are kept byte for byte and the fragment stays at the end
+func (p UTMParams) AppendTo(destination string) string {
+	if p.IsZero() {
+		return destination
+	}
+
+	u, err := url.Parse(destination)
+	if err != nil {
+		// Destinations are validated on creation; leave anything unparseable untouched
+		return destination
+	}
+
+	params := p.pairs()
+	overridden := make(map[string]bool, len(params))
+	for _, kv := range params {
+		overridden[kv[0]] = true
+	}
+
+	// Drop the parameters we are about to override, keeping everything else as written
+	var parts []string
+	for _, part := range strings.Split(u.RawQuery, "&") {
+		if part == "" {
+			continue
+		}
+		key := part
+		if i := strings.IndexByte(part, '='); i >= 0 {
+			key = part[:i]
+		}
+		if unescaped, err := url.QueryUnescape(key); err == nil {
+			key = unescaped
+		}
+		if !overridden[key] {
+			parts = append(parts, part)
+		}
+	}
+
+	for _, kv := range params {
+		parts = append(parts, kv[0]+"="+url.QueryEscape(kv[1]))
+	}
+
+	u.RawQuery = strings.Join(parts, "&")
+	return u.String()
+}
+
+// pairs lists the set parameters in a stable order
+func (p UTMParams) pairs() [][2]string {
+	all := [][2]string{
+		{"utm_source", p.Source},
+		{"utm_medium", p.Medium},
+		{"utm_campaign", p.Campaign},
+		{"utm_term", p.Term},
+		{"utm_content", p.Content},
+	}
+
+	set := all[:0]
+	for _, kv := range all {
+		if kv[1] != "" {
+			set = append(set, kv)
+		}
+	}
+	return set
+}
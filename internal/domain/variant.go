@@ -0,0 +1,36 @@
+package domain
+
+import (
+	"database/sql/driver"
+)
+
+// Variant is one destination of an A/B split
+type Variant struct {
+	URL    string `json:"url"`
+	Weight int    `json:"weight"` // Share of traffic in percent; weights of a link sum to 100
+}
+
+// Variants is the split configuration of a URL, stored as JSONB
+type Variants []Variant
+
+// Value implements driver.Valuer so GORM writes the variants as JSON
+func (v Variants) Value() (driver.Value, error) {
+	if len(v) == 0 {
+		return nil, nil
+	}
+	return jsonValue(v)
+}
+
+// Scan implements sql.Scanner for reading the JSON column back
+func (v *Variants) Scan(value interface{}) error {
+	return scanJSON(value, v)
+}
+
+// VariantStats reports how often a variant was served
+type VariantStats struct {
+	Index  int     `json:"index"`
+	URL    string  `json:"url"`
+	Weight int     `json:"weight"`
+	Clicks int64   `json:"clicks"`
+	Ratio  float64 `json:"ratio"` // Share of all variant clicks, 0..1
+}
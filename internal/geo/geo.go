@@ -0,0 +1,116 @@
+// Package geo resolves visitor IP addresses to ISO 3166-1 alpha-2 country codes
+package geo
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Resolver looks up the country of an IP address
+// Implementations return an empty string when the country is unknown
+type Resolver interface {
+	Country(ip string) string
+}
+
+// network is one row of a CIDR table
+type network struct {
+	start   net.IP // First address, always 16 bytes
+	ipNet   *net.IPNet
+	country string
+}
+
+// CIDRResolver resolves countries from a table of non-overlapping networks
+// The table format matches the country exports of common GeoIP providers
+type CIDRResolver struct {
+	networks []network
+}
+
+// LoadCIDRFile reads a CIDR table from disk
+func LoadCIDRFile(path string) (*CIDRResolver, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open GeoIP table: %w", err)
+	}
+	defer f.Close()
+
+	return ParseCIDRTable(f)
+}
+
+// ParseCIDRTable parses "network,country" lines, skipping blanks and # comments
+func ParseCIDRTable(r io.Reader) (*CIDRResolver, error) {
+	var networks []network
+
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		cidr, country, ok := strings.Cut(text, ",")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected network,country", line)
+		}
+
+		_, ipNet, err := net.ParseCIDR(strings.TrimSpace(cidr))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		country = strings.ToUpper(strings.TrimSpace(country))
+		if len(country) != 2 {
+			return nil, fmt.Errorf("line %d: invalid country code %q", line, country)
+		}
+
+		networks = append(networks, network{start: ipNet.IP.To16(), ipNet: ipNet, country: country})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read GeoIP table: %w", err)
+	}
+
+	sort.Slice(networks, func(i, j int) bool {
+		return bytes.Compare(networks[i].start, networks[j].start) < 0
+	})
+
+	return &CIDRResolver{networks: networks}, nil
+}
+
+// Country finds the network containing ip with a binary search
+func (r *CIDRResolver) Country(ip string) string {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return ""
+	}
+	addr = addr.To16()
+
+	// Last network starting at or before addr
+	i := sort.Search(len(r.networks), func(i int) bool {
+		return bytes.Compare(r.networks[i].start, addr) > 0
+	}) - 1
+	if i < 0 || !r.networks[i].ipNet.Contains(addr) {
+		return ""
+	}
+
+	return r.networks[i].country
+}
+
+// ValidCountryCode reports whether code looks like an ISO 3166-1 alpha-2 code
+func ValidCountryCode(code string) bool {
+	if len(code) != 2 {
+		return false
+	}
+	for _, c := range code {
+		if c < 'A' || c > 'Z' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,27 @@
+package geo
+
+// Region codes usable in place of a single country
+const RegionEU = "EU"
+
+// euMembers lists the member states of the European Union
+var euMembers = map[string]bool{
+	"AT": true, "BE": true, "BG": true, "HR": true, "CY": true, "CZ": true, "DK": true,
+	"EE": true, "FI": true, "FR": true, "DE": true, "GR": true, "HU": true, "IE": true,
+	"IT": true, "LV": true, "LT": true, "LU": true, "MT": true, "NL": true, "PL": true,
+	"PT": true, "RO": true, "SK": true, "SI": true, "ES": true, "SE": true,
+}
+
+// IsRegion reports whether code names a group of countries
+func IsRegion(code string) bool {
+	return code == RegionEU
+}
+
+// InRegion reports whether country belongs to the region
+func InRegion(country, region string) bool {
+	switch region {
+	case RegionEU:
+		return euMembers[country]
+	default:
+		return false
+	}
+}
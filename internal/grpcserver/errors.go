@@ -0,0 +1,71 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"url-shortener/internal/domain"
+)
+
+// toStatusError maps domain errors to gRPC status codes
+// Mirrors URLHandler.handleError so both transports report failures the same way
+func toStatusError(err error) error {
+	var appErr *domain.AppError
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, "the request was canceled")
+
+	case errors.Is(err, domain.ErrDependencyUnavailable):
+		return status.Error(codes.Unavailable, "the service is temporarily unavailable, please retry")
+
+	case errors.Is(err, domain.ErrURLNotFound):
+		return status.Error(codes.NotFound, "the requested URL was not found")
+
+	case errors.Is(err, domain.ErrURLExpired):
+		return status.Error(codes.NotFound, "this URL has expired and is no longer available")
+
+	case errors.Is(err, domain.ErrShortCodeTaken):
+		return status.Error(codes.AlreadyExists, "this short code is already in use")
+
+	case errors.Is(err, domain.ErrRateLimitExceeded):
+		return status.Error(codes.ResourceExhausted, "too many requests, please try again later")
+
+	case errors.As(err, &appErr):
+		// Never expose internal error details to callers
+		if appErr.Internal {
+			return status.Error(codes.Internal, "an internal error occurred")
+		}
+		return status.Error(codeForHTTPStatus(appErr.StatusCode), appErr.Message)
+
+	case errors.Is(err, domain.ErrInvalidURL):
+		return status.Error(codes.InvalidArgument, "the provided URL is invalid")
+
+	default:
+		return status.Error(codes.Internal, "an unexpected error occurred")
+	}
+}
+
+// codeForHTTPStatus translates the HTTP status carried by an AppError
+func codeForHTTPStatus(statusCode int) codes.Code {
+	switch statusCode {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound, http.StatusGone:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.AlreadyExists
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	default:
+		return codes.Internal
+	}
+}
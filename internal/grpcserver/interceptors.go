@@ -0,0 +1,75 @@
+package grpcserver
+
+import (
+	"context"
+	"crypto/subtle"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"url-shortener/internal/apikey"
+	"url-shortener/internal/config"
+	"url-shortener/internal/domain"
+	"url-shortener/pkg/logger"
+)
+
+// apiKeyMetadataKey is the metadata entry carrying the API key (gRPC keys are lowercase)
+const apiKeyMetadataKey = "x-api-key"
+
+// LoggingInterceptor logs every unary call with its outcome, like LoggerMiddleware does for HTTP
+func LoggingInterceptor(log *logger.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+
+		// Service lines logged during the call carry the method and caller
+		ctx = logger.WithContext(ctx, log.WithFields(map[string]interface{}{
+			"method": info.FullMethod,
+			"ip":     clientIP(ctx),
+		}))
+		resp, err := handler(ctx, req)
+
+		log.Info("gRPC request",
+			"method", info.FullMethod,
+			"code", status.Code(err).String(),
+			"ip", clientIP(ctx),
+			"latency", time.Since(start),
+		)
+		return resp, err
+	}
+}
+
+// AuthInterceptor validates the API key from request metadata when authentication is enabled
+// Accepts the bootstrap API_KEY and every active key in the api_keys table
+// Calls act on the links of the key's tenant, the default tenant for API_KEY and anonymous callers,
+// and new links get the key's expiry settings.
+func AuthInterceptor(cfg *config.Config, keys *apikey.Store) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		values := md.Get(apiKeyMetadataKey)
+
+		tenant, expiry, ok := domain.DefaultTenant, domain.KeyExpiry{}, false
+		if len(values) > 0 {
+			tenant, expiry, ok = validKey(ctx, cfg, keys, values[0])
+		}
+		if cfg.EnableAuthentication && !ok {
+			return nil, status.Error(codes.Unauthenticated, "valid API key required")
+		}
+
+		return handler(domain.ContextWithKeyExpiry(domain.ContextWithTenant(ctx, tenant), expiry), req)
+	}
+}
+
+// validKey reports whether key is the bootstrap API_KEY or an active issued key, and the key's tenant and expiry settings
+func validKey(ctx context.Context, cfg *config.Config, keys *apikey.Store, key string) (string, domain.KeyExpiry, bool) {
+	if cfg.APIKey != "" && subtle.ConstantTimeCompare([]byte(key), []byte(cfg.APIKey)) == 1 {
+		return domain.DefaultTenant, domain.KeyExpiry{}, true
+	}
+	issued, ok := keys.Validate(ctx, key)
+	if !ok {
+		return domain.DefaultTenant, domain.KeyExpiry{}, false
+	}
+	return issued.TenantID, issued.Expiry(), true
+}
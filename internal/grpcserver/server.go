@@ -0,0 +1,168 @@
+package grpcserver
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "url-shortener/api/urlshortener/v1"
+	"url-shortener/internal/apikey"
+	"url-shortener/internal/config"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/service"
+	"url-shortener/pkg/logger"
+)
+
+// Server adapts URLService to the generated gRPC interface
+// It holds no business logic of its own - every call goes through the service layer
+type Server struct {
+	pb.UnimplementedURLShortenerServer
+	service service.URLService
+	logger  *logger.Logger
+}
+
+// NewServer creates the gRPC adapter for the URL service
+func NewServer(service service.URLService, logger *logger.Logger) *Server {
+	return &Server{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// NewGRPCServer builds a grpc.Server with logging and auth interceptors and registers the URL service
+func NewGRPCServer(urlService service.URLService, keys *apikey.Store, cfg *config.Config, log *logger.Logger) *grpc.Server {
+	srv := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(
+			LoggingInterceptor(log),
+			AuthInterceptor(cfg, keys),
+		),
+	)
+
+	pb.RegisterURLShortenerServer(srv, NewServer(urlService, log))
+	return srv
+}
+
+// Shorten creates a new short URL
+func (s *Server) Shorten(ctx context.Context, req *pb.ShortenRequest) (*pb.ShortenResponse, error) {
+	resp, err := s.service.ShortenURL(ctx, &domain.CreateURLRequest{
+		URL:         req.GetUrl(),
+		CustomAlias: req.GetCustomAlias(),
+		ExpiryDays:  int(req.GetExpiryDays()),
+	}, creatorContext(ctx))
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return &pb.ShortenResponse{
+		ShortCode:   resp.ShortCode,
+		ShortUrl:    resp.ShortURL,
+		OriginalUrl: resp.OriginalURL,
+		CreatedAt:   timestamppb.New(resp.CreatedAt),
+		ExpiresAt:   optionalTimestamp(resp.ExpiresAt),
+	}, nil
+}
+
+// Resolve returns the original URL for a short code, counting the click
+// gRPC callers are services rather than browsers, so platform rules fall through to the default
+func (s *Server) Resolve(ctx context.Context, req *pb.ResolveRequest) (*pb.ResolveResponse, error) {
+	originalURL, err := s.service.GetOriginalURL(ctx, req.GetShortCode(), domain.Visitor{IP: clientIP(ctx)})
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return &pb.ResolveResponse{OriginalUrl: originalURL}, nil
+}
+
+// GetStats returns click statistics for a short code
+func (s *Server) GetStats(ctx context.Context, req *pb.GetStatsRequest) (*pb.GetStatsResponse, error) {
+	stats, err := s.service.GetStats(ctx, req.GetShortCode())
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	resp := &pb.GetStatsResponse{
+		ShortCode:    stats.ShortCode,
+		OriginalUrl:  stats.OriginalURL,
+		TotalClicks:  stats.TotalClicks,
+		CreatedAt:    timestamppb.New(stats.CreatedAt),
+		LastAccessAt: optionalTimestamp(stats.LastAccessAt),
+		ExpiresAt:    optionalTimestamp(stats.ExpiresAt),
+		IsActive:     stats.IsActive,
+	}
+	if stats.DaysRemaining != nil {
+		days := int32(*stats.DaysRemaining)
+		resp.DaysRemaining = &days
+	}
+
+	return resp, nil
+}
+
+// Delete deactivates a short code
+func (s *Server) Delete(ctx context.Context, req *pb.DeleteRequest) (*pb.DeleteResponse, error) {
+	if err := s.service.DeleteURL(ctx, req.GetShortCode()); err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return &pb.DeleteResponse{}, nil
+}
+
+// Serve accepts connections on the listener until the server is stopped
+func Serve(srv *grpc.Server, lis net.Listener) error {
+	if err := srv.Serve(lis); err != nil && err != grpc.ErrServerStopped {
+		return err
+	}
+	return nil
+}
+
+// Shutdown stops the server gracefully, forcing it closed when ctx expires first
+func Shutdown(ctx context.Context, srv *grpc.Server) {
+	done := make(chan struct{})
+	go func() {
+		srv.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		srv.Stop()
+	}
+}
+
+// clientIP extracts the caller address from the gRPC peer info
+func clientIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+// creatorContext describes the calling peer; gRPC clients have no origin, only a user agent
+func creatorContext(ctx context.Context) domain.CreatorContext {
+	creator := domain.CreatorContext{IP: clientIP(ctx)}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if agents := md.Get("user-agent"); len(agents) > 0 {
+			creator.UserAgent = agents[0]
+		}
+	}
+	return creator
+}
+
+// optionalTimestamp converts a nullable time to a protobuf timestamp
+func optionalTimestamp(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
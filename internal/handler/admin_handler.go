@@ -0,0 +1,184 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"url-shortener/internal/domain"
+)
+
+// DeactivateURL handles PUT /api/v1/urls/:shortCode/deactivate
+// Disables the link without deleting it and returns the updated resource
+func (h *URLHandler) DeactivateURL(c *gin.Context) {
+	h.setURLActive(c, false)
+}
+
+// ActivateURL handles PUT /api/v1/urls/:shortCode/activate
+// Re-enables a deactivated link and returns the updated resource
+func (h *URLHandler) ActivateURL(c *gin.Context) {
+	h.setURLActive(c, true)
+}
+
+// setURLActive runs the activate/deactivate operation on behalf of the authenticated actor
+func (h *URLHandler) setURLActive(c *gin.Context, active bool) {
+	shortCode := c.Param("shortCode")
+
+	if shortCode == "" {
+		respondError(c, http.StatusBadRequest, domain.ErrorResponse{
+			Error:   "invalid_short_code",
+			Message: "Short code is required",
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	actor := actorFromContext(c)
+
+	var (
+		url *domain.URL
+		err error
+	)
+	if active {
+		url, err = h.service.ActivateURL(c.Request.Context(), shortCode, actor)
+	} else {
+		url, err = h.service.DeactivateURL(c.Request.Context(), shortCode, actor)
+	}
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, url)
+}
+
+// BulkDeactivate handles POST /api/v1/urls/bulk-delete
+// Deactivates links by short code or filter in one write; dry_run only reports how many would be affected
+func (h *URLHandler) BulkDeactivate(c *gin.Context) {
+	var req domain.BulkDeactivateRequest
+	if err := bindStrictJSON(c, &req); err != nil {
+		writeBindError(c, h.logger, err)
+		return
+	}
+
+	resp, err := h.service.BulkDeactivate(c.Request.Context(), &req, actorFromContext(c))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// GetSnapshot handles GET /api/v1/urls/:shortCode/snapshot
+// Serves the archived destination as it was fetched, under a sandboxing CSP so its scripts never run on our origin
+func (h *URLHandler) GetSnapshot(c *gin.Context) {
+	snap, err := h.service.GetSnapshot(c.Request.Context(), c.Param("shortCode"))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.Header("Content-Security-Policy", "sandbox; default-src 'none'")
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("Cache-Control", "private, no-store")
+	c.Header("X-Snapshot-Source-URL", snap.SourceURL)
+	c.Header("X-Snapshot-Status", strconv.Itoa(snap.StatusCode))
+	c.Header("X-Snapshot-Fetched-At", snap.FetchedAt.UTC().Format(time.RFC3339))
+	c.Header("X-Snapshot-Truncated", strconv.FormatBool(snap.Truncated))
+
+	contentType := snap.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	c.Data(http.StatusOK, contentType, snap.Content)
+}
+
+// actorFromContext builds the audit identity set by the auth middleware
+func actorFromContext(c *gin.Context) domain.Actor {
+	id := c.GetString(actorContextKey)
+	if id == "" {
+		id = "anonymous"
+	}
+
+	return domain.Actor{ID: id, IP: clientIP(c)}
+}
+
+// FlushCache handles POST /api/v1/admin/cache/flush
+// Deletes every cache key in the current namespace; redirects are served from the database meanwhile
+func (h *URLHandler) FlushCache(c *gin.Context) {
+	deleted, err := h.service.FlushCache(c.Request.Context(), actorFromContext(c))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"namespace": h.cfg.CacheNamespace,
+		"deleted":   deleted,
+	})
+}
+
+// RefreshMetadata handles POST /api/v1/admin/urls/:shortCode/metadata
+// Fetches the destination's title and favicon again and returns the updated resource
+func (h *URLHandler) RefreshMetadata(c *gin.Context) {
+	url, err := h.service.RefreshMetadata(c.Request.Context(), c.Param("shortCode"))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, url)
+}
+
+// GetAdminURLInfo handles GET /api/v1/admin/urls/:shortCode
+// Like GetURLInfo, but deactivated links are found too and the creator metadata is included
+func (h *URLHandler) GetAdminURLInfo(c *gin.Context) {
+	info, err := h.service.GetAdminURLInfo(c.Request.Context(), c.Param("shortCode"))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "private, no-store")
+	c.JSON(http.StatusOK, info)
+}
+
+// SearchURLs handles GET /api/v1/admin/urls/search?host=&path_prefix=
+// Finds the active links pointing at a host for abuse reports; host=*.spam.example includes subdomains.
+// Each page lists its short codes, which POST /api/v1/urls/bulk-delete takes as they are.
+func (h *URLHandler) SearchURLs(c *gin.Context) {
+	limit, offset, ok := pageParams(c)
+	if !ok {
+		return
+	}
+
+	page, err := h.service.SearchByDestination(c.Request.Context(), c.Query("host"), c.Query("path_prefix"), limit, offset)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "private, no-store")
+	c.JSON(http.StatusOK, page)
+}
+
+// ListByCreatorIP handles GET /api/v1/admin/urls?creator_ip=
+// Pages through the links created from one address; their short codes can go straight to bulk-delete
+func (h *URLHandler) ListByCreatorIP(c *gin.Context) {
+	limit, offset, ok := pageParams(c)
+	if !ok {
+		return
+	}
+
+	page, err := h.service.ListByCreatorIP(c.Request.Context(), c.Query("creator_ip"), limit, offset)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "private, no-store")
+	c.JSON(http.StatusOK, page)
+}
@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"url-shortener/internal/apikey"
+	"url-shortener/internal/domain"
+	"url-shortener/pkg/logger"
+)
+
+// APIKeyHandler serves the admin endpoints that issue and revoke API keys
+type APIKeyHandler struct {
+	keys   *apikey.Store
+	logger *logger.Logger
+}
+
+// NewAPIKeyHandler creates a new API key handler
+func NewAPIKeyHandler(keys *apikey.Store, logger *logger.Logger) *APIKeyHandler {
+	return &APIKeyHandler{keys: keys, logger: logger}
+}
+
+// CreateKey handles POST /api/v1/admin/api-keys
+// The secret is only part of this response; afterwards just its hash is kept
+func (h *APIKeyHandler) CreateKey(c *gin.Context) {
+	var req domain.CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeBindError(c, h.logger, err)
+			return
+		}
+		h.logger.Warn("Invalid request body", "error", err)
+		respondError(c, http.StatusBadRequest, domain.ErrorResponse{
+			Error:   "invalid_request",
+			Message: "A label of at most 100 characters is required",
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	resp, err := h.keys.Create(c.Request.Context(), &req)
+	if err != nil {
+		writeError(c, h.logger, err)
+		return
+	}
+
+	h.logger.Info("API key created", "fingerprint", resp.Fingerprint, "label", resp.Label, "actor", actorFromContext(c).ID)
+	c.JSON(http.StatusCreated, resp)
+}
+
+// ListKeys handles GET /api/v1/admin/api-keys
+func (h *APIKeyHandler) ListKeys(c *gin.Context) {
+	keys, err := h.keys.List(c.Request.Context())
+	if err != nil {
+		writeError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"keys": keys})
+}
+
+// RevokeKey handles DELETE /api/v1/admin/api-keys/:id
+// Other instances stop accepting the key once their key cache refreshes
+func (h *APIKeyHandler) RevokeKey(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		respondError(c, http.StatusBadRequest, domain.ErrorResponse{
+			Error:   "invalid_id",
+			Message: "API key id must be a positive integer",
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	key, err := h.keys.Revoke(c.Request.Context(), uint(id))
+	if err != nil {
+		writeError(c, h.logger, err)
+		return
+	}
+
+	h.logger.Info("API key revoked", "fingerprint", key.Fingerprint, "actor", actorFromContext(c).ID)
+	c.JSON(http.StatusOK, key)
+}
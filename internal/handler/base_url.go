@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"url-shortener/internal/config"
+	"url-shortener/internal/service"
+)
+
+// BaseURLMiddleware builds short URLs from the request's host when BASE_URL_MODE=request
+// Hosts outside ALLOWED_DOMAINS keep BASE_URL, so a forged Host header never ends up in a response
+func BaseURLMiddleware(cfg *config.Config) gin.HandlerFunc {
+	if cfg.BaseURLMode != config.BaseURLModeRequest {
+		return func(c *gin.Context) { c.Next() }
+	}
+
+	proxies := parseProxies(cfg.TrustedProxies)
+	return func(c *gin.Context) {
+		if base, ok := requestBaseURL(c.Request, proxies, cfg.AllowedDomains); ok {
+			c.Request = c.Request.WithContext(service.ContextWithBaseURL(c.Request.Context(), base))
+		}
+		c.Next()
+	}
+}
+
+// requestBaseURL returns scheme://host of r, with X-Forwarded-* applied when r comes from a trusted proxy
+func requestBaseURL(r *http.Request, proxies []*net.IPNet, allowed []string) (string, bool) {
+	scheme, host := requestHost(r, proxies)
+	if !allowedHost(host, allowed) {
+		return "", false
+	}
+	return scheme + "://" + strings.ToLower(host), true
+}
+
+// requestHost returns the scheme and host r was sent to, as X-Forwarded-* tell when r comes from a trusted proxy
+func requestHost(r *http.Request, proxies []*net.IPNet) (string, string) {
+	scheme, host := "http", r.Host
+	if r.TLS != nil {
+		scheme = "https"
+	}
+
+	if fromTrustedProxy(r, proxies) {
+		if proto := strings.ToLower(firstForwarded(r.Header.Get("X-Forwarded-Proto"))); proto == "http" || proto == "https" {
+			scheme = proto
+		}
+		if forwarded := firstForwarded(r.Header.Get("X-Forwarded-Host")); forwarded != "" {
+			host = forwarded
+		}
+	}
+	return scheme, host
+}
+
+// allowedHost reports whether host, optionally with a port, is exactly one of allowed
+// Anything that is not a plain host name, e.g. with a path or credentials, is refused
+func allowedHost(host string, allowed []string) bool {
+	name := host
+	if h, port, err := net.SplitHostPort(host); err == nil {
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			return false
+		}
+		name = h
+	}
+	if name == "" || strings.ContainsAny(name, "/\\@?#[]: \t") {
+		return false
+	}
+
+	for _, domain := range allowed {
+		if strings.EqualFold(name, domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// firstForwarded is the value added by the proxy closest to the client
+func firstForwarded(header string) string {
+	first, _, _ := strings.Cut(header, ",")
+	return strings.TrimSpace(first)
+}
+
+// fromTrustedProxy reports whether the peer of r is one of proxies
+func fromTrustedProxy(r *http.Request, proxies []*net.IPNet) bool {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, proxy := range proxies {
+		if proxy.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseProxies turns TRUSTED_PROXIES entries into networks; single addresses match only themselves
+// Entries were checked by config validation, so unparseable ones are skipped
+func parseProxies(entries []string) []*net.IPNet {
+	var proxies []*net.IPNet
+	for _, entry := range entries {
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			proxies = append(proxies, network)
+			continue
+		}
+		if ip := net.ParseIP(entry); ip != nil {
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			proxies = append(proxies, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+		}
+	}
+	return proxies
+}
@@ -0,0 +1,221 @@
+package handler
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// incompressibleTypes are content type prefixes gzip can't shrink, or would hold back like server-sent events
+var incompressibleTypes = []string{
+	"image/", "video/", "audio/", "font/woff",
+	"application/zip", "application/gzip", "application/x-gzip", "application/pdf", "application/octet-stream",
+	"text/event-stream",
+}
+
+// gzipWriters reuses compressors, each holds a few hundred KB of state
+var gzipWriters = sync.Pool{
+	New: func() interface{} { return gzip.NewWriter(nil) },
+}
+
+// CompressionMiddleware gzips API responses of at least minBytes for clients that accept gzip
+// The body is held back until it reaches minBytes, so small answers go out as they are. A handler that flushes
+// streams a response of unknown size, like the export, so it is compressed from there on and flushed along.
+func CompressionMiddleware(minBytes int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		addVary(c.Writer.Header(), "Accept-Encoding")
+		if c.Request.Method == http.MethodHead || !acceptsGzip(c.Request.Header.Get("Accept-Encoding")) {
+			c.Next()
+			return
+		}
+
+		// After a panic the buffered body is dropped, so the recovery middleware can still answer 500
+		original := c.Writer
+		cw := &compressWriter{ResponseWriter: original, minBytes: minBytes, status: http.StatusOK}
+		c.Writer = cw
+		defer func() { c.Writer = original }()
+
+		c.Next()
+		cw.close()
+	}
+}
+
+// compressWriter buffers the start of a response until it knows whether compressing it is worth it
+type compressWriter struct {
+	gin.ResponseWriter
+
+	minBytes int
+	status   int          // Recorded until the headers are sent
+	buf      []byte       // Body written before the decision
+	started  bool         // Headers are sent, the body goes to gz or straight through
+	gz       *gzip.Writer // Set when the body is compressed
+}
+
+// WriteHeader records the status, sent with the first body bytes past the threshold or when the handler returns
+func (w *compressWriter) WriteHeader(code int) {
+	if !w.started {
+		w.status = code
+	}
+}
+
+// WriteHeaderNow sends the headers with whatever body is buffered
+func (w *compressWriter) WriteHeaderNow() {
+	if !w.started {
+		w.start(false)
+	}
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+// Write buffers data until minBytes are collected, then compresses or passes it through
+func (w *compressWriter) Write(data []byte) (int, error) {
+	if !w.started {
+		w.buf = append(w.buf, data...)
+		if len(w.buf) < w.minBytes || len(w.buf) == 0 {
+			return len(data), nil
+		}
+		if err := w.start(true); err != nil {
+			return 0, err
+		}
+		return len(data), nil
+	}
+	if w.gz != nil {
+		return w.gz.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// WriteString is Write for strings
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Flush sends what was written so far; a response flushed before the threshold is compressed regardless
+func (w *compressWriter) Flush() {
+	if !w.started {
+		w.start(true)
+	}
+	if w.gz != nil {
+		w.gz.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// Status returns the status sent or about to be sent
+func (w *compressWriter) Status() int {
+	if !w.started {
+		return w.status
+	}
+	return w.ResponseWriter.Status()
+}
+
+// Written reports whether the handler started its response, even if it is still buffered
+func (w *compressWriter) Written() bool {
+	return w.started || len(w.buf) > 0
+}
+
+// start sends the headers and the buffered body, compressed if large is set and the response qualifies
+func (w *compressWriter) start(large bool) error {
+	w.started = true
+	header := w.Header()
+	addVary(header, "Accept-Encoding") // Again, in case the handler replaced Vary
+	if large && compressible(w.status, header) {
+		// Detected now, net/http would otherwise sniff the compressed bytes
+		if header.Get("Content-Type") == "" && len(w.buf) > 0 {
+			header.Set("Content-Type", http.DetectContentType(w.buf))
+		}
+		header.Set("Content-Encoding", "gzip")
+		header.Del("Content-Length")
+		w.gz = gzipWriters.Get().(*gzip.Writer)
+		w.gz.Reset(w.ResponseWriter)
+	}
+
+	w.ResponseWriter.WriteHeader(w.status)
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	var err error
+	if w.gz != nil {
+		_, err = w.gz.Write(buf)
+	} else {
+		_, err = w.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+// close sends a body that stayed under the threshold, or finishes the gzip stream
+func (w *compressWriter) close() {
+	if !w.started {
+		w.start(len(w.buf) > 0 && len(w.buf) >= w.minBytes)
+	}
+	if w.gz != nil {
+		w.gz.Close()
+		gzipWriters.Put(w.gz)
+		w.gz = nil
+	}
+}
+
+// compressible reports whether a response with this status and these headers may be gzipped
+// Only successful bodies are; redirects, 204 and partial content are left alone
+func compressible(status int, header http.Header) bool {
+	if status < 200 || status >= 300 || status == http.StatusNoContent || status == http.StatusPartialContent {
+		return false
+	}
+	if header.Get("Content-Encoding") != "" {
+		return false
+	}
+
+	contentType := strings.ToLower(header.Get("Content-Type"))
+	if strings.HasPrefix(contentType, "image/svg+xml") {
+		return true
+	}
+	for _, prefix := range incompressibleTypes {
+		if strings.HasPrefix(contentType, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// acceptsGzip reads an Accept-Encoding header; an explicit "gzip;q=0" wins over "*"
+func acceptsGzip(header string) bool {
+	gzipQ, anyQ := -1.0, -1.0
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(part, ";")
+		q := 1.0
+		if name, value, ok := strings.Cut(params, "="); ok && strings.EqualFold(strings.TrimSpace(name), "q") {
+			if parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+				q = parsed
+			}
+		}
+
+		switch strings.ToLower(strings.TrimSpace(coding)) {
+		case "gzip", "x-gzip":
+			gzipQ = q
+		case "*":
+			anyQ = q
+		}
+	}
+	if gzipQ >= 0 {
+		return gzipQ > 0
+	}
+	return anyQ > 0
+}
+
+// addVary adds field to the Vary header unless it's listed already
+func addVary(header http.Header, field string) {
+	for _, value := range header.Values("Vary") {
+		for _, listed := range strings.Split(value, ",") {
+			listed = strings.TrimSpace(listed)
+			if listed == "*" || strings.EqualFold(listed, field) {
+				return
+			}
+		}
+	}
+	header.Add("Vary", field)
+}
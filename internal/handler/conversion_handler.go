@@ -0,0 +1,27 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"url-shortener/internal/domain"
+)
+
+// RecordConversion handles POST /api/v1/conversions
+// Redeems the ush_click receipt a visitor arrived with; the receipt itself names the link, so no key is needed
+func (h *URLHandler) RecordConversion(c *gin.Context) {
+	var req domain.ConversionRequest
+	if err := bindStrictJSON(c, &req); err != nil {
+		writeBindError(c, h.logger, err)
+		return
+	}
+
+	resp, err := h.service.RecordConversion(c.Request.Context(), req.ClickID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"crypto/rand"
+	_ "embed"
+	"encoding/base64"
+	"encoding/json"
+	"html/template"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"url-shortener/internal/config"
+	"url-shortener/pkg/logger"
+)
+
+// openAPISpec is the hand-maintained API description; a test fails when it and the routes disagree
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// swaggerUIAssets is where the docs page loads Swagger UI from, pinned to one release
+const swaggerUIAssets = "https://unpkg.com/swagger-ui-dist@5.9.0"
+
+// swaggerUIPage renders the spec with Swagger UI; the inline script runs under a per-request nonce
+var swaggerUIPage = template.Must(template.New("docs").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>URL Shortener API</title>
+<link rel="stylesheet" href="{{.Assets}}/swagger-ui.css">
+</head>
+<body>
+<div id="swagger-ui"></div>
+<script src="{{.Assets}}/swagger-ui-bundle.js"></script>
+<script nonce="{{.Nonce}}">
+window.ui = SwaggerUIBundle({url: "openapi.json", dom_id: "#swagger-ui"});
+</script>
+</body>
+</html>
+`))
+
+// docsPage is the data rendered by swaggerUIPage
+type docsPage struct {
+	Assets string
+	Nonce  string
+}
+
+// DocsHandler serves the OpenAPI spec, the error catalog and the optional Swagger UI
+type DocsHandler struct {
+	spec   []byte
+	logger *logger.Logger
+}
+
+// NewDocsHandler completes the embedded spec once at startup
+// BASE_URL becomes the server and ErrorCatalog the ErrorResponse enum, so neither is repeated in the file
+func NewDocsHandler(cfg *config.Config, logger *logger.Logger) *DocsHandler {
+	var spec map[string]interface{}
+	if err := json.Unmarshal(openAPISpec, &spec); err != nil {
+		logger.Fatal("Failed to parse OpenAPI spec", "error", err)
+	}
+
+	codes := make([]string, len(ErrorCatalog))
+	for i, code := range ErrorCatalog {
+		codes[i] = code.Error
+	}
+	schemas := spec["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+	errorField := schemas["ErrorResponse"].(map[string]interface{})["properties"].(map[string]interface{})["error"].(map[string]interface{})
+	errorField["enum"] = codes
+	spec["x-error-catalog"] = ErrorCatalog
+	spec["servers"] = []map[string]string{{"url": cfg.BaseURL}}
+
+	body, err := json.Marshal(spec)
+	if err != nil {
+		logger.Fatal("Failed to encode OpenAPI spec", "error", err)
+	}
+
+	return &DocsHandler{spec: body, logger: logger}
+}
+
+// OpenAPISpec handles GET /api/v1/openapi.json
+func (h *DocsHandler) OpenAPISpec(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json; charset=utf-8", h.spec)
+}
+
+// ErrorCatalog handles GET /api/v1/errors
+func (h *DocsHandler) ErrorCatalog(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"errors": ErrorCatalog})
+}
+
+// SwaggerUI handles GET /api/v1/docs
+// The page relaxes the global policy for the Swagger UI assets and its own nonce only
+func (h *DocsHandler) SwaggerUI(c *gin.Context) {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		writeError(c, h.logger, err)
+		return
+	}
+	nonce := base64.RawURLEncoding.EncodeToString(raw)
+
+	c.Header("Content-Security-Policy", "default-src 'none'; script-src 'nonce-"+nonce+"' "+swaggerUIAssets+"/; "+
+		"style-src 'unsafe-inline' "+swaggerUIAssets+"/; img-src 'self' data:; connect-src 'self'")
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Status(http.StatusOK)
+
+	if err := swaggerUIPage.Execute(c.Writer, docsPage{Assets: swaggerUIAssets, Nonce: nonce}); err != nil {
+		h.logger.Error("Failed to render page", "error", err, "template", "docs")
+	}
+}
@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"url-shortener/internal/domain"
+)
+
+// dependencyRetryAfter is how long clients are asked to wait when the database is unreachable
+const dependencyRetryAfter = 5 * time.Second
+
+// DomainError is the response a domain error is answered with, in either error format
+type DomainError struct {
+	Err        error
+	Status     int
+	Error      string // The "error" value, one of ErrorCatalog; problem types are derived from it
+	Message    string
+	RetryAfter func(now time.Time) time.Duration // Sets Retry-After when not nil
+}
+
+// DomainErrors maps every exported error of the domain package, first match wins
+// Outages come first: they wrap their cause, which may be another domain error
+var DomainErrors = []DomainError{
+	{Err: domain.ErrDependencyUnavailable, Status: http.StatusServiceUnavailable, Error: "service_unavailable",
+		Message: "The service is temporarily unavailable, please try again shortly", RetryAfter: retryDependency},
+	{Err: domain.ErrDatabaseConnection, Status: http.StatusServiceUnavailable, Error: "service_unavailable",
+		Message: "The service is temporarily unavailable, please try again shortly", RetryAfter: retryDependency},
+	{Err: domain.ErrCacheUnavailable, Status: http.StatusServiceUnavailable, Error: "service_unavailable",
+		Message: "The service is temporarily unavailable, please try again shortly", RetryAfter: retryDependency},
+	{Err: domain.ErrInvalidClickID, Status: http.StatusBadRequest, Error: "invalid_click_id",
+		Message: "The click ID is malformed, was not issued by this service or has expired"},
+	{Err: domain.ErrClickIDUsed, Status: http.StatusConflict, Error: "click_id_used",
+		Message: "A conversion was already recorded for this click ID"},
+	{Err: domain.ErrURLNotFound, Status: http.StatusNotFound, Error: "not_found",
+		Message: "The requested URL was not found"},
+	{Err: domain.ErrURLExpired, Status: http.StatusGone, Error: "url_expired",
+		Message: "This URL has expired and is no longer available"},
+	{Err: domain.ErrShortCodeTaken, Status: http.StatusConflict, Error: "short_code_taken",
+		Message: "This short code is already in use"},
+	{Err: domain.ErrShortCodeInvalid, Status: http.StatusBadRequest, Error: "invalid_short_code",
+		Message: "The short code contains invalid characters"},
+	{Err: domain.ErrInvalidURL, Status: http.StatusBadRequest, Error: "invalid_url",
+		Message: "The provided URL is invalid"},
+	{Err: domain.ErrQuotaExceeded, Status: http.StatusTooManyRequests, Error: "quota_exceeded",
+		Message: "Daily link creation quota exceeded, please try again tomorrow", RetryAfter: retryQuota},
+	{Err: domain.ErrAPIKeyNotFound, Status: http.StatusNotFound, Error: "not_found",
+		Message: "The requested API key was not found or is already revoked"},
+	{Err: domain.ErrRateLimitExceeded, Status: http.StatusTooManyRequests, Error: "rate_limit_exceeded",
+		Message: "Too many requests, please try again later"},
+}
+
+// lookupDomainError returns the first entry of DomainErrors that err matches
+func lookupDomainError(err error) (DomainError, bool) {
+	for _, mapping := range DomainErrors {
+		if errors.Is(err, mapping.Err) {
+			return mapping, true
+		}
+	}
+	return DomainError{}, false
+}
+
+// retryDependency asks clients to come back once a short outage is likely over
+func retryDependency(time.Time) time.Duration {
+	return dependencyRetryAfter
+}
+
+// retryQuota tells the client exactly when the daily quotas reset at midnight UTC
+func retryQuota(now time.Time) time.Duration {
+	return domain.NextQuotaReset(now).Sub(now) + time.Second
+}
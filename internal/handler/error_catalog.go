@@ -0,0 +1,43 @@
+package handler
+
+import "net/http"
+
+// ErrorCode documents one value of the "error" field in error responses
+type ErrorCode struct {
+	Error       string `json:"error"`
+	Status      []int  `json:"status"`
+	Description string `json:"description"`
+}
+
+// ErrorCatalog lists every "error" value the API answers with
+// The ErrorResponse enum in the OpenAPI spec and GET /api/v1/errors are built from it
+var ErrorCatalog = []ErrorCode{
+	{"invalid_request", []int{http.StatusBadRequest}, "The body is not valid JSON, has unknown fields or misses required fields, or a query flag is not a boolean"},
+	{"request_too_large", []int{http.StatusRequestEntityTooLarge}, "The body is larger than MAX_REQUEST_BODY_BYTES"},
+	{"invalid_url", []int{http.StatusBadRequest}, "The destination URL is invalid"},
+	{"invalid_short_code", []int{http.StatusBadRequest}, "The short code in the path is missing or malformed"},
+	{"invalid_id", []int{http.StatusBadRequest}, "The API key id in the path is not a number"},
+	{"invalid_window", []int{http.StatusBadRequest}, "The days, limit or offset query parameter is out of range"},
+	{"invalid_format", []int{http.StatusBadRequest}, "The export format is neither csv nor json"},
+	{"invalid_filter", []int{http.StatusBadRequest}, "An export filter value can't be parsed"},
+	{"invalid_tenant", []int{http.StatusBadRequest}, "The X-Tenant-ID header of an admin request is not a valid tenant ID"},
+	{"invalid_range", []int{http.StatusBadRequest}, "The from or to value of a time series can't be parsed"},
+	{"invalid_click_id", []int{http.StatusBadRequest}, "The click_id of a conversion is malformed, wrongly signed or older than CLICK_ID_TTL_SECONDS"},
+	{"client_error", []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusConflict, http.StatusGone}, "The request was refused; message says why"},
+	{"unauthorized", []int{http.StatusUnauthorized}, "A valid API key or admin API key is required"},
+	{"invalid_token", []int{http.StatusForbidden}, "The interstitial continue link is invalid or has expired"},
+	{"invalid_signature", []int{http.StatusUnauthorized}, "The signature of a GET /api/v1/shorten link doesn't match, or its ts is further than SHORTEN_LINK_MAX_SKEW_SECONDS from now"},
+	{"admin_disabled", []int{http.StatusForbidden}, "Admin endpoints are disabled because ADMIN_API_KEY is not set"},
+	{"not_found", []int{http.StatusNotFound}, "The link or API key doesn't exist, was deleted or is deactivated"},
+	{"endpoint not found", []int{http.StatusNotFound}, "No route matches the request path"},
+	{"short_code_taken", []int{http.StatusConflict}, "The custom alias is already in use; suggestions lists free alternatives"},
+	{"click_id_used", []int{http.StatusConflict}, "A conversion was already recorded for the click_id"},
+	{"restart_required", []int{http.StatusConflict}, "The reloaded configuration changes settings that only take effect after a restart; nothing was applied"},
+	{"url_expired", []int{http.StatusGone}, "The link has expired"},
+	{"quota_exceeded", []int{http.StatusTooManyRequests}, "The daily creation quota is used up; Retry-After says when it resets"},
+	{"rate_limit_exceeded", []int{http.StatusTooManyRequests}, "Too many requests in the bucket named by X-RateLimit-Bucket"},
+	{"internal_error", []int{http.StatusInternalServerError}, "An unexpected error occurred"},
+	{"invalid_config", []int{http.StatusInternalServerError}, "The configuration failed to load or validate on reload; nothing was applied"},
+	{"service_unavailable", []int{http.StatusServiceUnavailable}, "The database or cache is unreachable; Retry-After says when to try again"},
+	{"request_timeout", []int{http.StatusGatewayTimeout}, "The request took longer than REQUEST_TIMEOUT_SECONDS or REDIRECT_TIMEOUT_SECONDS"},
+}
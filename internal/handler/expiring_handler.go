@@ -0,0 +1,39 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"url-shortener/internal/domain"
+)
+
+// defaultExpiringDays is the look-ahead used when ?days is omitted
+const defaultExpiringDays = 7
+
+// ListExpiring handles GET /api/v1/urls/expiring
+// Lists the active links that expire within ?days days, soonest first
+func (h *URLHandler) ListExpiring(c *gin.Context) {
+	days := defaultExpiringDays
+	if raw := c.Query("days"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, domain.ErrorResponse{
+				Error:   "invalid_window",
+				Message: "invalid 'days' value: " + raw,
+				Code:    http.StatusBadRequest,
+			})
+			return
+		}
+		days = n
+	}
+
+	urls, err := h.service.ListExpiring(c.Request.Context(), days)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, urls)
+}
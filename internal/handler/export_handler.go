@@ -0,0 +1,228 @@
+package handler
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+
+	"url-shortener/internal/domain"
+)
+
+// exportFlushEvery controls how many rows are written before flushing to the client
+const exportFlushEvery = 100
+
+// maxSearchLength bounds the export search term, which is matched with ILIKE against every row
+const maxSearchLength = 200
+
+// exportColumns lists the CSV header in output order
+var exportColumns = []string{
+	"short_code",
+	"original_url",
+	"created_at",
+	"expires_at",
+	"click_count",
+	"last_access_at",
+	"is_active",
+	"title",
+	"description",
+}
+
+// ExportURLs handles GET /api/v1/export
+// Streams all URLs as a CSV or JSON download, optionally filtered by creation date and a search term
+func (h *URLHandler) ExportURLs(c *gin.Context) {
+	format := c.DefaultQuery("format", "csv")
+	if format != "csv" && format != "json" {
+		respondError(c, http.StatusBadRequest, domain.ErrorResponse{
+			Error:   "invalid_format",
+			Message: "Format must be either csv or json",
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	filter, err := parseExportFilter(c)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, domain.ErrorResponse{
+			Error:   "invalid_filter",
+			Message: err.Error(),
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	filename := fmt.Sprintf("urls-%s.%s", time.Now().UTC().Format("20060102-150405"), format)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+
+	if format == "csv" {
+		err = h.exportCSV(c, filter)
+	} else {
+		err = h.exportJSON(c, filter)
+	}
+
+	if err != nil {
+		// Once rows are on the wire the status code is already sent
+		if !c.Writer.Written() {
+			h.handleError(c, err)
+			return
+		}
+		h.logger.Error("Export aborted mid-stream", "error", err, "format", format)
+		c.Abort()
+	}
+}
+
+// exportCSV writes the export as CSV, relying on encoding/csv for field quoting
+func (h *URLHandler) exportCSV(c *gin.Context, filter domain.URLFilter) error {
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+
+	w := csv.NewWriter(c.Writer)
+	headerWritten := false
+	rows := 0
+
+	err := h.service.ExportURLs(c.Request.Context(), filter, func(url *domain.URL) error {
+		if !headerWritten {
+			if err := w.Write(exportColumns); err != nil {
+				return err
+			}
+			headerWritten = true
+		}
+
+		record := domain.NewExportRecord(url)
+		if err := w.Write([]string{
+			record.ShortCode,
+			record.OriginalURL,
+			formatExportTime(&record.CreatedAt),
+			formatExportTime(record.ExpiresAt),
+			strconv.FormatInt(record.ClickCount, 10),
+			formatExportTime(record.LastAccessAt),
+			strconv.FormatBool(record.IsActive),
+			record.Title,
+			record.Description,
+		}); err != nil {
+			return err
+		}
+
+		rows++
+		if rows%exportFlushEvery == 0 {
+			w.Flush()
+			c.Writer.Flush()
+		}
+		return w.Error()
+	})
+	if err != nil {
+		return err
+	}
+
+	// Empty exports still get a header row
+	if !headerWritten {
+		if err := w.Write(exportColumns); err != nil {
+			return err
+		}
+	}
+
+	w.Flush()
+	return w.Error()
+}
+
+// exportJSON writes the export as a JSON array, encoding one record at a time
+func (h *URLHandler) exportJSON(c *gin.Context, filter domain.URLFilter) error {
+	c.Header("Content-Type", "application/json; charset=utf-8")
+
+	started := false
+	rows := 0
+
+	err := h.service.ExportURLs(c.Request.Context(), filter, func(url *domain.URL) error {
+		data, err := json.Marshal(domain.NewExportRecord(url))
+		if err != nil {
+			return err
+		}
+
+		prefix := ","
+		if !started {
+			prefix = "["
+			started = true
+		}
+		if _, err := c.Writer.WriteString(prefix); err != nil {
+			return err
+		}
+		if _, err := c.Writer.Write(data); err != nil {
+			return err
+		}
+
+		rows++
+		if rows%exportFlushEvery == 0 {
+			c.Writer.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if !started {
+		_, err = c.Writer.WriteString("[]")
+		return err
+	}
+	_, err = c.Writer.WriteString("]")
+	return err
+}
+
+// parseExportFilter reads the optional from/to query parameters
+// Accepts RFC3339 timestamps or plain dates (YYYY-MM-DD); a plain "to" date is inclusive
+func parseExportFilter(c *gin.Context) (domain.URLFilter, error) {
+	var filter domain.URLFilter
+
+	// Matched against short code, destination, title and description
+	filter.Search = strings.TrimSpace(c.Query("q"))
+	if utf8.RuneCountInString(filter.Search) > maxSearchLength {
+		return filter, fmt.Errorf("'q' must be at most %d characters", maxSearchLength)
+	}
+
+	if from := c.Query("from"); from != "" {
+		t, _, err := parseDateParam(from)
+		if err != nil {
+			return filter, fmt.Errorf("invalid 'from' value: %s", from)
+		}
+		filter.CreatedFrom = &t
+	}
+
+	if to := c.Query("to"); to != "" {
+		t, dateOnly, err := parseDateParam(to)
+		if err != nil {
+			return filter, fmt.Errorf("invalid 'to' value: %s", to)
+		}
+		if dateOnly {
+			t = t.AddDate(0, 0, 1)
+		}
+		filter.CreatedTo = &t
+	}
+
+	if filter.CreatedFrom != nil && filter.CreatedTo != nil && !filter.CreatedFrom.Before(*filter.CreatedTo) {
+		return filter, fmt.Errorf("'from' must be before 'to'")
+	}
+
+	return filter, nil
+}
+
+// parseDateParam parses an RFC3339 timestamp or a YYYY-MM-DD date in UTC
+func parseDateParam(value string) (time.Time, bool, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, false, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, time.UTC)
+	return t, true, err
+}
+
+// formatExportTime renders optional timestamps as RFC3339, empty when unset
+func formatExportTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
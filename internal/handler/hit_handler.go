@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// transparentGIF is a 1×1 transparent GIF89a
+var transparentGIF = []byte{
+	0x47, 0x49, 0x46, 0x38, 0x39, 0x61, 0x01, 0x00, 0x01, 0x00, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0xff, 0xff, 0xff, 0x21, 0xf9, 0x04, 0x01, 0x00, 0x00, 0x00, 0x00, 0x2c, 0x00, 0x00, 0x00, 0x00,
+	0x01, 0x00, 0x01, 0x00, 0x00, 0x02, 0x02, 0x44, 0x01, 0x00, 0x3b,
+}
+
+// RegisterHit handles POST /api/v1/urls/:shortCode/hit
+// Counts a click for clients that open the destination themselves; answers 204 without redirecting
+func (h *URLHandler) RegisterHit(c *gin.Context) {
+	shortCode := c.Param("shortCode")
+	if err := h.service.RegisterHit(c.Request.Context(), shortCode, h.visitorFromRequest(c)); err != nil {
+		h.handleRedirectError(c, shortCode, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// TrackingPixel handles GET /api/v1/urls/:shortCode/pixel.gif
+// Counts a click and returns a transparent GIF, so e.g. email opens can be attributed to a link
+func (h *URLHandler) TrackingPixel(c *gin.Context) {
+	shortCode := c.Param("shortCode")
+	if err := h.service.RegisterHit(c.Request.Context(), shortCode, h.visitorFromRequest(c)); err != nil {
+		h.handleRedirectError(c, shortCode, err)
+		return
+	}
+
+	// Every load should reach us, so neither mail proxies nor browsers may keep it
+	c.Header("Cache-Control", "no-store, no-cache, must-revalidate, max-age=0")
+	c.Header("Pragma", "no-cache")
+	c.Data(http.StatusOK, "image/gif", transparentGIF)
+}
@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"url-shortener/internal/domain"
+)
+
+// ListURLs handles GET /api/v1/urls
+// Pages through the active links, newest first; ?broken=true keeps the ones the link check found broken
+func (h *URLHandler) ListURLs(c *gin.Context) {
+	var filter domain.URLFilter
+	if raw := c.Query("broken"); raw != "" {
+		broken, err := strconv.ParseBool(raw)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, domain.ErrorResponse{
+				Error:   "invalid_request",
+				Message: "invalid 'broken' value: " + raw,
+				Code:    http.StatusBadRequest,
+			})
+			return
+		}
+		filter.Broken = &broken
+	}
+
+	limit, offset, ok := pageParams(c)
+	if !ok {
+		return
+	}
+
+	page, err := h.service.ListURLs(c.Request.Context(), filter, limit, offset)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// pageParams reads ?limit and ?offset, leaving them zero when absent; ok is false once it answered 400
+func pageParams(c *gin.Context) (limit, offset int, ok bool) {
+	for _, param := range []struct {
+		name   string
+		target *int
+	}{{"limit", &limit}, {"offset", &offset}} {
+		name, raw := param.name, c.Query(param.name)
+		if raw == "" {
+			continue
+		}
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			respondError(c, http.StatusBadRequest, domain.ErrorResponse{
+				Error:   "invalid_window",
+				Message: "invalid '" + name + "' value: " + raw,
+				Code:    http.StatusBadRequest,
+			})
+			return 0, 0, false
+		}
+		*param.target = n
+	}
+	return limit, offset, true
+}
@@ -1,28 +1,103 @@
 package handler
 
 import (
-	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"net/http"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"golang.org/x/time/rate"
 
+	"url-shortener/internal/apikey"
+	"url-shortener/internal/clientip"
 	"url-shortener/internal/config"
 	"url-shortener/internal/domain"
 	"url-shortener/pkg/logger"
+	"url-shortener/pkg/signer"
 )
 
-// rateLimiter stores rate limiters per IP
-var rateLimiters = make(map[string]*rate.Limiter)
+// clientIPContextKey caches the caller's address once it was resolved for a request
+const clientIPContextKey = "client_ip"
+
+// actorContextKey holds the identity of the authenticated caller for audit records
+const actorContextKey = "actor"
+
+// deferredAuthContextKey holds the API key check ManagementAuthMiddleware put off for a request with a
+// management token; authorizeManagement runs it when the link has no token to verify that one against
+const deferredAuthContextKey = "deferred_auth"
+
+// keyTierContextKey holds the rate limit of a key from the api_keys table, when it has one
+const keyTierContextKey = "key_tier"
+
+// managementTokenHeader carries the token a link's creator got in the create response
+const managementTokenHeader = "X-Management-Token"
+
+// requestIDHeader carries the ID that correlates a request's log lines, in and out
+const requestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds IDs taken from clients so they can't flood the logs
+const maxRequestIDLength = 64
+
+// RequestIDMiddleware tags each request with an ID, reusing a well-formed X-Request-ID from a proxy
+// The ID is stored in the request context for deeper layers and echoed in the response
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(requestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+
+		c.Request = c.Request.WithContext(logger.ContextWithRequestID(c.Request.Context(), id))
+		c.Header(requestIDHeader, id)
+		c.Next()
+	}
+}
+
+// validRequestID accepts short IDs made of characters that are safe to log and echo
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID returns 16 random hex characters
+func newRequestID() string {
+	buf := make([]byte, 8)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
 
 // LoggerMiddleware logs HTTP requests with structured logging
-func LoggerMiddleware(log *logger.Logger) gin.HandlerFunc {
+// It also stores a logger tagged with the request ID, client IP and route in the request context,
+// so lines logged further down, e.g. by the service, can be matched to the request.
+// Requests to quiet paths are logged at debug level unless they fail with a 5xx.
+func LoggerMiddleware(log *logger.Logger, quiet QuietPaths) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		start := time.Now()
 		path := c.Request.URL.Path
 		query := c.Request.URL.RawQuery
 
+		ctx := c.Request.Context()
+		requestLog := log.WithFields(map[string]interface{}{
+			"request_id": logger.RequestIDFromContext(ctx),
+			"ip":         clientIP(c),
+			"route":      c.FullPath(),
+		})
+		c.Request = c.Request.WithContext(logger.WithContext(ctx, requestLog))
+
 		// Process request
 		c.Next()
 
@@ -30,38 +105,74 @@ func LoggerMiddleware(log *logger.Logger) gin.HandlerFunc {
 		end := time.Now()
 		latency := end.Sub(start)
 
-		clientIP := c.ClientIP()
 		method := c.Request.Method
 		statusCode := c.Writer.Status()
 		errorMessage := c.Errors.ByType(gin.ErrorTypePrivate).String()
 
-		log.Info("HTTP request",
+		logRequest := log.Info
+		if quiet.Has(path) && statusCode < http.StatusInternalServerError {
+			logRequest = log.Debug
+		}
+		logRequest("HTTP request",
 			"status", statusCode,
 			"method", method,
 			"path", path,
 			"query", query,
-			"ip", clientIP,
+			"ip", clientIP(c),
 			"latency", latency,
 			"user_agent", c.Request.UserAgent(),
 			"error", errorMessage,
+			"request_id", logger.RequestIDFromContext(c.Request.Context()),
 		)
 	}
 }
 
+// QuietPaths is the set of request paths, matched exactly, that QUIET_PATHS exempts from rate limits and the request log
+// Health probes and metrics scrapes arrive every few seconds from the same node addresses; limiting them
+// would eat into the budget of clients sharing the address, and logging them drowns real traffic.
+type QuietPaths map[string]bool
+
+// NewQuietPaths builds the set from the configured paths
+func NewQuietPaths(paths []string) QuietPaths {
+	quiet := make(QuietPaths, len(paths))
+	for _, path := range paths {
+		quiet[path] = true
+	}
+	return quiet
+}
+
+// Has reports whether path is quiet; a nil set has no quiet paths
+func (q QuietPaths) Has(path string) bool {
+	return q[path]
+}
+
+// clientIP returns the caller's canonical address, resolving it only on the first call of a request
+// The logger, the rate limiter and the handlers all need it, and gin re-parses the forwarding headers every time.
+// A proxy forwarding ::ffff:192.0.2.1 and one forwarding 192.0.2.1 report the same client.
+func clientIP(c *gin.Context) string {
+	if ip := c.GetString(clientIPContextKey); ip != "" {
+		return ip
+	}
+	ip := clientip.Canonical(c.ClientIP())
+	c.Set(clientIPContextKey, ip)
+	return ip
+}
+
 // CORSMiddleware handles Cross-Origin Resource Sharing
-func CORSMiddleware(cfg *config.Config) gin.HandlerFunc {
+// The allowed origins are read from runtime on every request, so a reload changes them for the next one
+func CORSMiddleware(cfg *config.Config, runtime *config.Runtime) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		origin := c.Request.Header.Get("Origin")
 		
-		// Allow specific origins in production, all in development
-		if cfg.IsDevelopment() || origin == "https://yourdomain.com" {
+		// Allow the configured origins in production, all in development
+		if origin != "" && (cfg.IsDevelopment() || runtime.Load().AllowsOrigin(origin)) {
 			c.Writer.Header().Set("Access-Control-Allow-Origin", origin)
 		}
 		
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
 		c.Writer.Header().Set("Access-Control-Allow-Headers", 
-			"Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
+			"Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, X-Management-Token, X-Request-ID")
+		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, PATCH, DELETE")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -85,65 +196,401 @@ func SecurityHeadersMiddleware() gin.HandlerFunc {
 	}
 }
 
-// RateLimitMiddleware implements IP-based rate limiting
-func RateLimitMiddleware(requestsPerMinute int) gin.HandlerFunc {
+// BodyLimitMiddleware caps request bodies at maxBytes so clients can't make the server buffer megabytes
+// Declared lengths over the cap are refused up front; chunked bodies fail with 413 once reading passes it
+func BodyLimitMiddleware(maxBytes int64) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		clientIP := c.ClientIP()
-		
-		limiter, exists := rateLimiters[clientIP]
-		if !exists {
-			limiter = rate.NewLimiter(rate.Every(time.Minute/time.Duration(requestsPerMinute)), requestsPerMinute)
-			rateLimiters[clientIP] = limiter
+		if c.Request.ContentLength > maxBytes {
+			writeBodyTooLarge(c, maxBytes)
+			return
 		}
 
-		if !limiter.Allow() {
-			c.JSON(http.StatusTooManyRequests, domain.ErrorResponse{
-				Error:   "rate_limit_exceeded",
-				Message: "Too many requests, please try again later",
-				Code:    http.StatusTooManyRequests,
-			})
-			c.Abort()
+		if c.Request.Body != nil {
+			c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes)
+		}
+		c.Next()
+	}
+}
+
+// Rate limit buckets; each route group draws from its own
+const (
+	RateLimitBucketRedirects = "redirects"  // Short link redirects and their continue hop
+	RateLimitBucketAPIReads  = "api_reads"  // GET, HEAD and OPTIONS on /api/v1
+	RateLimitBucketAPIWrites = "api_writes" // Every other method on /api/v1, e.g. creating links, and GET /api/v1/shorten
+)
+
+// limiterKey identifies a rate limit bucket
+// Keys and IPs live in separate namespaces so a key fingerprint can never collide with an address
+type limiterKey struct {
+	bucket string // Route group the limit applies to
+	kind   string // "ip" or "key"
+	id     string
+}
+
+// limiterStore holds one token bucket per client
+type limiterStore struct {
+	mu       sync.Mutex
+	limiters map[limiterKey]*rate.Limiter
+}
+
+// get returns the bucket for key, creating it with the given per-minute rate
+// A bucket whose limit was changed by a reload starts over full at the new rate
+func (s *limiterStore) get(key limiterKey, requestsPerMinute int) *rate.Limiter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	limiter, exists := s.limiters[key]
+	if !exists || limiter.Burst() != requestsPerMinute {
+		limiter = rate.NewLimiter(rate.Every(time.Minute/time.Duration(requestsPerMinute)), requestsPerMinute)
+		s.limiters[key] = limiter
+	}
+	return limiter
+}
+
+// RateLimiter hands out rate limit middleware for route groups that share one client store
+// A client only gets an entry for the buckets it actually uses
+type RateLimiter struct {
+	store      *limiterStore
+	tiers      map[string]int
+	runtime    *config.Runtime // When set, limits and tiers are read from it per request
+	quiet      QuietPaths      // Paths passed through without taking a token
+	ipv6Prefix int             // Anonymous IPv6 callers share a bucket per prefix of this length (128 = per address)
+}
+
+// NewRateLimiter returns a limiter applying tiers, requests per minute by key fingerprint, in every bucket
+func NewRateLimiter(tiers map[string]int) *RateLimiter {
+	return &RateLimiter{store: &limiterStore{limiters: make(map[limiterKey]*rate.Limiter)}, tiers: tiers}
+}
+
+// NewRuntimeRateLimiter returns a limiter whose limits and tiers follow runtime across reloads
+// Use RuntimeRedirectMiddleware and RuntimeMethodMiddleware to take the bucket limits from it as well
+func NewRuntimeRateLimiter(runtime *config.Runtime) *RateLimiter {
+	l := NewRateLimiter(nil)
+	l.runtime = runtime
+	return l
+}
+
+// SkipQuietPaths lets requests to quiet paths through every bucket without creating an entry or taking a token
+// Returns l, so it can be chained onto the constructor
+func (l *RateLimiter) SkipQuietPaths(quiet QuietPaths) *RateLimiter {
+	l.quiet = quiet
+	return l
+}
+
+// GroupIPv6 keys anonymous IPv6 callers by their first bits bits, e.g. RATE_LIMIT_IPV6_PREFIX
+// Returns l, so it can be chained onto the constructor
+func (l *RateLimiter) GroupIPv6(bits int) *RateLimiter {
+	l.ipv6Prefix = bits
+	return l
+}
+
+// Middleware limits requests in bucket per API key, or per IP for anonymous callers
+// Identified keys use their api_keys tier, then their entry in tiers, falling back to requestsPerMinute
+// Must run after APIKeyIdentityMiddleware so the key identity is in the context
+func (l *RateLimiter) Middleware(bucket string, requestsPerMinute int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		l.limit(c, bucket, requestsPerMinute)
+	}
+}
+
+// MethodMiddleware limits reads and writes of the same route group in separate buckets
+func (l *RateLimiter) MethodMiddleware(readsPerMinute, writesPerMinute int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		l.limitMethod(c, readsPerMinute, writesPerMinute)
+	}
+}
+
+// RuntimeRedirectMiddleware is Middleware for the redirects bucket with the limit read from the runtime settings
+func (l *RateLimiter) RuntimeRedirectMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		l.limit(c, RateLimitBucketRedirects, l.runtime.Load().RateLimitRedirects)
+	}
+}
+
+// RuntimeMethodMiddleware is MethodMiddleware with the limits read from the runtime settings
+func (l *RateLimiter) RuntimeMethodMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		settings := l.runtime.Load()
+		l.limitMethod(c, settings.RateLimitAPIReads, settings.RateLimitAPIWrites)
+	}
+}
+
+// shortenLinkRoute is the GET route that creates links, limited as a write despite its method
+const shortenLinkRoute = "/api/v1/shorten"
+
+// limitMethod picks the read or write bucket by request method
+func (l *RateLimiter) limitMethod(c *gin.Context, readsPerMinute, writesPerMinute int) {
+	switch c.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		if c.Request.Method == http.MethodGet && c.FullPath() == shortenLinkRoute {
+			l.limit(c, RateLimitBucketAPIWrites, writesPerMinute)
 			return
 		}
+		l.limit(c, RateLimitBucketAPIReads, readsPerMinute)
+	default:
+		l.limit(c, RateLimitBucketAPIWrites, writesPerMinute)
+	}
+}
 
+// limit takes a token from the caller's bucket, answering 429 with the bucket's name when it is empty
+func (l *RateLimiter) limit(c *gin.Context, bucket string, requestsPerMinute int) {
+	if l.quiet.Has(c.Request.URL.Path) {
 		c.Next()
+		return
 	}
+	
+	key := limiterKey{bucket: bucket, kind: "ip", id: clientip.Network(clientIP(c), l.ipv6Prefix)}
+	limit := requestsPerMinute
+	
+	tiers := l.tiers
+	if l.runtime != nil {
+		tiers = l.runtime.Load().RateLimitTiers
+	}
+	
+	if keyID := actorKeyID(c.GetString(actorContextKey)); keyID != "" {
+		key = limiterKey{bucket: bucket, kind: "key", id: keyID}
+		if tier, ok := tiers[keyID]; ok {
+			limit = tier
+		}
+		if tier := c.GetInt(keyTierContextKey); tier != 0 {
+			limit = tier
+		}
+	}
+	
+	if limit == config.RateLimitUnlimited {
+		c.Next()
+		return
+	}
+	
+	if !l.store.get(key, limit).Allow() {
+		c.Header("X-RateLimit-Bucket", bucket)
+		respondError(c, http.StatusTooManyRequests, domain.ErrorResponse{
+			Error:   "rate_limit_exceeded",
+			Message: fmt.Sprintf("Too many requests in the %s rate limit bucket, please try again later", bucket),
+			Code:    http.StatusTooManyRequests,
+		})
+		c.Abort()
+		return
+	}
+
+	c.Next()
+}
+
+// RateLimitMiddleware limits all requests in a single bucket with a store of its own
+// Routers with several route groups use NewRateLimiter so the groups share one store
+func RateLimitMiddleware(requestsPerMinute int, tiers map[string]int) gin.HandlerFunc {
+	return NewRateLimiter(tiers).Middleware("default", requestsPerMinute)
 }
 
 // AuthMiddleware validates API keys for protected endpoints
-func AuthMiddleware(cfg *config.Config) gin.HandlerFunc {
+// Accepts the bootstrap API_KEY and every active key in the api_keys table
+func AuthMiddleware(cfg *config.Config, keys *apikey.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		if !cfg.EnableAuthentication {
+		if authenticate(c, cfg, keys) {
+			c.Next()
+		}
+	}
+}
+
+// authenticate checks the request's API key when authentication is enabled
+// It answers 401 and aborts the request when the key is missing or unknown, and reports whether it may go on
+func authenticate(c *gin.Context, cfg *config.Config, keys *apikey.Store) bool {
+	if !cfg.EnableAuthentication {
+		return true
+	}
+
+	apiKey := c.GetHeader("X-API-Key")
+	if apiKey == "" {
+		apiKey = c.Query("api_key")
+	}
+
+	if !isBootstrapKey(cfg, apiKey) && !isIssuedKey(c, keys, apiKey) {
+		respondError(c, http.StatusUnauthorized, domain.ErrorResponse{
+			Error:   "unauthorized",
+			Message: "Valid API key required",
+			Code:    http.StatusUnauthorized,
+		})
+		c.Abort()
+		return false
+	}
+
+	c.Set(actorContextKey, "api_key:"+keyFingerprint(apiKey))
+	return true
+}
+
+// ManagementAuthMiddleware lets link creators and admins through to edit and delete endpoints
+// The admin key skips the API key check. A request carrying X-Management-Token only puts it off: the handler
+// verifies the token against the link, and runs the API key check after all when the link has no token.
+// Everyone else goes through AuthMiddleware as before.
+func ManagementAuthMiddleware(cfg *config.Config, keys *apikey.Store) gin.HandlerFunc {
+	auth := AuthMiddleware(cfg, keys)
+	return func(c *gin.Context) {
+		if isAdminKey(cfg, c.GetHeader("X-API-Key")) {
+			c.Next()
+			return
+		}
+		if c.GetHeader(managementTokenHeader) != "" {
+			c.Set(deferredAuthContextKey, func() bool { return authenticate(c, cfg, keys) })
 			c.Next()
 			return
 		}
+		auth(c)
+	}
+}
 
-		apiKey := c.GetHeader("X-API-Key")
-		if apiKey == "" {
-			apiKey = c.Query("api_key")
+// ShortenLinkAuthMiddleware guards GET /api/v1/shorten, which bookmarklets and share targets call as a plain link
+// The link carries an API key as ?key=, or ?ts= and ?sig= signing url and ts with SHORTEN_LINK_SECRET. Signed
+// links are only accepted within SHORTEN_LINK_MAX_SKEW_SECONDS of ts, so one found in a log can't be replayed later
+func ShortenLinkAuthMiddleware(cfg *config.Config, keys *apikey.Store) gin.HandlerFunc {
+	var linkSigner *signer.Signer
+	if cfg.ShortenLinkSecret != "" {
+		linkSigner = signer.New([]byte(cfg.ShortenLinkSecret))
+	}
+
+	return func(c *gin.Context) {
+		// A GET that creates something must never be answered from a cache, refusals included
+		c.Header("Cache-Control", "no-store")
+
+		if apiKey := c.Query("key"); apiKey != "" {
+			switch {
+			case isBootstrapKey(cfg, apiKey):
+				c.Set(actorContextKey, "api_key:"+keyFingerprint(apiKey))
+			case isIssuedKey(c, keys, apiKey):
+			default:
+				respondError(c, http.StatusUnauthorized, domain.ErrorResponse{
+					Error:   "unauthorized",
+					Message: "Valid API key required",
+					Code:    http.StatusUnauthorized,
+				})
+				c.Abort()
+				return
+			}
+			c.Next()
+			return
 		}
 
-		if apiKey != cfg.APIKey {
-			c.JSON(http.StatusUnauthorized, domain.ErrorResponse{
+		if linkSigner == nil || c.Query("sig") == "" {
+			message := "API key required as ?key="
+			if linkSigner != nil {
+				message = "API key required as ?key=, or a signature as ?ts= and ?sig="
+			}
+			respondError(c, http.StatusUnauthorized, domain.ErrorResponse{
 				Error:   "unauthorized",
-				Message: "Valid API key required",
+				Message: message,
+				Code:    http.StatusUnauthorized,
+			})
+			c.Abort()
+			return
+		}
+
+		err := linkSigner.VerifyTimestamp(c.Query("sig"), c.Query("url"), c.Query("ts"), time.Now(), cfg.ShortenLinkMaxSkew)
+		if err != nil {
+			message := "Signature doesn't match url and ts"
+			if errors.Is(err, signer.ErrTokenExpired) {
+				message = "Signed link is too old or too far in the future, sign it again with the current time"
+			}
+			respondError(c, http.StatusUnauthorized, domain.ErrorResponse{
+				Error:   "invalid_signature",
+				Message: message,
 				Code:    http.StatusUnauthorized,
 			})
 			c.Abort()
 			return
 		}
 
+		c.Set(actorContextKey, "signed_link")
 		c.Next()
 	}
 }
 
-// TimeoutMiddleware sets a timeout for request processing
-func TimeoutMiddleware(timeout time.Duration) gin.HandlerFunc {
+// APIKeyIdentityMiddleware records the caller's key fingerprint when a valid API key is sent
+// Unlike AuthMiddleware it never rejects, so anonymous requests to public endpoints still pass
+// Invalid keys are ignored, otherwise random keys would each get a fresh rate limit bucket
+func APIKeyIdentityMiddleware(cfg *config.Config, keys *apikey.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
-		defer cancel()
-		
-		c.Request = c.Request.WithContext(ctx)
+		apiKey := c.GetHeader("X-API-Key")
+		switch {
+		case apiKey == "":
+		case isBootstrapKey(cfg, apiKey):
+			c.Set(actorContextKey, "api_key:"+keyFingerprint(apiKey))
+		case isAdminKey(cfg, apiKey):
+			c.Set(actorContextKey, "admin:"+keyFingerprint(apiKey))
+		default:
+			isIssuedKey(c, keys, apiKey)
+		}
 		c.Next()
 	}
-}
\ No newline at end of file
+}
+
+// isBootstrapKey reports whether apiKey is the static API_KEY from the environment
+func isBootstrapKey(cfg *config.Config, apiKey string) bool {
+	return cfg.APIKey != "" && subtle.ConstantTimeCompare([]byte(apiKey), []byte(cfg.APIKey)) == 1
+}
+
+// isAdminKey reports whether apiKey is the ADMIN_API_KEY from the environment
+func isAdminKey(cfg *config.Config, apiKey string) bool {
+	return cfg.AdminAPIKey != "" && subtle.ConstantTimeCompare([]byte(apiKey), []byte(cfg.AdminAPIKey)) == 1
+}
+
+// isIssuedKey validates apiKey against the api_keys table and records its identity, tier, tenant and expiry settings
+func isIssuedKey(c *gin.Context, keys *apikey.Store, apiKey string) bool {
+	key, ok := keys.Validate(c.Request.Context(), apiKey)
+	if !ok {
+		return false
+	}
+
+	c.Set(actorContextKey, "api_key:"+key.Fingerprint)
+	c.Set(tenantContextKey, key.TenantID)
+	// Keys sent as ?api_key= are only seen by AuthMiddleware, after TenantMiddleware ran
+	c.Request = c.Request.WithContext(domain.ContextWithKeyExpiry(domain.ContextWithTenant(c.Request.Context(), key.TenantID), key.Expiry()))
+	if key.Tier != 0 {
+		c.Set(keyTierContextKey, key.Tier)
+	}
+	return true
+}
+
+// actorKeyID extracts the key fingerprint from an actor such as "api_key:1a2b3c4d"
+// Returns an empty string for anonymous callers
+func actorKeyID(actor string) string {
+	_, keyID, ok := strings.Cut(actor, ":")
+	if !ok {
+		return ""
+	}
+	return keyID
+}
+
+// AdminAuthMiddleware protects admin endpoints with the separate ADMIN_API_KEY
+// The admin API is disabled entirely when no admin key is configured
+func AdminAuthMiddleware(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if cfg.AdminAPIKey == "" {
+			respondError(c, http.StatusForbidden, domain.ErrorResponse{
+				Error:   "admin_disabled",
+				Message: "Admin endpoints are disabled",
+				Code:    http.StatusForbidden,
+			})
+			c.Abort()
+			return
+		}
+
+		apiKey := c.GetHeader("X-API-Key")
+		if subtle.ConstantTimeCompare([]byte(apiKey), []byte(cfg.AdminAPIKey)) != 1 {
+			respondError(c, http.StatusUnauthorized, domain.ErrorResponse{
+				Error:   "unauthorized",
+				Message: "Valid admin API key required",
+				Code:    http.StatusUnauthorized,
+			})
+			c.Abort()
+			return
+		}
+
+		c.Set(actorContextKey, "admin:"+keyFingerprint(apiKey))
+		c.Next()
+	}
+}
+
+// keyFingerprint identifies an API key in logs and audit records without revealing it
+func keyFingerprint(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:4])
+}
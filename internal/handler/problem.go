@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"url-shortener/internal/config"
+	"url-shortener/internal/domain"
+	"url-shortener/pkg/logger"
+)
+
+// problemContentType is the media type of RFC 7807 error bodies
+const problemContentType = "application/problem+json"
+
+// errorCatalogPath documents every error value; problem types point into it by fragment
+const errorCatalogPath = "/api/v1/errors"
+
+// errorFormatContextKey holds the errorFormat ErrorFormatMiddleware chose for the request
+const errorFormatContextKey = "error_format"
+
+// errorFormat is how error responses of one request are written
+type errorFormat struct {
+	problem   bool   // Every error is problem+json, whatever the client accepts
+	typesBase string // Absolute URL of the error catalog
+}
+
+// ErrorFormatMiddleware fixes the error format for the request: problem details for everyone with
+// PROBLEM_DETAILS, otherwise only for clients that accept application/problem+json
+func ErrorFormatMiddleware(cfg *config.Config) gin.HandlerFunc {
+	format := errorFormat{problem: cfg.ProblemDetails, typesBase: strings.TrimRight(cfg.BaseURL, "/") + errorCatalogPath}
+	return func(c *gin.Context) {
+		c.Set(errorFormatContextKey, format)
+		c.Next()
+	}
+}
+
+// respondError writes an error body in the format the request negotiated
+// Browser form posts have their errors rendered into the page they came from instead
+func respondError(c *gin.Context, status int, body domain.ErrorResponse) {
+	if value, exists := c.Get(htmlErrorsContextKey); exists {
+		render := value.(func(int, domain.ErrorResponse))
+		render(status, body)
+		return
+	}
+	contentType, data := encodeError(c, status, body)
+	c.Data(status, contentType, data)
+}
+
+// abortWithError is respondError for middleware that stops the chain
+func abortWithError(c *gin.Context, status int, body domain.ErrorResponse) {
+	respondError(c, status, body)
+	c.Abort()
+}
+
+// encodeError renders an error body without writing it, for writers that send it later
+func encodeError(c *gin.Context, status int, body domain.ErrorResponse) (string, []byte) {
+	if !wantsProblem(c) {
+		data, _ := json.Marshal(body)
+		return "application/json; charset=utf-8", data
+	}
+
+	data, _ := json.Marshal(newProblem(c, status, body))
+	return problemContentType, data
+}
+
+// newProblem maps an error body onto RFC 7807 members
+// The type is the error value's entry in the catalog, so one type covers every status it is sent with
+func newProblem(c *gin.Context, status int, body domain.ErrorResponse) domain.ProblemDetails {
+	typesBase := errorCatalogPath
+	if format, ok := requestErrorFormat(c); ok {
+		typesBase = format.typesBase
+	}
+
+	instance := c.Request.URL.Path
+	if id := logger.RequestIDFromContext(c.Request.Context()); id != "" {
+		instance += "#" + id
+	}
+
+	return domain.ProblemDetails{
+		Type:        typesBase + "#" + strings.ReplaceAll(body.Error, " ", "_"),
+		Title:       problemTitle(body.Error, status),
+		Status:      status,
+		Detail:      body.Message,
+		Instance:    instance,
+		Error:       body.Error,
+		Suggestions: body.Suggestions,
+		Errors:      body.Errors,
+	}
+}
+
+// problemTitle is the catalog description of an error value, which stays the same for every occurrence
+func problemTitle(code string, status int) string {
+	for _, entry := range ErrorCatalog {
+		if entry.Error == code {
+			return entry.Description
+		}
+	}
+	return http.StatusText(status)
+}
+
+// requestErrorFormat returns the format ErrorFormatMiddleware stored; routers without it get relative types
+func requestErrorFormat(c *gin.Context) (errorFormat, bool) {
+	value, ok := c.Get(errorFormatContextKey)
+	if !ok {
+		return errorFormat{}, false
+	}
+	format, ok := value.(errorFormat)
+	return format, ok
+}
+
+// wantsProblem reports whether the request gets problem details instead of ErrorResponse
+func wantsProblem(c *gin.Context) bool {
+	if format, ok := requestErrorFormat(c); ok && format.problem {
+		return true
+	}
+	return acceptsProblem(c.GetHeader("Accept"))
+}
+
+// acceptsProblem reports whether an Accept header lists application/problem+json with a non-zero quality
+// Wildcards don't count: clients that take anything keep getting the default shape
+func acceptsProblem(accept string) bool {
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil || mediaType != problemContentType {
+			continue
+		}
+		if q, ok := params["q"]; ok {
+			if quality, err := strconv.ParseFloat(q, 64); err != nil || quality == 0 {
+				continue
+			}
+		}
+		return true
+	}
+	return false
+}
@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"url-shortener/internal/domain"
+)
+
+// publicStatsCacheControl lets browsers and CDNs reuse a public stats page for a minute
+// The counts move with every click, so hot links would otherwise cost a query per view.
+const publicStatsCacheControl = "public, max-age=60"
+
+// PublicStats handles GET /:shortCode/stats, reached through RedirectPath
+// Browsers get an HTML page and other clients the JSON. Links without public_stats answer like an
+// unknown path, so a private link's stats page doesn't reveal that the link exists.
+func (h *URLHandler) PublicStats(c *gin.Context) {
+	shortCode := c.Param("shortCode")
+
+	stats, err := h.service.GetPublicStats(c.Request.Context(), shortCode)
+	if errors.Is(err, domain.ErrURLNotFound) {
+		h.NoRoute(c)
+		return
+	}
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", publicStatsCacheControl)
+	addVary(c.Writer.Header(), "Accept")
+	if !wantsHTML(c) {
+		c.JSON(http.StatusOK, stats)
+		return
+	}
+
+	var busiest int64
+	for _, day := range stats.Daily {
+		busiest = max(busiest, day.Clicks)
+	}
+	page := publicStatsPage{
+		ShortCode:   stats.ShortCode,
+		TotalClicks: stats.TotalClicks,
+		CreatedAt:   stats.CreatedAt.UTC().Format("2006-01-02"),
+	}
+	for _, day := range stats.Daily {
+		row := publicStatsDay{Date: day.Date, Clicks: day.Clicks}
+		if busiest > 0 {
+			row.Percent = day.Clicks * 100 / busiest
+		}
+		page.Days = append(page.Days, row)
+	}
+	h.renderPage(c, http.StatusOK, publicStatsTemplate, page)
+}
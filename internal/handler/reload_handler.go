@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"url-shortener/internal/config"
+	"url-shortener/internal/domain"
+	"url-shortener/pkg/logger"
+)
+
+// ReloadHandler re-reads the configuration and applies the settings that can change without a restart
+type ReloadHandler struct {
+	runtime *config.Runtime
+	logger  *logger.Logger
+}
+
+// NewReloadHandler creates a handler reloading runtime
+func NewReloadHandler(runtime *config.Runtime, logger *logger.Logger) *ReloadHandler {
+	return &ReloadHandler{runtime: runtime, logger: logger}
+}
+
+// ReloadConfig reloads the configuration and logs the outcome; SIGHUP and the admin endpoint share it
+func (h *ReloadHandler) ReloadConfig() ([]string, error) {
+	changed, err := h.runtime.Reload()
+	switch {
+	case errors.Is(err, config.ErrRestartRequired):
+		h.logger.Error("Configuration reload rejected, nothing was applied", "error", err)
+	case err != nil:
+		h.logger.Error("Configuration reload failed, nothing was applied", "error", err)
+	case len(changed) == 0:
+		h.logger.Info("Configuration reloaded, no reloadable setting changed")
+	default:
+		h.logger.Info("Configuration reloaded", "changed", changed)
+	}
+	return changed, err
+}
+
+// Reload handles POST /api/v1/admin/reload
+func (h *ReloadHandler) Reload(c *gin.Context) {
+	changed, err := h.ReloadConfig()
+	if errors.Is(err, config.ErrRestartRequired) {
+		respondError(c, http.StatusConflict, domain.ErrorResponse{
+			Error:   "restart_required",
+			Message: err.Error(),
+			Code:    http.StatusConflict,
+		})
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, domain.ErrorResponse{
+			Error:   "invalid_config",
+			Message: err.Error(),
+			Code:    http.StatusInternalServerError,
+		})
+		return
+	}
+
+	if changed == nil {
+		changed = []string{}
+	}
+	c.JSON(http.StatusOK, gin.H{"changed": changed})
+}
@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+
+	"url-shortener/internal/domain"
+	"url-shortener/pkg/logger"
+)
+
+// errTrailingData is returned when a body holds more than one JSON value
+var errTrailingData = errors.New("unexpected data after the JSON object")
+
+// bindStrictJSON decodes the request body into dst and runs its binding validation
+// Unlike ShouldBindJSON it rejects unknown fields, so typos such as "custom_alais" are not silently ignored.
+// Validation failures come back as invalidFields.
+func bindStrictJSON(c *gin.Context, dst interface{}) error {
+	if c.Request.Body == nil {
+		return io.EOF
+	}
+
+	dec := json.NewDecoder(c.Request.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(dst); err != nil {
+		return err
+	}
+	if err := dec.Decode(&json.RawMessage{}); err != io.EOF {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return err
+		}
+		return errTrailingData
+	}
+
+	return fieldErrorsOf(dst, binding.Validator.ValidateStruct(dst))
+}
+
+// writeBindError answers a request whose JSON body could not be decoded
+// Bodies cut off by BodyLimitMiddleware get 413, everything else 400 with a reason the client can act on
+func writeBindError(c *gin.Context, log *logger.Logger, err error) {
+	log.Warn("Invalid request body", "error", err)
+
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		writeBodyTooLarge(c, tooLarge.Limit)
+		return
+	}
+
+	respondError(c, http.StatusBadRequest, domain.ErrorResponse{
+		Error:   "invalid_request",
+		Message: "Invalid request body: " + describeBindError(err),
+		Code:    http.StatusBadRequest,
+		Errors:  requestFieldErrors(err),
+	})
+}
+
+// writeBodyTooLarge aborts with 413 for a body over the configured limit
+func writeBodyTooLarge(c *gin.Context, limit int64) {
+	abortWithError(c, http.StatusRequestEntityTooLarge, domain.ErrorResponse{
+		Error:   "request_too_large",
+		Message: fmt.Sprintf("Request body must not exceed %d bytes", limit),
+		Code:    http.StatusRequestEntityTooLarge,
+	})
+}
+
+// describeBindError turns decoder errors into messages that name the offending field or position
+func describeBindError(err error) string {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var timeErr *time.ParseError
+
+	switch {
+	case errors.Is(err, io.EOF):
+		return "body is empty"
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return "body is truncated"
+	case errors.As(err, &syntaxErr):
+		return fmt.Sprintf("malformed JSON at byte %d", syntaxErr.Offset)
+	case errors.As(err, &typeErr):
+		return fmt.Sprintf("field %q must be %s", typeErr.Field, typeErr.Type)
+	case errors.As(err, &timeErr):
+		// encoding/json doesn't say which field held the timestamp
+		return fmt.Sprintf("timestamp %q must be RFC3339, e.g. 2025-06-03T18:00:00Z", timeErr.Value)
+	}
+
+	// encoding/json has no typed error for unknown fields, only this message
+	if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		return "unknown field " + field
+	}
+	return err.Error()
+}
@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/service"
+)
+
+// ShortenLink handles GET /api/v1/shorten?url=, for bookmarklets and share targets that can only open a link
+// ShortenLinkAuthMiddleware checks ?key= or the signature first. The response is the one of POST /api/v1/shorten;
+// with ?redirect=true and ENABLE_WEB_UI the browser is sent on to the form page showing the new link instead.
+func (h *URLHandler) ShortenLink(c *gin.Context) {
+	toPage := false
+	if raw := c.Query("redirect"); raw != "" {
+		var err error
+		if toPage, err = strconv.ParseBool(raw); err != nil {
+			respondError(c, http.StatusBadRequest, domain.ErrorResponse{
+				Error:   "invalid_request",
+				Message: "redirect must be true or false",
+				Code:    http.StatusBadRequest,
+			})
+			return
+		}
+	}
+	if toPage && !h.cfg.EnableWebUI {
+		respondError(c, http.StatusBadRequest, domain.ErrorResponse{
+			Error:   "invalid_request",
+			Message: "redirect needs the web UI, which is disabled",
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	// Only the URL can be given; aliases and expiry stay with the POST body
+	req := domain.CreateURLRequest{URL: strings.TrimSpace(c.Query("url"))}
+	if err := fieldErrorsOf(&req, binding.Validator.ValidateStruct(&req)); err != nil {
+		writeBindError(c, h.logger, err)
+		return
+	}
+
+	ctx := service.ContextWithAPIKey(c.Request.Context(), c.GetString(actorContextKey))
+	response, err := h.service.ShortenURL(ctx, &req, creatorContext(c))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	status := writeShortenHeaders(c, response)
+	if toPage {
+		// See Other, so reloading the page doesn't repeat the request
+		c.Redirect(http.StatusSeeOther, "/?created="+url.QueryEscape(response.ShortCode))
+		return
+	}
+	c.JSON(status, response)
+}
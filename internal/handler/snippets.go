@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"html"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"url-shortener/internal/domain"
+	"url-shortener/pkg/validator"
+)
+
+// markdownURLEscaper keeps a short URL from ending the Markdown link target early
+var markdownURLEscaper = strings.NewReplacer("(", "%28", ")", "%29", " ", "%20", "<", "%3C", ">", "%3E")
+
+// wantsSnippets reports whether ?include_snippets or the body flag asks for snippets
+// An unparsable query value is answered with a 400 and ok is false.
+func wantsSnippets(c *gin.Context, body bool) (want, ok bool) {
+	raw := c.Query("include_snippets")
+	if raw == "" {
+		return body, true
+	}
+	want, err := strconv.ParseBool(raw)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, domain.ErrorResponse{
+			Error:   "invalid_request",
+			Message: "invalid 'include_snippets' value: " + raw,
+			Code:    http.StatusBadRequest,
+		})
+		return false, false
+	}
+	return want || body, true
+}
+
+// newSnippets formats shortURL under label for Markdown, HTML and plain text
+// The label may come from a destination's page title, so it is reduced to one line of text and escaped
+// for each format; nothing in it can add markup or break out of the link.
+func newSnippets(label, shortURL string) *domain.Snippets {
+	label = validator.StripHTML(label, false)
+	if label == "" {
+		label = shortURL
+	}
+
+	return &domain.Snippets{
+		Markdown: "[" + validator.EscapeMarkdown(label) + "](" + markdownURLEscaper.Replace(shortURL) + ")",
+		HTML:     `<a href="` + html.EscapeString(shortURL) + `">` + html.EscapeString(label) + "</a>",
+		Text:     label + ": " + shortURL,
+	}
+}
@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"net/http"
+	"os"
+
+	"github.com/gin-gonic/gin"
+
+	"url-shortener/internal/config"
+	"url-shortener/pkg/logger"
+)
+
+// defaultRobotsTxt keeps crawlers off short links, every crawl of one would be a redirect
+const defaultRobotsTxt = "User-agent: *\nDisallow: /\n"
+
+// staticMaxAge is how long clients may cache robots.txt and the favicon
+const staticMaxAge = "public, max-age=86400"
+
+// StaticHandler serves the well-known files crawlers and browsers fetch from the redirect domain
+// They are registered before the /:shortCode catch-all, so they never reach a link lookup
+type StaticHandler struct {
+	robots      []byte
+	favicon     []byte // nil answers 204 No Content
+	faviconType string
+}
+
+// NewStaticHandler loads ROBOTS_TXT_FILE and FAVICON_FILE once at startup
+// A configured file that can't be read is fatal, a typo in the path shouldn't go unnoticed
+func NewStaticHandler(cfg *config.Config, logger *logger.Logger) *StaticHandler {
+	h := &StaticHandler{robots: []byte(defaultRobotsTxt)}
+
+	if cfg.RobotsTxtFile != "" {
+		robots, err := os.ReadFile(cfg.RobotsTxtFile)
+		if err != nil {
+			logger.Fatal("Failed to load robots.txt", "error", err, "file", cfg.RobotsTxtFile)
+		}
+		h.robots = robots
+	}
+
+	if cfg.FaviconFile != "" {
+		favicon, err := os.ReadFile(cfg.FaviconFile)
+		if err != nil {
+			logger.Fatal("Failed to load favicon", "error", err, "file", cfg.FaviconFile)
+		}
+		h.favicon = favicon
+		h.faviconType = http.DetectContentType(favicon)
+	}
+
+	return h
+}
+
+// RobotsTxt handles GET /robots.txt
+func (h *StaticHandler) RobotsTxt(c *gin.Context) {
+	c.Header("Cache-Control", staticMaxAge)
+	c.Data(http.StatusOK, "text/plain; charset=utf-8", h.robots)
+}
+
+// Favicon handles GET /favicon.ico
+// Without a configured icon browsers get a cacheable 204, so they stop asking on every page
+func (h *StaticHandler) Favicon(c *gin.Context) {
+	c.Header("Cache-Control", staticMaxAge)
+	if h.favicon == nil {
+		c.Status(http.StatusNoContent)
+		return
+	}
+	c.Data(http.StatusOK, h.faviconType, h.favicon)
+}
@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"url-shortener/internal/domain"
+)
+
+const (
+	// defaultSummaryDays is the window used when ?days is omitted: the current week
+	defaultSummaryDays = 7
+
+	// defaultSummaryLimit is the number of top links returned when ?limit is omitted
+	defaultSummaryLimit = 10
+)
+
+// GetSummary handles GET /api/v1/stats/summary
+// Returns service-wide totals, the most clicked links and daily creation counts
+func (h *URLHandler) GetSummary(c *gin.Context) {
+	window, err := parseSummaryWindow(c)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, domain.ErrorResponse{
+			Error:   "invalid_window",
+			Message: err.Error(),
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	summary, err := h.service.GetSummary(c.Request.Context(), window)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
+
+// parseSummaryWindow reads the optional days and limit query parameters
+// Range checks are left to the service so every caller gets the same bounds
+func parseSummaryWindow(c *gin.Context) (domain.SummaryWindow, error) {
+	window := domain.SummaryWindow{Days: defaultSummaryDays, Limit: defaultSummaryLimit}
+
+	if days := c.Query("days"); days != "" {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return window, fmt.Errorf("invalid 'days' value: %s", days)
+		}
+		window.Days = n
+	}
+
+	if limit := c.Query("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil {
+			return window, fmt.Errorf("invalid 'limit' value: %s", limit)
+		}
+		window.Limit = n
+	}
+
+	return window, nil
+}
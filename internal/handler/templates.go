@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"embed"
+	"fmt"
+	"html/template"
+	"path/filepath"
+)
+
+// templateFS holds the HTML pages served to browsers
+//
+//go:embed templates/*.html
+var templateFS embed.FS
+
+// Page template names; an override directory must use the same file names
+const (
+	interstitialTemplate = "interstitial.html"
+	notFoundTemplate     = "not_found.html"
+	expiredTemplate      = "expired.html"
+	bundleTemplate       = "bundle.html"
+	bounceTemplate       = "bounce.html"
+	appLinkTemplate      = "app_link.html"
+	shortenFormTemplate  = "shorten_form.html"
+	publicStatsTemplate  = "public_stats.html"
+)
+
+// interstitialPage is the data rendered by templates/interstitial.html
+type interstitialPage struct {
+	ShortCode   string
+	Destination string
+	ContinueURL template.URL // Our signed continue link, or the destination itself when it isn't http(s)
+}
+
+// bouncePage is the data rendered by templates/bounce.html
+type bouncePage struct {
+	Destination string
+}
+
+// appLinkPage is the data rendered by templates/app_link.html
+type appLinkPage struct {
+	AppURL          template.URL // Validated deep link or the intent:// URL built from it; html/template would reject custom schemes
+	Fallback        string
+	FallbackDelayMS int64
+	Nonce           string // Lets the inline script run under the page's CSP
+}
+
+// bundlePage is the data rendered by templates/bundle.html
+type bundlePage struct {
+	ShortCode string
+	Items     []bundleLink
+}
+
+// bundleLink is one member on the bundle page
+// Href points at the member's own short link so the click is counted
+type bundleLink struct {
+	Title string
+	URL   string
+	Href  string
+}
+
+// shortenFormPage is the data rendered by templates/shorten_form.html
+// The submitted values are echoed back so a rejected link can be fixed instead of retyped
+type shortenFormPage struct {
+	URL         string
+	CustomAlias string
+	ExpiryDays  string
+	ShortURL    string   // Set once the link exists
+	Error       string   // Message of the error response the API would have sent
+	Suggestions []string // Free aliases when the custom alias is taken
+}
+
+// publicStatsPage is the data rendered by templates/public_stats.html
+type publicStatsPage struct {
+	ShortCode   string
+	TotalClicks int64
+	CreatedAt   string // UTC day the link was created
+	Days        []publicStatsDay
+}
+
+// publicStatsDay is one row of the daily chart
+type publicStatsDay struct {
+	Date    string
+	Clicks  int64
+	Percent int64 // Bar width relative to the busiest day of the series
+}
+
+// errorPage is the data rendered by the not_found and expired templates
+type errorPage struct {
+	ShortCode string // Empty for unknown paths that aren't short links
+}
+
+// pageCSP relaxes the global policy just enough for the pages' inline styles
+const pageCSP = "default-src 'none'; style-src 'unsafe-inline'; form-action 'self'"
+
+// loadPageTemplates parses the embedded pages, replaced by any same-named *.html files in dir
+// Pages missing from dir keep the embedded version, so a brand only has to override what it changes
+// html/template escapes all values in both cases
+func loadPageTemplates(dir string) (*template.Template, error) {
+	pages, err := template.ParseFS(templateFS, "templates/*.html")
+	if err != nil || dir == "" {
+		return pages, err
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.html"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid template directory %q: %w", dir, err)
+	}
+	if len(files) == 0 {
+		return pages, nil
+	}
+
+	if _, err := pages.ParseFiles(files...); err != nil {
+		return nil, fmt.Errorf("failed to parse templates in %q: %w", dir, err)
+	}
+	return pages, nil
+}
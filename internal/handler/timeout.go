@@ -0,0 +1,219 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"url-shortener/internal/domain"
+)
+
+// TimeoutMiddleware answers 504 when a handler hasn't started its response within timeout
+// The handler's context is canceled at the deadline, and anything it writes afterwards is discarded
+// A timeout of zero or less disables the middleware
+func TimeoutMiddleware(timeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if timeout <= 0 {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+
+		// Rendered up front: the deadline fires on its own goroutine, which must not touch the gin context
+		contentType, body := encodeError(c, http.StatusGatewayTimeout, domain.ErrorResponse{
+			Error:   "request_timeout",
+			Message: "The request took too long to process",
+			Code:    http.StatusGatewayTimeout,
+		})
+
+		original := c.Writer
+		tw := newTimeoutWriter(ctx, original, contentType, body)
+		c.Writer = tw
+		c.Request = c.Request.WithContext(ctx)
+
+		// The handler keeps the request goroutine, the deadline answers from its own
+		stop := context.AfterFunc(ctx, tw.expire)
+
+		c.Next()
+
+		stop()
+		tw.finish()
+		c.Writer = original
+	}
+}
+
+// timeoutWriter serializes a handler's writes with the 504 written when its deadline passes
+// Headers go to a private map until the handler commits, so the deadline never races a handler setting them
+type timeoutWriter struct {
+	gin.ResponseWriter
+
+	ctx      context.Context
+	mu       sync.Mutex
+	header   http.Header
+	timedOut bool // The 504 was written, the handler's output is discarded
+	done     bool // The handler returned, the deadline no longer applies
+
+	contentType string // Of the 504 body
+	body        []byte
+}
+
+// newTimeoutWriter wraps w for a handler running under ctx, starting from the headers earlier middleware set
+// contentType and body are the 504 sent at the deadline
+func newTimeoutWriter(ctx context.Context, w gin.ResponseWriter, contentType string, body []byte) *timeoutWriter {
+	return &timeoutWriter{
+		ResponseWriter: w,
+		ctx:            ctx,
+		header:         w.Header().Clone(),
+		contentType:    contentType,
+		body:           body,
+	}
+}
+
+// Header returns the handler's headers, sent when it writes its status or body
+func (w *timeoutWriter) Header() http.Header {
+	return w.header
+}
+
+// WriteHeader records the status unless the request already timed out
+func (w *timeoutWriter) WriteHeader(code int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.expireLocked()
+	if w.timedOut {
+		return
+	}
+	w.copyHeaders()
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// WriteHeaderNow sends the status and headers unless the request already timed out
+func (w *timeoutWriter) WriteHeaderNow() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.expireLocked()
+	if w.timedOut {
+		return
+	}
+	w.copyHeaders()
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+// Write sends body bytes, or fails with http.ErrHandlerTimeout once the 504 was written
+func (w *timeoutWriter) Write(data []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.expireLocked()
+	if w.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	w.copyHeaders()
+	return w.ResponseWriter.Write(data)
+}
+
+// WriteString is Write for strings
+func (w *timeoutWriter) WriteString(s string) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.expireLocked()
+	if w.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	w.copyHeaders()
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Flush pushes buffered output to the client unless the request already timed out
+func (w *timeoutWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.expireLocked()
+	if w.timedOut {
+		return
+	}
+	w.copyHeaders()
+	w.ResponseWriter.Flush()
+}
+
+// Status returns the status sent or about to be sent, 504 after a timeout
+func (w *timeoutWriter) Status() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.ResponseWriter.Status()
+}
+
+// Size returns the number of body bytes written
+func (w *timeoutWriter) Size() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.ResponseWriter.Size()
+}
+
+// Written reports whether a response was started, by the handler or by the timeout
+func (w *timeoutWriter) Written() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.ResponseWriter.Written()
+}
+
+// expire writes the 504 if the deadline passed before the handler returned or started its response
+func (w *timeoutWriter) expire() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.expireLocked()
+}
+
+// expireLocked is expire with mu held
+// Writes check it too, so a handler woken by the deadline can't slip its response in before the 504
+func (w *timeoutWriter) expireLocked() {
+	if w.timedOut || w.done || w.ResponseWriter.Written() || !errors.Is(w.ctx.Err(), context.DeadlineExceeded) {
+		return
+	}
+	w.timedOut = true
+
+	w.ResponseWriter.Header().Set("Content-Type", w.contentType)
+	w.ResponseWriter.WriteHeader(http.StatusGatewayTimeout)
+	w.ResponseWriter.Write(w.body)
+	w.ResponseWriter.Flush()
+}
+
+// finish marks the handler as returned and sends headers it set without writing a body
+// A handler that ignored its context and returned late without writing still gets the 504
+func (w *timeoutWriter) finish() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.expireLocked()
+	w.done = true
+	if !w.timedOut {
+		w.copyHeaders()
+	}
+}
+
+// copyHeaders replaces the underlying headers with the handler's until the response is committed
+// Must be called with mu held
+func (w *timeoutWriter) copyHeaders() {
+	if w.ResponseWriter.Written() {
+		return
+	}
+
+	dst := w.ResponseWriter.Header()
+	for key := range dst {
+		if _, ok := w.header[key]; !ok {
+			delete(dst, key)
+		}
+	}
+	for key, values := range w.header {
+		dst[key] = values
+	}
+}
@@ -1,27 +1,64 @@
 package handler
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
 	"errors"
+	"html/template"
+	"io"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 	
 	"github.com/gin-gonic/gin"
 	
+	"url-shortener/internal/config"
 	"url-shortener/internal/domain"
+	"url-shortener/internal/geo"
+	"url-shortener/internal/redirect"
 	"url-shortener/internal/service"
 	"url-shortener/pkg/logger"
+	"url-shortener/pkg/signer"
+	"url-shortener/pkg/validator"
 )
 
 // URLHandler handles HTTP requests for URL shortening operations
 type URLHandler struct {
 	service service.URLService
+	cfg     *config.Config
 	logger  *logger.Logger
+	signer  *signer.Signer // Signs interstitial continue tokens
+	pages   *template.Template // HTML pages for browser visitors
 }
 
 // NewURLHandler creates a new URL handler with dependencies
-func NewURLHandler(service service.URLService, logger *logger.Logger) *URLHandler {
+func NewURLHandler(service service.URLService, cfg *config.Config, logger *logger.Logger) *URLHandler {
+	var tokenSigner *signer.Signer
+	if cfg.InterstitialSecret != "" {
+		tokenSigner = signer.New([]byte(cfg.InterstitialSecret))
+	} else {
+		// Fall back to a per-process key; continue links won't survive restarts or cross replicas
+		var err error
+		tokenSigner, err = signer.NewRandom()
+		if err != nil {
+			logger.Fatal("Failed to initialize interstitial signer", "error", err)
+		}
+	}
+	
+	pages, err := loadPageTemplates(cfg.TemplateDir)
+	if err != nil {
+		logger.Fatal("Failed to load page templates", "error", err, "dir", cfg.TemplateDir)
+	}
+	
 	return &URLHandler{
 		service: service,
+		cfg:     cfg,
 		logger:  logger,
+		signer:  tokenSigner,
+		pages:   pages,
 	}
 }
 
@@ -30,31 +67,137 @@ func NewURLHandler(service service.URLService, logger *logger.Logger) *URLHandle
 func (h *URLHandler) ShortenURL(c *gin.Context) {
 	var req domain.CreateURLRequest
 	
-	// Bind and validate request body
-	if err := c.ShouldBindJSON(&req); err != nil {
-		h.logger.Warn("Invalid request body", "error", err)
-		c.JSON(http.StatusBadRequest, domain.ErrorResponse{
-			Error:   "invalid_request",
-			Message: "Invalid request body: " + err.Error(),
-			Code:    http.StatusBadRequest,
-		})
+	// Browsers posting the web form get the form back, with the new link or the error
+	page := h.shortenFormResult(c)
+	
+	// Bind and validate request body, rejecting fields the API doesn't know
+	var err error
+	if isFormPost(c) {
+		err = bindShortenForm(c, &req)
+	} else {
+		err = bindStrictJSON(c, &req)
+	}
+	if err != nil {
+		writeBindError(c, h.logger, err)
+		return
+	}
+	snippets, ok := wantsSnippets(c, req.IncludeSnippets)
+	if !ok {
 		return
 	}
 	
-	// Get client IP for tracking
-	clientIP := c.ClientIP()
+	// Who created the link is kept for abuse investigations
+	creator := creatorContext(c)
+	
+	// Identified API keys get their own daily quota on top of the per-IP one
+	ctx := service.ContextWithAPIKey(c.Request.Context(), c.GetString(actorContextKey))
 	
 	// Call service layer
-	response, err := h.service.ShortenURL(c.Request.Context(), &req, clientIP)
+	response, err := h.service.ShortenURL(ctx, &req, creator)
 	if err != nil {
+		if errors.Is(err, domain.ErrShortCodeTaken) && req.CustomAlias != "" && c.Query("suggestions") != "false" {
+			h.aliasTaken(c, req.CustomAlias)
+			return
+		}
 		h.handleError(c, err)
 		return
 	}
 	
+	status := writeShortenHeaders(c, response)
+	if snippets && response.ShortCode != "" {
+		response.Snippets = newSnippets(response.Label, response.ShortURL)
+	}
+	
+	if page != nil {
+		page.ShortURL = response.ShortURL
+		h.renderShortenForm(c, status, page)
+		return
+	}
+	
 	// Return success response
+	c.JSON(status, response)
+}
+
+// writeShortenHeaders sets the headers of a shorten response and returns its status
+func writeShortenHeaders(c *gin.Context, response *domain.CreateURLResponse) int {
+	switch {
+	case response.Deduplicated:
+		// Nothing was created for a duplicate, so it isn't a 201; the Link header names the link to use
+		c.Header("Link", "<"+response.ShortURL+">; rel=\"canonical\"")
+		return http.StatusOK
+	case response.DryRun:
+		// A dry run creates nothing and uses no quota
+		return http.StatusOK
+	default:
+		writeQuotaHeaders(c, response.Quota)
+		return http.StatusCreated
+	}
+}
+
+// CloneURL handles POST /api/v1/urls/:shortCode/clone
+// Creates a new link with the source's settings; the body is optional
+func (h *URLHandler) CloneURL(c *gin.Context) {
+	var req domain.CloneURLRequest
+	if err := bindStrictJSON(c, &req); err != nil && !errors.Is(err, io.EOF) {
+		writeBindError(c, h.logger, err)
+		return
+	}
+	
+	ctx := service.ContextWithAPIKey(c.Request.Context(), c.GetString(actorContextKey))
+	
+	response, err := h.service.CloneURL(ctx, c.Param("shortCode"), &req, creatorContext(c))
+	if err != nil {
+		if errors.Is(err, domain.ErrShortCodeTaken) && req.CustomAlias != "" && c.Query("suggestions") != "false" {
+			h.aliasTaken(c, req.CustomAlias)
+			return
+		}
+		h.handleError(c, err)
+		return
+	}
+	
+	writeQuotaHeaders(c, response.Quota)
 	c.JSON(http.StatusCreated, response)
 }
 
+// writeQuotaHeaders reports the daily creation quota that applied to a new link
+func writeQuotaHeaders(c *gin.Context, quota *domain.QuotaStatus) {
+	if quota == nil {
+		return
+	}
+	c.Header("X-Quota-Limit", strconv.FormatInt(quota.Limit, 10))
+	c.Header("X-Quota-Remaining", strconv.FormatInt(quota.Remaining, 10))
+	c.Header("X-Quota-Reset", strconv.FormatInt(quota.ResetAt.Unix(), 10))
+}
+
+// writeLinkStateHeaders tells clients embedding a link when it stops working, without a second call
+// Off with LINK_STATE_HEADERS=false; X-URL-Expires-At is left out for links that never expire
+func (h *URLHandler) writeLinkStateHeaders(c *gin.Context, expiresAt *time.Time, active bool) {
+	if !h.cfg.LinkStateHeaders {
+		return
+	}
+	if expiresAt != nil {
+		c.Header("X-URL-Expires-At", expiresAt.UTC().Format(time.RFC3339))
+	}
+	c.Header("X-URL-Active", strconv.FormatBool(active))
+}
+
+// aliasTaken answers a custom alias conflict with free alternatives
+// Clients can skip the extra lookup with ?suggestions=false
+func (h *URLHandler) aliasTaken(c *gin.Context, alias string) {
+	suggestions, err := h.service.SuggestAliases(c.Request.Context(), alias)
+	if err != nil {
+		// Suggestions are best effort; the conflict itself is still reported
+		h.logger.Warn("Failed to suggest aliases", "alias", alias, "error", err)
+	}
+	
+	respondError(c, http.StatusConflict, domain.ErrorResponse{
+		Error:       "short_code_taken",
+		Message:     "This short code is already in use",
+		Code:        http.StatusConflict,
+		Suggestions: suggestions,
+	})
+}
+
 // RedirectURL handles GET /:shortCode
 // Redirects to the original URL
 func (h *URLHandler) RedirectURL(c *gin.Context) {
@@ -62,7 +205,7 @@ func (h *URLHandler) RedirectURL(c *gin.Context) {
 	
 	// Validate short code format
 	if shortCode == "" {
-		c.JSON(http.StatusBadRequest, domain.ErrorResponse{
+		respondError(c, http.StatusBadRequest, domain.ErrorResponse{
 			Error:   "invalid_short_code",
 			Message: "Short code is required",
 			Code:    http.StatusBadRequest,
@@ -70,25 +213,290 @@ func (h *URLHandler) RedirectURL(c *gin.Context) {
 		return
 	}
 	
-	// Get original URL from service
-	originalURL, err := h.service.GetOriginalURL(c.Request.Context(), shortCode)
+	// Resolve the short code; interstitial links are not counted yet
+	decision, err := h.service.PrepareRedirect(c.Request.Context(), shortCode, h.visitorFromRequest(c))
 	if err != nil {
-		h.handleError(c, err)
+		h.handleRedirectError(c, shortCode, err)
+		return
+	}
+	h.writeLinkStateHeaders(c, decision.ExpiresAt, true) // Only active links get this far
+	
+	// The interstitial's continue link is rel=noreferrer, so its hop needs no policy of its own
+	if decision.Interstitial {
+		h.renderInterstitial(c, decision)
+		return
+	}
+	
+	if len(decision.Bundle) > 0 {
+		h.renderBundle(c, decision)
+		return
+	}
+	
+	if decision.ForwardQuery {
+		decision.OriginalURL = redirect.MergeQuery(decision.OriginalURL, c.Request.URL.RawQuery,
+			h.cfg.ForwardQueryPrecedence == config.ForwardQueryIncomingWins)
+	}
+	// The receipt goes on last, so a forwarded ush_click can't stand in for it
+	decision.OriginalURL = redirect.WithClickID(decision.OriginalURL, decision.ClickID)
+	
+	// Custom-scheme deep links can't go in a Location either; the page tries the app before the fallback
+	if decision.App != nil {
+		h.renderAppLink(c, decision)
+		return
+	}
+	
+	// Browsers don't reliably follow a Location to mailto: or tel:, so the visitor taps through instead
+	if !validator.IsWebURL(decision.OriginalURL) {
+		h.renderSchemeLink(c, decision.ShortCode, decision.OriginalURL)
+		return
+	}
+	
+	if policy := decision.ReferrerPolicy.Header(); policy != "" {
+		c.Header("Referrer-Policy", policy)
+	}
+	
+	// The click was counted while resolving; the page navigates straight to the destination
+	if decision.ReferrerPolicy.Bounce() {
+		h.renderBounce(c, decision)
+		return
+	}
+	
+	// Every redirect carries a receipt of its own, which a reused response would hand to someone else
+	if decision.ClickID != "" {
+		c.Header("Cache-Control", "no-store")
+		c.Redirect(http.StatusFound, decision.OriginalURL)
+		return
+	}
+	
+	// Links with targeting rules depend on the visitor, so browsers and proxies must not reuse them
+	if decision.Conditional {
+		c.Header("Cache-Control", "private, no-cache")
+		vary := "User-Agent"
+		if h.cfg.GeoIPCountryHeader != "" {
+			vary += ", " + h.cfg.GeoIPCountryHeader
+		}
+		c.Header("Vary", vary)
+		c.Redirect(http.StatusFound, decision.OriginalURL)
 		return
 	}
 	
 	// Perform 301 permanent redirect for SEO benefits
 	// Use 302 temporary redirect if you want to always track clicks
-	c.Redirect(http.StatusMovedPermanently, originalURL)
+	c.Redirect(http.StatusMovedPermanently, decision.OriginalURL)
 }
 
+// ContinueRedirect handles GET /:shortCode/continue
+// Completes the redirect from the interstitial page once the signed token checks out
+func (h *URLHandler) ContinueRedirect(c *gin.Context) {
+	shortCode := c.Param("shortCode")
+	
+	// The token binds the hop to this short code and expires quickly
+	if err := h.signer.Verify(c.Query("token"), shortCode, time.Now()); err != nil {
+		respondError(c, http.StatusForbidden, domain.ErrorResponse{
+			Error:   "invalid_token",
+			Message: "This continue link is invalid or has expired",
+			Code:    http.StatusForbidden,
+		})
+		return
+	}
+	
+	// The click is counted here, not when the interstitial was shown
+	originalURL, err := h.service.GetOriginalURL(c.Request.Context(), shortCode, h.visitorFromRequest(c))
+	if err != nil {
+		h.handleRedirectError(c, shortCode, err)
+		return
+	}
+	
+	if !validator.IsWebURL(originalURL) {
+		h.renderSchemeLink(c, shortCode, originalURL)
+		return
+	}
+	
+	// Temporary redirect: the continue link is single-purpose and must not be cached
+	c.Header("Cache-Control", "no-store")
+	c.Redirect(http.StatusFound, originalURL)
+}
+
+// RedirectPath handles GET /:shortCode/*path, the redirects of multi-segment aliases such as /promo/summer-2024
+// A trailing /continue is the interstitial hop and a trailing /stats the public stats page of the code before it.
+// Paths below the service's own routes, such as /api/unknown, get the usual 404 instead of a link lookup.
+func (h *URLHandler) RedirectPath(c *gin.Context) {
+	first := c.Param("shortCode")
+	if validator.IsReservedAlias(first) {
+		h.NoRoute(c)
+		return
+	}
+	
+	// Both handlers read the full code from the shortCode parameter
+	code := strings.TrimSuffix(first+c.Param("path"), "/")
+	if prefix, ok := strings.CutSuffix(code, "/continue"); ok {
+		c.Params = gin.Params{{Key: "shortCode", Value: prefix}}
+		h.ContinueRedirect(c)
+		return
+	}
+	if prefix, ok := strings.CutSuffix(code, "/stats"); ok {
+		c.Params = gin.Params{{Key: "shortCode", Value: prefix}}
+		h.PublicStats(c)
+		return
+	}
+	c.Params = gin.Params{{Key: "shortCode", Value: code}}
+	h.RedirectURL(c)
+}
+
+// renderInterstitial serves the warning page with a signed continue link
+func (h *URLHandler) renderInterstitial(c *gin.Context, decision *domain.RedirectDecision) {
+	token := h.signer.Sign(decision.ShortCode, time.Now().Add(h.cfg.InterstitialTokenTTL))
+	
+	c.Header("Cache-Control", "no-store")
+	h.renderPage(c, http.StatusOK, interstitialTemplate, interstitialPage{
+		ShortCode:   decision.ShortCode,
+		Destination: decision.OriginalURL,
+		ContinueURL: template.URL("/" + escapeShortCode(decision.ShortCode) + "/continue?token=" + url.QueryEscape(token)),
+	})
+}
+
+// renderSchemeLink serves the interstitial page linking straight to a destination that isn't http(s)
+// The click was already counted; the destination passed ValidateURL, so its scheme is one ALLOWED_URL_SCHEMES permits
+func (h *URLHandler) renderSchemeLink(c *gin.Context, shortCode, destination string) {
+	c.Header("Cache-Control", "no-store")
+	h.renderPage(c, http.StatusOK, interstitialTemplate, interstitialPage{
+		ShortCode:   shortCode,
+		Destination: destination,
+		ContinueURL: template.URL(destination), // html/template would otherwise replace tel: links with #ZgotmplZ
+	})
+}
+
+// renderBounce serves a page that navigates to the destination with the referrer stripped
+// It is for clients that keep the Referer across a Location redirect despite Referrer-Policy
+func (h *URLHandler) renderBounce(c *gin.Context, decision *domain.RedirectDecision) {
+	// Never reused from cache: a cached page would skip the click count
+	c.Header("Cache-Control", "no-store")
+	h.renderPage(c, http.StatusOK, bounceTemplate, bouncePage{Destination: decision.OriginalURL})
+}
+
+// appLinkFallbackDelay is how long the app page waits for the app to take over before opening the fallback
+const appLinkFallbackDelay = 1500 * time.Millisecond
+
+// renderAppLink serves the page that opens a custom-scheme deep link, then the fallback when no app took over
+// Android gets an intent:// URL, so Chrome opens the fallback itself and the timer is only a backstop
+func (h *URLHandler) renderAppLink(c *gin.Context, decision *domain.RedirectDecision) {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		writeError(c, h.logger, err)
+		return
+	}
+	nonce := base64.RawURLEncoding.EncodeToString(raw)
+
+	appURL := decision.App.DeepLink
+	if decision.App.Android {
+		appURL = redirect.IntentURL(appURL, decision.OriginalURL)
+	}
+	if policy := decision.ReferrerPolicy.Header(); policy != "" {
+		c.Header("Referrer-Policy", policy)
+	}
+
+	// Never reused from cache: a cached page would skip the click count
+	c.Header("Cache-Control", "no-store")
+	h.renderPageWithCSP(c, http.StatusOK, appLinkTemplate, pageCSP+"; script-src 'nonce-"+nonce+"'", appLinkPage{
+		AppURL:          template.URL(appURL),
+		Fallback:        decision.OriginalURL,
+		FallbackDelayMS: appLinkFallbackDelay.Milliseconds(),
+		Nonce:           nonce,
+	})
+}
+
+// renderBundle serves the landing page listing a bundle's member links
+func (h *URLHandler) renderBundle(c *gin.Context, decision *domain.RedirectDecision) {
+	base := h.cfg.BaseURL
+	if requestBase, ok := service.BaseURLFromContext(c.Request.Context()); ok {
+		base = requestBase
+	}
+	
+	page := bundlePage{ShortCode: decision.ShortCode}
+	for _, item := range decision.Bundle {
+		page.Items = append(page.Items, bundleLink{
+			Title: item.Title,
+			URL:   item.URL,
+			Href:  base + "/" + escapeShortCode(item.ShortCode),
+		})
+	}
+	
+	// Every view is counted, so the page must be revalidated
+	c.Header("Cache-Control", "no-cache")
+	h.renderPage(c, http.StatusOK, bundleTemplate, page)
+}
+
+// NoRoute handles requests that match no route
+// Browsers get the branded 404 page, API clients keep the JSON error
+func (h *URLHandler) NoRoute(c *gin.Context) {
+	if wantsHTML(c) {
+		h.renderPage(c, http.StatusNotFound, notFoundTemplate, errorPage{})
+		return
+	}
+	
+	c.JSON(http.StatusNotFound, gin.H{
+		"error": "endpoint not found",
+	})
+}
+
+// handleRedirectError shows browsers a page for unknown and expired links
+// Everything else, and every non-browser client, goes through handleError
+func (h *URLHandler) handleRedirectError(c *gin.Context, shortCode string, err error) {
+	if wantsHTML(c) {
+		switch {
+		case errors.Is(err, domain.ErrURLNotFound):
+			h.renderPage(c, http.StatusNotFound, notFoundTemplate, errorPage{ShortCode: shortCode})
+			return
+		case errors.Is(err, domain.ErrURLExpired):
+			h.renderPage(c, http.StatusGone, expiredTemplate, errorPage{ShortCode: shortCode})
+			return
+		}
+	}
+	
+	h.handleError(c, err)
+}
+
+// renderPage writes one of the HTML page templates with the given status
+func (h *URLHandler) renderPage(c *gin.Context, status int, name string, data interface{}) {
+	h.renderPageWithCSP(c, status, name, pageCSP, data)
+}
+
+// renderPageWithCSP renders a page under csp instead of pageCSP, for pages with a script of their own
+func (h *URLHandler) renderPageWithCSP(c *gin.Context, status int, name, csp string, data interface{}) {
+	c.Header("Content-Security-Policy", csp)
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Status(status)
+	
+	if err := h.pages.ExecuteTemplate(c.Writer, name, data); err != nil {
+		h.logger.Error("Failed to render page", "error", err, "template", name)
+	}
+}
+
+// wantsHTML reports whether the client prefers an HTML page over JSON
+// Requests without an Accept header, or with */*, are treated as API clients
+func wantsHTML(c *gin.Context) bool {
+	return c.NegotiateFormat(gin.MIMEJSON, gin.MIMEHTML) == gin.MIMEHTML
+}
+
+// escapeShortCode escapes each segment of a short code for a path, keeping the slashes between them
+func escapeShortCode(code string) string {
+	segments := strings.Split(code, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// URLInfoMediaType selects the URLInfoResponse shape while the legacy shape is still the default
+const URLInfoMediaType = "application/vnd.url-shortener.url-info.v2+json"
+
 // GetURLInfo handles GET /api/v1/urls/:shortCode
 // Returns detailed information about a shortened URL
 func (h *URLHandler) GetURLInfo(c *gin.Context) {
 	shortCode := c.Param("shortCode")
 	
 	if shortCode == "" {
-		c.JSON(http.StatusBadRequest, domain.ErrorResponse{
+		respondError(c, http.StatusBadRequest, domain.ErrorResponse{
 			Error:   "invalid_short_code",
 			Message: "Short code is required",
 			Code:    http.StatusBadRequest,
@@ -96,8 +504,123 @@ func (h *URLHandler) GetURLInfo(c *gin.Context) {
 		return
 	}
 	
+	if h.wantsLegacyURLInfo(c) {
+		url, err := h.service.GetLegacyURLInfo(c.Request.Context(), shortCode)
+		if err != nil {
+			h.handleError(c, err)
+			return
+		}
+		
+		h.writeLinkStateHeaders(c, url.ExpiresAt, url.IsActive)
+		c.Header("Deprecation", "true")
+		c.JSON(http.StatusOK, url)
+		return
+	}
+	
+	snippets, ok := wantsSnippets(c, false)
+	if !ok {
+		return
+	}
+	
 	// Get URL info from service
-	url, err := h.service.GetURLInfo(c.Request.Context(), shortCode)
+	info, err := h.service.GetURLInfo(c.Request.Context(), shortCode)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	if snippets {
+		info.Snippets = newSnippets(domain.LinkLabel(info.Title, info.PageTitle, info.OriginalURL), info.ShortURL)
+	}
+	h.writeLinkStateHeaders(c, info.ExpiresAt, info.IsActive)
+	
+	// Dashboards poll this, so an unchanged link is answered without a body
+	if notModified(c, infoETag(info), lastModified(info.UpdatedAt, info.LastAccessAt)) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	
+	c.JSON(http.StatusOK, info)
+}
+
+// creatorContext collects the metadata of a create call kept with the new link
+// Without an Origin header only the origin of the Referer is kept, never its path or query
+func creatorContext(c *gin.Context) domain.CreatorContext {
+	creator := domain.CreatorContext{
+		IP:        clientIP(c),
+		UserAgent: c.Request.UserAgent(),
+		Origin:    c.GetHeader("Origin"),
+	}
+	if creator.Origin == "" {
+		if referer, err := url.Parse(c.Request.Referer()); err == nil && referer.Scheme != "" && referer.Host != "" {
+			creator.Origin = referer.Scheme + "://" + referer.Host
+		}
+	}
+	return creator
+}
+
+// wantsLegacyURLInfo reports whether the old raw-model shape should be served
+// Only while LEGACY_URL_INFO is on, and clients listing URLInfoMediaType in Accept opt out early
+func (h *URLHandler) wantsLegacyURLInfo(c *gin.Context) bool {
+	if !h.cfg.LegacyURLInfo {
+		return false
+	}
+	return !acceptsMediaType(c.Request.Header.Values("Accept"), URLInfoMediaType)
+}
+
+// acceptsMediaType reports whether mediaType appears anywhere in the Accept headers
+// Looks at every header line and every comma-separated entry, ignoring parameters such as q
+func acceptsMediaType(accept []string, mediaType string) bool {
+	for _, line := range accept {
+		for _, entry := range strings.Split(line, ",") {
+			value, _, _ := strings.Cut(entry, ";")
+			if strings.EqualFold(strings.TrimSpace(value), mediaType) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// UpdateURL handles PATCH /api/v1/urls/:shortCode
+// Applies a partial update and returns the updated resource
+func (h *URLHandler) UpdateURL(c *gin.Context) {
+	shortCode := c.Param("shortCode")
+	
+	if !h.authorizeManagement(c, shortCode) {
+		return
+	}
+	
+	var req domain.UpdateURLRequest
+	if err := bindJSON(c, &req); err != nil {
+		writeBindError(c, h.logger, err)
+		return
+	}
+	
+	url, err := h.service.UpdateURL(c.Request.Context(), shortCode, &req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	
+	c.JSON(http.StatusOK, url)
+}
+
+// ExtendURL handles POST /api/v1/urls/:shortCode/extend
+// Pushes out the expiry; an expired link needs allow_revive to come back
+func (h *URLHandler) ExtendURL(c *gin.Context) {
+	shortCode := c.Param("shortCode")
+	
+	if !h.authorizeManagement(c, shortCode) {
+		return
+	}
+	
+	var req domain.ExtendURLRequest
+	if err := bindJSON(c, &req); err != nil {
+		writeBindError(c, h.logger, err)
+		return
+	}
+	
+	url, err := h.service.ExtendURL(c.Request.Context(), shortCode, &req)
 	if err != nil {
 		h.handleError(c, err)
 		return
@@ -107,12 +630,12 @@ func (h *URLHandler) GetURLInfo(c *gin.Context) {
 }
 
 // DeleteURL handles DELETE /api/v1/urls/:shortCode
-// Removes a shortened URL
+// Removes a shortened URL; ?hard=true erases it instead, which only the admin may do
 func (h *URLHandler) DeleteURL(c *gin.Context) {
 	shortCode := c.Param("shortCode")
 	
 	if shortCode == "" {
-		c.JSON(http.StatusBadRequest, domain.ErrorResponse{
+		respondError(c, http.StatusBadRequest, domain.ErrorResponse{
 			Error:   "invalid_short_code",
 			Message: "Short code is required",
 			Code:    http.StatusBadRequest,
@@ -120,8 +643,25 @@ func (h *URLHandler) DeleteURL(c *gin.Context) {
 		return
 	}
 	
-	// Optional: Add authentication check here
-	// if !h.isAuthorized(c) { ... }
+	if raw := c.Query("hard"); raw != "" {
+		hard, err := strconv.ParseBool(raw)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, domain.ErrorResponse{
+				Error:   "invalid_request",
+				Message: "invalid 'hard' value: " + raw,
+				Code:    http.StatusBadRequest,
+			})
+			return
+		}
+		if hard {
+			h.hardDeleteURL(c, shortCode)
+			return
+		}
+	}
+	
+	if !h.authorizeManagement(c, shortCode) {
+		return
+	}
 	
 	if err := h.service.DeleteURL(c.Request.Context(), shortCode); err != nil {
 		h.handleError(c, err)
@@ -134,13 +674,63 @@ func (h *URLHandler) DeleteURL(c *gin.Context) {
 	})
 }
 
+// hardDeleteURL erases a link for an erasure request
+// Management tokens don't qualify: erasure also removes the audit trail, so it stays with the admin
+func (h *URLHandler) hardDeleteURL(c *gin.Context, shortCode string) {
+	if h.cfg.AdminAPIKey == "" {
+		respondError(c, http.StatusForbidden, domain.ErrorResponse{
+			Error:   "admin_disabled",
+			Message: "Hard delete requires ADMIN_API_KEY to be configured",
+			Code:    http.StatusForbidden,
+		})
+		return
+	}
+	apiKey := c.GetHeader("X-API-Key")
+	if !isAdminKey(h.cfg, apiKey) {
+		respondError(c, http.StatusUnauthorized, domain.ErrorResponse{
+			Error:   "unauthorized",
+			Message: "Valid admin API key required for hard delete",
+			Code:    http.StatusUnauthorized,
+		})
+		return
+	}
+	
+	actor := domain.Actor{ID: "admin:" + keyFingerprint(apiKey), IP: clientIP(c)}
+	if err := h.service.HardDeleteURL(c.Request.Context(), shortCode, actor); err != nil {
+		h.handleError(c, err)
+		return
+	}
+	
+	c.Status(http.StatusNoContent)
+}
+
+// authorizeManagement lets the admin or the holder of the link's management token through
+// Writes the error response and returns false for anyone else
+func (h *URLHandler) authorizeManagement(c *gin.Context, shortCode string) bool {
+	if isAdminKey(h.cfg, c.GetHeader("X-API-Key")) {
+		return true
+	}
+	
+	verified, err := h.service.AuthorizeManagement(c.Request.Context(), shortCode, c.GetHeader(managementTokenHeader))
+	if err != nil {
+		h.handleError(c, err)
+		return false
+	}
+	
+	// A link without a token of its own verified nothing, so a token header can't stand in for the API key
+	if deferred, ok := c.Get(deferredAuthContextKey); ok && !verified {
+		return deferred.(func() bool)()
+	}
+	return true
+}
+
 // GetStats handles GET /api/v1/urls/:shortCode/stats
 // Returns statistics for a shortened URL
 func (h *URLHandler) GetStats(c *gin.Context) {
 	shortCode := c.Param("shortCode")
 	
 	if shortCode == "" {
-		c.JSON(http.StatusBadRequest, domain.ErrorResponse{
+		respondError(c, http.StatusBadRequest, domain.ErrorResponse{
 			Error:   "invalid_short_code",
 			Message: "Short code is required",
 			Code:    http.StatusBadRequest,
@@ -154,72 +744,202 @@ func (h *URLHandler) GetStats(c *gin.Context) {
 		return
 	}
 	
+	if notModified(c, statsETag(stats), lastModified(stats.UpdatedAt, stats.LastAccessAt)) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	
+	c.JSON(http.StatusOK, stats)
+}
+
+// ResetStats handles POST /api/v1/urls/:shortCode/stats/reset
+// Zeroes the link's click statistics for the admin or the holder of its management token
+func (h *URLHandler) ResetStats(c *gin.Context) {
+	shortCode := c.Param("shortCode")
+	
+	if !h.authorizeManagement(c, shortCode) {
+		return
+	}
+	
+	actor := actorFromContext(c)
+	if actor.ID == "anonymous" && c.GetHeader(managementTokenHeader) != "" {
+		actor.ID = "management_token"
+	}
+	
+	stats, err := h.service.ResetStats(c.Request.Context(), shortCode, actor)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	
 	c.JSON(http.StatusOK, stats)
 }
 
+// GetClickTimeSeries handles GET /api/v1/urls/:shortCode/stats/timeseries
+// Returns one {bucket_start, clicks} entry per hour, day or week of the requested range
+func (h *URLHandler) GetClickTimeSeries(c *gin.Context) {
+	query := domain.TimeSeriesQuery{Granularity: domain.Granularity(c.Query("granularity"))}
+	
+	// from and to take the same formats as the export; a plain "to" date is inclusive
+	if from := c.Query("from"); from != "" {
+		t, _, err := parseDateParam(from)
+		if err != nil {
+			h.invalidRange(c, "invalid 'from' value: "+from)
+			return
+		}
+		query.From = t
+	}
+	if to := c.Query("to"); to != "" {
+		t, dateOnly, err := parseDateParam(to)
+		if err != nil {
+			h.invalidRange(c, "invalid 'to' value: "+to)
+			return
+		}
+		if dateOnly {
+			t = t.AddDate(0, 0, 1)
+		}
+		query.To = t
+	}
+	
+	series, err := h.service.GetClickTimeSeries(c.Request.Context(), c.Param("shortCode"), query)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	
+	c.JSON(http.StatusOK, series)
+}
+
+// GetClickHeatmap handles GET /api/v1/urls/:shortCode/stats/heatmap
+// Returns a 7×24 matrix of clicks per weekday and hour of day in the ?tz time zone, UTC by default
+func (h *URLHandler) GetClickHeatmap(c *gin.Context) {
+	loc, err := domain.LoadTimeZone(c.Query("tz"))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	query := domain.HeatmapQuery{Location: loc}
+	
+	// from and to take the same formats as the time series; plain dates are midnights in tz and "to" is inclusive
+	if from := c.Query("from"); from != "" {
+		t, dateOnly, err := parseDateParam(from)
+		if err != nil {
+			h.invalidRange(c, "invalid 'from' value: "+from)
+			return
+		}
+		if dateOnly {
+			t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+		}
+		query.From = t
+	}
+	if to := c.Query("to"); to != "" {
+		t, dateOnly, err := parseDateParam(to)
+		if err != nil {
+			h.invalidRange(c, "invalid 'to' value: "+to)
+			return
+		}
+		if dateOnly {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		}
+		query.To = t
+	}
+	
+	heatmap, err := h.service.GetClickHeatmap(c.Request.Context(), c.Param("shortCode"), query)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	
+	c.JSON(http.StatusOK, heatmap)
+}
+
+// invalidRange answers a time series or heat map request whose from or to can't be parsed
+func (h *URLHandler) invalidRange(c *gin.Context, message string) {
+	respondError(c, http.StatusBadRequest, domain.ErrorResponse{
+		Error:   "invalid_range",
+		Message: message,
+		Code:    http.StatusBadRequest,
+	})
+}
+
+// visitorFromRequest collects the request attributes used by targeting rules
+func (h *URLHandler) visitorFromRequest(c *gin.Context) domain.Visitor {
+	visitor := domain.Visitor{
+		IP:        clientIP(c),
+		UserAgent: c.Request.UserAgent(),
+		Referrer:  c.Request.Referer(),
+	}
+	
+	// A CDN in front of us may already know the country; "XX" is its marker for unknown
+	if h.cfg.GeoIPCountryHeader != "" {
+		country := strings.ToUpper(strings.TrimSpace(c.GetHeader(h.cfg.GeoIPCountryHeader)))
+		if geo.ValidCountryCode(country) && country != "XX" {
+			visitor.Country = country
+		}
+	}
+	
+	return visitor
+}
+
 // handleError processes domain errors and returns appropriate HTTP responses
 func (h *URLHandler) handleError(c *gin.Context, err error) {
+	writeError(c, h.logger, err)
+}
+
+// statusClientClosedRequest is the nginx convention for requests the client abandoned
+const statusClientClosedRequest = 499
+
+// writeError maps domain errors to HTTP responses for every handler in this package
+func writeError(c *gin.Context, log *logger.Logger, err error) {
 	var appErr *domain.AppError
 	
 	switch {
-	case errors.As(err, &appErr):
+	case errors.Is(err, context.Canceled):
+		// The client went away, nobody reads the response and it's no reason to page anyone
+		log.Debug("Request canceled by client", "path", c.Request.URL.Path, "error", err)
+		c.AbortWithStatus(statusClientClosedRequest)
+	
+	// Outages also arrive wrapped in internal errors and are answered from the table below
+	case errors.As(err, &appErr) && !errors.Is(err, domain.ErrDependencyUnavailable):
 		// Log internal errors but don't expose details to users
 		if appErr.Internal {
-			h.logger.Error("Internal server error", "error", appErr.Err)
-			c.JSON(appErr.StatusCode, domain.ErrorResponse{
+			log.Error("Internal server error", "error", appErr.Err)
+			respondError(c, appErr.StatusCode, domain.ErrorResponse{
 				Error:   "internal_error",
 				Message: "An internal error occurred",
 				Code:    appErr.StatusCode,
 			})
 		} else {
-			c.JSON(appErr.StatusCode, domain.ErrorResponse{
+			respondError(c, appErr.StatusCode, domain.ErrorResponse{
 				Error:   "client_error",
 				Message: appErr.Message,
 				Code:    appErr.StatusCode,
+				Errors:  appErr.Fields,
 			})
 		}
 	
-	case errors.Is(err, domain.ErrURLNotFound):
-		c.JSON(http.StatusNotFound, domain.ErrorResponse{
-			Error:   "not_found",
-			Message: "The requested URL was not found",
-			Code:    http.StatusNotFound,
-		})
-	
-	case errors.Is(err, domain.ErrURLExpired):
-		c.JSON(http.StatusGone, domain.ErrorResponse{
-			Error:   "url_expired",
-			Message: "This URL has expired and is no longer available",
-			Code:    http.StatusGone,
-		})
-	
-	case errors.Is(err, domain.ErrShortCodeTaken):
-		c.JSON(http.StatusConflict, domain.ErrorResponse{
-			Error:   "short_code_taken",
-			Message: "This short code is already in use",
-			Code:    http.StatusConflict,
-		})
-	
-	case errors.Is(err, domain.ErrInvalidURL):
-		c.JSON(http.StatusBadRequest, domain.ErrorResponse{
-			Error:   "invalid_url",
-			Message: "The provided URL is invalid",
-			Code:    http.StatusBadRequest,
-		})
-	
-	case errors.Is(err, domain.ErrRateLimitExceeded):
-		c.JSON(http.StatusTooManyRequests, domain.ErrorResponse{
-			Error:   "rate_limit_exceeded",
-			Message: "Too many requests, please try again later",
-			Code:    http.StatusTooManyRequests,
-		})
-	
 	default:
-		h.logger.Error("Unexpected error", "error", err)
-		c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
-			Error:   "internal_error",
-			Message: "An unexpected error occurred",
-			Code:    http.StatusInternalServerError,
+		mapping, ok := lookupDomainError(err)
+		if !ok {
+			log.Error("Unexpected error", "error", err)
+			respondError(c, http.StatusInternalServerError, domain.ErrorResponse{
+				Error:   "internal_error",
+				Message: "An unexpected error occurred",
+				Code:    http.StatusInternalServerError,
+			})
+			return
+		}
+		
+		if mapping.Status == http.StatusServiceUnavailable {
+			log.Warn("Dependency unavailable", "path", c.Request.URL.Path, "error", err)
+		}
+		if mapping.RetryAfter != nil {
+			c.Header("Retry-After", strconv.Itoa(int(mapping.RetryAfter(time.Now()).Seconds())))
+		}
+		respondError(c, mapping.Status, domain.ErrorResponse{
+			Error:   mapping.Error,
+			Message: mapping.Message,
+			Code:    mapping.Status,
 		})
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+
+	"url-shortener/internal/domain"
+)
+
+// invalidFields is a binding failure listing every field it concerns, named as in the JSON body
+type invalidFields []domain.FieldError
+
+// Error joins the field messages, so the top-level message reads like before
+func (f invalidFields) Error() string {
+	messages := make([]string, len(f))
+	for i, field := range f {
+		messages[i] = field.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// bindJSON is ShouldBindJSON with its validation failures named by JSON field
+// Bodies that may carry unknown fields use it; bindStrictJSON refuses them
+func bindJSON(c *gin.Context, dst interface{}) error {
+	return fieldErrorsOf(dst, c.ShouldBindJSON(dst))
+}
+
+// fieldErrorsOf converts the binding validation errors of dst into invalidFields; other errors pass through
+func fieldErrorsOf(dst interface{}, err error) error {
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return err
+	}
+
+	fields := make(invalidFields, 0, len(validationErrs))
+	for _, fe := range validationErrs {
+		name := jsonFieldPath(reflect.TypeOf(dst), fe.StructNamespace())
+		fields = append(fields, domain.FieldError{Field: name, Rule: fe.Tag(), Message: ruleMessage(name, fe)})
+	}
+	return fields
+}
+
+// requestFieldErrors lists the fields a bind error names, nil when it doesn't concern a field
+func requestFieldErrors(err error) []domain.FieldError {
+	var fields invalidFields
+	var typeErr *json.UnmarshalTypeError
+
+	switch {
+	case errors.As(err, &fields):
+		return fields
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		return []domain.FieldError{{Field: typeErr.Field, Rule: "type", Message: fmt.Sprintf("%s must be %s", typeErr.Field, typeErr.Type)}}
+	}
+
+	// encoding/json has no typed error for unknown fields, only this message
+	if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		field = strings.Trim(field, `"`)
+		return []domain.FieldError{{Field: field, Rule: "unknown", Message: "unknown field " + field}}
+	}
+	return nil
+}
+
+// jsonFieldPath maps a validator namespace such as "CreateURLRequest.UTM.Source" onto the JSON names of
+// the fields, "utm.source"; list indexes are kept, e.g. "targets[0].url"
+func jsonFieldPath(t reflect.Type, namespace string) string {
+	parts := strings.Split(namespace, ".")[1:]
+	names := make([]string, 0, len(parts))
+	for _, part := range parts {
+		t = elemType(t)
+		name, index, indexed := strings.Cut(part, "[")
+		field, ok := t.FieldByName(name)
+		if !ok {
+			names = append(names, part)
+			continue
+		}
+
+		jsonName := jsonTagName(field)
+		t = field.Type
+		if indexed {
+			jsonName += "[" + index
+			t = elemType(t).Elem()
+		}
+		names = append(names, jsonName)
+	}
+	return strings.Join(names, ".")
+}
+
+// elemType dereferences pointer types
+func elemType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
+
+// jsonTagName is the name encoding/json uses for a struct field
+func jsonTagName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return field.Name
+	}
+	return name
+}
+
+// ruleMessage words a failed binding rule for the client
+func ruleMessage(name string, fe validator.FieldError) string {
+	unit := ""
+	if fe.Kind() == reflect.String {
+		unit = " characters"
+	}
+
+	switch fe.Tag() {
+	case "required", "required_without":
+		return name + " is required"
+	case "max":
+		return fmt.Sprintf("%s must be at most %s%s", name, fe.Param(), unit)
+	case "min":
+		return fmt.Sprintf("%s must be at least %s%s", name, fe.Param(), unit)
+	case "oneof":
+		return fmt.Sprintf("%s must be one of %s", name, fe.Param())
+	}
+	return fmt.Sprintf("%s fails the %s check", name, fe.Tag())
+}
@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+
+	"url-shortener/internal/domain"
+)
+
+// htmlErrorsContextKey holds the func rendering error responses of a browser form post as a page
+const htmlErrorsContextKey = "html_errors"
+
+// formMemory bounds the multipart fields held in memory; the body itself is capped by MAX_REQUEST_BODY_BYTES
+const formMemory = 1 << 20
+
+// shortenFormFields are the only fields a form-encoded shorten request may carry
+var shortenFormFields = map[string]bool{"url": true, "custom_alias": true, "expiry_days": true}
+
+// errUnknownFormField is returned for form fields the shorten form doesn't have, like bindStrictJSON does for JSON
+type errUnknownFormField string
+
+func (e errUnknownFormField) Error() string {
+	return "unknown field " + strconv.Quote(string(e))
+}
+
+// ShortenForm handles GET / with ENABLE_WEB_UI
+// The page posts to /api/v1/shorten, which answers browser form posts with the same page.
+// ?created= is where GET /api/v1/shorten?redirect=true lands; the link is looked up rather than
+// echoed, so the page can't be made to show an arbitrary destination. Unknown codes show the empty form.
+func (h *URLHandler) ShortenForm(c *gin.Context) {
+	page := shortenFormPage{}
+	if code := c.Query("created"); code != "" {
+		if info, err := h.service.GetURLInfo(c.Request.Context(), code); err == nil {
+			page.URL = info.DisplayURL
+			page.ShortURL = info.ShortURL
+		}
+	}
+	h.renderPage(c, http.StatusOK, shortenFormTemplate, page)
+}
+
+// isFormPost reports whether the request body is form-encoded rather than JSON
+func isFormPost(c *gin.Context) bool {
+	switch c.ContentType() {
+	case binding.MIMEPOSTForm, binding.MIMEMultipartPOSTForm:
+		return true
+	}
+	return false
+}
+
+// bindShortenForm reads a form-encoded or multipart shorten request into req
+// Unknown fields and file parts are refused, as unknown JSON fields are
+func bindShortenForm(c *gin.Context, req *domain.CreateURLRequest) error {
+	var err error
+	if c.ContentType() == binding.MIMEMultipartPOSTForm {
+		err = c.Request.ParseMultipartForm(formMemory)
+	} else {
+		err = c.Request.ParseForm()
+	}
+	if err != nil {
+		return err
+	}
+
+	if c.Request.MultipartForm != nil {
+		for name := range c.Request.MultipartForm.File {
+			return errUnknownFormField(name)
+		}
+	}
+	for name := range c.Request.PostForm {
+		if !shortenFormFields[name] {
+			return errUnknownFormField(name)
+		}
+	}
+
+	req.URL = strings.TrimSpace(c.Request.PostForm.Get("url"))
+	req.CustomAlias = strings.TrimSpace(c.Request.PostForm.Get("custom_alias"))
+	if days := strings.TrimSpace(c.Request.PostForm.Get("expiry_days")); days != "" {
+		if req.ExpiryDays, err = strconv.Atoi(days); err != nil {
+			return errors.New("expiry_days must be a whole number")
+		}
+	}
+	return fieldErrorsOf(req, binding.Validator.ValidateStruct(req))
+}
+
+// shortenFormResult starts the HTML flow of a browser form post, or returns nil for API clients
+// From here on error responses are rendered into the form with the values that were submitted.
+func (h *URLHandler) shortenFormResult(c *gin.Context) *shortenFormPage {
+	if !isFormPost(c) || !wantsHTML(c) {
+		return nil
+	}
+
+	page := &shortenFormPage{}
+	c.Set(htmlErrorsContextKey, func(status int, body domain.ErrorResponse) {
+		page.Error = body.Message
+		page.Suggestions = body.Suggestions
+		h.renderShortenForm(c, status, page)
+	})
+	return page
+}
+
+// renderShortenForm writes the form page with the submitted values echoed back
+// html/template escapes them, so a URL carrying markup is shown as text.
+func (h *URLHandler) renderShortenForm(c *gin.Context, status int, page *shortenFormPage) {
+	page.URL = c.Request.PostForm.Get("url")
+	page.CustomAlias = c.Request.PostForm.Get("custom_alias")
+	page.ExpiryDays = c.Request.PostForm.Get("expiry_days")
+	h.renderPage(c, status, shortenFormTemplate, page)
+}
@@ -0,0 +1,151 @@
+// Package hooks is where custom builds plug their own behaviour into the URL service
+//
+// A hook is a file added to this package that registers itself by name from init:
+//
+//	func init() {
+//		Register("notify_crm", func(cfg *config.Config) (service.Hooks, error) {
+//			return service.Hooks{AfterCreate: notifyCRM}, nil
+//		})
+//	}
+//
+// Compiled-in hooks only run when HOOKS names them, so a build can carry hooks it doesn't always use.
+package hooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"url-shortener/internal/config"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/service"
+)
+
+// Factory builds a hook from the configuration, failing startup with its error
+type Factory func(cfg *config.Config) (service.Hooks, error)
+
+var (
+	mu        sync.Mutex
+	factories = make(map[string]Factory)
+)
+
+// Register makes a hook available under name, the lowercase name HOOKS enables it by
+// It panics when name is taken, like a duplicate flag, as both builds would silently differ otherwise.
+func Register(name string, factory Factory) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	name = strings.ToLower(name)
+	if factory == nil {
+		panic("hooks: Register factory is nil for " + name)
+	}
+	if _, taken := factories[name]; taken {
+		panic("hooks: Register called twice for " + name)
+	}
+	factories[name] = factory
+}
+
+// Registered lists the names of the hooks compiled into the build, sorted
+func Registered() []string {
+	mu.Lock()
+	defer mu.Unlock()
+
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Build creates the hooks listed in cfg.Hooks and chains them in that order
+// An empty list returns zero Hooks, leaving the service as it is without them.
+func Build(cfg *config.Config) (service.Hooks, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	built := make([]service.Hooks, 0, len(cfg.Hooks))
+	for _, name := range cfg.Hooks {
+		factory, ok := factories[name]
+		if !ok {
+			return service.Hooks{}, fmt.Errorf("HOOKS names %q, which this build doesn't include", name)
+		}
+		h, err := factory(cfg)
+		if err != nil {
+			return service.Hooks{}, fmt.Errorf("hook %s: %w", name, err)
+		}
+		built = append(built, h)
+	}
+	return Chain(built...), nil
+}
+
+// Chain combines hooks into one running each in order
+// Before callbacks stop at the first veto; after callbacks all run and their errors are joined.
+// A callback none of hooks sets stays nil.
+func Chain(hooks ...service.Hooks) service.Hooks {
+	if len(hooks) == 1 {
+		return hooks[0]
+	}
+
+	var chained service.Hooks
+	var beforeCreate, afterCreate []func(context.Context, *domain.URL) error
+	var beforeRedirect []func(context.Context, *domain.RedirectDecision) error
+	var afterDelete []func(context.Context, string) error
+	for _, h := range hooks {
+		if h.BeforeCreate != nil {
+			beforeCreate = append(beforeCreate, h.BeforeCreate)
+		}
+		if h.AfterCreate != nil {
+			afterCreate = append(afterCreate, h.AfterCreate)
+		}
+		if h.BeforeRedirect != nil {
+			beforeRedirect = append(beforeRedirect, h.BeforeRedirect)
+		}
+		if h.AfterDelete != nil {
+			afterDelete = append(afterDelete, h.AfterDelete)
+		}
+	}
+
+	if len(beforeCreate) > 0 {
+		chained.BeforeCreate = func(ctx context.Context, url *domain.URL) error {
+			for _, fn := range beforeCreate {
+				if err := fn(ctx, url); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+	if len(afterCreate) > 0 {
+		chained.AfterCreate = func(ctx context.Context, url *domain.URL) error {
+			var errs []error
+			for _, fn := range afterCreate {
+				errs = append(errs, fn(ctx, url))
+			}
+			return errors.Join(errs...)
+		}
+	}
+	if len(beforeRedirect) > 0 {
+		chained.BeforeRedirect = func(ctx context.Context, decision *domain.RedirectDecision) error {
+			for _, fn := range beforeRedirect {
+				if err := fn(ctx, decision); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+	if len(afterDelete) > 0 {
+		chained.AfterDelete = func(ctx context.Context, shortCode string) error {
+			var errs []error
+			for _, fn := range afterDelete {
+				errs = append(errs, fn(ctx, shortCode))
+			}
+			return errors.Join(errs...)
+		}
+	}
+	return chained
+}
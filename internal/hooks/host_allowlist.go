@@ -0,0 +1,68 @@
+package hooks
+
+import (
+	"context"
+	"errors"
+	"net/url"
+
+	"url-shortener/internal/config"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/service"
+	"url-shortener/internal/unshorten"
+)
+
+// HostAllowlist is the name of the example hook restricting destinations to HOOK_ALLOWED_HOSTS
+const HostAllowlist = "host_allowlist"
+
+func init() {
+	Register(HostAllowlist, newHostAllowlist)
+}
+
+// newHostAllowlist refuses new links with a destination outside cfg.HookAllowedHosts
+func newHostAllowlist(cfg *config.Config) (service.Hooks, error) {
+	if len(cfg.HookAllowedHosts) == 0 {
+		return service.Hooks{}, errors.New("HOOK_ALLOWED_HOSTS is empty, so every link would be refused")
+	}
+	allowed := cfg.HookAllowedHosts
+
+	return service.Hooks{
+		BeforeCreate: func(ctx context.Context, link *domain.URL) error {
+			// A bundle's own address is the landing page; its members are checked one by one
+			if link.IsBundle() {
+				return nil
+			}
+			for _, dest := range destinations(link) {
+				parsed, err := url.Parse(dest.address)
+				if err != nil || !unshorten.MatchesDomain(parsed.Host, allowed) {
+					return domain.NewFieldError(dest.field, "host_not_allowed", "Destination host is not in HOOK_ALLOWED_HOSTS")
+				}
+			}
+			return nil
+		},
+	}, nil
+}
+
+// destination is one address a link can send visitors to, with the request field it came from
+type destination struct {
+	field   string
+	address string
+}
+
+// destinations returns every address link can send visitors to, the main one first
+// Deep links open an app rather than a page and are left to the app link checks.
+func destinations(link *domain.URL) []destination {
+	found := []destination{{"url", link.OriginalURL}}
+	for _, target := range link.Targets {
+		found = append(found, destination{"targets.url", target.URL})
+	}
+	for _, variant := range link.Variants {
+		found = append(found, destination{"variants.url", variant.URL})
+	}
+	if link.IOSFallbackURL != "" {
+		found = append(found, destination{"ios_fallback_url", link.IOSFallbackURL})
+	}
+	if link.AndroidFallbackURL != "" {
+		found = append(found, destination{"android_fallback_url", link.AndroidFallbackURL})
+	}
+	return found
+}
@@ -0,0 +1,99 @@
+package linkcheck
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	// maxHostWait is the longest a check waits for its host's turn; later links of the host wait for the next run
+	maxHostWait = 30 * time.Second
+
+	// defaultBackoff pauses a host that answered 429 without a Retry-After
+	defaultBackoff = 10 * time.Minute
+)
+
+// hostLimiter gives every destination host its own token bucket
+// A host that rate limits the checks is paused, across runs, for as long as it asked
+type hostLimiter struct {
+	mu      sync.Mutex
+	perHost rate.Limit
+	hosts   map[string]*hostState
+}
+
+// hostState is the bucket and pause of one host
+type hostState struct {
+	limiter     *rate.Limiter
+	pausedUntil time.Time
+}
+
+// newHostLimiter allows perSecond checks per second on each host, in bursts of one
+func newHostLimiter(perSecond float64) *hostLimiter {
+	return &hostLimiter{perHost: rate.Limit(perSecond), hosts: make(map[string]*hostState)}
+}
+
+// wait blocks until host may be checked; false when the host is paused or its turn is too far off
+func (l *hostLimiter) wait(ctx context.Context, host string) bool {
+	l.mu.Lock()
+	state := l.state(host)
+	if time.Now().Before(state.pausedUntil) {
+		l.mu.Unlock()
+		return false
+	}
+	reservation := state.limiter.Reserve()
+	l.mu.Unlock()
+
+	delay := reservation.Delay()
+	if delay > maxHostWait {
+		reservation.Cancel()
+		return false
+	}
+	if delay == 0 {
+		return true
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		reservation.Cancel()
+		return false
+	}
+}
+
+// pause stops checks of host for d, or defaultBackoff when d is zero
+func (l *hostLimiter) pause(host string, d time.Duration) {
+	if d <= 0 {
+		d = defaultBackoff
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.state(host).pausedUntil = time.Now().Add(d)
+}
+
+// prune forgets hosts that aren't paused, so the map only grows with the hosts of one run
+func (l *hostLimiter) prune() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	for host, state := range l.hosts {
+		if !now.Before(state.pausedUntil) {
+			delete(l.hosts, host)
+		}
+	}
+}
+
+// state returns the state of host, creating it; callers hold mu
+func (l *hostLimiter) state(host string) *hostState {
+	state, ok := l.hosts[host]
+	if !ok {
+		state = &hostState{limiter: rate.NewLimiter(l.perHost, 1)}
+		l.hosts[host] = state
+	}
+	return state
+}
@@ -0,0 +1,120 @@
+// Package linkcheck finds short links whose destinations went away
+package linkcheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"url-shortener/internal/netguard"
+)
+
+const (
+	// DefaultTimeout bounds one check including redirects
+	DefaultTimeout = 10 * time.Second
+
+	// maxRedirects stops checks that bounce between hosts
+	maxRedirects = 5
+
+	// userAgent identifies the checks in the destinations' logs
+	userAgent = "url-shortener-linkcheck/1.0"
+)
+
+// Result is how a destination answered a check
+type Result struct {
+	StatusCode int
+	RetryAfter time.Duration // Retry-After of a 429, zero when absent
+}
+
+// Checker requests a destination and reports how it answered
+type Checker interface {
+	Check(ctx context.Context, rawURL string) (Result, error)
+}
+
+// HTTPChecker requests destinations with HEAD, falling back to GET for servers that refuse HEAD
+// Connections go through the SSRF guard, redirects included
+type HTTPChecker struct {
+	client *http.Client
+}
+
+// NewHTTPChecker creates a checker whose requests time out after timeout
+func NewHTTPChecker(timeout time.Duration) *HTTPChecker {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: netguard.Control,
+	}
+
+	return &HTTPChecker{
+		client: &http.Client{
+			Timeout: timeout,
+			Transport: &http.Transport{
+				DialContext:         dialer.DialContext,
+				TLSHandshakeTimeout: timeout,
+				MaxIdleConns:        10,
+				IdleConnTimeout:     30 * time.Second,
+			},
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) >= maxRedirects {
+					return fmt.Errorf("stopped after %d redirects", maxRedirects)
+				}
+				if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+					return fmt.Errorf("redirect to unsupported scheme %q", req.URL.Scheme)
+				}
+				return nil
+			},
+		},
+	}
+}
+
+// Check returns the status of the final response after redirects
+func (c *HTTPChecker) Check(ctx context.Context, rawURL string) (Result, error) {
+	result, err := c.do(ctx, http.MethodHead, rawURL)
+	if err == nil && (result.StatusCode == http.StatusMethodNotAllowed || result.StatusCode == http.StatusNotImplemented) {
+		return c.do(ctx, http.MethodGet, rawURL)
+	}
+	return result, err
+}
+
+// do sends one request and discards the body
+func (c *HTTPChecker) do(ctx context.Context, method, rawURL string) (Result, error) {
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, nil)
+	if err != nil {
+		return Result{}, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return Result{}, err
+	}
+	defer resp.Body.Close()
+	// Drain a little so the connection is reused; a GET of a large page is cut short instead
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	result := Result{StatusCode: resp.StatusCode}
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		result.RetryAfter = time.Duration(seconds) * time.Second
+	}
+	return result, nil
+}
+
+// IsBroken reports whether an answer means the destination is gone for good
+// Only 404 and 410 count: 5xx and timeouts pass, and 401 or 403 often just mean checks aren't welcome
+func IsBroken(status int) bool {
+	return status == http.StatusNotFound || status == http.StatusGone
+}
+
+// HostGone reports whether a failed check means the destination's host no longer resolves
+func HostGone(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
@@ -0,0 +1,274 @@
+package linkcheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"url-shortener/internal/cache"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/metrics"
+	"url-shortener/internal/notify"
+	"url-shortener/internal/repository"
+	"url-shortener/pkg/logger"
+)
+
+// monitorLockTTL bounds one run; the lock is never held longer, even by a crashed instance
+const monitorLockTTL = 30 * time.Minute
+
+// Config holds the settings of a Monitor
+type Config struct {
+	RecheckAfter time.Duration // Links checked more recently are skipped
+	Concurrency  int           // Checks in flight at once
+	BatchSize    int           // Links loaded per query
+	HostRate     float64       // Checks per second on one destination host
+	BaseURL      string        // Builds the short URL of broken link notices
+}
+
+// outcome is what happened to one link in a run
+type outcome int
+
+const (
+	outcomeSkipped outcome = iota
+	outcomeChecked
+	outcomeFailed
+)
+
+// Monitor checks the destinations of active links and records which ones are broken
+// Run it as a scheduler job: each run pages through the links not checked within RecheckAfter.
+// A link turning broken is sent to the notifier; a failed notice is sent again by the next run.
+type Monitor struct {
+	repo     repository.URLRepository
+	checker  Checker
+	notifier notify.BrokenNotifier
+	locker   cache.Locker
+	cfg      Config
+	hosts    *hostLimiter
+	logger   *logger.Logger
+}
+
+// NewMonitor creates a monitor; a nil notifier records broken links without telling anyone
+// locker keeps instances from scanning at the same time; nil runs without a lock
+func NewMonitor(repo repository.URLRepository, checker Checker, notifier notify.BrokenNotifier, locker cache.Locker, cfg Config, log *logger.Logger) *Monitor {
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 1
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	return &Monitor{
+		repo:     repo,
+		checker:  checker,
+		notifier: notifier,
+		locker:   locker,
+		cfg:      cfg,
+		hosts:    newHostLimiter(cfg.HostRate),
+		logger:   log,
+	}
+}
+
+// Run checks every active link that is due, one batch at a time
+func (m *Monitor) Run(ctx context.Context) error {
+	release, ok, err := m.lock(ctx)
+	if err != nil || !ok {
+		return err
+	}
+	defer release()
+
+	ctx, cancel := context.WithTimeout(ctx, monitorLockTTL)
+	defer cancel()
+
+	m.hosts.prune()
+	checkedBefore := time.Now().Add(-m.cfg.RecheckAfter)
+
+	var checked, failed int
+	var afterID uint
+	for ctx.Err() == nil {
+		links, err := m.repo.FindDueForCheck(ctx, checkedBefore, afterID, m.cfg.BatchSize)
+		if err != nil {
+			return err
+		}
+		if len(links) == 0 {
+			break
+		}
+
+		c, f := m.checkBatch(ctx, links)
+		checked += c
+		failed += f
+
+		// A short batch means every due link was seen
+		if len(links) < m.cfg.BatchSize {
+			break
+		}
+		afterID = links[len(links)-1].ID
+	}
+
+	if checked > 0 {
+		m.logger.Info("Checked link destinations", "count", checked)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d link checks could not be recorded, retrying next run", failed)
+	}
+	return ctx.Err()
+}
+
+// checkBatch checks links on Concurrency workers and counts the outcomes
+func (m *Monitor) checkBatch(ctx context.Context, links []domain.URL) (checked, failed int) {
+	queue := make(chan *domain.URL)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for i := 0; i < m.cfg.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for link := range queue {
+				result := m.checkLink(ctx, link)
+				mu.Lock()
+				switch result {
+				case outcomeChecked:
+					checked++
+				case outcomeFailed:
+					failed++
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for i := range links {
+		if ctx.Err() != nil {
+			break
+		}
+		queue <- &links[i]
+	}
+	close(queue)
+	wg.Wait()
+	return checked, failed
+}
+
+// checkLink checks one destination and records the outcome
+// Links whose host is rate limiting us are skipped; they are still due on the next run.
+func (m *Monitor) checkLink(ctx context.Context, link *domain.URL) outcome {
+	// A bundle's page is ours, and an expired link no longer sends anyone to its destination
+	if link.IsBundle() || link.IsExpired() {
+		return outcomeSkipped
+	}
+	host := destinationHost(link.OriginalURL)
+	if host == "" || !m.hosts.wait(ctx, host) {
+		return outcomeSkipped
+	}
+
+	result, err := m.checker.Check(ctx, link.OriginalURL)
+	if ctx.Err() != nil {
+		return outcomeSkipped
+	}
+	if err == nil && result.StatusCode == http.StatusTooManyRequests {
+		m.hosts.pause(host, result.RetryAfter)
+		metrics.LinkChecks.WithLabelValues("rate_limited").Inc()
+		m.logger.Debug("Destination host rate limits link checks, pausing it", "host", host, "retry_after", result.RetryAfter)
+		return outcomeSkipped
+	}
+
+	check := domain.LinkCheck{CheckedAt: time.Now()}
+	switch {
+	case err == nil:
+		status := result.StatusCode
+		check.StatusCode = &status
+		check.Broken = IsBroken(status)
+	case HostGone(err):
+		check.Broken = true
+	default:
+		// Timeouts and refused connections are often temporary, so they keep the previous verdict
+		check.Broken = link.IsBroken
+		m.logger.Debug("Link check got no answer", "short_code", link.ShortCode, "error", err)
+	}
+	metrics.LinkChecks.WithLabelValues(resultLabel(check, err)).Inc()
+
+	if check.Broken && !link.IsBroken && m.notifier != nil {
+		if err := m.notifier.NotifyBroken(ctx, m.notice(link, check)); err != nil {
+			// Not recording the check keeps the link due, so the next run sends the notice again
+			m.logger.Warn("Failed to send broken link notice", "error", err, "short_code", link.ShortCode)
+			return outcomeFailed
+		}
+	}
+
+	// With TENANT_SCOPED_CODES another tenant can hold the same code
+	if err := m.repo.RecordLinkCheck(domain.ContextWithTenant(ctx, link.TenantID), link.ShortCode, check); err != nil {
+		if errors.Is(err, domain.ErrURLNotFound) {
+			return outcomeSkipped
+		}
+		m.logger.Warn("Failed to record link check", "error", err, "short_code", link.ShortCode)
+		return outcomeFailed
+	}
+	return outcomeChecked
+}
+
+// lock takes the monitor lock when a locker is configured
+// ok is false without an error when another instance is running the checks
+func (m *Monitor) lock(ctx context.Context) (func(), bool, error) {
+	noop := func() {}
+	if m.locker == nil {
+		return noop, true, nil
+	}
+
+	release, ok, err := m.locker.TryLock(ctx, cache.LockKey("check_links"), monitorLockTTL)
+	if errors.Is(err, cache.ErrLockUnsupported) {
+		return noop, true, nil
+	}
+	if err != nil {
+		// Running unlocked would check every host twice, waiting for the next run is the safer choice
+		return nil, false, fmt.Errorf("link check lock: %w", err)
+	}
+	if !ok {
+		m.logger.Debug("Link check already running on another instance")
+		return nil, false, nil
+	}
+	return release, true, nil
+}
+
+// notice builds the payload for a link that turned broken
+func (m *Monitor) notice(link *domain.URL, check domain.LinkCheck) notify.BrokenNotice {
+	return notify.BrokenNotice{
+		Type:        domain.EventURLBroken,
+		ShortCode:   link.ShortCode,
+		ShortURL:    fmt.Sprintf("%s/%s", m.cfg.BaseURL, link.ShortCode),
+		OriginalURL: link.OriginalURL,
+		StatusCode:  check.StatusCode,
+		CheckedAt:   check.CheckedAt,
+	}
+}
+
+// Close releases the notifier's connections
+func (m *Monitor) Close() error {
+	if m.notifier == nil {
+		return nil
+	}
+	return m.notifier.Close()
+}
+
+// destinationHost is the lowercased host the token buckets are kept by
+func destinationHost(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(parsed.Hostname())
+}
+
+// resultLabel names a check for the link_checks_total metric
+func resultLabel(check domain.LinkCheck, err error) string {
+	switch {
+	case check.StatusCode == nil && !HostGone(err):
+		return "unreachable"
+	case check.Broken:
+		return "broken"
+	default:
+		return "ok"
+	}
+}
@@ -0,0 +1,202 @@
+// Package metadata fetches the title and favicon of link destinations for dashboards
+package metadata
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+
+	"url-shortener/internal/netguard"
+)
+
+const (
+	// DefaultTimeout bounds a whole fetch including redirects
+	DefaultTimeout = 5 * time.Second
+
+	// maxBodyBytes is how much of a page is scanned; titles and icons live in <head>
+	maxBodyBytes = 512 << 10
+
+	// maxRedirects stops fetches that bounce between hosts
+	maxRedirects = 5
+
+	// maxTitleLength keeps adversarial pages from storing huge titles
+	maxTitleLength = 300
+)
+
+// ErrBlockedAddress is returned when a destination resolves to a private or local network
+var ErrBlockedAddress = netguard.ErrBlockedAddress
+
+// Metadata is what enrichment extracts from a destination page
+// Empty fields mean the page didn't provide them
+type Metadata struct {
+	Title      string
+	FaviconURL string
+}
+
+// Fetcher retrieves metadata for a destination URL
+type Fetcher interface {
+	Fetch(ctx context.Context, rawURL string) (*Metadata, error)
+}
+
+// HTTPFetcher downloads destination pages over HTTP with an SSRF guard
+// Every connection, including those made for redirects, is checked after DNS resolution
+type HTTPFetcher struct {
+	client *http.Client
+}
+
+// NewHTTPFetcher creates a fetcher whose requests time out after timeout
+func NewHTTPFetcher(timeout time.Duration) *HTTPFetcher {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: netguard.Control,
+	}
+
+	return &HTTPFetcher{
+		client: &http.Client{
+			Timeout: timeout,
+			Transport: &http.Transport{
+				DialContext:         dialer.DialContext,
+				TLSHandshakeTimeout: timeout,
+				MaxIdleConns:        10,
+				IdleConnTimeout:     30 * time.Second,
+			},
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) >= maxRedirects {
+					return fmt.Errorf("stopped after %d redirects", maxRedirects)
+				}
+				if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+					return fmt.Errorf("redirect to unsupported scheme %q", req.URL.Scheme)
+				}
+				return nil
+			},
+		},
+	}
+}
+
+// Fetch downloads the page and extracts its metadata
+// Non-HTML responses yield empty metadata rather than an error
+func (f *HTTPFetcher) Fetch(ctx context.Context, rawURL string) (*Metadata, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+	req.Header.Set("User-Agent", "url-shortener-metadata/1.0")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	if contentType := resp.Header.Get("Content-Type"); contentType != "" && !strings.Contains(contentType, "html") {
+		return &Metadata{}, nil
+	}
+
+	// Relative icon links resolve against the final URL after redirects
+	return Parse(io.LimitReader(resp.Body, maxBodyBytes), resp.Request.URL), nil
+}
+
+// Parse extracts the title and favicon from an HTML document
+// Without an icon link the conventional /favicon.ico of the page's host is assumed
+func Parse(r io.Reader, base *url.URL) *Metadata {
+	meta := &Metadata{}
+	tokenizer := html.NewTokenizer(r)
+	inTitle := false
+	var title strings.Builder
+
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return finish(meta, title.String(), base)
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			switch token.Data {
+			case "title":
+				inTitle = meta.Title == "" && title.Len() == 0
+			case "link":
+				if meta.FaviconURL == "" && isIconLink(token) {
+					meta.FaviconURL = resolve(base, attr(token, "href"))
+				}
+			case "body":
+				// Everything enrichment needs is in <head>
+				return finish(meta, title.String(), base)
+			}
+
+		case html.TextToken:
+			if inTitle {
+				title.Write(tokenizer.Text())
+			}
+
+		case html.EndTagToken:
+			if name, _ := tokenizer.TagName(); string(name) == "title" {
+				inTitle = false
+			}
+		}
+	}
+}
+
+// finish normalises the collected title and fills in the default favicon
+func finish(meta *Metadata, title string, base *url.URL) *Metadata {
+	title = strings.Join(strings.Fields(title), " ")
+	if runes := []rune(title); len(runes) > maxTitleLength {
+		title = string(runes[:maxTitleLength])
+	}
+	meta.Title = title
+
+	if meta.FaviconURL == "" && base != nil {
+		meta.FaviconURL = resolve(base, "/favicon.ico")
+	}
+	return meta
+}
+
+// isIconLink matches rel="icon", rel="shortcut icon" and rel="apple-touch-icon"
+func isIconLink(token html.Token) bool {
+	for _, rel := range strings.Fields(strings.ToLower(attr(token, "rel"))) {
+		if rel == "icon" || rel == "apple-touch-icon" {
+			return attr(token, "href") != ""
+		}
+	}
+	return false
+}
+
+// attr returns the value of the named attribute, or an empty string
+func attr(token html.Token, name string) string {
+	for _, a := range token.Attr {
+		if a.Key == name {
+			return strings.TrimSpace(a.Val)
+		}
+	}
+	return ""
+}
+
+// resolve makes href absolute and keeps only http(s) results
+func resolve(base *url.URL, href string) string {
+	ref, err := url.Parse(href)
+	if err != nil {
+		return ""
+	}
+	if base != nil {
+		ref = base.ResolveReference(ref)
+	}
+	if ref.Scheme != "http" && ref.Scheme != "https" {
+		return ""
+	}
+	return ref.String()
+}
@@ -0,0 +1,165 @@
+// Package metrics defines the Prometheus metrics exported at /metrics
+package metrics
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	// CacheBreakerState is the current cache circuit breaker state (0 closed, 1 half-open, 2 open)
+	CacheBreakerState = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "urlshortener",
+		Subsystem: "cache",
+		Name:      "breaker_state",
+		Help:      "Cache circuit breaker state: 0 closed, 1 half-open, 2 open.",
+	})
+
+	// CacheBreakerTransitions counts breaker state changes by target state
+	CacheBreakerTransitions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "urlshortener",
+		Subsystem: "cache",
+		Name:      "breaker_transitions_total",
+		Help:      "Cache circuit breaker state transitions by new state.",
+	}, []string{"state"})
+
+	// CacheWritesDropped counts background cache writes dropped because the queue was full
+	CacheWritesDropped = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "urlshortener",
+		Subsystem: "cache",
+		Name:      "writes_dropped_total",
+		Help:      "Background cache writes dropped, oldest first, because the write queue was full.",
+	})
+
+	// CacheStaleServed counts redirects answered from a link entry past its soft TTL
+	CacheStaleServed = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "urlshortener",
+		Subsystem: "cache",
+		Name:      "stale_served_total",
+		Help:      "Redirects served from a stale link cache entry while it was refreshed.",
+	})
+
+	// CacheStaleRefreshes counts background refreshes of stale link entries by result (refreshed, removed, failed)
+	CacheStaleRefreshes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "urlshortener",
+		Subsystem: "cache",
+		Name:      "stale_refreshes_total",
+		Help:      "Background refreshes of stale link cache entries by result.",
+	}, []string{"result"})
+
+	// CacheWarmedLinks is the number of links the startup warm-up loaded into the cache
+	CacheWarmedLinks = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "urlshortener",
+		Subsystem: "cache",
+		Name:      "warmed_links",
+		Help:      "Links loaded into the cache by the startup warm-up.",
+	})
+
+	// DBConnections is the number of pooled database connections by state (in_use, idle)
+	DBConnections = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "urlshortener",
+		Subsystem: "db",
+		Name:      "connections",
+		Help:      "Database connections in the pool by state.",
+	}, []string{"state"})
+
+	// DBMaxOpenConnections is the configured upper bound on open connections, 0 for unlimited
+	DBMaxOpenConnections = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "urlshortener",
+		Subsystem: "db",
+		Name:      "max_open_connections",
+		Help:      "Maximum number of open database connections, 0 for unlimited.",
+	})
+
+	// DBWaits counts queries that had to wait for a free connection
+	DBWaits = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "urlshortener",
+		Subsystem: "db",
+		Name:      "waits_total",
+		Help:      "Times a query waited for a free database connection.",
+	})
+
+	// DBWaitSeconds is the total time spent waiting for a free connection
+	DBWaitSeconds = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "urlshortener",
+		Subsystem: "db",
+		Name:      "wait_seconds_total",
+		Help:      "Time spent waiting for a free database connection.",
+	})
+
+	// DBReplicaFallbacks counts replica reads answered by the primary, by reason (error, lag)
+	DBReplicaFallbacks = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "urlshortener",
+		Subsystem: "db",
+		Name:      "replica_fallbacks_total",
+		Help:      "Reads sent to the primary after a replica failed or lagged behind.",
+	}, []string{"reason"})
+
+	// DBRetries counts link queries run again after a transient failure, by repository method
+	DBRetries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "urlshortener",
+		Subsystem: "db",
+		Name:      "retries_total",
+		Help:      "Link queries retried after a transient failure such as a failover.",
+	}, []string{"operation"})
+
+	// FilteredClicks counts repeat clicks left out of the click counts by CLICK_DEDUP_WINDOW
+	FilteredClicks = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "urlshortener",
+		Name:      "filtered_clicks_total",
+		Help:      "Repeat clicks of one visitor on one link within the dedup window, not counted as clicks.",
+	})
+
+	// Conversions counts click receipts sent to POST /api/v1/conversions by result (recorded, replayed, invalid)
+	Conversions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "urlshortener",
+		Name:      "conversions_total",
+		Help:      "Click receipts redeemed as conversions, or refused as replayed or invalid.",
+	}, []string{"result"})
+
+	// LinkChecks counts destination checks by result (ok, broken, unreachable, rate_limited)
+	LinkChecks = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "urlshortener",
+		Name:      "link_checks_total",
+		Help:      "Destination checks of the link check job, by result.",
+	}, []string{"result"})
+
+	// OutboxPendingEvents is the number of events not yet published to the broker
+	OutboxPendingEvents = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "urlshortener",
+		Subsystem: "outbox",
+		Name:      "pending_events",
+		Help:      "Events in the outbox not yet published.",
+	})
+
+	// OutboxLagSeconds is the age of the oldest unpublished event
+	OutboxLagSeconds = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "urlshortener",
+		Subsystem: "outbox",
+		Name:      "lag_seconds",
+		Help:      "Age of the oldest unpublished outbox event, 0 when caught up.",
+	})
+
+	// OutboxPublished counts events acknowledged by the broker
+	OutboxPublished = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "urlshortener",
+		Subsystem: "outbox",
+		Name:      "published_total",
+		Help:      "Outbox events published to the broker.",
+	})
+
+	// OutboxPublishFailures counts failed publish attempts; the batch is retried on the next run
+	OutboxPublishFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "urlshortener",
+		Subsystem: "outbox",
+		Name:      "publish_failures_total",
+		Help:      "Failed outbox publish attempts.",
+	})
+)
+
+// Handler serves the default registry in the Prometheus text format
+func Handler() gin.HandlerFunc {
+	return gin.WrapH(promhttp.Handler())
+}
@@ -0,0 +1,8 @@
+-- Drops every table of the service, with all links and statistics
+DROP TABLE IF EXISTS events;
+DROP TABLE IF EXISTS api_keys;
+DROP TABLE IF EXISTS url_stats_daily;
+DROP TABLE IF EXISTS click_events;
+DROP TABLE IF EXISTS audit_logs;
+DROP TABLE IF EXISTS urls;
+DROP FUNCTION IF EXISTS update_updated_at_column();
@@ -0,0 +1,165 @@
+-- Schema as built by the former migrations/001 to 020 files
+-- Every statement is idempotent, so this also brings installs set up from those files, or by AutoMigrate, up to date
+
+-- Shortened links
+CREATE TABLE IF NOT EXISTS urls (
+    id BIGSERIAL PRIMARY KEY,
+    short_code VARCHAR(12) NOT NULL UNIQUE,
+    original_url TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP WITH TIME ZONE NULL,
+    click_count BIGINT DEFAULT 0,
+    last_access_at TIMESTAMP WITH TIME ZONE NULL,
+    creator_ip VARCHAR(45) NULL, -- Support IPv6
+    is_active BOOLEAN DEFAULT TRUE,
+    custom_alias BOOLEAN DEFAULT FALSE
+);
+
+-- Flag links that must show the "you are leaving" interstitial before redirecting
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS requires_interstitial BOOLEAN DEFAULT FALSE;
+
+-- UTM parameters appended to the destination at redirect time
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS utm_source VARCHAR(255) NULL;
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS utm_medium VARCHAR(255) NULL;
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS utm_campaign VARCHAR(255) NULL;
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS utm_term VARCHAR(255) NULL;
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS utm_content VARCHAR(255) NULL;
+
+-- Device/platform targeting rules, weighted A/B split of the default destination and bundle members
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS targets JSONB NULL;
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS variants JSONB NULL;
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS sticky_variants BOOLEAN DEFAULT FALSE;
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS bundle JSONB NULL;
+
+-- Destination page metadata fetched after a link is created; NULL when unknown or the fetch failed
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS page_title TEXT NULL;
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS favicon_url TEXT NULL;
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS metadata_fetched_at TIMESTAMP WITH TIME ZONE NULL;
+
+-- Opt-in pass-through of the short link's query string to the destination
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS forward_query BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Per-link Referrer-Policy, or "bounce" for the HTML bounce page; NULL sends no header
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS referrer_policy VARCHAR(32);
+
+-- Link on another shortener that original_url was resolved from; NULL when it was submitted directly
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS submitted_url TEXT NULL;
+
+-- Redirects of crawlers, counted apart from click_count so they don't inflate it
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS bot_clicks BIGINT NOT NULL DEFAULT 0;
+
+-- SHA-256 of the token handed to a link's creator; NULL for links created before tokens existed
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS management_token_hash VARCHAR(64);
+
+-- When the owner was warned that the link is about to expire; NULL until the notifier sent the webhook
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS expiry_notified_at TIMESTAMP WITH TIME ZONE;
+
+-- Host of the latest click's Referer and clicks per referring host, kept on the row so stats need no click events
+-- referrer_counts names at most 20 hosts; clicks from hosts seen later are pooled under "(other)"
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS last_referrer VARCHAR(255);
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS referrer_counts JSONB;
+
+-- Owner's title and description of a link, stored as plain text with HTML stripped
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS title VARCHAR(200);
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS description TEXT;
+
+-- User agent and origin of the request that created a link, for abuse investigations
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS creator_user_agent VARCHAR(255);
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS creator_origin VARCHAR(255);
+
+CREATE INDEX IF NOT EXISTS idx_urls_short_code ON urls(short_code) WHERE is_active = true;
+CREATE INDEX IF NOT EXISTS idx_urls_original_url ON urls(original_url);
+CREATE INDEX IF NOT EXISTS idx_urls_expires_at ON urls(expires_at) WHERE expires_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_urls_created_at ON urls(created_at);
+CREATE INDEX IF NOT EXISTS idx_urls_is_active ON urls(is_active);
+-- The expiry notifier scans active links by expiry date
+CREATE INDEX IF NOT EXISTS idx_urls_active_expires_at ON urls (expires_at) WHERE is_active = true AND expires_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_urls_creator_ip ON urls (creator_ip, created_at DESC);
+
+-- Keep updated_at current on every update
+CREATE OR REPLACE FUNCTION update_updated_at_column()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.updated_at = CURRENT_TIMESTAMP;
+    RETURN NEW;
+END;
+$$ language 'plpgsql';
+
+DROP TRIGGER IF EXISTS update_urls_updated_at ON urls;
+CREATE TRIGGER update_urls_updated_at
+    BEFORE UPDATE ON urls
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+-- Audit trail of administrative actions (deactivate, activate, ...)
+CREATE TABLE IF NOT EXISTS audit_logs (
+    id BIGSERIAL PRIMARY KEY,
+    action VARCHAR(64) NOT NULL,
+    short_code VARCHAR(12) NOT NULL,
+    actor_id VARCHAR(64) NOT NULL,
+    actor_ip VARCHAR(45) NULL,
+    details TEXT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_logs_short_code ON audit_logs(short_code);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_action ON audit_logs(action);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_created_at ON audit_logs(created_at);
+
+-- Individual click events, used for per-target and per-variant statistics and the daily rollup
+CREATE TABLE IF NOT EXISTS click_events (
+    id BIGSERIAL PRIMARY KEY,
+    short_code VARCHAR(12) NOT NULL,
+    target VARCHAR(64) NOT NULL DEFAULT 'default',
+    clicked_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Variant served for each click, NULL for links without a split
+ALTER TABLE click_events ADD COLUMN IF NOT EXISTS variant INTEGER NULL;
+ALTER TABLE click_events ADD COLUMN IF NOT EXISTS ip VARCHAR(45) NOT NULL DEFAULT '';
+ALTER TABLE click_events ADD COLUMN IF NOT EXISTS referrer VARCHAR(255) NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_click_events_short_code ON click_events(short_code);
+CREATE INDEX IF NOT EXISTS idx_click_events_clicked_at ON click_events(clicked_at);
+
+-- One row per short code and UTC day, rebuilt from click_events by the rollup job
+CREATE TABLE IF NOT EXISTS url_stats_daily (
+    short_code VARCHAR(12) NOT NULL,
+    date DATE NOT NULL,
+    clicks BIGINT NOT NULL DEFAULT 0,
+    unique_ips BIGINT NOT NULL DEFAULT 0,
+    top_referrer VARCHAR(255) NOT NULL DEFAULT '',
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (short_code, date)
+);
+
+CREATE INDEX IF NOT EXISTS idx_url_stats_daily_date ON url_stats_daily(date);
+
+-- Issued API keys; the secret itself is never stored, only its SHA-256
+CREATE TABLE IF NOT EXISTS api_keys (
+    id BIGSERIAL PRIMARY KEY,
+    key_hash VARCHAR(64) NOT NULL,
+    fingerprint VARCHAR(8) NOT NULL,
+    label VARCHAR(100) NOT NULL,
+    tier INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    revoked_at TIMESTAMP WITH TIME ZONE NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_api_keys_key_hash ON api_keys(key_hash);
+CREATE INDEX IF NOT EXISTS idx_api_keys_revoked_at ON api_keys(revoked_at);
+
+-- Transactional outbox of link lifecycle events, relayed to Kafka or NATS
+CREATE TABLE IF NOT EXISTS events (
+    id BIGSERIAL PRIMARY KEY,
+    type VARCHAR(32) NOT NULL,
+    short_code VARCHAR(12) NOT NULL,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    published_at TIMESTAMP WITH TIME ZONE NULL
+);
+
+-- The relay only ever scans the unpublished tail
+CREATE INDEX IF NOT EXISTS idx_events_unpublished ON events(id) WHERE published_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_events_published_at ON events(published_at) WHERE published_at IS NOT NULL;
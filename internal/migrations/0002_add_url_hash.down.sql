@@ -0,0 +1,4 @@
+-- Back to the dedup lookup on original_url itself
+CREATE INDEX IF NOT EXISTS idx_urls_original_url ON urls(original_url);
+DROP INDEX IF EXISTS idx_urls_url_hash;
+ALTER TABLE urls DROP COLUMN IF EXISTS url_hash;
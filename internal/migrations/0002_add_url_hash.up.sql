@@ -0,0 +1,10 @@
+-- SHA-256 of original_url for the dedup lookup
+-- A B-tree entry is limited to about 2700 bytes, so idx_urls_original_url fails inserts of very long URLs;
+-- the fixed-size hash is indexed instead and original_url is only compared on the matching rows
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS url_hash VARCHAR(64);
+
+-- Backfill existing rows with the same hex digest the application computes
+UPDATE urls SET url_hash = encode(sha256(convert_to(original_url, 'UTF8')), 'hex') WHERE url_hash IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_urls_url_hash ON urls (url_hash);
+DROP INDEX IF EXISTS idx_urls_original_url;
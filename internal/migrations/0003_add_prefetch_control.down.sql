@@ -0,0 +1,2 @@
+ALTER TABLE urls DROP COLUMN IF EXISTS prefetch_hits;
+ALTER TABLE urls DROP COLUMN IF EXISTS confirm_before_redirect;
@@ -0,0 +1,4 @@
+-- Links can ask for the confirm page to be shown to chat apps prefetching them
+-- Those prefetches are counted apart from clicks
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS confirm_before_redirect BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS prefetch_hits BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,2 @@
+-- Serves the case-folded fallback lookup of CASE_INSENSITIVE_CODES
+CREATE INDEX IF NOT EXISTS idx_urls_short_code_lower ON urls (lower(short_code));
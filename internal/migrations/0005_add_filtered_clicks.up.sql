@@ -0,0 +1,2 @@
+-- Repeat clicks of one visitor within CLICK_DEDUP_WINDOW are counted apart from clicks
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS filtered_clicks BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_urls_broken;
+ALTER TABLE urls DROP COLUMN IF EXISTS is_broken;
+ALTER TABLE urls DROP COLUMN IF EXISTS last_status_code;
+ALTER TABLE urls DROP COLUMN IF EXISTS last_checked_at;
@@ -0,0 +1,6 @@
+-- The link check job records how each destination answered it
+-- Broken links are few, so the partial index stays small
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS last_checked_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS last_status_code INTEGER;
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS is_broken BOOLEAN NOT NULL DEFAULT FALSE;
+CREATE INDEX IF NOT EXISTS idx_urls_broken ON urls (created_at DESC) WHERE is_broken;
@@ -0,0 +1,15 @@
+-- Fails while two tenants hold the same code; erase one of the links first
+ALTER TABLE url_stats_daily DROP CONSTRAINT IF EXISTS url_stats_daily_pkey;
+ALTER TABLE url_stats_daily ADD PRIMARY KEY (short_code, date);
+ALTER TABLE url_stats_daily DROP COLUMN IF EXISTS tenant_id;
+
+ALTER TABLE urls ADD CONSTRAINT urls_short_code_key UNIQUE (short_code);
+DROP INDEX IF EXISTS idx_urls_code_scope_short_code;
+ALTER TABLE urls DROP COLUMN IF EXISTS code_scope;
+
+DROP INDEX IF EXISTS idx_urls_tenant_id;
+ALTER TABLE events DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE audit_logs DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE click_events DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE api_keys DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE urls DROP COLUMN IF EXISTS tenant_id;
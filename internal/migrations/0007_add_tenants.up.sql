@@ -0,0 +1,21 @@
+-- Links, API keys and everything recorded about a link belong to a tenant; '' is the default tenant
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE click_events ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE events ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(64) NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS idx_urls_tenant_id ON urls (tenant_id);
+
+-- A code is unique within its code_scope: the tenant with TENANT_SCOPED_CODES, '' for every link otherwise
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS code_scope VARCHAR(64) NOT NULL DEFAULT '';
+CREATE UNIQUE INDEX IF NOT EXISTS idx_urls_code_scope_short_code ON urls (code_scope, short_code);
+ALTER TABLE urls DROP CONSTRAINT IF EXISTS urls_short_code_key;
+
+-- Installs set up by AutoMigrate have idx_urls_short_code as a unique index; it becomes the plain lookup index
+DROP INDEX IF EXISTS idx_urls_short_code;
+CREATE INDEX IF NOT EXISTS idx_urls_short_code ON urls (short_code) WHERE is_active = true;
+
+-- Two tenants can hold the same code, so daily rows are per tenant as well
+ALTER TABLE url_stats_daily ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE url_stats_daily DROP CONSTRAINT IF EXISTS url_stats_daily_pkey;
+ALTER TABLE url_stats_daily ADD PRIMARY KEY (tenant_id, short_code, date);
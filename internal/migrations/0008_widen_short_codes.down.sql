@@ -0,0 +1,6 @@
+-- Fails while a code is longer than 12 characters; erase those links first
+ALTER TABLE events ALTER COLUMN short_code TYPE VARCHAR(12);
+ALTER TABLE url_stats_daily ALTER COLUMN short_code TYPE VARCHAR(12);
+ALTER TABLE click_events ALTER COLUMN short_code TYPE VARCHAR(12);
+ALTER TABLE audit_logs ALTER COLUMN short_code TYPE VARCHAR(12);
+ALTER TABLE urls ALTER COLUMN short_code TYPE VARCHAR(12);
@@ -0,0 +1,6 @@
+-- Multi-segment aliases such as promo/summer-2024 are up to 64 characters long
+ALTER TABLE urls ALTER COLUMN short_code TYPE VARCHAR(64);
+ALTER TABLE audit_logs ALTER COLUMN short_code TYPE VARCHAR(64);
+ALTER TABLE click_events ALTER COLUMN short_code TYPE VARCHAR(64);
+ALTER TABLE url_stats_daily ALTER COLUMN short_code TYPE VARCHAR(64);
+ALTER TABLE events ALTER COLUMN short_code TYPE VARCHAR(64);
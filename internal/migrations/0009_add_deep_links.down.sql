@@ -0,0 +1,3 @@
+ALTER TABLE urls DROP COLUMN IF EXISTS android_fallback_url;
+ALTER TABLE urls DROP COLUMN IF EXISTS ios_fallback_url;
+ALTER TABLE urls DROP COLUMN IF EXISTS deep_link;
@@ -0,0 +1,4 @@
+-- Links can send iOS and Android visitors into an app, with a web fallback per platform
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS deep_link TEXT NOT NULL DEFAULT '';
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS ios_fallback_url TEXT NOT NULL DEFAULT '';
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS android_fallback_url TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_urls_destination_host_reverse;
+DROP INDEX IF EXISTS idx_urls_destination_host;
+ALTER TABLE urls DROP COLUMN IF EXISTS destination_host;
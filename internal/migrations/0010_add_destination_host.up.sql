@@ -0,0 +1,12 @@
+-- Lowercased host of original_url, without userinfo, port or IPv6 brackets, for the admin search by host
+-- Links without a host, such as mailto:, store an empty string
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS destination_host VARCHAR(255) NOT NULL DEFAULT '';
+
+-- Backfill existing rows the way the application derives the host
+UPDATE urls SET destination_host = coalesce(left(lower(trim(both '[]' from
+    substring(original_url from '^[A-Za-z][A-Za-z0-9+.-]*://(?:[^/?#@]*@)?(\[[^]/?#]*\]|[^/?#:]+)'))), 255), '')
+WHERE destination_host = '';
+
+-- Exact hosts use the plain index; "*.example.com" matches a prefix of the reversed host
+CREATE INDEX IF NOT EXISTS idx_urls_destination_host ON urls (destination_host);
+CREATE INDEX IF NOT EXISTS idx_urls_destination_host_reverse ON urls (reverse(destination_host) text_pattern_ops);
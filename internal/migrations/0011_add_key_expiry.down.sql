@@ -0,0 +1,2 @@
+ALTER TABLE api_keys DROP COLUMN IF EXISTS max_expiry_days;
+ALTER TABLE api_keys DROP COLUMN IF EXISTS default_expiry_days;
@@ -0,0 +1,3 @@
+-- API keys can have their own default expiry and cap on requested expiries; 0 keeps the global setting
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS default_expiry_days INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS max_expiry_days INTEGER NOT NULL DEFAULT 0;
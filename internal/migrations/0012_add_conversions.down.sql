@@ -0,0 +1,2 @@
+ALTER TABLE urls DROP COLUMN IF EXISTS conversions;
+ALTER TABLE urls DROP COLUMN IF EXISTS attach_click_id;
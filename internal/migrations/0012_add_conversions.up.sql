@@ -0,0 +1,3 @@
+-- Links can hand visitors a signed click receipt, redeemed as a conversion by the destination site
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS attach_click_id BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS conversions BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,2 @@
+-- Creators can share a link's click counts on a public page without handing out API keys
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS public_stats BOOLEAN NOT NULL DEFAULT FALSE;
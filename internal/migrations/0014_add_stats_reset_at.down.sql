@@ -0,0 +1 @@
+ALTER TABLE urls DROP COLUMN IF EXISTS stats_reset_at;
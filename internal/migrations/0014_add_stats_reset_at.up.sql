@@ -0,0 +1,2 @@
+-- Bumped by a stats reset, so click stats cached before it are no longer read
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS stats_reset_at TIMESTAMP WITH TIME ZONE;
@@ -0,0 +1,283 @@
+// Package migrations applies the numbered SQL files embedded in the binary to the database
+// The server runs them on startup when AUTO_MIGRATE is on and `server migrate` runs them by hand;
+// both go through Migrator, so they always agree on what is applied.
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+//go:embed *.sql
+var files embed.FS
+
+// lockID keys the advisory lock held while migrating; any constant works as long as it stays the same
+const lockID int64 = 0x75726c73 // "urls"
+
+// ErrPending is returned by Check when the database is behind the embedded migrations
+var ErrPending = errors.New("database schema is not up to date")
+
+// ErrNothingToRollBack is returned by Down when no migration is applied
+var ErrNothingToRollBack = errors.New("no migration is applied")
+
+// fileName matches "0002_add_url_hash.up.sql" and its ".down.sql" counterpart
+var fileName = regexp.MustCompile(`^(\d{4})_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Migration is one numbered schema change with the SQL to apply and to revert it
+type Migration struct {
+	Version int64
+	Name    string
+	up      string
+	down    string
+}
+
+// Status is a migration together with when it was applied; AppliedAt is nil for pending ones
+type Status struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// Parse reads the migrations in fsys, ordered by version
+// Every version needs both an up and a down file, and versions must be unique
+func Parse(fsys fs.FS) ([]Migration, error) {
+	names, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, name := range names {
+		m := fileName.FindStringSubmatch(path.Base(name))
+		if m == nil {
+			return nil, fmt.Errorf("migration %s: name must look like 0001_description.up.sql", name)
+		}
+		version, _ := strconv.ParseInt(m[1], 10, 64)
+		if version == 0 {
+			return nil, fmt.Errorf("migration %s: versions start at 0001", name)
+		}
+		content, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = migration
+		} else if migration.Name != m[2] {
+			return nil, fmt.Errorf("migration %s: version %04d is already used by %s", name, version, migration.Name)
+		}
+		if m[3] == "up" {
+			migration.up = string(content)
+		} else {
+			migration.down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.up == "" || migration.down == "" {
+			return nil, fmt.Errorf("migration %04d_%s: needs both an up and a down file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Migrator applies and reverts migrations and records them in schema_migrations
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+// New creates a Migrator for the migrations embedded in the binary
+// db must not prepare statements: the migration files hold several statements each,
+// which Postgres only accepts through the simple query protocol.
+func New(db *sql.DB) (*Migrator, error) {
+	migrations, err := Embedded()
+	if err != nil {
+		return nil, err
+	}
+	return NewWithMigrations(db, migrations), nil
+}
+
+// Embedded returns the migrations built into the binary
+func Embedded() ([]Migration, error) {
+	return Parse(files)
+}
+
+// NewWithMigrations creates a Migrator for a given set, e.g. a prefix of Embedded in tests
+func NewWithMigrations(db *sql.DB, migrations []Migration) *Migrator {
+	return &Migrator{db: db, migrations: migrations}
+}
+
+// Latest returns the highest version known to the migrator, 0 without migrations
+func (m *Migrator) Latest() int64 {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Up applies every pending migration and returns the versions it applied
+// All of them run in one transaction holding an advisory lock, so replicas starting at the same
+// time migrate one after the other and the later ones find nothing left to do. A failing migration
+// leaves the schema as it was. The transaction-scoped lock is released on commit, which also works
+// behind PgBouncer.
+func (m *Migrator) Up(ctx context.Context) ([]int64, error) {
+	var applied []int64
+	err := m.locked(ctx, func(tx *sql.Tx, done map[int64]time.Time) error {
+		for _, migration := range m.migrations {
+			if _, ok := done[migration.Version]; ok {
+				continue
+			}
+			if _, err := tx.ExecContext(ctx, migration.up); err != nil {
+				return fmt.Errorf("migration %04d_%s: %w", migration.Version, migration.Name, err)
+			}
+			if _, err := tx.ExecContext(ctx,
+				"INSERT INTO schema_migrations (version, description, applied_at) VALUES ($1, $2, $3)",
+				migration.Version, migration.Name, time.Now().UTC()); err != nil {
+				return fmt.Errorf("record migration %04d: %w", migration.Version, err)
+			}
+			applied = append(applied, migration.Version)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return applied, nil
+}
+
+// Down reverts the latest applied migration and returns its version
+func (m *Migrator) Down(ctx context.Context) (int64, error) {
+	var reverted int64
+	err := m.locked(ctx, func(tx *sql.Tx, done map[int64]time.Time) error {
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			migration := m.migrations[i]
+			if _, ok := done[migration.Version]; !ok {
+				continue
+			}
+			if _, err := tx.ExecContext(ctx, migration.down); err != nil {
+				return fmt.Errorf("revert migration %04d_%s: %w", migration.Version, migration.Name, err)
+			}
+			if _, err := tx.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version = $1", migration.Version); err != nil {
+				return fmt.Errorf("unrecord migration %04d: %w", migration.Version, err)
+			}
+			reverted = migration.Version
+			return nil
+		}
+		return ErrNothingToRollBack
+	})
+	return reverted, err
+}
+
+// Status lists every known migration and when it was applied
+// It only reads, so it works for a database user that may not change the schema.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var exists bool
+	if err := m.db.QueryRowContext(ctx, "SELECT to_regclass('schema_migrations') IS NOT NULL").Scan(&exists); err != nil {
+		return nil, fmt.Errorf("read schema_migrations: %w", err)
+	}
+	done := map[int64]time.Time{}
+	if exists {
+		var err error
+		if done, err = appliedVersions(ctx, m.db); err != nil {
+			return nil, err
+		}
+	}
+
+	status := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		s := Status{Migration: migration}
+		if at, ok := done[migration.Version]; ok {
+			s.AppliedAt = &at
+		}
+		status = append(status, s)
+	}
+	return status, nil
+}
+
+// Check fails with ErrPending, naming the first pending migration, when any migration isn't applied
+func (m *Migrator) Check(ctx context.Context) error {
+	status, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+	for _, s := range status {
+		if s.AppliedAt == nil {
+			return fmt.Errorf("%w: migration %04d_%s is pending", ErrPending, s.Version, s.Name)
+		}
+	}
+	return nil
+}
+
+// locked runs fn in a transaction holding the migration lock, with the applied versions read from schema_migrations
+// Versions unknown to this binary, written by a newer release, are passed through untouched.
+func (m *Migrator) locked(ctx context.Context, fn func(tx *sql.Tx, done map[int64]time.Time) error) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() //nolint:errcheck // no-op after Commit
+
+	if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1)", lockID); err != nil {
+		return fmt.Errorf("acquire migration lock: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version BIGINT PRIMARY KEY,
+		description VARCHAR(255) NOT NULL,
+		applied_at TIMESTAMP WITH TIME ZONE NOT NULL
+	)`); err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+	// Earlier releases ran AutoMigrate and recorded a single "auto-migrate" row under version 20;
+	// migration 0001 is idempotent and takes over from it
+	if _, err := tx.ExecContext(ctx, "DELETE FROM schema_migrations WHERE description = 'auto-migrate'"); err != nil {
+		return fmt.Errorf("clear legacy schema version: %w", err)
+	}
+
+	done, err := appliedVersions(ctx, tx)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx, done); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// querier is satisfied by both *sql.DB and *sql.Tx
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// appliedVersions reads schema_migrations into a map of version to applied time
+func appliedVersions(ctx context.Context, q querier) (map[int64]time.Time, error) {
+	rows, err := q.QueryContext(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	done := make(map[int64]time.Time)
+	for rows.Next() {
+		var version int64
+		var at time.Time
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		done[version] = at.UTC()
+	}
+	return done, rows.Err()
+}
@@ -0,0 +1,126 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"url-shortener/internal/cache"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/repository"
+	"url-shortener/pkg/logger"
+)
+
+// expiryLockTTL bounds one run; the lock is never held longer, even by a crashed instance
+const expiryLockTTL = 10 * time.Minute
+
+// ExpiryJob warns owners of links that expire within a window
+// Run it as a scheduler job: a link is notified once, and a failed notice is sent again by the next run
+type ExpiryJob struct {
+	repo     repository.URLRepository
+	notifier Notifier
+	locker   cache.Locker
+	baseURL  string
+	window   time.Duration
+	logger   *logger.Logger
+}
+
+// NewExpiryJob creates a job notifying links that expire less than window from now
+// locker keeps instances from scanning at the same time; nil runs without a lock
+func NewExpiryJob(repo repository.URLRepository, notifier Notifier, locker cache.Locker, baseURL string, window time.Duration, log *logger.Logger) *ExpiryJob {
+	return &ExpiryJob{repo: repo, notifier: notifier, locker: locker, baseURL: baseURL, window: window, logger: log}
+}
+
+// Run sends a notice for every expiring link not notified yet and marks the ones delivered
+// Links are marked one by one, so a run cut short only repeats the notice that was in flight
+func (j *ExpiryJob) Run(ctx context.Context) error {
+	release, ok, err := j.lock(ctx)
+	if err != nil || !ok {
+		return err
+	}
+	defer release()
+
+	ctx, cancel := context.WithTimeout(ctx, expiryLockTTL)
+	defer cancel()
+
+	now := time.Now()
+	links, err := j.repo.FindExpiringBetween(ctx, now, now.Add(j.window), 0)
+	if err != nil {
+		return err
+	}
+
+	var sent, failed int
+	var lastErr error
+	for i := range links {
+		link := &links[i]
+		if link.ExpiryNotifiedAt != nil || link.ExpiresAt == nil {
+			continue
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		if err := j.notifier.Notify(ctx, j.notice(link)); err != nil {
+			failed++
+			lastErr = err
+			j.logger.Warn("Failed to send expiry notice", "error", err, "short_code", link.ShortCode)
+			continue
+		}
+		if err := j.repo.MarkExpiryNotified(domain.ContextWithTenant(ctx, link.TenantID), link.ShortCode, time.Now()); err != nil {
+			// The notice went out, so the next run sends it a second time
+			failed++
+			lastErr = err
+			j.logger.Warn("Failed to record expiry notice", "error", err, "short_code", link.ShortCode)
+			continue
+		}
+		sent++
+	}
+
+	if sent > 0 {
+		j.logger.Info("Sent expiry notices", "count", sent)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d expiry notices failed, retrying next run: %w", failed, lastErr)
+	}
+	return ctx.Err()
+}
+
+// lock takes the job lock when a locker is configured
+// ok is false without an error when another instance is running the job
+func (j *ExpiryJob) lock(ctx context.Context) (func(), bool, error) {
+	noop := func() {}
+	if j.locker == nil {
+		return noop, true, nil
+	}
+
+	release, ok, err := j.locker.TryLock(ctx, cache.LockKey("notify_expiring"), expiryLockTTL)
+	if errors.Is(err, cache.ErrLockUnsupported) {
+		return noop, true, nil
+	}
+	if err != nil {
+		// Running unlocked could notify twice, waiting for the next run is the safer choice
+		return nil, false, fmt.Errorf("expiry notifier lock: %w", err)
+	}
+	if !ok {
+		j.logger.Debug("Expiry notifier already running on another instance")
+		return nil, false, nil
+	}
+	return release, true, nil
+}
+
+// notice builds the payload for one link
+func (j *ExpiryJob) notice(link *domain.URL) ExpiryNotice {
+	return ExpiryNotice{
+		Type:        domain.EventLinkExpiring,
+		ShortCode:   link.ShortCode,
+		ShortURL:    fmt.Sprintf("%s/%s", j.baseURL, link.ShortCode),
+		OriginalURL: link.OriginalURL,
+		ExpiresAt:   *link.ExpiresAt,
+	}
+}
+
+// Close releases the notifier's connections
+func (j *ExpiryJob) Close() error {
+	return j.notifier.Close()
+}
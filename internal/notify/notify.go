@@ -0,0 +1,39 @@
+// Package notify warns link owners about events they have to act on, such as an upcoming expiry or a broken destination
+package notify
+
+import (
+	"context"
+	"time"
+)
+
+// ExpiryNotice tells an owner that a link stops redirecting soon
+type ExpiryNotice struct {
+	Type        string    `json:"type"` // Always domain.EventLinkExpiring
+	ShortCode   string    `json:"short_code"`
+	ShortURL    string    `json:"short_url"`
+	OriginalURL string    `json:"original_url"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// BrokenNotice tells an owner that a link's destination stopped answering
+type BrokenNotice struct {
+	Type        string    `json:"type"` // Always domain.EventURLBroken
+	ShortCode   string    `json:"short_code"`
+	ShortURL    string    `json:"short_url"`
+	OriginalURL string    `json:"original_url"`
+	StatusCode  *int      `json:"status_code"` // Null when the destination's host no longer resolves
+	CheckedAt   time.Time `json:"checked_at"`
+}
+
+// Notifier delivers notices to owners
+// Notify returns nil only once the notice was accepted; failed notices are sent again later
+type Notifier interface {
+	Notify(ctx context.Context, notice ExpiryNotice) error
+	Close() error
+}
+
+// BrokenNotifier delivers broken link notices, with the same delivery promise as Notifier
+type BrokenNotifier interface {
+	NotifyBroken(ctx context.Context, notice BrokenNotice) error
+	Close() error
+}
@@ -0,0 +1,70 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// WebhookNotifier posts every notice as JSON to one URL
+type WebhookNotifier struct {
+	endpoint string
+	client   *http.Client
+}
+
+// NewWebhookNotifier creates a notifier posting to endpoint
+func NewWebhookNotifier(endpoint string, timeout time.Duration) *WebhookNotifier {
+	return &WebhookNotifier{
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: timeout},
+	}
+}
+
+// Notify posts the notice and treats any 2xx status as delivered
+func (n *WebhookNotifier) Notify(ctx context.Context, notice ExpiryNotice) error {
+	return n.post(ctx, "expiry webhook", notice)
+}
+
+// NotifyBroken posts the notice like Notify does
+func (n *WebhookNotifier) NotifyBroken(ctx context.Context, notice BrokenNotice) error {
+	return n.post(ctx, "link check webhook", notice)
+}
+
+// post sends notice as JSON; name prefixes the errors
+func (n *WebhookNotifier) post(ctx context.Context, name string, notice interface{}) error {
+	body, err := json.Marshal(notice)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+		return fmt.Errorf("%s: status %d: %s", name, resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+	// Drain the body so the connection is reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+	return nil
+}
+
+// Close releases idle connections
+func (n *WebhookNotifier) Close() error {
+	n.client.CloseIdleConnections()
+	return nil
+}
@@ -0,0 +1,95 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"url-shortener/internal/domain"
+)
+
+// kafkaContentType is the embedded-JSON format of the Confluent REST proxy v2 API
+const kafkaContentType = "application/vnd.kafka.json.v2+json"
+
+// KafkaRESTPublisher produces to a Kafka topic through a Confluent-compatible REST proxy
+// Records are keyed by short code so all events of one link land in the same partition, in order
+type KafkaRESTPublisher struct {
+	endpoint string
+	client   *http.Client
+}
+
+// NewKafkaRESTPublisher creates a publisher for topic behind the proxy at baseURL
+func NewKafkaRESTPublisher(baseURL, topic string, timeout time.Duration) *KafkaRESTPublisher {
+	return &KafkaRESTPublisher{
+		endpoint: strings.TrimRight(baseURL, "/") + "/topics/" + url.PathEscape(topic),
+		client:   &http.Client{Timeout: timeout},
+	}
+}
+
+// kafkaRecord is one record of a produce request
+type kafkaRecord struct {
+	Key   string   `json:"key"`
+	Value Envelope `json:"value"`
+}
+
+// kafkaProduceResponse reports the outcome per record
+type kafkaProduceResponse struct {
+	Offsets []struct {
+		ErrorCode *int   `json:"error_code"`
+		Error     string `json:"error"`
+	} `json:"offsets"`
+}
+
+// Publish produces the batch in one request and fails if any record was rejected
+func (p *KafkaRESTPublisher) Publish(ctx context.Context, events []domain.OutboxEvent) error {
+	records := make([]kafkaRecord, len(events))
+	for i, event := range events {
+		records[i] = kafkaRecord{Key: event.ShortCode, Value: NewEnvelope(event)}
+	}
+
+	body, err := json.Marshal(map[string]interface{}{"records": records})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", kafkaContentType)
+	req.Header.Set("Accept", "application/vnd.kafka.v2+json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("kafka rest proxy: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("kafka rest proxy: status %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+
+	var result kafkaProduceResponse
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return fmt.Errorf("kafka rest proxy: invalid response: %w", err)
+	}
+	for _, offset := range result.Offsets {
+		if offset.ErrorCode != nil || offset.Error != "" {
+			return fmt.Errorf("kafka rest proxy: record rejected: %s", offset.Error)
+		}
+	}
+	return nil
+}
+
+// Close releases idle connections
+func (p *KafkaRESTPublisher) Close() error {
+	p.client.CloseIdleConnections()
+	return nil
+}
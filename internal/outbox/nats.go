@@ -0,0 +1,273 @@
+package outbox
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"url-shortener/internal/domain"
+)
+
+// NATSPublisher publishes to a subject captured by a JetStream stream, speaking the client protocol
+// Every event is sent with a reply inbox, and a batch counts as delivered once the stream acknowledged
+// each one as stored. A subject no stream captures fails the batch instead of dropping it. Events carry
+// their ID as Nats-Msg-Id, so the stream discards resends within its duplicate window; the connection is
+// re-dialed after any failure
+type NATSPublisher struct {
+	addr    string
+	user    string
+	pass    string
+	subject string
+	timeout time.Duration
+
+	mu     sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+	inbox  string // Prefix of the reply subjects, subscribed to as inbox.* on connect
+	next   uint64 // Suffix of the next reply subject, so a late ack can't be taken for a later message's
+}
+
+// pubAck is the reply of a JetStream stream to a publish
+type pubAck struct {
+	Stream string `json:"stream"`
+	Seq    uint64 `json:"seq"`
+	Error  *struct {
+		Code        int    `json:"code"`
+		Description string `json:"description"`
+	} `json:"error"`
+}
+
+// NewNATSPublisher creates a publisher for subject on the server at rawURL (nats://[user:pass@]host:port)
+func NewNATSPublisher(rawURL, subject string, timeout time.Duration) (*NATSPublisher, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "nats" || u.Host == "" {
+		return nil, fmt.Errorf("invalid NATS url %q", rawURL)
+	}
+	if subject == "" || strings.ContainsAny(subject, " \t\r\n") {
+		return nil, fmt.Errorf("invalid NATS subject %q", subject)
+	}
+
+	p := &NATSPublisher{addr: u.Host, subject: subject, timeout: timeout}
+	if u.User != nil {
+		p.user = u.User.Username()
+		p.pass, _ = u.User.Password()
+	}
+	return p, nil
+}
+
+// Publish sends every event as its own message and waits for the stream to acknowledge all of them
+func (p *NATSPublisher) Publish(ctx context.Context, events []domain.OutboxEvent) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.publish(ctx, events); err != nil {
+		p.reset()
+		return fmt.Errorf("nats: %w", err)
+	}
+	return nil
+}
+
+// publish writes the batch on the current connection and collects an ack per event
+func (p *NATSPublisher) publish(ctx context.Context, events []domain.OutboxEvent) error {
+	if p.conn == nil {
+		if err := p.connect(ctx); err != nil {
+			return err
+		}
+	}
+
+	deadline := time.Now().Add(p.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := p.conn.SetDeadline(deadline); err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(p.conn)
+	pending := make(map[string]uint64, len(events))
+	for _, event := range events {
+		body, err := json.Marshal(NewEnvelope(event))
+		if err != nil {
+			return err
+		}
+		p.next++
+		reply := p.inbox + "." + strconv.FormatUint(p.next, 10)
+		pending[reply] = event.ID
+
+		headers := "NATS/1.0\r\nNats-Msg-Id: " + strconv.FormatUint(event.ID, 10) + "\r\n\r\n"
+		fmt.Fprintf(w, "HPUB %s %s %d %d\r\n", p.subject, reply, len(headers), len(headers)+len(body))
+		w.WriteString(headers)
+		w.Write(body)
+		w.WriteString("\r\n")
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	return p.awaitAcks(pending)
+}
+
+// connect dials the server, reads its INFO, sends CONNECT and subscribes to a fresh reply inbox
+func (p *NATSPublisher) connect(ctx context.Context) error {
+	dialer := net.Dialer{Timeout: p.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", p.addr)
+	if err != nil {
+		return err
+	}
+	p.conn = conn
+	p.reader = bufio.NewReader(conn)
+
+	if err := conn.SetDeadline(time.Now().Add(p.timeout)); err != nil {
+		return err
+	}
+	line, err := p.readLine()
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(line, "INFO ") {
+		return fmt.Errorf("unexpected greeting %q", line)
+	}
+
+	// Headers carry the message ID; no_responders turns a subject without a stream into an immediate 503
+	options := map[string]interface{}{
+		"verbose":       false,
+		"pedantic":      false,
+		"headers":       true,
+		"no_responders": true,
+		"name":          "url-shortener-outbox",
+		"lang":          "go",
+		"version":       "1.0.0",
+	}
+	if p.user != "" {
+		options["user"] = p.user
+		options["pass"] = p.pass
+	}
+	connect, _ := json.Marshal(options)
+
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
+	}
+	p.inbox = "_INBOX." + hex.EncodeToString(suffix)
+	_, err = fmt.Fprintf(conn, "CONNECT %s\r\nSUB %s.* 1\r\n", connect, p.inbox)
+	return err
+}
+
+// awaitAcks reads until every reply subject in pending got a positive ack, answering the server's PINGs
+// A negative ack, a missing stream or -ERR fails the batch
+func (p *NATSPublisher) awaitAcks(pending map[string]uint64) error {
+	for len(pending) > 0 {
+		line, err := p.readLine()
+		if err != nil {
+			return err
+		}
+		switch {
+		case line == "PING":
+			if _, err := p.conn.Write([]byte("PONG\r\n")); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return fmt.Errorf("server error: %s", strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+		case strings.HasPrefix(line, "MSG "), strings.HasPrefix(line, "HMSG "):
+			subject, status, payload, err := p.readMessage(line)
+			if err != nil {
+				return err
+			}
+			id, ok := pending[subject]
+			if !ok {
+				continue // An ack of a batch that already failed
+			}
+			if status == "503" {
+				return fmt.Errorf("no JetStream stream captures subject %q", p.subject)
+			}
+			var ack pubAck
+			if err := json.Unmarshal(payload, &ack); err != nil {
+				return fmt.Errorf("event %d: unexpected ack %q", id, payload)
+			}
+			if ack.Error != nil {
+				return fmt.Errorf("event %d: stream error %d: %s", id, ack.Error.Code, ack.Error.Description)
+			}
+			if ack.Stream == "" {
+				return fmt.Errorf("event %d: unexpected ack %q", id, payload)
+			}
+			delete(pending, subject)
+		}
+	}
+	return nil
+}
+
+// readMessage reads the body of the MSG or HMSG announced by line
+// It returns the subject, the status of the header block, if any, and the payload after the headers
+func (p *NATSPublisher) readMessage(line string) (string, string, []byte, error) {
+	fields := strings.Fields(line)
+	withHeaders := fields[0] == "HMSG"
+	// MSG subject sid [reply] size, HMSG subject sid [reply] header-size total-size
+	minFields := 4
+	if withHeaders {
+		minFields = 5
+	}
+	if len(fields) < minFields || len(fields) > minFields+1 {
+		return "", "", nil, fmt.Errorf("malformed %s line %q", fields[0], line)
+	}
+	total, err := strconv.Atoi(fields[len(fields)-1])
+	if err != nil || total < 0 {
+		return "", "", nil, fmt.Errorf("malformed %s line %q", fields[0], line)
+	}
+	headerSize := 0
+	if withHeaders {
+		headerSize, err = strconv.Atoi(fields[len(fields)-2])
+		if err != nil || headerSize < 0 || headerSize > total {
+			return "", "", nil, fmt.Errorf("malformed %s line %q", fields[0], line)
+		}
+	}
+
+	body := make([]byte, total+2)
+	if _, err := io.ReadFull(p.reader, body); err != nil {
+		return "", "", nil, err
+	}
+	status := ""
+	if withHeaders {
+		// The block starts with "NATS/1.0" and an optional status code, e.g. "NATS/1.0 503"
+		first, _, _ := strings.Cut(string(body[:headerSize]), "\r\n")
+		if parts := strings.Fields(first); len(parts) > 1 {
+			status = parts[1]
+		}
+	}
+	return fields[1], status, body[headerSize:total], nil
+}
+
+// readLine returns the next protocol line without its CRLF
+func (p *NATSPublisher) readLine() (string, error) {
+	line, err := p.reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// reset drops the connection so the next Publish dials again
+func (p *NATSPublisher) reset() {
+	if p.conn != nil {
+		p.conn.Close()
+	}
+	p.conn = nil
+	p.reader = nil
+}
+
+// Close closes the connection
+func (p *NATSPublisher) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.reset()
+	return nil
+}
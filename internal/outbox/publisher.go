@@ -0,0 +1,54 @@
+// Package outbox relays lifecycle events from the events table to Kafka or NATS
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"url-shortener/internal/config"
+	"url-shortener/internal/domain"
+)
+
+// Publisher delivers a batch of events to a message broker
+// Publish returns nil only once the broker accepted every event in the batch
+type Publisher interface {
+	Publish(ctx context.Context, events []domain.OutboxEvent) error
+	Close() error
+}
+
+// Envelope is the message body consumers receive
+// ID is unique per event; delivery is at-least-once, so consumers deduplicate on it
+type Envelope struct {
+	ID         uint64          `json:"id"`
+	Type       string          `json:"type"`
+	TenantID   string          `json:"tenant_id,omitempty"` // Omitted for the default tenant
+	ShortCode  string          `json:"short_code"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Data       json.RawMessage `json:"data"`
+}
+
+// NewEnvelope wraps a stored event for publishing
+func NewEnvelope(event domain.OutboxEvent) Envelope {
+	return Envelope{
+		ID:         event.ID,
+		Type:       event.Type,
+		TenantID:   event.TenantID,
+		ShortCode:  event.ShortCode,
+		OccurredAt: event.CreatedAt,
+		Data:       json.RawMessage(event.Payload),
+	}
+}
+
+// NewPublisher builds the publisher selected by EVENTS_DRIVER
+func NewPublisher(cfg *config.Config) (Publisher, error) {
+	switch cfg.EventsDriver {
+	case config.EventsDriverKafka:
+		return NewKafkaRESTPublisher(cfg.EventsKafkaRESTURL, cfg.EventsTopic, 10*time.Second), nil
+	case config.EventsDriverNATS:
+		return NewNATSPublisher(cfg.EventsNATSURL, cfg.EventsTopic, 10*time.Second)
+	default:
+		return nil, fmt.Errorf("unsupported events driver %q", cfg.EventsDriver)
+	}
+}
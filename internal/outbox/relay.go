@@ -0,0 +1,71 @@
+package outbox
+
+import (
+	"context"
+	"time"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/metrics"
+	"url-shortener/internal/repository"
+	"url-shortener/pkg/logger"
+)
+
+// Relay moves events from the outbox table to the broker
+// Run it as a scheduler job: every run drains what is pending and then reports the lag
+type Relay struct {
+	repo      repository.OutboxRepository
+	publisher Publisher
+	batchSize int
+	logger    *logger.Logger
+}
+
+// NewRelay creates a relay publishing up to batchSize events per round trip
+func NewRelay(repo repository.OutboxRepository, publisher Publisher, batchSize int, log *logger.Logger) *Relay {
+	return &Relay{repo: repo, publisher: publisher, batchSize: batchSize, logger: log}
+}
+
+// Run publishes pending batches until the outbox is empty, publishing fails or ctx is done
+// A failed batch stays unpublished and is retried by the next run
+func (r *Relay) Run(ctx context.Context) error {
+	defer r.reportLag(ctx)
+
+	for ctx.Err() == nil {
+		published, err := r.repo.PublishPending(ctx, r.batchSize, func(batch []domain.OutboxEvent) error {
+			return r.publisher.Publish(ctx, batch)
+		})
+		if err != nil {
+			metrics.OutboxPublishFailures.Inc()
+			return err
+		}
+
+		metrics.OutboxPublished.Add(float64(published))
+		if published < r.batchSize {
+			return nil
+		}
+	}
+	return nil
+}
+
+// reportLag updates the lag gauges; it runs even when the relay is being stopped
+func (r *Relay) reportLag(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+
+	pending, oldest, err := r.repo.Lag(ctx)
+	if err != nil {
+		r.logger.Warn("Failed to measure outbox lag", "error", err)
+		return
+	}
+
+	metrics.OutboxPendingEvents.Set(float64(pending))
+	lag := 0.0
+	if oldest != nil {
+		lag = time.Since(*oldest).Seconds()
+	}
+	metrics.OutboxLagSeconds.Set(lag)
+}
+
+// Close releases the publisher's connections
+func (r *Relay) Close() error {
+	return r.publisher.Close()
+}
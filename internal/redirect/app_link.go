@@ -0,0 +1,51 @@
+package redirect
+
+import (
+	"net/url"
+	"strings"
+
+	"url-shortener/internal/domain"
+)
+
+// AppTargetPrefix labels clicks sent into the app, followed by the platform, e.g. "app:ios"
+const AppTargetPrefix = "app:"
+
+// LaunchApp sends iOS and Android visitors of a link with a deep link into its app
+// An https deep link, a universal link or Android app link, becomes the destination: the OS opens the app
+// when it's installed and the browser loads the page otherwise. A custom scheme fails with an error page
+// when the app is missing, so the destination becomes the platform's fallback, or the link's own
+// destination, and the returned launch tells the caller to try the deep link from a page first.
+// Other visitors get result unchanged.
+func LaunchApp(link domain.AppLink, result Result, userAgent string) (Result, *domain.AppLaunch) {
+	platform := DetectPlatform(userAgent)
+	if link.DeepLink == "" || (platform != PlatformIOS && platform != PlatformAndroid) {
+		return result, nil
+	}
+
+	result.Target = AppTargetPrefix + platform
+	if strings.HasPrefix(strings.ToLower(link.DeepLink), "https:") {
+		result.Destination = link.DeepLink
+		return result, nil
+	}
+
+	fallback := link.IOSFallback
+	if platform == PlatformAndroid {
+		fallback = link.AndroidFallback
+	}
+	if fallback != "" {
+		result.Destination = fallback
+	}
+	return result, &domain.AppLaunch{DeepLink: link.DeepLink, Android: platform == PlatformAndroid}
+}
+
+// IntentURL rewrites a custom-scheme deep link as an Android intent:// URL
+// Chrome opens fallback itself when no app handles the scheme, which a timer can't do reliably.
+// Intent parameters live in the fragment, so a fragment of the deep link is dropped.
+func IntentURL(deepLink, fallback string) string {
+	deepLink, _, _ = strings.Cut(deepLink, "#")
+	scheme, rest, _ := strings.Cut(deepLink, ":")
+	rest = strings.TrimPrefix(rest, "//")
+
+	return "intent://" + rest + "#Intent;scheme=" + scheme +
+		";S.browser_fallback_url=" + url.QueryEscape(fallback) + ";end"
+}
@@ -0,0 +1,19 @@
+package redirect
+
+import "strings"
+
+// IsBot reports whether userAgent belongs to a crawler or link preview fetcher
+// patterns are lowercase substrings, e.g. "bot" or "facebookexternalhit"; an empty User-Agent is not a bot
+func IsBot(userAgent string, patterns []string) bool {
+	if userAgent == "" {
+		return false
+	}
+
+	ua := strings.ToLower(userAgent)
+	for _, pattern := range patterns {
+		if strings.Contains(ua, pattern) {
+			return true
+		}
+	}
+	return false
+}
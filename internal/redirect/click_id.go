@@ -0,0 +1,21 @@
+package redirect
+
+import (
+	"net/url"
+	"strings"
+
+	"url-shortener/internal/domain"
+)
+
+// WithClickID sets the ush_click parameter of destination to clickID, replacing any value it already had
+// Only web destinations get it; an app scheme or mailto: has no page to read it. Empty clickID changes nothing.
+func WithClickID(destination, clickID string) string {
+	if clickID == "" {
+		return destination
+	}
+	scheme, _, ok := strings.Cut(destination, ":")
+	if !ok || (!strings.EqualFold(scheme, "http") && !strings.EqualFold(scheme, "https")) {
+		return destination
+	}
+	return MergeQuery(destination, domain.ClickIDParam+"="+url.QueryEscape(clickID), true)
+}
@@ -0,0 +1,31 @@
+package redirect
+
+import (
+	"regexp"
+	"strings"
+)
+
+// UserAgentMatcher recognizes User-Agents by substring, compiled once into a single expression
+// Prefetch detection runs on every redirect of a link that opts in, so the patterns aren't rescanned per request
+type UserAgentMatcher struct {
+	re *regexp.Regexp // nil when there are no patterns
+}
+
+// NewUserAgentMatcher compiles patterns into a case-insensitive matcher; empty patterns are skipped
+func NewUserAgentMatcher(patterns []string) *UserAgentMatcher {
+	quoted := make([]string, 0, len(patterns))
+	for _, pattern := range patterns {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			quoted = append(quoted, regexp.QuoteMeta(pattern))
+		}
+	}
+	if len(quoted) == 0 {
+		return &UserAgentMatcher{}
+	}
+	return &UserAgentMatcher{re: regexp.MustCompile("(?i)" + strings.Join(quoted, "|"))}
+}
+
+// Match reports whether userAgent contains one of the patterns; an empty User-Agent never matches
+func (m *UserAgentMatcher) Match(userAgent string) bool {
+	return m != nil && m.re != nil && userAgent != "" && m.re.MatchString(userAgent)
+}
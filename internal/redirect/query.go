@@ -0,0 +1,80 @@
+package redirect
+
+import (
+	"net/url"
+	"strings"
+)
+
+// MergeQuery adds the query string of the short link request to destination
+// Parameters are copied as raw pairs, so repeated keys and their order survive, and the
+// destination's fragment is kept. On conflicting keys the destination's values win unless
+// incomingWins is set, in which case all of the destination's values for that key are replaced.
+func MergeQuery(destination, rawIncoming string, incomingWins bool) string {
+	incoming := queryPairs(rawIncoming)
+	if len(incoming) == 0 {
+		return destination
+	}
+
+	parsed, err := url.Parse(destination)
+	if err != nil {
+		return destination
+	}
+	existing := queryPairs(parsed.RawQuery)
+
+	var merged []string
+	if incomingWins {
+		replaced := pairKeys(incoming)
+		for _, pair := range existing {
+			if !replaced[pair.key] {
+				merged = append(merged, pair.raw)
+			}
+		}
+		for _, pair := range incoming {
+			merged = append(merged, pair.raw)
+		}
+	} else {
+		kept := pairKeys(existing)
+		for _, pair := range existing {
+			merged = append(merged, pair.raw)
+		}
+		for _, pair := range incoming {
+			if !kept[pair.key] {
+				merged = append(merged, pair.raw)
+			}
+		}
+	}
+
+	parsed.RawQuery = strings.Join(merged, "&")
+	return parsed.String()
+}
+
+// queryPair is one key=value segment of a query string in its original encoding
+type queryPair struct {
+	key string // Unescaped, used for conflict detection
+	raw string
+}
+
+// queryPairs splits a raw query string without re-encoding it
+func queryPairs(rawQuery string) []queryPair {
+	var pairs []queryPair
+	for _, segment := range strings.Split(rawQuery, "&") {
+		if segment == "" {
+			continue
+		}
+		key, _, _ := strings.Cut(segment, "=")
+		if unescaped, err := url.QueryUnescape(key); err == nil {
+			key = unescaped
+		}
+		pairs = append(pairs, queryPair{key: key, raw: segment})
+	}
+	return pairs
+}
+
+// pairKeys returns the set of keys present in pairs
+func pairKeys(pairs []queryPair) map[string]bool {
+	keys := make(map[string]bool, len(pairs))
+	for _, pair := range pairs {
+		keys[pair.key] = true
+	}
+	return keys
+}
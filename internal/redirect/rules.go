@@ -0,0 +1,149 @@
+// Package redirect selects the destination of a short link for a given visitor
+// Evaluation is pure so rules can be tested without HTTP, cache or database
+package redirect
+
+import (
+	"fmt"
+	"strings"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/geo"
+)
+
+// Supported platform conditions
+const (
+	PlatformIOS     = "ios"
+	PlatformAndroid = "android"
+	PlatformWindows = "windows"
+	PlatformMacOS   = "macos"
+	PlatformLinux   = "linux"
+)
+
+// DefaultTarget labels clicks that fell through to the original URL
+const DefaultTarget = "default"
+
+// MaxTargets bounds the rule set of a single link
+const MaxTargets = 10
+
+var knownPlatforms = map[string]bool{
+	PlatformIOS:     true,
+	PlatformAndroid: true,
+	PlatformWindows: true,
+	PlatformMacOS:   true,
+	PlatformLinux:   true,
+}
+
+// Result is the outcome of evaluating a link's rules
+type Result struct {
+	Destination string
+	Target      string // Label of the matched rule, DefaultTarget for the fallback
+	Variant     *int   // A/B variant chosen for the fallback, nil without a split
+}
+
+// DetectPlatform derives the visitor platform from a User-Agent header
+// Returns an empty string when the platform is unknown
+func DetectPlatform(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+
+	// Order matters: Android UAs contain "linux", iOS UAs contain "mac os x"
+	switch {
+	case strings.Contains(ua, "android"):
+		return PlatformAndroid
+	case strings.Contains(ua, "iphone"), strings.Contains(ua, "ipad"), strings.Contains(ua, "ipod"):
+		return PlatformIOS
+	case strings.Contains(ua, "windows"):
+		return PlatformWindows
+	case strings.Contains(ua, "macintosh"), strings.Contains(ua, "mac os x"):
+		return PlatformMacOS
+	case strings.Contains(ua, "linux"), strings.Contains(ua, "x11"):
+		return PlatformLinux
+	default:
+		return ""
+	}
+}
+
+// Evaluate picks the destination for a visitor
+// Precedence is explicit country, then region (e.g. EU), then platform, then the fallback.
+// Within each level the first matching target wins. Without geo data country rules are skipped,
+// so the outcome only depends on the platform and stays deterministic.
+func Evaluate(targets []domain.Target, fallback string, visitor domain.Visitor) Result {
+	if len(targets) == 0 {
+		return Result{Destination: fallback, Target: DefaultTarget}
+	}
+
+	if country := visitor.Country; country != "" {
+		for _, target := range targets {
+			if target.Country == country {
+				return Result{Destination: target.URL, Target: countryLabel(target.Country)}
+			}
+		}
+		for _, target := range targets {
+			if geo.IsRegion(target.Country) && geo.InRegion(country, target.Country) {
+				return Result{Destination: target.URL, Target: countryLabel(target.Country)}
+			}
+		}
+	}
+
+	if platform := DetectPlatform(visitor.UserAgent); platform != "" {
+		for _, target := range targets {
+			if target.Platform == platform {
+				return Result{Destination: target.URL, Target: target.Platform}
+			}
+		}
+	}
+
+	return Result{Destination: fallback, Target: DefaultTarget}
+}
+
+// NeedsCountry reports whether any target has a country condition
+// Callers use it to skip the GeoIP lookup for links that don't need it
+func NeedsCountry(targets []domain.Target) bool {
+	for _, target := range targets {
+		if target.Country != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateTargets checks rule conditions; destination URLs are validated by the caller
+// Each target has exactly one condition, and rules that could never be selected are rejected
+func ValidateTargets(targets []domain.Target) error {
+	if len(targets) > MaxTargets {
+		return fmt.Errorf("at most %d targets are allowed", MaxTargets)
+	}
+
+	seen := make(map[string]bool, len(targets))
+	for i, target := range targets {
+		var condition string
+		switch {
+		case target.Platform != "" && target.Country != "":
+			return fmt.Errorf("target %d: set either platform or country, not both", i)
+		case target.Platform != "":
+			if !knownPlatforms[target.Platform] {
+				return fmt.Errorf("target %d: unknown platform %q", i, target.Platform)
+			}
+			condition = "platform:" + target.Platform
+		case target.Country != "":
+			if !geo.ValidCountryCode(target.Country) {
+				return fmt.Errorf("target %d: invalid country code %q", i, target.Country)
+			}
+			condition = countryLabel(target.Country)
+		default:
+			return fmt.Errorf("target %d: a platform or country condition is required", i)
+		}
+
+		// A repeated condition is shadowed by the earlier one and would never be reached
+		if seen[condition] {
+			return fmt.Errorf("target %d: unreachable, %s is already targeted", i, condition)
+		}
+		seen[condition] = true
+	}
+
+	return nil
+}
+
+// countryLabel names a country rule in click statistics
+func countryLabel(country string) string {
+	return "country:" + country
+}
@@ -0,0 +1,111 @@
+package redirect
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+
+	"url-shortener/internal/domain"
+)
+
+// MaxVariants bounds the number of destinations in one split
+const MaxVariants = 10
+
+// RandomRoll returns a uniformly distributed roll in [0, 100)
+// math/rand is seeded automatically and is plenty for traffic splitting
+func RandomRoll() int {
+	return rand.Intn(100)
+}
+
+// StickyRoll derives a stable roll from the visitor's IP and User-Agent
+// The same visitor keeps landing on the same variant as long as the weights don't change
+func StickyRoll(visitor domain.Visitor) int {
+	h := fnv.New64a()
+	h.Write([]byte(visitor.IP))
+	h.Write([]byte{0})
+	h.Write([]byte(visitor.UserAgent))
+	return int(h.Sum64() % 100)
+}
+
+// PickVariant maps a roll in [0, 100) onto the cumulative weights
+func PickVariant(variants []domain.Variant, roll int) int {
+	cumulative := 0
+	for i, variant := range variants {
+		cumulative += variant.Weight
+		if roll < cumulative {
+			return i
+		}
+	}
+	return len(variants) - 1
+}
+
+// NormalizeWeights validates a split and scales its weights to sum to exactly 100
+// Rounding uses the largest remainder so the result never drifts from 100
+func NormalizeWeights(variants []domain.Variant) ([]domain.Variant, error) {
+	if len(variants) < 2 {
+		return nil, fmt.Errorf("a split needs at least 2 variants")
+	}
+	if len(variants) > MaxVariants {
+		return nil, fmt.Errorf("at most %d variants are allowed", MaxVariants)
+	}
+
+	total := 0
+	for i, variant := range variants {
+		if variant.Weight < 1 {
+			return nil, fmt.Errorf("variant %d: weight must be positive", i)
+		}
+		total += variant.Weight
+	}
+
+	normalized := make([]domain.Variant, len(variants))
+	copy(normalized, variants)
+	if total == 100 {
+		return normalized, nil
+	}
+
+	assigned := 0
+	remainders := make([]int, len(variants))
+	for i, variant := range variants {
+		normalized[i].Weight = variant.Weight * 100 / total
+		remainders[i] = variant.Weight * 100 % total
+		assigned += normalized[i].Weight
+	}
+
+	// Hand out the missing points to the largest remainders, earliest first on ties
+	for ; assigned < 100; assigned++ {
+		best := 0
+		for i := range remainders {
+			if remainders[i] > remainders[best] {
+				best = i
+			}
+		}
+		normalized[best].Weight++
+		remainders[best] = -1
+	}
+
+	for i, variant := range normalized {
+		if variant.Weight == 0 {
+			return nil, fmt.Errorf("variant %d: weight is too small to receive traffic", i)
+		}
+	}
+
+	return normalized, nil
+}
+
+// ApplySplit sends traffic that fell through to the default destination to an A/B variant
+// Targeted visitors keep their target; links without variants are returned unchanged
+func ApplySplit(result Result, variants []domain.Variant, sticky bool, visitor domain.Visitor) Result {
+	if result.Target != DefaultTarget || len(variants) == 0 {
+		return result
+	}
+
+	roll := RandomRoll()
+	if sticky {
+		roll = StickyRoll(visitor)
+	}
+
+	i := PickVariant(variants, roll)
+	result.Destination = variants[i].URL
+	result.Variant = &i
+	return result
+}
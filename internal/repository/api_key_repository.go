@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"url-shortener/internal/domain"
+)
+
+// ErrRestoreTargetNotEmpty is returned when a backup would be restored over existing rows
+// It is only seen by `server restore-critical`, never by API clients, so it isn't a domain error.
+var ErrRestoreTargetNotEmpty = errors.New("table to restore into is not empty")
+
+// APIKeyRepository persists issued API keys
+type APIKeyRepository interface {
+	// Create stores a new key
+	Create(ctx context.Context, key *domain.APIKey) error
+	
+	// Revoke marks an active key as revoked and returns it
+	// Returns ErrAPIKeyNotFound if no active key has the id
+	Revoke(ctx context.Context, id uint) (*domain.APIKey, error)
+	
+	// List returns all keys, revoked ones included, oldest first
+	List(ctx context.Context) ([]domain.APIKey, error)
+	
+	// ListActive returns the keys that have not been revoked
+	ListActive(ctx context.Context) ([]domain.APIKey, error)
+	
+	// Restore inserts keys from a backup with their ids into an empty table
+	// Returns ErrRestoreTargetNotEmpty if any key is already stored
+	Restore(ctx context.Context, keys []domain.APIKey) error
+}
@@ -0,0 +1,15 @@
+package repository
+
+import (
+	"context"
+	"url-shortener/internal/domain"
+)
+
+// AuditRepository persists the audit trail of administrative actions
+type AuditRepository interface {
+	// Record appends an entry to the audit trail
+	Record(ctx context.Context, entry *domain.AuditEntry) error
+	
+	// ListByShortCode returns the audit history of a short code, newest first
+	ListByShortCode(ctx context.Context, shortCode string) ([]domain.AuditEntry, error)
+}
@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"context"
+	"time"
+	
+	"url-shortener/internal/domain"
+)
+
+// ClickRepository stores individual click events for detailed statistics
+type ClickRepository interface {
+	// Record stores a single click event
+	Record(ctx context.Context, event *domain.ClickEvent) error
+	
+	// CountByTarget returns the number of clicks per matched target for a short code
+	CountByTarget(ctx context.Context, shortCode string) (map[string]int64, error)
+	
+	// CountByVariant returns the number of clicks per A/B variant index for a short code
+	CountByVariant(ctx context.Context, shortCode string) (map[int]int64, error)
+	
+	// RollupDay aggregates the click events of one UTC day into url_stats_daily
+	// Rows are replaced rather than incremented, so re-running a day is safe
+	RollupDay(ctx context.Context, day time.Time) (int64, error)
+	
+	// GetDailySeries returns rolled-up days in [from, to) for a short code, oldest first
+	GetDailySeries(ctx context.Context, shortCode string, from, to time.Time) ([]domain.DailyClickStats, error)
+	
+	// AggregateDay computes the stats of one UTC day directly from click events
+	// Used for the current day, which the rollup doesn't cover yet
+	AggregateDay(ctx context.Context, shortCode string, day time.Time) (*domain.DailyClickStats, error)
+	
+	// CountByBucket counts click events in [from, to) per UTC time bucket, oldest first
+	// Buckets without clicks are omitted
+	CountByBucket(ctx context.Context, shortCode string, granularity domain.Granularity, from, to time.Time) ([]domain.ClickBucket, error)
+	
+	// SumDailyByBucket adds up rolled-up days in [from, to) per day or week, oldest first
+	// Buckets without clicks are omitted
+	SumDailyByBucket(ctx context.Context, shortCode string, granularity domain.Granularity, from, to time.Time) ([]domain.ClickBucket, error)
+	
+	// CountByWeekdayHour counts click events in [from, to) per weekday and hour of day in loc
+	// Cells without clicks are omitted
+	CountByWeekdayHour(ctx context.Context, shortCode string, from, to time.Time, loc *time.Location) ([]domain.HeatmapCell, error)
+}
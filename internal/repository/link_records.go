@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"url-shortener/internal/domain"
+)
+
+// LinkRecords clears and ranks what the service recorded about links besides the links themselves
+// A URLRepository keeping its links in another store than Postgres, such as MongoDB, uses it so that
+// HardDelete, ResetStats and TopByClicks still cover the click events, daily stats, audit entries and outbox
+type LinkRecords interface {
+	// DeleteLinkRecords removes the click events, daily stats, audit entries and outbox events of links
+	// Only TenantID and ShortCode of each link are read
+	DeleteLinkRecords(ctx context.Context, links []domain.URL) error
+
+	// DeleteClickRecords removes the click events and daily stats of one link, as a stats reset does
+	DeleteClickRecords(ctx context.Context, tenantID, shortCode string) error
+
+	// CountClicksSince counts the click events recorded since the given time per link, most clicked first
+	// Ties are ordered by short code; offset skips that many links, so callers can page past links they drop
+	CountClicksSince(ctx context.Context, since time.Time, limit, offset int) ([]LinkClicks, error)
+}
+
+// LinkClicks is the number of click events of one link
+type LinkClicks struct {
+	TenantID  string
+	ShortCode string
+	Clicks    int64
+}
@@ -0,0 +1,255 @@
+package mongo
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"strings"
+	"time"
+
+	"url-shortener/internal/domain"
+	"url-shortener/pkg/validator"
+)
+
+// urlDocument is a link as stored in the urls collection, one field per column of the Postgres urls table
+// The nested rule lists keep the JSON field names they have in the Postgres JSONB columns. The fields
+// after DestinationHost that Postgres computes in expression indexes are stored, so they can be indexed.
+type urlDocument struct {
+	ID                    uint                  `bson:"_id"`
+	TenantID              string                `bson:"tenant_id"`
+	CodeScope             string                `bson:"code_scope"`
+	ShortCode             string                `bson:"short_code"`
+	ShortCodeLower        string                `bson:"short_code_lower"` // lower(short_code), for FindByShortCodeFold
+	OriginalURL           string                `bson:"original_url"`
+	URLHash               string                `bson:"url_hash"`
+	DestinationHost       string                `bson:"destination_host"`
+	ReversedHost          string                `bson:"reversed_host"`    // reverse(destination_host), so subdomains are matched by prefix
+	DestinationPath       string                `bson:"destination_path"` // Path of original_url, for URLFilter.PathPrefix
+	SubmittedURL          *string               `bson:"submitted_url"`
+	Title                 string                `bson:"title"`
+	Description           string                `bson:"description"`
+	CreatedAt             time.Time             `bson:"created_at"`
+	UpdatedAt             time.Time             `bson:"updated_at"`
+	ExpiresAt             *time.Time            `bson:"expires_at"`
+	ClickCount            int64                 `bson:"click_count"`
+	BotClicks             int64                 `bson:"bot_clicks"`
+	PrefetchHits          int64                 `bson:"prefetch_hits"`
+	FilteredClicks        int64                 `bson:"filtered_clicks"`
+	Conversions           int64                 `bson:"conversions"`
+	LastAccessAt          *time.Time            `bson:"last_access_at"`
+	LastReferrer          string                `bson:"last_referrer"`
+	ReferrerCounts        map[string]int64      `bson:"referrer_counts"` // Keys escaped by referrerKey, never null so $inc can add to it
+	ReferrerHosts         int                   `bson:"referrer_hosts"`  // Hosts counted by name in ReferrerCounts, OtherReferrers not included
+	StatsResetAt          *time.Time            `bson:"stats_reset_at"`
+	CreatorIP             string                `bson:"creator_ip"`
+	CreatorUserAgent      string                `bson:"creator_user_agent"`
+	CreatorOrigin         string                `bson:"creator_origin"`
+	IsActive              bool                  `bson:"is_active"`
+	CustomAlias           bool                  `bson:"custom_alias"`
+	RequiresInterstitial  bool                  `bson:"requires_interstitial"`
+	ConfirmBeforeRedirect bool                  `bson:"confirm_before_redirect"`
+	UTM                   domain.UTMParams      `bson:"utm"`
+	Targets               domain.Targets        `bson:"targets"`
+	Variants              domain.Variants       `bson:"variants"`
+	StickyVariants        bool                  `bson:"sticky_variants"`
+	ForwardQuery          bool                  `bson:"forward_query"`
+	AttachClickID         bool                  `bson:"attach_click_id"`
+	PublicStats           bool                  `bson:"public_stats"`
+	ReferrerPolicy        domain.ReferrerPolicy `bson:"referrer_policy"`
+	DeepLink              string                `bson:"deep_link"`
+	IOSFallbackURL        string                `bson:"ios_fallback_url"`
+	AndroidFallbackURL    string                `bson:"android_fallback_url"`
+	Bundle                domain.BundleItems    `bson:"bundle"`
+	PageTitle             *string               `bson:"page_title"`
+	FaviconURL            *string               `bson:"favicon_url"`
+	MetadataFetchedAt     *time.Time            `bson:"metadata_fetched_at"`
+	ManagementTokenHash   *string               `bson:"management_token_hash"`
+	ExpiryNotifiedAt      *time.Time            `bson:"expiry_notified_at"`
+	LastCheckedAt         *time.Time            `bson:"last_checked_at"`
+	LastStatusCode        *int                  `bson:"last_status_code"`
+	IsBroken              bool                  `bson:"is_broken"`
+}
+
+// newDocument converts url for storage, filling in the derived fields as the Postgres repository does
+func newDocument(url *domain.URL) *urlDocument {
+	host := validator.DestinationHost(url.OriginalURL)
+	counts := make(map[string]int64, len(url.ReferrerCounts))
+	hosts := 0
+	for referrer, clicks := range url.ReferrerCounts {
+		counts[referrerKey(referrer)] = clicks
+		if referrer != domain.OtherReferrers {
+			hosts++
+		}
+	}
+
+	return &urlDocument{
+		ID:                    url.ID,
+		TenantID:              url.TenantID,
+		CodeScope:             url.CodeScope,
+		ShortCode:             url.ShortCode,
+		ShortCodeLower:        strings.ToLower(url.ShortCode),
+		OriginalURL:           url.OriginalURL,
+		URLHash:               hashURL(url.OriginalURL),
+		DestinationHost:       host,
+		ReversedHost:          reverse(host),
+		DestinationPath:       destinationPath(url.OriginalURL),
+		SubmittedURL:          url.SubmittedURL,
+		Title:                 url.Title,
+		Description:           url.Description,
+		CreatedAt:             url.CreatedAt,
+		UpdatedAt:             url.UpdatedAt,
+		ExpiresAt:             url.ExpiresAt,
+		ClickCount:            url.ClickCount,
+		BotClicks:             url.BotClicks,
+		PrefetchHits:          url.PrefetchHits,
+		FilteredClicks:        url.FilteredClicks,
+		Conversions:           url.Conversions,
+		LastAccessAt:          url.LastAccessAt,
+		LastReferrer:          url.LastReferrer,
+		ReferrerCounts:        counts,
+		ReferrerHosts:         hosts,
+		StatsResetAt:          url.StatsResetAt,
+		CreatorIP:             url.CreatorIP,
+		CreatorUserAgent:      url.CreatorUserAgent,
+		CreatorOrigin:         url.CreatorOrigin,
+		IsActive:              url.IsActive,
+		CustomAlias:           url.CustomAlias,
+		RequiresInterstitial:  url.RequiresInterstitial,
+		ConfirmBeforeRedirect: url.ConfirmBeforeRedirect,
+		UTM:                   url.UTM,
+		Targets:               url.Targets,
+		Variants:              url.Variants,
+		StickyVariants:        url.StickyVariants,
+		ForwardQuery:          url.ForwardQuery,
+		AttachClickID:         url.AttachClickID,
+		PublicStats:           url.PublicStats,
+		ReferrerPolicy:        url.ReferrerPolicy,
+		DeepLink:              url.DeepLink,
+		IOSFallbackURL:        url.IOSFallbackURL,
+		AndroidFallbackURL:    url.AndroidFallbackURL,
+		Bundle:                url.Bundle,
+		PageTitle:             url.PageTitle,
+		FaviconURL:            url.FaviconURL,
+		MetadataFetchedAt:     url.MetadataFetchedAt,
+		ManagementTokenHash:   url.ManagementTokenHash,
+		ExpiryNotifiedAt:      url.ExpiryNotifiedAt,
+		LastCheckedAt:         url.LastCheckedAt,
+		LastStatusCode:        url.LastStatusCode,
+		IsBroken:              url.IsBroken,
+	}
+}
+
+// toURL converts a stored document back into a link
+func (d *urlDocument) toURL() *domain.URL {
+	var counts domain.ReferrerCounts
+	if len(d.ReferrerCounts) > 0 {
+		counts = make(domain.ReferrerCounts, len(d.ReferrerCounts))
+		for key, clicks := range d.ReferrerCounts {
+			counts[referrerOfKey(key)] = clicks
+		}
+	}
+
+	return &domain.URL{
+		ID:                    d.ID,
+		TenantID:              d.TenantID,
+		CodeScope:             d.CodeScope,
+		ShortCode:             d.ShortCode,
+		OriginalURL:           d.OriginalURL,
+		URLHash:               d.URLHash,
+		DestinationHost:       d.DestinationHost,
+		SubmittedURL:          d.SubmittedURL,
+		Title:                 d.Title,
+		Description:           d.Description,
+		CreatedAt:             d.CreatedAt,
+		UpdatedAt:             d.UpdatedAt,
+		ExpiresAt:             d.ExpiresAt,
+		ClickCount:            d.ClickCount,
+		BotClicks:             d.BotClicks,
+		PrefetchHits:          d.PrefetchHits,
+		FilteredClicks:        d.FilteredClicks,
+		Conversions:           d.Conversions,
+		LastAccessAt:          d.LastAccessAt,
+		LastReferrer:          d.LastReferrer,
+		ReferrerCounts:        counts,
+		StatsResetAt:          d.StatsResetAt,
+		CreatorIP:             d.CreatorIP,
+		CreatorUserAgent:      d.CreatorUserAgent,
+		CreatorOrigin:         d.CreatorOrigin,
+		IsActive:              d.IsActive,
+		CustomAlias:           d.CustomAlias,
+		RequiresInterstitial:  d.RequiresInterstitial,
+		ConfirmBeforeRedirect: d.ConfirmBeforeRedirect,
+		UTM:                   d.UTM,
+		Targets:               d.Targets,
+		Variants:              d.Variants,
+		StickyVariants:        d.StickyVariants,
+		ForwardQuery:          d.ForwardQuery,
+		AttachClickID:         d.AttachClickID,
+		PublicStats:           d.PublicStats,
+		ReferrerPolicy:        d.ReferrerPolicy,
+		DeepLink:              d.DeepLink,
+		IOSFallbackURL:        d.IOSFallbackURL,
+		AndroidFallbackURL:    d.AndroidFallbackURL,
+		Bundle:                d.Bundle,
+		PageTitle:             d.PageTitle,
+		FaviconURL:            d.FaviconURL,
+		MetadataFetchedAt:     d.MetadataFetchedAt,
+		ManagementTokenHash:   d.ManagementTokenHash,
+		ExpiryNotifiedAt:      d.ExpiryNotifiedAt,
+		LastCheckedAt:         d.LastCheckedAt,
+		LastStatusCode:        d.LastStatusCode,
+		IsBroken:              d.IsBroken,
+	}
+}
+
+// toURLs converts a batch of documents
+func toURLs(docs []urlDocument) []domain.URL {
+	urls := make([]domain.URL, len(docs))
+	for i := range docs {
+		urls[i] = *docs[i].toURL()
+	}
+	return urls
+}
+
+// hashURL returns the hex SHA-256 stored in url_hash, the digest the Postgres repository stores
+func hashURL(originalURL string) string {
+	sum := sha256.Sum256([]byte(originalURL))
+	return hex.EncodeToString(sum[:])
+}
+
+// destinationPathPattern is the expression the Postgres repository extracts the destination path with
+var destinationPathPattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9+.-]*://[^/?#]*([^?#]*)`)
+
+// destinationPath returns the path of originalURL, empty when it has none
+func destinationPath(originalURL string) string {
+	if match := destinationPathPattern.FindStringSubmatch(originalURL); match != nil {
+		return match[1]
+	}
+	return ""
+}
+
+// reverse returns s with its bytes in reverse order, as Postgres reverse() does for ASCII hosts
+func reverse(s string) string {
+	b := []byte(s)
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+	return string(b)
+}
+
+// referrerKeyEscaper keeps hosts usable as field names: a dot would be read as a path in $inc, a
+// leading $ as an operator, and % is the escape character itself
+var referrerKeyEscaper = strings.NewReplacer("%", "%25", ".", "%2E", "$", "%24")
+
+// referrerKeyUnescaper undoes referrerKeyEscaper
+var referrerKeyUnescaper = strings.NewReplacer("%25", "%", "%2E", ".", "%24", "$")
+
+// referrerKey returns the field of referrer_counts a referring host is counted in
+func referrerKey(referrer string) string {
+	return referrerKeyEscaper.Replace(referrer)
+}
+
+// referrerOfKey returns the host counted in a field of referrer_counts
+func referrerOfKey(key string) string {
+	return referrerKeyUnescaper.Replace(key)
+}
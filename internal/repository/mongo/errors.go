@@ -0,0 +1,70 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+
+	"go.mongodb.org/mongo-driver/v2/mongo"
+
+	"url-shortener/internal/domain"
+)
+
+// errEmptyFilter guards bulk writes against a filter that would match every link
+var errEmptyFilter = errors.New("refusing a bulk write with an empty filter")
+
+// dbError classifies a failed operation for the service layer, as the Postgres repositories do
+// Outages become ErrDependencyUnavailable so the API can answer 503, a caller that went away keeps
+// context.Canceled so it isn't logged as a failure, and everything else is an internal error
+func dbError(err error) error {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return err
+	case isUnavailable(err):
+		return domain.NewDependencyError(err)
+	}
+	return domain.NewInternalError(err)
+}
+
+// unavailableCodes are the server error codes of a deployment that is shutting down or electing a primary
+var unavailableCodes = []int{
+	91,    // ShutdownInProgress
+	189,   // PrimarySteppedDown
+	10107, // NotWritablePrimary
+	11600, // InterruptedAtShutdown
+	11602, // InterruptedDueToReplStateChange
+	13435, // NotPrimaryNoSecondaryOk
+	13436, // NotPrimaryOrSecondary
+}
+
+// isUnavailable reports whether err means MongoDB could not be reached or didn't answer in time
+// Server selection failing within its timeout is reported as a timeout by the driver
+func isUnavailable(err error) bool {
+	if mongo.IsTimeout(err) || mongo.IsNetworkError(err) || errors.Is(err, mongo.ErrClientDisconnected) ||
+		errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	// Refused or dropped connections and DNS failures
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	var serverErr mongo.ServerError
+	if errors.As(err, &serverErr) {
+		for _, code := range unavailableCodes {
+			if serverErr.HasErrorCode(code) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isDuplicateKey reports whether err means a document with the same unique key already exists
+// That is error code 11000, which the driver also recognizes in bulk write and mongos errors
+func isDuplicateKey(err error) bool {
+	return mongo.IsDuplicateKeyError(err)
+}
@@ -0,0 +1,27 @@
+package mongo
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"url-shortener/internal/domain"
+)
+
+// tenantFilter limits filter to the documents of the tenant in ctx, see domain.ContextWithTenant
+// Without a tenant in ctx every tenant's documents match, as background jobs need
+func tenantFilter(ctx context.Context, filter bson.M) bson.M {
+	if tenant, ok := domain.TenantFromContext(ctx); ok {
+		filter["tenant_id"] = tenant
+	}
+	return filter
+}
+
+// tenantOf returns the tenant a document inserted with ctx belongs to
+// Documents written without a tenant in ctx keep the one they were given
+func tenantOf(ctx context.Context, given string) string {
+	if tenant, ok := domain.TenantFromContext(ctx); ok {
+		return tenant
+	}
+	return given
+}
@@ -0,0 +1,832 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/repository"
+)
+
+// Collections of the database the links are kept in
+const (
+	urlsCollection     = "urls"
+	countersCollection = "counters" // One sequence per collection, the next ID is handed out with $inc
+)
+
+// urlRepository implements the URLRepository interface for MongoDB
+// IDs come from a counter rather than ObjectIDs, so they keep the insertion order ForEach and the
+// link check page by, as the Postgres BIGSERIAL does
+type urlRepository struct {
+	urls     *mongo.Collection
+	counters *mongo.Collection
+	records  repository.LinkRecords
+}
+
+// NewURLRepository creates a MongoDB URL repository on db; EnsureIndexes must have run on db
+// records clear and rank what is kept about the links in Postgres; nil when there are no such records
+func NewURLRepository(db *mongo.Database, records repository.LinkRecords) repository.URLRepository {
+	// The rule lists are encoded with their JSON field names, the layout of the Postgres JSONB columns
+	collectionOptions := options.Collection().SetBSONOptions(&options.BSONOptions{UseJSONStructTags: true})
+	return &urlRepository{
+		urls:     db.Collection(urlsCollection, collectionOptions),
+		counters: db.Collection(countersCollection),
+		records:  records,
+	}
+}
+
+// EnsureIndexes creates the indexes of the urls collection, the counterpart of the Postgres migrations
+// Creating an index that already exists is a no-op, so it runs on every server start.
+func EnsureIndexes(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection(urlsCollection).Indexes().CreateMany(ctx, []mongo.IndexModel{
+		// Like idx_urls_code_scope_short_code, this makes a short code unique within its namespace
+		{Keys: bson.D{{Key: "code_scope", Value: 1}, {Key: "short_code", Value: 1}}, Options: options.Index().SetUnique(true).SetName("code_scope_short_code")},
+		{Keys: bson.D{{Key: "short_code", Value: 1}, {Key: "tenant_id", Value: 1}}, Options: options.Index().SetName("short_code_tenant_id")},
+		{Keys: bson.D{{Key: "short_code_lower", Value: 1}}, Options: options.Index().SetName("short_code_lower")},
+		{Keys: bson.D{{Key: "url_hash", Value: 1}}, Options: options.Index().SetName("url_hash")},
+		{Keys: bson.D{{Key: "creator_ip", Value: 1}, {Key: "created_at", Value: -1}}, Options: options.Index().SetName("creator_ip_created_at")},
+		{Keys: bson.D{{Key: "destination_host", Value: 1}}, Options: options.Index().SetName("destination_host")},
+		{Keys: bson.D{{Key: "reversed_host", Value: 1}}, Options: options.Index().SetName("reversed_host")},
+		// Serves DeleteExpired and FindExpiringBetween. It is not a TTL index: expired links are only
+		// deactivated, their stats stay readable until HardDeleteInactive erases them.
+		{Keys: bson.D{{Key: "is_active", Value: 1}, {Key: "expires_at", Value: 1}}, Options: options.Index().SetName("is_active_expires_at")},
+		{Keys: bson.D{{Key: "is_active", Value: 1}, {Key: "updated_at", Value: 1}}, Options: options.Index().SetName("is_active_updated_at")},
+		{Keys: bson.D{{Key: "is_active", Value: 1}, {Key: "click_count", Value: -1}}, Options: options.Index().SetName("is_active_click_count")},
+		{Keys: bson.D{{Key: "created_at", Value: 1}}, Options: options.Index().SetName("created_at")},
+	})
+	if err != nil {
+		return dbError(err)
+	}
+	return nil
+}
+
+// nextIDs reserves n consecutive IDs and returns the first
+func (r *urlRepository) nextIDs(ctx context.Context, n int) (uint, error) {
+	var counter struct {
+		Seq int64 `bson:"seq"`
+	}
+	err := r.counters.FindOneAndUpdate(ctx,
+		bson.M{"_id": urlsCollection},
+		bson.M{"$inc": bson.M{"seq": n}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&counter)
+	if err != nil {
+		return 0, err
+	}
+	return uint(counter.Seq) - uint(n) + 1, nil
+}
+
+// prepareInsert fills in what the database would on insert: the tenant, timestamps and derived fields
+// An explicit created_at is kept, as gorm keeps it for Postgres
+func prepareInsert(ctx context.Context, url *domain.URL, id uint, now time.Time) *urlDocument {
+	url.ID = id
+	url.TenantID = tenantOf(ctx, url.TenantID)
+	if url.CreatedAt.IsZero() {
+		url.CreatedAt = now
+	}
+	if url.UpdatedAt.IsZero() {
+		url.UpdatedAt = now
+	}
+	doc := newDocument(url)
+	url.URLHash, url.DestinationHost = doc.URLHash, doc.DestinationHost
+	return doc
+}
+
+// Create inserts a new URL document
+func (r *urlRepository) Create(ctx context.Context, url *domain.URL) error {
+	id, err := r.nextIDs(ctx, 1)
+	if err != nil {
+		return dbError(err)
+	}
+	if _, err := r.urls.InsertOne(ctx, prepareInsert(ctx, url, id, time.Now())); err != nil {
+		url.ID = 0
+		// Duplicate key (error 11000) on the code_scope_short_code index
+		if isDuplicateKey(err) {
+			return domain.ErrShortCodeTaken
+		}
+		return dbError(err)
+	}
+	return nil
+}
+
+// CreateBundle inserts the member links and then the bundle, removing what it inserted when one fails
+// Multi-document transactions need a replica set, so the insert is undone instead of rolled back; a
+// process dying halfway can leave members behind, but never a bundle pointing at missing codes.
+func (r *urlRepository) CreateBundle(ctx context.Context, bundle *domain.URL, members []*domain.URL) error {
+	links := append(append([]*domain.URL{}, members...), bundle)
+	first, err := r.nextIDs(ctx, len(links))
+	if err != nil {
+		return dbError(err)
+	}
+
+	now := time.Now()
+	docs := make([]interface{}, len(links))
+	ids := make([]uint, len(links))
+	for i, link := range links {
+		ids[i] = first + uint(i)
+		docs[i] = prepareInsert(ctx, link, ids[i], now)
+	}
+
+	_, err = r.urls.InsertMany(ctx, docs)
+	if err == nil {
+		return nil
+	}
+
+	// Ordered inserts stop at the first failure; whatever made it in is removed again
+	if _, cleanupErr := r.urls.DeleteMany(context.WithoutCancel(ctx), bson.M{"_id": bson.M{"$in": ids}}); cleanupErr != nil {
+		err = errors.Join(err, cleanupErr)
+	}
+	for _, link := range links {
+		link.ID = 0
+	}
+	if isDuplicateKey(err) {
+		return domain.ErrShortCodeTaken
+	}
+	return dbError(err)
+}
+
+// findOne decodes the first document matching filter, ErrURLNotFound when there is none
+func (r *urlRepository) findOne(ctx context.Context, filter bson.M) (*domain.URL, error) {
+	var doc urlDocument
+	if err := r.urls.FindOne(ctx, tenantFilter(ctx, filter)).Decode(&doc); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, domain.ErrURLNotFound
+		}
+		return nil, dbError(err)
+	}
+	return doc.toURL(), nil
+}
+
+// find decodes every document matching filter
+func (r *urlRepository) find(ctx context.Context, filter bson.M, opts *options.FindOptionsBuilder) ([]domain.URL, error) {
+	cursor, err := r.urls.Find(ctx, tenantFilter(ctx, filter), opts)
+	if err != nil {
+		return nil, dbError(err)
+	}
+	var docs []urlDocument
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, dbError(err)
+	}
+	return toURLs(docs), nil
+}
+
+// FindByShortCode retrieves an active URL by its short code
+func (r *urlRepository) FindByShortCode(ctx context.Context, shortCode string) (*domain.URL, error) {
+	return r.findOne(ctx, bson.M{"short_code": shortCode, "is_active": true})
+}
+
+// FindByShortCodeFold retrieves the only URL whose code matches shortCode ignoring case, if it is active
+// Two candidates, e.g. abc and ABC, are ambiguous and treated as no match, deactivated ones included
+func (r *urlRepository) FindByShortCodeFold(ctx context.Context, shortCode string) (*domain.URL, error) {
+	urls, err := r.find(ctx, bson.M{"short_code_lower": strings.ToLower(shortCode)}, options.Find().SetLimit(2))
+	if err != nil {
+		return nil, err
+	}
+	if len(urls) != 1 || !urls[0].IsActive {
+		return nil, domain.ErrURLNotFound
+	}
+	return &urls[0], nil
+}
+
+// FindAnyByShortCode retrieves a URL by its short code including deactivated links
+func (r *urlRepository) FindAnyByShortCode(ctx context.Context, shortCode string) (*domain.URL, error) {
+	return r.findOne(ctx, bson.M{"short_code": shortCode})
+}
+
+// FindByCreatorIP pages through the links created from ip, newest first, including deactivated ones
+func (r *urlRepository) FindByCreatorIP(ctx context.Context, ip string, limit, offset int) ([]domain.URL, error) {
+	return r.find(ctx, bson.M{"creator_ip": ip}, options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetLimit(int64(limit)).
+		SetSkip(int64(offset)))
+}
+
+// FindByOriginalURL finds an active link to originalURL through the indexed url_hash
+func (r *urlRepository) FindByOriginalURL(ctx context.Context, originalURL string) (*domain.URL, error) {
+	return r.findOne(ctx, bson.M{"url_hash": hashURL(originalURL), "original_url": originalURL, "is_active": true})
+}
+
+// Update replaces the stored document with url, never inserting one that doesn't exist
+func (r *urlRepository) Update(ctx context.Context, url *domain.URL) error {
+	if url.ID == 0 {
+		return domain.ErrURLNotFound
+	}
+
+	url.UpdatedAt = time.Now()
+	doc := newDocument(url)
+	url.URLHash, url.DestinationHost = doc.URLHash, doc.DestinationHost
+	result, err := r.urls.ReplaceOne(ctx, tenantFilter(ctx, bson.M{"_id": url.ID}), doc)
+	if err != nil {
+		return dbError(err)
+	}
+
+	if result.MatchedCount == 0 {
+		return domain.ErrURLNotFound
+	}
+
+	return nil
+}
+
+// setFields returns a $set of fields that also stamps updated_at, as the Postgres trigger does
+func setFields(fields bson.M) bson.M {
+	fields["updated_at"] = time.Now()
+	return bson.M{"$set": fields}
+}
+
+// updateOne applies update to the document matching filter, ErrURLNotFound when there is none
+func (r *urlRepository) updateOne(ctx context.Context, filter, update bson.M) error {
+	result, err := r.urls.UpdateOne(ctx, tenantFilter(ctx, filter), update)
+	if err != nil {
+		return dbError(err)
+	}
+
+	if result.MatchedCount == 0 {
+		return domain.ErrURLNotFound
+	}
+
+	return nil
+}
+
+// SetActive updates is_active regardless of the current state and returns the updated document
+func (r *urlRepository) SetActive(ctx context.Context, shortCode string, active bool) (*domain.URL, error) {
+	var doc urlDocument
+	err := r.urls.FindOneAndUpdate(ctx,
+		tenantFilter(ctx, bson.M{"short_code": shortCode}),
+		setFields(bson.M{"is_active": active}),
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, domain.ErrURLNotFound
+	}
+	if err != nil {
+		return nil, dbError(err)
+	}
+	return doc.toURL(), nil
+}
+
+// Delete soft-deletes a URL by setting is_active to false
+// This preserves data for analytics while preventing access
+func (r *urlRepository) Delete(ctx context.Context, shortCode string) error {
+	return r.updateOne(ctx, bson.M{"short_code": shortCode}, setFields(bson.M{"is_active": false}))
+}
+
+// HardDelete erases the document, after the records kept about it elsewhere
+// The records go first, so a failure leaves the link in place for the erasure to be run again
+func (r *urlRepository) HardDelete(ctx context.Context, shortCode string) error {
+	link, err := r.FindAnyByShortCode(ctx, shortCode)
+	if err != nil {
+		return err
+	}
+	if err := r.deleteLinkRecords(ctx, []domain.URL{*link}); err != nil {
+		return err
+	}
+
+	result, err := r.urls.DeleteOne(ctx, bson.M{"_id": link.ID})
+	if err != nil {
+		return dbError(err)
+	}
+	if result.DeletedCount == 0 {
+		return domain.ErrURLNotFound
+	}
+	return nil
+}
+
+// deleteLinkRecords clears the records of links kept in Postgres, if there are any
+func (r *urlRepository) deleteLinkRecords(ctx context.Context, links []domain.URL) error {
+	if r.records == nil {
+		return nil
+	}
+	return r.records.DeleteLinkRecords(ctx, links)
+}
+
+// ResetStats zeroes the counters in one update and returns the document as it was before
+// A single-document update is atomic, so clicks counted meanwhile land either before or after the reset
+func (r *urlRepository) ResetStats(ctx context.Context, shortCode string) (*domain.URL, error) {
+	var prior urlDocument
+	err := r.urls.FindOneAndUpdate(ctx,
+		tenantFilter(ctx, bson.M{"short_code": shortCode}),
+		setFields(bson.M{
+			"click_count":     0,
+			"bot_clicks":      0,
+			"prefetch_hits":   0,
+			"filtered_clicks": 0,
+			"last_access_at":  nil,
+			"last_referrer":   "",
+			"referrer_counts": bson.M{},
+			"referrer_hosts":  0,
+			"stats_reset_at":  time.Now(),
+		}),
+		options.FindOneAndUpdate().SetReturnDocument(options.Before),
+	).Decode(&prior)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, domain.ErrURLNotFound
+	}
+	if err != nil {
+		return nil, dbError(err)
+	}
+
+	if r.records != nil {
+		if err := r.records.DeleteClickRecords(ctx, prior.TenantID, prior.ShortCode); err != nil {
+			return nil, err
+		}
+	}
+	return prior.toURL(), nil
+}
+
+// HardDeleteInactive erases the longest-inactive links first, the way HardDelete does
+// Every deactivation stamps updated_at, so it is never earlier than the deactivation. Documents are
+// deleted one by one and only those this call deleted are returned, so instances running the cleanup
+// together never report a link twice.
+func (r *urlRepository) HardDeleteInactive(ctx context.Context, before time.Time, limit int) ([]domain.URL, error) {
+	candidates, err := r.find(ctx, bson.M{"is_active": false, "updated_at": bson.M{"$lt": before}}, options.Find().
+		SetProjection(bson.M{"_id": 1, "tenant_id": 1, "short_code": 1}).
+		SetSort(bson.D{{Key: "updated_at", Value: 1}, {Key: "_id", Value: 1}}).
+		SetLimit(int64(limit)))
+	if err != nil || len(candidates) == 0 {
+		return nil, err
+	}
+
+	if err := r.deleteLinkRecords(ctx, candidates); err != nil {
+		return nil, err
+	}
+
+	var links []domain.URL
+	for _, candidate := range candidates {
+		// A link reactivated since it was picked is kept
+		result, err := r.urls.DeleteOne(ctx, bson.M{"_id": candidate.ID, "is_active": false})
+		if err != nil {
+			return links, dbError(err)
+		}
+		if result.DeletedCount > 0 {
+			links = append(links, domain.URL{ID: candidate.ID, TenantID: candidate.TenantID, ShortCode: candidate.ShortCode})
+		}
+	}
+	return links, nil
+}
+
+// maxReferrerLength is the size of the Postgres last_referrer column; longer hosts are not recorded
+const maxReferrerLength = 255
+
+// referrerRounds bounds how often IncrementClickCount retries when concurrent clicks move a link
+// between the cases it tries one after the other
+const referrerRounds = 3
+
+// IncrementClickCount atomically increments the click counter with $inc
+// The referrer counts are updated in the same update. Which key a click is counted under depends on the
+// document, so the cases are tried in turn, each filtered on the state it applies to. A host already
+// counted keeps counting; a new host gets its own key only while fewer than MaxReferrerCounts are named.
+func (r *urlRepository) IncrementClickCount(ctx context.Context, shortCode, referrer string) error {
+	now := time.Now()
+	active := bson.M{"short_code": shortCode, "is_active": true}
+	set := bson.M{"last_access_at": now, "updated_at": now}
+	if referrer == "" || len(referrer) > maxReferrerLength {
+		return r.updateOne(ctx, active, bson.M{"$inc": bson.M{"click_count": 1}, "$set": set})
+	}
+
+	set["last_referrer"] = referrer
+	own := "referrer_counts." + referrerKey(referrer)
+	other := "referrer_counts." + referrerKey(domain.OtherReferrers)
+	cases := []struct {
+		filter bson.M
+		inc    bson.M
+	}{
+		{bson.M{own: bson.M{"$exists": true}}, bson.M{"click_count": 1, own: 1}},
+		{bson.M{own: bson.M{"$exists": false}, "referrer_hosts": bson.M{"$lt": domain.MaxReferrerCounts}},
+			bson.M{"click_count": 1, own: 1, "referrer_hosts": 1}},
+		{bson.M{own: bson.M{"$exists": false}, "referrer_hosts": bson.M{"$gte": domain.MaxReferrerCounts}},
+			bson.M{"click_count": 1, other: 1}},
+	}
+
+	for round := 0; round < referrerRounds; round++ {
+		for _, c := range cases {
+			filter := bson.M{}
+			for key, value := range active {
+				filter[key] = value
+			}
+			for key, value := range c.filter {
+				filter[key] = value
+			}
+			err := r.updateOne(ctx, filter, bson.M{"$inc": c.inc, "$set": set})
+			if !errors.Is(err, domain.ErrURLNotFound) {
+				return err
+			}
+		}
+
+		// No case matched: either there is no active link, or a concurrent click changed it in between
+		count, err := r.urls.CountDocuments(ctx, tenantFilter(ctx, bson.M{"short_code": shortCode, "is_active": true}))
+		if err != nil {
+			return dbError(err)
+		}
+		if count == 0 {
+			return domain.ErrURLNotFound
+		}
+	}
+	return domain.NewInternalError(errors.New("referrer counts kept changing while counting a click"))
+}
+
+// incrementActive atomically increments a counter of an active link, leaving last_access_at alone
+func (r *urlRepository) incrementActive(ctx context.Context, shortCode, field string) error {
+	return r.updateOne(ctx, bson.M{"short_code": shortCode, "is_active": true},
+		bson.M{"$inc": bson.M{field: 1}, "$set": bson.M{"updated_at": time.Now()}})
+}
+
+// IncrementBotClickCount atomically increments the bot click counter
+func (r *urlRepository) IncrementBotClickCount(ctx context.Context, shortCode string) error {
+	return r.incrementActive(ctx, shortCode, "bot_clicks")
+}
+
+// IncrementPrefetchCount atomically increments prefetch_hits of an active link
+func (r *urlRepository) IncrementPrefetchCount(ctx context.Context, shortCode string) error {
+	return r.incrementActive(ctx, shortCode, "prefetch_hits")
+}
+
+// IncrementFilteredClickCount atomically increments filtered_clicks of an active link
+func (r *urlRepository) IncrementFilteredClickCount(ctx context.Context, shortCode string) error {
+	return r.incrementActive(ctx, shortCode, "filtered_clicks")
+}
+
+// IncrementConversionCount atomically increments conversions
+// The visitor clicked while the link worked, so a link deactivated since still gets the conversion
+func (r *urlRepository) IncrementConversionCount(ctx context.Context, shortCode string) error {
+	return r.updateOne(ctx, bson.M{"short_code": shortCode},
+		bson.M{"$inc": bson.M{"conversions": 1}, "$set": bson.M{"updated_at": time.Now()}})
+}
+
+// UpdateMetadata sets only the enrichment fields
+// Replacing the document would overwrite click_count with a stale value when redirects happened during the fetch
+func (r *urlRepository) UpdateMetadata(ctx context.Context, shortCode string, pageTitle, faviconURL *string) error {
+	return r.updateOne(ctx, bson.M{"short_code": shortCode}, setFields(bson.M{
+		"page_title":          pageTitle,
+		"favicon_url":         faviconURL,
+		"metadata_fetched_at": time.Now(),
+	}))
+}
+
+// GetStats retrieves the statistics of a link, computed by domain.URL.Stats as for Postgres
+func (r *urlRepository) GetStats(ctx context.Context, shortCode string) (*domain.URLStats, error) {
+	url, err := r.FindAnyByShortCode(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+	return url.Stats(), nil
+}
+
+// DeleteExpired deactivates every active link past its expiry, through the is_active_expires_at index
+// Links deactivated by earlier runs are skipped, so each run only counts what it removed
+func (r *urlRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	now := time.Now()
+	result, err := r.urls.UpdateMany(ctx,
+		tenantFilter(ctx, bson.M{"is_active": true, "expires_at": bson.M{"$ne": nil, "$lt": now}}),
+		bson.M{"$set": bson.M{"is_active": false, "updated_at": now}})
+	if err != nil {
+		return 0, dbError(err)
+	}
+	return result.ModifiedCount, nil
+}
+
+// FindExpiringBetween lists active links whose expiry falls in [from, to), soonest first
+func (r *urlRepository) FindExpiringBetween(ctx context.Context, from, to time.Time, limit int) ([]domain.URL, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "expires_at", Value: 1}, {Key: "_id", Value: 1}})
+	if limit > 0 {
+		opts.SetLimit(int64(limit))
+	}
+	return r.find(ctx, bson.M{"is_active": true, "expires_at": bson.M{"$gte": from, "$lt": to}}, opts)
+}
+
+// MarkExpiryNotified stamps expiry_notified_at without touching updated_at
+func (r *urlRepository) MarkExpiryNotified(ctx context.Context, shortCode string, at time.Time) error {
+	return r.updateOne(ctx, bson.M{"short_code": shortCode}, bson.M{"$set": bson.M{"expiry_notified_at": at}})
+}
+
+// FindDueForCheck pages through the active links the link check should look at, in ID order
+func (r *urlRepository) FindDueForCheck(ctx context.Context, checkedBefore time.Time, afterID uint, limit int) ([]domain.URL, error) {
+	return r.find(ctx, bson.M{
+		"is_active": true,
+		"_id":       bson.M{"$gt": afterID},
+		"$or":       bson.A{bson.M{"last_checked_at": nil}, bson.M{"last_checked_at": bson.M{"$lt": checkedBefore}}},
+	}, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetLimit(int64(limit)))
+}
+
+// RecordLinkCheck sets only the link check fields
+// Like MarkExpiryNotified it leaves updated_at alone, a check isn't a change of the link
+func (r *urlRepository) RecordLinkCheck(ctx context.Context, shortCode string, check domain.LinkCheck) error {
+	return r.updateOne(ctx, bson.M{"short_code": shortCode}, bson.M{"$set": bson.M{
+		"last_checked_at":  check.CheckedAt,
+		"last_status_code": check.StatusCode,
+		"is_broken":        check.Broken,
+	}})
+}
+
+// ExistsByShortCode checks if an active link has the code without loading the document
+func (r *urlRepository) ExistsByShortCode(ctx context.Context, shortCode string) (bool, error) {
+	count, err := r.urls.CountDocuments(ctx, tenantFilter(ctx, bson.M{"short_code": shortCode, "is_active": true}),
+		options.Count().SetLimit(1))
+	if err != nil {
+		return false, dbError(err)
+	}
+	return count > 0, nil
+}
+
+// existsManyChunkSize caps the codes of one $in query
+const existsManyChunkSize = 1000
+
+// ExistsManyByShortCode checks a batch of candidate codes with one $in query per 1000 distinct codes
+// Unlike ExistsByShortCode it ignores is_active, because the unique index covers inactive links as well
+func (r *urlRepository) ExistsManyByShortCode(ctx context.Context, shortCodes []string) (map[string]bool, error) {
+	taken := make(map[string]bool, len(shortCodes))
+
+	// Duplicates would only bloat the query
+	seen := make(map[string]bool, len(shortCodes))
+	unique := make([]string, 0, len(shortCodes))
+	for _, code := range shortCodes {
+		if !seen[code] {
+			seen[code] = true
+			unique = append(unique, code)
+		}
+	}
+
+	for start := 0; start < len(unique); start += existsManyChunkSize {
+		end := start + existsManyChunkSize
+		if end > len(unique) {
+			end = len(unique)
+		}
+
+		found, err := r.find(ctx, bson.M{"short_code": bson.M{"$in": unique[start:end]}},
+			options.Find().SetProjection(bson.M{"short_code": 1}))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, link := range found {
+			taken[link.ShortCode] = true
+		}
+	}
+
+	return taken, nil
+}
+
+// ForEach iterates over URLs matching the filter using keyset pagination on _id
+// Only one batch is held in memory at a time, so it is safe to use on the full collection
+func (r *urlRepository) ForEach(ctx context.Context, filter domain.URLFilter, batchSize int, fn func(*domain.URL) error) error {
+	if batchSize <= 0 {
+		batchSize = 500
+	}
+
+	var lastID uint
+	for {
+		query := urlFilter(filter)
+		query["_id"] = bson.M{"$gt": lastID}
+		batch, err := r.find(ctx, query, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetLimit(int64(batchSize)))
+		if err != nil {
+			return err
+		}
+
+		for i := range batch {
+			if err := fn(&batch[i]); err != nil {
+				return err
+			}
+		}
+
+		// A short batch means we reached the end of the result set
+		if len(batch) < batchSize {
+			return nil
+		}
+		lastID = batch[len(batch)-1].ID
+	}
+}
+
+// urlFilter translates filter into a query, with the same matching rules as the Postgres repository
+func urlFilter(filter domain.URLFilter) bson.M {
+	query := bson.M{}
+	var and bson.A
+	if len(filter.ShortCodes) > 0 {
+		query["short_code"] = bson.M{"$in": filter.ShortCodes}
+	}
+	if filter.CreatorIP != "" {
+		query["creator_ip"] = filter.CreatorIP
+	}
+	if host := strings.ToLower(filter.DestinationHost); host != "" {
+		if filter.IncludeSubdomains {
+			// The reversed host starts with the reversed ".host" for subdomains; an anchored regex uses the index
+			and = append(and, bson.M{"$or": bson.A{
+				bson.M{"destination_host": host},
+				bson.M{"reversed_host": bson.M{"$regex": "^" + regexp.QuoteMeta(reverse("."+host))}},
+			}})
+		} else {
+			query["destination_host"] = host
+		}
+	}
+	if filter.PathPrefix != "" {
+		query["destination_path"] = bson.M{"$regex": "^" + regexp.QuoteMeta(filter.PathPrefix)}
+	}
+	if filter.CreatedFrom != nil || filter.CreatedTo != nil {
+		created := bson.M{}
+		if filter.CreatedFrom != nil {
+			created["$gte"] = *filter.CreatedFrom
+		}
+		if filter.CreatedTo != nil {
+			created["$lt"] = *filter.CreatedTo
+		}
+		query["created_at"] = created
+	}
+	if filter.Search != "" {
+		pattern := bson.M{"$regex": regexp.QuoteMeta(filter.Search), "$options": "i"}
+		and = append(and, bson.M{"$or": bson.A{
+			bson.M{"short_code": pattern},
+			bson.M{"original_url": pattern},
+			bson.M{"title": pattern},
+			bson.M{"description": pattern},
+		}})
+	}
+	if filter.Broken != nil {
+		query["is_broken"] = *filter.Broken
+	}
+	if len(and) > 0 {
+		query["$and"] = and
+	}
+	return query
+}
+
+// DeactivateMatching sets is_active to false on every active link matching the filter
+// Each link is deactivated by its own update filtered on is_active, so only the links this call
+// deactivated are returned, even while another call works through the same filter
+func (r *urlRepository) DeactivateMatching(ctx context.Context, filter domain.URLFilter) ([]domain.URL, error) {
+	if filter.IsEmpty() {
+		return nil, errEmptyFilter
+	}
+
+	query := urlFilter(filter)
+	query["is_active"] = true
+	candidates, err := r.find(ctx, query, options.Find().SetProjection(bson.M{"_id": 1}).SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+
+	var urls []domain.URL
+	for _, candidate := range candidates {
+		var doc urlDocument
+		err := r.urls.FindOneAndUpdate(ctx,
+			bson.M{"_id": candidate.ID, "is_active": true},
+			setFields(bson.M{"is_active": false}),
+			options.FindOneAndUpdate().SetReturnDocument(options.After),
+		).Decode(&doc)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			continue
+		}
+		if err != nil {
+			return urls, dbError(err)
+		}
+		urls = append(urls, *doc.toURL())
+	}
+
+	return urls, nil
+}
+
+// CountActiveMatching counts the active links matching the filter
+func (r *urlRepository) CountActiveMatching(ctx context.Context, filter domain.URLFilter) (int64, error) {
+	if filter.IsEmpty() {
+		return 0, errEmptyFilter
+	}
+
+	query := urlFilter(filter)
+	query["is_active"] = true
+	count, err := r.urls.CountDocuments(ctx, tenantFilter(ctx, query))
+	if err != nil {
+		return 0, dbError(err)
+	}
+
+	return count, nil
+}
+
+// FindActiveMatching lists a page of the active links matching the filter, newest first
+func (r *urlRepository) FindActiveMatching(ctx context.Context, filter domain.URLFilter, limit, offset int) ([]domain.URL, error) {
+	query := urlFilter(filter)
+	query["is_active"] = true
+	return r.find(ctx, query, options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetLimit(int64(limit)).
+		SetSkip(int64(offset)))
+}
+
+// CountURLs returns the number of active links
+func (r *urlRepository) CountURLs(ctx context.Context) (int64, error) {
+	count, err := r.urls.CountDocuments(ctx, tenantFilter(ctx, bson.M{"is_active": true}))
+	if err != nil {
+		return 0, dbError(err)
+	}
+	return count, nil
+}
+
+// SumClicks adds up click_count over every link, including deactivated ones
+// Clicks on links that were later removed still happened, so they stay in the total
+func (r *urlRepository) SumClicks(ctx context.Context) (int64, error) {
+	cursor, err := r.urls.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: tenantFilter(ctx, bson.M{})}},
+		{{Key: "$group", Value: bson.M{"_id": nil, "total": bson.M{"$sum": "$click_count"}}}},
+	})
+	if err != nil {
+		return 0, dbError(err)
+	}
+
+	var rows []struct {
+		Total int64 `bson:"total"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return 0, dbError(err)
+	}
+	if len(rows) == 0 {
+		return 0, nil
+	}
+	return rows[0].Total, nil
+}
+
+// TopByClicks ranks active links by their click events since the given time
+// The click events are in Postgres, so the ranking comes from the LinkRecords and the links that are
+// no longer active are dropped here, paging on until limit links are found or the ranking runs out
+func (r *urlRepository) TopByClicks(ctx context.Context, since time.Time, limit int) ([]domain.TopLink, error) {
+	if r.records == nil || limit <= 0 {
+		return nil, nil
+	}
+
+	var links []domain.TopLink
+	for offset := 0; len(links) < limit; offset += limit {
+		ranked, err := r.records.CountClicksSince(ctx, since, limit, offset)
+		if err != nil {
+			return nil, err
+		}
+
+		// One query per page finds which of the ranked links are still active
+		var or bson.A
+		for _, link := range ranked {
+			or = append(or, bson.M{"tenant_id": link.TenantID, "short_code": link.ShortCode})
+		}
+		var active []domain.URL
+		if len(or) > 0 {
+			active, err = r.find(ctx, bson.M{"is_active": true, "$or": or},
+				options.Find().SetProjection(bson.M{"tenant_id": 1, "short_code": 1, "original_url": 1}))
+			if err != nil {
+				return nil, err
+			}
+		}
+		destinations := make(map[[2]string]string, len(active))
+		for _, link := range active {
+			destinations[[2]string{link.TenantID, link.ShortCode}] = link.OriginalURL
+		}
+
+		for _, link := range ranked {
+			if destination, ok := destinations[[2]string{link.TenantID, link.ShortCode}]; ok && len(links) < limit {
+				links = append(links, domain.TopLink{ShortCode: link.ShortCode, OriginalURL: destination, Clicks: link.Clicks})
+			}
+		}
+		if len(ranked) < limit {
+			break
+		}
+	}
+
+	return links, nil
+}
+
+// TopByClickCount returns the most clicked links that still redirect, by the lifetime click_count
+// Unlike TopByClicks it reads no click events, so it stays cheap however many clicks were recorded
+func (r *urlRepository) TopByClickCount(ctx context.Context, limit int) ([]domain.URL, error) {
+	return r.find(ctx, bson.M{
+		"is_active": true,
+		"$or":       bson.A{bson.M{"expires_at": nil}, bson.M{"expires_at": bson.M{"$gt": time.Now()}}},
+	}, options.Find().SetSort(bson.D{{Key: "click_count", Value: -1}, {Key: "short_code", Value: 1}}).SetLimit(int64(limit)))
+}
+
+// CreatedBetween groups links created in [from, to) by UTC day
+func (r *urlRepository) CreatedBetween(ctx context.Context, from, to time.Time) ([]domain.DailyCount, error) {
+	cursor, err := r.urls.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: tenantFilter(ctx, bson.M{"created_at": bson.M{"$gte": from, "$lt": to}})}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   bson.M{"$dateToString": bson.M{"format": "%Y-%m-%d", "date": "$created_at", "timezone": "UTC"}},
+			"count": bson.M{"$sum": 1},
+		}}},
+		{{Key: "$sort", Value: bson.M{"_id": 1}}},
+	})
+	if err != nil {
+		return nil, dbError(err)
+	}
+
+	var rows []struct {
+		Date  string `bson:"_id"`
+		Count int64  `bson:"count"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, dbError(err)
+	}
+
+	counts := make([]domain.DailyCount, len(rows))
+	for i, row := range rows {
+		counts[i] = domain.DailyCount{Date: row.Date, Count: row.Count}
+	}
+	return counts, nil
+}
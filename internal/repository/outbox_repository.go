@@ -0,0 +1,32 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"url-shortener/internal/domain"
+)
+
+// Transactor runs several repository calls in one database transaction
+type Transactor interface {
+	// WithinTx runs fn in a transaction, committed when fn returns nil
+	// Repository calls made with the context passed to fn take part in it; nested calls join the outer one
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// OutboxRepository stores lifecycle events until the relay has published them
+type OutboxRepository interface {
+	// Append adds events; call it inside WithinTx together with the change they describe
+	Append(ctx context.Context, events ...*domain.OutboxEvent) error
+	
+	// PublishPending hands up to limit unpublished events, oldest first, to publish
+	// They are marked published only when publish returns nil, giving at-least-once delivery
+	// Concurrent relays skip each other's locked rows; returns the number of events published
+	PublishPending(ctx context.Context, limit int, publish func([]domain.OutboxEvent) error) (int, error)
+	
+	// Lag returns the number of unpublished events and the creation time of the oldest one
+	Lag(ctx context.Context) (int64, *time.Time, error)
+	
+	// DeletePublished removes events published before the cutoff
+	DeletePublished(ctx context.Context, before time.Time) (int64, error)
+}
@@ -0,0 +1,106 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/repository"
+)
+
+// apiKeyRepository implements the APIKeyRepository interface for PostgreSQL
+type apiKeyRepository struct {
+	db *gorm.DB
+}
+
+// NewAPIKeyRepository creates a new PostgreSQL API key repository
+func NewAPIKeyRepository(db *gorm.DB) repository.APIKeyRepository {
+	return &apiKeyRepository{db: db}
+}
+
+// Create inserts a new API key
+func (r *apiKeyRepository) Create(ctx context.Context, key *domain.APIKey) error {
+	if err := r.db.WithContext(ctx).Create(key).Error; err != nil {
+		return dbError(err)
+	}
+	return nil
+}
+
+// Revoke sets revoked_at on an active key
+// The revoked_at IS NULL guard keeps the original revocation time when called twice
+func (r *apiKeyRepository) Revoke(ctx context.Context, id uint) (*domain.APIKey, error) {
+	result := r.db.WithContext(ctx).
+		Model(&domain.APIKey{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now())
+
+	if result.Error != nil {
+		return nil, dbError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, domain.ErrAPIKeyNotFound
+	}
+
+	var key domain.APIKey
+	if err := r.db.WithContext(ctx).First(&key, id).Error; err != nil {
+		return nil, dbError(err)
+	}
+	return &key, nil
+}
+
+// List returns every key ordered by id
+func (r *apiKeyRepository) List(ctx context.Context) ([]domain.APIKey, error) {
+	var keys []domain.APIKey
+
+	if err := r.db.WithContext(ctx).Order("id").Find(&keys).Error; err != nil {
+		return nil, dbError(err)
+	}
+	return keys, nil
+}
+
+// ListActive returns the keys without a revocation time
+func (r *apiKeyRepository) ListActive(ctx context.Context) ([]domain.APIKey, error) {
+	var keys []domain.APIKey
+
+	result := r.db.WithContext(ctx).
+		Where("revoked_at IS NULL").
+		Order("id").
+		Find(&keys)
+
+	if result.Error != nil {
+		return nil, dbError(result.Error)
+	}
+	return keys, nil
+}
+
+// Restore inserts the keys in one transaction and moves the id sequence past them
+// Keys issued after the restore then get fresh ids instead of colliding with restored ones.
+func (r *apiKeyRepository) Restore(ctx context.Context, keys []domain.APIKey) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing int64
+		if err := tx.Model(&domain.APIKey{}).Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing > 0 {
+			return repository.ErrRestoreTargetNotEmpty
+		}
+		if len(keys) == 0 {
+			return nil
+		}
+
+		if err := tx.CreateInBatches(keys, 500).Error; err != nil {
+			return err
+		}
+		return tx.Exec("SELECT setval(pg_get_serial_sequence('api_keys', 'id'), (SELECT MAX(id) FROM api_keys))").Error
+	})
+	if errors.Is(err, repository.ErrRestoreTargetNotEmpty) {
+		return err
+	}
+	if err != nil {
+		return dbError(err)
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package postgres
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/repository"
+)
+
+// auditRepository implements the AuditRepository interface for PostgreSQL
+type auditRepository struct {
+	db *gorm.DB
+}
+
+// NewAuditRepository creates a new PostgreSQL audit repository
+func NewAuditRepository(db *gorm.DB) repository.AuditRepository {
+	return &auditRepository{db: db}
+}
+
+// Record inserts a new audit entry
+func (r *auditRepository) Record(ctx context.Context, entry *domain.AuditEntry) error {
+	entry.TenantID = tenantOf(ctx, entry.TenantID)
+	if err := r.db.WithContext(ctx).Create(entry).Error; err != nil {
+		return dbError(err)
+	}
+	return nil
+}
+
+// ListByShortCode returns all audit entries for a short code, newest first
+func (r *auditRepository) ListByShortCode(ctx context.Context, shortCode string) ([]domain.AuditEntry, error) {
+	var entries []domain.AuditEntry
+
+	result := r.db.WithContext(ctx).Scopes(tenantScope(ctx)).
+		Where("short_code = ?", shortCode).
+		Order("created_at DESC, id DESC").
+		Find(&entries)
+
+	if result.Error != nil {
+		return nil, dbError(result.Error)
+	}
+
+	return entries, nil
+}
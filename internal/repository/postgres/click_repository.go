@@ -0,0 +1,282 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/repository"
+)
+
+// clickRepository implements the ClickRepository interface for PostgreSQL
+type clickRepository struct {
+	db *gorm.DB
+}
+
+// NewClickRepository creates a new PostgreSQL click event repository
+func NewClickRepository(db *gorm.DB) repository.ClickRepository {
+	return &clickRepository{db: db}
+}
+
+// Record inserts a click event
+func (r *clickRepository) Record(ctx context.Context, event *domain.ClickEvent) error {
+	event.TenantID = tenantOf(ctx, event.TenantID)
+	if err := r.db.WithContext(ctx).Create(event).Error; err != nil {
+		return dbError(err)
+	}
+	return nil
+}
+
+// CountByTarget aggregates click events per target in the database
+func (r *clickRepository) CountByTarget(ctx context.Context, shortCode string) (map[string]int64, error) {
+	var rows []struct {
+		Target string
+		Clicks int64
+	}
+
+	result := r.db.WithContext(ctx).Scopes(tenantScope(ctx)).
+		Model(&domain.ClickEvent{}).
+		Select("target, COUNT(*) AS clicks").
+		Where("short_code = ?", shortCode).
+		Group("target").
+		Scan(&rows)
+
+	if result.Error != nil {
+		return nil, dbError(result.Error)
+	}
+
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Target] = row.Clicks
+	}
+
+	return counts, nil
+}
+
+// CountByVariant aggregates click events per A/B variant, ignoring clicks without a variant
+func (r *clickRepository) CountByVariant(ctx context.Context, shortCode string) (map[int]int64, error) {
+	var rows []struct {
+		Variant int
+		Clicks  int64
+	}
+
+	result := r.db.WithContext(ctx).Scopes(tenantScope(ctx)).
+		Model(&domain.ClickEvent{}).
+		Select("variant, COUNT(*) AS clicks").
+		Where("short_code = ? AND variant IS NOT NULL", shortCode).
+		Group("variant").
+		Scan(&rows)
+
+	if result.Error != nil {
+		return nil, dbError(result.Error)
+	}
+
+	counts := make(map[int]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Variant] = row.Clicks
+	}
+
+	return counts, nil
+}
+
+// topReferrerSQL picks the most frequent referrer host of a tenant's code within the day bounds
+// Ties go to the alphabetically first host so repeated rollups give the same answer
+const topReferrerSQL = `(SELECT r.referrer FROM click_events r
+	WHERE r.tenant_id = c.tenant_id AND r.short_code = c.short_code AND r.clicked_at >= @from AND r.clicked_at < @to AND r.referrer <> ''
+	GROUP BY r.referrer ORDER BY COUNT(*) DESC, r.referrer ASC LIMIT 1)`
+
+// RollupDay upserts one row per tenant and short code clicked on the given UTC day
+func (r *clickRepository) RollupDay(ctx context.Context, day time.Time) (int64, error) {
+	from, to := dayBounds(day)
+
+	result := r.db.WithContext(ctx).Exec(`
+		INSERT INTO url_stats_daily (tenant_id, short_code, date, clicks, unique_ips, top_referrer, updated_at)
+		SELECT c.tenant_id, c.short_code, CAST(@date AS DATE), COUNT(*), COUNT(DISTINCT NULLIF(c.ip, '')),
+			COALESCE(`+topReferrerSQL+`, ''), @now
+		FROM click_events c
+		WHERE c.clicked_at >= @from AND c.clicked_at < @to
+		GROUP BY c.tenant_id, c.short_code
+		ON CONFLICT (tenant_id, short_code, date) DO UPDATE SET
+			clicks = EXCLUDED.clicks,
+			unique_ips = EXCLUDED.unique_ips,
+			top_referrer = EXCLUDED.top_referrer,
+			updated_at = EXCLUDED.updated_at`,
+		map[string]interface{}{
+			"date": from.Format("2006-01-02"),
+			"from": from,
+			"to":   to,
+			"now":  time.Now(),
+		})
+
+	if result.Error != nil {
+		return 0, dbError(result.Error)
+	}
+
+	return result.RowsAffected, nil
+}
+
+// GetDailySeries reads rolled-up days for one short code
+func (r *clickRepository) GetDailySeries(ctx context.Context, shortCode string, from, to time.Time) ([]domain.DailyClickStats, error) {
+	var series []domain.DailyClickStats
+
+	result := r.db.WithContext(ctx).Scopes(tenantScope(ctx)).
+		Model(&domain.DailyClickStats{}).
+		Select("short_code, to_char(date, 'YYYY-MM-DD') AS date, clicks, unique_ips, top_referrer").
+		Where("short_code = ? AND date >= ? AND date < ?", shortCode, from.UTC().Format("2006-01-02"), to.UTC().Format("2006-01-02")).
+		Order("date ASC").
+		Scan(&series)
+
+	if result.Error != nil {
+		return nil, dbError(result.Error)
+	}
+
+	return series, nil
+}
+
+// AggregateDay runs the rollup query for a single code without storing the result
+func (r *clickRepository) AggregateDay(ctx context.Context, shortCode string, day time.Time) (*domain.DailyClickStats, error) {
+	from, to := dayBounds(day)
+	stats := domain.DailyClickStats{ShortCode: shortCode, Date: from.Format("2006-01-02")}
+	vars := map[string]interface{}{"code": shortCode, "from": from, "to": to}
+
+	where := "c.short_code = @code AND c.clicked_at >= @from AND c.clicked_at < @to"
+	if tenant, ok := domain.TenantFromContext(ctx); ok {
+		where += " AND c.tenant_id = @tenant"
+		vars["tenant"] = tenant
+	}
+
+	result := r.db.WithContext(ctx).Raw(`
+		SELECT COUNT(*) AS clicks, COUNT(DISTINCT NULLIF(c.ip, '')) AS unique_ips,
+			COALESCE(`+topReferrerSQL+`, '') AS top_referrer
+		FROM click_events c
+		WHERE `+where+`
+		GROUP BY c.tenant_id, c.short_code`, vars).
+		Scan(&stats)
+
+	if result.Error != nil {
+		return nil, dbError(result.Error)
+	}
+
+	return &stats, nil
+}
+
+// CountByBucket groups click events by the start of their time bucket
+func (r *clickRepository) CountByBucket(ctx context.Context, shortCode string, granularity domain.Granularity, from, to time.Time) ([]domain.ClickBucket, error) {
+	db := r.db.WithContext(ctx).Scopes(tenantScope(ctx))
+	bucket := bucketExpr(granularity, "clicked_at")
+
+	var rows []bucketRow
+	result := db.Model(&domain.ClickEvent{}).
+		Select(bucket+" AS bucket, COUNT(*) AS clicks").
+		Where("short_code = ? AND clicked_at >= ? AND clicked_at < ?", shortCode, from.UTC(), to.UTC()).
+		Group("bucket").
+		Order("bucket ASC").
+		Scan(&rows)
+
+	if result.Error != nil {
+		return nil, dbError(result.Error)
+	}
+
+	return bucketsFromRows(rows)
+}
+
+// SumDailyByBucket groups rolled-up days by the start of their day or week
+func (r *clickRepository) SumDailyByBucket(ctx context.Context, shortCode string, granularity domain.Granularity, from, to time.Time) ([]domain.ClickBucket, error) {
+	db := r.db.WithContext(ctx).Scopes(tenantScope(ctx))
+	bucket := bucketExpr(granularity, "date")
+
+	var rows []bucketRow
+	result := db.Model(&domain.DailyClickStats{}).
+		Select(bucket+" AS bucket, SUM(clicks) AS clicks").
+		Where("short_code = ? AND date >= ? AND date < ?", shortCode, from.UTC().Format("2006-01-02"), to.UTC().Format("2006-01-02")).
+		Group("bucket").
+		Order("bucket ASC").
+		Scan(&rows)
+
+	if result.Error != nil {
+		return nil, dbError(result.Error)
+	}
+
+	return bucketsFromRows(rows)
+}
+
+// CountByWeekdayHour groups click events by their weekday and hour of day in loc
+func (r *clickRepository) CountByWeekdayHour(ctx context.Context, shortCode string, from, to time.Time, loc *time.Location) ([]domain.HeatmapCell, error) {
+	db := r.db.WithContext(ctx).Scopes(tenantScope(ctx))
+	weekday, hour, vars := weekdayHourExprs(loc, "clicked_at")
+
+	var rows []struct {
+		Weekday   int
+		HourOfDay int
+		Clicks    int64
+	}
+	result := db.Model(&domain.ClickEvent{}).
+		Select(weekday+" AS weekday, "+hour+" AS hour_of_day, COUNT(*) AS clicks", vars...).
+		Where("short_code = ? AND clicked_at >= ? AND clicked_at < ?", shortCode, from.UTC(), to.UTC()).
+		Group("weekday, hour_of_day").
+		Scan(&rows)
+
+	if result.Error != nil {
+		return nil, dbError(result.Error)
+	}
+
+	cells := make([]domain.HeatmapCell, 0, len(rows))
+	for _, row := range rows {
+		cells = append(cells, domain.HeatmapCell{Weekday: row.Weekday, Hour: row.HourOfDay, Clicks: row.Clicks})
+	}
+	return cells, nil
+}
+
+// weekdayHourExprs returns SQL extracting the weekday, 0 for Sunday, and the hour of column in loc
+// The conversion uses AT TIME ZONE, so it follows daylight saving time within the range.
+func weekdayHourExprs(loc *time.Location, column string) (string, string, []interface{}) {
+	local := column + " AT TIME ZONE CAST(? AS TEXT)"
+	return "CAST(EXTRACT(DOW FROM " + local + ") AS INTEGER)",
+		"CAST(EXTRACT(HOUR FROM " + local + ") AS INTEGER)",
+		[]interface{}{loc.String(), loc.String()}
+}
+
+// bucketLayout is how bucketExpr renders a bucket start
+const bucketLayout = "2006-01-02 15:04:05"
+
+// bucketRow is one group of a bucketed count; the bucket is rendered as text and parsed by bucketsFromRows
+type bucketRow struct {
+	Bucket string
+	Clicks int64
+}
+
+// bucketExpr returns SQL rendering the UTC start of column's bucket in bucketLayout
+// It truncates with date_trunc, so weeks start on Monday
+func bucketExpr(granularity domain.Granularity, column string) string {
+	unit := "day"
+	switch granularity {
+	case domain.GranularityHour:
+		unit = "hour"
+	case domain.GranularityWeek:
+		unit = "week"
+	}
+	return "to_char(date_trunc('" + unit + "', " + column + "), 'YYYY-MM-DD HH24:MI:SS')"
+}
+
+// bucketsFromRows parses the bucket starts rendered by bucketExpr
+func bucketsFromRows(rows []bucketRow) ([]domain.ClickBucket, error) {
+	buckets := make([]domain.ClickBucket, 0, len(rows))
+	for _, row := range rows {
+		start, err := time.ParseInLocation(bucketLayout, row.Bucket, time.UTC)
+		if err != nil {
+			return nil, domain.NewInternalError(fmt.Errorf("unexpected bucket %q: %w", row.Bucket, err))
+		}
+		buckets = append(buckets, domain.ClickBucket{BucketStart: start, Clicks: row.Clicks})
+	}
+	return buckets, nil
+}
+
+// dayBounds returns the UTC midnight that starts day and the one that ends it
+func dayBounds(day time.Time) (time.Time, time.Time) {
+	day = day.UTC()
+	from := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+	return from, from.AddDate(0, 0, 1)
+}
@@ -0,0 +1,140 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"url-shortener/internal/domain"
+)
+
+// errEmptyFilter guards bulk writes against a filter that would match every link
+var errEmptyFilter = errors.New("refusing a bulk write with an empty filter")
+
+// dbError classifies a failed query for the service layer
+// Outages become ErrDependencyUnavailable so the API can answer 503, a caller that went away keeps
+// context.Canceled so it isn't logged as a failure, and everything else is an internal error
+func dbError(err error) error {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return err
+	case isUnavailable(err):
+		return domain.NewDependencyError(err)
+	}
+	return domain.NewInternalError(err)
+}
+
+// sqlStateError is implemented by driver errors that carry a SQLSTATE, such as pgconn.PgError
+type sqlStateError interface {
+	SQLState() string
+}
+
+// isUnavailable reports whether err means Postgres could not be reached or didn't answer in time
+func isUnavailable(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, sql.ErrConnDone) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	// Refused or dropped connections, DNS failures and socket timeouts
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	var stateErr sqlStateError
+	if errors.As(err, &stateErr) {
+		switch state := stateErr.SQLState(); {
+		case strings.HasPrefix(state, "08"): // connection_exception class
+			return true
+		case state == "53300", // too_many_connections
+			state == "57P01", // admin_shutdown
+			state == "57P02", // crash_shutdown
+			state == "57P03": // cannot_connect_now, e.g. still starting up
+			return true
+		}
+	}
+	return false
+}
+
+// uniqueViolation is the SQLSTATE Postgres reports when an insert breaks a unique index
+const uniqueViolation = "23505"
+
+// isUniqueViolation reports whether err means a row with the same unique key already exists
+// gorm only maps this to ErrDuplicatedKey when TranslateError is on, so the SQLSTATE is checked as well
+func isUniqueViolation(err error) bool {
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return true
+	}
+	var stateErr sqlStateError
+	return errors.As(err, &stateErr) && stateErr.SQLState() == uniqueViolation
+}
+
+// isTransient reports whether a failed read is likely to succeed when run again moments later
+// That's serialization failures and deadlocks, connections dropped or refused during a failover, and
+// everything isPreExecution accepts; timeouts are not, the caller's deadline has passed by then
+func isTransient(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if isPreExecution(err) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, sql.ErrConnDone) {
+		return true
+	}
+
+	// Connections reset or closed by a restarting server
+	var netErr net.Error
+	if errors.As(err, &netErr) && !netErr.Timeout() {
+		return true
+	}
+
+	var stateErr sqlStateError
+	if errors.As(err, &stateErr) {
+		switch state := stateErr.SQLState(); {
+		case strings.HasPrefix(state, "08"): // connection_exception class
+			return true
+		case state == "40001", // serialization_failure
+			state == "40P01", // deadlock_detected
+			state == "57P01": // admin_shutdown, e.g. a failover
+			return true
+		}
+	}
+	return false
+}
+
+// isPreExecution reports whether err was raised before the statement reached the server
+// Only such errors are retried for writes, which may otherwise have been applied already
+func isPreExecution(err error) bool {
+	// database/sql and pgx only report a bad connection when nothing was sent on it
+	if errors.Is(err, driver.ErrBadConn) {
+		return true
+	}
+
+	// pgconn marks its errors that happened before sending, such as a busy or closed connection
+	var retryable interface{ SafeToRetry() bool }
+	if errors.As(err, &retryable) && retryable.SafeToRetry() {
+		return true
+	}
+
+	// No connection could be opened
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+
+	// Postgres sends these while a new connection starts up, before it took any statement
+	var stateErr sqlStateError
+	if errors.As(err, &stateErr) {
+		switch stateErr.SQLState() {
+		case "53300", // too_many_connections
+			"57P03": // cannot_connect_now
+			return true
+		}
+	}
+	return false
+}
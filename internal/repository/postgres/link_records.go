@@ -0,0 +1,73 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/repository"
+)
+
+// linkRecords implements the LinkRecords interface for PostgreSQL
+type linkRecords struct {
+	db *gorm.DB
+}
+
+// NewLinkRecords creates the records of links kept outside Postgres, see repository.LinkRecords
+func NewLinkRecords(db *gorm.DB) repository.LinkRecords {
+	return &linkRecords{db: db}
+}
+
+// DeleteLinkRecords clears the records of every link in one transaction, as HardDelete does
+func (r *linkRecords) DeleteLinkRecords(ctx context.Context, links []domain.URL) error {
+	if len(links) == 0 {
+		return nil
+	}
+	err := conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		return deleteLinkRecords(tx, links)
+	})
+	if err != nil {
+		return dbError(err)
+	}
+	return nil
+}
+
+// DeleteClickRecords removes what ResetStats clears besides the counters on the link
+func (r *linkRecords) DeleteClickRecords(ctx context.Context, tenantID, shortCode string) error {
+	err := conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		for _, model := range []interface{}{&domain.ClickEvent{}, &domain.DailyClickStats{}} {
+			if err := tx.Where("tenant_id = ? AND short_code = ?", tenantID, shortCode).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return dbError(err)
+	}
+	return nil
+}
+
+// CountClicksSince ranks the links of the tenant in ctx, or of every tenant, by their click events
+// Unlike TopByClicks it can't join urls, so links no longer active are left for the caller to drop
+func (r *linkRecords) CountClicksSince(ctx context.Context, since time.Time, limit, offset int) ([]repository.LinkClicks, error) {
+	var counts []repository.LinkClicks
+
+	result := r.db.WithContext(ctx).Scopes(tenantScope(ctx)).
+		Model(&domain.ClickEvent{}).
+		Select("tenant_id, short_code, COUNT(*) AS clicks").
+		Where("clicked_at >= ?", since).
+		Group("tenant_id, short_code").
+		Order("clicks DESC, short_code ASC, tenant_id ASC").
+		Limit(limit).
+		Offset(offset).
+		Scan(&counts)
+
+	if result.Error != nil {
+		return nil, dbError(result.Error)
+	}
+
+	return counts, nil
+}
@@ -0,0 +1,108 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/repository"
+)
+
+// outboxRepository implements the OutboxRepository interface for PostgreSQL
+type outboxRepository struct {
+	db *gorm.DB
+}
+
+// NewOutboxRepository creates a new PostgreSQL outbox repository
+func NewOutboxRepository(db *gorm.DB) repository.OutboxRepository {
+	return &outboxRepository{db: db}
+}
+
+// Append inserts events, inside the caller's transaction when there is one
+func (r *outboxRepository) Append(ctx context.Context, events ...*domain.OutboxEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	for _, event := range events {
+		event.TenantID = tenantOf(ctx, event.TenantID)
+	}
+	if err := conn(ctx, r.db).Create(events).Error; err != nil {
+		return dbError(err)
+	}
+	return nil
+}
+
+// PublishPending locks a batch with FOR UPDATE SKIP LOCKED, publishes it and marks it in one transaction
+// If publish succeeds but the commit fails, the batch is published again by the next run
+func (r *outboxRepository) PublishPending(ctx context.Context, limit int, publish func([]domain.OutboxEvent) error) (int, error) {
+	published := 0
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var events []domain.OutboxEvent
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("published_at IS NULL").
+			Order("id").
+			Limit(limit).
+			Find(&events).Error
+		if err != nil {
+			return dbError(err)
+		}
+		if len(events) == 0 {
+			return nil
+		}
+
+		if err := publish(events); err != nil {
+			return err
+		}
+
+		ids := make([]uint64, len(events))
+		for i, event := range events {
+			ids[i] = event.ID
+		}
+		err = tx.Model(&domain.OutboxEvent{}).
+			Where("id IN ?", ids).
+			Update("published_at", time.Now()).Error
+		if err != nil {
+			return dbError(err)
+		}
+
+		published = len(events)
+		return nil
+	})
+
+	return published, err
+}
+
+// Lag counts unpublished events and finds the oldest one
+func (r *outboxRepository) Lag(ctx context.Context) (int64, *time.Time, error) {
+	var row struct {
+		Pending int64
+		Oldest  *time.Time
+	}
+
+	err := r.db.WithContext(ctx).
+		Model(&domain.OutboxEvent{}).
+		Select("COUNT(*) AS pending, MIN(created_at) AS oldest").
+		Where("published_at IS NULL").
+		Scan(&row).Error
+	if err != nil {
+		return 0, nil, dbError(err)
+	}
+
+	return row.Pending, row.Oldest, nil
+}
+
+// DeletePublished removes relayed events older than the cutoff
+func (r *outboxRepository) DeletePublished(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Where("published_at IS NOT NULL AND published_at < ?", before).
+		Delete(&domain.OutboxEvent{})
+
+	if result.Error != nil {
+		return 0, dbError(result.Error)
+	}
+	return result.RowsAffected, nil
+}
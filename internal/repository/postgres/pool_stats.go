@@ -0,0 +1,39 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+
+	"url-shortener/internal/metrics"
+)
+
+// PoolStatsReporter copies database/sql pool statistics into the Prometheus metrics
+// database/sql only keeps running totals of waits, so the reporter adds the change since its last run
+type PoolStatsReporter struct {
+	db   *sql.DB
+	mu   sync.Mutex
+	last sql.DBStats
+}
+
+// NewPoolStatsReporter creates a reporter for db's connection pool
+func NewPoolStatsReporter(db *sql.DB) *PoolStatsReporter {
+	return &PoolStatsReporter{db: db}
+}
+
+// Run exports the current pool statistics; it is meant to run as a scheduler job
+func (r *PoolStatsReporter) Run(context.Context) error {
+	stats := r.db.Stats()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	metrics.DBConnections.WithLabelValues("in_use").Set(float64(stats.InUse))
+	metrics.DBConnections.WithLabelValues("idle").Set(float64(stats.Idle))
+	metrics.DBMaxOpenConnections.Set(float64(stats.MaxOpenConnections))
+	metrics.DBWaits.Add(float64(stats.WaitCount - r.last.WaitCount))
+	metrics.DBWaitSeconds.Add((stats.WaitDuration - r.last.WaitDuration).Seconds())
+
+	r.last = stats
+	return nil
+}
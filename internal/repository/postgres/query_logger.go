@@ -0,0 +1,97 @@
+package postgres
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+
+	"url-shortener/pkg/logger"
+)
+
+// QueryLogger reports slow and failed queries through the application logger
+// Statements are logged as a digest with literals stripped, so destinations and IPs never reach the logs
+type QueryLogger struct {
+	log           *logger.Logger
+	slowThreshold time.Duration
+}
+
+// NewQueryLogger returns a gorm logger warning about queries slower than slowThreshold (0 disables it)
+func NewQueryLogger(log *logger.Logger, slowThreshold time.Duration) *QueryLogger {
+	return &QueryLogger{log: log, slowThreshold: slowThreshold}
+}
+
+// LogMode is part of gorm's logger interface; the level is governed by LOG_LEVEL instead
+func (l *QueryLogger) LogMode(gormlogger.LogLevel) gormlogger.Interface {
+	return l
+}
+
+// Info logs gorm's own informational messages
+func (l *QueryLogger) Info(_ context.Context, msg string, args ...interface{}) {
+	l.log.Infof(msg, args...)
+}
+
+// Warn logs gorm's own warnings
+func (l *QueryLogger) Warn(_ context.Context, msg string, args ...interface{}) {
+	l.log.Warnf(msg, args...)
+}
+
+// Error logs gorm's own errors
+func (l *QueryLogger) Error(_ context.Context, msg string, args ...interface{}) {
+	l.log.Errorf(msg, args...)
+}
+
+// Trace is called after every statement
+// Missing records are an expected outcome, so like gorm's default logger they aren't reported
+func (l *QueryLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	elapsed := time.Since(begin)
+	slow := l.slowThreshold > 0 && elapsed > l.slowThreshold
+	failed := err != nil && !errors.Is(err, gorm.ErrRecordNotFound)
+	if !slow && !failed {
+		return
+	}
+
+	sql, rows := fc()
+	statement, digest := StatementDigest(sql)
+	fields := []interface{}{
+		"digest", digest,
+		"statement", statement,
+		"duration_ms", elapsed.Milliseconds(),
+		"rows", rows,
+		"request_id", logger.RequestIDFromContext(ctx),
+	}
+
+	if slow {
+		l.log.Warnw("Slow query", fields...)
+		return
+	}
+	l.log.Infow("Query failed", append(fields, "error", err)...)
+}
+
+var (
+	// stringLiteral matches a single-quoted SQL string, '' being an escaped quote
+	stringLiteral = regexp.MustCompile(`'(?:[^']|'')*'`)
+	// numberLiteral matches numbers that aren't part of an identifier such as idx_urls_2
+	numberLiteral = regexp.MustCompile(`\b\d+(?:\.\d+)?\b`)
+	// placeholderList matches the placeholders of an IN list, whose length varies with the input
+	placeholderList = regexp.MustCompile(`\?(?:\s*,\s*\?)+`)
+	whitespace      = regexp.MustCompile(`\s+`)
+)
+
+// StatementDigest replaces the literals in sql with ? and returns the result with a short hash of it
+// Statements that differ only in their values get the same digest, so slow queries can be grouped
+func StatementDigest(sql string) (string, string) {
+	normalized := stringLiteral.ReplaceAllString(sql, "?")
+	normalized = numberLiteral.ReplaceAllString(normalized, "?")
+	normalized = placeholderList.ReplaceAllString(normalized, "?")
+	normalized = strings.TrimSpace(whitespace.ReplaceAllString(normalized, " "))
+
+	sum := sha256.Sum256([]byte(normalized))
+	return normalized, hex.EncodeToString(sum[:8])
+}
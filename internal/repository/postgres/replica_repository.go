@@ -0,0 +1,205 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"url-shortener/internal/cache"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/metrics"
+	"url-shortener/internal/repository"
+	"url-shortener/pkg/logger"
+)
+
+// replicaURLRepository sends link lookups and stats to read replicas and everything else to the primary
+// Replicas lag behind, so a code written within the fallback window is re-read from the primary
+// when a replica can't find it; writes are remembered in the cache for that long
+type replicaURLRepository struct {
+	repository.URLRepository
+	replicas []repository.URLRepository
+	next     atomic.Uint64
+	recent   cache.Cache
+	window   time.Duration
+	log      *logger.Logger
+}
+
+// NewReplicaURLRepository wraps primary so reads are spread over replicas in turn
+// recent may be nil, in which case every not-found from a replica is checked against the primary
+func NewReplicaURLRepository(primary repository.URLRepository, replicas []repository.URLRepository, recent cache.Cache, window time.Duration, log *logger.Logger) repository.URLRepository {
+	if len(replicas) == 0 {
+		return primary
+	}
+	return &replicaURLRepository{
+		URLRepository: primary,
+		replicas:      replicas,
+		recent:        recent,
+		window:        window,
+		log:           log,
+	}
+}
+
+// replica picks the replica for the next read
+func (r *replicaURLRepository) replica() repository.URLRepository {
+	return r.replicas[(r.next.Add(1)-1)%uint64(len(r.replicas))]
+}
+
+// FindByShortCode reads from a replica, falling back to the primary on errors or a fresh code
+func (r *replicaURLRepository) FindByShortCode(ctx context.Context, shortCode string) (*domain.URL, error) {
+	url, err := r.replica().FindByShortCode(ctx, shortCode)
+	if r.useReplica(ctx, "FindByShortCode", err, cache.RecentWriteKey(shortCode)) {
+		return url, err
+	}
+	return r.URLRepository.FindByShortCode(ctx, shortCode)
+}
+
+// FindByOriginalURL reads from a replica, falling back to the primary on errors or a fresh destination
+func (r *replicaURLRepository) FindByOriginalURL(ctx context.Context, originalURL string) (*domain.URL, error) {
+	url, err := r.replica().FindByOriginalURL(ctx, originalURL)
+	if r.useReplica(ctx, "FindByOriginalURL", err, cache.RecentDestinationKey(originalURL)) {
+		return url, err
+	}
+	return r.URLRepository.FindByOriginalURL(ctx, originalURL)
+}
+
+// GetStats reads from a replica, falling back to the primary on errors or a fresh code
+func (r *replicaURLRepository) GetStats(ctx context.Context, shortCode string) (*domain.URLStats, error) {
+	stats, err := r.replica().GetStats(ctx, shortCode)
+	if r.useReplica(ctx, "GetStats", err, cache.RecentWriteKey(shortCode)) {
+		return stats, err
+	}
+	return r.URLRepository.GetStats(ctx, shortCode)
+}
+
+// ExistsByShortCode reads from a replica, asking the primary again when a fresh code looks free
+func (r *replicaURLRepository) ExistsByShortCode(ctx context.Context, shortCode string) (bool, error) {
+	exists, err := r.replica().ExistsByShortCode(ctx, shortCode)
+	if err == nil && !exists {
+		err = domain.ErrURLNotFound
+	}
+	if r.useReplica(ctx, "ExistsByShortCode", err, cache.RecentWriteKey(shortCode)) {
+		return exists, nil
+	}
+	return r.URLRepository.ExistsByShortCode(ctx, shortCode)
+}
+
+// useReplica reports whether a replica's answer stands
+// Errors other than not-found fail over to the primary; not-found does too while key marks a recent write
+func (r *replicaURLRepository) useReplica(ctx context.Context, op string, err error, key string) bool {
+	if err == nil {
+		return true
+	}
+	if !errors.Is(err, domain.ErrURLNotFound) {
+		if ctx.Err() != nil {
+			return true
+		}
+		r.log.Warn("Replica read failed, using the primary", "operation", op, "error", err)
+		metrics.DBReplicaFallbacks.WithLabelValues("error").Inc()
+		return false
+	}
+	if !r.writtenRecently(ctx, key) {
+		return true
+	}
+	metrics.DBReplicaFallbacks.WithLabelValues("lag").Inc()
+	return false
+}
+
+// writtenRecently reports whether key was marked within the window
+// Without a working cache it can't tell, so it assumes so and lets the primary decide
+func (r *replicaURLRepository) writtenRecently(ctx context.Context, key string) bool {
+	if r.recent == nil {
+		return true
+	}
+	exists, err := r.recent.Exists(ctx, key)
+	return err != nil || exists
+}
+
+// markWritten remembers writes for the fallback window; failing to is harmless beyond a short lag
+func (r *replicaURLRepository) markWritten(ctx context.Context, keys ...string) {
+	if r.recent == nil {
+		return
+	}
+	for _, key := range keys {
+		if err := r.recent.Set(ctx, key, "1", r.window); err != nil {
+			r.log.Warn("Failed to mark recent write", "key", key, "error", err)
+			return
+		}
+	}
+}
+
+// Create stores the link on the primary and marks its code and destination as fresh
+func (r *replicaURLRepository) Create(ctx context.Context, url *domain.URL) error {
+	if err := r.URLRepository.Create(ctx, url); err != nil {
+		return err
+	}
+	r.markWritten(ctx, cache.RecentWriteKey(url.ShortCode), cache.RecentDestinationKey(url.OriginalURL))
+	return nil
+}
+
+// CreateBundle stores the bundle on the primary and marks every code in it as fresh
+func (r *replicaURLRepository) CreateBundle(ctx context.Context, bundle *domain.URL, members []*domain.URL) error {
+	if err := r.URLRepository.CreateBundle(ctx, bundle, members); err != nil {
+		return err
+	}
+	keys := []string{cache.RecentWriteKey(bundle.ShortCode)}
+	for _, member := range members {
+		keys = append(keys, cache.RecentWriteKey(member.ShortCode))
+	}
+	r.markWritten(ctx, keys...)
+	return nil
+}
+
+// Update modifies the link on the primary and marks its code and destination as fresh
+func (r *replicaURLRepository) Update(ctx context.Context, url *domain.URL) error {
+	if err := r.URLRepository.Update(ctx, url); err != nil {
+		return err
+	}
+	r.markWritten(ctx, cache.RecentWriteKey(url.ShortCode), cache.RecentDestinationKey(url.OriginalURL))
+	return nil
+}
+
+// HardDelete erases the link on the primary and marks the code as fresh
+// A lagging replica would otherwise still find the row and let a redirect cache it again
+func (r *replicaURLRepository) HardDelete(ctx context.Context, shortCode string) error {
+	if err := r.URLRepository.HardDelete(ctx, shortCode); err != nil {
+		return err
+	}
+	r.markWritten(ctx, cache.RecentWriteKey(shortCode))
+	return nil
+}
+
+// ResetStats zeroes the counters on the primary and marks the code as fresh
+// The stats read right after the reset then come from the primary, not a replica still showing the old totals
+func (r *replicaURLRepository) ResetStats(ctx context.Context, shortCode string) (*domain.URL, error) {
+	prior, err := r.URLRepository.ResetStats(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+	r.markWritten(ctx, cache.RecentWriteKey(shortCode))
+	return prior, nil
+}
+
+// HardDeleteInactive erases the links on the primary and marks their codes as fresh
+func (r *replicaURLRepository) HardDeleteInactive(ctx context.Context, before time.Time, limit int) ([]domain.URL, error) {
+	links, err := r.URLRepository.HardDeleteInactive(ctx, before, limit)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, len(links))
+	for i, link := range links {
+		keys[i] = cache.RecentWriteKey(link.ShortCode)
+	}
+	r.markWritten(ctx, keys...)
+	return links, nil
+}
+
+// SetActive flips the flag on the primary and marks the code as fresh
+func (r *replicaURLRepository) SetActive(ctx context.Context, shortCode string, active bool) (*domain.URL, error) {
+	url, err := r.URLRepository.SetActive(ctx, shortCode, active)
+	if err != nil {
+		return nil, err
+	}
+	r.markWritten(ctx, cache.RecentWriteKey(shortCode))
+	return url, nil
+}
@@ -0,0 +1,201 @@
+package postgres
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"gorm.io/gorm"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/metrics"
+	"url-shortener/internal/repository"
+	"url-shortener/pkg/logger"
+)
+
+// RetryPolicy is how often and how soon a query that failed transiently is run again
+type RetryPolicy struct {
+	Attempts int           // Retries after the first try; 0 disables them
+	Backoff  time.Duration // Wait before the first retry, doubled for each further one and jittered
+}
+
+// retryingURLRepository runs link queries again after transient failures, e.g. while the primary fails over
+// Reads are retried on every error isTransient accepts. Create and Update may already have been applied
+// when the connection dropped, so they are only retried when the error came before the statement was sent.
+// Other writes, transactions and ForEach, whose callback has side effects, are passed through unchanged.
+type retryingURLRepository struct {
+	repository.URLRepository
+	policy RetryPolicy
+	log    *logger.Logger
+}
+
+// NewRetryingURLRepository wraps inner so its queries are retried under policy
+// A policy without attempts returns inner as is
+func NewRetryingURLRepository(inner repository.URLRepository, policy RetryPolicy, log *logger.Logger) repository.URLRepository {
+	if policy.Attempts <= 0 {
+		return inner
+	}
+	return &retryingURLRepository{URLRepository: inner, policy: policy, log: log}
+}
+
+// retry calls op until it succeeds, fails with an error retryable refuses or the attempts are used up
+func (r *retryingURLRepository) retry(ctx context.Context, op string, retryable func(error) bool, fn func() error) error {
+	err := fn()
+	for attempt := 0; err != nil && attempt < r.policy.Attempts && retryable(err); attempt++ {
+		// The failure aborted the open transaction, running the statement in it again can't succeed
+		if _, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+			return err
+		}
+		if !r.wait(ctx, attempt) {
+			return err
+		}
+
+		r.log.Warn("Transient database error, retrying", "operation", op, "attempt", attempt+1, "error", err)
+		metrics.DBRetries.WithLabelValues(op).Inc()
+		err = fn()
+	}
+	return err
+}
+
+// wait sleeps before the retry after attempt, between half and one and a half times the backoff
+// Returns false when ctx ends first
+func (r *retryingURLRepository) wait(ctx context.Context, attempt int) bool {
+	backoff := r.policy.Backoff << attempt
+	backoff = backoff/2 + time.Duration(rand.Int63n(int64(backoff)+1))
+
+	timer := time.NewTimer(backoff)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// read retries fn as a read
+func read[T any](r *retryingURLRepository, ctx context.Context, op string, fn func() (T, error)) (T, error) {
+	var result T
+	err := r.retry(ctx, op, isTransient, func() error {
+		var err error
+		result, err = fn()
+		return err
+	})
+	return result, err
+}
+
+// Create retries only failures that happened before the insert was sent
+func (r *retryingURLRepository) Create(ctx context.Context, url *domain.URL) error {
+	return r.retry(ctx, "Create", isPreExecution, func() error {
+		return r.URLRepository.Create(ctx, url)
+	})
+}
+
+// Update retries only failures that happened before the update was sent
+func (r *retryingURLRepository) Update(ctx context.Context, url *domain.URL) error {
+	return r.retry(ctx, "Update", isPreExecution, func() error {
+		return r.URLRepository.Update(ctx, url)
+	})
+}
+
+func (r *retryingURLRepository) FindByShortCode(ctx context.Context, shortCode string) (*domain.URL, error) {
+	return read(r, ctx, "FindByShortCode", func() (*domain.URL, error) {
+		return r.URLRepository.FindByShortCode(ctx, shortCode)
+	})
+}
+
+func (r *retryingURLRepository) FindByShortCodeFold(ctx context.Context, shortCode string) (*domain.URL, error) {
+	return read(r, ctx, "FindByShortCodeFold", func() (*domain.URL, error) {
+		return r.URLRepository.FindByShortCodeFold(ctx, shortCode)
+	})
+}
+
+func (r *retryingURLRepository) FindAnyByShortCode(ctx context.Context, shortCode string) (*domain.URL, error) {
+	return read(r, ctx, "FindAnyByShortCode", func() (*domain.URL, error) {
+		return r.URLRepository.FindAnyByShortCode(ctx, shortCode)
+	})
+}
+
+func (r *retryingURLRepository) FindByCreatorIP(ctx context.Context, ip string, limit, offset int) ([]domain.URL, error) {
+	return read(r, ctx, "FindByCreatorIP", func() ([]domain.URL, error) {
+		return r.URLRepository.FindByCreatorIP(ctx, ip, limit, offset)
+	})
+}
+
+func (r *retryingURLRepository) FindByOriginalURL(ctx context.Context, originalURL string) (*domain.URL, error) {
+	return read(r, ctx, "FindByOriginalURL", func() (*domain.URL, error) {
+		return r.URLRepository.FindByOriginalURL(ctx, originalURL)
+	})
+}
+
+func (r *retryingURLRepository) GetStats(ctx context.Context, shortCode string) (*domain.URLStats, error) {
+	return read(r, ctx, "GetStats", func() (*domain.URLStats, error) {
+		return r.URLRepository.GetStats(ctx, shortCode)
+	})
+}
+
+func (r *retryingURLRepository) FindExpiringBetween(ctx context.Context, from, to time.Time, limit int) ([]domain.URL, error) {
+	return read(r, ctx, "FindExpiringBetween", func() ([]domain.URL, error) {
+		return r.URLRepository.FindExpiringBetween(ctx, from, to, limit)
+	})
+}
+
+func (r *retryingURLRepository) FindDueForCheck(ctx context.Context, checkedBefore time.Time, afterID uint, limit int) ([]domain.URL, error) {
+	return read(r, ctx, "FindDueForCheck", func() ([]domain.URL, error) {
+		return r.URLRepository.FindDueForCheck(ctx, checkedBefore, afterID, limit)
+	})
+}
+
+func (r *retryingURLRepository) ExistsByShortCode(ctx context.Context, shortCode string) (bool, error) {
+	return read(r, ctx, "ExistsByShortCode", func() (bool, error) {
+		return r.URLRepository.ExistsByShortCode(ctx, shortCode)
+	})
+}
+
+func (r *retryingURLRepository) ExistsManyByShortCode(ctx context.Context, shortCodes []string) (map[string]bool, error) {
+	return read(r, ctx, "ExistsManyByShortCode", func() (map[string]bool, error) {
+		return r.URLRepository.ExistsManyByShortCode(ctx, shortCodes)
+	})
+}
+
+func (r *retryingURLRepository) CountActiveMatching(ctx context.Context, filter domain.URLFilter) (int64, error) {
+	return read(r, ctx, "CountActiveMatching", func() (int64, error) {
+		return r.URLRepository.CountActiveMatching(ctx, filter)
+	})
+}
+
+func (r *retryingURLRepository) FindActiveMatching(ctx context.Context, filter domain.URLFilter, limit, offset int) ([]domain.URL, error) {
+	return read(r, ctx, "FindActiveMatching", func() ([]domain.URL, error) {
+		return r.URLRepository.FindActiveMatching(ctx, filter, limit, offset)
+	})
+}
+
+func (r *retryingURLRepository) CountURLs(ctx context.Context) (int64, error) {
+	return read(r, ctx, "CountURLs", func() (int64, error) {
+		return r.URLRepository.CountURLs(ctx)
+	})
+}
+
+func (r *retryingURLRepository) SumClicks(ctx context.Context) (int64, error) {
+	return read(r, ctx, "SumClicks", func() (int64, error) {
+		return r.URLRepository.SumClicks(ctx)
+	})
+}
+
+func (r *retryingURLRepository) TopByClicks(ctx context.Context, since time.Time, limit int) ([]domain.TopLink, error) {
+	return read(r, ctx, "TopByClicks", func() ([]domain.TopLink, error) {
+		return r.URLRepository.TopByClicks(ctx, since, limit)
+	})
+}
+
+func (r *retryingURLRepository) TopByClickCount(ctx context.Context, limit int) ([]domain.URL, error) {
+	return read(r, ctx, "TopByClickCount", func() ([]domain.URL, error) {
+		return r.URLRepository.TopByClickCount(ctx, limit)
+	})
+}
+
+func (r *retryingURLRepository) CreatedBetween(ctx context.Context, from, to time.Time) ([]domain.DailyCount, error) {
+	return read(r, ctx, "CreatedBetween", func() ([]domain.DailyCount, error) {
+		return r.URLRepository.CreatedBetween(ctx, from, to)
+	})
+}
@@ -0,0 +1,34 @@
+package postgres
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"url-shortener/internal/domain"
+)
+
+// tenantScope limits a query to the rows of the tenant in ctx, see domain.ContextWithTenant
+// Without a tenant in ctx every tenant's rows match, as background jobs need
+func tenantScope(ctx context.Context) func(*gorm.DB) *gorm.DB {
+	return tenantColumnScope(ctx, "tenant_id")
+}
+
+// tenantColumnScope is tenantScope for queries that have to qualify the column, e.g. in a join
+func tenantColumnScope(ctx context.Context, column string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if tenant, ok := domain.TenantFromContext(ctx); ok {
+			return db.Where(column+" = ?", tenant)
+		}
+		return db
+	}
+}
+
+// tenantOf returns the tenant a row inserted with ctx belongs to
+// Rows written without a tenant in ctx keep the one they were given
+func tenantOf(ctx context.Context, given string) string {
+	if tenant, ok := domain.TenantFromContext(ctx); ok {
+		return tenant
+	}
+	return given
+}
@@ -0,0 +1,41 @@
+package postgres
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"url-shortener/internal/repository"
+)
+
+// txKey carries the open transaction in the context
+type txKey struct{}
+
+// transactor implements the Transactor interface for PostgreSQL
+type transactor struct {
+	db *gorm.DB
+}
+
+// NewTransactor creates a transactor whose transactions are picked up by the repositories in this package
+func NewTransactor(db *gorm.DB) repository.Transactor {
+	return &transactor{db: db}
+}
+
+// WithinTx runs fn in a new transaction, or in the caller's when one is already open
+func (t *transactor) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return fn(ctx)
+	}
+
+	return t.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(context.WithValue(ctx, txKey{}, tx))
+	})
+}
+
+// conn returns the transaction open in ctx, or db outside of WithinTx
+func conn(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return tx.WithContext(ctx)
+	}
+	return db.WithContext(ctx)
+}
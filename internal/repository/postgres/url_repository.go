@@ -2,13 +2,18 @@ package postgres
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"strings"
 	"time"
 	
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	
 	"url-shortener/internal/domain"
 	"url-shortener/internal/repository"
+	"url-shortener/pkg/validator"
 )
 
 // urlRepository implements the URLRepository interface for PostgreSQL
@@ -24,24 +29,62 @@ func NewURLRepository(db *gorm.DB) repository.URLRepository {
 // Create inserts a new URL record into the database
 // Uses GORM's Create method with proper error handling
 func (r *urlRepository) Create(ctx context.Context, url *domain.URL) error {
-	result := r.db.WithContext(ctx).Create(url)
+	setURLHash(url)
+	url.TenantID = tenantOf(ctx, url.TenantID)
+	result := conn(ctx, r.db).Create(url)
 	if result.Error != nil {
 		// Check for unique constraint violation (duplicate short code)
-		if errors.Is(result.Error, gorm.ErrDuplicatedKey) {
+		if isUniqueViolation(result.Error) {
 			return domain.ErrShortCodeTaken
 		}
-		return domain.NewInternalError(result.Error)
+		return dbError(result.Error)
 	}
 	return nil
 }
 
+// CreateBundle inserts the member links and the bundle atomically
+// A failed insert leaves neither orphaned members nor a bundle pointing at missing codes
+func (r *urlRepository) CreateBundle(ctx context.Context, bundle *domain.URL, members []*domain.URL) error {
+	setURLHash(bundle)
+	bundle.TenantID = tenantOf(ctx, bundle.TenantID)
+	for _, member := range members {
+		setURLHash(member)
+		member.TenantID = tenantOf(ctx, member.TenantID)
+	}
+	err := conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(members).Error; err != nil {
+			return err
+		}
+		return tx.Create(bundle).Error
+	})
+	if err != nil {
+		if isUniqueViolation(err) {
+			return domain.ErrShortCodeTaken
+		}
+		return dbError(err)
+	}
+	return nil
+}
+
+// hashURL returns the hex SHA-256 stored in url_hash, the same digest migration 0002 backfilled
+func hashURL(originalURL string) string {
+	sum := sha256.Sum256([]byte(originalURL))
+	return hex.EncodeToString(sum[:])
+}
+
+// setURLHash keeps url_hash and destination_host in step with original_url before a write
+func setURLHash(url *domain.URL) {
+	url.URLHash = hashURL(url.OriginalURL)
+	url.DestinationHost = validator.DestinationHost(url.OriginalURL)
+}
+
 // FindByShortCode retrieves a URL by its short code
 // Returns ErrURLNotFound if the code doesn't exist
 func (r *urlRepository) FindByShortCode(ctx context.Context, shortCode string) (*domain.URL, error) {
 	var url domain.URL
 	
 	// Use First to get a single record, with index hint for performance
-	result := r.db.WithContext(ctx).
+	result := r.db.WithContext(ctx).Scopes(tenantScope(ctx)).
 		Where("short_code = ? AND is_active = ?", shortCode, true).
 		First(&url)
 	
@@ -49,36 +92,99 @@ func (r *urlRepository) FindByShortCode(ctx context.Context, shortCode string) (
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 			return nil, domain.ErrURLNotFound
 		}
-		return nil, domain.NewInternalError(result.Error)
+		return nil, dbError(result.Error)
+	}
+	
+	return &url, nil
+}
+
+// FindByShortCodeFold retrieves the only URL whose code matches shortCode ignoring case, if it is active
+// Two candidates, e.g. abc and ABC, are ambiguous and treated as no match. Deactivated links count as
+// candidates, so a deactivated code never starts redirecting to a link differing only in case.
+func (r *urlRepository) FindByShortCodeFold(ctx context.Context, shortCode string) (*domain.URL, error) {
+	var urls []domain.URL
+	
+	// The expression matches idx_urls_short_code_lower, so this is an index lookup
+	result := r.db.WithContext(ctx).Scopes(tenantScope(ctx)).
+		Where("lower(short_code) = ?", strings.ToLower(shortCode)).
+		Limit(2).
+		Find(&urls)
+	
+	if result.Error != nil {
+		return nil, dbError(result.Error)
+	}
+	if len(urls) != 1 || !urls[0].IsActive {
+		return nil, domain.ErrURLNotFound
+	}
+	
+	return &urls[0], nil
+}
+
+// FindAnyByShortCode retrieves a URL by its short code including deactivated links
+// Returns ErrURLNotFound if the code doesn't exist
+func (r *urlRepository) FindAnyByShortCode(ctx context.Context, shortCode string) (*domain.URL, error) {
+	var url domain.URL
+	
+	result := r.db.WithContext(ctx).Scopes(tenantScope(ctx)).Where("short_code = ?", shortCode).First(&url)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, domain.ErrURLNotFound
+		}
+		return nil, dbError(result.Error)
 	}
 	
 	return &url, nil
 }
 
+// FindByCreatorIP pages through the links created from ip, newest first, including deactivated ones
+func (r *urlRepository) FindByCreatorIP(ctx context.Context, ip string, limit, offset int) ([]domain.URL, error) {
+	var urls []domain.URL
+	
+	result := r.db.WithContext(ctx).Scopes(tenantScope(ctx)).
+		Where("creator_ip = ?", ip).
+		Order("created_at DESC, id DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&urls)
+	
+	if result.Error != nil {
+		return nil, dbError(result.Error)
+	}
+	
+	return urls, nil
+}
+
 // FindByOriginalURL checks if an original URL already exists
-// This helps prevent duplicate URLs and can be used for deduplication
+// This helps prevent duplicate URLs and can be used for deduplication.
+// The lookup goes through the indexed url_hash; comparing original_url as well rules out collisions.
 func (r *urlRepository) FindByOriginalURL(ctx context.Context, originalURL string) (*domain.URL, error) {
 	var url domain.URL
 	
-	result := r.db.WithContext(ctx).
-		Where("original_url = ? AND is_active = ?", originalURL, true).
+	result := r.db.WithContext(ctx).Scopes(tenantScope(ctx)).
+		Where("url_hash = ? AND original_url = ? AND is_active = ?", hashURL(originalURL), originalURL, true).
 		First(&url)
 	
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 			return nil, domain.ErrURLNotFound
 		}
-		return nil, domain.NewInternalError(result.Error)
+		return nil, dbError(result.Error)
 	}
 	
 	return &url, nil
 }
 
 // Update modifies an existing URL record
+// Save alone would insert a link whose row doesn't exist; selecting every column keeps it an UPDATE
 func (r *urlRepository) Update(ctx context.Context, url *domain.URL) error {
-	result := r.db.WithContext(ctx).Save(url)
+	if url.ID == 0 {
+		return domain.ErrURLNotFound
+	}
+	
+	setURLHash(url)
+	result := r.db.WithContext(ctx).Scopes(tenantScope(ctx)).Select("*").Save(url)
 	if result.Error != nil {
-		return domain.NewInternalError(result.Error)
+		return dbError(result.Error)
 	}
 	
 	if result.RowsAffected == 0 {
@@ -88,16 +194,47 @@ func (r *urlRepository) Update(ctx context.Context, url *domain.URL) error {
 	return nil
 }
 
+// SetActive updates is_active regardless of the current state and reloads the row
+func (r *urlRepository) SetActive(ctx context.Context, shortCode string, active bool) (*domain.URL, error) {
+	var url domain.URL
+	
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Scopes(tenantScope(ctx)).
+			Model(&domain.URL{}).
+			Where("short_code = ?", shortCode).
+			Update("is_active", active)
+		
+		if result.Error != nil {
+			return dbError(result.Error)
+		}
+		
+		if result.RowsAffected == 0 {
+			return domain.ErrURLNotFound
+		}
+		
+		if err := tx.Scopes(tenantScope(ctx)).Where("short_code = ?", shortCode).First(&url).Error; err != nil {
+			return dbError(err)
+		}
+		
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	
+	return &url, nil
+}
+
 // Delete soft-deletes a URL by setting is_active to false
 // This preserves data for analytics while preventing access
 func (r *urlRepository) Delete(ctx context.Context, shortCode string) error {
-	result := r.db.WithContext(ctx).
+	result := conn(ctx, r.db).Scopes(tenantScope(ctx)).
 		Model(&domain.URL{}).
 		Where("short_code = ?", shortCode).
 		Update("is_active", false)
 	
 	if result.Error != nil {
-		return domain.NewInternalError(result.Error)
+		return dbError(result.Error)
 	}
 	
 	if result.RowsAffected == 0 {
@@ -107,22 +244,259 @@ func (r *urlRepository) Delete(ctx context.Context, shortCode string) error {
 	return nil
 }
 
+// HardDelete erases the row and every record derived from it in one transaction
+func (r *urlRepository) HardDelete(ctx context.Context, shortCode string) error {
+	err := conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		var erased []domain.URL
+		result := tx.Clauses(clause.Returning{Columns: []clause.Column{{Name: "tenant_id"}, {Name: "short_code"}}}).
+			Scopes(tenantScope(ctx)).
+			Where("short_code = ?", shortCode).
+			Delete(&erased)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return domain.ErrURLNotFound
+		}
+		return deleteLinkRecords(tx, erased)
+	})
+	if errors.Is(err, domain.ErrURLNotFound) {
+		return err
+	}
+	if err != nil {
+		return dbError(err)
+	}
+	return nil
+}
+
+// ResetStats locks the row before zeroing it, so clicks counted meanwhile wait and land after the reset
+func (r *urlRepository) ResetStats(ctx context.Context, shortCode string) (*domain.URL, error) {
+	var prior domain.URL
+	err := conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		err := tx.Scopes(tenantScope(ctx)).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("short_code = ?", shortCode).
+			First(&prior).Error
+		if err != nil {
+			return err
+		}
+		
+		err = tx.Model(&domain.URL{}).Where("id = ?", prior.ID).Updates(map[string]interface{}{
+			"click_count":     0,
+			"bot_clicks":      0,
+			"prefetch_hits":   0,
+			"filtered_clicks": 0,
+			"last_access_at":  nil,
+			"last_referrer":   "",
+			"referrer_counts": nil,
+			"stats_reset_at":  time.Now(),
+		}).Error
+		if err != nil {
+			return err
+		}
+		
+		for _, model := range []interface{}{&domain.ClickEvent{}, &domain.DailyClickStats{}} {
+			if err := tx.Where("tenant_id = ? AND short_code = ?", prior.TenantID, prior.ShortCode).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, domain.ErrURLNotFound
+	}
+	if err != nil {
+		return nil, dbError(err)
+	}
+	return &prior, nil
+}
+
+// HardDeleteInactive erases the longest-inactive links first
+// The rows are locked with SKIP LOCKED, so instances running the cleanup together take different batches.
+// The trigger keeps updated_at current, so it is never earlier than the deactivation and no link is erased early.
+func (r *urlRepository) HardDeleteInactive(ctx context.Context, before time.Time, limit int) ([]domain.URL, error) {
+	var links []domain.URL
+	err := conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		err := tx.Scopes(tenantScope(ctx)).
+			Select("id", "tenant_id", "short_code").
+			Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("is_active = ? AND updated_at < ?", false, before).
+			Order("updated_at ASC, id ASC").
+			Limit(limit).
+			Find(&links).Error
+		if err != nil || len(links) == 0 {
+			return err
+		}
+		
+		ids := make([]uint, len(links))
+		for i, link := range links {
+			ids[i] = link.ID
+		}
+		if err := tx.Where("id IN ?", ids).Delete(&domain.URL{}).Error; err != nil {
+			return err
+		}
+		return deleteLinkRecords(tx, links)
+	})
+	if err != nil {
+		return nil, dbError(err)
+	}
+	return links, nil
+}
+
+// deleteLinkRecords removes what the service recorded about the links besides their rows
+// None of these tables has a foreign key to urls, so they have to be cleared explicitly, tenant by tenant
+// since a code can name a link of each tenant.
+func deleteLinkRecords(tx *gorm.DB, links []domain.URL) error {
+	codesByTenant := make(map[string][]string)
+	for _, link := range links {
+		codesByTenant[link.TenantID] = append(codesByTenant[link.TenantID], link.ShortCode)
+	}
+	for tenant, codes := range codesByTenant {
+		for _, model := range []interface{}{&domain.ClickEvent{}, &domain.DailyClickStats{}, &domain.AuditEntry{}, &domain.OutboxEvent{}} {
+			if err := tx.Where("tenant_id = ? AND short_code IN ?", tenant, codes).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// referrerKeySQL picks the key a click from @referrer is counted under
+// Hosts already counted keep counting; a new host gets its own key only while fewer than @max are named
+const referrerKeySQL = `(CASE WHEN referrer_counts -> CAST(@referrer AS TEXT) IS NOT NULL
+		OR (SELECT COUNT(*) FROM jsonb_object_keys(COALESCE(referrer_counts, '{}'::jsonb)) AS k
+			WHERE k <> CAST(@other AS TEXT)) < @max
+	THEN CAST(@referrer AS TEXT) ELSE CAST(@other AS TEXT) END)`
+
+// referrerCountsSQL adds one click to the referrer's key in the JSONB counts
+const referrerCountsSQL = `jsonb_set(COALESCE(referrer_counts, '{}'::jsonb), ARRAY[` + referrerKeySQL + `],
+	to_jsonb(COALESCE(CAST(referrer_counts ->> ` + referrerKeySQL + ` AS BIGINT), 0) + 1))`
+
+// maxReferrerLength is the size of the last_referrer column; longer hosts are not recorded
+const maxReferrerLength = 255
+
 // IncrementClickCount atomically increments the click counter
 // Uses SQL UPDATE to ensure thread-safety without SELECT-then-UPDATE race condition
-func (r *urlRepository) IncrementClickCount(ctx context.Context, shortCode string) error {
+// The referrer counts are updated in the same statement, so they add no row lock of their own
+func (r *urlRepository) IncrementClickCount(ctx context.Context, shortCode, referrer string) error {
 	now := time.Now()
+	updates := map[string]interface{}{
+		"click_count":    gorm.Expr("click_count + ?", 1),
+		"last_access_at": now,
+	}
+	if referrer != "" && len(referrer) <= maxReferrerLength {
+		updates["last_referrer"] = referrer
+		updates["referrer_counts"] = clause.NamedExpr{SQL: referrerCountsSQL, Vars: []interface{}{map[string]interface{}{
+			"referrer": referrer,
+			"other":    domain.OtherReferrers,
+			"max":      domain.MaxReferrerCounts,
+		}}}
+	}
 	
 	// Use raw SQL for atomic increment to prevent race conditions
-	result := r.db.WithContext(ctx).
+	result := conn(ctx, r.db).Scopes(tenantScope(ctx)).
+		Model(&domain.URL{}).
+		Where("short_code = ? AND is_active = ?", shortCode, true).
+		Updates(updates)
+	
+	if result.Error != nil {
+		return dbError(result.Error)
+	}
+	
+	if result.RowsAffected == 0 {
+		return domain.ErrURLNotFound
+	}
+	
+	return nil
+}
+
+// IncrementBotClickCount atomically increments the bot click counter
+func (r *urlRepository) IncrementBotClickCount(ctx context.Context, shortCode string) error {
+	result := r.db.WithContext(ctx).Scopes(tenantScope(ctx)).
 		Model(&domain.URL{}).
 		Where("short_code = ? AND is_active = ?", shortCode, true).
+		Update("bot_clicks", gorm.Expr("bot_clicks + ?", 1))
+	
+	if result.Error != nil {
+		return dbError(result.Error)
+	}
+	
+	if result.RowsAffected == 0 {
+		return domain.ErrURLNotFound
+	}
+	
+	return nil
+}
+
+// IncrementPrefetchCount atomically increments prefetch_hits of an active link
+func (r *urlRepository) IncrementPrefetchCount(ctx context.Context, shortCode string) error {
+	result := r.db.WithContext(ctx).Scopes(tenantScope(ctx)).
+		Model(&domain.URL{}).
+		Where("short_code = ? AND is_active = ?", shortCode, true).
+		Update("prefetch_hits", gorm.Expr("prefetch_hits + ?", 1))
+	
+	if result.Error != nil {
+		return dbError(result.Error)
+	}
+	
+	if result.RowsAffected == 0 {
+		return domain.ErrURLNotFound
+	}
+	
+	return nil
+}
+
+// IncrementFilteredClickCount atomically increments filtered_clicks of an active link
+func (r *urlRepository) IncrementFilteredClickCount(ctx context.Context, shortCode string) error {
+	result := r.db.WithContext(ctx).Scopes(tenantScope(ctx)).
+		Model(&domain.URL{}).
+		Where("short_code = ? AND is_active = ?", shortCode, true).
+		Update("filtered_clicks", gorm.Expr("filtered_clicks + ?", 1))
+	
+	if result.Error != nil {
+		return dbError(result.Error)
+	}
+	
+	if result.RowsAffected == 0 {
+		return domain.ErrURLNotFound
+	}
+	
+	return nil
+}
+
+// IncrementConversionCount atomically increments conversions
+// The visitor clicked while the link worked, so a link deactivated since still gets the conversion
+func (r *urlRepository) IncrementConversionCount(ctx context.Context, shortCode string) error {
+	result := r.db.WithContext(ctx).Scopes(tenantScope(ctx)).
+		Model(&domain.URL{}).
+		Where("short_code = ?", shortCode).
+		Update("conversions", gorm.Expr("conversions + ?", 1))
+	
+	if result.Error != nil {
+		return dbError(result.Error)
+	}
+	
+	if result.RowsAffected == 0 {
+		return domain.ErrURLNotFound
+	}
+	
+	return nil
+}
+
+// UpdateMetadata writes only the enrichment columns
+// Save would overwrite click_count with a stale value when redirects happened during the fetch
+func (r *urlRepository) UpdateMetadata(ctx context.Context, shortCode string, pageTitle, faviconURL *string) error {
+	result := r.db.WithContext(ctx).Scopes(tenantScope(ctx)).
+		Model(&domain.URL{}).
+		Where("short_code = ?", shortCode).
 		Updates(map[string]interface{}{
-			"click_count":    gorm.Expr("click_count + ?", 1),
-			"last_access_at": now,
+			"page_title":          pageTitle,
+			"favicon_url":         faviconURL,
+			"metadata_fetched_at": time.Now(),
 		})
 	
 	if result.Error != nil {
-		return domain.NewInternalError(result.Error)
+		return dbError(result.Error)
 	}
 	
 	if result.RowsAffected == 0 {
@@ -136,7 +510,7 @@ func (r *urlRepository) IncrementClickCount(ctx context.Context, shortCode strin
 func (r *urlRepository) GetStats(ctx context.Context, shortCode string) (*domain.URLStats, error) {
 	var url domain.URL
 	
-	result := r.db.WithContext(ctx).
+	result := r.db.WithContext(ctx).Scopes(tenantScope(ctx)).
 		Where("short_code = ?", shortCode).
 		First(&url)
 	
@@ -144,58 +518,388 @@ func (r *urlRepository) GetStats(ctx context.Context, shortCode string) (*domain
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 			return nil, domain.ErrURLNotFound
 		}
-		return nil, domain.NewInternalError(result.Error)
-	}
-	
-	stats := &domain.URLStats{
-		ShortCode:    url.ShortCode,
-		OriginalURL:  url.OriginalURL,
-		TotalClicks:  url.ClickCount,
-		CreatedAt:    url.CreatedAt,
-		LastAccessAt: url.LastAccessAt,
-		ExpiresAt:    url.ExpiresAt,
-		IsActive:     url.IsActive,
-	}
-	
-	// Calculate days remaining if URL has expiration
-	if url.ExpiresAt != nil {
-		remaining := int(time.Until(*url.ExpiresAt).Hours() / 24)
-		if remaining >= 0 {
-			stats.DaysRemaining = &remaining
-		}
+		return nil, dbError(result.Error)
 	}
 	
-	return stats, nil
+	return url.Stats(), nil
 }
 
 // DeleteExpired removes all URLs that have passed their expiration date
 // This should be called periodically by a cleanup job
+// Links deactivated by earlier runs are skipped, so each run only counts what it removed
 func (r *urlRepository) DeleteExpired(ctx context.Context) (int64, error) {
-	result := r.db.WithContext(ctx).
+	result := r.db.WithContext(ctx).Scopes(tenantScope(ctx)).
 		Model(&domain.URL{}).
-		Where("expires_at IS NOT NULL AND expires_at < ?", time.Now()).
+		Where("expires_at IS NOT NULL AND expires_at < ? AND is_active = ?", time.Now(), true).
 		Update("is_active", false)
 	
 	if result.Error != nil {
-		return 0, domain.NewInternalError(result.Error)
+		return 0, dbError(result.Error)
 	}
 	
 	return result.RowsAffected, nil
 }
 
+// FindExpiringBetween lists active links whose expiry falls in [from, to), soonest first
+func (r *urlRepository) FindExpiringBetween(ctx context.Context, from, to time.Time, limit int) ([]domain.URL, error) {
+	var urls []domain.URL
+	
+	query := r.db.WithContext(ctx).Scopes(tenantScope(ctx)).
+		Where("is_active = ? AND expires_at >= ? AND expires_at < ?", true, from, to).
+		Order("expires_at ASC, id ASC")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	
+	if result := query.Find(&urls); result.Error != nil {
+		return nil, dbError(result.Error)
+	}
+	
+	return urls, nil
+}
+
+// MarkExpiryNotified stamps expiry_notified_at without touching updated_at
+func (r *urlRepository) MarkExpiryNotified(ctx context.Context, shortCode string, at time.Time) error {
+	result := r.db.WithContext(ctx).Scopes(tenantScope(ctx)).
+		Model(&domain.URL{}).
+		Where("short_code = ?", shortCode).
+		UpdateColumn("expiry_notified_at", at)
+	
+	if result.Error != nil {
+		return dbError(result.Error)
+	}
+	
+	if result.RowsAffected == 0 {
+		return domain.ErrURLNotFound
+	}
+	
+	return nil
+}
+
+// FindDueForCheck pages through the active links the link check should look at, in ID order
+func (r *urlRepository) FindDueForCheck(ctx context.Context, checkedBefore time.Time, afterID uint, limit int) ([]domain.URL, error) {
+	var urls []domain.URL
+	
+	result := r.db.WithContext(ctx).Scopes(tenantScope(ctx)).
+		Where("is_active = ? AND id > ? AND (last_checked_at IS NULL OR last_checked_at < ?)", true, afterID, checkedBefore).
+		Order("id ASC").
+		Limit(limit).
+		Find(&urls)
+	
+	if result.Error != nil {
+		return nil, dbError(result.Error)
+	}
+	
+	return urls, nil
+}
+
+// RecordLinkCheck writes only the link check columns
+// Like MarkExpiryNotified it leaves updated_at alone, a check isn't a change of the link
+func (r *urlRepository) RecordLinkCheck(ctx context.Context, shortCode string, check domain.LinkCheck) error {
+	result := r.db.WithContext(ctx).Scopes(tenantScope(ctx)).
+		Model(&domain.URL{}).
+		Where("short_code = ?", shortCode).
+		UpdateColumns(map[string]interface{}{
+			"last_checked_at":  check.CheckedAt,
+			"last_status_code": check.StatusCode,
+			"is_broken":        check.Broken,
+		})
+	
+	if result.Error != nil {
+		return dbError(result.Error)
+	}
+	
+	if result.RowsAffected == 0 {
+		return domain.ErrURLNotFound
+	}
+	
+	return nil
+}
+
 // ExistsByShortCode checks if a short code exists without loading the full record
 // More efficient than FindByShortCode when you only need existence check
 func (r *urlRepository) ExistsByShortCode(ctx context.Context, shortCode string) (bool, error) {
 	var count int64
 	
-	result := r.db.WithContext(ctx).
+	result := r.db.WithContext(ctx).Scopes(tenantScope(ctx)).
 		Model(&domain.URL{}).
 		Where("short_code = ? AND is_active = ?", shortCode, true).
 		Count(&count)
 	
 	if result.Error != nil {
-		return false, domain.NewInternalError(result.Error)
+		return false, dbError(result.Error)
 	}
 	
 	return count > 0, nil
-}
\ No newline at end of file
+}
+
+// existsManyChunkSize caps the codes bound to one IN query
+const existsManyChunkSize = 1000
+
+// ExistsManyByShortCode checks a batch of candidate codes with one IN query per 1000 distinct codes
+// Unlike ExistsByShortCode it ignores is_active, because the unique index covers inactive rows as well
+func (r *urlRepository) ExistsManyByShortCode(ctx context.Context, shortCodes []string) (map[string]bool, error) {
+	taken := make(map[string]bool, len(shortCodes))
+	
+	// Duplicates would only bloat the query
+	seen := make(map[string]bool, len(shortCodes))
+	unique := make([]string, 0, len(shortCodes))
+	for _, code := range shortCodes {
+		if !seen[code] {
+			seen[code] = true
+			unique = append(unique, code)
+		}
+	}
+	
+	for start := 0; start < len(unique); start += existsManyChunkSize {
+		end := start + existsManyChunkSize
+		if end > len(unique) {
+			end = len(unique)
+		}
+	
+		var found []string
+		result := r.db.WithContext(ctx).Scopes(tenantScope(ctx)).
+			Model(&domain.URL{}).
+			Where("short_code IN ?", unique[start:end]).
+			Pluck("short_code", &found)
+	
+		if result.Error != nil {
+			return nil, dbError(result.Error)
+		}
+	
+		for _, code := range found {
+			taken[code] = true
+		}
+	}
+	
+	return taken, nil
+}
+
+// ForEach iterates over URLs matching the filter using keyset pagination on the primary key
+// Only one batch is held in memory at a time, so it is safe to use on the full table
+func (r *urlRepository) ForEach(ctx context.Context, filter domain.URLFilter, batchSize int, fn func(*domain.URL) error) error {
+	if batchSize <= 0 {
+		batchSize = 500
+	}
+	
+	var lastID uint
+	for {
+		var batch []domain.URL
+		
+		query := applyURLFilter(r.db.WithContext(ctx).Scopes(tenantScope(ctx)).Where("id > ?", lastID), filter)
+		result := query.Order("id ASC").Limit(batchSize).Find(&batch)
+		if result.Error != nil {
+			return dbError(result.Error)
+		}
+		
+		for i := range batch {
+			if err := fn(&batch[i]); err != nil {
+				return err
+			}
+		}
+		
+		// A short batch means we reached the end of the result set
+		if len(batch) < batchSize {
+			return nil
+		}
+		lastID = batch[len(batch)-1].ID
+	}
+}
+
+// destinationPathExpr extracts the path of original_url, empty when it has none
+const destinationPathExpr = `coalesce(substring(original_url from '^[A-Za-z][A-Za-z0-9+.-]*://[^/?#]*([^?#]*)'), '')`
+
+// applyURLFilter adds the constraints of filter to query
+func applyURLFilter(query *gorm.DB, filter domain.URLFilter) *gorm.DB {
+	if len(filter.ShortCodes) > 0 {
+		query = query.Where("short_code IN ?", filter.ShortCodes)
+	}
+	if filter.CreatorIP != "" {
+		query = query.Where("creator_ip = ?", filter.CreatorIP)
+	}
+	if host := strings.ToLower(filter.DestinationHost); host != "" {
+		if filter.IncludeSubdomains {
+			// The reversed host starts with the reversed ".host" for subdomains, which the reverse() index serves
+			query = query.Where("(destination_host = ? OR reverse(destination_host) LIKE ?)", host, likeEscaper.Replace(reverse("."+host))+"%")
+		} else {
+			query = query.Where("destination_host = ?", host)
+		}
+	}
+	if filter.PathPrefix != "" {
+		query = query.Where(destinationPathExpr+" LIKE ?", likeEscaper.Replace(filter.PathPrefix)+"%")
+	}
+	if filter.CreatedFrom != nil {
+		query = query.Where("created_at >= ?", *filter.CreatedFrom)
+	}
+	if filter.CreatedTo != nil {
+		query = query.Where("created_at < ?", *filter.CreatedTo)
+	}
+	if filter.Search != "" {
+		pattern := "%" + likeEscaper.Replace(filter.Search) + "%"
+		query = query.Where("(short_code ILIKE ? OR original_url ILIKE ? OR title ILIKE ? OR description ILIKE ?)",
+			pattern, pattern, pattern, pattern)
+	}
+	if filter.Broken != nil {
+		query = query.Where("is_broken = ?", *filter.Broken)
+	}
+	return query
+}
+
+// reverse returns s with its bytes in reverse order, as Postgres reverse() does for ASCII hosts
+func reverse(s string) string {
+	b := []byte(s)
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+	return string(b)
+}
+
+// likeEscaper makes the wildcards of a search term match literally; backslash is ILIKE's default escape
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// DeactivateMatching sets is_active to false on every active link matching the filter in one UPDATE
+// RETURNING hands back the deactivated rows, so no second query can race with new matches
+func (r *urlRepository) DeactivateMatching(ctx context.Context, filter domain.URLFilter) ([]domain.URL, error) {
+	if filter.IsEmpty() {
+		return nil, errEmptyFilter
+	}
+	
+	var urls []domain.URL
+	result := applyURLFilter(conn(ctx, r.db).Scopes(tenantScope(ctx)).Model(&urls).Clauses(clause.Returning{}), filter).
+		Where("is_active = ?", true).
+		Update("is_active", false)
+	
+	if result.Error != nil {
+		return nil, dbError(result.Error)
+	}
+	
+	return urls, nil
+}
+
+// CountActiveMatching counts the active links matching the filter
+func (r *urlRepository) CountActiveMatching(ctx context.Context, filter domain.URLFilter) (int64, error) {
+	if filter.IsEmpty() {
+		return 0, errEmptyFilter
+	}
+	
+	var count int64
+	result := applyURLFilter(conn(ctx, r.db).Scopes(tenantScope(ctx)).Model(&domain.URL{}), filter).
+		Where("is_active = ?", true).
+		Count(&count)
+	
+	if result.Error != nil {
+		return 0, dbError(result.Error)
+	}
+	
+	return count, nil
+}
+
+// FindActiveMatching lists a page of the active links matching the filter, newest first
+func (r *urlRepository) FindActiveMatching(ctx context.Context, filter domain.URLFilter, limit, offset int) ([]domain.URL, error) {
+	var urls []domain.URL
+	
+	result := applyURLFilter(r.db.WithContext(ctx).Scopes(tenantScope(ctx)), filter).
+		Where("is_active = ?", true).
+		Order("created_at DESC, id DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&urls)
+	
+	if result.Error != nil {
+		return nil, dbError(result.Error)
+	}
+	
+	return urls, nil
+}
+
+// CountURLs returns the number of active links
+func (r *urlRepository) CountURLs(ctx context.Context) (int64, error) {
+	var count int64
+	
+	result := r.db.WithContext(ctx).Scopes(tenantScope(ctx)).
+		Model(&domain.URL{}).
+		Where("is_active = ?", true).
+		Count(&count)
+	
+	if result.Error != nil {
+		return 0, dbError(result.Error)
+	}
+	
+	return count, nil
+}
+
+// SumClicks adds up click_count over every link, including deactivated ones
+// Clicks on links that were later removed still happened, so they stay in the total
+func (r *urlRepository) SumClicks(ctx context.Context) (int64, error) {
+	var total int64
+	
+	result := r.db.WithContext(ctx).Scopes(tenantScope(ctx)).
+		Model(&domain.URL{}).
+		Select("COALESCE(SUM(click_count), 0)").
+		Scan(&total)
+	
+	if result.Error != nil {
+		return 0, dbError(result.Error)
+	}
+	
+	return total, nil
+}
+
+// TopByClicks ranks active links by their click events since the given time
+// click_count is a lifetime counter, so windowed rankings are computed from click_events
+func (r *urlRepository) TopByClicks(ctx context.Context, since time.Time, limit int) ([]domain.TopLink, error) {
+	var links []domain.TopLink
+	
+	result := r.db.WithContext(ctx).Scopes(tenantColumnScope(ctx, "u.tenant_id")).
+		Table("click_events AS c").
+		Select("c.short_code, u.original_url, COUNT(*) AS clicks").
+		Joins("JOIN urls u ON u.short_code = c.short_code AND u.tenant_id = c.tenant_id").
+		Where("c.clicked_at >= ? AND u.is_active = ?", since, true).
+		Group("c.short_code, u.original_url").
+		Order("clicks DESC, c.short_code ASC").
+		Limit(limit).
+		Scan(&links)
+	
+	if result.Error != nil {
+		return nil, dbError(result.Error)
+	}
+	
+	return links, nil
+}
+
+// TopByClickCount returns the most clicked links that still redirect, by the lifetime click_count
+// Unlike TopByClicks it reads no click events, so it stays cheap however many clicks were recorded
+func (r *urlRepository) TopByClickCount(ctx context.Context, limit int) ([]domain.URL, error) {
+	var urls []domain.URL
+	
+	result := r.db.WithContext(ctx).Scopes(tenantScope(ctx)).
+		Where("is_active = ? AND (expires_at IS NULL OR expires_at > ?)", true, time.Now()).
+		Order("click_count DESC, short_code ASC").
+		Limit(limit).
+		Find(&urls)
+	
+	if result.Error != nil {
+		return nil, dbError(result.Error)
+	}
+	
+	return urls, nil
+}
+
+// CreatedBetween groups links created in [from, to) by UTC day
+func (r *urlRepository) CreatedBetween(ctx context.Context, from, to time.Time) ([]domain.DailyCount, error) {
+	var counts []domain.DailyCount
+	
+	result := r.db.WithContext(ctx).Scopes(tenantScope(ctx)).
+		Model(&domain.URL{}).
+		Select("to_char(created_at AT TIME ZONE 'UTC', 'YYYY-MM-DD') AS date, COUNT(*) AS count").
+		Where("created_at >= ? AND created_at < ?", from, to).
+		Group("date").
+		Order("date ASC").
+		Scan(&counts)
+	
+	if result.Error != nil {
+		return nil, dbError(result.Error)
+	}
+	
+	return counts, nil
+}
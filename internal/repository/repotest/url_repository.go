@@ -0,0 +1,868 @@
+// Package repotest holds contract tests that every repository implementation must pass
+// An implementation's own test calls the Run functions with a factory handing out empty repositories.
+package repotest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/repository"
+)
+
+// URLRepositoryFactory returns a repository with no links in it
+// It is called once per subtest, so state never leaks from one check into the next
+type URLRepositoryFactory func(t *testing.T) repository.URLRepository
+
+// RunURLRepository checks the documented semantics of every URLRepository method
+// TopByClicks is left out because it ranks click events, which belong to the ClickRepository
+func RunURLRepository(t *testing.T, newRepo URLRepositoryFactory) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, repo repository.URLRepository)
+	}{
+		{"CreateAndFind", testCreateAndFind},
+		{"CreateDuplicateShortCode", testCreateDuplicateShortCode},
+		{"CreateBundleIsAtomic", testCreateBundleIsAtomic},
+		{"NotFound", testNotFound},
+		{"FindByShortCodeFold", testFindByShortCodeFold},
+		{"SoftDeleteVisibility", testSoftDeleteVisibility},
+		{"SetActive", testSetActive},
+		{"Update", testUpdate},
+		{"UpdateMissingDoesNotInsert", testUpdateMissingDoesNotInsert},
+		{"ConcurrentIncrements", testConcurrentIncrements},
+		{"IncrementBotClickCount", testIncrementBotClickCount},
+		{"IncrementPrefetchCount", testIncrementPrefetchCount},
+		{"IncrementFilteredClickCount", testIncrementFilteredClickCount},
+		{"IncrementConversionCount", testIncrementConversionCount},
+		{"ReferrerCounts", testReferrerCounts},
+		{"ReferrerCountsCapped", testReferrerCountsCapped},
+		{"UpdateMetadataKeepsCounters", testUpdateMetadataKeepsCounters},
+		{"GetStats", testGetStats},
+		{"DeleteExpired", testDeleteExpired},
+		{"ResetStats", testResetStats},
+		{"HardDelete", testHardDelete},
+		{"HardDeleteInactive", testHardDeleteInactive},
+		{"FindExpiringBetween", testFindExpiringBetween},
+		{"MarkExpiryNotified", testMarkExpiryNotified},
+		{"LinkChecks", testLinkChecks},
+		{"ExistsManyByShortCode", testExistsManyByShortCode},
+		{"ForEach", testForEach},
+		{"ForEachSearch", testForEachSearch},
+		{"DeactivateMatching", testDeactivateMatching},
+		{"FindByCreatorIP", testFindByCreatorIP},
+		{"CountsAndSums", testCountsAndSums},
+		{"TopByClickCount", testTopByClickCount},
+		{"CreatedBetween", testCreatedBetween},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newRepo(t))
+		})
+	}
+}
+
+// newLink returns an active link that was never clicked
+func newLink(shortCode string) *domain.URL {
+	return &domain.URL{
+		ShortCode:   shortCode,
+		OriginalURL: "https://example.com/" + shortCode,
+		CreatorIP:   "203.0.113.9",
+		IsActive:    true,
+	}
+}
+
+// create stores links, failing the test on any error
+func create(t *testing.T, repo repository.URLRepository, links ...*domain.URL) {
+	t.Helper()
+	for _, link := range links {
+		require.NoError(t, repo.Create(context.Background(), link), link.ShortCode)
+	}
+}
+
+// expiringIn returns a link expiring d from now
+func expiringIn(shortCode string, d time.Duration) *domain.URL {
+	link := newLink(shortCode)
+	at := time.Now().Add(d)
+	link.ExpiresAt = &at
+	return link
+}
+
+func testCreateAndFind(t *testing.T, repo repository.URLRepository) {
+	ctx := context.Background()
+	link := newLink("abc123")
+	create(t, repo, link)
+	assert.NotZero(t, link.ID, "Create assigns the ID")
+	assert.False(t, link.CreatedAt.IsZero(), "Create sets created_at")
+
+	found, err := repo.FindByShortCode(ctx, "abc123")
+	require.NoError(t, err)
+	assert.Equal(t, link.ID, found.ID)
+	assert.Equal(t, "https://example.com/abc123", found.OriginalURL)
+	assert.True(t, found.IsActive)
+	assert.Zero(t, found.ClickCount)
+	assert.Nil(t, found.LastAccessAt)
+
+	byDestination, err := repo.FindByOriginalURL(ctx, "https://example.com/abc123")
+	require.NoError(t, err)
+	assert.Equal(t, "abc123", byDestination.ShortCode)
+
+	exists, err := repo.ExistsByShortCode(ctx, "abc123")
+	require.NoError(t, err)
+	assert.True(t, exists)
+}
+
+func testCreateDuplicateShortCode(t *testing.T, repo repository.URLRepository) {
+	create(t, repo, newLink("abc123"))
+
+	duplicate := newLink("abc123")
+	duplicate.OriginalURL = "https://example.com/other"
+	err := repo.Create(context.Background(), duplicate)
+
+	assert.ErrorIs(t, err, domain.ErrShortCodeTaken)
+}
+
+func testCreateBundleIsAtomic(t *testing.T, repo repository.URLRepository) {
+	ctx := context.Background()
+	create(t, repo, newLink("taken"))
+
+	bundle := newLink("bundle")
+	err := repo.CreateBundle(ctx, bundle, []*domain.URL{newLink("member1"), newLink("taken")})
+	assert.ErrorIs(t, err, domain.ErrShortCodeTaken)
+
+	// Neither the bundle nor the member inserted before the conflict may remain
+	for _, code := range []string{"bundle", "member1"} {
+		_, err := repo.FindAnyByShortCode(ctx, code)
+		assert.ErrorIs(t, err, domain.ErrURLNotFound, code)
+	}
+
+	require.NoError(t, repo.CreateBundle(ctx, newLink("bundle"), []*domain.URL{newLink("member1"), newLink("member2")}))
+	taken, err := repo.ExistsManyByShortCode(ctx, []string{"bundle", "member1", "member2"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{"bundle": true, "member1": true, "member2": true}, taken)
+}
+
+func testNotFound(t *testing.T, repo repository.URLRepository) {
+	ctx := context.Background()
+	title := "Title"
+
+	checks := map[string]func() error{
+		"FindByShortCode":             func() error { _, err := repo.FindByShortCode(ctx, "missing"); return err },
+		"FindAnyByShortCode":          func() error { _, err := repo.FindAnyByShortCode(ctx, "missing"); return err },
+		"FindByShortCodeFold":         func() error { _, err := repo.FindByShortCodeFold(ctx, "missing"); return err },
+		"FindByOriginalURL":           func() error { _, err := repo.FindByOriginalURL(ctx, "https://example.com/missing"); return err },
+		"SetActive":                   func() error { _, err := repo.SetActive(ctx, "missing", true); return err },
+		"Delete":                      func() error { return repo.Delete(ctx, "missing") },
+		"IncrementClickCount":         func() error { return repo.IncrementClickCount(ctx, "missing", "") },
+		"IncrementBotClickCount":      func() error { return repo.IncrementBotClickCount(ctx, "missing") },
+		"IncrementPrefetchCount":      func() error { return repo.IncrementPrefetchCount(ctx, "missing") },
+		"IncrementFilteredClickCount": func() error { return repo.IncrementFilteredClickCount(ctx, "missing") },
+		"IncrementConversionCount":    func() error { return repo.IncrementConversionCount(ctx, "missing") },
+		"GetStats":                    func() error { _, err := repo.GetStats(ctx, "missing"); return err },
+		"MarkExpiryNotified":          func() error { return repo.MarkExpiryNotified(ctx, "missing", time.Now()) },
+		"RecordLinkCheck":             func() error { return repo.RecordLinkCheck(ctx, "missing", domain.LinkCheck{CheckedAt: time.Now()}) },
+		"UpdateMetadata":              func() error { return repo.UpdateMetadata(ctx, "missing", &title, nil) },
+	}
+	for name, check := range checks {
+		assert.ErrorIs(t, check(), domain.ErrURLNotFound, name)
+	}
+
+	exists, err := repo.ExistsByShortCode(ctx, "missing")
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func testFindByShortCodeFold(t *testing.T, repo repository.URLRepository) {
+	ctx := context.Background()
+	create(t, repo, newLink("PromoX7"))
+
+	link, err := repo.FindByShortCodeFold(ctx, "promox7")
+	require.NoError(t, err)
+	assert.Equal(t, "PromoX7", link.ShortCode)
+
+	// A second link differing only in case makes the folded code ambiguous
+	create(t, repo, newLink("PROMOX7"))
+	_, err = repo.FindByShortCodeFold(ctx, "promox7")
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+
+	require.NoError(t, repo.Delete(ctx, "PROMOX7"))
+	_, err = repo.FindByShortCodeFold(ctx, "promox7")
+	assert.ErrorIs(t, err, domain.ErrURLNotFound, "a deleted code stays a candidate and keeps the match ambiguous")
+
+	create(t, repo, newLink("solo01"))
+	require.NoError(t, repo.Delete(ctx, "solo01"))
+	_, err = repo.FindByShortCodeFold(ctx, "SOLO01")
+	assert.ErrorIs(t, err, domain.ErrURLNotFound, "the only match is deleted")
+}
+
+func testSoftDeleteVisibility(t *testing.T, repo repository.URLRepository) {
+	ctx := context.Background()
+	create(t, repo, newLink("abc123"))
+	require.NoError(t, repo.IncrementClickCount(ctx, "abc123", ""))
+
+	require.NoError(t, repo.Delete(ctx, "abc123"))
+	// Deleting again is not an error, the row is still there
+	assert.NoError(t, repo.Delete(ctx, "abc123"))
+
+	_, err := repo.FindByShortCode(ctx, "abc123")
+	assert.ErrorIs(t, err, domain.ErrURLNotFound, "FindByShortCode hides deleted links")
+	_, err = repo.FindByOriginalURL(ctx, "https://example.com/abc123")
+	assert.ErrorIs(t, err, domain.ErrURLNotFound, "deleted links are not reused for dedup")
+	assert.ErrorIs(t, repo.IncrementClickCount(ctx, "abc123", ""), domain.ErrURLNotFound, "deleted links are not counted")
+	assert.ErrorIs(t, repo.IncrementBotClickCount(ctx, "abc123"), domain.ErrURLNotFound)
+
+	link, err := repo.FindAnyByShortCode(ctx, "abc123")
+	require.NoError(t, err)
+	assert.False(t, link.IsActive)
+
+	stats, err := repo.GetStats(ctx, "abc123")
+	require.NoError(t, err, "stats stay readable after a delete")
+	assert.False(t, stats.IsActive)
+	assert.Equal(t, int64(1), stats.TotalClicks)
+
+	exists, err := repo.ExistsByShortCode(ctx, "abc123")
+	require.NoError(t, err)
+	assert.False(t, exists, "ExistsByShortCode only sees active links")
+	taken, err := repo.ExistsManyByShortCode(ctx, []string{"abc123"})
+	require.NoError(t, err)
+	assert.True(t, taken["abc123"], "deleted codes can't be reused")
+	assert.ErrorIs(t, repo.Create(ctx, newLink("abc123")), domain.ErrShortCodeTaken)
+}
+
+func testSetActive(t *testing.T, repo repository.URLRepository) {
+	ctx := context.Background()
+	create(t, repo, newLink("abc123"))
+
+	link, err := repo.SetActive(ctx, "abc123", false)
+	require.NoError(t, err)
+	assert.False(t, link.IsActive)
+	_, err = repo.FindByShortCode(ctx, "abc123")
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+
+	// Setting the current state again is not "not found"
+	_, err = repo.SetActive(ctx, "abc123", false)
+	require.NoError(t, err)
+
+	link, err = repo.SetActive(ctx, "abc123", true)
+	require.NoError(t, err)
+	assert.True(t, link.IsActive)
+	_, err = repo.FindByShortCode(ctx, "abc123")
+	assert.NoError(t, err)
+}
+
+func testUpdate(t *testing.T, repo repository.URLRepository) {
+	ctx := context.Background()
+	link := newLink("abc123")
+	create(t, repo, link)
+
+	expiresAt := time.Now().Add(48 * time.Hour).UTC().Truncate(time.Second)
+	link.OriginalURL = "https://example.com/changed"
+	link.ExpiresAt = &expiresAt
+	link.ForwardQuery = true
+	require.NoError(t, repo.Update(ctx, link))
+
+	found, err := repo.FindByShortCode(ctx, "abc123")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/changed", found.OriginalURL)
+	require.NotNil(t, found.ExpiresAt)
+	assert.True(t, expiresAt.Equal(*found.ExpiresAt))
+	assert.True(t, found.ForwardQuery)
+}
+
+func testUpdateMissingDoesNotInsert(t *testing.T, repo repository.URLRepository) {
+	ctx := context.Background()
+
+	assert.ErrorIs(t, repo.Update(ctx, newLink("ghost")), domain.ErrURLNotFound, "link that was never stored")
+
+	stale := newLink("ghost")
+	stale.ID = 4242
+	assert.ErrorIs(t, repo.Update(ctx, stale), domain.ErrURLNotFound, "ID of a row that doesn't exist")
+
+	_, err := repo.FindAnyByShortCode(ctx, "ghost")
+	assert.ErrorIs(t, err, domain.ErrURLNotFound, "Update must not create the link")
+}
+
+func testConcurrentIncrements(t *testing.T, repo repository.URLRepository) {
+	ctx := context.Background()
+	create(t, repo, newLink("abc123"))
+
+	const clicks = 50
+	var wg sync.WaitGroup
+	errs := make(chan error, clicks)
+	for i := 0; i < clicks; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- repo.IncrementClickCount(ctx, "abc123", "")
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	link, err := repo.FindByShortCode(ctx, "abc123")
+	require.NoError(t, err)
+	assert.Equal(t, int64(clicks), link.ClickCount, "no increment may be lost")
+	require.NotNil(t, link.LastAccessAt)
+	assert.WithinDuration(t, time.Now(), *link.LastAccessAt, time.Minute)
+}
+
+func testIncrementBotClickCount(t *testing.T, repo repository.URLRepository) {
+	ctx := context.Background()
+	create(t, repo, newLink("abc123"))
+
+	require.NoError(t, repo.IncrementBotClickCount(ctx, "abc123"))
+	require.NoError(t, repo.IncrementBotClickCount(ctx, "abc123"))
+
+	link, err := repo.FindByShortCode(ctx, "abc123")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), link.BotClicks)
+	assert.Zero(t, link.ClickCount, "bots are not counted as clicks")
+	assert.Nil(t, link.LastAccessAt, "bots leave last_access_at alone")
+}
+
+func testIncrementPrefetchCount(t *testing.T, repo repository.URLRepository) {
+	ctx := context.Background()
+	create(t, repo, newLink("abc123"))
+
+	require.NoError(t, repo.IncrementPrefetchCount(ctx, "abc123"))
+
+	stats, err := repo.GetStats(ctx, "abc123")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), stats.PrefetchHits)
+	assert.Zero(t, stats.TotalClicks, "prefetches are not counted as clicks")
+	assert.Zero(t, stats.BotClicks)
+	assert.Nil(t, stats.LastAccessAt, "prefetches leave last_access_at alone")
+}
+
+func testIncrementFilteredClickCount(t *testing.T, repo repository.URLRepository) {
+	ctx := context.Background()
+	create(t, repo, newLink("abc123"))
+
+	require.NoError(t, repo.IncrementClickCount(ctx, "abc123", ""))
+	require.NoError(t, repo.IncrementFilteredClickCount(ctx, "abc123"))
+	require.NoError(t, repo.IncrementFilteredClickCount(ctx, "abc123"))
+
+	stats, err := repo.GetStats(ctx, "abc123")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), stats.TotalClicks, "filtered clicks are not counted as clicks")
+	assert.Equal(t, int64(2), stats.FilteredClicks)
+}
+
+func testIncrementConversionCount(t *testing.T, repo repository.URLRepository) {
+	ctx := context.Background()
+	create(t, repo, newLink("abc123"))
+
+	require.NoError(t, repo.IncrementClickCount(ctx, "abc123", ""))
+	require.NoError(t, repo.IncrementConversionCount(ctx, "abc123"))
+	_, err := repo.SetActive(ctx, "abc123", false)
+	require.NoError(t, err)
+	require.NoError(t, repo.IncrementConversionCount(ctx, "abc123"), "conversions of earlier clicks still count")
+
+	stats, err := repo.GetStats(ctx, "abc123")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), stats.TotalClicks)
+	assert.Equal(t, int64(2), stats.Conversions)
+}
+
+func testReferrerCounts(t *testing.T, repo repository.URLRepository) {
+	ctx := context.Background()
+	create(t, repo, newLink("abc123"))
+
+	require.NoError(t, repo.IncrementClickCount(ctx, "abc123", "news.example"))
+	require.NoError(t, repo.IncrementClickCount(ctx, "abc123", "blog.example"))
+	require.NoError(t, repo.IncrementClickCount(ctx, "abc123", "news.example"))
+	require.NoError(t, repo.IncrementClickCount(ctx, "abc123", ""))
+
+	stats, err := repo.GetStats(ctx, "abc123")
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), stats.TotalClicks)
+	assert.Equal(t, "news.example", stats.LastReferrer, "direct visits keep the last referrer")
+	assert.Equal(t, domain.ReferrerCounts{"news.example": 2, "blog.example": 1}, stats.ReferrerCounts)
+}
+
+func testReferrerCountsCapped(t *testing.T, repo repository.URLRepository) {
+	ctx := context.Background()
+	create(t, repo, newLink("abc123"))
+
+	for i := 0; i < domain.MaxReferrerCounts+3; i++ {
+		require.NoError(t, repo.IncrementClickCount(ctx, "abc123", fmt.Sprintf("site%02d.example", i)))
+	}
+	// Hosts counted before the cap was reached keep their own key
+	require.NoError(t, repo.IncrementClickCount(ctx, "abc123", "site00.example"))
+
+	stats, err := repo.GetStats(ctx, "abc123")
+	require.NoError(t, err)
+	assert.Len(t, stats.ReferrerCounts, domain.MaxReferrerCounts+1, "the named hosts and the pooled rest")
+	assert.Equal(t, int64(2), stats.ReferrerCounts["site00.example"])
+	assert.Equal(t, int64(3), stats.ReferrerCounts[domain.OtherReferrers])
+	assert.NotContains(t, stats.ReferrerCounts, fmt.Sprintf("site%02d.example", domain.MaxReferrerCounts))
+	assert.Equal(t, "site00.example", stats.LastReferrer)
+}
+
+func testUpdateMetadataKeepsCounters(t *testing.T, repo repository.URLRepository) {
+	ctx := context.Background()
+	create(t, repo, newLink("abc123"))
+	require.NoError(t, repo.IncrementClickCount(ctx, "abc123", ""))
+
+	title := "Example"
+	require.NoError(t, repo.UpdateMetadata(ctx, "abc123", &title, nil))
+
+	link, err := repo.FindByShortCode(ctx, "abc123")
+	require.NoError(t, err)
+	require.NotNil(t, link.PageTitle)
+	assert.Equal(t, "Example", *link.PageTitle)
+	assert.Nil(t, link.FaviconURL)
+	assert.NotNil(t, link.MetadataFetchedAt)
+	assert.Equal(t, int64(1), link.ClickCount, "metadata writes must not reset clicks")
+
+	require.NoError(t, repo.UpdateMetadata(ctx, "abc123", nil, nil))
+	link, err = repo.FindByShortCode(ctx, "abc123")
+	require.NoError(t, err)
+	assert.Nil(t, link.PageTitle, "nil is written as NULL")
+}
+
+func testGetStats(t *testing.T, repo repository.URLRepository) {
+	ctx := context.Background()
+	variants := newLink("split")
+	variants.Variants = domain.Variants{{URL: "https://example.com/a", Weight: 1}, {URL: "https://example.com/b", Weight: 3}}
+	create(t, repo,
+		expiringIn("tendays", 10*24*time.Hour+time.Hour),
+		expiringIn("today", time.Hour),
+		expiringIn("expired", -time.Hour),
+		newLink("forever"),
+		variants,
+	)
+	require.NoError(t, repo.IncrementClickCount(ctx, "tendays", ""))
+	require.NoError(t, repo.IncrementBotClickCount(ctx, "tendays"))
+
+	// Days remaining are whole days left, rounded down; expired and non-expiring links have none
+	expected := map[string]*int{"tendays": intPtr(10), "today": intPtr(0), "expired": nil, "forever": nil}
+	for code, days := range expected {
+		stats, err := repo.GetStats(ctx, code)
+		require.NoError(t, err, code)
+		assert.Equal(t, days, stats.DaysRemaining, code)
+		assert.Equal(t, code, stats.ShortCode)
+		assert.Equal(t, "https://example.com/"+code, stats.OriginalURL)
+	}
+
+	stats, err := repo.GetStats(ctx, "tendays")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), stats.TotalClicks)
+	assert.Equal(t, int64(1), stats.BotClicks)
+	assert.NotNil(t, stats.LastAccessAt)
+	assert.True(t, stats.IsActive)
+	assert.False(t, stats.CreatedAt.IsZero())
+
+	stats, err = repo.GetStats(ctx, "split")
+	require.NoError(t, err)
+	require.Len(t, stats.Variants, 2)
+	assert.Equal(t, domain.VariantStats{Index: 1, URL: "https://example.com/b", Weight: 3}, stats.Variants[1])
+}
+
+// intPtr returns a pointer to n
+func intPtr(n int) *int {
+	return &n
+}
+
+func testDeleteExpired(t *testing.T, repo repository.URLRepository) {
+	ctx := context.Background()
+	create(t, repo,
+		expiringIn("expired", -time.Hour),
+		expiringIn("gone", -2*time.Hour),
+		expiringIn("future", time.Hour),
+		newLink("forever"),
+	)
+	require.NoError(t, repo.Delete(ctx, "gone"))
+
+	removed, err := repo.DeleteExpired(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), removed, "only active expired links are counted")
+
+	removed, err = repo.DeleteExpired(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, removed, "a second run finds nothing left to do")
+
+	_, err = repo.FindByShortCode(ctx, "expired")
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+	for _, code := range []string{"future", "forever"} {
+		_, err := repo.FindByShortCode(ctx, code)
+		assert.NoError(t, err, code)
+	}
+}
+
+func testResetStats(t *testing.T, repo repository.URLRepository) {
+	ctx := context.Background()
+	create(t, repo, newLink("abc123"), newLink("keep01"))
+	require.NoError(t, repo.IncrementClickCount(ctx, "abc123", "news.example.com"))
+	require.NoError(t, repo.IncrementClickCount(ctx, "abc123", ""))
+	require.NoError(t, repo.IncrementBotClickCount(ctx, "abc123"))
+	require.NoError(t, repo.IncrementClickCount(ctx, "keep01", ""))
+
+	prior, err := repo.ResetStats(ctx, "abc123")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), prior.ClickCount, "the totals before the reset are returned")
+	assert.Equal(t, int64(1), prior.BotClicks)
+
+	stats, err := repo.GetStats(ctx, "abc123")
+	require.NoError(t, err)
+	assert.Zero(t, stats.TotalClicks)
+	assert.Zero(t, stats.BotClicks)
+	assert.Nil(t, stats.LastAccessAt)
+	assert.Empty(t, stats.LastReferrer)
+	assert.Empty(t, stats.ReferrerCounts)
+	assert.Equal(t, "https://example.com/abc123", stats.OriginalURL, "the link itself is kept")
+
+	link, err := repo.FindAnyByShortCode(ctx, "abc123")
+	require.NoError(t, err)
+	assert.NotNil(t, link.StatsResetAt, "the reset is stamped, so cached stats from before it aren't read")
+
+	stats, err = repo.GetStats(ctx, "keep01")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), stats.TotalClicks, "other links are untouched")
+
+	_, err = repo.ResetStats(ctx, "nope01")
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+}
+
+func testHardDelete(t *testing.T, repo repository.URLRepository) {
+	ctx := context.Background()
+	create(t, repo, newLink("abc123"), newLink("keep01"))
+	require.NoError(t, repo.Delete(ctx, "abc123"))
+
+	require.NoError(t, repo.HardDelete(ctx, "abc123"), "soft-deleted links can be erased")
+	_, err := repo.FindAnyByShortCode(ctx, "abc123")
+	assert.ErrorIs(t, err, domain.ErrURLNotFound, "the row is gone, not just hidden")
+	_, err = repo.GetStats(ctx, "abc123")
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+	assert.ErrorIs(t, repo.HardDelete(ctx, "abc123"), domain.ErrURLNotFound, "erasing twice reports the missing row")
+
+	_, err = repo.FindByShortCode(ctx, "keep01")
+	assert.NoError(t, err, "other links are untouched")
+	assert.NoError(t, repo.Create(ctx, newLink("abc123")), "an erased code is free again")
+}
+
+func testHardDeleteInactive(t *testing.T, repo repository.URLRepository) {
+	ctx := context.Background()
+	create(t, repo, newLink("gone01"), newLink("gone02"), newLink("gone03"), newLink("active"))
+	for _, code := range []string{"gone01", "gone02", "gone03"} {
+		require.NoError(t, repo.Delete(ctx, code))
+	}
+
+	erased, err := repo.HardDeleteInactive(ctx, time.Now().Add(-time.Hour), 10)
+	require.NoError(t, err)
+	assert.Empty(t, erased, "links deleted after the cutoff are kept")
+
+	cutoff := time.Now().Add(time.Hour)
+	erased, err = repo.HardDeleteInactive(ctx, cutoff, 2)
+	require.NoError(t, err)
+	assert.Len(t, erased, 2, "the limit bounds a batch")
+	rest, err := repo.HardDeleteInactive(ctx, cutoff, 10)
+	require.NoError(t, err)
+	var codes []string
+	for _, link := range append(erased, rest...) {
+		codes = append(codes, link.ShortCode)
+	}
+	assert.ElementsMatch(t, []string{"gone01", "gone02", "gone03"}, codes)
+
+	for _, code := range []string{"gone01", "gone02", "gone03"} {
+		_, err := repo.FindAnyByShortCode(ctx, code)
+		assert.ErrorIs(t, err, domain.ErrURLNotFound, code)
+	}
+	_, err = repo.FindByShortCode(ctx, "active")
+	assert.NoError(t, err, "active links are never erased")
+}
+
+func testFindExpiringBetween(t *testing.T, repo repository.URLRepository) {
+	ctx := context.Background()
+	create(t, repo,
+		expiringIn("third", 3*time.Hour),
+		expiringIn("first", time.Hour),
+		expiringIn("second", 2*time.Hour),
+		expiringIn("later", 48*time.Hour),
+		expiringIn("past", -time.Hour),
+		expiringIn("deleted", 90*time.Minute),
+		newLink("forever"),
+	)
+	require.NoError(t, repo.Delete(ctx, "deleted"))
+
+	now := time.Now()
+	links, err := repo.FindExpiringBetween(ctx, now, now.Add(24*time.Hour), 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"first", "second", "third"}, shortCodes(links))
+
+	links, err = repo.FindExpiringBetween(ctx, now, now.Add(24*time.Hour), 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"first", "second"}, shortCodes(links))
+}
+
+// shortCodes lists the codes of links in order
+func shortCodes(links []domain.URL) []string {
+	codes := make([]string, len(links))
+	for i, link := range links {
+		codes[i] = link.ShortCode
+	}
+	return codes
+}
+
+func testMarkExpiryNotified(t *testing.T, repo repository.URLRepository) {
+	ctx := context.Background()
+	create(t, repo, expiringIn("abc123", time.Hour))
+
+	at := time.Now().UTC().Truncate(time.Second)
+	require.NoError(t, repo.MarkExpiryNotified(ctx, "abc123", at))
+
+	link, err := repo.FindByShortCode(ctx, "abc123")
+	require.NoError(t, err)
+	require.NotNil(t, link.ExpiryNotifiedAt)
+	assert.True(t, at.Equal(*link.ExpiryNotifiedAt))
+}
+
+func testLinkChecks(t *testing.T, repo repository.URLRepository) {
+	ctx := context.Background()
+	create(t, repo, newLink("fresh"), newLink("stale"), newLink("gone"), newLink("never"))
+	_, err := repo.SetActive(ctx, "gone", false)
+	require.NoError(t, err)
+
+	due, err := repo.FindDueForCheck(ctx, time.Now(), 0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"fresh", "stale", "never"}, shortCodes(due), "unchecked active links, in ID order")
+
+	now := time.Now().UTC().Truncate(time.Second)
+	notFound := 404
+	require.NoError(t, repo.RecordLinkCheck(ctx, "fresh", domain.LinkCheck{CheckedAt: now}))
+	require.NoError(t, repo.RecordLinkCheck(ctx, "stale", domain.LinkCheck{CheckedAt: now.Add(-48 * time.Hour), StatusCode: &notFound, Broken: true}))
+
+	due, err = repo.FindDueForCheck(ctx, now.Add(-time.Hour), 0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"stale", "never"}, shortCodes(due), "recently checked links are skipped")
+
+	due, err = repo.FindDueForCheck(ctx, now.Add(-time.Hour), due[0].ID, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"never"}, shortCodes(due), "paged by ID")
+
+	stale, err := repo.FindByShortCode(ctx, "stale")
+	require.NoError(t, err)
+	require.NotNil(t, stale.LastCheckedAt)
+	assert.True(t, now.Add(-48*time.Hour).Equal(*stale.LastCheckedAt))
+	require.NotNil(t, stale.LastStatusCode)
+	assert.Equal(t, 404, *stale.LastStatusCode)
+	assert.True(t, stale.IsBroken)
+
+	fresh, err := repo.FindByShortCode(ctx, "fresh")
+	require.NoError(t, err)
+	assert.Nil(t, fresh.LastStatusCode, "no answer is stored as NULL")
+	assert.False(t, fresh.IsBroken)
+
+	broken := true
+	links, err := repo.FindActiveMatching(ctx, domain.URLFilter{Broken: &broken}, 10, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"stale"}, shortCodes(links))
+
+	links, err = repo.FindActiveMatching(ctx, domain.URLFilter{}, 2, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"stale", "fresh"}, shortCodes(links), "newest first, inactive links left out")
+}
+
+func testExistsManyByShortCode(t *testing.T, repo repository.URLRepository) {
+	ctx := context.Background()
+	create(t, repo, newLink("one"), newLink("two"))
+
+	taken, err := repo.ExistsManyByShortCode(ctx, nil)
+	require.NoError(t, err)
+	assert.Empty(t, taken)
+
+	taken, err = repo.ExistsManyByShortCode(ctx, []string{"one", "free", "two", "one"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{"one": true, "two": true}, taken, "free codes are absent, not false")
+}
+
+func testForEach(t *testing.T, repo repository.URLRepository) {
+	ctx := context.Background()
+	var codes []string
+	for i := 0; i < 7; i++ {
+		code := fmt.Sprintf("code%d", i)
+		codes = append(codes, code)
+		create(t, repo, newLink(code))
+	}
+
+	var visited []string
+	err := repo.ForEach(ctx, domain.URLFilter{}, 3, func(link *domain.URL) error {
+		visited = append(visited, link.ShortCode)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, codes, visited, "every link once, in insertion order")
+
+	visited = nil
+	err = repo.ForEach(ctx, domain.URLFilter{ShortCodes: []string{"code1", "code5"}}, 3, func(link *domain.URL) error {
+		visited = append(visited, link.ShortCode)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"code1", "code5"}, visited)
+
+	stop := errors.New("stop")
+	calls := 0
+	err = repo.ForEach(ctx, domain.URLFilter{}, 3, func(*domain.URL) error {
+		calls++
+		return stop
+	})
+	assert.ErrorIs(t, err, stop)
+	assert.Equal(t, 1, calls, "iteration stops at the first error")
+}
+
+func testForEachSearch(t *testing.T, repo repository.URLRepository) {
+	ctx := context.Background()
+	titled := newLink("titled")
+	titled.Title = "Spring Launch"
+	described := newLink("described")
+	described.Description = "Owner: growth\nlaunch checklist"
+	wildcard := newLink("wildcard")
+	wildcard.Title = "100% off"
+	create(t, repo, titled, described, wildcard, newLink("launchpad"), newLink("plain"))
+
+	search := func(term string) []string {
+		var visited []string
+		err := repo.ForEach(ctx, domain.URLFilter{Search: term}, 10, func(link *domain.URL) error {
+			visited = append(visited, link.ShortCode)
+			return nil
+		})
+		require.NoError(t, err)
+		return visited
+	}
+
+	assert.Equal(t, []string{"titled", "described", "launchpad"}, search("LAUNCH"), "title, description, short code and destination, ignoring case")
+	assert.Equal(t, []string{"wildcard"}, search("0%"), "wildcards match literally")
+	assert.Empty(t, search("_"))
+}
+
+func testDeactivateMatching(t *testing.T, repo repository.URLRepository) {
+	ctx := context.Background()
+	other := newLink("other")
+	other.CreatorIP = "198.51.100.1"
+	create(t, repo, newLink("one"), newLink("two"), other)
+
+	_, err := repo.DeactivateMatching(ctx, domain.URLFilter{})
+	assert.Error(t, err, "an empty filter is refused")
+	_, err = repo.CountActiveMatching(ctx, domain.URLFilter{})
+	assert.Error(t, err, "an empty filter is refused")
+
+	filter := domain.URLFilter{CreatorIP: "203.0.113.9"}
+	count, err := repo.CountActiveMatching(ctx, filter)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	links, err := repo.DeactivateMatching(ctx, filter)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"one", "two"}, shortCodes(links))
+	for _, link := range links {
+		assert.False(t, link.IsActive, link.ShortCode)
+	}
+
+	links, err = repo.DeactivateMatching(ctx, filter)
+	require.NoError(t, err)
+	assert.Empty(t, links, "already inactive links are not returned again")
+
+	count, err = repo.CountActiveMatching(ctx, filter)
+	require.NoError(t, err)
+	assert.Zero(t, count)
+	_, err = repo.FindByShortCode(ctx, "other")
+	assert.NoError(t, err, "links outside the filter are untouched")
+}
+
+func testFindByCreatorIP(t *testing.T, repo repository.URLRepository) {
+	ctx := context.Background()
+	first := newLink("first")
+	first.CreatorUserAgent = "curl/8.4.0"
+	first.CreatorOrigin = "https://spam.example"
+	other := newLink("other")
+	other.CreatorIP = "198.51.100.1"
+	create(t, repo, first, newLink("second"), other, newLink("third"))
+	_, err := repo.SetActive(ctx, "second", false)
+	require.NoError(t, err)
+
+	page, err := repo.FindByCreatorIP(ctx, "203.0.113.9", 2, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"third", "second"}, shortCodes(page), "newest first, deactivated links included")
+
+	page, err = repo.FindByCreatorIP(ctx, "203.0.113.9", 2, 2)
+	require.NoError(t, err)
+	require.Equal(t, []string{"first"}, shortCodes(page))
+	assert.Equal(t, "curl/8.4.0", page[0].CreatorUserAgent)
+	assert.Equal(t, "https://spam.example", page[0].CreatorOrigin)
+
+	page, err = repo.FindByCreatorIP(ctx, "192.0.2.1", 10, 0)
+	require.NoError(t, err)
+	assert.Empty(t, page)
+}
+
+func testCountsAndSums(t *testing.T, repo repository.URLRepository) {
+	ctx := context.Background()
+	create(t, repo, newLink("one"), newLink("two"), newLink("three"))
+	require.NoError(t, repo.IncrementClickCount(ctx, "one", ""))
+	require.NoError(t, repo.IncrementClickCount(ctx, "two", ""))
+	require.NoError(t, repo.IncrementClickCount(ctx, "two", ""))
+	require.NoError(t, repo.Delete(ctx, "two"))
+
+	count, err := repo.CountURLs(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count, "only active links are counted")
+
+	total, err := repo.SumClicks(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), total, "clicks on deleted links still happened")
+}
+
+func testTopByClickCount(t *testing.T, repo repository.URLRepository) {
+	ctx := context.Background()
+	expired := time.Now().Add(-time.Hour)
+	gone := newLink("gone")
+	gone.ExpiresAt = &expired
+	create(t, repo, newLink("one"), newLink("two"), newLink("three"), newLink("off"), gone)
+	for code, clicks := range map[string]int{"one": 1, "two": 3, "off": 5, "gone": 5} {
+		for i := 0; i < clicks; i++ {
+			require.NoError(t, repo.IncrementClickCount(ctx, code, ""))
+		}
+	}
+	_, err := repo.SetActive(ctx, "off", false)
+	require.NoError(t, err)
+
+	top, err := repo.TopByClickCount(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"two", "one"}, shortCodes(top), "inactive and expired links are left out")
+
+	top, err = repo.TopByClickCount(ctx, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"two", "one", "three"}, shortCodes(top), "ties are broken by short code")
+}
+
+func testCreatedBetween(t *testing.T, repo repository.URLRepository) {
+	ctx := context.Background()
+	day := time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)
+	for code, createdAt := range map[string]time.Time{
+		"before": day.Add(-time.Minute),
+		"first":  day.Add(time.Hour),
+		"second": day.Add(23 * time.Hour),
+		"next":   day.Add(26 * time.Hour),
+		"after":  day.Add(72 * time.Hour),
+	} {
+		link := newLink(code)
+		link.CreatedAt = createdAt
+		create(t, repo, link)
+	}
+
+	counts, err := repo.CreatedBetween(ctx, day, day.Add(48*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, []domain.DailyCount{{Date: "2024-03-10", Count: 2}, {Date: "2024-03-11", Count: 1}}, counts)
+}
@@ -2,6 +2,8 @@ package repository
 
 import (
 	"context"
+	"time"
+	
 	"url-shortener/internal/domain"
 )
 
@@ -12,21 +14,68 @@ type URLRepository interface {
 	// Create stores a new shortened URL in the database
 	Create(ctx context.Context, url *domain.URL) error
 	
+	// CreateBundle stores a bundle and its member links in one transaction
+	CreateBundle(ctx context.Context, bundle *domain.URL, members []*domain.URL) error
+	
 	// FindByShortCode retrieves a URL by its short code
 	FindByShortCode(ctx context.Context, shortCode string) (*domain.URL, error)
 	
+	// FindByShortCodeFold retrieves the active URL whose short code equals shortCode ignoring case
+	// Returns ErrURLNotFound when no link or more than one matches, deactivated links included;
+	// served by the lower(short_code) index
+	FindByShortCodeFold(ctx context.Context, shortCode string) (*domain.URL, error)
+	
+	// FindAnyByShortCode retrieves a URL by its short code whether or not it is active
+	FindAnyByShortCode(ctx context.Context, shortCode string) (*domain.URL, error)
+	
+	// FindByCreatorIP returns up to limit links created from ip, deactivated ones included, newest first
+	// offset skips that many links, so an abuse run can be enumerated page by page
+	FindByCreatorIP(ctx context.Context, ip string, limit, offset int) ([]domain.URL, error)
+	
 	// FindByOriginalURL checks if an original URL already has a short code
 	FindByOriginalURL(ctx context.Context, originalURL string) (*domain.URL, error)
 	
 	// Update modifies an existing URL record
 	Update(ctx context.Context, url *domain.URL) error
 	
+	// SetActive flips the is_active flag and returns the updated record
+	// Unlike FindByShortCode it also matches inactive rows
+	SetActive(ctx context.Context, shortCode string, active bool) (*domain.URL, error)
+	
 	// Delete removes a URL by its short code
 	Delete(ctx context.Context, shortCode string) error
 	
+	// HardDelete erases a link's row together with its click events, daily stats, audit entries and outbox events
+	// Everything goes in one transaction; ErrURLNotFound when no row has the code
+	HardDelete(ctx context.Context, shortCode string) error
+	
+	// ResetStats zeroes a link's counters, clears its last access and referrers and deletes its click events
+	// and daily stats in one transaction; returns the row as it was before, ErrURLNotFound when no row has the code
+	ResetStats(ctx context.Context, shortCode string) (*domain.URL, error)
+	
+	// HardDeleteInactive erases up to limit links deactivated or deleted before the cutoff, the way HardDelete does,
+	// and returns them with only ID, TenantID and ShortCode set
+	HardDeleteInactive(ctx context.Context, before time.Time, limit int) ([]domain.URL, error)
+	
 	// IncrementClickCount atomically increments the click counter
 	// This prevents race conditions with concurrent requests
-	IncrementClickCount(ctx context.Context, shortCode string) error
+	// A non-empty referrer host becomes the last referrer and is counted in the referrer counts
+	IncrementClickCount(ctx context.Context, shortCode, referrer string) error
+	
+	// IncrementBotClickCount atomically increments the counter of clicks by crawlers
+	// Unlike IncrementClickCount it leaves last_access_at alone
+	IncrementBotClickCount(ctx context.Context, shortCode string) error
+	
+	// IncrementPrefetchCount atomically increments the counter of prefetches shown the confirm page
+	// Like IncrementBotClickCount it leaves last_access_at alone
+	IncrementPrefetchCount(ctx context.Context, shortCode string) error
+	
+	// IncrementFilteredClickCount atomically increments the counter of repeat clicks left out of the click count
+	// Like IncrementBotClickCount it leaves last_access_at alone
+	IncrementFilteredClickCount(ctx context.Context, shortCode string) error
+	
+	// IncrementConversionCount atomically increments the conversions of a link, whether or not it is still active
+	IncrementConversionCount(ctx context.Context, shortCode string) error
 	
 	// GetStats retrieves statistics for a short URL
 	GetStats(ctx context.Context, shortCode string) (*domain.URLStats, error)
@@ -34,6 +83,57 @@ type URLRepository interface {
 	// DeleteExpired removes all expired URLs (cleanup job)
 	DeleteExpired(ctx context.Context) (int64, error)
 	
+	// FindExpiringBetween returns active links expiring in [from, to), soonest first
+	// A limit of zero or less returns every match
+	FindExpiringBetween(ctx context.Context, from, to time.Time, limit int) ([]domain.URL, error)
+	
+	// MarkExpiryNotified records that the owner was warned of the link's upcoming expiry
+	MarkExpiryNotified(ctx context.Context, shortCode string, at time.Time) error
+	
+	// FindDueForCheck returns up to limit active links with an ID above afterID that were never checked
+	// or last checked before checkedBefore, in ID order, so the link check can page through them
+	FindDueForCheck(ctx context.Context, checkedBefore time.Time, afterID uint, limit int) ([]domain.URL, error)
+	
+	// RecordLinkCheck stores the outcome of a link check without touching updated_at
+	RecordLinkCheck(ctx context.Context, shortCode string, check domain.LinkCheck) error
+	
 	// ExistsByShortCode checks if a short code exists without fetching data
 	ExistsByShortCode(ctx context.Context, shortCode string) (bool, error)
+	
+	// UpdateMetadata stores the enrichment result; nil values are written as NULL
+	UpdateMetadata(ctx context.Context, shortCode string, pageTitle, faviconURL *string) error
+	
+	// ExistsManyByShortCode reports which of the given short codes are taken, without a query per code
+	// Deactivated links count as taken since their codes cannot be reused
+	ExistsManyByShortCode(ctx context.Context, shortCodes []string) (map[string]bool, error)
+	
+	// ForEach streams every URL matching the filter to fn, loading batchSize rows at a time
+	// Iteration stops at the first error returned by fn
+	ForEach(ctx context.Context, filter domain.URLFilter, batchSize int, fn func(*domain.URL) error) error
+	
+	// DeactivateMatching deactivates every active link matching the filter and returns them
+	// An empty filter is refused rather than deactivating every link
+	DeactivateMatching(ctx context.Context, filter domain.URLFilter) ([]domain.URL, error)
+	
+	// CountActiveMatching counts the active links matching the filter; an empty filter is refused
+	CountActiveMatching(ctx context.Context, filter domain.URLFilter) (int64, error)
+	
+	// FindActiveMatching returns up to limit active links matching the filter, newest first, skipping offset
+	// Unlike CountActiveMatching it accepts an empty filter, which lists every active link
+	FindActiveMatching(ctx context.Context, filter domain.URLFilter, limit, offset int) ([]domain.URL, error)
+	
+	// CountURLs returns the number of active links
+	CountURLs(ctx context.Context) (int64, error)
+	
+	// SumClicks returns the total click count across all links
+	SumClicks(ctx context.Context) (int64, error)
+	
+	// TopByClicks ranks links by the click events recorded since the given time
+	TopByClicks(ctx context.Context, since time.Time, limit int) ([]domain.TopLink, error)
+	
+	// TopByClickCount returns up to limit active, unexpired links with the highest lifetime click count
+	TopByClickCount(ctx context.Context, limit int) ([]domain.URL, error)
+	
+	// CreatedBetween counts links created in [from, to) per UTC day, omitting empty days
+	CreatedBetween(ctx context.Context, from, to time.Time) ([]domain.DailyCount, error)
 }
\ No newline at end of file
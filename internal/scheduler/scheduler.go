@@ -0,0 +1,82 @@
+// Package scheduler runs periodic maintenance jobs such as expired link cleanup and stats rollups
+package scheduler
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"url-shortener/pkg/logger"
+)
+
+// Job is a unit of periodic work
+// Jobs must be idempotent: several instances of the service may run the same job concurrently
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) error
+}
+
+// Scheduler runs each job on its own ticker until the context passed to Start is cancelled
+type Scheduler struct {
+	logger *logger.Logger
+	jobs   []Job
+	wg     sync.WaitGroup
+}
+
+// New creates an empty scheduler
+func New(log *logger.Logger) *Scheduler {
+	return &Scheduler{logger: log}
+}
+
+// Add registers a job; jobs with a non-positive interval are disabled and skipped
+func (s *Scheduler) Add(job Job) {
+	if job.Interval <= 0 {
+		s.logger.Info("Scheduled job disabled", "job", job.Name)
+		return
+	}
+	s.jobs = append(s.jobs, job)
+}
+
+// Start launches all jobs; each runs once immediately and then every Interval
+func (s *Scheduler) Start(ctx context.Context) {
+	for _, job := range s.jobs {
+		s.wg.Add(1)
+		go func(job Job) {
+			defer s.wg.Done()
+			s.loop(ctx, job)
+		}(job)
+	}
+}
+
+// Wait blocks until every job loop has returned after cancellation
+// A run in progress is allowed to finish, so call it with the shutdown deadline in mind
+func (s *Scheduler) Wait() {
+	s.wg.Wait()
+}
+
+// loop runs one job until ctx is done
+func (s *Scheduler) loop(ctx context.Context, job Job) {
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+
+	for {
+		s.run(ctx, job)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// run executes a job once, logging failures instead of stopping the loop
+func (s *Scheduler) run(ctx context.Context, job Job) {
+	start := time.Now()
+	if err := job.Run(ctx); err != nil {
+		s.logger.Error("Scheduled job failed", "job", job.Name, "error", err, "duration", time.Since(start))
+		return
+	}
+	s.logger.Debug("Scheduled job finished", "job", job.Name, "duration", time.Since(start))
+}
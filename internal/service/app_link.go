@@ -0,0 +1,53 @@
+package service
+
+import (
+	"strings"
+
+	"url-shortener/internal/domain"
+	"url-shortener/pkg/validator"
+)
+
+// normalizeAppLink validates a link's deep link and its fallbacks
+// Only the deep link may use a custom scheme. The fallbacks are opened by the browser when the app
+// isn't installed, so like a Location they have to be http or https.
+func (s *urlService) normalizeAppLink(deepLink, iosFallback, androidFallback string) (domain.AppLink, error) {
+	link := domain.AppLink{DeepLink: strings.TrimSpace(deepLink)}
+	iosFallback, androidFallback = strings.TrimSpace(iosFallback), strings.TrimSpace(androidFallback)
+
+	if link.DeepLink == "" {
+		if iosFallback != "" || androidFallback != "" {
+			return domain.AppLink{}, domain.NewFieldError("deep_link", "required_with", "ios_fallback_url and android_fallback_url need a deep_link")
+		}
+		return link, nil
+	}
+	if err := validator.ValidateDeepLink(link.DeepLink); err != nil {
+		return domain.AppLink{}, domain.NewFieldError("deep_link", "format", err.Error())
+	}
+
+	var err error
+	if link.IOSFallback, err = s.normalizeFallback("ios_fallback_url", iosFallback); err != nil {
+		return domain.AppLink{}, err
+	}
+	if link.AndroidFallback, err = s.normalizeFallback("android_fallback_url", androidFallback); err != nil {
+		return domain.AppLink{}, err
+	}
+	return link, nil
+}
+
+// normalizeFallback validates and normalizes one fallback URL, empty when none is given
+func (s *urlService) normalizeFallback(field, rawURL string) (string, error) {
+	if rawURL == "" {
+		return "", nil
+	}
+	if err := s.urlValidator.ValidateURL(rawURL); err != nil || !validator.IsWebURL(rawURL) {
+		return "", domain.NewFieldError(field, "format", "Invalid URL format; fallbacks must be http or https")
+	}
+	return s.normalizeURL(rawURL), nil
+}
+
+// setAppLink stores a normalized deep link and its fallbacks on url
+func setAppLink(url *domain.URL, link domain.AppLink) {
+	url.DeepLink = link.DeepLink
+	url.IOSFallbackURL = link.IOSFallback
+	url.AndroidFallbackURL = link.AndroidFallback
+}
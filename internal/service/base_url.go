@@ -0,0 +1,29 @@
+package service
+
+import "context"
+
+// baseURLContextKey carries the base URL of the request's host into the response builders
+type baseURLContextKey struct{}
+
+// ContextWithBaseURL makes short URLs in responses start with base instead of BASE_URL
+// The caller is responsible for base being a host this deployment serves
+func ContextWithBaseURL(ctx context.Context, base string) context.Context {
+	if base == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, baseURLContextKey{}, base)
+}
+
+// BaseURLFromContext returns the base URL set by ContextWithBaseURL, if any
+func BaseURLFromContext(ctx context.Context) (string, bool) {
+	base, ok := ctx.Value(baseURLContextKey{}).(string)
+	return base, ok
+}
+
+// baseURL is the base of short URLs built for ctx, BASE_URL unless the request's host was set
+func (s *urlService) baseURL(ctx context.Context) string {
+	if base, ok := BaseURLFromContext(ctx); ok {
+		return base
+	}
+	return s.cfg.BaseURL
+}
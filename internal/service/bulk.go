@@ -0,0 +1,218 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"url-shortener/internal/cache"
+	"url-shortener/internal/clientip"
+	"url-shortener/internal/domain"
+)
+
+const (
+	// MaxBulkShortCodes caps the short codes listed in one bulk request
+	MaxBulkShortCodes = 1000
+
+	// bulkInvalidateBatch is how many cache keys go into one pipelined delete
+	bulkInvalidateBatch = 500
+)
+
+// BulkDeactivate deactivates every active link selected by short code or filter in a single write
+// A dry run only counts the matches; an empty selection is refused so one typo can't take down every link
+func (s *urlService) BulkDeactivate(ctx context.Context, req *domain.BulkDeactivateRequest, actor domain.Actor) (*domain.BulkDeactivateResponse, error) {
+	// Step 1: Turn the request into a repository filter
+	filter, err := bulkFilter(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Step 2: Report listed codes that don't exist at all; deactivated ones exist and are just not affected
+	resp := &domain.BulkDeactivateResponse{DryRun: req.DryRun}
+	if len(filter.ShortCodes) > 0 {
+		taken, err := s.repo.ExistsManyByShortCode(ctx, filter.ShortCodes)
+		if err != nil {
+			s.log(ctx).Error("Failed to look up bulk short codes", "error", err)
+			return nil, err
+		}
+		for _, code := range filter.ShortCodes {
+			if !taken[code] {
+				resp.NotFound = append(resp.NotFound, code)
+			}
+		}
+	}
+
+	// Step 3: A dry run stops at counting
+	if req.DryRun {
+		resp.Affected, err = s.repo.CountActiveMatching(ctx, filter)
+		if err != nil {
+			s.log(ctx).Error("Failed to count bulk matches", "error", err)
+			return nil, err
+		}
+		return resp, nil
+	}
+
+	// Step 4: Deactivate every match in one statement
+	urls, err := s.repo.DeactivateMatching(ctx, filter)
+	if err != nil {
+		s.log(ctx).Error("Failed to bulk deactivate URLs", "error", err)
+		return nil, err
+	}
+	resp.Affected = int64(len(urls))
+
+	// Step 5: Drop the cached redirects so the links stop resolving now rather than at TTL
+	s.invalidateLinks(ctx, urls)
+
+	s.log(ctx).Warn("URLs bulk deactivated",
+		"affected", resp.Affected,
+		"actor", actor.ID,
+		"ip", actor.IP,
+	)
+
+	// Step 6: One audit entry covers the whole run; the links are already deactivated either way
+	if s.audit != nil {
+		entry := &domain.AuditEntry{
+			Action:  domain.AuditActionBulkDeactivate,
+			ActorID: actor.ID,
+			ActorIP: actor.IP,
+			Details: describeBulkFilter(filter, resp.Affected),
+		}
+		if err := s.audit.Record(ctx, entry); err != nil {
+			s.log(ctx).Error("Failed to record audit entry", "error", err, "action", entry.Action)
+		}
+	}
+
+	return resp, nil
+}
+
+// bulkFilter validates a bulk request and builds its filter
+func bulkFilter(req *domain.BulkDeactivateRequest) (domain.URLFilter, error) {
+	var filter domain.URLFilter
+
+	if len(req.ShortCodes) > 0 && req.Filter != nil {
+		return filter, domain.NewValidationError("short_codes and filter are mutually exclusive")
+	}
+
+	if len(req.ShortCodes) > 0 {
+		if len(req.ShortCodes) > MaxBulkShortCodes {
+			return filter, domain.NewValidationError(fmt.Sprintf("short_codes must not list more than %d codes", MaxBulkShortCodes))
+		}
+		seen := make(map[string]bool, len(req.ShortCodes))
+		for _, code := range req.ShortCodes {
+			code = strings.TrimSpace(code)
+			if code == "" {
+				return filter, domain.NewValidationError("short_codes must not contain empty codes")
+			}
+			if !seen[code] {
+				seen[code] = true
+				filter.ShortCodes = append(filter.ShortCodes, code)
+			}
+		}
+		return filter, nil
+	}
+
+	if req.Filter != nil {
+		f := req.Filter
+		if ip := clientip.Canonical(f.CreatorIP); ip != "" {
+			if net.ParseIP(ip) == nil {
+				return filter, domain.NewValidationError(fmt.Sprintf("filter.creator_ip %q is not an IP address", f.CreatorIP))
+			}
+			filter.CreatorIP = ip
+		}
+		if strings.TrimSpace(f.DestinationHost) != "" || f.PathPrefix != "" {
+			destination, err := destinationFilter(f.DestinationHost, f.PathPrefix, "filter.destination_host", "filter.path_prefix")
+			if err != nil {
+				return filter, err
+			}
+			filter.DestinationHost, filter.IncludeSubdomains, filter.PathPrefix = destination.DestinationHost, destination.IncludeSubdomains, destination.PathPrefix
+		}
+		if f.CreatedFrom != nil && f.CreatedTo != nil && !f.CreatedFrom.Before(*f.CreatedTo) {
+			return filter, domain.NewValidationError("filter.created_from must be before filter.created_to")
+		}
+		filter.CreatedFrom = f.CreatedFrom
+		filter.CreatedTo = f.CreatedTo
+	}
+
+	if filter.IsEmpty() {
+		return filter, domain.NewValidationError("short_codes or a non-empty filter is required")
+	}
+	return filter, nil
+}
+
+// describeBulkFilter records what a bulk run selected for the audit trail
+func describeBulkFilter(filter domain.URLFilter, affected int64) string {
+	parts := []string{fmt.Sprintf("affected=%d", affected)}
+	if len(filter.ShortCodes) > 0 {
+		parts = append(parts, "short_codes="+strings.Join(filter.ShortCodes, ","))
+	}
+	if filter.CreatorIP != "" {
+		parts = append(parts, "creator_ip="+filter.CreatorIP)
+	}
+	if filter.DestinationHost != "" {
+		host := filter.DestinationHost
+		if filter.IncludeSubdomains {
+			host = "*." + host
+		}
+		parts = append(parts, "destination_host="+host)
+	}
+	if filter.PathPrefix != "" {
+		parts = append(parts, "path_prefix="+filter.PathPrefix)
+	}
+	if filter.CreatedFrom != nil {
+		parts = append(parts, "created_from="+filter.CreatedFrom.UTC().Format("2006-01-02T15:04:05Z"))
+	}
+	if filter.CreatedTo != nil {
+		parts = append(parts, "created_to="+filter.CreatedTo.UTC().Format("2006-01-02T15:04:05Z"))
+	}
+	return strings.Join(parts, " ")
+}
+
+// invalidateLinks is the batched form of invalidateLink for bulk writes
+// The dedup key is derived from each row instead of read back through its reference, so it costs no reads
+func (s *urlService) invalidateLinks(ctx context.Context, urls []domain.URL) {
+	if s.cache == nil || len(urls) == 0 {
+		return
+	}
+
+	deleter, ok := s.cache.(cache.BatchDeleter)
+	if !ok {
+		for i := range urls {
+			s.invalidateLink(ctx, urls[i].ShortCode)
+		}
+		return
+	}
+
+	codes := make([]string, len(urls))
+	keys := make([]string, 0, len(urls)*3)
+	for i := range urls {
+		url := &urls[i]
+		codes[i] = url.ShortCode
+		keys = append(keys, s.codeKey(ctx, cache.LinkKey(url.ShortCode)))
+		if s.dedupCacheEnabled() {
+			keys = append(keys,
+				s.codeKey(ctx, cache.DedupRefKey(url.ShortCode)),
+				tenantKey(ctx, cache.DedupKey(dedupFingerprint(url.OriginalURL, url.UTM, url.ForwardQuery, url.ReferrerPolicy))),
+			)
+		}
+	}
+
+	s.cacheWrites.cancel(codes...)
+
+	for start := 0; start < len(keys); start += bulkInvalidateBatch {
+		end := start + bulkInvalidateBatch
+		if end > len(keys) {
+			end = len(keys)
+		}
+		if err := deleter.DeleteMultiple(ctx, keys[start:end]); err != nil {
+			if errors.Is(err, cache.ErrBatchDeleteUnsupported) {
+				for i := range urls {
+					s.invalidateLink(ctx, urls[i].ShortCode)
+				}
+				return
+			}
+			s.log(ctx).Warn("Failed to delete from cache", "error", err, "keys", end-start)
+		}
+	}
+}
@@ -0,0 +1,240 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"url-shortener/internal/domain"
+)
+
+// shortenBundle creates a landing page link plus one short link per member
+// Member links are ordinary redirects, so clicks on them are tracked like any other link
+func (s *urlService) shortenBundle(ctx context.Context, req *domain.CreateURLRequest, creator domain.CreatorContext) (*domain.CreateURLResponse, error) {
+	// Step 1: Validate the bundle and every member
+	if req.URL != "" || len(req.Targets) > 0 || len(req.Variants) > 0 || req.ReferrerPolicy != domain.ReferrerPolicyNone ||
+		req.DeepLink != "" || req.IOSFallbackURL != "" || req.AndroidFallbackURL != "" || req.AttachClickID {
+		return nil, domain.NewValidationError("bundle cannot be combined with url, targets, variants, referrer_policy, deep_link or attach_click_id")
+	}
+
+	items, err := s.normalizeBundle(req.Bundle)
+	if err != nil {
+		s.log(ctx).Warn("Invalid bundle provided", "error", err)
+		return nil, domain.NewValidationError(err.Error())
+	}
+
+	var utm domain.UTMParams
+	if req.UTM != nil {
+		utm = *req.UTM
+	}
+
+	title, description, err := cleanNotes(req.Title, req.Description)
+	if err != nil {
+		return nil, err
+	}
+
+	// Step 2: Pick the bundle code; member codes are generated once the bundle exists
+	shortCode, err := s.chooseShortCode(req.CustomAlias)
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt, err := s.expiryFor(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	members := make([]*domain.URL, len(items))
+	for i := range items {
+		// Members share the bundle's lifetime and campaign parameters
+		members[i] = &domain.URL{
+			OriginalURL: items[i].URL,
+			ExpiresAt:   expiresAt,
+			IsActive:    true,
+			UTM:         utm,
+		}
+	}
+
+	// The landing page is the bundle's destination, which also keeps it out of URL deduplication
+	bundle := &domain.URL{
+		ShortCode:   shortCode,
+		OriginalURL: fmt.Sprintf("%s/%s", s.cfg.BaseURL, shortCode),
+		Title:       title,
+		Description: description,
+		ExpiresAt:   expiresAt,
+		IsActive:    true,
+		CustomAlias: req.CustomAlias != "",
+		PublicStats: req.PublicStats,
+		Bundle:      items,
+	}
+	setCreator(creator, append([]*domain.URL{bundle}, members...)...)
+	if err := s.beforeCreate(ctx, append([]*domain.URL{bundle}, members...)...); err != nil {
+		return nil, err
+	}
+	if req.DryRun {
+		response, err := s.previewURL(ctx, bundle, false)
+		if err != nil {
+			return nil, err
+		}
+		response.Bundle = items
+		return response, nil
+	}
+	s.assignMemberCodes(bundle, members)
+
+	managementToken, err := issueManagementToken(append([]*domain.URL{bundle}, members...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Step 3: Reserve quota once for the whole bundle and save it with its members
+	quota, releaseQuota, err := s.reserveQuota(ctx, creator.IP, apiKeyFromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.insertBundle(ctx, bundle, members); err != nil {
+		releaseQuota()
+		if !errors.Is(err, domain.ErrShortCodeTaken) {
+			s.log(ctx).Error("Failed to create bundle", "error", err, "short_code", shortCode)
+		}
+		return nil, err
+	}
+	shortCode = bundle.ShortCode
+	s.afterCreate(ctx, append([]*domain.URL{bundle}, members...)...)
+
+	s.log(ctx).Info("Bundle created", "short_code", shortCode, "members", len(items))
+
+	// Members are ordinary links with real destinations
+	for _, member := range members {
+		s.enrichAsync(ctx, member.ShortCode, member.OriginalURL)
+		s.snapshotAsync(ctx, member.ShortCode, member.OriginalURL)
+	}
+
+	response := s.buildResponse(ctx, bundle)
+	response.Bundle = items
+	response.ManagementToken = managementToken
+	response.Quota = quota
+	return response, nil
+}
+
+// assignMemberCodes gives every member a fresh generated code, distinct within the bundle
+// Bundle items mirror the members so the landing page links to the codes that are saved
+func (s *urlService) assignMemberCodes(bundle *domain.URL, members []*domain.URL) {
+	claimed := map[string]bool{bundle.ShortCode: true}
+	for i, member := range members {
+		code := s.generator.Generate()
+		for claimed[code] {
+			code = s.generator.Generate()
+		}
+		claimed[code] = true
+		member.ShortCode = code
+		bundle.Bundle[i].ShortCode = code
+	}
+}
+
+// insertBundle saves the bundle with its members, retrying with new codes on a unique-index conflict
+// The conflict doesn't say which code was taken, so all of them are looked up at once and only those are replaced
+func (s *urlService) insertBundle(ctx context.Context, bundle *domain.URL, members []*domain.URL) error {
+	bundle.CodeScope = s.codeScope(ctx)
+	for _, member := range members {
+		member.CodeScope = bundle.CodeScope
+	}
+	for attempt := 1; ; attempt++ {
+		err := s.withEvents(ctx, func(ctx context.Context) error {
+			return s.repo.CreateBundle(ctx, bundle, members)
+		}, func() []*domain.OutboxEvent {
+			events := make([]*domain.OutboxEvent, 0, len(members)+1)
+			for _, member := range members {
+				events = append(events, createdEvent(member))
+			}
+			return append(events, createdEvent(bundle))
+		})
+		if !errors.Is(err, domain.ErrShortCodeTaken) {
+			return err
+		}
+
+		// One lookup tells which of the bundle's codes collided
+		codes := make([]string, 0, len(members)+1)
+		codes = append(codes, bundle.ShortCode)
+		for _, member := range members {
+			codes = append(codes, member.ShortCode)
+		}
+		taken, err := s.repo.ExistsManyByShortCode(s.codeLookupContext(ctx), codes)
+		if err != nil {
+			return err
+		}
+		if bundle.CustomAlias && taken[bundle.ShortCode] {
+			return domain.ErrShortCodeTaken
+		}
+		if attempt == maxCodeAttempts {
+			return domain.NewInternalError(fmt.Errorf("failed to generate unique bundle codes after %d attempts", maxCodeAttempts))
+		}
+
+		s.log(ctx).Warn("Short code collision in bundle, retrying", "short_code", bundle.ShortCode, "attempt", attempt)
+		s.reassignCodes(bundle, members, taken)
+	}
+}
+
+// reassignCodes regenerates the codes found taken and keeps the rest
+// If none of them is taken anymore, e.g. the conflicting insert was rolled back, every generated code is replaced
+func (s *urlService) reassignCodes(bundle *domain.URL, members []*domain.URL, taken map[string]bool) {
+	all := true
+	for _, isTaken := range taken {
+		all = all && !isTaken
+	}
+	if !bundle.CustomAlias && (all || taken[bundle.ShortCode]) {
+		bundle.ShortCode = s.generator.Generate()
+		bundle.OriginalURL = fmt.Sprintf("%s/%s", s.cfg.BaseURL, bundle.ShortCode)
+	}
+
+	claimed := map[string]bool{bundle.ShortCode: true}
+	for _, member := range members {
+		claimed[member.ShortCode] = true
+	}
+	for i, member := range members {
+		if !all && !taken[member.ShortCode] {
+			continue
+		}
+		code := s.generator.Generate()
+		for claimed[code] || taken[code] {
+			code = s.generator.Generate()
+		}
+		claimed[code] = true
+		member.ShortCode = code
+		bundle.Bundle[i].ShortCode = code
+	}
+}
+
+// normalizeBundle validates bundle members, normalizes their URLs and fills in missing titles
+func (s *urlService) normalizeBundle(items []domain.BundleItem) (domain.BundleItems, error) {
+	if len(items) > domain.MaxBundleItems {
+		return nil, fmt.Errorf("bundle can contain at most %d links", domain.MaxBundleItems)
+	}
+
+	normalized := make(domain.BundleItems, len(items))
+	for i, item := range items {
+		if err := s.urlValidator.ValidateURL(item.URL); err != nil {
+			return nil, fmt.Errorf("bundle item %d: invalid URL", i)
+		}
+		item.URL = s.normalizeURL(item.URL)
+		// Members are never resolved: one slow shortener would hold up the whole bundle
+		if s.isShortenerLink(item.URL) {
+			return nil, fmt.Errorf("bundle item %d: %s is already a short link", i, item.URL)
+		}
+
+		item.Title = strings.TrimSpace(item.Title)
+		if item.Title == "" {
+			item.Title = item.URL
+		}
+		if utf8.RuneCountInString(item.Title) > domain.MaxBundleTitleLength {
+			return nil, fmt.Errorf("bundle item %d: title is longer than %d characters", i, domain.MaxBundleTitleLength)
+		}
+
+		// Member codes are always generated
+		item.ShortCode = ""
+		normalized[i] = item
+	}
+
+	return normalized, nil
+}
@@ -0,0 +1,203 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"url-shortener/internal/metrics"
+	"url-shortener/pkg/logger"
+)
+
+// defaultCacheWriteQueueSize is used when CACHE_WRITE_QUEUE_SIZE is not configured
+const defaultCacheWriteQueueSize = 1024
+
+// cacheWriteTimeout bounds a single background write, so a stuck Redis can't hold up the drain forever
+const cacheWriteTimeout = 3 * time.Second
+
+// cacheWrite is a cache entry populated off the request path
+type cacheWrite struct {
+	key       string
+	value     string
+	ttl       time.Duration
+	shortCode string         // Link the entry derives from, so invalidating the link cancels it
+	log       *logger.Logger // Logger of the request the write came from
+}
+
+// cacheWriter owns the goroutine that populates the cache after database reads and writes
+// A slow Redis then delays the cache, not the response. Writes to a key still queued replace the
+// queued value in place, and when the queue is full the oldest write is dropped: a missing entry only
+// costs a database read later. Like the click worker it drains on shutdown.
+type cacheWriter struct {
+	size   int
+	signal chan struct{} // Wakes the goroutine; buffered so a wake-up is never lost
+	done   chan struct{}
+
+	mu       sync.Mutex // Guards the fields below
+	pending  map[string]cacheWrite
+	order    []string // Keys in pending, oldest first
+	closed   bool
+	writing  bool       // A write taken off the queue is being stored
+	inflight string     // Short code of that write
+	stored   *sync.Cond // Broadcast when it is done
+}
+
+// startCacheWriter launches the writer; write is called for every queued entry, oldest first
+func startCacheWriter(size int, write func(cacheWrite)) *cacheWriter {
+	if size <= 0 {
+		size = defaultCacheWriteQueueSize
+	}
+
+	w := &cacheWriter{
+		size:    size,
+		signal:  make(chan struct{}, 1),
+		done:    make(chan struct{}),
+		pending: make(map[string]cacheWrite),
+	}
+	w.stored = sync.NewCond(&w.mu)
+
+	go func() {
+		defer close(w.done)
+		for {
+			job, ok, closed := w.next()
+			if ok {
+				write(job)
+				w.finish()
+				continue
+			}
+			if closed {
+				return
+			}
+			<-w.signal
+		}
+	}()
+
+	return w
+}
+
+// enqueue hands a write to the goroutine without blocking
+// It returns false once the writer is closed, and the caller writes the entry itself
+func (w *cacheWriter) enqueue(job cacheWrite) bool {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return false
+	}
+
+	if _, queued := w.pending[job.key]; !queued {
+		if len(w.order) >= w.size {
+			oldest := w.order[0]
+			w.order = w.order[1:]
+			delete(w.pending, oldest)
+			metrics.CacheWritesDropped.Inc()
+		}
+		w.order = append(w.order, job.key)
+	}
+	w.pending[job.key] = job
+	w.mu.Unlock()
+
+	w.wake()
+	return true
+}
+
+// cancel drops the queued writes derived from any of shortCodes and waits for one already being stored
+// Invalidation calls it before deleting the cached entries, so a queued write can't bring back a stale one.
+// The wait is bounded by cacheWriteTimeout.
+func (w *cacheWriter) cancel(shortCodes ...string) {
+	codes := make(map[string]bool, len(shortCodes))
+	for _, code := range shortCodes {
+		codes[code] = true
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	kept := w.order[:0]
+	for _, key := range w.order {
+		if codes[w.pending[key].shortCode] {
+			delete(w.pending, key)
+			continue
+		}
+		kept = append(kept, key)
+	}
+	w.order = kept
+
+	for w.writing && codes[w.inflight] {
+		w.stored.Wait()
+	}
+}
+
+// next takes the oldest queued write; closed tells an empty queue apart from a finished one
+func (w *cacheWriter) next() (job cacheWrite, ok, closed bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.order) == 0 {
+		return cacheWrite{}, false, w.closed
+	}
+	key := w.order[0]
+	w.order = w.order[1:]
+	job = w.pending[key]
+	delete(w.pending, key)
+	w.writing, w.inflight = true, job.shortCode
+	return job, true, false
+}
+
+// finish marks the write taken by next as stored and wakes the cancels waiting for it
+func (w *cacheWriter) finish() {
+	w.mu.Lock()
+	w.writing, w.inflight = false, ""
+	w.mu.Unlock()
+	w.stored.Broadcast()
+}
+
+// wake signals the goroutine unless a wake-up is already pending
+func (w *cacheWriter) wake() {
+	select {
+	case w.signal <- struct{}{}:
+	default:
+	}
+}
+
+// close stops accepting writes and waits for the queue to drain or ctx to expire
+// Returns the number of writes still queued when ctx expired
+func (w *cacheWriter) close(ctx context.Context) (int, error) {
+	w.mu.Lock()
+	w.closed = true
+	w.mu.Unlock()
+	w.wake()
+
+	select {
+	case <-w.done:
+		return 0, nil
+	case <-ctx.Done():
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		return len(w.order), ctx.Err()
+	}
+}
+
+// setCacheAsync queues a cache entry derived from shortCode for the writer
+// Once the writer is closed the entry is written inline rather than dropped
+func (s *urlService) setCacheAsync(ctx context.Context, shortCode, key, value string, ttl time.Duration) {
+	job := cacheWrite{key: key, value: value, ttl: ttl, shortCode: shortCode, log: s.log(ctx)}
+	if s.cacheWrites.enqueue(job) {
+		return
+	}
+	s.writeCache(context.WithoutCancel(ctx), job)
+}
+
+// writeQueuedCache stores an entry taken off the queue, logging as the request it came from
+func (s *urlService) writeQueuedCache(job cacheWrite) {
+	s.writeCache(logger.WithContext(context.Background(), job.log), job)
+}
+
+// writeCache stores one entry; failures are logged, a missing entry is filled on the next miss
+func (s *urlService) writeCache(ctx context.Context, job cacheWrite) {
+	ctx, cancel := context.WithTimeout(ctx, cacheWriteTimeout)
+	defer cancel()
+
+	if err := s.cache.Set(ctx, job.key, job.value, job.ttl); err != nil {
+		s.log(ctx).Warn("Failed to update cache", "error", err, "short_code", job.shortCode)
+	}
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/unshorten"
+)
+
+// resolveChain keeps links on other shorteners from becoming redirect chains
+// Such a destination is refused, or resolved to its final page when a chain resolver is set.
+// Returns the destination to store and, when it was resolved, the URL the caller submitted.
+func (s *urlService) resolveChain(ctx context.Context, destination string) (string, *string, error) {
+	if !s.isShortenerLink(destination) {
+		return destination, nil, nil
+	}
+
+	if s.chains == nil {
+		s.log(ctx).Info("Refusing already shortened URL", "url", destination)
+		return "", nil, domain.NewValidationError(fmt.Sprintf("%s is already a short link; shorten its final destination instead", destination))
+	}
+
+	if s.cfg.ChainResolveTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.cfg.ChainResolveTimeout)
+		defer cancel()
+	}
+
+	final, err := s.chains.Resolve(ctx, destination)
+	if err != nil {
+		s.log(ctx).Warn("Failed to resolve short link chain", "url", destination, "error", err)
+		return "", nil, domain.NewValidationError(fmt.Sprintf("Could not resolve %s to its final destination: %v", destination, err))
+	}
+
+	// A chain that ends on a shortener, e.g. its "link not found" page, has no destination worth storing
+	final = s.normalizeURL(final)
+	if s.isShortenerLink(final) {
+		return "", nil, domain.NewValidationError(fmt.Sprintf("%s does not lead away from the shortener", destination))
+	}
+
+	s.log(ctx).Info("Resolved short link chain", "url", destination, "destination", final)
+	return final, &destination, nil
+}
+
+// isShortenerLink reports whether rawURL points at a known shortener or at this service
+// Subdomains of SHORTENER_DOMAINS match; BASE_URL only matches its exact host, since the
+// service often runs on a subdomain of a site whose other pages are legitimate destinations
+func (s *urlService) isShortenerLink(rawURL string) bool {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	if unshorten.MatchesDomain(parsed.Host, s.shortenerDomains()) {
+		return true
+	}
+
+	base, err := url.Parse(s.cfg.BaseURL)
+	return err == nil && base.Hostname() != "" && strings.EqualFold(parsed.Hostname(), base.Hostname())
+}
+
+// shortenerDomains returns the hosts of known shorteners, reloaded ones if a runtime is set
+func (s *urlService) shortenerDomains() []string {
+	if s.runtime == nil {
+		return s.cfg.ShortenerDomains
+	}
+	return s.runtime.Load().ShortenerDomains
+}
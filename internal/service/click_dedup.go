@@ -0,0 +1,39 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"url-shortener/internal/cache"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/metrics"
+)
+
+// isRepeatClick reports whether visitor already clicked shortCode within CLICK_DEDUP_WINDOW
+// The first click claims a key expiring with the window, so the window runs from the first click, not the latest.
+// Filtering is best effort: without a cache that can claim keys, or while it fails, every click counts.
+// It runs where the click is recorded, which for cache hits is the click worker, off the redirect.
+func (s *urlService) isRepeatClick(ctx context.Context, shortCode string, visitor domain.Visitor) bool {
+	if s.cfg.ClickDedupWindow <= 0 || visitor.IP == "" {
+		return false
+	}
+	claimer, ok := s.cache.(cache.Claimer)
+	if !ok {
+		return false
+	}
+
+	first, err := claimer.SetIfAbsent(ctx, s.codeKey(ctx, cache.ClickDedupKey(shortCode, visitor.IP, visitor.UserAgent)), "1", s.cfg.ClickDedupWindow)
+	if err != nil {
+		s.log(ctx).Warn("Skipping click dedup, cache unavailable", "error", err, "short_code", shortCode)
+		return false
+	}
+	return !first
+}
+
+// recordFilteredClick counts a repeat click in filtered_clicks; like bot clicks it produces no click event
+func (s *urlService) recordFilteredClick(ctx context.Context, shortCode string) {
+	metrics.FilteredClicks.Inc()
+	if err := s.repo.IncrementFilteredClickCount(ctx, shortCode); err != nil && !errors.Is(err, domain.ErrURLNotFound) {
+		s.log(ctx).Error("Failed to increment filtered click count", "error", err, "short_code", shortCode)
+	}
+}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"url-shortener/internal/cache"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/metrics"
+	"url-shortener/pkg/signer"
+)
+
+// clickNonceBytes is the randomness that tells apart receipts of one link issued in the same second
+const clickNonceBytes = 12
+
+// clickReceipt is what an ush_click value vouches for: a redirect to shortCode of tenant
+type clickReceipt struct {
+	tenant    string
+	shortCode string
+	nonce     string
+	expiresAt time.Time
+}
+
+// newClickSigner returns the signer of click receipts, keyed by CLICK_ID_SECRET
+// Without one a random key is used, and receipts only redeem on the instance that issued them
+func newClickSigner(secret string) (*signer.Signer, error) {
+	if secret != "" {
+		return signer.New([]byte(secret)), nil
+	}
+	return signer.NewRandom()
+}
+
+// issueClickID returns a receipt for a redirect to shortCode of tenant, valid for CLICK_ID_TTL_SECONDS
+// Format: base64url(tenant "\n" code "\n" nonce).<unix-expiry>.<signature>; the payload is opaque to
+// destinations, the signature binds it and the expiry. Empty when no randomness was available.
+func (s *urlService) issueClickID(ctx context.Context, tenant, shortCode string) string {
+	raw := make([]byte, clickNonceBytes)
+	if _, err := rand.Read(raw); err != nil {
+		s.log(ctx).Warn("Redirecting without click ID", "error", err, "short_code", shortCode)
+		return ""
+	}
+	nonce := base64.RawURLEncoding.EncodeToString(raw)
+
+	payload := base64.RawURLEncoding.EncodeToString([]byte(tenant + "\n" + shortCode + "\n" + nonce))
+	return payload + "." + s.clickIDs.Sign(payload, time.Now().Add(s.cfg.ClickIDTTL))
+}
+
+// parseClickID verifies a receipt and returns what it vouches for
+// The signature is compared in constant time. Instances whose clocks run behind the issuer's only make the
+// receipt last longer, so a check after the expiry is granted CLICK_ID_MAX_SKEW_SECONDS for clocks ahead.
+func (s *urlService) parseClickID(clickID string, now time.Time) (clickReceipt, error) {
+	payload, signed, ok := strings.Cut(clickID, ".")
+	if !ok {
+		return clickReceipt{}, domain.ErrInvalidClickID
+	}
+	if err := s.clickIDs.Verify(signed, payload, now.Add(-s.cfg.ClickIDMaxSkew)); err != nil {
+		return clickReceipt{}, domain.ErrInvalidClickID
+	}
+
+	// Signed by us, so the parts below are well formed unless the key leaked
+	expiry, _, _ := strings.Cut(signed, ".")
+	unix, err := strconv.ParseInt(expiry, 10, 64)
+	if err != nil {
+		return clickReceipt{}, domain.ErrInvalidClickID
+	}
+	decoded, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return clickReceipt{}, domain.ErrInvalidClickID
+	}
+	parts := strings.Split(string(decoded), "\n")
+	if len(parts) != 3 || parts[1] == "" || parts[2] == "" {
+		return clickReceipt{}, domain.ErrInvalidClickID
+	}
+
+	return clickReceipt{tenant: parts[0], shortCode: parts[1], nonce: parts[2], expiresAt: time.Unix(unix, 0)}, nil
+}
+
+// RecordConversion redeems a click receipt as a conversion of the link it was issued for
+// Each receipt counts once: redeeming marks its nonce in the cache until the receipt can no longer verify.
+// Without a cache able to do so, replays couldn't be refused, so conversions fail as unavailable.
+func (s *urlService) RecordConversion(ctx context.Context, clickID string) (*domain.ConversionResponse, error) {
+	now := time.Now()
+	receipt, err := s.parseClickID(clickID, now)
+	if err != nil {
+		metrics.Conversions.WithLabelValues("invalid").Inc()
+		return nil, err
+	}
+
+	claimer, ok := s.cache.(cache.Claimer)
+	if !ok {
+		return nil, domain.ErrCacheUnavailable
+	}
+	ttl := receipt.expiresAt.Add(s.cfg.ClickIDMaxSkew).Sub(now)
+	if ttl < time.Second {
+		ttl = time.Second
+	}
+	first, err := claimer.SetIfAbsent(ctx, cache.ClickReceiptKey(receipt.nonce), "1", ttl)
+	if err != nil {
+		s.log(ctx).Warn("Failed to claim click ID", "error", err, "short_code", receipt.shortCode)
+		return nil, fmt.Errorf("%w: %w", domain.ErrCacheUnavailable, err)
+	}
+	if !first {
+		metrics.Conversions.WithLabelValues("replayed").Inc()
+		return nil, domain.ErrClickIDUsed
+	}
+
+	// The receipt names the owner, whoever calls; the caller's own tenant is irrelevant
+	ctx = domain.ContextWithTenant(ctx, receipt.tenant)
+	if err := s.repo.IncrementConversionCount(ctx, receipt.shortCode); err != nil {
+		if !errors.Is(err, domain.ErrURLNotFound) {
+			// Nothing was counted, so the destination may retry with the same receipt
+			s.log(ctx).Error("Failed to record conversion", "error", err, "short_code", receipt.shortCode)
+			if delErr := s.cache.Delete(ctx, cache.ClickReceiptKey(receipt.nonce)); delErr != nil {
+				s.log(ctx).Warn("Failed to release click ID", "error", delErr, "short_code", receipt.shortCode)
+			}
+		}
+		return nil, err
+	}
+
+	metrics.Conversions.WithLabelValues("recorded").Inc()
+	s.log(ctx).Info("Conversion recorded", "short_code", receipt.shortCode)
+	return &domain.ConversionResponse{ShortCode: receipt.shortCode}, nil
+}
+
+// conversionRate is conversions per counted click, 0 before the first click
+func conversionRate(conversions, clicks int64) float64 {
+	if clicks <= 0 {
+		return 0
+	}
+	return float64(conversions) / float64(clicks)
+}
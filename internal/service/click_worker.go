@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"sync"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/redirect"
+	"url-shortener/pkg/logger"
+)
+
+// defaultClickQueueSize is used when CLICK_QUEUE_SIZE is not configured
+const defaultClickQueueSize = 1024
+
+// clickJob is a click recorded off the request path
+type clickJob struct {
+	shortCode string
+	tenant    string // Owner of the link, set on the context the click is recorded with
+	result    redirect.Result
+	visitor   domain.Visitor
+	prefetch  bool           // A link preview shown the confirm page, counted apart from clicks
+	log       *logger.Logger // Logger of the request the click came from
+}
+
+// clickWorker owns the goroutine that persists clicks from cache hits
+// Unlike fire-and-forget goroutines it can be drained on shutdown, so deploys don't lose clicks
+type clickWorker struct {
+	jobs chan clickJob
+	done chan struct{}
+
+	mu     sync.RWMutex // Guards closed against concurrent sends on a closing channel
+	closed bool
+}
+
+// startClickWorker launches the worker; record is called for every job in order
+func startClickWorker(size int, record func(clickJob)) *clickWorker {
+	if size <= 0 {
+		size = defaultClickQueueSize
+	}
+
+	w := &clickWorker{
+		jobs: make(chan clickJob, size),
+		done: make(chan struct{}),
+	}
+
+	go func() {
+		defer close(w.done)
+		for job := range w.jobs {
+			record(job)
+		}
+	}()
+
+	return w
+}
+
+// enqueue hands a click to the worker without blocking
+// It returns false when the queue is full or closed, and the caller records the click itself
+func (w *clickWorker) enqueue(job clickJob) bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if w.closed {
+		return false
+	}
+
+	select {
+	case w.jobs <- job:
+		return true
+	default:
+		return false
+	}
+}
+
+// close stops accepting clicks and waits for the queue to drain or ctx to expire
+// Returns the number of clicks still queued when ctx expired
+func (w *clickWorker) close(ctx context.Context) (int, error) {
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.jobs)
+	}
+	w.mu.Unlock()
+
+	select {
+	case <-w.done:
+		return 0, nil
+	case <-ctx.Done():
+		return len(w.jobs), ctx.Err()
+	}
+}
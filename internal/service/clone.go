@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"url-shortener/internal/domain"
+)
+
+// CloneURL creates a new link with the settings of an existing one
+// Deactivated and expired links can be cloned; the clone always starts active with a fresh expiry
+func (s *urlService) CloneURL(ctx context.Context, shortCode string, req *domain.CloneURLRequest, creator domain.CreatorContext) (*domain.CreateURLResponse, error) {
+	creator = cleanCreator(creator)
+
+	// Step 1: Load the source, including deactivated links
+	source, err := s.repo.FindAnyByShortCode(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+
+	// Members belong to their bundle, so a copy of the page would share them
+	if source.IsBundle() {
+		return nil, domain.NewValidationError("bundles cannot be cloned")
+	}
+
+	// Step 2: Validate the new destination, or keep the source's
+	destination, submittedURL := source.OriginalURL, source.SubmittedURL
+	if req.URL != "" {
+		if err := s.urlValidator.ValidateURL(req.URL); err != nil {
+			s.log(ctx).Warn("Invalid URL provided", "url", req.URL, "error", err)
+			return nil, domain.NewFieldError("url", "format", "Invalid URL format")
+		}
+		destination, submittedURL, err = s.resolveChain(ctx, s.normalizeURL(req.URL))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	code, err := s.chooseShortCode(req.CustomAlias)
+	if err != nil {
+		return nil, err
+	}
+
+	// Step 3: Copy everything but identity, counters and metadata of the old destination
+	clone := &domain.URL{
+		ShortCode:            code,
+		OriginalURL:          destination,
+		SubmittedURL:         submittedURL,
+		Title:                source.Title,
+		Description:          source.Description,
+		ExpiresAt:            cloneExpiry(source),
+		IsActive:             true,
+		CustomAlias:          req.CustomAlias != "",
+		RequiresInterstitial: source.RequiresInterstitial,
+		ConfirmBeforeRedirect: source.ConfirmBeforeRedirect,
+		UTM:                  source.UTM,
+		Targets:              source.Targets,
+		Variants:             source.Variants,
+		StickyVariants:       source.StickyVariants,
+		ForwardQuery:         source.ForwardQuery,
+		AttachClickID:        source.AttachClickID,
+		PublicStats:          source.PublicStats,
+		ReferrerPolicy:       source.ReferrerPolicy,
+		DeepLink:             source.DeepLink,
+		IOSFallbackURL:       source.IOSFallbackURL,
+		AndroidFallbackURL:   source.AndroidFallbackURL,
+	}
+	setCreator(creator, clone)
+	if err := s.beforeCreate(ctx, clone); err != nil {
+		return nil, err
+	}
+
+	managementToken, err := issueManagementToken(clone)
+	if err != nil {
+		return nil, err
+	}
+
+	// Step 4: A clone is a new link and counts against the creation quota
+	quota, releaseQuota, err := s.reserveQuota(ctx, creator.IP, apiKeyFromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.insertURL(ctx, clone); err != nil {
+		releaseQuota()
+		if !errors.Is(err, domain.ErrShortCodeTaken) {
+			s.log(ctx).Error("Failed to clone URL", "error", err, "source", shortCode)
+		}
+		return nil, err
+	}
+
+	s.afterCreate(ctx, clone)
+
+	// Step 5: Cache the clone like any new link
+	if s.cache != nil && !s.linkRequiresInterstitial(clone) {
+		s.cacheLink(ctx, clone)
+	}
+
+	s.log(ctx).Info("URL cloned",
+		"short_code", clone.ShortCode,
+		"source", shortCode,
+		"original_url", clone.OriginalURL,
+	)
+
+	s.enrichAsync(ctx, clone.ShortCode, clone.OriginalURL)
+	s.snapshotAsync(ctx, clone.ShortCode, clone.OriginalURL)
+
+	response := s.buildResponse(ctx, clone)
+	response.ManagementToken = managementToken
+	response.ClonedFrom = source.ShortCode
+	response.Quota = quota
+	return response, nil
+}
+
+// cloneExpiry gives a clone the lifetime the source was created with, counted from now
+// Links that never expire stay that way
+func cloneExpiry(source *domain.URL) *time.Time {
+	if source.ExpiresAt == nil {
+		return nil
+	}
+
+	expiry := time.Now().Add(source.ExpiresAt.Sub(source.CreatedAt))
+	return &expiry
+}
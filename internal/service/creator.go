@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"url-shortener/internal/clientip"
+	"url-shortener/internal/domain"
+)
+
+// maxCreatorPageSize caps the links returned per page of ListByCreatorIP
+const maxCreatorPageSize = 100
+
+// cleanCreator canonicalizes the creator IP, drops control characters from the rest and truncates it to the stored lengths
+// They come straight from request headers, so they are normalized and bounded before they reach the database
+func cleanCreator(creator domain.CreatorContext) domain.CreatorContext {
+	creator.IP = clientip.Canonical(creator.IP)
+	creator.UserAgent = truncateRunes(stripControl(creator.UserAgent), domain.MaxCreatorUserAgentLength)
+	creator.Origin = truncateRunes(stripControl(creator.Origin), domain.MaxCreatorOriginLength)
+	return creator
+}
+
+// stripControl removes control characters and surrounding whitespace
+func stripControl(s string) string {
+	return strings.TrimSpace(strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, s))
+}
+
+// truncateRunes shortens s to at most max characters without splitting one
+func truncateRunes(s string, max int) string {
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	return string([]rune(s)[:max])
+}
+
+// setCreator stores the creator metadata on links about to be created
+func setCreator(creator domain.CreatorContext, urls ...*domain.URL) {
+	for _, url := range urls {
+		url.CreatorIP = creator.IP
+		url.CreatorUserAgent = creator.UserAgent
+		url.CreatorOrigin = creator.Origin
+	}
+}
+
+// GetAdminURLInfo returns GetURLInfo's view of a link plus who created it
+// Deactivated links are included, since they are often the ones under investigation
+func (s *urlService) GetAdminURLInfo(ctx context.Context, shortCode string) (*domain.AdminURLInfoResponse, error) {
+	url, err := s.repo.FindAnyByShortCode(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.buildAdminInfoResponse(ctx, url), nil
+}
+
+// ListByCreatorIP pages through the links created from one IP address, newest first
+func (s *urlService) ListByCreatorIP(ctx context.Context, ip string, limit, offset int) (*domain.CreatorLinksPage, error) {
+	ip = clientip.Canonical(ip)
+	if net.ParseIP(ip) == nil {
+		return nil, domain.NewValidationError(fmt.Sprintf("creator_ip %q is not an IP address", ip))
+	}
+	if limit <= 0 || limit > maxCreatorPageSize {
+		limit = maxCreatorPageSize
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	// One extra row tells whether another page follows without a separate count
+	urls, err := s.repo.FindByCreatorIP(ctx, ip, limit+1, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &domain.CreatorLinksPage{
+		CreatorIP: ip,
+		URLs:      make([]*domain.AdminURLInfoResponse, 0, limit),
+		Limit:     limit,
+		Offset:    offset,
+	}
+	if len(urls) > limit {
+		urls = urls[:limit]
+		next := offset + limit
+		page.NextOffset = &next
+	}
+	for i := range urls {
+		page.URLs = append(page.URLs, s.buildAdminInfoResponse(ctx, &urls[i]))
+	}
+	return page, nil
+}
+
+// buildAdminInfoResponse adds the creator metadata to the public info response
+func (s *urlService) buildAdminInfoResponse(ctx context.Context, url *domain.URL) *domain.AdminURLInfoResponse {
+	return &domain.AdminURLInfoResponse{
+		URLInfoResponse: *s.buildInfoResponse(ctx, url),
+		Creator: domain.CreatorInfo{
+			IP:        url.CreatorIP,
+			UserAgent: url.CreatorUserAgent,
+			Origin:    url.CreatorOrigin,
+		},
+	}
+}
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"time"
+
+	"url-shortener/internal/domain"
+)
+
+// statsSeriesDays is how many UTC days GetStats reports, today included
+const statsSeriesDays = 30
+
+// dailySeries combines rolled-up days with today's partial counts from raw events
+// Days without clicks are filled with zeros so charts get one point per day
+func (s *urlService) dailySeries(ctx context.Context, shortCode string, now time.Time) ([]domain.DailyClickStats, error) {
+	now = now.UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	from := today.AddDate(0, 0, -(statsSeriesDays - 1))
+
+	rolledUp, err := s.clicks.GetDailySeries(ctx, shortCode, from, today)
+	if err != nil {
+		return nil, err
+	}
+
+	partial, err := s.clicks.AggregateDay(ctx, shortCode, today)
+	if err != nil {
+		return nil, err
+	}
+
+	byDate := make(map[string]domain.DailyClickStats, len(rolledUp)+1)
+	for _, day := range rolledUp {
+		byDate[day.Date] = day
+	}
+	byDate[partial.Date] = *partial
+
+	series := make([]domain.DailyClickStats, 0, statsSeriesDays)
+	for day := from; !day.After(today); day = day.AddDate(0, 0, 1) {
+		date := day.Format("2006-01-02")
+		stats, ok := byDate[date]
+		if !ok {
+			stats = domain.DailyClickStats{Date: date}
+		}
+		stats.ShortCode = shortCode
+		series = append(series, stats)
+	}
+
+	return series, nil
+}
+
+// referrerHost reduces a Referer header to its host
+// Our own pages, such as the interstitial, are not a referrer worth reporting
+func (s *urlService) referrerHost(referrer string) string {
+	if referrer == "" {
+		return ""
+	}
+
+	parsed, err := url.Parse(referrer)
+	if err != nil || parsed.Hostname() == "" {
+		return ""
+	}
+
+	host := strings.ToLower(parsed.Hostname())
+	if base, err := url.Parse(s.cfg.BaseURL); err == nil && strings.EqualFold(base.Hostname(), host) {
+		return ""
+	}
+
+	if len(host) > 255 {
+		host = host[:255]
+	}
+	return host
+}
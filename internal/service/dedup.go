@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+
+	"url-shortener/internal/cache"
+	"url-shortener/internal/domain"
+)
+
+// dedupFingerprint identifies what two create requests must share to be answered with the same link
+func dedupFingerprint(destination string, utm domain.UTMParams, forwardQuery bool, policy domain.ReferrerPolicy) string {
+	data, _ := json.Marshal(struct {
+		URL            string                `json:"url"`
+		UTM            domain.UTMParams      `json:"utm"`
+		ForwardQuery   bool                  `json:"forward_query"`
+		ReferrerPolicy domain.ReferrerPolicy `json:"referrer_policy"`
+	}{destination, utm, forwardQuery, policy})
+	return string(data)
+}
+
+// shareable reports whether later requests for the same destination may be answered with url
+// Links with public stats are never shared, their page would show one creator the other's clicks
+func shareable(url *domain.URL) bool {
+	return url.IsActive && !hasRules(url) && !url.IsBundle() && !url.PublicStats
+}
+
+// dedupCacheEnabled reports whether the destination → short code lookup is cached
+func (s *urlService) dedupCacheEnabled() bool {
+	return s.cache != nil && s.cfg.DedupCacheTTL > 0
+}
+
+// findCachedDuplicate returns the link cached for fingerprint, or nil on a miss
+// Cache errors count as a miss, the database lookup still follows
+func (s *urlService) findCachedDuplicate(ctx context.Context, fingerprint string) *domain.URL {
+	if !s.dedupCacheEnabled() {
+		return nil
+	}
+
+	cached, err := s.cache.Get(ctx, tenantKey(ctx, cache.DedupKey(fingerprint)))
+	if err != nil || cached == "" {
+		return nil
+	}
+
+	entry, ok := cache.DecodeDedupEntry(cached)
+	if !ok {
+		s.log(ctx).Warn("Ignoring malformed dedup cache entry")
+		return nil
+	}
+
+	url := entry.URL()
+	if url.IsExpired() {
+		return nil
+	}
+	return url
+}
+
+// rememberDuplicate caches url as the answer to later requests for the same destination
+// The entry never outlives the link, and the back reference lets invalidateLink find it
+func (s *urlService) rememberDuplicate(ctx context.Context, url *domain.URL) {
+	if !s.dedupCacheEnabled() || !shareable(url) {
+		return
+	}
+
+	ttl := linkTTL(s.cfg.DedupCacheTTL, url)
+	if ttl <= 0 {
+		return
+	}
+
+	key := tenantKey(ctx, cache.DedupKey(dedupFingerprint(url.OriginalURL, url.UTM, url.ForwardQuery, url.ReferrerPolicy)))
+	s.setCacheAsync(ctx, url.ShortCode, key, cache.NewDedupEntry(url).Encode(), ttl)
+	s.setCacheAsync(ctx, url.ShortCode, s.codeKey(ctx, cache.DedupRefKey(url.ShortCode)), key, ttl)
+}
+
+// forgetDuplicate drops the dedup entry pointing at shortCode, if there is one
+func (s *urlService) forgetDuplicate(ctx context.Context, shortCode string) {
+	if !s.dedupCacheEnabled() {
+		return
+	}
+
+	refKey := s.codeKey(ctx, cache.DedupRefKey(shortCode))
+	key, err := s.cache.Get(ctx, refKey)
+	if err != nil || key == "" {
+		return
+	}
+
+	for _, k := range []string{key, refKey} {
+		if err := s.cache.Delete(ctx, k); err != nil {
+			s.log(ctx).Warn("Failed to delete dedup entry", "error", err, "short_code", shortCode)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package service
+
+import (
+	"context"
+
+	"url-shortener/internal/domain"
+)
+
+// previewURL answers a dry run with the link a create request would produce, without saving it
+// Custom aliases are looked up, inactive links included, so a taken alias fails as it would on insert
+func (s *urlService) previewURL(ctx context.Context, url *domain.URL, hashed bool) (*domain.CreateURLResponse, error) {
+	if url.CustomAlias {
+		taken, err := s.repo.ExistsManyByShortCode(s.codeLookupContext(ctx), []string{url.ShortCode})
+		if err != nil {
+			s.log(ctx).Error("Failed to check custom alias", "error", err, "short_code", url.ShortCode)
+			return nil, err
+		}
+		if taken[url.ShortCode] {
+			return nil, domain.ErrShortCodeTaken
+		}
+	}
+
+	response := s.buildResponse(ctx, url)
+	response.DryRun = true
+	if !url.CustomAlias && !hashed {
+		// A random code isn't reserved, so the real create would pick a different one
+		response.ShortCode = ""
+		response.ShortURL = ""
+	}
+
+	s.log(ctx).Info("Dry run shorten validated", "short_code", response.ShortCode, "custom", url.CustomAlias)
+	return response, nil
+}
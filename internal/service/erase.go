@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"url-shortener/internal/cache"
+	"url-shortener/internal/domain"
+)
+
+// purgeDeletedBatch is how many links one repository call erases during a retention purge
+const purgeDeletedBatch = 500
+
+// HardDeleteURL erases a link for an erasure request, unlike DeleteURL which only deactivates it
+// The row, its click events, daily stats, audit entries and unpublished events go in one transaction
+// together with the link.deleted event; the cache and the snapshot are cleaned up after the commit.
+func (s *urlService) HardDeleteURL(ctx context.Context, shortCode string, actor domain.Actor) error {
+	// Step 1: Erase the link and everything recorded about it
+	err := s.withEvents(ctx, func(ctx context.Context) error {
+		return s.repo.HardDelete(ctx, shortCode)
+	}, func() []*domain.OutboxEvent {
+		return []*domain.OutboxEvent{newEvent(domain.EventLinkDeleted, shortCode, domain.LinkEventData{})}
+	})
+	if err != nil {
+		s.log(ctx).Error("Failed to erase URL", "error", err, "short_code", shortCode)
+		return err
+	}
+
+	// Step 2: Drop what lives outside the database
+	s.eraseOutsideDatabase(ctx, shortCode)
+	s.afterDelete(ctx, shortCode)
+
+	s.log(ctx).Warn("URL erased",
+		"short_code", shortCode,
+		"actor", actor.ID,
+		"ip", actor.IP,
+	)
+
+	// Step 3: Record the erasure itself; it names only the code, not what the link pointed to
+	if s.audit != nil {
+		entry := &domain.AuditEntry{
+			Action:    domain.AuditActionErase,
+			ShortCode: shortCode,
+			ActorID:   actor.ID,
+			ActorIP:   actor.IP,
+		}
+		if err := s.audit.Record(ctx, entry); err != nil {
+			s.log(ctx).Error("Failed to record audit entry", "error", err, "short_code", shortCode, "action", entry.Action)
+		}
+	}
+
+	return nil
+}
+
+// PurgeDeleted erases inactive links in batches until none older than before is left
+// Every batch is its own transaction, so a failure keeps what earlier batches erased
+func (s *urlService) PurgeDeleted(ctx context.Context, before time.Time) (int, error) {
+	erased := 0
+	for {
+		links, err := s.repo.HardDeleteInactive(ctx, before, purgeDeletedBatch)
+		if err != nil {
+			return erased, err
+		}
+		for _, link := range links {
+			s.eraseOutsideDatabase(domain.ContextWithTenant(ctx, link.TenantID), link.ShortCode)
+		}
+		erased += len(links)
+
+		if len(links) < purgeDeletedBatch {
+			return erased, nil
+		}
+	}
+}
+
+// eraseOutsideDatabase removes an erased link's cache entries and destination snapshot
+// The database erasure is already committed, so failures are logged; cached entries expire on their own.
+// Cached time series and heat maps are keyed by the link's ID, so a new link taking the code doesn't see them.
+func (s *urlService) eraseOutsideDatabase(ctx context.Context, shortCode string) {
+	s.invalidateLink(ctx, shortCode)
+	s.forgetVisits(ctx, shortCode)
+	if s.cache != nil {
+		if err := s.cache.Delete(ctx, s.codeKey(ctx, cache.InactiveKey(shortCode))); err != nil {
+			s.log(ctx).Warn("Failed to delete negative cache entry", "error", err, "short_code", shortCode)
+		}
+	}
+
+	if s.snapshots != nil {
+		if err := s.snapshots.Delete(ctx, shortCode); err != nil {
+			s.log(ctx).Error("Failed to delete destination snapshot", "error", err, "short_code", shortCode)
+		}
+	}
+}
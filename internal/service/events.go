@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"math/rand"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/redirect"
+)
+
+// withEvents runs mutate and appends the events describing it in the same transaction
+// events is called after mutate so it sees what the database filled in
+// Without an outbox mutate simply runs on its own
+func (s *urlService) withEvents(ctx context.Context, mutate func(ctx context.Context) error, events func() []*domain.OutboxEvent) error {
+	if s.outbox == nil {
+		return mutate(ctx)
+	}
+	
+	return s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := mutate(ctx); err != nil {
+			return err
+		}
+		if batch := events(); len(batch) > 0 {
+			return s.outbox.Append(ctx, batch...)
+		}
+		return nil
+	})
+}
+
+// createURL inserts url together with its link.created event
+func (s *urlService) createURL(ctx context.Context, url *domain.URL) error {
+	url.CodeScope = s.codeScope(ctx)
+	return s.withEvents(ctx, func(ctx context.Context) error {
+		return s.repo.Create(ctx, url)
+	}, func() []*domain.OutboxEvent {
+		return []*domain.OutboxEvent{createdEvent(url)}
+	})
+}
+
+// createdEvent describes a new link
+func createdEvent(url *domain.URL) *domain.OutboxEvent {
+	return newEvent(domain.EventLinkCreated, url.ShortCode, domain.LinkEventData{
+		OriginalURL: url.OriginalURL,
+		ExpiresAt:   url.ExpiresAt,
+	})
+}
+
+// clickEvents returns the events for one click, honouring EVENTS_CLICK_SAMPLING
+// A sampled event carries the number of clicks it stands for so consumers can scale counts back up
+func (s *urlService) clickEvents(shortCode string, result redirect.Result, visitor domain.Visitor) []*domain.OutboxEvent {
+	every := s.cfg.EventsClickSampling
+	if every <= 0 || (every > 1 && rand.Intn(every) != 0) {
+		return nil
+	}
+	
+	return []*domain.OutboxEvent{newEvent(domain.EventLinkClicked, shortCode, domain.LinkEventData{
+		Target:  result.Target,
+		Variant: result.Variant,
+		Country: visitor.Country,
+		Weight:  every,
+	})}
+}
+
+// newEvent builds an outbox row; the payload only holds plain fields, so marshalling can't fail
+func newEvent(eventType, shortCode string, data domain.LinkEventData) *domain.OutboxEvent {
+	payload, _ := json.Marshal(data)
+	return &domain.OutboxEvent{
+		Type:      eventType,
+		ShortCode: shortCode,
+		Payload:   string(payload),
+	}
+}
@@ -0,0 +1,37 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"url-shortener/internal/domain"
+)
+
+const (
+	// MaxExpiringDays is the furthest ahead the expiring links endpoint looks
+	MaxExpiringDays = 90
+
+	// maxExpiringLinks caps one listing; the soonest to expire come first
+	maxExpiringLinks = 1000
+)
+
+// ListExpiring returns the active links that expire within the next days days, soonest first
+func (s *urlService) ListExpiring(ctx context.Context, days int) ([]*domain.URLInfoResponse, error) {
+	if days < 1 || days > MaxExpiringDays {
+		return nil, domain.NewValidationError(fmt.Sprintf("days must be between 1 and %d", MaxExpiringDays))
+	}
+
+	now := time.Now()
+	urls, err := s.repo.FindExpiringBetween(ctx, now, now.AddDate(0, 0, days), maxExpiringLinks)
+	if err != nil {
+		s.log(ctx).Error("Failed to list expiring URLs", "error", err)
+		return nil, err
+	}
+
+	infos := make([]*domain.URLInfoResponse, len(urls))
+	for i := range urls {
+		infos[i] = s.buildInfoResponse(ctx, &urls[i])
+	}
+	return infos, nil
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"url-shortener/internal/cache"
+	"url-shortener/internal/domain"
+)
+
+// MaxExtendDays bounds a single extension
+const MaxExtendDays = 3650
+
+// ExtendURL pushes out a link's expiry and rewrites its cache entry
+// An expired link is only brought back when the request allows it, counting from now
+func (s *urlService) ExtendURL(ctx context.Context, shortCode string, req *domain.ExtendURLRequest) (*domain.URL, error) {
+	if req.Days < 1 || req.Days > MaxExtendDays {
+		return nil, domain.NewValidationError(fmt.Sprintf("days must be between 1 and %d", MaxExtendDays))
+	}
+	from := req.From
+	if from == "" {
+		from = domain.ExtendFromExpiry
+	}
+	if from != domain.ExtendFromExpiry && from != domain.ExtendFromNow {
+		return nil, domain.NewValidationError(fmt.Sprintf("from must be %q or %q", domain.ExtendFromExpiry, domain.ExtendFromNow))
+	}
+
+	// Step 1: Load the link; the cleanup job deactivates expired links, so inactive rows are included
+	url, err := s.repo.FindAnyByShortCode(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+	expired := url.IsExpired()
+	if !url.IsActive && !expired {
+		// Deactivated by an admin, which an extension must not undo
+		return nil, domain.ErrURLNotFound
+	}
+	if url.ExpiresAt == nil {
+		return nil, domain.NewValidationError("Link never expires, there is nothing to extend")
+	}
+
+	// Step 2: Compute the new expiry
+	now := time.Now()
+	base := *url.ExpiresAt
+	revived := false
+	switch {
+	case expired && !req.AllowRevive:
+		return nil, domain.NewAppError(domain.ErrURLExpired, "Link has expired, pass allow_revive to reactivate it", 410, false)
+	case expired:
+		base = now
+		revived = true
+	case from == domain.ExtendFromNow:
+		base = now
+	}
+	expiresAt := base.AddDate(0, 0, req.Days)
+	if expiresAt.Before(*url.ExpiresAt) {
+		return nil, domain.NewValidationError("Extension would bring the expiry forward")
+	}
+
+	// Step 3: Persist; the new expiry gets its own expiry notice
+	url.ExpiresAt = &expiresAt
+	url.IsActive = true
+	url.ExpiryNotifiedAt = nil
+	if err := s.repo.Update(ctx, url); err != nil {
+		s.log(ctx).Error("Failed to extend URL", "error", err, "short_code", shortCode)
+		return nil, err
+	}
+
+	// Step 4: Replace the cached entry, whose TTL was bound to the old expiry
+	if s.cache != nil {
+		s.invalidateLink(ctx, shortCode)
+		if revived {
+			if err := s.cache.Delete(ctx, s.codeKey(ctx, cache.InactiveKey(shortCode))); err != nil {
+				s.log(ctx).Warn("Failed to clear negative cache entry", "error", err, "short_code", shortCode)
+			}
+		}
+		s.cacheLink(ctx, url)
+	}
+
+	s.log(ctx).Info("URL expiry extended", "short_code", shortCode, "expires_at", expiresAt, "revived", revived)
+	return url, nil
+}
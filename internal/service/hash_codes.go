@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"url-shortener/internal/config"
+	"url-shortener/internal/domain"
+)
+
+// maxHashCodeLength is the short_code column size; collisions can extend codes up to it
+const maxHashCodeLength = 12
+
+// hashCodes reports whether the new link gets a code derived from its destination
+// Custom aliases keep their name, and links with rules are never shared, so both use the normal path
+func (s *urlService) hashCodes(req *domain.CreateURLRequest, targets domain.Targets, variants domain.Variants) bool {
+	return s.cfg.ShortCodeStrategy == config.ShortCodeStrategyHash &&
+		req.CustomAlias == "" && len(targets) == 0 && len(variants) == 0 && req.DeepLink == ""
+}
+
+// createHashed inserts url under its hash-derived code without checking for the code first
+// On a unique-constraint conflict the row holding the code is loaded:
+//   - same destination: another request created this link already, and it is returned instead
+//   - anything else (different URL, deactivated or expired row): a true collision, so the code is
+//     extended by one character of the same hash and the insert retried
+//
+// Because extended codes are prefixes of each other, every replica walks the same sequence
+func (s *urlService) createHashed(ctx context.Context, url *domain.URL) (*domain.URL, error) {
+	destination := url.Destination()
+
+	for {
+		// Each attempt gets its own transaction; a failed insert aborts the one it ran in
+		err := s.createURL(ctx, url)
+		if err == nil {
+			return nil, nil
+		}
+		if !errors.Is(err, domain.ErrShortCodeTaken) {
+			return nil, err
+		}
+
+		existing, err := s.repo.FindByShortCode(ctx, url.ShortCode)
+		if err != nil && !errors.Is(err, domain.ErrURLNotFound) {
+			return nil, err
+		}
+		if err == nil && sameDestination(existing, url) {
+			return existing, nil
+		}
+
+		next := len(url.ShortCode) + 1
+		if next > maxHashCodeLength {
+			return nil, domain.NewInternalError(fmt.Errorf("hash code collisions up to %d characters for %s", maxHashCodeLength, destination))
+		}
+
+		s.log(ctx).Warn("Hash code collision, extending code", "short_code", url.ShortCode, "length", next)
+		url.ShortCode = s.generator.GenerateFromContent(destination, next)
+	}
+}
+
+// sameDestination reports whether existing can be handed out in place of the new link
+func sameDestination(existing, url *domain.URL) bool {
+	return existing.OriginalURL == url.OriginalURL && existing.UTM == url.UTM && existing.ForwardQuery == url.ForwardQuery &&
+		existing.ReferrerPolicy == url.ReferrerPolicy && existing.AttachClickID == url.AttachClickID &&
+		existing.PublicStats == url.PublicStats &&
+		!hasRules(existing) && !existing.IsBundle() && !existing.IsExpired()
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"url-shortener/internal/cache"
+	"url-shortener/internal/domain"
+)
+
+// MaxHeatmapDays is the longest range a click heat map may cover; it is counted from raw click events
+const MaxHeatmapDays = 366
+
+// defaultHeatmapDays is the range covered when from is omitted: four whole weeks, so every weekday counts alike
+const defaultHeatmapDays = 28
+
+// GetClickHeatmap returns the clicks of a link per weekday and hour of day in the query's time zone
+// Ranges that ended before the current hour can't change anymore and are cached
+func (s *urlService) GetClickHeatmap(ctx context.Context, shortCode string, query domain.HeatmapQuery) (*domain.ClickHeatmap, error) {
+	if s.clicks == nil {
+		return nil, domain.NewAppError(errors.New("click events are not recorded"), "Click heat maps are not available", 409, false)
+	}
+
+	// Step 1: Apply the defaults and check the range
+	loc := query.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	now := time.Now().UTC()
+	from, to := query.From, query.To
+	if to.IsZero() {
+		to = now
+	}
+	if from.IsZero() {
+		from = to.In(loc).AddDate(0, 0, -defaultHeatmapDays)
+	}
+	if !from.Before(to) {
+		return nil, domain.NewValidationError("'from' must be before 'to'")
+	}
+	if to.Sub(from) > MaxHeatmapDays*24*time.Hour {
+		return nil, domain.NewValidationError(fmt.Sprintf("a heat map covers at most %d days", MaxHeatmapDays))
+	}
+
+	// Step 2: Unknown codes are a 404 rather than a matrix of zeros
+	link, err := s.repo.FindAnyByShortCode(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+
+	// Step 3: A range that has ended comes from the cache when it's there
+	settled := !to.After(now.Truncate(time.Hour))
+	key := tenantKey(ctx, cache.HeatmapKey(shortCode, statsGeneration(link), loc.String(), from, to))
+	if settled && s.cache != nil {
+		if cached, err := s.cache.Get(ctx, key); err == nil && cached != "" {
+			var heatmap domain.ClickHeatmap
+			if err := json.Unmarshal([]byte(cached), &heatmap); err == nil {
+				return &heatmap, nil
+			}
+			s.log(ctx).Warn("Ignoring malformed heat map cache entry", "key", key)
+		}
+	}
+
+	// Step 4: Count in the database and place the cells in a full matrix, so charts don't have to fill gaps
+	cells, err := s.clicks.CountByWeekdayHour(ctx, shortCode, from, to, loc)
+	if err != nil {
+		s.log(ctx).Error("Failed to count clicks per weekday and hour", "error", err, "short_code", shortCode)
+		return nil, err
+	}
+
+	heatmap := &domain.ClickHeatmap{ShortCode: shortCode, TimeZone: loc.String(), From: from.In(loc), To: to.In(loc)}
+	for _, cell := range cells {
+		if cell.Weekday < 0 || cell.Weekday > 6 || cell.Hour < 0 || cell.Hour > 23 {
+			continue
+		}
+		heatmap.Clicks[cell.Weekday][cell.Hour] += cell.Clicks
+		heatmap.Total += cell.Clicks
+	}
+
+	if settled && s.cache != nil {
+		if data, err := json.Marshal(heatmap); err == nil {
+			if err := s.cache.Set(ctx, key, string(data), s.cfg.CacheTTL); err != nil {
+				s.log(ctx).Warn("Failed to cache click heat map", "error", err, "short_code", shortCode)
+			}
+		}
+	}
+	return heatmap, nil
+}
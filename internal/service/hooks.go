@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+
+	"url-shortener/internal/domain"
+)
+
+// Hooks are callbacks custom builds run around the service's operations, see WithHooks
+// Every field is optional; a nil one is skipped. Before hooks veto the operation by returning an error,
+// which the caller gets as is, so a domain error or an AppError picks the response. After hooks run once
+// the change is saved and can't undo it; their errors are only logged.
+type Hooks struct {
+	// BeforeCreate sees a new link, bundle member or clone once it is fully built, before it is saved or
+	// previewed by a dry run. It may change the link, except for its short code.
+	BeforeCreate func(ctx context.Context, url *domain.URL) error
+	// AfterCreate sees a link once it is saved; deduplicated requests created nothing and don't call it
+	AfterCreate func(ctx context.Context, url *domain.URL) error
+	// BeforeRedirect sees where a visitor is about to be sent. The click is already counted by then,
+	// so a veto only stops the redirect. Hits counted without a redirect don't call it.
+	BeforeRedirect func(ctx context.Context, decision *domain.RedirectDecision) error
+	// AfterDelete sees the short code of a link once it is deactivated or erased
+	AfterDelete func(ctx context.Context, shortCode string) error
+}
+
+// beforeCreate runs the BeforeCreate hook for each of urls, stopping at the first veto
+func (s *urlService) beforeCreate(ctx context.Context, urls ...*domain.URL) error {
+	if s.hooks.BeforeCreate == nil {
+		return nil
+	}
+	for _, url := range urls {
+		if err := s.hooks.BeforeCreate(ctx, url); err != nil {
+			s.log(ctx).Info("Link creation vetoed by hook", "error", err, "short_code", url.ShortCode)
+			return err
+		}
+	}
+	return nil
+}
+
+// afterCreate runs the AfterCreate hook for each of urls
+func (s *urlService) afterCreate(ctx context.Context, urls ...*domain.URL) {
+	if s.hooks.AfterCreate == nil {
+		return
+	}
+	for _, url := range urls {
+		if err := s.hooks.AfterCreate(ctx, url); err != nil {
+			s.log(ctx).Error("AfterCreate hook failed", "error", err, "short_code", url.ShortCode)
+		}
+	}
+}
+
+// beforeRedirect runs the BeforeRedirect hook for decision
+func (s *urlService) beforeRedirect(ctx context.Context, decision *domain.RedirectDecision) error {
+	if s.hooks.BeforeRedirect == nil {
+		return nil
+	}
+	if err := s.hooks.BeforeRedirect(ctx, decision); err != nil {
+		s.log(ctx).Info("Redirect vetoed by hook", "error", err, "short_code", decision.ShortCode)
+		return err
+	}
+	return nil
+}
+
+// afterDelete runs the AfterDelete hook for shortCode
+func (s *urlService) afterDelete(ctx context.Context, shortCode string) {
+	if s.hooks.AfterDelete == nil {
+		return
+	}
+	if err := s.hooks.AfterDelete(ctx, shortCode); err != nil {
+		s.log(ctx).Error("AfterDelete hook failed", "error", err, "short_code", shortCode)
+	}
+}
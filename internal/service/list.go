@@ -0,0 +1,42 @@
+package service
+
+import (
+	"context"
+
+	"url-shortener/internal/domain"
+)
+
+// maxListPageSize caps the links returned per page of ListURLs
+const maxListPageSize = 100
+
+// ListURLs pages through the active links matching the filter, newest first
+func (s *urlService) ListURLs(ctx context.Context, filter domain.URLFilter, limit, offset int) (*domain.URLPage, error) {
+	if limit <= 0 || limit > maxListPageSize {
+		limit = maxListPageSize
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	// One extra row tells whether another page follows without a separate count
+	urls, err := s.repo.FindActiveMatching(ctx, filter, limit+1, offset)
+	if err != nil {
+		s.log(ctx).Error("Failed to list URLs", "error", err)
+		return nil, err
+	}
+
+	page := &domain.URLPage{
+		URLs:   make([]*domain.URLInfoResponse, 0, limit),
+		Limit:  limit,
+		Offset: offset,
+	}
+	if len(urls) > limit {
+		urls = urls[:limit]
+		next := offset + limit
+		page.NextOffset = &next
+	}
+	for i := range urls {
+		page.URLs = append(page.URLs, s.buildInfoResponse(ctx, &urls[i]))
+	}
+	return page, nil
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"url-shortener/internal/domain"
+	"url-shortener/pkg/logger"
+	"url-shortener/pkg/validator"
+)
+
+// enrichAsync fetches metadata for a new link without delaying the create response
+// The fetch outlives the request, so only the request's logger and tenant are carried over from ctx
+func (s *urlService) enrichAsync(ctx context.Context, shortCode, destination string) {
+	// mailto: and tel: links have no page to read metadata from
+	if s.metadata == nil || !validator.IsWebURL(destination) {
+		return
+	}
+
+	bg := domain.CopyTenant(logger.WithContext(context.Background(), s.log(ctx)), ctx)
+	s.enrichments.Add(1)
+	go func() {
+		defer s.enrichments.Done()
+		s.enrich(bg, shortCode, destination)
+	}()
+}
+
+// enrich fetches and stores the title and favicon of destination
+// A failed fetch is stored as null so the link itself is never affected
+func (s *urlService) enrich(ctx context.Context, shortCode, destination string) (*string, *string) {
+	var pageTitle, faviconURL *string
+
+	meta, err := s.metadata.Fetch(ctx, destination)
+	if err != nil {
+		s.log(ctx).Info("Failed to fetch link metadata", "short_code", shortCode, "error", err)
+	} else {
+		pageTitle = optionalString(meta.Title)
+		faviconURL = optionalString(meta.FaviconURL)
+	}
+
+	if err := s.repo.UpdateMetadata(ctx, shortCode, pageTitle, faviconURL); err != nil {
+		s.log(ctx).Warn("Failed to store link metadata", "short_code", shortCode, "error", err)
+	}
+
+	return pageTitle, faviconURL
+}
+
+// RefreshMetadata re-runs enrichment synchronously, e.g. after the destination page changed
+func (s *urlService) RefreshMetadata(ctx context.Context, shortCode string) (*domain.URL, error) {
+	if s.metadata == nil {
+		return nil, domain.NewAppError(errors.New("metadata enrichment is disabled"), "Metadata enrichment is disabled", 409, false)
+	}
+
+	url, err := s.repo.FindByShortCode(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+
+	// The "destination" of a bundle is its own landing page
+	if url.IsBundle() {
+		return nil, domain.NewValidationError("Bundles have no destination page to fetch")
+	}
+
+	url.PageTitle, url.FaviconURL = s.enrich(ctx, shortCode, url.OriginalURL)
+	now := time.Now()
+	url.MetadataFetchedAt = &now
+	return url, nil
+}
+
+// optionalString maps an empty string to nil so missing values are stored as NULL
+func optionalString(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"url-shortener/internal/domain"
+	"url-shortener/pkg/validator"
+)
+
+// cleanNotes strips HTML from a link's title and description and checks their lengths
+// The result is stored as plain text, so the preview and interstitial pages never receive markup
+func cleanNotes(title, description string) (string, string, error) {
+	title, err := cleanTitle(title)
+	if err != nil {
+		return "", "", err
+	}
+	description, err = cleanDescription(description)
+	if err != nil {
+		return "", "", err
+	}
+	return title, description, nil
+}
+
+// cleanTitle reduces a title to a single line of plain text
+func cleanTitle(title string) (string, error) {
+	title = validator.StripHTML(title, false)
+	if utf8.RuneCountInString(title) > domain.MaxTitleLength {
+		return "", domain.NewFieldError("title", "max", fmt.Sprintf("title is longer than %d characters", domain.MaxTitleLength))
+	}
+	return title, nil
+}
+
+// cleanDescription reduces a description to plain text, keeping its line breaks
+func cleanDescription(description string) (string, error) {
+	description = validator.StripHTML(description, true)
+	if utf8.RuneCountInString(description) > domain.MaxDescriptionLength {
+		return "", domain.NewFieldError("description", "max", fmt.Sprintf("description is longer than %d characters", domain.MaxDescriptionLength))
+	}
+	return description, nil
+}
+
+// hasNotes reports whether a new link carries its own title or description
+// Such a link is the creator's own, so it is never answered with an existing link for the same destination
+func hasNotes(title, description string) bool {
+	return title != "" || description != ""
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"url-shortener/internal/archive"
+	"url-shortener/internal/config"
+	"url-shortener/internal/geo"
+	"url-shortener/internal/metadata"
+	"url-shortener/internal/repository"
+	"url-shortener/internal/unshorten"
+	"url-shortener/pkg/validator"
+)
+
+// Option configures optional dependencies of the URL service
+type Option func(*urlService)
+
+// WithAuditRepository records administrative actions in the given audit trail
+// Without it, actions are only written to the application log
+func WithAuditRepository(audit repository.AuditRepository) Option {
+	return func(s *urlService) {
+		s.audit = audit
+	}
+}
+
+// WithClickRepository stores a click event per redirect for per-target statistics
+func WithClickRepository(clicks repository.ClickRepository) Option {
+	return func(s *urlService) {
+		s.clicks = clicks
+	}
+}
+
+// WithGeoResolver enables country rules for visitors whose country isn't supplied upstream
+func WithGeoResolver(resolver geo.Resolver) Option {
+	return func(s *urlService) {
+		s.geo = resolver
+	}
+}
+
+// WithMetadataFetcher enables storing the title and favicon of new links' destinations
+// Without it no outbound requests are made
+func WithMetadataFetcher(fetcher metadata.Fetcher) Option {
+	return func(s *urlService) {
+		s.metadata = fetcher
+	}
+}
+
+// WithOutbox writes a lifecycle event in the same transaction as every create, click and delete
+// A relay publishes them to the message broker; without it no events are written
+func WithOutbox(outbox repository.OutboxRepository, tx repository.Transactor) Option {
+	return func(s *urlService) {
+		s.outbox = outbox
+		s.tx = tx
+	}
+}
+
+// WithChainResolver follows links on SHORTENER_DOMAINS to their final destination
+// Without it such links are refused with a validation error
+func WithChainResolver(resolver unshorten.Resolver) Option {
+	return func(s *urlService) {
+		s.chains = resolver
+	}
+}
+
+// WithSnapshots archives each new link's destination with fetcher and keeps it in store
+// Without it no snapshots are taken and GetSnapshot reports the feature as disabled
+func WithSnapshots(fetcher archive.Fetcher, store archive.SnapshotStore) Option {
+	return func(s *urlService) {
+		s.snapshotFetcher = fetcher
+		s.snapshots = store
+	}
+}
+
+// WithRuntimeConfig reads the reloadable settings from runtime instead of the startup configuration
+func WithRuntimeConfig(runtime *config.Runtime) Option {
+	return func(s *urlService) {
+		s.runtime = runtime
+	}
+}
+
+// WithValidator checks destinations with v instead of one built from ALLOWED_URL_SCHEMES
+func WithValidator(v *validator.Validator) Option {
+	return func(s *urlService) {
+		s.urlValidator = v
+	}
+}
+
+// WithoutClickCounting redirects without writing click, bot or prefetch counts
+// For a database role that may only read; the clicks it serves are not counted anywhere
+func WithoutClickCounting() Option {
+	return func(s *urlService) {
+		s.skipCounts = true
+	}
+}
+
+// WithHooks runs the callbacks of hooks around creates, redirects and deletes
+// Without it, or with a zero Hooks, the service behaves exactly as it does without hooks
+func WithHooks(hooks Hooks) Option {
+	return func(s *urlService) {
+		s.hooks = hooks
+	}
+}
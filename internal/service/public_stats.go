@@ -0,0 +1,39 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"url-shortener/internal/domain"
+)
+
+// GetPublicStats returns the counts the public stats page of a link shows
+// Unknown codes, links without public_stats and deactivated links all report ErrURLNotFound,
+// so the page can't be used to find out which codes exist.
+func (s *urlService) GetPublicStats(ctx context.Context, shortCode string) (*domain.PublicStats, error) {
+	url, err := s.repo.FindByShortCode(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+	if !url.PublicStats || !url.IsActive {
+		return nil, domain.ErrURLNotFound
+	}
+
+	stats := &domain.PublicStats{
+		ShortCode:   url.ShortCode,
+		TotalClicks: url.ClickCount,
+		CreatedAt:   url.CreatedAt,
+		Daily:       []domain.PublicDailyClicks{},
+	}
+	if s.clicks != nil {
+		daily, err := s.dailySeries(ctx, url.ShortCode, time.Now())
+		if err != nil {
+			s.log(ctx).Error("Failed to load daily click series", "error", err, "short_code", shortCode)
+			return nil, err
+		}
+		for _, day := range daily {
+			stats.Daily = append(stats.Daily, domain.PublicDailyClicks{Date: day.Date, Clicks: day.Clicks})
+		}
+	}
+	return stats, nil
+}
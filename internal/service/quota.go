@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"url-shortener/internal/cache"
+	"url-shortener/internal/clientip"
+	"url-shortener/internal/domain"
+)
+
+// apiKeyContextKey carries the authenticated API key identity into ShortenURL
+type apiKeyContextKey struct{}
+
+// ContextWithAPIKey attaches the caller's API key identity, used for per-key quotas
+// An empty id means the caller is anonymous and only the per-IP quota applies
+func ContextWithAPIKey(ctx context.Context, id string) context.Context {
+	if id == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, apiKeyContextKey{}, id)
+}
+
+// apiKeyFromContext returns the identity set by ContextWithAPIKey, or ""
+func apiKeyFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(apiKeyContextKey{}).(string)
+	return id
+}
+
+// quotaScope is one daily limit that applies to a create
+type quotaScope struct {
+	name  string // "ip", "key" or "tenant"
+	id    string
+	limit int64
+}
+
+// reserveQuota counts a create against every applicable daily quota
+// Counting happens with INCR before the comparison, so concurrent creates can never all see
+// the last free slot. The returned release undoes the reservation when the create fails later.
+// Quotas are soft: without a counter-capable cache, or while Redis is failing, creates are allowed.
+func (s *urlService) reserveQuota(ctx context.Context, clientIP, apiKey string) (*domain.QuotaStatus, func(), error) {
+	noop := func() {}
+	clientIP = clientip.Network(clientIP, s.cfg.RateLimitIPv6Prefix)
+
+	var scopes []quotaScope
+	if s.cfg.MaxURLsPerDayPerIP > 0 && clientIP != "" {
+		scopes = append(scopes, quotaScope{name: "ip", id: clientIP, limit: int64(s.cfg.MaxURLsPerDayPerIP)})
+	}
+	if s.cfg.MaxURLsPerDayPerKey > 0 && apiKey != "" {
+		scopes = append(scopes, quotaScope{name: "key", id: apiKey, limit: int64(s.cfg.MaxURLsPerDayPerKey)})
+	}
+	// The default tenant is everyone without a tenant of their own, the IP and key quotas cover it
+	if tenant, _ := domain.TenantFromContext(ctx); s.cfg.MaxURLsPerDayPerTenant > 0 && tenant != domain.DefaultTenant {
+		scopes = append(scopes, quotaScope{name: "tenant", id: tenant, limit: int64(s.cfg.MaxURLsPerDayPerTenant)})
+	}
+
+	counter, ok := s.cache.(cache.Counter)
+	if len(scopes) == 0 || !ok {
+		return nil, noop, nil
+	}
+
+	now := time.Now()
+	reset := domain.NextQuotaReset(now)
+
+	var reserved []string
+	release := func() {
+		// The request context may already be canceled on failure paths
+		releaseCtx := context.WithoutCancel(ctx)
+		for _, key := range reserved {
+			if _, err := counter.IncrBy(releaseCtx, key, -1, reset); err != nil {
+				s.log(ctx).Warn("Failed to release quota reservation", "error", err, "key", key)
+			}
+		}
+	}
+
+	var status *domain.QuotaStatus
+	for _, scope := range scopes {
+		key := cache.QuotaKey(scope.name, scope.id, now)
+
+		count, err := counter.IncrBy(ctx, key, 1, reset)
+		if err != nil {
+			s.log(ctx).Warn("Skipping quota check, cache unavailable", "error", err, "scope", scope.name)
+			continue
+		}
+		reserved = append(reserved, key)
+
+		if count > scope.limit {
+			release()
+			s.log(ctx).Warn("Daily creation quota exceeded", "scope", scope.name, "id", scope.id, "limit", scope.limit)
+			return nil, noop, domain.ErrQuotaExceeded
+		}
+
+		// Report the tightest quota so clients back off before any limit is hit
+		remaining := scope.limit - count
+		if status == nil || remaining < status.Remaining {
+			status = &domain.QuotaStatus{Limit: scope.limit, Remaining: remaining, ResetAt: reset}
+		}
+	}
+
+	return status, release, nil
+}
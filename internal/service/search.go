@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	"url-shortener/internal/domain"
+	"url-shortener/pkg/validator"
+)
+
+// maxSearchPageSize caps the links returned per page of SearchByDestination
+const maxSearchPageSize = 100
+
+// maxPathPrefixLength bounds path_prefix like ValidateURL bounds a whole URL
+const maxPathPrefixLength = 2048
+
+// SearchByDestination pages through the active links pointing at host, newest first
+// "*.host" includes its subdomains, and pathPrefix narrows the matches down to paths starting with it
+func (s *urlService) SearchByDestination(ctx context.Context, host, pathPrefix string, limit, offset int) (*domain.URLSearchPage, error) {
+	filter, err := destinationFilter(host, pathPrefix, "host", "path_prefix")
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 || limit > maxSearchPageSize {
+		limit = maxSearchPageSize
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	total, err := s.repo.CountActiveMatching(ctx, filter)
+	if err != nil {
+		s.log(ctx).Error("Failed to count search matches", "error", err, "host", filter.DestinationHost)
+		return nil, err
+	}
+
+	// One extra row tells whether another page follows without a separate count
+	urls, err := s.repo.FindActiveMatching(ctx, filter, limit+1, offset)
+	if err != nil {
+		s.log(ctx).Error("Failed to search URLs", "error", err, "host", filter.DestinationHost)
+		return nil, err
+	}
+
+	page := &domain.URLSearchPage{
+		Host:       filter.DestinationHost,
+		Subdomains: filter.IncludeSubdomains,
+		PathPrefix: filter.PathPrefix,
+		Total:      total,
+		URLs:       make([]*domain.AdminURLInfoResponse, 0, limit),
+		ShortCodes: make([]string, 0, limit),
+		Limit:      limit,
+		Offset:     offset,
+	}
+	if len(urls) > limit {
+		urls = urls[:limit]
+		next := offset + limit
+		page.NextOffset = &next
+	}
+	for i := range urls {
+		page.URLs = append(page.URLs, s.buildAdminInfoResponse(ctx, &urls[i]))
+		page.ShortCodes = append(page.ShortCodes, urls[i].ShortCode)
+	}
+	return page, nil
+}
+
+// destinationFilter builds the filter for a host pattern and optional path prefix
+// hostField and pathField name the inputs in error messages, e.g. "filter.destination_host" for the bulk body
+func destinationFilter(host, pathPrefix, hostField, pathField string) (domain.URLFilter, error) {
+	var filter domain.URLFilter
+	if strings.TrimSpace(host) == "" {
+		if pathPrefix != "" {
+			return filter, domain.NewFieldError(hostField, "required_with", pathField+" needs a "+hostField)
+		}
+		return filter, domain.NewFieldError(hostField, "required", hostField+" is required")
+	}
+
+	var err error
+	filter.DestinationHost, filter.IncludeSubdomains, err = validator.ParseHostPattern(host)
+	if err != nil {
+		return filter, domain.NewFieldError(hostField, "format", hostField+" "+err.Error())
+	}
+
+	if pathPrefix != "" {
+		if !strings.HasPrefix(pathPrefix, "/") || strings.ContainsAny(pathPrefix, "?# \t") || len(pathPrefix) > maxPathPrefixLength {
+			return filter, domain.NewFieldError(pathField, "format", pathField+" must be a path starting with /, such as /scam")
+		}
+		filter.PathPrefix = pathPrefix
+	}
+	return filter, nil
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"url-shortener/internal/archive"
+	"url-shortener/internal/domain"
+	"url-shortener/pkg/logger"
+	"url-shortener/pkg/validator"
+)
+
+// snapshotAsync archives what destination looks like without delaying the create response
+// Like enrichAsync it keeps only the request's logger and tenant from ctx
+func (s *urlService) snapshotAsync(ctx context.Context, shortCode, destination string) {
+	// mailto: and tel: links have no page to archive
+	if s.snapshots == nil || !validator.IsWebURL(destination) {
+		return
+	}
+
+	bg := domain.CopyTenant(logger.WithContext(context.Background(), s.log(ctx)), ctx)
+	s.enrichments.Add(1)
+	go func() {
+		defer s.enrichments.Done()
+		s.snapshot(bg, shortCode, destination)
+	}()
+}
+
+// snapshot fetches destination and stores the response under shortCode
+// Failures are only logged; the link exists whether or not its destination could be archived
+func (s *urlService) snapshot(ctx context.Context, shortCode, destination string) {
+	snap, err := s.snapshotFetcher.Capture(ctx, destination)
+	if err != nil {
+		s.log(ctx).Info("Failed to capture destination snapshot", "short_code", shortCode, "error", err)
+		return
+	}
+	snap.ShortCode = shortCode
+
+	if err := s.snapshots.Save(ctx, snap); err != nil {
+		s.log(ctx).Warn("Failed to store destination snapshot", "short_code", shortCode, "error", err)
+		return
+	}
+
+	s.log(ctx).Debug("Destination snapshot stored",
+		"short_code", shortCode,
+		"bytes", len(snap.Content),
+		"truncated", snap.Truncated,
+	)
+}
+
+// GetSnapshot returns the destination snapshot taken when the link was created
+// The link itself isn't looked up: a snapshot is kept after its link is deleted until retention purges it
+func (s *urlService) GetSnapshot(ctx context.Context, shortCode string) (*archive.Snapshot, error) {
+	if s.snapshots == nil {
+		return nil, domain.NewAppError(errors.New("snapshots are disabled"), "Destination snapshots are disabled", 409, false)
+	}
+	if !validator.ValidateShortCodePath(shortCode) {
+		return nil, domain.NewValidationError("Invalid short code format")
+	}
+
+	snap, err := s.snapshots.Get(ctx, shortCode)
+	if errors.Is(err, archive.ErrSnapshotNotFound) {
+		return nil, domain.NewNotFoundError("Snapshot")
+	}
+	if err != nil {
+		return nil, domain.NewInternalError(err)
+	}
+	return snap, nil
+}
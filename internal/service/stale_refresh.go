@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"url-shortener/internal/cache"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/metrics"
+)
+
+// staleRefreshTimeout bounds the database read renewing a stale entry; the stale value is served meanwhile
+const staleRefreshTimeout = 5 * time.Second
+
+// refreshGroup runs at most one refresh per cache key at a time
+// A popular link going stale then costs one database read, however many hits arrive before it's renewed
+type refreshGroup struct {
+	mu       sync.Mutex
+	inflight map[string]struct{}
+	wg       sync.WaitGroup // Refreshes still running, awaited by Close
+}
+
+// start runs refresh in the background unless one for key is running already
+func (g *refreshGroup) start(key string, refresh func()) bool {
+	g.mu.Lock()
+	if _, running := g.inflight[key]; running {
+		g.mu.Unlock()
+		return false
+	}
+	if g.inflight == nil {
+		g.inflight = make(map[string]struct{})
+	}
+	g.inflight[key] = struct{}{}
+	g.wg.Add(1)
+	g.mu.Unlock()
+
+	go func() {
+		defer func() {
+			g.mu.Lock()
+			delete(g.inflight, key)
+			g.mu.Unlock()
+			g.wg.Done()
+		}()
+		refresh()
+	}()
+	return true
+}
+
+// refreshStale renews the cached entry of a link past its soft TTL
+// A link deleted, deactivated or expired since is dropped from the cache, so the next hit takes the database path.
+// When the database can't be read the stale entry stays and is served until Redis expires it at the hard TTL.
+func (s *urlService) refreshStale(ctx context.Context, shortCode string) {
+	bg := context.WithoutCancel(ctx)
+	s.refreshes.start(s.codeKey(ctx, cache.LinkKey(shortCode)), func() {
+		ctx, cancel := context.WithTimeout(bg, staleRefreshTimeout)
+		defer cancel()
+
+		url, err := s.repo.FindByShortCode(ctx, shortCode)
+		switch {
+		case errors.Is(err, domain.ErrURLNotFound):
+			s.invalidateLink(ctx, shortCode)
+			metrics.CacheStaleRefreshes.WithLabelValues("removed").Inc()
+		case err != nil:
+			s.log(ctx).Warn("Failed to refresh stale cache entry, serving it until it expires", "error", err, "short_code", shortCode)
+			metrics.CacheStaleRefreshes.WithLabelValues("failed").Inc()
+		case url.IsExpired() || s.linkRequiresInterstitial(url):
+			s.invalidateLink(ctx, shortCode)
+			metrics.CacheStaleRefreshes.WithLabelValues("removed").Inc()
+		default:
+			s.cacheLink(ctx, url)
+			metrics.CacheStaleRefreshes.WithLabelValues("refreshed").Inc()
+		}
+	})
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"url-shortener/internal/domain"
+)
+
+// ResetStats zeroes a link's counters and deletes its click events and daily stats, keeping the link and its code
+// The database part is one transaction; the Redis visit counters are dropped after the commit.
+// Cached time series and heat maps are keyed by the reset time the transaction stamps, so none from before it are read.
+func (s *urlService) ResetStats(ctx context.Context, shortCode string, actor domain.Actor) (*domain.URLStats, error) {
+	// Step 1: Zero everything the database holds about the link's clicks
+	prior, err := s.repo.ResetStats(ctx, shortCode)
+	if err != nil {
+		s.log(ctx).Error("Failed to reset URL stats", "error", err, "short_code", shortCode)
+		return nil, err
+	}
+
+	// Step 2: Drop today's and yesterday's visit counters; counters that don't exist are fine
+	s.forgetVisits(ctx, shortCode)
+
+	s.log(ctx).Info("URL stats reset",
+		"short_code", shortCode,
+		"actor", actor.ID,
+		"ip", actor.IP,
+		"click_count", prior.ClickCount,
+	)
+
+	// Step 3: Record the totals the reset threw away; the reset is committed, so a failed write is logged
+	if s.audit != nil {
+		entry := &domain.AuditEntry{
+			Action:    domain.AuditActionStatsReset,
+			TenantID:  prior.TenantID,
+			ShortCode: shortCode,
+			ActorID:   actor.ID,
+			ActorIP:   actor.IP,
+			Details: fmt.Sprintf("click_count=%d bot_clicks=%d prefetch_hits=%d filtered_clicks=%d",
+				prior.ClickCount, prior.BotClicks, prior.PrefetchHits, prior.FilteredClicks),
+		}
+		if err := s.audit.Record(ctx, entry); err != nil {
+			s.log(ctx).Error("Failed to record audit entry", "error", err, "short_code", shortCode, "action", entry.Action)
+		}
+	}
+
+	return s.GetStats(ctx, shortCode)
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"url-shortener/pkg/validator"
+)
+
+const (
+	// maxAliasSuggestions caps how many alternatives a conflict response offers
+	maxAliasSuggestions = 5
+
+	// maxSuggestionLength keeps suggestions about as short as generated codes; a lower MAX_CUSTOM_ALIAS_LENGTH wins
+	maxSuggestionLength = 12
+)
+
+// SuggestAliases derives free alternatives from a taken alias
+// All candidates are checked with one ExistsManyByShortCode query instead of one lookup each
+func (s *urlService) SuggestAliases(ctx context.Context, alias string) ([]string, error) {
+	maxLength := maxSuggestionLength
+	if limit := s.maxAliasLength(); limit < maxLength {
+		maxLength = limit
+	}
+	candidates := aliasCandidates(alias, maxLength)
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	taken, err := s.repo.ExistsManyByShortCode(s.codeLookupContext(ctx), candidates)
+	if err != nil {
+		s.log(ctx).Error("Failed to check alias suggestions", "alias", alias, "error", err)
+		return nil, err
+	}
+
+	suggestions := make([]string, 0, maxAliasSuggestions)
+	for _, candidate := range candidates {
+		if taken[candidate] {
+			continue
+		}
+		suggestions = append(suggestions, candidate)
+		if len(suggestions) == maxAliasSuggestions {
+			break
+		}
+	}
+
+	return suggestions, nil
+}
+
+// aliasCandidates lists valid, unreserved variants of alias of at most maxLength in order of preference
+// Numbered and hyphenated forms are interleaved with shorter keyword prefixes for variety
+func aliasCandidates(alias string, maxLength int) []string {
+	seen := map[string]bool{alias: true}
+	var candidates []string
+
+	add := func(candidate string) {
+		if seen[candidate] || !validator.ValidateShortCode(candidate) || validator.IsReservedAlias(candidate) {
+			return
+		}
+		seen[candidate] = true
+		candidates = append(candidates, candidate)
+	}
+
+	prefixes := keywordPrefixes(alias, maxLength)
+	for n := 2; n <= 9; n++ {
+		suffix := strconv.Itoa(n)
+		add(fitAlias(alias, suffix, maxLength))
+		add(fitAlias(alias, "-"+suffix, maxLength))
+		if n-2 < len(prefixes) {
+			add(prefixes[n-2])
+		}
+	}
+
+	return candidates
+}
+
+// keywordPrefixes drops trailing words from a separated alias, e.g. summer-sale-24 gives summer-sale and summer
+func keywordPrefixes(alias string, maxLength int) []string {
+	var prefixes []string
+	for i := len(alias) - 1; i > 0; i-- {
+		if alias[i] != '-' && alias[i] != '_' {
+			continue
+		}
+		if prefix := strings.TrimRight(alias[:i], "-_"); prefix != "" && len(prefix) <= maxLength {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	return prefixes
+}
+
+// fitAlias appends suffix, truncating alias so the result fits maxLength
+// Truncation cuts at the last separator when possible so the leading keyword survives intact
+func fitAlias(alias, suffix string, maxLength int) string {
+	room := maxLength - len(suffix)
+	if room <= 0 {
+		return ""
+	}
+	if len(alias) <= room {
+		return alias + suffix
+	}
+
+	base := alias[:room]
+	if cut := strings.LastIndexAny(base, "-_"); cut >= 2 {
+		base = base[:cut]
+	}
+	return strings.TrimRight(base, "-_") + suffix
+}
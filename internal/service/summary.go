@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"url-shortener/internal/cache"
+	"url-shortener/internal/domain"
+)
+
+const (
+	// MaxSummaryDays bounds the summary window so the daily series stays a cheap index scan
+	MaxSummaryDays = 90
+
+	// MaxSummaryLimit bounds the number of top links returned
+	MaxSummaryLimit = 100
+)
+
+// GetSummary returns service-wide aggregates, cached for SummaryCacheTTL
+// A burst of dashboard refreshes costs at most one set of aggregate queries per window
+func (s *urlService) GetSummary(ctx context.Context, window domain.SummaryWindow) (*domain.SummaryStats, error) {
+	if window.Days < 1 || window.Days > MaxSummaryDays {
+		return nil, domain.NewValidationError(fmt.Sprintf("days must be between 1 and %d", MaxSummaryDays))
+	}
+	if window.Limit < 1 || window.Limit > MaxSummaryLimit {
+		return nil, domain.NewValidationError(fmt.Sprintf("limit must be between 1 and %d", MaxSummaryLimit))
+	}
+
+	key := tenantKey(ctx, cache.SummaryKey(window.Days, window.Limit))
+	if s.cache != nil && s.cfg.SummaryCacheTTL > 0 {
+		if cached, err := s.cache.Get(ctx, key); err == nil && cached != "" {
+			var summary domain.SummaryStats
+			if err := json.Unmarshal([]byte(cached), &summary); err == nil {
+				return &summary, nil
+			}
+			s.log(ctx).Warn("Ignoring malformed summary cache entry", "key", key)
+		}
+	}
+
+	summary, err := s.computeSummary(ctx, window, time.Now().UTC())
+	if err != nil {
+		s.log(ctx).Error("Failed to compute stats summary", "error", err)
+		return nil, err
+	}
+
+	if s.cache != nil && s.cfg.SummaryCacheTTL > 0 {
+		if data, err := json.Marshal(summary); err == nil {
+			if err := s.cache.Set(ctx, key, string(data), s.cfg.SummaryCacheTTL); err != nil {
+				s.log(ctx).Warn("Failed to cache stats summary", "error", err)
+			}
+		}
+	}
+
+	return summary, nil
+}
+
+// computeSummary runs the aggregate queries for a window ending at now
+func (s *urlService) computeSummary(ctx context.Context, window domain.SummaryWindow, now time.Time) (*domain.SummaryStats, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	since := today.AddDate(0, 0, -(window.Days - 1))
+
+	totalURLs, err := s.repo.CountURLs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	totalClicks, err := s.repo.SumClicks(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	topLinks, err := s.repo.TopByClicks(ctx, since, window.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	created, err := s.repo.CreatedBetween(ctx, since, today.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+
+	// The repository omits empty days; the dashboard wants a continuous series
+	byDay := make(map[string]int64, len(created))
+	for _, day := range created {
+		byDay[day.Date] = day.Count
+	}
+
+	summary := &domain.SummaryStats{
+		TotalURLs:    totalURLs,
+		TotalClicks:  totalClicks,
+		CreatedToday: byDay[today.Format("2006-01-02")],
+		Since:        since,
+		TopLinks:     topLinks,
+		CreatedByDay: make([]domain.DailyCount, 0, window.Days),
+		GeneratedAt:  now,
+	}
+	if summary.TopLinks == nil {
+		summary.TopLinks = []domain.TopLink{}
+	}
+
+	for day := since; !day.After(today); day = day.AddDate(0, 0, 1) {
+		date := day.Format("2006-01-02")
+		summary.CreatedByDay = append(summary.CreatedByDay, domain.DailyCount{Date: date, Count: byDay[date]})
+	}
+
+	return summary, nil
+}
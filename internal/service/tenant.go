@@ -0,0 +1,53 @@
+package service
+
+import (
+	"context"
+
+	"url-shortener/internal/cache"
+	"url-shortener/internal/domain"
+)
+
+// codeScope is the namespace a new link's code has to be unique in, see domain.URL.CodeScope
+func (s *urlService) codeScope(ctx context.Context) string {
+	if !s.cfg.TenantScopedCodes {
+		return ""
+	}
+	tenant, _ := domain.TenantFromContext(ctx)
+	return tenant
+}
+
+// codeLookupContext is ctx for checking whether codes are free
+// Without TENANT_SCOPED_CODES a code taken by any tenant is taken, so the check looks at every tenant
+func (s *urlService) codeLookupContext(ctx context.Context) context.Context {
+	if _, ok := domain.TenantFromContext(ctx); !ok || s.cfg.TenantScopedCodes {
+		return ctx
+	}
+	return domain.ContextWithoutTenant(ctx)
+}
+
+// codeKey is the cache key derived from a short code, in the tenant's keyspace when codes are tenant-scoped
+// Codes are unique across tenants otherwise, and redirects have no tenant to namespace by
+func (s *urlService) codeKey(ctx context.Context, key string) string {
+	if !s.cfg.TenantScopedCodes {
+		return key
+	}
+	tenant, _ := domain.TenantFromContext(ctx)
+	return cache.TenantKey(tenant, key)
+}
+
+// tenantKey is the cache key of results that differ per tenant whatever the code scope, e.g. summaries
+func tenantKey(ctx context.Context, key string) string {
+	tenant, _ := domain.TenantFromContext(ctx)
+	return cache.TenantKey(tenant, key)
+}
+
+// linkContext is ctx for recording a click on a link of tenant
+// Redirects without TENANT_SCOPED_CODES look at every tenant, so the owner is only known once the link is found.
+// A context without a tenant already matches the default tenant's links, whose codes are unique then.
+func linkContext(ctx context.Context, tenant string) context.Context {
+	current, ok := domain.TenantFromContext(ctx)
+	if (ok && current == tenant) || (!ok && tenant == domain.DefaultTenant) {
+		return ctx
+	}
+	return domain.ContextWithTenant(ctx, tenant)
+}
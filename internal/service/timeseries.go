@@ -0,0 +1,223 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"url-shortener/internal/cache"
+	"url-shortener/internal/domain"
+)
+
+// Longest range a time series may cover per granularity, so one request stays a bounded index scan
+const (
+	MaxHourlySeriesDays = 14
+	MaxDailySeriesDays  = 366
+	MaxWeeklySeriesDays = 3 * 364
+)
+
+// defaultSeriesBuckets is how many buckets a series spans when from is omitted
+var defaultSeriesBuckets = map[domain.Granularity]int{
+	domain.GranularityHour: 24,
+	domain.GranularityDay:  30,
+	domain.GranularityWeek: 12,
+}
+
+// GetClickTimeSeries returns the clicks of a link per hour, day or week, with empty buckets as zero
+// Buckets that can no longer change are cached; the recent ones are always counted afresh
+func (s *urlService) GetClickTimeSeries(ctx context.Context, shortCode string, query domain.TimeSeriesQuery) ([]domain.ClickBucket, error) {
+	if s.clicks == nil {
+		return nil, domain.NewAppError(errors.New("click events are not recorded"), "Click time series are not available", 409, false)
+	}
+
+	// Step 1: Validate granularity and range, then align the range to whole buckets
+	granularity := query.Granularity
+	if granularity == "" {
+		granularity = domain.GranularityDay
+	}
+	if !granularity.Valid() {
+		return nil, domain.NewValidationError("granularity must be hour, day or week")
+	}
+
+	now := time.Now().UTC()
+	from, to, err := seriesRange(granularity, query.From, query.To, now)
+	if err != nil {
+		return nil, err
+	}
+
+	// Step 2: Unknown codes are a 404 rather than a series of zeros
+	link, err := s.repo.FindAnyByShortCode(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+
+	// Step 3: Settled buckets come from the cache, the rest from the rollups and raw events
+	counts := make(map[int64]int64)
+	settled := settledBefore(granularity, now)
+	if from.Before(settled) {
+		end := settled
+		if to.Before(end) {
+			end = to
+		}
+		buckets, err := s.settledBuckets(ctx, shortCode, statsGeneration(link), granularity, from, end, now)
+		if err != nil {
+			return nil, err
+		}
+		addBuckets(counts, buckets)
+	}
+	if to.After(settled) {
+		start := settled
+		if from.After(start) {
+			start = from
+		}
+		buckets, err := s.countBuckets(ctx, shortCode, granularity, start, to, now)
+		if err != nil {
+			return nil, err
+		}
+		addBuckets(counts, buckets)
+	}
+
+	// Step 4: One point per bucket so charts don't have to fill gaps
+	series := make([]domain.ClickBucket, 0)
+	for start := from; start.Before(to); start = granularity.Next(start) {
+		series = append(series, domain.ClickBucket{BucketStart: start, Clicks: counts[start.Unix()]})
+	}
+	return series, nil
+}
+
+// seriesRange applies the defaults to from and to, checks the maximum span and widens both to bucket edges
+// to is exclusive; it defaults to now and from to the default number of buckets before it
+func seriesRange(granularity domain.Granularity, from, to, now time.Time) (time.Time, time.Time, error) {
+	if to.IsZero() {
+		to = now
+	}
+	end := granularity.Truncate(to)
+	if end.Before(to) {
+		end = granularity.Next(end)
+	}
+
+	if from.IsZero() {
+		n := defaultSeriesBuckets[granularity]
+		switch granularity {
+		case domain.GranularityHour:
+			from = end.Add(-time.Duration(n) * time.Hour)
+		case domain.GranularityWeek:
+			from = end.AddDate(0, 0, -7*n)
+		default:
+			from = end.AddDate(0, 0, -n)
+		}
+	}
+	start := granularity.Truncate(from)
+
+	if !start.Before(end) {
+		return time.Time{}, time.Time{}, domain.NewValidationError("'from' must be before 'to'")
+	}
+
+	maxDays := MaxDailySeriesDays
+	switch granularity {
+	case domain.GranularityHour:
+		maxDays = MaxHourlySeriesDays
+	case domain.GranularityWeek:
+		maxDays = MaxWeeklySeriesDays
+	}
+	if end.Sub(start) > time.Duration(maxDays)*24*time.Hour {
+		return time.Time{}, time.Time{}, domain.NewValidationError(fmt.Sprintf("%s granularity covers at most %d days", granularity, maxDays))
+	}
+
+	return start, end, nil
+}
+
+// settledBefore returns the edge before which a granularity's buckets no longer change
+// Hours settle once they are over; the rollup job rewrites the last two days, so days and weeks settle later
+func settledBefore(granularity domain.Granularity, now time.Time) time.Time {
+	if granularity == domain.GranularityHour {
+		return granularity.Truncate(now)
+	}
+	return granularity.Truncate(now.AddDate(0, 0, -2))
+}
+
+// statsGeneration is part of the key of every cached click stat of a link, time series and heat maps alike
+// An erased code can be taken again; the new row's ID keeps it from inheriting the old link's stats, and a
+// reset moves StatsResetAt on, so both erasing and resetting leave nothing stale to read.
+func statsGeneration(link *domain.URL) string {
+	generation := strconv.FormatUint(uint64(link.ID), 10)
+	if link.StatsResetAt != nil {
+		generation += "." + strconv.FormatInt(link.StatsResetAt.UnixNano(), 10)
+	}
+	return generation
+}
+
+// settledBuckets returns the buckets of a settled range, from the cache when it has them
+func (s *urlService) settledBuckets(ctx context.Context, shortCode, generation string, granularity domain.Granularity, from, to, now time.Time) ([]domain.ClickBucket, error) {
+	key := tenantKey(ctx, cache.TimeSeriesKey(shortCode, generation, string(granularity), from, to))
+	if s.cache != nil {
+		if cached, err := s.cache.Get(ctx, key); err == nil && cached != "" {
+			var buckets []domain.ClickBucket
+			if err := json.Unmarshal([]byte(cached), &buckets); err == nil {
+				return buckets, nil
+			}
+			s.log(ctx).Warn("Ignoring malformed time series cache entry", "key", key)
+		}
+	}
+
+	buckets, err := s.countBuckets(ctx, shortCode, granularity, from, to, now)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.cache != nil {
+		if data, err := json.Marshal(buckets); err == nil {
+			if err := s.cache.Set(ctx, key, string(data), s.cfg.CacheTTL); err != nil {
+				s.log(ctx).Warn("Failed to cache click time series", "error", err, "short_code", shortCode)
+			}
+		}
+	}
+	return buckets, nil
+}
+
+// countBuckets counts clicks in [from, to)
+// Hours come from raw events; days and weeks from the daily rollup, except today which isn't rolled up yet
+func (s *urlService) countBuckets(ctx context.Context, shortCode string, granularity domain.Granularity, from, to, now time.Time) ([]domain.ClickBucket, error) {
+	if granularity == domain.GranularityHour {
+		return s.clicks.CountByBucket(ctx, shortCode, granularity, from, to)
+	}
+
+	today := domain.GranularityDay.Truncate(now)
+	var buckets []domain.ClickBucket
+	if from.Before(today) {
+		end := today
+		if to.Before(end) {
+			end = to
+		}
+		rolledUp, err := s.clicks.SumDailyByBucket(ctx, shortCode, granularity, from, end)
+		if err != nil {
+			s.log(ctx).Error("Failed to load rolled-up clicks", "error", err, "short_code", shortCode)
+			return nil, err
+		}
+		buckets = append(buckets, rolledUp...)
+	}
+	if to.After(today) {
+		start := today
+		if from.After(start) {
+			start = from
+		}
+		recent, err := s.clicks.CountByBucket(ctx, shortCode, granularity, start, to)
+		if err != nil {
+			s.log(ctx).Error("Failed to count recent clicks", "error", err, "short_code", shortCode)
+			return nil, err
+		}
+		buckets = append(buckets, recent...)
+	}
+	return buckets, nil
+}
+
+// addBuckets sums buckets into counts by bucket start
+// A week can be split between the rollup and today's events, so the same start may appear twice
+func addBuckets(counts map[int64]int64, buckets []domain.ClickBucket) {
+	for _, bucket := range buckets {
+		counts[bucket.BucketStart.Unix()] += bucket.Clicks
+	}
+}
@@ -2,6 +2,9 @@ package service
 
 import (
 	"context"
+	"time"
+
+	"url-shortener/internal/archive"
 	"url-shortener/internal/domain"
 )
 
@@ -9,17 +12,118 @@ import (
 // This layer orchestrates between repositories, cache, and external services
 type URLService interface {
 	// ShortenURL creates a new shortened URL
-	ShortenURL(ctx context.Context, req *domain.CreateURLRequest, clientIP string) (*domain.CreateURLResponse, error)
+	ShortenURL(ctx context.Context, req *domain.CreateURLRequest, creator domain.CreatorContext) (*domain.CreateURLResponse, error)
+	
+	// CloneURL creates a new link with the settings of an existing one, optionally with another destination
+	CloneURL(ctx context.Context, shortCode string, req *domain.CloneURLRequest, creator domain.CreatorContext) (*domain.CreateURLResponse, error)
 	
 	// GetOriginalURL retrieves and redirects to the original URL
-	GetOriginalURL(ctx context.Context, shortCode string) (string, error)
+	GetOriginalURL(ctx context.Context, shortCode string, visitor domain.Visitor) (string, error)
+	
+	// PrepareRedirect decides whether a visitor is redirected or shown the interstitial
+	// Clicks are only counted when the redirect actually happens
+	PrepareRedirect(ctx context.Context, shortCode string, visitor domain.Visitor) (*domain.RedirectDecision, error)
+	
+	// RegisterHit counts a click on a short code without redirecting, e.g. for apps or tracking pixels
+	RegisterHit(ctx context.Context, shortCode string, visitor domain.Visitor) error
+	
+	// SuggestAliases returns up to five free aliases derived from one that is already taken
+	SuggestAliases(ctx context.Context, alias string) ([]string, error)
 	
 	// GetURLInfo returns detailed information about a shortened URL
-	GetURLInfo(ctx context.Context, shortCode string) (*domain.URL, error)
+	GetURLInfo(ctx context.Context, shortCode string) (*domain.URLInfoResponse, error)
+	
+	// GetAdminURLInfo returns GetURLInfo's view of any link, deactivated ones included, plus who created it
+	// Creator metadata is for admins only and never part of GetURLInfo
+	GetAdminURLInfo(ctx context.Context, shortCode string) (*domain.AdminURLInfoResponse, error)
+	
+	// ListByCreatorIP pages through the links created from one IP address, newest first
+	// A limit of zero or less, or above 100, returns 100 links
+	ListByCreatorIP(ctx context.Context, ip string, limit, offset int) (*domain.CreatorLinksPage, error)
+	
+	// SearchByDestination pages through the active links pointing at a host, newest first
+	// "*.spam.example" includes subdomains; the limit is capped at 100 like ListByCreatorIP's
+	SearchByDestination(ctx context.Context, host, pathPrefix string, limit, offset int) (*domain.URLSearchPage, error)
+	
+	// GetLegacyURLInfo returns the raw model as GetURLInfo did before URLInfoResponse
+	// Deprecated: kept for one release behind LEGACY_URL_INFO, use GetURLInfo
+	GetLegacyURLInfo(ctx context.Context, shortCode string) (*domain.URL, error)
+	
+	// UpdateURL applies a partial update to a shortened URL
+	UpdateURL(ctx context.Context, shortCode string, req *domain.UpdateURLRequest) (*domain.URL, error)
+	
+	// ExtendURL pushes out the expiry of a link, optionally reviving one that already expired
+	ExtendURL(ctx context.Context, shortCode string, req *domain.ExtendURLRequest) (*domain.URL, error)
+	
+	// DeactivateURL disables a link without deleting it and records the actor in the audit trail
+	DeactivateURL(ctx context.Context, shortCode string, actor domain.Actor) (*domain.URL, error)
+	
+	// ActivateURL re-enables a deactivated link and records the actor in the audit trail
+	ActivateURL(ctx context.Context, shortCode string, actor domain.Actor) (*domain.URL, error)
+	
+	// BulkDeactivate disables every active link selected by short code or filter in one write
+	// A dry run reports the match count without changing anything
+	BulkDeactivate(ctx context.Context, req *domain.BulkDeactivateRequest, actor domain.Actor) (*domain.BulkDeactivateResponse, error)
+	
+	// FlushCache deletes every cached entry in the current namespace and returns the number removed
+	// Meant for emergencies such as a bad cache format rollout; redirects fall back to the database
+	FlushCache(ctx context.Context, actor domain.Actor) (int64, error)
+	
+	// RefreshMetadata fetches the destination's title and favicon again and returns the updated link
+	RefreshMetadata(ctx context.Context, shortCode string) (*domain.URL, error)
+	
+	// GetSnapshot returns the copy of the destination archived when the link was created
+	GetSnapshot(ctx context.Context, shortCode string) (*archive.Snapshot, error)
+	
+	// AuthorizeManagement checks a creator's management token before an edit or delete, reporting whether the
+	// token was verified; links without a token are approved unverified and need the caller's usual authentication
+	// Admins skip the check; callers decide who counts as admin
+	AuthorizeManagement(ctx context.Context, shortCode, token string) (bool, error)
 	
 	// DeleteURL removes a shortened URL
 	DeleteURL(ctx context.Context, shortCode string) error
 	
+	// HardDeleteURL erases a link with its click history, audit trail and snapshot, for erasure requests
+	HardDeleteURL(ctx context.Context, shortCode string, actor domain.Actor) error
+	
+	// ResetStats zeroes a link's click statistics without touching the link, records the totals it had in the
+	// audit trail and returns the fresh statistics
+	ResetStats(ctx context.Context, shortCode string, actor domain.Actor) (*domain.URLStats, error)
+	
+	// PurgeDeleted erases the links deactivated or deleted before the cutoff and returns how many were erased
+	PurgeDeleted(ctx context.Context, before time.Time) (int, error)
+	
 	// GetStats returns statistics for a shortened URL
 	GetStats(ctx context.Context, shortCode string) (*domain.URLStats, error)
+	
+	// GetPublicStats returns the click counts of a link with public_stats for anyone to see
+	// Links without it are not found, the same as unknown codes.
+	GetPublicStats(ctx context.Context, shortCode string) (*domain.PublicStats, error)
+	
+	// RecordConversion redeems the ush_click receipt of a redirect as a conversion of its link, once
+	RecordConversion(ctx context.Context, clickID string) (*domain.ConversionResponse, error)
+	
+	// GetClickTimeSeries returns a link's clicks per hour, day or week with empty buckets filled in
+	GetClickTimeSeries(ctx context.Context, shortCode string, query domain.TimeSeriesQuery) ([]domain.ClickBucket, error)
+	
+	// GetClickHeatmap returns a link's clicks per weekday and hour of day, all 7×24 cells filled in
+	GetClickHeatmap(ctx context.Context, shortCode string, query domain.HeatmapQuery) (*domain.ClickHeatmap, error)
+	
+	// GetSummary returns service-wide totals, top links and daily creation counts for a window
+	GetSummary(ctx context.Context, window domain.SummaryWindow) (*domain.SummaryStats, error)
+	
+	// ListExpiring returns the active links expiring within the next days days, soonest first
+	ListExpiring(ctx context.Context, days int) ([]*domain.URLInfoResponse, error)
+	
+	// ListURLs pages through the active links matching the filter, newest first
+	ListURLs(ctx context.Context, filter domain.URLFilter, limit, offset int) (*domain.URLPage, error)
+	
+	// ExportURLs streams all URLs matching the filter to fn
+	ExportURLs(ctx context.Context, filter domain.URLFilter, fn func(*domain.URL) error) error
+	
+	// WarmCache loads the limit most clicked links into the cache and returns how many it stored
+	WarmCache(ctx context.Context, limit int) (int, error)
+	
+	// Close flushes work still queued in the background, waiting at most until ctx is done
+	Close(ctx context.Context) error
 }
\ No newline at end of file
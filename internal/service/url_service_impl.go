@@ -2,15 +2,26 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
+	"sync"
 	"time"
 	
+	"url-shortener/internal/archive"
 	"url-shortener/internal/cache"
+	"url-shortener/internal/clientip"
 	"url-shortener/internal/config"
 	"url-shortener/internal/domain"
+	"url-shortener/internal/geo"
+	"url-shortener/internal/metadata"
+	"url-shortener/internal/metrics"
+	"url-shortener/internal/redirect"
 	"url-shortener/internal/repository"
 	"url-shortener/internal/shortener"
+	"url-shortener/internal/unshorten"
 	"url-shortener/pkg/logger"
+	"url-shortener/pkg/signer"
 	"url-shortener/pkg/validator"
 )
 
@@ -19,8 +30,27 @@ type urlService struct {
 	repo      repository.URLRepository
 	cache     cache.Cache
 	cfg       *config.Config
+	runtime   *config.Runtime // Settings a reload can change, read per request; nil keeps cfg
 	logger    *logger.Logger
 	generator *shortener.CodeGenerator
+	urlValidator *validator.Validator // Checks destinations against ALLOWED_URL_SCHEMES
+	audit     repository.AuditRepository
+	clicks    repository.ClickRepository
+	geo       geo.Resolver
+	clickQueue *clickWorker // Persists clicks from cache hits off the request path
+	cacheWrites *cacheWriter // Populates the cache off the request path
+	metadata  metadata.Fetcher
+	enrichments sync.WaitGroup // Metadata and snapshot fetches still running, awaited by Close
+	refreshes refreshGroup // Background refreshes of stale cache entries
+	outbox    repository.OutboxRepository // Lifecycle events for the relay, nil when disabled
+	tx        repository.Transactor
+	chains    unshorten.Resolver // Resolves links on other shorteners, nil refuses them
+	snapshotFetcher archive.Fetcher
+	snapshots archive.SnapshotStore // Destination archive, nil when disabled
+	prefetchers *redirect.UserAgentMatcher // Compiled PREFETCH_USER_AGENTS
+	clickIDs  *signer.Signer // Signs the ush_click receipts of attach_click_id links
+	skipCounts bool // Redirects write no counts, see WithoutClickCounting
+	hooks     Hooks // Callbacks of custom builds, all nil unless WithHooks is given
 }
 
 // NewURLService creates a new URL service with dependencies injected
@@ -29,183 +59,930 @@ func NewURLService(
 	cache cache.Cache,
 	cfg *config.Config,
 	logger *logger.Logger,
+	opts ...Option,
 ) URLService {
-	return &urlService{
+	s := &urlService{
 		repo:      repo,
 		cache:     cache,
 		cfg:       cfg,
 		logger:    logger,
-		generator: shortener.NewCodeGenerator(cfg.ShortCodeLength),
+		generator: newCodeGenerator(cfg),
+		prefetchers: redirect.NewUserAgentMatcher(cfg.PrefetchUserAgents),
 	}
+	
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.urlValidator == nil {
+		s.urlValidator = validator.New(cfg.AllowedURLSchemes)
+	}
+	
+	clickIDs, err := newClickSigner(cfg.ClickIDSecret)
+	if err != nil {
+		logger.Fatal("Failed to initialize click ID signer", "error", err)
+	}
+	s.clickIDs = clickIDs
+	
+	s.clickQueue = startClickWorker(cfg.ClickQueueSize, s.recordQueuedClick)
+	s.cacheWrites = startCacheWriter(cfg.CacheWriteQueueSize, s.writeQueuedCache)
+	
+	return s
+}
+
+// newCodeGenerator builds the generator for random codes from the short code settings
+func newCodeGenerator(cfg *config.Config) *shortener.CodeGenerator {
+	var opts []shortener.GeneratorOption
+	if cfg.ShortCodeExcludeAmbiguous {
+		opts = append(opts, shortener.WithoutAmbiguousChars())
+	}
+	return shortener.NewCodeGenerator(cfg.ShortCodeLength, opts...)
+}
+
+// botUserAgents returns the User-Agent substrings of crawlers, reloaded ones if a runtime is set
+func (s *urlService) botUserAgents() []string {
+	if s.runtime == nil {
+		return s.cfg.BotUserAgents
+	}
+	return s.runtime.Load().BotUserAgents
 }
 
 // ShortenURL creates a new shortened URL with validation and deduplication
-func (s *urlService) ShortenURL(ctx context.Context, req *domain.CreateURLRequest, clientIP string) (*domain.CreateURLResponse, error) {
+func (s *urlService) ShortenURL(ctx context.Context, req *domain.CreateURLRequest, creator domain.CreatorContext) (*domain.CreateURLResponse, error) {
+	creator = cleanCreator(creator)
+	
+	// Bundles list several links on a landing page and take a separate path
+	if len(req.Bundle) > 0 {
+		return s.shortenBundle(ctx, req, creator)
+	}
+	
 	// Step 1: Validate the original URL
-	if err := validator.ValidateURL(req.URL); err != nil {
-		s.logger.Warn("Invalid URL provided", "url", req.URL, "error", err)
-		return nil, domain.NewValidationError("Invalid URL format")
+	if err := s.urlValidator.ValidateURL(req.URL); err != nil {
+		s.log(ctx).Warn("Invalid URL provided", "url", req.URL, "error", err)
+		return nil, domain.NewFieldError("url", "format", "Invalid URL format")
+	}
+	
+	// The expiry is checked up front so a duplicate can't answer a request that is invalid
+	expiresAt, err := s.expiryFor(ctx, req)
+	if err != nil {
+		return nil, err
 	}
 	
 	// Step 2: Normalize URL (add https:// if missing, remove trailing slash)
-	normalizedURL := validator.NormalizeURL(req.URL)
+	normalizedURL := s.normalizeURL(req.URL)
+	
+	// Links on other shorteners would become redirect chains, so refuse or resolve them
+	normalizedURL, submittedURL, err := s.resolveChain(ctx, normalizedURL)
+	if err != nil {
+		return nil, err
+	}
+	
+	targets, err := s.normalizeTargets(req.Targets)
+	if err != nil {
+		s.log(ctx).Warn("Invalid targets provided", "error", err)
+		return nil, domain.NewValidationError(err.Error())
+	}
+	
+	variants, err := s.normalizeVariants(req.Variants)
+	if err != nil {
+		s.log(ctx).Warn("Invalid variants provided", "error", err)
+		return nil, domain.NewValidationError(err.Error())
+	}
+	
+	appLink, err := s.normalizeAppLink(req.DeepLink, req.IOSFallbackURL, req.AndroidFallbackURL)
+	if err != nil {
+		s.log(ctx).Warn("Invalid deep link provided", "error", err)
+		return nil, err
+	}
 	
 	// Step 3: Check if URL already exists (optional deduplication)
 	// This prevents creating multiple short codes for the same URL
+	// Links with different UTM parameters are distinct, and links with rules are never shared
+	var utm domain.UTMParams
+	if req.UTM != nil {
+		utm = *req.UTM
+	}
+	
+	forwardQuery := s.cfg.ForwardQueryDefault
+	if req.ForwardQuery != nil {
+		forwardQuery = *req.ForwardQuery
+	}
+	
+	if err := req.ReferrerPolicy.Validate(); err != nil {
+		return nil, domain.NewFieldError("referrer_policy", "oneof", err.Error())
+	}
+	
+	title, description, err := cleanNotes(req.Title, req.Description)
+	if err != nil {
+		return nil, err
+	}
+	noted := hasNotes(title, description)
+	
+	// Repeat submissions, e.g. from bulk importers, are answered from the cache without a query
+	if len(targets) == 0 && len(variants) == 0 && appLink.DeepLink == "" && !req.AttachClickID && !req.PublicStats && !noted {
+		if cached := s.findCachedDuplicate(ctx, dedupFingerprint(normalizedURL, utm, forwardQuery, req.ReferrerPolicy)); cached != nil {
+			s.log(ctx).Info("URL already shortened, returning existing", "short_code", cached.ShortCode, "source", "cache")
+			response := s.buildDuplicateResponse(ctx, cached)
+			response.DryRun = req.DryRun
+			return response, nil
+		}
+	}
+	
 	existingURL, err := s.repo.FindByOriginalURL(ctx, normalizedURL)
-	if err == nil && existingURL != nil && !existingURL.IsExpired() {
-		s.logger.Info("URL already shortened, returning existing", "short_code", existingURL.ShortCode)
-		return s.buildResponse(existingURL), nil
+	if err == nil && existingURL != nil && !existingURL.IsExpired() && existingURL.UTM == utm && existingURL.ForwardQuery == forwardQuery &&
+		existingURL.ReferrerPolicy == req.ReferrerPolicy &&
+		!hasRules(existingURL) && !existingURL.IsBundle() && len(targets) == 0 && len(variants) == 0 &&
+		appLink.DeepLink == "" && !existingURL.AttachClickID && !req.AttachClickID &&
+		!existingURL.PublicStats && !req.PublicStats && !noted {
+		s.log(ctx).Info("URL already shortened, returning existing", "short_code", existingURL.ShortCode)
+		response := s.buildDuplicateResponse(ctx, existingURL)
+		if req.DryRun {
+			response.DryRun = true
+			return response, nil
+		}
+		s.rememberDuplicate(ctx, existingURL)
+		return response, nil
 	}
 	
 	// Step 4: Generate or validate custom short code
+	// Codes aren't checked for existence here; conflicts are resolved when inserting
+	hashed := s.hashCodes(req, targets, variants) && !noted
 	var shortCode string
-	if req.CustomAlias != "" {
-		// Validate custom alias format
-		if !validator.ValidateShortCode(req.CustomAlias) {
-			return nil, domain.NewValidationError("Custom alias contains invalid characters")
-		}
-		
-		// Check if custom alias is already taken
-		exists, err := s.repo.ExistsByShortCode(ctx, req.CustomAlias)
-		if err != nil {
-			s.logger.Error("Failed to check short code existence", "error", err)
-			return nil, domain.NewInternalError(err)
-		}
-		if exists {
-			return nil, domain.ErrShortCodeTaken
-		}
-		
-		shortCode = req.CustomAlias
+	if hashed {
+		shortCode = s.generator.GenerateFromContent(utm.AppendTo(normalizedURL), s.cfg.ShortCodeLength)
 	} else {
-		// Generate unique short code with collision handling
-		shortCode, err = s.generateUniqueShortCode(ctx)
+		shortCode, err = s.chooseShortCode(req.CustomAlias)
 		if err != nil {
-			s.logger.Error("Failed to generate short code", "error", err)
-			return nil, domain.NewInternalError(err)
+			return nil, err
 		}
 	}
 	
-	// Step 5: Calculate expiration date if specified
-	var expiresAt *time.Time
-	if req.ExpiryDays > 0 {
-		expiry := time.Now().AddDate(0, 0, req.ExpiryDays)
-		expiresAt = &expiry
-	} else if s.cfg.URLExpirationDays > 0 {
-		// Use default expiration from config
-		expiry := time.Now().AddDate(0, 0, s.cfg.URLExpirationDays)
-		expiresAt = &expiry
-	}
-	
-	// Step 6: Create URL entity
+	// Step 5: Create URL entity
 	url := &domain.URL{
 		ShortCode:   shortCode,
 		OriginalURL: normalizedURL,
+		SubmittedURL: submittedURL,
+		Title:       title,
+		Description: description,
 		ExpiresAt:   expiresAt,
-		CreatorIP:   clientIP,
 		IsActive:    true,
 		CustomAlias: req.CustomAlias != "",
 		ClickCount:  0,
+		UTM:         utm,
+		Targets:     targets,
+		Variants:    variants,
+		StickyVariants: req.StickyVariants,
+		ConfirmBeforeRedirect: req.ConfirmBeforeRedirect,
+		ForwardQuery: forwardQuery,
+		AttachClickID: req.AttachClickID,
+		PublicStats: req.PublicStats,
+		ReferrerPolicy: req.ReferrerPolicy,
+	}
+	setAppLink(url, appLink)
+	setCreator(creator, url)
+	if err := s.beforeCreate(ctx, url); err != nil {
+		return nil, err
+	}
+	
+	// A dry run stops here, before anything is reserved, saved or cached
+	if req.DryRun {
+		return s.previewURL(ctx, url, hashed)
+	}
+	
+	// Only the hash is stored, the token itself is returned once below
+	managementToken, err := issueManagementToken(url)
+	if err != nil {
+		return nil, err
+	}
+	
+	// Step 6: Reserve the daily creation quota; the reservation is returned if the insert fails
+	quota, releaseQuota, err := s.reserveQuota(ctx, creator.IP, apiKeyFromContext(ctx))
+	if err != nil {
+		return nil, err
 	}
 	
 	// Step 7: Save to database
-	if err := s.repo.Create(ctx, url); err != nil {
-		s.logger.Error("Failed to create URL", "error", err, "short_code", shortCode)
+	if hashed {
+		existing, err := s.createHashed(ctx, url)
+		if err != nil {
+			releaseQuota()
+			s.log(ctx).Error("Failed to create URL", "error", err, "short_code", url.ShortCode)
+			return nil, err
+		}
+		if existing != nil {
+			// Another replica stored the same destination first; nothing new was created
+			releaseQuota()
+			s.log(ctx).Info("URL already shortened, returning existing", "short_code", existing.ShortCode)
+			s.rememberDuplicate(ctx, existing)
+			return s.buildDuplicateResponse(ctx, existing), nil
+		}
+	} else if err := s.insertURL(ctx, url); err != nil {
+		releaseQuota()
+		if !errors.Is(err, domain.ErrShortCodeTaken) {
+			s.log(ctx).Error("Failed to create URL", "error", err, "short_code", shortCode)
+		}
 		return nil, err
 	}
+	shortCode = url.ShortCode
+	s.afterCreate(ctx, url)
 	
 	// Step 8: Cache the URL for fast retrieval
-	if s.cache != nil {
-		if err := s.cache.Set(ctx, shortCode, normalizedURL, s.cfg.CacheTTL); err != nil {
-			// Log cache error but don't fail the request
-			s.logger.Warn("Failed to cache URL", "error", err, "short_code", shortCode)
-		}
+	// Links inside the new-link interstitial window stay uncached so the check still runs
+	if s.cache != nil && !s.linkRequiresInterstitial(url) {
+		// Queued, so a slow cache doesn't hold up the response
+		s.cacheLink(ctx, url)
 	}
+	s.rememberDuplicate(ctx, url)
 	
-	s.logger.Info("URL shortened successfully", 
+	s.log(ctx).Info("URL shortened successfully", 
 		"short_code", shortCode, 
 		"original_url", normalizedURL,
 		"custom", req.CustomAlias != "",
 	)
 	
-	return s.buildResponse(url), nil
+	// Step 9: Fetch the destination's title and favicon, and archive it, in the background
+	s.enrichAsync(ctx, url.ShortCode, url.OriginalURL)
+	s.snapshotAsync(ctx, url.ShortCode, url.OriginalURL)
+	
+	response := s.buildResponse(ctx, url)
+	response.ManagementToken = managementToken
+	response.Quota = quota
+	return response, nil
 }
 
 // GetOriginalURL retrieves the original URL and tracks the access
 // Uses cache-aside pattern for optimal performance
-func (s *urlService) GetOriginalURL(ctx context.Context, shortCode string) (string, error) {
+func (s *urlService) GetOriginalURL(ctx context.Context, shortCode string, visitor domain.Visitor) (string, error) {
+	decision, err := s.resolve(ctx, shortCode, visitor, false)
+	if err != nil {
+		return "", err
+	}
+	if err := s.beforeRedirect(ctx, decision); err != nil {
+		return "", err
+	}
+	
+	return redirect.WithClickID(decision.OriginalURL, decision.ClickID), nil
+}
+
+// PrepareRedirect resolves a short code and decides whether to show the interstitial page
+// Interstitial views are not counted; the click is recorded when the visitor continues
+func (s *urlService) PrepareRedirect(ctx context.Context, shortCode string, visitor domain.Visitor) (*domain.RedirectDecision, error) {
+	decision, err := s.resolve(ctx, shortCode, visitor, true)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.beforeRedirect(ctx, decision); err != nil {
+		return nil, err
+	}
+	return decision, nil
+}
+
+// RegisterHit counts a click the way a redirect would, including the bot heuristic
+// The caller already sent the visitor on, so interstitials don't apply
+func (s *urlService) RegisterHit(ctx context.Context, shortCode string, visitor domain.Visitor) error {
+	_, err := s.resolve(ctx, shortCode, visitor, false)
+	return err
+}
+
+// resolve looks up a short code and records the click unless an interstitial is served instead
+// honorInterstitial is false for callers that always redirect (continue links, gRPC)
+func (s *urlService) resolve(ctx context.Context, shortCode string, visitor domain.Visitor, honorInterstitial bool) (*domain.RedirectDecision, error) {
+	// Dedup, unique visitors, sticky variants and click events all see the client's network, not its current address
+	visitor.IP = clientip.Network(visitor.IP, s.cfg.RateLimitIPv6Prefix)
+	
 	// Step 1: Try to get from cache first (fast path)
-	if s.cache != nil {
-		cachedURL, err := s.cache.Get(ctx, shortCode)
-		if err == nil && cachedURL != "" {
-			// Cache hit - increment counter asynchronously to avoid blocking
-			go func() {
-				if err := s.repo.IncrementClickCount(context.Background(), shortCode); err != nil {
-					s.logger.Error("Failed to increment click count", "error", err, "short_code", shortCode)
-				}
-			}()
+	// Cached links never need an interstitial of their own, so only the global switch bypasses it
+	var lookup cacheLookup
+	if s.cache != nil && !(honorInterstitial && s.cfg.InterstitialAll) {
+		var err error
+		lookup, err = s.lookupCachedLink(ctx, shortCode, visitor)
+		if err == nil && lookup.value != "" {
+			entry, ok := cache.DecodeLinkEntry(lookup.value)
+			if ok {
+				// Clicks count for the link's owner, also when the redirect looked at every tenant
+				ctx = linkContext(ctx, entry.TenantID)
+			}
+			
+			// A hit answers like the database path: 404 for an inactive link, 410 once expired
+			if ok && entry.Inactive {
+				s.log(ctx).Debug("Cache hit for inactive link", "short_code", shortCode)
+				return nil, domain.ErrURLNotFound
+			}
+			if ok && entry.Expired(time.Now()) {
+				s.log(ctx).Info("Attempted to access expired URL", "short_code", shortCode)
+				return nil, domain.ErrURLExpired
+			}
 			
-			s.logger.Debug("Cache hit", "short_code", shortCode)
-			return cachedURL, nil
+			// Past its soft TTL the entry still answers, and one background read renews it
+			if ok && entry.Stale(time.Now()) {
+				metrics.CacheStaleServed.Inc()
+				s.refreshStale(ctx, shortCode)
+			}
+			
+			if ok && len(entry.Bundle) > 0 {
+				// Bundle page views are counted like redirects
+				s.recordClickAsync(ctx, shortCode, redirect.Result{Destination: entry.URL, Target: redirect.DefaultTarget}, visitor)
+				
+				s.log(ctx).Debug("Cache hit", "short_code", shortCode)
+				return &domain.RedirectDecision{ShortCode: shortCode, OriginalURL: entry.URL, Bundle: entry.Bundle, ExpiresAt: entry.ExpiresAt}, nil
+			} else if ok {
+				// The cached rule set is evaluated here so hits still branch per visitor
+				result := redirect.Evaluate(entry.Targets, entry.URL, s.locate(visitor, entry.Targets))
+				result = redirect.ApplySplit(result, entry.Variants, entry.Sticky, visitor)
+				
+				if honorInterstitial && s.isPrefetch(entry.ConfirmPrefetch, visitor) {
+					s.recordPrefetchAsync(ctx, shortCode, visitor)
+					
+					s.log(ctx).Debug("Serving interstitial to prefetcher", "short_code", shortCode)
+					return &domain.RedirectDecision{ShortCode: shortCode, OriginalURL: result.Destination, Target: result.Target, Variant: result.Variant, Interstitial: true, ExpiresAt: entry.ExpiresAt}, nil
+				}
+				
+				var app *domain.AppLaunch
+				if entry.App != nil {
+					result, app = redirect.LaunchApp(*entry.App, result, visitor.UserAgent)
+				}
+				
+				// Cache hit - record the click asynchronously to avoid blocking
+				s.recordClickAsync(ctx, shortCode, result, visitor)
+				
+				var clickID string
+				if entry.AttachClickID {
+					clickID = s.issueClickID(ctx, entry.TenantID, shortCode)
+				}
+				
+				s.log(ctx).Debug("Cache hit", "short_code", shortCode)
+				return &domain.RedirectDecision{
+					ShortCode:   shortCode,
+					OriginalURL: result.Destination,
+					Target:      result.Target,
+					Variant:     result.Variant,
+					Conditional: entry.Conditional(),
+					ForwardQuery: entry.ForwardQuery,
+					ReferrerPolicy: entry.ReferrerPolicy,
+					App:         app,
+					ClickID:     clickID,
+					ExpiresAt:   entry.ExpiresAt,
+				}, nil
+			}
+			s.log(ctx).Warn("Ignoring malformed cache entry", "short_code", shortCode)
+		}
+	}
+	
+	// Recently deactivated links are answered from the negative cache without a database query
+	if s.cache != nil {
+		inactive := lookup.inactive
+		if !lookup.checked {
+			exists, err := s.cache.Exists(ctx, s.codeKey(ctx, cache.InactiveKey(shortCode)))
+			inactive = err == nil && exists
+		}
+		if inactive {
+			s.log(ctx).Debug("Negative cache hit", "short_code", shortCode)
+			return nil, domain.ErrURLNotFound
 		}
 	}
 	
 	// Step 2: Cache miss or no cache - query database
-	url, err := s.repo.FindByShortCode(ctx, shortCode)
+	url, err := s.findLink(ctx, shortCode)
 	if err != nil {
-		s.logger.Warn("Short code not found", "short_code", shortCode)
-		return "", err
+		s.log(ctx).Warn("Short code not found", "short_code", shortCode)
+		return nil, err
 	}
+	// A case-folded match is counted, cached and continued under its own code
+	shortCode = url.ShortCode
+	ctx = linkContext(ctx, url.TenantID)
 	
 	// Step 3: Check if URL has expired
 	if url.IsExpired() {
-		s.logger.Info("Attempted to access expired URL", "short_code", shortCode)
-		return "", domain.ErrURLExpired
+		s.log(ctx).Info("Attempted to access expired URL", "short_code", shortCode)
+		return nil, domain.ErrURLExpired
 	}
 	
-	// Step 4: Increment click count
-	if err := s.repo.IncrementClickCount(ctx, shortCode); err != nil {
-		// Log but don't fail the redirect
-		s.logger.Error("Failed to increment click count", "error", err, "short_code", shortCode)
+	if s.cache != nil && !lookup.counted {
+		s.countVisit(ctx, shortCode, visitor)
 	}
 	
-	// Step 5: Update cache for future requests
-	if s.cache != nil {
-		if err := s.cache.Set(ctx, shortCode, url.OriginalURL, s.cfg.CacheTTL); err != nil {
-			s.logger.Warn("Failed to update cache", "error", err, "short_code", shortCode)
+	// Step 4: Pick the destination for this visitor and apply UTM parameters
+	result := redirect.Evaluate(url.Targets, url.OriginalURL, s.locate(visitor, url.Targets))
+	result = redirect.ApplySplit(result, url.Variants, url.StickyVariants, visitor)
+	decision := &domain.RedirectDecision{
+		ShortCode:   shortCode,
+		OriginalURL: url.UTM.AppendTo(result.Destination),
+		Target:      result.Target,
+		Variant:     result.Variant,
+		Conditional: hasRules(url),
+		ForwardQuery: url.ForwardQuery,
+		ReferrerPolicy: url.ReferrerPolicy,
+		ExpiresAt:   url.ExpiresAt,
+	}
+	
+	// Bundles list their members instead of redirecting
+	// They never get an interstitial of their own: the member links they point to are checked on click
+	if url.IsBundle() {
+		decision.OriginalURL = url.OriginalURL
+		decision.Bundle = url.Bundle
+	}
+	
+	// Step 5: Serve the interstitial without counting a click
+	if honorInterstitial && !url.IsBundle() && (s.cfg.InterstitialAll || s.linkRequiresInterstitial(url)) {
+		s.log(ctx).Debug("Serving interstitial", "short_code", shortCode)
+		decision.Interstitial = true
+		return decision, nil
+	}
+	
+	// Chat apps prefetching the link get the confirm page too, so a side-effectful destination isn't fired
+	// Browsers still redirect straight away
+	if honorInterstitial && !url.IsBundle() && s.isPrefetch(url.ConfirmBeforeRedirect, visitor) {
+		s.log(ctx).Debug("Serving interstitial to prefetcher", "short_code", shortCode)
+		s.recordPrefetch(ctx, shortCode)
+		decision.Interstitial = true
+		return decision, nil
+	}
+	
+	// Mobile visitors are sent into the app; interstitials above still showed the web destination
+	// Like the cached fallbacks, the destination it starts from has the UTM parameters applied
+	if url.HasDeepLink() && !url.IsBundle() {
+		result.Destination = decision.OriginalURL
+		result, decision.App = redirect.LaunchApp(url.AppLink(), result, visitor.UserAgent)
+		decision.OriginalURL, decision.Target = result.Destination, result.Target
+	}
+	
+	// Step 6: Record the click, with a receipt the destination can redeem as a conversion
+	s.recordClick(ctx, shortCode, result, visitor)
+	if url.AttachClickID && !url.IsBundle() {
+		decision.ClickID = s.issueClickID(ctx, url.TenantID, shortCode)
+	}
+	
+	// Step 7: Update cache for future requests
+	// The cache stores composed destinations, so links needing an interstitial are never cached
+	if s.cache != nil && !s.linkRequiresInterstitial(url) {
+		s.cacheLink(ctx, url)
+	}
+	
+	s.log(ctx).Info("URL accessed", "short_code", shortCode, "clicks", url.ClickCount+1, "target", result.Target)
+	return decision, nil
+}
+
+// findLink looks up a code to redirect, falling back to the one link matching it ignoring case
+// with CASE_INSENSITIVE_CODES; the exact code always wins, so codes differing only in case keep working
+func (s *urlService) findLink(ctx context.Context, shortCode string) (*domain.URL, error) {
+	url, err := s.repo.FindByShortCode(ctx, shortCode)
+	if !s.cfg.CaseInsensitiveCodes || !errors.Is(err, domain.ErrURLNotFound) {
+		return url, err
+	}
+	return s.repo.FindByShortCodeFold(ctx, shortCode)
+}
+
+// locate fills in the visitor country when a country rule needs it and no upstream header set it
+func (s *urlService) locate(visitor domain.Visitor, targets []domain.Target) domain.Visitor {
+	if visitor.Country == "" && s.geo != nil && redirect.NeedsCountry(targets) {
+		visitor.Country = s.geo.Country(visitor.IP)
+	}
+	return visitor
+}
+
+// recordQueuedClick persists a click taken off the queue, logging as the request it came from
+func (s *urlService) recordQueuedClick(job clickJob) {
+	ctx := linkContext(logger.WithContext(context.Background(), job.log), job.tenant)
+	if job.prefetch {
+		s.recordPrefetch(ctx, job.shortCode)
+		return
+	}
+	s.recordClick(ctx, job.shortCode, job.result, job.visitor)
+}
+
+// recordClickAsync queues a click for the worker
+// When the queue is full or shutting down the click is recorded inline rather than dropped
+func (s *urlService) recordClickAsync(ctx context.Context, shortCode string, result redirect.Result, visitor domain.Visitor) {
+	tenant, _ := domain.TenantFromContext(ctx)
+	if s.clickQueue.enqueue(clickJob{shortCode: shortCode, tenant: tenant, result: result, visitor: visitor, log: s.log(ctx)}) {
+		return
+	}
+	
+	s.log(ctx).Debug("Click queue unavailable, recording inline", "short_code", shortCode)
+	s.recordClick(context.WithoutCancel(ctx), shortCode, result, visitor)
+}
+
+// recordPrefetchAsync queues a prefetch hit for the click worker, or records it inline like recordClickAsync
+func (s *urlService) recordPrefetchAsync(ctx context.Context, shortCode string, visitor domain.Visitor) {
+	tenant, _ := domain.TenantFromContext(ctx)
+	if s.clickQueue.enqueue(clickJob{shortCode: shortCode, tenant: tenant, visitor: visitor, prefetch: true, log: s.log(ctx)}) {
+		return
+	}
+	
+	s.recordPrefetch(context.WithoutCancel(ctx), shortCode)
+}
+
+// Close stops the click and cache workers and waits for their queues to drain
+// Call it after the servers stopped accepting requests; ctx bounds the wait
+func (s *urlService) Close(ctx context.Context) error {
+	pending, err := s.clickQueue.close(ctx)
+	if err != nil {
+		s.log(ctx).Error("Click queue not drained before shutdown deadline", "error", err, "pending", pending)
+		return err
+	}
+	
+	s.log(ctx).Info("Click queue drained")
+	
+	// Cache entries left behind would only cost database reads later
+	if pending, err := s.cacheWrites.close(ctx); err != nil {
+		s.log(ctx).Warn("Cache write queue not drained before shutdown deadline", "error", err, "pending", pending)
+		return err
+	}
+	
+	// Metadata and snapshot fetches and stale refreshes are bounded by their own timeouts; links left without
+	// metadata can be refreshed
+	done := make(chan struct{})
+	go func() {
+		s.enrichments.Wait()
+		s.refreshes.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		s.log(ctx).Warn("Metadata, snapshot or stale cache refreshes still running at shutdown", "error", ctx.Err())
+		return ctx.Err()
+	}
+	
+	return nil
+}
+
+// recordClick increments the click counter and stores the click event with the rule that matched
+// Failures are logged but never fail the redirect
+func (s *urlService) recordClick(ctx context.Context, shortCode string, result redirect.Result, visitor domain.Visitor) {
+	if s.skipCounts {
+		return
+	}
+
+	// Crawlers would inflate the counts, so they are kept apart or not counted at all
+	if redirect.IsBot(visitor.UserAgent, s.botUserAgents()) {
+		s.recordBotClick(ctx, shortCode)
+		return
+	}
+	
+	// Spam clicks are still redirected, they just don't count twice
+	if s.isRepeatClick(ctx, shortCode, visitor) {
+		s.recordFilteredClick(ctx, shortCode)
+		return
+	}
+	
+	referrer := s.referrerHost(visitor.Referrer)
+	err := s.withEvents(ctx, func(ctx context.Context) error {
+		return s.repo.IncrementClickCount(ctx, shortCode, referrer)
+	}, func() []*domain.OutboxEvent {
+		return s.clickEvents(shortCode, result, visitor)
+	})
+	if errors.Is(err, domain.ErrURLNotFound) {
+		// The link was deactivated or erased while the click was queued; an event now would outlive an erasure
+		return
+	}
+	if err != nil {
+		s.log(ctx).Error("Failed to increment click count", "error", err, "short_code", shortCode)
+	}
+	
+	if s.clicks != nil {
+		event := &domain.ClickEvent{
+			ShortCode: shortCode,
+			Target:    result.Target,
+			Variant:   result.Variant,
+			ClickedAt: time.Now(),
+			IP:        visitor.IP,
+			Referrer:  referrer,
+		}
+		if err := s.clicks.Record(ctx, event); err != nil {
+			s.log(ctx).Error("Failed to record click event", "error", err, "short_code", shortCode)
 		}
 	}
+}
+
+// recordBotClick counts a crawler's redirect in bot_clicks, unless BOT_CLICKS=ignore
+// Bots get no click event, so per-target, per-variant and daily stats only reflect visitors
+func (s *urlService) recordBotClick(ctx context.Context, shortCode string) {
+	if s.cfg.BotClicks == config.BotClicksIgnore {
+		return
+	}
+	
+	if err := s.repo.IncrementBotClickCount(ctx, shortCode); err != nil {
+		s.log(ctx).Error("Failed to increment bot click count", "error", err, "short_code", shortCode)
+	}
+}
+
+// isPrefetch reports whether visitor is a link prefetcher that a confirm_before_redirect link must not redirect
+func (s *urlService) isPrefetch(confirm bool, visitor domain.Visitor) bool {
+	return confirm && s.prefetchers.Match(visitor.UserAgent)
+}
+
+// recordPrefetch counts a prefetch in prefetch_hits; like bot clicks it produces no click event
+func (s *urlService) recordPrefetch(ctx context.Context, shortCode string) {
+	if s.skipCounts {
+		return
+	}
+	if err := s.repo.IncrementPrefetchCount(ctx, shortCode); err != nil && !errors.Is(err, domain.ErrURLNotFound) {
+		s.log(ctx).Error("Failed to increment prefetch count", "error", err, "short_code", shortCode)
+	}
+}
+
+// GetURLInfo returns the public view of a shortened URL
+func (s *urlService) GetURLInfo(ctx context.Context, shortCode string) (*domain.URLInfoResponse, error) {
+	url, err := s.repo.FindByShortCode(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
 	
-	s.logger.Info("URL accessed", "short_code", shortCode, "clicks", url.ClickCount+1)
-	return url.OriginalURL, nil
+	return s.buildInfoResponse(ctx, url), nil
 }
 
-// GetURLInfo returns detailed information about a shortened URL
-func (s *urlService) GetURLInfo(ctx context.Context, shortCode string) (*domain.URL, error) {
+// GetLegacyURLInfo returns the raw model for clients still on the old response shape
+func (s *urlService) GetLegacyURLInfo(ctx context.Context, shortCode string) (*domain.URL, error) {
+	return s.repo.FindByShortCode(ctx, shortCode)
+}
+
+// UpdateURL applies the non-nil fields of req and invalidates the cached entry
+func (s *urlService) UpdateURL(ctx context.Context, shortCode string, req *domain.UpdateURLRequest) (*domain.URL, error) {
 	url, err := s.repo.FindByShortCode(ctx, shortCode)
 	if err != nil {
 		return nil, err
 	}
 	
+	// A bundle page has no single destination for rules to replace
+	if url.IsBundle() && (req.Targets != nil || req.Variants != nil || req.DeepLink != nil || req.AttachClickID != nil) {
+		return nil, domain.NewValidationError("bundles cannot have targets, variants, a deep_link or attach_click_id")
+	}
+	
+	if req.RequiresInterstitial != nil {
+		url.RequiresInterstitial = *req.RequiresInterstitial
+	}
+	if req.UTM != nil {
+		url.UTM = *req.UTM
+	}
+	if req.Targets != nil {
+		targets, err := s.normalizeTargets(*req.Targets)
+		if err != nil {
+			return nil, domain.NewValidationError(err.Error())
+		}
+		url.Targets = targets
+	}
+	if req.Variants != nil {
+		variants, err := s.normalizeVariants(*req.Variants)
+		if err != nil {
+			return nil, domain.NewValidationError(err.Error())
+		}
+		url.Variants = variants
+	}
+	if req.StickyVariants != nil {
+		url.StickyVariants = *req.StickyVariants
+	}
+	if req.ConfirmBeforeRedirect != nil {
+		url.ConfirmBeforeRedirect = *req.ConfirmBeforeRedirect
+	}
+	if req.ForwardQuery != nil {
+		url.ForwardQuery = *req.ForwardQuery
+	}
+	if req.AttachClickID != nil {
+		url.AttachClickID = *req.AttachClickID
+	}
+	if req.PublicStats != nil {
+		url.PublicStats = *req.PublicStats
+	}
+	if req.ReferrerPolicy != nil {
+		if err := req.ReferrerPolicy.Validate(); err != nil {
+			return nil, domain.NewFieldError("referrer_policy", "oneof", err.Error())
+		}
+		url.ReferrerPolicy = *req.ReferrerPolicy
+	}
+	if req.DeepLink != nil || req.IOSFallbackURL != nil || req.AndroidFallbackURL != nil {
+		deepLink, iosFallback, androidFallback := url.DeepLink, url.IOSFallbackURL, url.AndroidFallbackURL
+		if req.DeepLink != nil {
+			deepLink = *req.DeepLink
+			if deepLink == "" {
+				iosFallback, androidFallback = "", "" // Fallbacks are meaningless without the deep link
+			}
+		}
+		if req.IOSFallbackURL != nil {
+			iosFallback = *req.IOSFallbackURL
+		}
+		if req.AndroidFallbackURL != nil {
+			androidFallback = *req.AndroidFallbackURL
+		}
+		appLink, err := s.normalizeAppLink(deepLink, iosFallback, androidFallback)
+		if err != nil {
+			return nil, err
+		}
+		setAppLink(url, appLink)
+	}
+	if req.Title != nil {
+		title, err := cleanTitle(*req.Title)
+		if err != nil {
+			return nil, err
+		}
+		url.Title = title
+	}
+	if req.Description != nil {
+		description, err := cleanDescription(*req.Description)
+		if err != nil {
+			return nil, err
+		}
+		url.Description = description
+	}
+	
+	if err := s.repo.Update(ctx, url); err != nil {
+		s.log(ctx).Error("Failed to update URL", "error", err, "short_code", shortCode)
+		return nil, err
+	}
+	
+	// Drop the cached destination so the next redirect sees the new settings
+	s.invalidateLink(ctx, shortCode)
+	
+	s.log(ctx).Info("URL updated", "short_code", shortCode)
+	return url, nil
+}
+
+// DeactivateURL disables a link without deleting it
+// The cached destination is purged and a short negative-cache entry stops further lookups
+func (s *urlService) DeactivateURL(ctx context.Context, shortCode string, actor domain.Actor) (*domain.URL, error) {
+	url, err := s.setActive(ctx, shortCode, false, actor)
+	if err != nil {
+		return nil, err
+	}
+	
+	s.invalidateLink(ctx, shortCode)
+	s.markInactive(ctx, shortCode)
+	
+	return url, nil
+}
+
+// ActivateURL re-enables a previously deactivated link
+// The next redirect repopulates the cache from the database
+func (s *urlService) ActivateURL(ctx context.Context, shortCode string, actor domain.Actor) (*domain.URL, error) {
+	url, err := s.setActive(ctx, shortCode, true, actor)
+	if err != nil {
+		return nil, err
+	}
+	
+	if s.cache != nil {
+		if err := s.cache.Delete(ctx, s.codeKey(ctx, cache.InactiveKey(shortCode))); err != nil {
+			s.log(ctx).Warn("Failed to clear negative cache entry", "error", err, "short_code", shortCode)
+		}
+	}
+	
+	return url, nil
+}
+
+// invalidateLink removes all cache entries derived from a link
+// Every write path goes through here so new derived keys only need to be added once, here and in invalidateLinks
+func (s *urlService) invalidateLink(ctx context.Context, shortCode string) {
+	if s.cache == nil {
+		return
+	}
+	
+	s.cacheWrites.cancel(shortCode)
+	if err := s.cache.Delete(ctx, s.codeKey(ctx, cache.LinkKey(shortCode))); err != nil {
+		s.log(ctx).Warn("Failed to delete from cache", "error", err, "short_code", shortCode)
+	}
+	s.forgetDuplicate(ctx, shortCode)
+}
+
+// FlushCache drops the whole cache namespace at the configured rate and audits the flush
+func (s *urlService) FlushCache(ctx context.Context, actor domain.Actor) (int64, error) {
+	flusher, ok := s.cache.(cache.Flusher)
+	if !ok {
+		return 0, domain.NewAppError(cache.ErrFlushUnsupported, "No flushable cache is configured", 409, false)
+	}
+	
+	s.log(ctx).Warn("Flushing cache namespace", "namespace", s.cfg.CacheNamespace, "actor", actor.ID, "ip", actor.IP)
+	
+	deleted, err := flusher.FlushNamespace(ctx, s.cfg.CacheFlushRate)
+	if err != nil {
+		// Keys deleted before the failure are gone either way, so report how far the flush got
+		s.log(ctx).Error("Cache flush failed", "error", err, "deleted", deleted)
+		return deleted, domain.NewInternalError(err)
+	}
+	
+	s.log(ctx).Info("Cache namespace flushed", "namespace", s.cfg.CacheNamespace, "deleted", deleted)
+	
+	if s.audit != nil {
+		entry := &domain.AuditEntry{
+			Action:  domain.AuditActionCacheFlush,
+			ActorID: actor.ID,
+			ActorIP: actor.IP,
+			Details: fmt.Sprintf("namespace=%s deleted=%d", s.cfg.CacheNamespace, deleted),
+		}
+		if err := s.audit.Record(ctx, entry); err != nil {
+			s.log(ctx).Error("Failed to record audit entry", "error", err, "action", entry.Action)
+		}
+	}
+	
+	return deleted, nil
+}
+
+// cacheLink queues the redirect entry for a link
+func (s *urlService) cacheLink(ctx context.Context, url *domain.URL) {
+	value, ttl := s.linkEntry(url)
+	if ttl <= 0 {
+		return
+	}
+	
+	s.setCacheAsync(ctx, url.ShortCode, s.codeKey(ctx, cache.LinkKey(url.ShortCode)), value, ttl)
+}
+
+// linkEntry returns the cached form of a link and how long it may be kept, zero or less for not at all
+// The TTL never outlives the link itself, so a cached redirect can't serve past expires_at
+// With CACHE_STALE_TTL_SECONDS the entry is fresh for CacheTTL and kept as long again as the stale window
+func (s *urlService) linkEntry(url *domain.URL) (string, time.Duration) {
+	ttl := linkTTL(s.cfg.CacheTTL+s.cfg.CacheStaleTTL, url)
+	entry := cache.NewLinkEntry(url)
+	if s.cfg.CacheStaleTTL > 0 {
+		freshUntil := time.Now().Add(s.cfg.CacheTTL)
+		entry.FreshUntil = &freshUntil
+	}
+	return entry.Encode(), ttl
+}
+
+// log returns the request-scoped logger carried by ctx, or the service's own outside a request
+func (s *urlService) log(ctx context.Context) *logger.Logger {
+	if l := logger.FromContext(ctx); l != nil {
+		return l
+	}
+	return s.logger
+}
+
+// linkTTL caps ttl at the time url has left before it expires
+// It is zero or negative for an expired link, which must not be cached at all
+func linkTTL(ttl time.Duration, url *domain.URL) time.Duration {
+	if url.ExpiresAt == nil {
+		return ttl
+	}
+	if remaining := time.Until(*url.ExpiresAt); remaining < ttl {
+		return remaining
+	}
+	return ttl
+}
+
+// markInactive writes the short-lived negative-cache entry for a link that no longer redirects
+func (s *urlService) markInactive(ctx context.Context, shortCode string) {
+	if s.cache == nil || s.cfg.NegativeCacheTTL <= 0 {
+		return
+	}
+	
+	if err := s.cache.Set(ctx, s.codeKey(ctx, cache.InactiveKey(shortCode)), "1", s.cfg.NegativeCacheTTL); err != nil {
+		s.log(ctx).Warn("Failed to set negative cache entry", "error", err, "short_code", shortCode)
+	}
+}
+
+// setActive persists the new state and records the change in the audit trail
+func (s *urlService) setActive(ctx context.Context, shortCode string, active bool, actor domain.Actor) (*domain.URL, error) {
+	url, err := s.repo.SetActive(ctx, shortCode, active)
+	if err != nil {
+		s.log(ctx).Error("Failed to change URL state", "error", err, "short_code", shortCode, "active", active)
+		return nil, err
+	}
+	
+	action := domain.AuditActionDeactivate
+	if active {
+		action = domain.AuditActionActivate
+	}
+	
+	s.log(ctx).Info("URL state changed",
+		"short_code", shortCode,
+		"action", action,
+		"actor", actor.ID,
+		"ip", actor.IP,
+	)
+	
+	// The state change is already committed, so a failed audit write is logged rather than returned
+	if s.audit != nil {
+		entry := &domain.AuditEntry{
+			Action:    action,
+			ShortCode: shortCode,
+			ActorID:   actor.ID,
+			ActorIP:   actor.IP,
+		}
+		if err := s.audit.Record(ctx, entry); err != nil {
+			s.log(ctx).Error("Failed to record audit entry", "error", err, "short_code", shortCode, "action", action)
+		}
+	}
+	
 	return url, nil
 }
 
 // DeleteURL removes a shortened URL and invalidates cache
 func (s *urlService) DeleteURL(ctx context.Context, shortCode string) error {
 	// Delete from database
-	if err := s.repo.Delete(ctx, shortCode); err != nil {
-		s.logger.Error("Failed to delete URL", "error", err, "short_code", shortCode)
+	err := s.withEvents(ctx, func(ctx context.Context) error {
+		return s.repo.Delete(ctx, shortCode)
+	}, func() []*domain.OutboxEvent {
+		return []*domain.OutboxEvent{newEvent(domain.EventLinkDeleted, shortCode, domain.LinkEventData{})}
+	})
+	if err != nil {
+		s.log(ctx).Error("Failed to delete URL", "error", err, "short_code", shortCode)
 		return err
 	}
 	
-	// Invalidate cache
-	if s.cache != nil {
-		if err := s.cache.Delete(ctx, shortCode); err != nil {
-			s.logger.Warn("Failed to delete from cache", "error", err, "short_code", shortCode)
-		}
-	}
+	// Invalidate every cache entry derived from the row, so a stale redirect can't outlive the link
+	s.invalidateLink(ctx, shortCode)
+	s.markInactive(ctx, shortCode)
+	s.afterDelete(ctx, shortCode)
 	
-	s.logger.Info("URL deleted", "short_code", shortCode)
+	s.log(ctx).Info("URL deleted", "short_code", shortCode)
 	return nil
 }
 
@@ -216,45 +993,262 @@ func (s *urlService) GetStats(ctx context.Context, shortCode string) (*domain.UR
 		return nil, err
 	}
 	
+	if s.clicks != nil {
+		byTarget, err := s.clicks.CountByTarget(ctx, shortCode)
+		if err != nil {
+			s.log(ctx).Error("Failed to count clicks by target", "error", err, "short_code", shortCode)
+			return nil, err
+		}
+		stats.ClicksByTarget = byTarget
+		
+		if len(stats.Variants) > 0 {
+			byVariant, err := s.clicks.CountByVariant(ctx, shortCode)
+			if err != nil {
+				s.log(ctx).Error("Failed to count clicks by variant", "error", err, "short_code", shortCode)
+				return nil, err
+			}
+			applyVariantCounts(stats.Variants, byVariant)
+		}
+		
+		daily, err := s.dailySeries(ctx, shortCode, time.Now())
+		if err != nil {
+			s.log(ctx).Error("Failed to load daily click series", "error", err, "short_code", shortCode)
+			return nil, err
+		}
+		stats.Daily = daily
+	}
+	stats.Today = s.visitsToday(ctx, shortCode)
+	stats.ConversionRate = conversionRate(stats.Conversions, stats.TotalClicks)
+	
 	return stats, nil
 }
 
-// generateUniqueShortCode generates a short code and ensures it's unique
-// Implements collision handling with retry logic
-func (s *urlService) generateUniqueShortCode(ctx context.Context) (string, error) {
-	const maxRetries = 5
+// ExportURLs streams URLs from the repository in batches
+// Rows are never collected in memory so exports scale with the table size
+func (s *urlService) ExportURLs(ctx context.Context, filter domain.URLFilter, fn func(*domain.URL) error) error {
+	const exportBatchSize = 500
 	
-	for i := 0; i < maxRetries; i++ {
-		// Generate random short code
-		shortCode := s.generator.Generate()
-		
-		// Check if it already exists
-		exists, err := s.repo.ExistsByShortCode(ctx, shortCode)
-		if err != nil {
-			return "", err
+	if err := s.repo.ForEach(ctx, filter, exportBatchSize, fn); err != nil {
+		s.log(ctx).Error("Failed to export URLs", "error", err)
+		return err
+	}
+	
+	return nil
+}
+
+// chooseShortCode validates a custom alias, or generates a fresh code when alias is empty
+// Neither is checked against the database; the unique index decides when the row is inserted
+func (s *urlService) chooseShortCode(alias string) (string, error) {
+	if alias == "" {
+		return s.generator.Generate(), nil
+	}
+	
+	// Validate custom alias length before its format, so an overlong alias is told the limit
+	if maxLength := s.maxAliasLength(); len(alias) > maxLength {
+		return "", domain.NewFieldError("custom_alias", "max", fmt.Sprintf("Custom alias must be at most %d characters, got %d", maxLength, len(alias)))
+	}
+	for _, segment := range strings.Split(alias, "/") {
+		if len(segment) > validator.MaxShortCodeSegmentLength {
+			return "", domain.NewFieldError("custom_alias", "max", fmt.Sprintf("Each part of a custom alias between slashes must be at most %d characters", validator.MaxShortCodeSegmentLength))
 		}
-		
-		if !exists {
-			return shortCode, nil
+	}
+	if !validator.ValidateShortCodePath(alias) {
+		return "", domain.NewFieldError("custom_alias", "format", "Custom alias contains invalid characters")
+	}
+	if validator.IsReservedAlias(alias) {
+		return "", domain.NewFieldError("custom_alias", "reserved", "Custom alias is reserved")
+	}
+	
+	return alias, nil
+}
+
+// maxAliasLength is MAX_CUSTOM_ALIAS_LENGTH, or the short_code column size when it is unset
+func (s *urlService) maxAliasLength() int {
+	if s.cfg.MaxCustomAliasLength > 0 {
+		return s.cfg.MaxCustomAliasLength
+	}
+	return validator.MaxShortCodePathLength
+}
+
+// expiryFor returns the expiration requested for a new link, or the default of the caller's key or the config
+func (s *urlService) expiryFor(ctx context.Context, req *domain.CreateURLRequest) (*time.Time, error) {
+	policy := domain.NewExpiryPolicy(s.cfg.URLExpirationDays, s.cfg.MaxExpiryDays, domain.KeyExpiryFromContext(ctx))
+	return policy.Expiry(time.Now(), req.ExpiryDays, req.ExpiresAt)
+}
+
+// maxCodeAttempts bounds the inserts tried with fresh generated codes before giving up
+const maxCodeAttempts = 5
+
+// insertURL saves url and lets the unique index on short_code decide whether its code is free
+// A generated code that turns out to be taken is replaced and the insert retried, so two requests
+// racing for the same code both succeed; a taken custom alias is reported as ErrShortCodeTaken
+func (s *urlService) insertURL(ctx context.Context, url *domain.URL) error {
+	for attempt := 1; ; attempt++ {
+		// Each attempt gets its own transaction; a failed insert aborts the one it ran in
+		err := s.createURL(ctx, url)
+		if !errors.Is(err, domain.ErrShortCodeTaken) || url.CustomAlias {
+			return err
+		}
+		if attempt == maxCodeAttempts {
+			return domain.NewInternalError(fmt.Errorf("failed to generate unique short code after %d attempts", maxCodeAttempts))
 		}
 		
 		// Collision detected, log and retry
-		s.logger.Warn("Short code collision detected, retrying", 
-			"short_code", shortCode, 
-			"attempt", i+1,
+		s.log(ctx).Warn("Short code collision detected, retrying", 
+			"short_code", url.ShortCode, 
+			"attempt", attempt,
 		)
+		url.ShortCode = s.generator.Generate()
+	}
+}
+
+// normalizeURL applies the configured normalization to a destination
+func (s *urlService) normalizeURL(rawURL string) string {
+	return validator.NormalizeURLWith(rawURL, validator.NormalizeOptions{
+		Legacy:      s.cfg.LegacyNormalization,
+		StripParams: s.cfg.StripTrackingParams,
+	})
+}
+
+// normalizeTargets validates targeting rules and normalizes their destinations
+func (s *urlService) normalizeTargets(targets []domain.Target) (domain.Targets, error) {
+	if len(targets) == 0 {
+		return nil, nil
+	}
+	
+	normalized := make(domain.Targets, len(targets))
+	for i, target := range targets {
+		target.Platform = strings.ToLower(strings.TrimSpace(target.Platform))
+		target.Country = strings.ToUpper(strings.TrimSpace(target.Country))
+		
+		if err := s.urlValidator.ValidateURL(target.URL); err != nil {
+			return nil, fmt.Errorf("target %d: invalid URL", i)
+		}
+		target.URL = s.normalizeURL(target.URL)
+		normalized[i] = target
+	}
+	
+	if err := redirect.ValidateTargets(normalized); err != nil {
+		return nil, err
+	}
+	
+	return normalized, nil
+}
+
+// normalizeVariants validates an A/B split, normalizes its URLs and scales weights to 100
+func (s *urlService) normalizeVariants(variants []domain.Variant) (domain.Variants, error) {
+	if len(variants) == 0 {
+		return nil, nil
+	}
+	
+	normalized, err := redirect.NormalizeWeights(variants)
+	if err != nil {
+		return nil, err
+	}
+	
+	for i := range normalized {
+		if err := s.urlValidator.ValidateURL(normalized[i].URL); err != nil {
+			return nil, fmt.Errorf("variant %d: invalid URL", i)
+		}
+		normalized[i].URL = s.normalizeURL(normalized[i].URL)
+	}
+	
+	return normalized, nil
+}
+
+// applyVariantCounts fills in clicks and the share of all variant clicks
+func applyVariantCounts(variants []domain.VariantStats, counts map[int]int64) {
+	var total int64
+	for i := range variants {
+		variants[i].Clicks = counts[variants[i].Index]
+		total += variants[i].Clicks
 	}
 	
-	return "", fmt.Errorf("failed to generate unique short code after %d attempts", maxRetries)
+	if total == 0 {
+		return
+	}
+	for i := range variants {
+		variants[i].Ratio = float64(variants[i].Clicks) / float64(total)
+	}
+}
+
+// hasRules reports whether a link's destination depends on the visitor
+func hasRules(url *domain.URL) bool {
+	return len(url.Targets) > 0 || len(url.Variants) > 0 || url.HasDeepLink()
+}
+
+// linkRequiresInterstitial reports whether a link needs the interstitial based on its own state
+// The global INTERSTITIAL_ALL switch is handled separately in resolve
+func (s *urlService) linkRequiresInterstitial(url *domain.URL) bool {
+	if url.RequiresInterstitial {
+		return true
+	}
+	
+	if s.cfg.InterstitialNewLinkMinutes > 0 {
+		window := time.Duration(s.cfg.InterstitialNewLinkMinutes) * time.Minute
+		// Links not yet persisted have a zero CreatedAt and are new by definition
+		return url.CreatedAt.IsZero() || time.Since(url.CreatedAt) < window
+	}
+	
+	return false
+}
+
+// buildInfoResponse maps a URL onto the fields the info endpoint is allowed to expose
+func (s *urlService) buildInfoResponse(ctx context.Context, url *domain.URL) *domain.URLInfoResponse {
+	return &domain.URLInfoResponse{
+		ShortCode:            url.ShortCode,
+		ShortURL:             fmt.Sprintf("%s/%s", s.baseURL(ctx), url.ShortCode),
+		OriginalURL:          url.OriginalURL,
+		DisplayURL:           validator.DisplayURL(url.OriginalURL),
+		SubmittedURL:         url.SubmittedURL,
+		Title:                url.Title,
+		Description:          url.Description,
+		CreatedAt:            url.CreatedAt,
+		UpdatedAt:            url.UpdatedAt,
+		ExpiresAt:            url.ExpiresAt,
+		IsExpired:            url.IsExpired(),
+		IsActive:             url.IsActive,
+		ClickCount:           url.ClickCount,
+		LastAccessAt:         url.LastAccessAt,
+		CustomAlias:          url.CustomAlias,
+		RequiresInterstitial: url.RequiresInterstitial,
+		ConfirmBeforeRedirect: url.ConfirmBeforeRedirect,
+		UTM:                  url.UTM,
+		Targets:              url.Targets,
+		Variants:             url.Variants,
+		StickyVariants:       url.StickyVariants,
+		ForwardQuery:         url.ForwardQuery,
+		AttachClickID:        url.AttachClickID,
+		PublicStats:          url.PublicStats,
+		ReferrerPolicy:       url.ReferrerPolicy,
+		DeepLink:             url.DeepLink,
+		IOSFallbackURL:       url.IOSFallbackURL,
+		AndroidFallbackURL:   url.AndroidFallbackURL,
+		Bundle:               url.Bundle,
+		PageTitle:            url.PageTitle,
+		FaviconURL:           url.FaviconURL,
+		ExpiryNotifiedAt:     url.ExpiryNotifiedAt,
+		LastCheckedAt:        url.LastCheckedAt,
+		LastStatusCode:       url.LastStatusCode,
+		IsBroken:             url.IsBroken,
+	}
+}
+
+// buildDuplicateResponse answers a create request with a link that already existed
+func (s *urlService) buildDuplicateResponse(ctx context.Context, url *domain.URL) *domain.CreateURLResponse {
+	response := s.buildResponse(ctx, url)
+	response.Deduplicated = true
+	return response
 }
 
 // buildResponse constructs the API response with full short URL
-func (s *urlService) buildResponse(url *domain.URL) *domain.CreateURLResponse {
+func (s *urlService) buildResponse(ctx context.Context, url *domain.URL) *domain.CreateURLResponse {
 	return &domain.CreateURLResponse{
 		ShortCode:   url.ShortCode,
-		ShortURL:    fmt.Sprintf("%s/%s", s.cfg.BaseURL, url.ShortCode),
+		ShortURL:    fmt.Sprintf("%s/%s", s.baseURL(ctx), url.ShortCode),
 		OriginalURL: url.OriginalURL,
 		CreatedAt:   url.CreatedAt,
 		ExpiresAt:   url.ExpiresAt,
+		Label:       domain.LinkLabel(url.Title, url.PageTitle, url.OriginalURL),
 	}
 }
\ No newline at end of file
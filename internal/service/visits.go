@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"url-shortener/internal/cache"
+	"url-shortener/internal/domain"
+)
+
+// visitCounterRetention keeps a day's visit counters readable until the end of the following day
+const visitCounterRetention = 48 * time.Hour
+
+// cacheLookup is what one read of the redirect cache found for a short code
+type cacheLookup struct {
+	value    string // Encoded LinkEntry, empty on a miss
+	inactive bool   // The negative cache holds the code
+	checked  bool   // inactive was read along with the entry, so the negative cache needs no call of its own
+	counted  bool   // The visit was counted along with the read
+}
+
+// lookupCachedLink reads the redirect cache entry of shortCode
+// A cache.Resolver also reads the negative cache on a miss and counts a hit, all in one round trip;
+// other caches answer from Get and leave the negative cache to the caller
+func (s *urlService) lookupCachedLink(ctx context.Context, shortCode string, visitor domain.Visitor) (cacheLookup, error) {
+	key := s.codeKey(ctx, cache.LinkKey(shortCode))
+	if resolver, ok := s.cache.(cache.Resolver); ok {
+		res, err := resolver.ResolveAndCount(ctx, cache.ResolveRequest{
+			Key:         key,
+			InactiveKey: s.codeKey(ctx, cache.InactiveKey(shortCode)),
+			Counters:    s.visitCounters(ctx, shortCode, visitor, time.Now()),
+		})
+		if !errors.Is(err, cache.ErrResolveUnsupported) {
+			return cacheLookup{value: res.Value, inactive: res.Inactive, checked: err == nil, counted: res.Counted()}, err
+		}
+	}
+
+	value, err := s.cache.Get(ctx, key)
+	return cacheLookup{value: value}, err
+}
+
+// countVisit counts a visit lookupCachedLink didn't, e.g. one resolved from the database
+// Caches that don't implement cache.Resolver keep no visit counters
+func (s *urlService) countVisit(ctx context.Context, shortCode string, visitor domain.Visitor) {
+	resolver, ok := s.cache.(cache.Resolver)
+	if !ok {
+		return
+	}
+	err := resolver.CountVisit(ctx, s.visitCounters(ctx, shortCode, visitor, time.Now()))
+	if err != nil && !errors.Is(err, cache.ErrResolveUnsupported) {
+		s.log(ctx).Warn("Failed to count visit", "error", err, "short_code", shortCode)
+	}
+}
+
+// visitsToday returns the visits counted for shortCode today, or nil without visit counters
+func (s *urlService) visitsToday(ctx context.Context, shortCode string) *domain.VisitStats {
+	resolver, ok := s.cache.(cache.Resolver)
+	if !ok {
+		return nil
+	}
+	visits, visitors, err := resolver.VisitCounts(ctx, s.visitCounters(ctx, shortCode, domain.Visitor{}, time.Now()))
+	if err != nil {
+		if !errors.Is(err, cache.ErrResolveUnsupported) {
+			s.log(ctx).Warn("Failed to read visit counters", "error", err, "short_code", shortCode)
+		}
+		return nil
+	}
+	return &domain.VisitStats{Visits: visits, Visitors: visitors}
+}
+
+// forgetVisits drops the visit counters an erased or reset link may still have
+func (s *urlService) forgetVisits(ctx context.Context, shortCode string) {
+	if _, ok := s.cache.(cache.Resolver); !ok {
+		return
+	}
+	now := time.Now()
+	for _, day := range []time.Time{now, now.Add(-24 * time.Hour)} {
+		for _, key := range []string{cache.VisitsKey(shortCode, day), cache.VisitorsKey(shortCode, day)} {
+			if err := s.cache.Delete(ctx, s.codeKey(ctx, key)); err != nil {
+				s.log(ctx).Warn("Failed to delete visit counter", "error", err, "short_code", shortCode)
+			}
+		}
+	}
+}
+
+// visitCounters are the counters of shortCode's visits on the UTC day of now
+func (s *urlService) visitCounters(ctx context.Context, shortCode string, visitor domain.Visitor, now time.Time) cache.VisitCounters {
+	day := now.UTC().Truncate(24 * time.Hour)
+	return cache.VisitCounters{
+		VisitsKey:   s.codeKey(ctx, cache.VisitsKey(shortCode, day)),
+		VisitorsKey: s.codeKey(ctx, cache.VisitorsKey(shortCode, day)),
+		Visitor:     cache.VisitorID(visitor.IP, visitor.UserAgent),
+		ExpireAt:    day.Add(visitCounterRetention),
+	}
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"url-shortener/internal/cache"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/metrics"
+)
+
+// warmBatchSize bounds the keys stored per round trip, so a cancelled warm-up stops between batches
+const warmBatchSize = 500
+
+// WarmCache loads the most clicked links into the cache, e.g. after a deploy left it cold
+// Entries are built like on a miss, with the expiry-capped TTL; links needing an interstitial are skipped as there.
+// It stops with ctx, leaving the links it got to cached.
+func (s *urlService) WarmCache(ctx context.Context, limit int) (int, error) {
+	if s.cache == nil || limit <= 0 {
+		return 0, nil
+	}
+
+	urls, err := s.repo.TopByClickCount(ctx, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	warmed := 0
+	for start := 0; start < len(urls); start += warmBatchSize {
+		end := start + warmBatchSize
+		if end > len(urls) {
+			end = len(urls)
+		}
+		n, err := s.warmBatch(ctx, urls[start:end])
+		warmed += n
+		metrics.CacheWarmedLinks.Set(float64(warmed))
+		if err != nil {
+			return warmed, err
+		}
+	}
+	return warmed, nil
+}
+
+// warmBatch stores the entries of urls, one SetMultiple per TTL
+// Links without an expiry share the TTL; expiring ones are rounded down to the second to share it where they can
+func (s *urlService) warmBatch(ctx context.Context, urls []domain.URL) (int, error) {
+	byTTL := make(map[time.Duration]map[string]string)
+	for i := range urls {
+		url := &urls[i]
+		if s.linkRequiresInterstitial(url) {
+			continue
+		}
+		value, ttl := s.linkEntry(url)
+		ttl = ttl.Truncate(time.Second)
+		if ttl <= 0 {
+			continue
+		}
+		if byTTL[ttl] == nil {
+			byTTL[ttl] = make(map[string]string)
+		}
+		byTTL[ttl][s.codeKey(linkContext(ctx, url.TenantID), cache.LinkKey(url.ShortCode))] = value
+	}
+
+	warmed := 0
+	setter, batched := s.cache.(cache.BatchSetter)
+	for ttl, items := range byTTL {
+		if err := ctx.Err(); err != nil {
+			return warmed, err
+		}
+		if batched {
+			err := setter.SetMultiple(ctx, items, ttl)
+			if err == nil {
+				warmed += len(items)
+				continue
+			}
+			if !errors.Is(err, cache.ErrBatchSetUnsupported) {
+				return warmed, err
+			}
+			batched = false
+		}
+		for key, value := range items {
+			if err := s.cache.Set(ctx, key, value, ttl); err != nil {
+				return warmed, err
+			}
+			warmed++
+		}
+	}
+	return warmed, nil
+}
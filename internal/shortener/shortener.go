@@ -2,17 +2,39 @@ package shortener
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"math/big"
+	"strings"
 )
 
 // Base62 character set (0-9, A-Z, a-z) - 62 characters total
 // Using base62 instead of base64 avoids special characters that might cause URL issues
 const base62Chars = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
 
+// ambiguousChars are mistaken for one another when a code is typed from print: 0/O and 1/l/I
+const ambiguousChars = "01IOl"
+
 // CodeGenerator generates unique short codes using cryptographically secure random numbers
 // Thread-safe and collision-resistant
 type CodeGenerator struct {
-	length int // Length of generated codes
+	length   int    // Length of generated codes
+	alphabet string // Characters Generate picks from, base62 unless narrowed by an option
+}
+
+// GeneratorOption customizes a CodeGenerator
+type GeneratorOption func(*CodeGenerator)
+
+// WithoutAmbiguousChars drops 0, O, 1, l and I from the characters Generate picks from
+// Codes are still validated and decoded as base62, so existing codes keep working
+func WithoutAmbiguousChars() GeneratorOption {
+	return func(g *CodeGenerator) {
+		g.alphabet = strings.Map(func(r rune) rune {
+			if strings.ContainsRune(ambiguousChars, r) {
+				return -1
+			}
+			return r
+		}, g.alphabet)
+	}
 }
 
 // NewCodeGenerator creates a new code generator with specified length
@@ -20,7 +42,7 @@ type CodeGenerator struct {
 // - 6 chars = 62^6 = ~56 billion combinations
 // - 7 chars = 62^7 = ~3.5 trillion combinations
 // - 8 chars = 62^8 = ~218 trillion combinations
-func NewCodeGenerator(length int) *CodeGenerator {
+func NewCodeGenerator(length int, opts ...GeneratorOption) *CodeGenerator {
 	if length < 4 {
 		length = 6 // Minimum safe length
 	}
@@ -28,12 +50,17 @@ func NewCodeGenerator(length int) *CodeGenerator {
 		length = 12 // Maximum reasonable length
 	}
 	
-	return &CodeGenerator{
-		length: length,
+	g := &CodeGenerator{
+		length:   length,
+		alphabet: base62Chars,
+	}
+	for _, opt := range opts {
+		opt(g)
 	}
+	return g
 }
 
-// Generate creates a random short code using base62 encoding
+// Generate creates a random short code from the generator's alphabet
 // Uses crypto/rand for cryptographically secure random generation
 // This prevents predictability and ensures collision resistance
 func (g *CodeGenerator) Generate() string {
@@ -41,14 +68,14 @@ func (g *CodeGenerator) Generate() string {
 	
 	for i := 0; i < g.length; i++ {
 		// Generate random index using crypto/rand for security
-		num, err := rand.Int(rand.Reader, big.NewInt(int64(len(base62Chars))))
+		num, err := rand.Int(rand.Reader, big.NewInt(int64(len(g.alphabet))))
 		if err != nil {
 			// Fallback to less secure method if crypto/rand fails
 			// This should rarely happen in practice
-			num = big.NewInt(int64(i % len(base62Chars)))
+			num = big.NewInt(int64(i % len(g.alphabet)))
 		}
 		
-		result[i] = base62Chars[num.Int64()]
+		result[i] = g.alphabet[num.Int64()]
 	}
 	
 	return string(result)
@@ -80,6 +107,29 @@ func (g *CodeGenerator) GenerateFromID(id uint) string {
 	return string(result)
 }
 
+// GenerateFromContent derives a code of the given length from the SHA-256 of content
+// The same content always yields the same code, and a longer code extends the shorter one,
+// so a collision can be resolved by asking for one more character
+// It always uses base62: narrowing the alphabet would change the code of content already shortened
+func (g *CodeGenerator) GenerateFromContent(content string, length int) string {
+	sum := sha256.Sum256([]byte(content))
+	num := new(big.Int).SetBytes(sum[:])
+	base := big.NewInt(int64(len(base62Chars)))
+	
+	// 256 bits give 43 base62 digits, far more than any code needs
+	digits := make([]byte, 0, 43)
+	remainder := new(big.Int)
+	for num.Sign() > 0 {
+		num.DivMod(num, base, remainder)
+		digits = append(digits, base62Chars[remainder.Int64()])
+	}
+	for len(digits) < length {
+		digits = append(digits, base62Chars[0])
+	}
+	
+	return string(digits[:length])
+}
+
 // Decode converts a base62 short code back to numeric ID
 // Useful for reversing GenerateFromID operation
 func (g *CodeGenerator) Decode(code string) uint {
@@ -108,8 +158,9 @@ func (g *CodeGenerator) Decode(code string) uint {
 }
 
 // IsValid checks if a short code contains only valid base62 characters
+// Its length isn't bound to the generated length: custom aliases are longer and limited by MAX_CUSTOM_ALIAS_LENGTH
 func (g *CodeGenerator) IsValid(code string) bool {
-	if len(code) == 0 || len(code) > g.length {
+	if len(code) == 0 {
 		return false
 	}
 	
@@ -129,7 +180,7 @@ func (g *CodeGenerator) IsValid(code string) bool {
 	return true
 }
 
-// GetCollisionProbability calculates approximate collision probability
+// GetCollisionProbability calculates approximate collision probability of generated codes
 // Formula: 1 - (1 - 1/N)^k where N = total combinations, k = number of URLs
 // This is a simplified birthday problem calculation
 func (g *CodeGenerator) GetCollisionProbability(numURLs int) float64 {
@@ -137,10 +188,10 @@ func (g *CodeGenerator) GetCollisionProbability(numURLs int) float64 {
 		return 0.0
 	}
 	
-	// Calculate total possible combinations (62^length)
+	// Calculate total possible combinations (alphabet size^length)
 	totalCombinations := 1.0
 	for i := 0; i < g.length; i++ {
-		totalCombinations *= 62
+		totalCombinations *= float64(len(g.alphabet))
 	}
 	
 	// Approximate collision probability using birthday problem
@@ -0,0 +1,157 @@
+// Package unshorten follows the redirects of other URL shorteners to the page they point at
+package unshorten
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"url-shortener/internal/netguard"
+)
+
+const (
+	// DefaultTimeout bounds a whole resolution including every hop
+	DefaultTimeout = 5 * time.Second
+
+	// MaxHops is how many redirects are followed before giving up
+	MaxHops = 5
+)
+
+var (
+	// ErrRedirectLoop is returned when a chain comes back to a URL it already visited
+	ErrRedirectLoop = errors.New("redirect loop")
+
+	// ErrTooManyHops is returned when a chain is still redirecting after MaxHops
+	ErrTooManyHops = errors.New("too many redirects")
+)
+
+// Resolver returns the final destination of a short link
+type Resolver interface {
+	Resolve(ctx context.Context, rawURL string) (string, error)
+}
+
+// HTTPResolver resolves chains by requesting each hop without following redirects itself
+// Hops are checked one by one, so loops are detected and every host passes the SSRF guard
+type HTTPResolver struct {
+	client *http.Client
+}
+
+// NewHTTPResolver creates a resolver whose connections go through the SSRF guard
+func NewHTTPResolver(timeout time.Duration) *HTTPResolver {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: netguard.Control,
+	}
+
+	return NewHTTPResolverWithTransport(&http.Transport{
+		DialContext:         dialer.DialContext,
+		TLSHandshakeTimeout: timeout,
+		MaxIdleConns:        10,
+		IdleConnTimeout:     30 * time.Second,
+	}, timeout)
+}
+
+// NewHTTPResolverWithTransport creates a resolver on a custom transport, e.g. a stub in tests
+// The transport is responsible for its own address checks
+func NewHTTPResolverWithTransport(transport http.RoundTripper, timeout time.Duration) *HTTPResolver {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	return &HTTPResolver{
+		client: &http.Client{
+			Timeout:   timeout,
+			Transport: transport,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// Resolve follows rawURL until a response that isn't a redirect and returns that URL
+func (r *HTTPResolver) Resolve(ctx context.Context, rawURL string) (string, error) {
+	current, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+
+	seen := map[string]bool{}
+	for hops := 0; ; hops++ {
+		seen[current.String()] = true
+
+		next, err := r.hop(ctx, current)
+		if err != nil {
+			return "", err
+		}
+		if next == nil {
+			return current.String(), nil
+		}
+
+		if seen[next.String()] {
+			return "", fmt.Errorf("%w: %s comes back to %s", ErrRedirectLoop, current, next)
+		}
+		if hops == MaxHops {
+			return "", fmt.Errorf("%w: still redirecting after %d hops at %s", ErrTooManyHops, MaxHops, current)
+		}
+		current = next
+	}
+}
+
+// hop requests u and returns where it redirects to, or nil when it doesn't
+func (r *HTTPResolver) hop(ctx context.Context, u *url.URL) (*url.URL, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "url-shortener-unshorten/1.0")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	// Only the status line and headers matter
+	resp.Body.Close()
+
+	if resp.StatusCode < 300 || resp.StatusCode > 399 {
+		return nil, nil
+	}
+
+	location := resp.Header.Get("Location")
+	if location == "" {
+		return nil, fmt.Errorf("%s answered %d without a Location", u, resp.StatusCode)
+	}
+	next, err := u.Parse(location)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redirect from %s: %w", u, err)
+	}
+	if next.Scheme != "http" && next.Scheme != "https" {
+		return nil, fmt.Errorf("redirect to unsupported scheme %q", next.Scheme)
+	}
+	return next, nil
+}
+
+// MatchesDomain reports whether host is one of domains or a subdomain of one
+// Matching is case-insensitive and ignores a port
+func MatchesDomain(host string, domains []string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+
+	for _, domain := range domains {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
@@ -1,41 +0,0 @@
--- Create URLs table for PostgreSQL
-CREATE TABLE IF NOT EXISTS urls (
-    id BIGSERIAL PRIMARY KEY,
-    short_code VARCHAR(12) NOT NULL UNIQUE,
-    original_url TEXT NOT NULL,
-    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
-    expires_at TIMESTAMP WITH TIME ZONE NULL,
-    click_count BIGINT DEFAULT 0,
-    last_access_at TIMESTAMP WITH TIME ZONE NULL,
-    creator_ip VARCHAR(45) NULL, -- Support IPv6
-    is_active BOOLEAN DEFAULT TRUE,
-    custom_alias BOOLEAN DEFAULT FALSE
-);
-
--- Create indexes for performance
-CREATE INDEX IF NOT EXISTS idx_urls_short_code ON urls(short_code) WHERE is_active = true;
-CREATE INDEX IF NOT EXISTS idx_urls_original_url ON urls(original_url);
-CREATE INDEX IF NOT EXISTS idx_urls_expires_at ON urls(expires_at) WHERE expires_at IS NOT NULL;
-CREATE INDEX IF NOT EXISTS idx_urls_created_at ON urls(created_at);
-CREATE INDEX IF NOT EXISTS idx_urls_is_active ON urls(is_active);
-
--- Create function to update updated_at timestamp
-CREATE OR REPLACE FUNCTION update_updated_at_column()
-RETURNS TRIGGER AS $$
-BEGIN
-    NEW.updated_at = CURRENT_TIMESTAMP;
-    RETURN NEW;
-END;
-$$ language 'plpgsql';
-
--- Create trigger to automatically update updated_at
-CREATE TRIGGER update_urls_updated_at 
-    BEFORE UPDATE ON urls 
-    FOR EACH ROW 
-    EXECUTE FUNCTION update_updated_at_column();
-
--- Create partial index for active non-expired URLs (commonly queried)
--- CREATE INDEX IF NOT EXISTS idx_urls_active_non_expired 
--- ON urls(short_code) 
--- WHERE is_active = true AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP);
\ No newline at end of file
@@ -0,0 +1,244 @@
+// Package client calls the URL shortener's HTTP API from other Go services
+// Error responses come back as *APIError, and rate limits and server failures are retried with backoff.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"url-shortener/internal/domain"
+)
+
+// Defaults used when no option overrides them
+const (
+	DefaultTimeout    = 10 * time.Second
+	DefaultMaxRetries = 3
+	DefaultBackoff    = 200 * time.Millisecond
+	DefaultMaxWait    = 5 * time.Second
+)
+
+// maxErrorBody bounds how much of an error response is read
+const maxErrorBody = 64 << 10
+
+// Client calls one deployment of the API; it is safe for concurrent use
+type Client struct {
+	baseURL    string
+	apiKey     string
+	httpClient *http.Client
+	maxRetries int
+	backoff    time.Duration
+	maxWait    time.Duration
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithAPIKey sends key in X-API-Key on every request
+func WithAPIKey(key string) Option {
+	return func(c *Client) { c.apiKey = key }
+}
+
+// WithTimeout bounds each attempt, including reading the response
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) { c.httpClient.Timeout = timeout }
+}
+
+// WithHTTPClient replaces the HTTP client, e.g. for a custom transport
+// Its timeout applies instead of the default; WithTimeout after this option changes it
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) { c.httpClient = httpClient }
+}
+
+// WithRetry sets how often 429 and 5xx responses are retried; 0 turns retries off
+// The wait starts at backoff and doubles per attempt up to maxWait. A Retry-After longer than maxWait,
+// such as an exhausted daily quota, is not waited for; the error is returned instead.
+func WithRetry(maxRetries int, backoff, maxWait time.Duration) Option {
+	return func(c *Client) {
+		c.maxRetries = maxRetries
+		c.backoff = backoff
+		c.maxWait = maxWait
+	}
+}
+
+// New creates a client for the deployment at baseURL, e.g. https://sho.rt
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: &http.Client{Timeout: DefaultTimeout},
+		maxRetries: DefaultMaxRetries,
+		backoff:    DefaultBackoff,
+		maxWait:    DefaultMaxWait,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Shorten creates a short link, or returns the existing one for a destination that was already shortened
+func (c *Client) Shorten(ctx context.Context, req *CreateURLRequest) (*CreateURLResponse, error) {
+	var response CreateURLResponse
+	if err := c.do(ctx, http.MethodPost, "/api/v1/shorten", nil, req, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// Resolve looks up where shortCode points without counting a click
+func (c *Client) Resolve(ctx context.Context, shortCode string) (*URLInfoResponse, error) {
+	var info URLInfoResponse
+	if err := c.do(ctx, http.MethodGet, "/api/v1/urls/"+url.PathEscape(shortCode), nil, nil, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// GetStats returns the click statistics of shortCode
+func (c *Client) GetStats(ctx context.Context, shortCode string) (*URLStats, error) {
+	var stats URLStats
+	if err := c.do(ctx, http.MethodGet, "/api/v1/urls/"+url.PathEscape(shortCode)+"/stats", nil, nil, &stats); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// Delete removes shortCode, authorized by the management token from its create response
+// An empty token relies on the client's API key being the admin key
+func (c *Client) Delete(ctx context.Context, shortCode, managementToken string) error {
+	header := http.Header{}
+	if managementToken != "" {
+		header.Set("X-Management-Token", managementToken)
+	}
+	return c.do(ctx, http.MethodDelete, "/api/v1/urls/"+url.PathEscape(shortCode), header, nil, nil)
+}
+
+// do sends the request, retrying when retryable allows it, and decodes a 2xx body into out
+func (c *Client) do(ctx context.Context, method, path string, header http.Header, in, out interface{}) error {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return fmt.Errorf("url-shortener: encode request: %w", err)
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := c.send(ctx, method, path, header, body, out)
+		apiErr, ok := err.(*APIError)
+		if !ok || attempt >= c.maxRetries || !retryable(method, apiErr.StatusCode) {
+			return err
+		}
+
+		wait := c.backoff << attempt
+		if wait > c.maxWait || wait <= 0 {
+			wait = c.maxWait
+		}
+		if apiErr.RetryAfter > 0 {
+			if apiErr.RetryAfter > c.maxWait {
+				return err
+			}
+			wait = apiErr.RetryAfter
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// send makes one attempt
+func (c *Client) send(ctx context.Context, method, path string, header http.Header, body []byte, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("url-shortener: build request: %w", err)
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.apiKey != "" {
+		req.Header.Set("X-API-Key", c.apiKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("url-shortener: %s %s: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		if out == nil {
+			return nil
+		}
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return fmt.Errorf("url-shortener: decode response: %w", err)
+		}
+		return nil
+	}
+	return newAPIError(resp)
+}
+
+// newAPIError reads an error response; bodies that aren't an ErrorResponse, e.g. from a proxy, keep the status
+func newAPIError(resp *http.Response) *APIError {
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode,
+		Message:    http.StatusText(resp.StatusCode),
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+	}
+
+	// Problem details carry the same error and suggestions, with the message as detail
+	var body struct {
+		domain.ErrorResponse
+		Detail string `json:"detail"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	if json.Unmarshal(data, &body) == nil && body.Error != "" {
+		apiErr.Code = body.Error
+		apiErr.Suggestions = body.Suggestions
+		if body.Message != "" {
+			apiErr.Message = body.Message
+		} else if body.Detail != "" {
+			apiErr.Message = body.Detail
+		}
+	}
+	return apiErr
+}
+
+// retryable reports whether a response with status is worth another attempt
+// A create answered with 500 or 504 may still be stored, so POSTs only retry when the server didn't get to it
+func retryable(method string, status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable:
+		return true
+	}
+	return status >= 500 && method != http.MethodPost
+}
+
+// parseRetryAfter accepts both forms of Retry-After, seconds and an HTTP date
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+	return 0
+}
@@ -0,0 +1,76 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Errors matched with errors.Is against what the client methods return
+var (
+	// ErrValidation is returned when the API refused the request as malformed (400, 413)
+	ErrValidation = errors.New("invalid request")
+
+	// ErrUnauthorized is returned when the API key is missing or wrong (401)
+	ErrUnauthorized = errors.New("unauthorized")
+
+	// ErrForbidden is returned when the key or management token may not act on the link (403)
+	ErrForbidden = errors.New("forbidden")
+
+	// ErrNotFound is returned when the short code doesn't exist or was deleted (404)
+	ErrNotFound = errors.New("not found")
+
+	// ErrConflict is returned when a custom alias is already taken (409)
+	ErrConflict = errors.New("conflict")
+
+	// ErrExpired is returned when the link has expired (410)
+	ErrExpired = errors.New("link expired")
+
+	// ErrRateLimited is returned when the rate limit or daily quota is used up and retries didn't help (429)
+	ErrRateLimited = errors.New("rate limited")
+
+	// ErrUnavailable is returned when the server failed and retries didn't help (5xx)
+	ErrUnavailable = errors.New("service unavailable")
+)
+
+// APIError is an error response of the API
+// errors.Is matches the sentinel for its status, e.g. ErrNotFound for a 404
+type APIError struct {
+	StatusCode  int
+	Code        string // Machine-readable error, see GET /api/v1/errors
+	Message     string
+	Suggestions []string      // Free aliases offered when the requested one is taken
+	RetryAfter  time.Duration // From the Retry-After header, 0 when there was none
+}
+
+// Error implements the error interface
+func (e *APIError) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("url-shortener: %d %s", e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("url-shortener: %d %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// Unwrap returns the sentinel for the status code, or nil for statuses without one
+func (e *APIError) Unwrap() error {
+	switch {
+	case e.StatusCode == http.StatusBadRequest || e.StatusCode == http.StatusRequestEntityTooLarge:
+		return ErrValidation
+	case e.StatusCode == http.StatusUnauthorized:
+		return ErrUnauthorized
+	case e.StatusCode == http.StatusForbidden:
+		return ErrForbidden
+	case e.StatusCode == http.StatusNotFound:
+		return ErrNotFound
+	case e.StatusCode == http.StatusConflict:
+		return ErrConflict
+	case e.StatusCode == http.StatusGone:
+		return ErrExpired
+	case e.StatusCode == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case e.StatusCode >= 500:
+		return ErrUnavailable
+	}
+	return nil
+}
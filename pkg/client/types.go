@@ -0,0 +1,14 @@
+package client
+
+import "url-shortener/internal/domain"
+
+// The request and response types are the server's own; the aliases let callers outside this module,
+// which can't import internal packages, name them
+type (
+	CreateURLRequest  = domain.CreateURLRequest
+	CreateURLResponse = domain.CreateURLResponse
+	URLInfoResponse   = domain.URLInfoResponse
+	URLStats          = domain.URLStats
+	Snippets          = domain.Snippets
+	UTMParams         = domain.UTMParams
+)
@@ -2,10 +2,8 @@
 package logger
 
 import (
-	"io"
 	"log"
 	"os"
-	"path/filepath"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -17,27 +15,25 @@ type Logger struct {
 	level zap.AtomicLevel
 }
 
+// Formats selectable with Options.Format
+const (
+	FormatJSON    = "json"    // One JSON object per entry, for log shippers
+	FormatConsole = "console" // Tab-separated plain text, for reading in a terminal
+)
+
+// Options configures a logger; the zero value logs JSON at info level to stdout
+type Options struct {
+	Level       string      // debug, info, warn or error; empty for info
+	Format      string      // FormatJSON or FormatConsole; empty for FormatJSON
+	Development bool        // Adds the caller to each entry and panics on DPanic
+	File        FileOptions // Also writes to a rotating file when File.Path is set
+}
+
 // NewLogger creates a new structured logger
-func NewLogger() *Logger {
-	// Set up log level
+// Entries always go to stdout. A log file that can't be opened is reported there and left out.
+func NewLogger(opts Options) *Logger {
 	level := zap.NewAtomicLevel()
-	
-	// Default to info level, can be changed via environment variable
-	logLevel := os.Getenv("LOG_LEVEL")
-	if logLevel != "" {
-		switch logLevel {
-		case "debug":
-			level.SetLevel(zap.DebugLevel)
-		case "warn":
-			level.SetLevel(zap.WarnLevel)
-		case "error":
-			level.SetLevel(zap.ErrorLevel)
-		default:
-			level.SetLevel(zap.InfoLevel)
-		}
-	} else {
-		level.SetLevel(zap.InfoLevel)
-	}
+	level.SetLevel(parseLevel(opts.Level))
 
 	// Configure encoder
 	encoderConfig := zapcore.EncoderConfig{
@@ -53,41 +49,55 @@ func NewLogger() *Logger {
 		EncodeDuration: zapcore.SecondsDurationEncoder,
 		EncodeCaller:   zapcore.ShortCallerEncoder,
 	}
+	encoder := zapcore.NewJSONEncoder(encoderConfig)
+	if opts.Format == FormatConsole {
+		encoderConfig.EncodeLevel = zapcore.CapitalLevelEncoder
+		encoder = zapcore.NewConsoleEncoder(encoderConfig)
+	}
 
 	// Set up outputs
-	var output io.Writer = os.Stdout
-	
-	// In production, write to file and stdout
-	if os.Getenv("ENVIRONMENT") == "production" {
-		logDir := "logs"
-		if err := os.MkdirAll(logDir, 0755); err == nil {
-			logFile := filepath.Join(logDir, "url-shortener.log")
-			file, err := os.OpenFile(logFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
-			if err == nil {
-				output = io.MultiWriter(os.Stdout, file)
-			}
+	output := zapcore.AddSync(os.Stdout)
+	var fileErr error
+	if opts.File.Path != "" {
+		file, err := OpenRotatingFile(opts.File)
+		if err == nil {
+			output = zapcore.NewMultiWriteSyncer(output, file)
 		}
+		fileErr = err
 	}
 
-	core := zapcore.NewCore(
-		zapcore.NewJSONEncoder(encoderConfig),
-		zapcore.AddSync(output),
-		level,
-	)
+	core := zapcore.NewCore(encoder, output, level)
 
 	// Add caller information in development
-	development := os.Getenv("ENVIRONMENT") == "development"
 	var zapLogger *zap.Logger
-	if development {
+	if opts.Development {
 		zapLogger = zap.New(core, zap.AddCaller(), zap.Development())
 	} else {
 		zapLogger = zap.New(core)
 	}
 
-	return &Logger{
+	l := &Logger{
 		SugaredLogger: zapLogger.Sugar(),
 		level:         level,
 	}
+	if fileErr != nil {
+		l.Warnw("Logging to stdout only, the log file can't be opened", "error", fileErr, "path", opts.File.Path)
+	}
+	return l
+}
+
+// parseLevel maps a level name to its zap level, info for anything unknown
+func parseLevel(level string) zapcore.Level {
+	switch level {
+	case "debug":
+		return zap.DebugLevel
+	case "warn":
+		return zap.WarnLevel
+	case "error":
+		return zap.ErrorLevel
+	default:
+		return zap.InfoLevel
+	}
 }
 
 // GetStandardLogger returns a standard library logger (simplified for GORM)
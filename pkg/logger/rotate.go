@@ -0,0 +1,269 @@
+package logger
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupTimeFormat stamps rotated files, e.g. url-shortener-2026-03-01T12-00-00.000.log
+// It sorts like the time it names and contains no characters file systems refuse.
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// FileOptions configures the log file
+type FileOptions struct {
+	Path       string // Log file; empty writes no file
+	MaxSizeMB  int    // Size the file is rotated at, 0 for 100 MB
+	MaxBackups int    // Rotated files kept, 0 keeps all
+	MaxAgeDays int    // Rotated files older than this are removed, 0 keeps them regardless of age
+	Compress   bool   // Gzip rotated files
+}
+
+// RotatingFile is a log file that is renamed once it reaches its size limit and started afresh
+// Rotated files are pruned and compressed in the background so writes never wait for them.
+type RotatingFile struct {
+	opts    FileOptions
+	maxSize int64
+
+	mu     sync.Mutex
+	file   *os.File
+	size   int64
+	closed bool
+
+	mill     chan struct{} // Wakes the goroutine pruning and compressing backups
+	millDone chan struct{}
+}
+
+// OpenRotatingFile opens or creates the file at opts.Path, creating its directory, and appends to it
+func OpenRotatingFile(opts FileOptions) (*RotatingFile, error) {
+	if opts.Path == "" {
+		return nil, errors.New("log file path is empty")
+	}
+	opts.Path = filepath.Clean(opts.Path)
+	maxSizeMB := opts.MaxSizeMB
+	if maxSizeMB <= 0 {
+		maxSizeMB = 100
+	}
+
+	f := &RotatingFile{
+		opts:     opts,
+		maxSize:  int64(maxSizeMB) * 1024 * 1024,
+		mill:     make(chan struct{}, 1),
+		millDone: make(chan struct{}),
+	}
+	if err := os.MkdirAll(filepath.Dir(opts.Path), 0755); err != nil {
+		return nil, fmt.Errorf("log directory: %w", err)
+	}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+
+	go f.runMill()
+	// Backups left by an earlier run are held to the current limits too
+	f.mill <- struct{}{}
+	return f, nil
+}
+
+// Write appends p, first rotating the file if p would take it past its size limit
+// An entry larger than the limit on its own is still written whole, to a fresh file.
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return 0, os.ErrClosed
+	}
+	if f.size > 0 && f.size+int64(len(p)) > f.maxSize {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// Sync flushes the file to disk
+func (f *RotatingFile) Sync() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return nil
+	}
+	return f.file.Sync()
+}
+
+// Close closes the file and waits for pruning and compression still running
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	if f.closed {
+		f.mu.Unlock()
+		return nil
+	}
+	f.closed = true
+	err := f.file.Close()
+	close(f.mill)
+	f.mu.Unlock()
+
+	<-f.millDone
+	return err
+}
+
+// open opens the file for appending and picks up the size it already has
+func (f *RotatingFile) open() error {
+	file, err := os.OpenFile(f.opts.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("log file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("log file: %w", err)
+	}
+	f.file = file
+	f.size = info.Size()
+	return nil
+}
+
+// rotate renames the current file after the time of rotation and opens a new one; f.mu must be held
+func (f *RotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return fmt.Errorf("log file: %w", err)
+	}
+	if err := os.Rename(f.opts.Path, f.nextBackupName(time.Now().UTC())); err != nil {
+		return fmt.Errorf("log file: %w", err)
+	}
+	if err := f.open(); err != nil {
+		return err
+	}
+
+	select {
+	case f.mill <- struct{}{}:
+	default: // A run is already due and will see this backup
+	}
+	return nil
+}
+
+// nextBackupName is the name the current file is rotated to at t
+// Rotations within one millisecond take the following free stamps instead of overwriting each other.
+func (f *RotatingFile) nextBackupName(t time.Time) string {
+	prefix, ext := f.backupPattern()
+	for {
+		name := prefix + t.Format(backupTimeFormat) + ext
+		if !fileExists(name) && !fileExists(name+".gz") {
+			return name
+		}
+		t = t.Add(time.Millisecond)
+	}
+}
+
+// fileExists reports whether anything is at path
+func fileExists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
+
+// backupPattern returns what rotated files start and end with, before an optional .gz
+func (f *RotatingFile) backupPattern() (prefix, ext string) {
+	ext = filepath.Ext(f.opts.Path)
+	return strings.TrimSuffix(f.opts.Path, ext) + "-", ext
+}
+
+// runMill prunes and compresses backups each time a rotation asks for it, until Close
+func (f *RotatingFile) runMill() {
+	defer close(f.millDone)
+	for range f.mill {
+		f.pruneBackups()
+	}
+}
+
+// backup is one rotated file
+type backup struct {
+	path    string
+	rotated time.Time
+}
+
+// pruneBackups removes backups beyond MaxBackups or MaxAgeDays and compresses the rest if asked to
+// Failures are left for the next run; they must not be logged through the file they concern.
+func (f *RotatingFile) pruneBackups() {
+	backups := f.backups()
+	// Newest first, so the ones to keep come before the ones to remove
+	sort.Slice(backups, func(i, j int) bool { return backups[i].rotated.After(backups[j].rotated) })
+
+	cutoff := time.Now().AddDate(0, 0, -f.opts.MaxAgeDays)
+	for i, b := range backups {
+		if (f.opts.MaxBackups > 0 && i >= f.opts.MaxBackups) || (f.opts.MaxAgeDays > 0 && b.rotated.Before(cutoff)) {
+			_ = os.Remove(b.path)
+			continue
+		}
+		if f.opts.Compress && !strings.HasSuffix(b.path, ".gz") {
+			_ = compressFile(b.path)
+		}
+	}
+}
+
+// backups lists the rotated files next to the log file
+func (f *RotatingFile) backups() []backup {
+	prefix, ext := f.backupPattern()
+	entries, err := os.ReadDir(filepath.Dir(f.opts.Path))
+	if err != nil {
+		return nil
+	}
+
+	var backups []backup
+	for _, entry := range entries {
+		path := filepath.Join(filepath.Dir(f.opts.Path), entry.Name())
+		if entry.IsDir() || !strings.HasPrefix(path, prefix) {
+			continue
+		}
+		stamp := strings.TrimSuffix(strings.TrimSuffix(strings.TrimPrefix(path, prefix), ".gz"), ext)
+		rotated, err := time.Parse(backupTimeFormat, stamp)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, backup{path: path, rotated: rotated})
+	}
+	return backups
+}
+
+// compressFile gzips path to path.gz and removes path once the copy is complete
+func compressFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	tmp := path + ".gz.tmp"
+	dst, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(dst)
+	_, err = io.Copy(gz, src)
+	if closeErr := gz.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+
+	if err := os.Rename(tmp, path+".gz"); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return os.Remove(path)
+}
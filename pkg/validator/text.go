@@ -0,0 +1,61 @@
+package validator
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// htmlTagRegex matches tags, comments and an unterminated tag at the end of the text
+// A "<" not followed by a letter, "/", "!" or "?" is not a tag and is kept, e.g. in "a < b"
+var htmlTagRegex = regexp.MustCompile(`<[a-zA-Z/!?][^>]*(>|$)`)
+
+// StripHTML removes HTML tags and control characters from user-supplied text
+// Tags are removed until none are left, so nesting like "<<b>script>" can't reassemble one.
+// Line breaks are kept when multiline is set; otherwise all whitespace runs become one space.
+// Entities are left as written: the result is plain text and must still be escaped when rendered.
+func StripHTML(s string, multiline bool) string {
+	for {
+		stripped := htmlTagRegex.ReplaceAllString(s, "")
+		if stripped == s {
+			break
+		}
+		s = stripped
+	}
+
+	if !multiline {
+		return strings.Join(strings.FieldsFunc(s, isSpaceOrControl), " ")
+	}
+
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = strings.Map(func(r rune) rune {
+		switch {
+		case r == '\n':
+			return r
+		case r == '\t' || r == '\r':
+			return ' '
+		case unicode.IsControl(r):
+			return -1
+		}
+		return r
+	}, s)
+	return strings.TrimSpace(s)
+}
+
+// isSpaceOrControl splits single-line text, dropping control characters along with whitespace
+func isSpaceOrControl(r rune) bool {
+	return unicode.IsSpace(r) || unicode.IsControl(r)
+}
+
+// markdownEscaper backslash-escapes the characters that start Markdown formatting or inline HTML
+// Every ASCII punctuation character may be escaped in CommonMark, so the result reads as the text itself.
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "{", `\{`, "}", `\}`, "[", `\[`, "]", `\]`,
+	"(", `\(`, ")", `\)`, "<", `\<`, ">", `\>`, "!", `\!`, "~", `\~`, "|", `\|`, "#", `\#`, "&", `\&`,
+)
+
+// EscapeMarkdown makes s safe as Markdown link text: it renders as written, never as markup or HTML
+// Line breaks would end the link, so s should be single-line, e.g. from StripHTML.
+func EscapeMarkdown(s string) string {
+	return markdownEscaper.Replace(s)
+}
@@ -1,35 +1,82 @@
 package validator
 
 import (
+	"net"
 	"net/url"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+
+	"golang.org/x/net/idna"
 )
 
 var (
-	// urlRegex is a comprehensive URL validation regex
-	urlRegex = regexp.MustCompile(`^(https?|ftp)://[^\s/$.?#].[^\s]*$`)
+	// urlRegex is a comprehensive validation regex for URLs with a host; the scheme is checked separately
+	urlRegex = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.-]*://[^\s/$.?#].[^\s]*$`)
+	
+	// mailboxRegex matches one address of a mailto: URL
+	mailboxRegex = regexp.MustCompile(`^[^\s@]+@[^\s@]+$`)
+	
+	// phoneRegex matches the number of a tel: URL, with the visual separators RFC 3966 permits
+	phoneRegex = regexp.MustCompile(`^\+?[0-9][0-9().-]*$`)
 	
 	// shortCodeRegex validates short code format (alphanumeric, hyphens, underscores)
 	shortCodeRegex = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
 	
-	// allowedSchemes lists permitted URL schemes
-	allowedSchemes = map[string]bool{
-		"http":  true,
-		"https": true,
-		"ftp":   true,
+	// opaqueSchemes never have a host, so NormalizeURL must not mistake them for one
+	opaqueSchemes = map[string]bool{
+		"mailto": true,
+		"tel":    true,
+		"sms":    true,
+	}
+	
+	// reservedAliases are path segments served by the router itself, so links there would be unreachable
+	reservedAliases = map[string]bool{
+		"api":      true,
+		"health":   true,
+		"metrics":  true,
+		"static":   true,
+		"admin":    true,
+		"continue": true,
+
+		// Static segments under /api/v1/urls and /api/v1/admin/urls that would hide the link's info endpoints
+		"expiring":    true,
+		"bulk-delete": true,
+		"search":      true,
+
+		// Well-known files fetched by crawlers and browsers
+		"robots.txt":  true,
+		"favicon.ico": true,
 	}
 )
 
-// ValidateURL checks if a string is a valid URL
-func ValidateURL(rawURL string) error {
-	if rawURL == "" {
-		return &ValidationError{Field: "url", Message: "URL cannot be empty"}
+// DefaultSchemes are allowed by a Validator created without any
+var DefaultSchemes = []string{"http", "https"}
+
+// Validator checks destination URLs against the schemes a deployment allows
+type Validator struct {
+	schemes map[string]bool
+}
+
+// New creates a validator accepting URLs with one of schemes, or DefaultSchemes when none are given
+func New(schemes []string) *Validator {
+	if len(schemes) == 0 {
+		schemes = DefaultSchemes
+	}
+	
+	v := &Validator{schemes: make(map[string]bool, len(schemes))}
+	for _, scheme := range schemes {
+		v.schemes[strings.ToLower(scheme)] = true
 	}
+	return v
+}
 
-	// Basic regex check
-	if !urlRegex.MatchString(rawURL) {
-		return &ValidationError{Field: "url", Message: "Invalid URL format"}
+// ValidateURL checks if a string is a valid URL with an allowed scheme
+// mailto: and tel: have no host; their address or number is checked instead
+func (v *Validator) ValidateURL(rawURL string) error {
+	if rawURL == "" {
+		return &ValidationError{Field: "url", Message: "URL cannot be empty"}
 	}
 
 	// Parse URL
@@ -39,13 +86,24 @@ func ValidateURL(rawURL string) error {
 	}
 
 	// Validate scheme
-	if !allowedSchemes[strings.ToLower(parsed.Scheme)] {
+	if !v.schemes[strings.ToLower(parsed.Scheme)] {
 		return &ValidationError{Field: "url", Message: "Unsupported URL scheme"}
 	}
 
-	// Validate host
-	if parsed.Host == "" {
-		return &ValidationError{Field: "url", Message: "URL must contain a host"}
+	if parsed.Opaque != "" {
+		if !validOpaque(strings.ToLower(parsed.Scheme), parsed.Opaque) {
+			return &ValidationError{Field: "url", Message: "Invalid URL format"}
+		}
+	} else {
+		// Basic regex check
+		if !urlRegex.MatchString(rawURL) {
+			return &ValidationError{Field: "url", Message: "Invalid URL format"}
+		}
+
+		// Validate host
+		if parsed.Host == "" {
+			return &ValidationError{Field: "url", Message: "URL must contain a host"}
+		}
 	}
 
 	// Validate length (reasonable maximum)
@@ -56,18 +114,181 @@ func ValidateURL(rawURL string) error {
 	return nil
 }
 
+// validOpaque checks the part after "scheme:" of a URL without a host
+// Schemes other than mailto and tel only need it to be free of whitespace
+func validOpaque(scheme, opaque string) bool {
+	if strings.ContainsAny(opaque, " \t\r\n") {
+		return false
+	}
+	
+	switch scheme {
+	case "mailto":
+		for _, address := range strings.Split(opaque, ",") {
+			if decoded, err := url.PathUnescape(address); err != nil || !mailboxRegex.MatchString(decoded) {
+				return false
+			}
+		}
+	case "tel":
+		number, _, _ := strings.Cut(opaque, ";") // Parameters such as ;ext=123
+		return phoneRegex.MatchString(number)
+	}
+	return true
+}
+
+// IsWebURL reports whether rawURL is an http or https URL, the only schemes browsers follow in a Location header
+func IsWebURL(rawURL string) bool {
+	scheme, _, ok := strings.Cut(rawURL, ":")
+	if !ok {
+		return false
+	}
+	scheme = strings.ToLower(scheme)
+	return scheme == "http" || scheme == "https"
+}
+
+// blockedDeepLinkSchemes are never accepted as deep links: they run code, read local files or, for http, never
+// open an app. Universal links and Android app links are https.
+var blockedDeepLinkSchemes = map[string]bool{
+	"http":       true,
+	"javascript": true,
+	"data":       true,
+	"vbscript":   true,
+	"file":       true,
+	"blob":       true,
+	"intent":     true, // Built by the redirect page itself from the scheme
+}
+
+// ValidateDeepLink checks a link into a mobile app: an https URL or a custom scheme such as myapp://
+// Custom schemes are only allowed here, ValidateURL keeps applying the configured schemes to destinations
+func ValidateDeepLink(rawURL string) error {
+	if len(rawURL) > 2048 {
+		return &ValidationError{Field: "deep_link", Message: "Deep link too long (max 2048 characters)"}
+	}
+	if strings.ContainsAny(rawURL, " \t\r\n") {
+		return &ValidationError{Field: "deep_link", Message: "Deep link must not contain whitespace"}
+	}
+
+	scheme, rest, ok := strings.Cut(rawURL, ":")
+	if !ok || !isScheme(scheme) || strings.Trim(rest, "/") == "" {
+		return &ValidationError{Field: "deep_link", Message: "Deep link must be an https URL or scheme://path"}
+	}
+	scheme = strings.ToLower(scheme)
+	if blockedDeepLinkSchemes[scheme] {
+		return &ValidationError{Field: "deep_link", Message: "Unsupported deep link scheme"}
+	}
+
+	if scheme == "https" {
+		return New([]string{"https"}).ValidateURL(rawURL)
+	}
+	if _, err := url.Parse(rawURL); err != nil {
+		return &ValidationError{Field: "deep_link", Message: "Invalid deep link structure"}
+	}
+	return nil
+}
+
+// DestinationHost returns the lowercased host of rawURL without userinfo, port or IPv6 brackets
+// URLs without a host, such as mailto:, return an empty string
+func DestinationHost(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	host := strings.ToLower(parsed.Hostname())
+	if len(host) > 255 {
+		host = host[:255] // Cut like the backfill does; real host names are at most 253 characters
+	}
+	return host
+}
+
+// ParseHostPattern reads a host to look links up by, such as "spam.example" or "*.spam.example"
+// The result is lowercased and in punycode like DestinationHost, and a port is dropped since stored hosts
+// have none. subdomains is set by the "*." prefix, which matches the host itself and everything below it.
+func ParseHostPattern(raw string) (host string, subdomains bool, err error) {
+	invalid := &ValidationError{Field: "host", Message: "must be a bare host such as spam.example or *.spam.example"}
+	host = strings.ToLower(strings.TrimSpace(raw))
+	host, subdomains = strings.CutPrefix(host, "*.")
+	if strings.ContainsAny(host, "/@?#*% \t") {
+		return "", false, invalid
+	}
+
+	if h, _, splitErr := net.SplitHostPort(host); splitErr == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(strings.Trim(host, "[]"), ".")
+	if host == "" {
+		return "", false, invalid
+	}
+	if !strings.Contains(host, ":") { // IPv6 literals have no IDN form
+		if ascii, err := idna.Lookup.ToASCII(host); err == nil {
+			host = ascii
+		}
+	}
+	return host, subdomains, nil
+}
+
+// MaxShortCodeSegmentLength caps a single-segment code, and each segment of a multi-segment one
+const MaxShortCodeSegmentLength = 50
+
 // ValidateShortCode checks if a short code has valid format
 func ValidateShortCode(code string) bool {
-	if len(code) < 2 || len(code) > 50 {
+	if len(code) < 2 || len(code) > MaxShortCodeSegmentLength {
 		return false
 	}
 	return shortCodeRegex.MatchString(code)
 }
 
+// MaxShortCodePathLength caps a multi-segment code such as "promo/summer-2024", slashes included
+// It is the size of the short_code columns, so MAX_CUSTOM_ALIAS_LENGTH can't go past it
+const MaxShortCodePathLength = 64
+
+// ValidateShortCodePath checks a short code of one or more "/"-separated segments
+// Every segment has to pass ValidateShortCode, so single-segment codes are checked exactly as before
+func ValidateShortCodePath(code string) bool {
+	if len(code) > MaxShortCodePathLength {
+		return false
+	}
+	for _, segment := range strings.Split(code, "/") {
+		if !ValidateShortCode(segment) {
+			return false
+		}
+	}
+	return true
+}
+
+// IsReservedAlias reports whether a custom alias collides with a route of the service
+// Comparison ignores case so "API" and "Health" are rejected too. A multi-segment alias is reserved when its
+// first segment is, since the router serves everything below /api, /health and the like, or when it ends in
+// "continue" or "stats", the interstitial hop and the public stats page of the code before it.
+func IsReservedAlias(code string) bool {
+	code = strings.ToLower(code)
+	first, _, nested := strings.Cut(code, "/")
+	return reservedAliases[first] || (nested && (strings.HasSuffix(code, "/continue") || strings.HasSuffix(code, "/stats")))
+}
+
+// NormalizeOptions selects the optional steps of NormalizeURLWith
+type NormalizeOptions struct {
+	Legacy      bool     // Only lowercase scheme and host and trim the trailing slash, as releases before v2 did
+	StripParams []string // Query parameters to drop; a trailing * matches a prefix, e.g. "utm_*"
+}
+
+// defaultPorts are dropped from hosts since they don't change where the URL points
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+	"ftp":   "21",
+}
+
 // NormalizeURL standardizes URL format
 func NormalizeURL(rawURL string) string {
+	return NormalizeURLWith(rawURL, NormalizeOptions{})
+}
+
+// NormalizeURLWith standardizes a URL so equivalent spellings are stored, and deduplicated, the same way:
+// lowercase scheme, punycode host without default port, unreserved characters decoded, other
+// escapes in upper case, StripParams removed, the remaining query parameters sorted by name and the
+// trailing slash trimmed. The sort is stable, so the values of a repeated parameter keep their order
+func NormalizeURLWith(rawURL string, opts NormalizeOptions) string {
 	// Ensure scheme
-	if !strings.Contains(rawURL, "://") {
+	if !hasScheme(rawURL) {
 		rawURL = "https://" + rawURL
 	}
 
@@ -77,15 +298,223 @@ func NormalizeURL(rawURL string) string {
 	}
 
 	// Force lowercase scheme and host
+	// URLs without a host, such as mailto:, have nothing else to normalize
 	parsed.Scheme = strings.ToLower(parsed.Scheme)
-	parsed.Host = strings.ToLower(parsed.Host)
+	if parsed.Opaque != "" {
+		return parsed.String()
+	}
+	if opts.Legacy {
+		parsed.Host = strings.ToLower(parsed.Host)
+		parsed.Path = strings.TrimSuffix(parsed.Path, "/")
+		return parsed.String()
+	}
+
+	parsed.Host = normalizeHost(parsed.Scheme, parsed.Hostname(), parsed.Port())
 
-	// Remove trailing slash from path
-	parsed.Path = strings.TrimSuffix(parsed.Path, "/")
+	// Remove trailing slash from path, then settle on one spelling of its escapes
+	escaped := strings.TrimSuffix(normalizeEscapes(parsed.EscapedPath()), "/")
+	if path, err := url.PathUnescape(escaped); err == nil {
+		parsed.Path = path
+		parsed.RawPath = escaped
+	}
+
+	parsed.RawQuery = sortParams(normalizeEscapes(stripParams(parsed.RawQuery, opts.StripParams)))
+	if parsed.RawQuery == "" {
+		parsed.ForceQuery = false
+	}
 
 	return parsed.String()
 }
 
+// hasScheme reports whether rawURL starts with a scheme, as "https://" or "mailto:" do
+// "localhost:8080/path" has none: a colon followed by a port belongs to the host
+func hasScheme(rawURL string) bool {
+	scheme, rest, ok := strings.Cut(rawURL, ":")
+	if !ok || !isScheme(scheme) {
+		return false
+	}
+	if strings.HasPrefix(rest, "//") || opaqueSchemes[strings.ToLower(scheme)] {
+		return true
+	}
+	
+	// Host names have dots far more often than schemes do, e.g. "example.com:8080"
+	if strings.Contains(scheme, ".") {
+		return false
+	}
+	port := rest
+	if end := strings.IndexAny(port, "/?#"); end >= 0 {
+		port = port[:end]
+	}
+	return !isPort(port)
+}
+
+// isScheme reports whether s is an RFC 3986 scheme
+func isScheme(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z':
+		case i > 0 && ('0' <= c && c <= '9' || c == '+' || c == '-' || c == '.'):
+		default:
+			return false
+		}
+	}
+	return s != ""
+}
+
+// isPort reports whether s is a TCP port number
+func isPort(s string) bool {
+	port, err := strconv.Atoi(s)
+	return err == nil && len(s) <= 5 && port > 0 && port <= 65535
+}
+
+// DisplayURL returns rawURL with a punycode host converted back to Unicode for showing to people
+// The stored, normalized form keeps the punycode so both spellings deduplicate together
+func DisplayURL(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || !strings.Contains(parsed.Hostname(), "xn--") {
+		return rawURL
+	}
+
+	host, err := idna.Display.ToUnicode(parsed.Hostname())
+	if err != nil {
+		return rawURL
+	}
+	if port := parsed.Port(); port != "" {
+		host = net.JoinHostPort(host, port)
+	}
+
+	// url.URL.String would percent-encode the Unicode host, so swap it into the original text
+	scheme, rest, _ := strings.Cut(rawURL, "://")
+	userinfo := ""
+	if parsed.User != nil {
+		at := strings.Index(rest, "@") + 1
+		userinfo, rest = rest[:at], rest[at:]
+	}
+	return scheme + "://" + userinfo + strings.Replace(rest, parsed.Host, host, 1)
+}
+
+// normalizeHost lowercases the host, converts IDN labels to punycode and drops the scheme's default port
+func normalizeHost(scheme, hostname, port string) string {
+	host := strings.ToLower(hostname)
+	if !strings.Contains(host, ":") { // IPv6 literals have no IDN form
+		if ascii, err := idna.Lookup.ToASCII(host); err == nil {
+			host = ascii
+		}
+	}
+
+	if port == defaultPorts[scheme] {
+		port = ""
+	}
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	if port != "" {
+		host += ":" + port
+	}
+	return host
+}
+
+// normalizeEscapes decodes percent-escapes of unreserved characters and upper-cases the hex of the rest
+// Reserved characters stay escaped, as decoding them could change how the URL is interpreted
+func normalizeEscapes(s string) string {
+	if !strings.Contains(s, "%") {
+		return s
+	}
+
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' || i+2 >= len(s) || !isHex(s[i+1]) || !isHex(s[i+2]) {
+			b.WriteByte(s[i])
+			continue
+		}
+
+		c := unhex(s[i+1])<<4 | unhex(s[i+2])
+		if isUnreserved(c) {
+			b.WriteByte(c)
+		} else {
+			b.WriteByte('%')
+			b.WriteString(strings.ToUpper(s[i+1 : i+3]))
+		}
+		i += 2
+	}
+	return b.String()
+}
+
+// stripParams removes query parameters whose name matches one of patterns
+func stripParams(rawQuery string, patterns []string) string {
+	if rawQuery == "" || len(patterns) == 0 {
+		return rawQuery
+	}
+
+	pairs := strings.Split(rawQuery, "&")
+	kept := pairs[:0]
+	for _, pair := range pairs {
+		name, _, _ := strings.Cut(pair, "=")
+		if decoded, err := url.QueryUnescape(name); err == nil {
+			name = decoded
+		}
+		if pair != "" && !matchesParam(strings.ToLower(name), patterns) {
+			kept = append(kept, pair)
+		}
+	}
+	return strings.Join(kept, "&")
+}
+
+// sortParams orders the pairs of rawQuery by name, keeping repeated names in their order
+// Pairs are compared as written, so it runs after the escapes have a single spelling
+func sortParams(rawQuery string) string {
+	if rawQuery == "" {
+		return rawQuery
+	}
+
+	pairs := strings.Split(rawQuery, "&")
+	sort.SliceStable(pairs, func(i, j int) bool {
+		left, _, _ := strings.Cut(pairs[i], "=")
+		right, _, _ := strings.Cut(pairs[j], "=")
+		return left < right
+	})
+	return strings.Join(pairs, "&")
+}
+
+// matchesParam reports whether name matches a pattern, where a trailing * matches any suffix
+func matchesParam(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		} else if name == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// isUnreserved reports whether c is an RFC 3986 unreserved character
+func isUnreserved(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+		c == '-' || c == '.' || c == '_' || c == '~'
+}
+
+// isHex reports whether c is a hexadecimal digit
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+// unhex returns the value of a hexadecimal digit
+func unhex(c byte) byte {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0'
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10
+	default:
+		return c - 'A' + 10
+	}
+}
+
 // IsSafeURL checks if URL points to potentially dangerous protocols
 func IsSafeURL(rawURL string) bool {
 	parsed, err := url.Parse(rawURL)
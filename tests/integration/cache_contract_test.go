@@ -0,0 +1,36 @@
+package integration_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/cache"
+	"url-shortener/internal/cache/cachetest"
+)
+
+// TestRedisCacheContract runs the shared cache contract against Redis
+// TEST_REDIS_ADDR must point at a Redis the test may write to; each check gets its own namespace, flushed afterwards
+func TestRedisCacheContract(t *testing.T) {
+	addr := os.Getenv("TEST_REDIS_ADDR")
+	if addr == "" {
+		t.Skip("TEST_REDIS_ADDR is not set")
+	}
+
+	cachetest.RunCache(t, func(t *testing.T) cache.Cache {
+		namespace := fmt.Sprintf("cachetest-%d", time.Now().UnixNano())
+		c, err := cache.NewRedisCache(addr, os.Getenv("TEST_REDIS_PASSWORD"), 0, namespace)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			if flusher, ok := c.(cache.Flusher); ok {
+				flusher.FlushNamespace(context.Background(), 0)
+			}
+			c.Close()
+		})
+		return c
+	})
+}
@@ -0,0 +1,114 @@
+package integration_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/cache"
+	"url-shortener/internal/cache/cachetest"
+)
+
+// redisModes lists the deployments to test against, each enabled by its own variables:
+// TEST_REDIS_ADDR, TEST_REDIS_SENTINEL_ADDRS with TEST_REDIS_MASTER_NAME, and TEST_REDIS_CLUSTER_ADDRS
+func redisModes() map[string]cache.RedisOptions {
+	password := os.Getenv("TEST_REDIS_PASSWORD")
+	modes := map[string]cache.RedisOptions{}
+	if addr := os.Getenv("TEST_REDIS_ADDR"); addr != "" {
+		modes[cache.RedisModeSingle] = cache.RedisOptions{Mode: cache.RedisModeSingle, Addr: addr, Password: password}
+	}
+	if addrs := os.Getenv("TEST_REDIS_SENTINEL_ADDRS"); addrs != "" {
+		modes[cache.RedisModeSentinel] = cache.RedisOptions{
+			Mode:          cache.RedisModeSentinel,
+			SentinelAddrs: strings.Split(addrs, ","),
+			MasterName:    os.Getenv("TEST_REDIS_MASTER_NAME"),
+			Password:      password,
+		}
+	}
+	if addrs := os.Getenv("TEST_REDIS_CLUSTER_ADDRS"); addrs != "" {
+		modes[cache.RedisModeCluster] = cache.RedisOptions{Mode: cache.RedisModeCluster, ClusterAddrs: strings.Split(addrs, ","), Password: password}
+	}
+	return modes
+}
+
+// newModeCache connects with opts under a fresh namespace, flushed when t ends
+func newModeCache(t *testing.T, opts cache.RedisOptions) cache.Cache {
+	namespace := fmt.Sprintf("modetest-%d", time.Now().UnixNano())
+	c, err := cache.NewRedisCacheWith(opts, namespace)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		c.(cache.Flusher).FlushNamespace(context.Background(), 0)
+		c.Close()
+	})
+	return c
+}
+
+func TestRedisModes(t *testing.T) {
+	modes := redisModes()
+	if len(modes) == 0 {
+		t.Skip("none of TEST_REDIS_ADDR, TEST_REDIS_SENTINEL_ADDRS or TEST_REDIS_CLUSTER_ADDRS is set")
+	}
+
+	for mode, opts := range modes {
+		opts := opts
+		t.Run(mode, func(t *testing.T) {
+			t.Run("Contract", func(t *testing.T) {
+				cachetest.RunCache(t, func(t *testing.T) cache.Cache { return newModeCache(t, opts) })
+			})
+
+			// Enough keys to land in many cluster slots
+			t.Run("BatchAndFlush", func(t *testing.T) {
+				ctx := context.Background()
+				c := newModeCache(t, opts)
+				keys := make([]string, 50)
+				for i := range keys {
+					keys[i] = fmt.Sprintf("batch%02d", i)
+					require.NoError(t, c.Set(ctx, keys[i], "value", time.Minute))
+				}
+
+				require.NoError(t, c.(cache.BatchDeleter).DeleteMultiple(ctx, keys[:25]))
+				for i, key := range keys {
+					exists, err := c.Exists(ctx, key)
+					require.NoError(t, err)
+					assert.Equal(t, i >= 25, exists, key)
+				}
+
+				deleted, err := c.(cache.Flusher).FlushNamespace(ctx, 0)
+				require.NoError(t, err)
+				assert.Equal(t, int64(25), deleted)
+			})
+
+			t.Run("CountVisit", func(t *testing.T) {
+				ctx := context.Background()
+				c := newModeCache(t, opts)
+				resolver := c.(cache.Resolver)
+				now := time.Now()
+				counters := cache.VisitCounters{
+					VisitsKey:   cache.VisitsKey("mode01", now),
+					VisitorsKey: cache.VisitorsKey("mode01", now),
+					Visitor:     cache.VisitorID("192.0.2.1", "Mozilla/5.0"),
+					ExpireAt:    now.Add(time.Hour),
+				}
+
+				require.NoError(t, resolver.CountVisit(ctx, counters))
+				visits, visitors, err := resolver.VisitCounts(ctx, counters)
+				require.NoError(t, err)
+				assert.Equal(t, int64(1), visits)
+				assert.Equal(t, int64(1), visitors)
+
+				_, err = resolver.ResolveAndCount(ctx, cache.ResolveRequest{Key: cache.LinkKey("mode01"), InactiveKey: cache.InactiveKey("mode01"), Counters: counters})
+				if mode == cache.RedisModeCluster {
+					assert.ErrorIs(t, err, cache.ErrResolveUnsupported, "the script's keys span slots")
+				} else {
+					assert.NoError(t, err)
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package integration_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/cache"
+	"url-shortener/internal/domain"
+)
+
+// newTestRedis connects to TEST_REDIS_ADDR under a fresh namespace, flushed when tb ends
+func newTestRedis(tb testing.TB) cache.Cache {
+	addr := os.Getenv("TEST_REDIS_ADDR")
+	if addr == "" {
+		tb.Skip("TEST_REDIS_ADDR is not set")
+	}
+
+	namespace := fmt.Sprintf("resolvetest-%d", time.Now().UnixNano())
+	c, err := cache.NewRedisCache(addr, os.Getenv("TEST_REDIS_PASSWORD"), 0, namespace)
+	require.NoError(tb, err)
+	tb.Cleanup(func() {
+		c.(cache.Flusher).FlushNamespace(context.Background(), 0)
+		c.Close()
+	})
+	return c
+}
+
+// resolveRequest is the request a redirect of code by visitor sends
+func resolveRequest(code, visitor string) cache.ResolveRequest {
+	now := time.Now()
+	return cache.ResolveRequest{
+		Key:         cache.LinkKey(code),
+		InactiveKey: cache.InactiveKey(code),
+		Counters: cache.VisitCounters{
+			VisitsKey:   cache.VisitsKey(code, now),
+			VisitorsKey: cache.VisitorsKey(code, now),
+			Visitor:     visitor,
+			ExpireAt:    now.Add(time.Hour),
+		},
+	}
+}
+
+// TestRedisResolveAndCount checks the resolve script against a real Redis
+func TestRedisResolveAndCount(t *testing.T) {
+	c := newTestRedis(t)
+	resolver := c.(cache.Resolver)
+	ctx := context.Background()
+
+	t.Run("MissReadsNegativeCache", func(t *testing.T) {
+		res, err := resolver.ResolveAndCount(ctx, resolveRequest("miss01", "a"))
+		require.NoError(t, err)
+		assert.Equal(t, cache.Resolution{}, res)
+
+		require.NoError(t, c.Set(ctx, cache.InactiveKey("miss01"), "1", time.Minute))
+		res, err = resolver.ResolveAndCount(ctx, resolveRequest("miss01", "a"))
+		require.NoError(t, err)
+		assert.True(t, res.Inactive)
+		assert.False(t, res.Counted())
+
+		visits, _, err := resolver.VisitCounts(ctx, resolveRequest("miss01", "a").Counters)
+		require.NoError(t, err)
+		assert.Zero(t, visits, "misses are not counted")
+	})
+
+	t.Run("HitCountsVisit", func(t *testing.T) {
+		require.NoError(t, c.Set(ctx, cache.LinkKey("hit001"), "https://example.com", time.Minute))
+
+		for i, visitor := range []string{"a", "a", "b"} {
+			res, err := resolver.ResolveAndCount(ctx, resolveRequest("hit001", visitor))
+			require.NoError(t, err)
+			assert.Equal(t, "https://example.com", res.Value)
+			assert.Equal(t, int64(i+1), res.Visits)
+		}
+
+		visits, visitors, err := resolver.VisitCounts(ctx, resolveRequest("hit001", "").Counters)
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), visits)
+		assert.Equal(t, int64(2), visitors)
+	})
+
+	t.Run("InactiveEntryNotCounted", func(t *testing.T) {
+		entry := cache.NewLinkEntry(&domain.URL{OriginalURL: "https://example.com", IsActive: false}).Encode()
+		require.NoError(t, c.Set(ctx, cache.LinkKey("off001"), entry, time.Minute))
+
+		res, err := resolver.ResolveAndCount(ctx, resolveRequest("off001", "a"))
+		require.NoError(t, err)
+		assert.Equal(t, entry, res.Value)
+		assert.False(t, res.Counted())
+	})
+
+	t.Run("CountVisit", func(t *testing.T) {
+		counters := resolveRequest("db0001", "a").Counters
+		require.NoError(t, resolver.CountVisit(ctx, counters))
+		require.NoError(t, resolver.CountVisit(ctx, counters))
+
+		visits, visitors, err := resolver.VisitCounts(ctx, counters)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), visits)
+		assert.Equal(t, int64(1), visitors)
+	})
+}
+
+// BenchmarkRedisRedirectHit compares a cache hit done with the granular calls to one done with ResolveAndCount
+//
+//	TEST_REDIS_ADDR=localhost:6379 go test ./tests/integration -run '^$' -bench RedisRedirectHit
+func BenchmarkRedisRedirectHit(b *testing.B) {
+	c := newTestRedis(b)
+	ctx := context.Background()
+	require.NoError(b, c.Set(ctx, cache.LinkKey("bench1"), "https://example.com", time.Hour))
+	req := resolveRequest("bench1", "visitor")
+
+	b.Run("Granular", func(b *testing.B) {
+		resolver := c.(cache.Resolver)
+		for i := 0; i < b.N; i++ {
+			if _, err := c.Get(ctx, req.Key); err != nil {
+				b.Fatal(err)
+			}
+			if err := resolver.CountVisit(ctx, req.Counters); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("ResolveAndCount", func(b *testing.B) {
+		resolver := c.(cache.Resolver)
+		for i := 0; i < b.N; i++ {
+			if _, err := resolver.ResolveAndCount(ctx, req); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,119 @@
+package integration_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/migrations"
+	"url-shortener/internal/repository"
+	postgresRepo "url-shortener/internal/repository/postgres"
+)
+
+// linkRecordTables are the tables holding rows keyed by a link's short code besides urls
+var linkRecordTables = []string{"click_events", "url_stats_daily", "audit_logs", "events"}
+
+// seedLinkRecords gives a link a row in every table that HardDelete has to clear
+func seedLinkRecords(t *testing.T, db *gorm.DB, shortCode string) {
+	t.Helper()
+	now := time.Now().UTC()
+	require.NoError(t, db.Create(&domain.ClickEvent{ShortCode: shortCode, Target: "default", ClickedAt: now, IP: "198.51.100.4"}).Error)
+	require.NoError(t, db.Create(&domain.DailyClickStats{ShortCode: shortCode, Date: now.Format("2006-01-02"), Clicks: 1, UniqueIPs: 1}).Error)
+	require.NoError(t, db.Create(&domain.AuditEntry{Action: domain.AuditActionDeactivate, ShortCode: shortCode, ActorID: "admin:test"}).Error)
+	require.NoError(t, db.Create(&domain.OutboxEvent{Type: domain.EventLinkClicked, ShortCode: shortCode, Payload: "{}"}).Error)
+}
+
+// countLinkRecords returns the rows left for shortCode in each of linkRecordTables
+func countLinkRecords(t *testing.T, db *gorm.DB, shortCode string) map[string]int64 {
+	t.Helper()
+	counts := make(map[string]int64)
+	for _, table := range linkRecordTables {
+		var n int64
+		require.NoError(t, db.Table(table).Where("short_code = ?", shortCode).Count(&n).Error)
+		counts[table] = n
+	}
+	return counts
+}
+
+// openErasureRepository migrates an empty database and returns it with a repository on top
+func openErasureRepository(t *testing.T) (*gorm.DB, repository.URLRepository) {
+	db, sqlDB := openEmptyDatabase(t)
+	migrator, err := migrations.New(sqlDB)
+	require.NoError(t, err)
+	_, err = migrator.Up(context.Background())
+	require.NoError(t, err)
+	return db, postgresRepo.NewURLRepository(db)
+}
+
+// createWithRecords stores a link and seeds its records
+func createWithRecords(t *testing.T, db *gorm.DB, repo repository.URLRepository, url *domain.URL) {
+	t.Helper()
+	require.NoError(t, repo.Create(context.Background(), url))
+	seedLinkRecords(t, db, url.ShortCode)
+}
+
+// TestHardDelete_LeavesNoOrphans erases one link and checks nothing recorded about it survives
+func TestHardDelete_LeavesNoOrphans(t *testing.T) {
+	db, repo := openErasureRepository(t)
+	ctx := context.Background()
+	createWithRecords(t, db, repo, &domain.URL{ShortCode: "erase1", OriginalURL: "https://example.com/private", CreatorIP: "203.0.113.9", IsActive: true})
+	createWithRecords(t, db, repo, &domain.URL{ShortCode: "keep01", OriginalURL: "https://example.com/public", CreatorIP: "203.0.113.9", IsActive: true})
+
+	require.NoError(t, repo.HardDelete(ctx, "erase1"))
+
+	var rows int64
+	require.NoError(t, db.Model(&domain.URL{}).Where("short_code = ?", "erase1").Count(&rows).Error)
+	assert.Zero(t, rows)
+	for table, n := range countLinkRecords(t, db, "erase1") {
+		assert.Zero(t, n, "orphaned rows in %s", table)
+	}
+	for table, n := range countLinkRecords(t, db, "keep01") {
+		assert.Equal(t, int64(1), n, "another link's rows in %s are kept", table)
+	}
+
+	assert.ErrorIs(t, repo.HardDelete(ctx, "erase1"), domain.ErrURLNotFound)
+}
+
+// TestHardDeleteInactive_LeavesNoOrphans runs the retention purge over deleted and active links
+func TestHardDeleteInactive_LeavesNoOrphans(t *testing.T) {
+	db, repo := openErasureRepository(t)
+	ctx := context.Background()
+	createWithRecords(t, db, repo, &domain.URL{ShortCode: "old001", OriginalURL: "https://example.com/a", IsActive: true})
+	createWithRecords(t, db, repo, &domain.URL{ShortCode: "live01", OriginalURL: "https://example.com/b", IsActive: true})
+	require.NoError(t, repo.Delete(ctx, "old001"))
+
+	erased, err := repo.HardDeleteInactive(ctx, time.Now().Add(time.Minute), 100)
+	require.NoError(t, err)
+	require.Len(t, erased, 1)
+	assert.Equal(t, "old001", erased[0].ShortCode)
+
+	for table, n := range countLinkRecords(t, db, "old001") {
+		assert.Zero(t, n, "orphaned rows in %s", table)
+	}
+	for table, n := range countLinkRecords(t, db, "live01") {
+		assert.Equal(t, int64(1), n, "active link's rows in %s are kept", table)
+	}
+}
+
+// TestResetStats_ClearsOnlyClickHistory resets one link and checks its click events and rollups are gone
+// while its audit trail and events, and the other link's records, are kept
+func TestResetStats_ClearsOnlyClickHistory(t *testing.T) {
+	db, repo := openErasureRepository(t)
+	ctx := context.Background()
+	createWithRecords(t, db, repo, &domain.URL{ShortCode: "reset1", OriginalURL: "https://example.com/a", IsActive: true})
+	createWithRecords(t, db, repo, &domain.URL{ShortCode: "keep01", OriginalURL: "https://example.com/b", IsActive: true})
+
+	_, err := repo.ResetStats(ctx, "reset1")
+	require.NoError(t, err)
+
+	wantLeft := map[string]int64{"click_events": 0, "url_stats_daily": 0, "audit_logs": 1, "events": 1}
+	assert.Equal(t, wantLeft, countLinkRecords(t, db, "reset1"))
+	for table, n := range countLinkRecords(t, db, "keep01") {
+		assert.Equal(t, int64(1), n, "another link's rows in %s are kept", table)
+	}
+}
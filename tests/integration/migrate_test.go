@@ -0,0 +1,179 @@
+package integration_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	gormLogger "gorm.io/gorm/logger"
+
+	"url-shortener/internal/migrations"
+	postgresRepo "url-shortener/internal/repository/postgres"
+)
+
+// openEmptyDatabase connects to TEST_DATABASE_DSN and drops everything in it
+// TEST_DATABASE_DSN must point at a throwaway database
+func openEmptyDatabase(t *testing.T) (*gorm.DB, *sql.DB) {
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN is not set")
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: gormLogger.Default.LogMode(gormLogger.Silent)})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	_, err = sqlDB.Exec("DROP SCHEMA public CASCADE; CREATE SCHEMA public")
+	require.NoError(t, err)
+	return db, sqlDB
+}
+
+func TestMigrator_UpDownStatus(t *testing.T) {
+	_, sqlDB := openEmptyDatabase(t)
+	ctx := context.Background()
+	migrator, err := migrations.New(sqlDB)
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, migrator.Check(ctx), migrations.ErrPending)
+
+	applied, err := migrator.Up(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1, 2, 3, 4, 5, 6, 7}, applied)
+	require.NoError(t, migrator.Check(ctx))
+
+	applied, err = migrator.Up(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, applied, "a second run has nothing to do")
+
+	reverted, err := migrator.Down(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), reverted)
+
+	status, err := migrator.Status(ctx)
+	require.NoError(t, err)
+	require.Len(t, status, 7)
+	assert.NotNil(t, status[5].AppliedAt)
+	assert.Nil(t, status[6].AppliedAt)
+
+	var tenantColumn bool
+	require.NoError(t, sqlDB.QueryRow(`SELECT EXISTS (SELECT 1 FROM information_schema.columns
+		WHERE table_name = 'urls' AND column_name = 'tenant_id')`).Scan(&tenantColumn))
+	assert.False(t, tenantColumn, "down drops the column")
+
+	reverted, err = migrator.Down(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(6), reverted)
+
+	var brokenColumn bool
+	require.NoError(t, sqlDB.QueryRow(`SELECT EXISTS (SELECT 1 FROM information_schema.columns
+		WHERE table_name = 'urls' AND column_name = 'is_broken')`).Scan(&brokenColumn))
+	assert.False(t, brokenColumn, "down drops the column")
+
+	reverted, err = migrator.Down(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), reverted)
+
+	var filteredColumn bool
+	require.NoError(t, sqlDB.QueryRow(`SELECT EXISTS (SELECT 1 FROM information_schema.columns
+		WHERE table_name = 'urls' AND column_name = 'filtered_clicks')`).Scan(&filteredColumn))
+	assert.False(t, filteredColumn, "down drops the column")
+
+	reverted, err = migrator.Down(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), reverted)
+
+	var lowerIndex bool
+	require.NoError(t, sqlDB.QueryRow("SELECT to_regclass('idx_urls_short_code_lower') IS NOT NULL").Scan(&lowerIndex))
+	assert.False(t, lowerIndex, "down drops the index")
+
+	reverted, err = migrator.Down(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), reverted)
+
+	var confirmColumn bool
+	require.NoError(t, sqlDB.QueryRow(`SELECT EXISTS (SELECT 1 FROM information_schema.columns
+		WHERE table_name = 'urls' AND column_name = 'confirm_before_redirect')`).Scan(&confirmColumn))
+	assert.False(t, confirmColumn, "down drops the column")
+
+	reverted, err = migrator.Down(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), reverted)
+
+	var hashColumn bool
+	require.NoError(t, sqlDB.QueryRow(`SELECT EXISTS (SELECT 1 FROM information_schema.columns
+		WHERE table_name = 'urls' AND column_name = 'url_hash')`).Scan(&hashColumn))
+	assert.False(t, hashColumn, "down drops the column")
+
+	_, err = migrator.Down(ctx)
+	require.NoError(t, err)
+	_, err = migrator.Down(ctx)
+	assert.ErrorIs(t, err, migrations.ErrNothingToRollBack)
+
+	var urlsTable bool
+	require.NoError(t, sqlDB.QueryRow("SELECT to_regclass('urls') IS NOT NULL").Scan(&urlsTable))
+	assert.False(t, urlsTable)
+}
+
+// TestMigrator_BackfillsURLHash applies 0002 on top of existing links and finds them by destination afterwards
+func TestMigrator_BackfillsURLHash(t *testing.T) {
+	db, sqlDB := openEmptyDatabase(t)
+	ctx := context.Background()
+	all, err := migrations.Embedded()
+	require.NoError(t, err)
+
+	_, err = migrations.NewWithMigrations(sqlDB, all[:1]).Up(ctx)
+	require.NoError(t, err)
+	_, err = sqlDB.Exec("INSERT INTO urls (short_code, original_url) VALUES ('legacy', 'https://example.com/ünïcode')")
+	require.NoError(t, err)
+
+	applied, err := migrations.NewWithMigrations(sqlDB, all[:2]).Up(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{2}, applied)
+
+	url, err := postgresRepo.NewURLRepository(db).FindByOriginalURL(ctx, "https://example.com/ünïcode")
+	require.NoError(t, err, "the backfilled digest matches the one the repository computes")
+	assert.Equal(t, "legacy", url.ShortCode)
+}
+
+// TestMigrator_ConcurrentStartups runs the startup migration from several replicas at once
+func TestMigrator_ConcurrentStartups(t *testing.T) {
+	_, sqlDB := openEmptyDatabase(t)
+	ctx := context.Background()
+
+	const replicas = 4
+	var wg sync.WaitGroup
+	applied := make([][]int64, replicas)
+	errs := make([]error, replicas)
+	for i := 0; i < replicas; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			migrator, err := migrations.New(sqlDB)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			applied[i], errs[i] = migrator.Up(ctx)
+		}(i)
+	}
+	wg.Wait()
+
+	runs := 0
+	for i := range errs {
+		require.NoError(t, errs[i])
+		if len(applied[i]) > 0 {
+			runs++
+		}
+	}
+	assert.Equal(t, 1, runs, "one replica migrates, the others find every version recorded")
+
+	var count int
+	require.NoError(t, sqlDB.QueryRow("SELECT COUNT(*) FROM schema_migrations").Scan(&count))
+	assert.Equal(t, 6, count)
+}
@@ -0,0 +1,37 @@
+package integration_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+
+	"url-shortener/internal/repository"
+	mongoRepo "url-shortener/internal/repository/mongo"
+	"url-shortener/internal/repository/repotest"
+)
+
+// TestMongoURLRepositoryContract runs the shared repository contract against MongoDB
+// TEST_MONGO_URI must point at a throwaway deployment; the database is dropped before every check
+func TestMongoURLRepositoryContract(t *testing.T) {
+	uri := os.Getenv("TEST_MONGO_URI")
+	if uri == "" {
+		t.Skip("TEST_MONGO_URI is not set")
+	}
+
+	client, err := mongo.Connect(options.Client().ApplyURI(uri).SetServerSelectionTimeout(5 * time.Second))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Disconnect(context.Background()) })
+	db := client.Database("urlshortener_contract")
+
+	repotest.RunURLRepository(t, func(t *testing.T) repository.URLRepository {
+		ctx := context.Background()
+		require.NoError(t, db.Drop(ctx))
+		require.NoError(t, mongoRepo.EnsureIndexes(ctx, db))
+		return mongoRepo.NewURLRepository(db, nil)
+	})
+}
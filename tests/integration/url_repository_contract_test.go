@@ -0,0 +1,40 @@
+package integration_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	gormLogger "gorm.io/gorm/logger"
+
+	"url-shortener/internal/migrations"
+	"url-shortener/internal/repository"
+	postgresRepo "url-shortener/internal/repository/postgres"
+	"url-shortener/internal/repository/repotest"
+)
+
+// TestPostgresURLRepositoryContract runs the shared repository contract against Postgres
+// TEST_DATABASE_DSN must point at a throwaway database, the urls table is emptied before every check
+func TestPostgresURLRepositoryContract(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN is not set")
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: gormLogger.Default.LogMode(gormLogger.Silent)})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	migrator, err := migrations.New(sqlDB)
+	require.NoError(t, err)
+	_, err = migrator.Up(context.Background())
+	require.NoError(t, err)
+
+	repotest.RunURLRepository(t, func(t *testing.T) repository.URLRepository {
+		require.NoError(t, db.Exec("TRUNCATE urls RESTART IDENTITY").Error)
+		return postgresRepo.NewURLRepository(db)
+	})
+}
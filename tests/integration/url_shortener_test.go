@@ -2,6 +2,7 @@
 package integration_test
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -36,7 +37,7 @@ type URLShortenerIntegrationTestSuite struct {
 }
 
 func (suite *URLShortenerIntegrationTestSuite) SetupSuite() {
-	suite.logger = logger.NewLogger()
+	suite.logger = logger.NewLogger(logger.Options{})
 	
 	// Setup test configuration
 	suite.config = &config.Config{
@@ -47,6 +48,8 @@ func (suite *URLShortenerIntegrationTestSuite) SetupSuite() {
 		RateLimitPerMinute: 1000, // High limit for tests
 		CacheTTL:           time.Hour,
 		URLExpirationDays:  7,
+		AdminAPIKey:        "integration-admin",
+		NegativeCacheTTL:   time.Minute,
 	}
 	
 	// Setup test database
@@ -58,13 +61,13 @@ func (suite *URLShortenerIntegrationTestSuite) SetupSuite() {
 	suite.db = db
 	
 	// Run migrations
-	err = db.AutoMigrate(&domain.URL{})
+	err = db.AutoMigrate(&domain.URL{}, &domain.ClickEvent{}, &domain.DailyClickStats{})
 	if err != nil {
 		suite.T().Fatal("Failed to run migrations:", err)
 	}
 	
 	// Setup Redis cache (using mock or test instance)
-	suite.cache, err = cache.NewRedisCache("localhost:6379", "", 1)
+	suite.cache, err = cache.NewRedisCache("localhost:6379", "", 1, "urlshortener-test")
 	if err != nil {
 		suite.T().Log("Redis not available, continuing without cache")
 		suite.cache = nil
@@ -73,12 +76,12 @@ func (suite *URLShortenerIntegrationTestSuite) SetupSuite() {
 	// Setup application layers
 	repo := postgresRepo.NewURLRepository(db)
 	urlService := service.NewURLService(repo, suite.cache, suite.config, suite.logger)
-	urlHandler := handler.NewURLHandler(urlService, suite.logger)
+	urlHandler := handler.NewURLHandler(urlService, suite.config, suite.logger)
 	
 	// Setup router
 	suite.router = gin.New()
 	suite.router.Use(gin.Recovery())
-	suite.router.Use(handler.LoggerMiddleware(suite.logger))
+	suite.router.Use(handler.LoggerMiddleware(suite.logger, nil))
 	
 	// Register routes
 	suite.router.POST("/api/v1/shorten", urlHandler.ShortenURL)
@@ -86,6 +89,8 @@ func (suite *URLShortenerIntegrationTestSuite) SetupSuite() {
 	suite.router.GET("/api/v1/urls/:shortCode", urlHandler.GetURLInfo)
 	suite.router.GET("/api/v1/urls/:shortCode/stats", urlHandler.GetStats)
 	suite.router.DELETE("/api/v1/urls/:shortCode", urlHandler.DeleteURL)
+	suite.router.PUT("/api/v1/urls/:shortCode/deactivate", handler.AdminAuthMiddleware(suite.config), urlHandler.DeactivateURL)
+	suite.router.PUT("/api/v1/urls/:shortCode/activate", handler.AdminAuthMiddleware(suite.config), urlHandler.ActivateURL)
 	suite.router.GET("/health", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"status": "healthy"})
 	})
@@ -180,7 +185,7 @@ func (suite *URLShortenerIntegrationTestSuite) TestGetURLInfo() {
 	
 	assert.Equal(suite.T(), http.StatusOK, infoW.Code)
 	
-	var urlInfo domain.URL
+	var urlInfo domain.URLInfoResponse
 	err := json.Unmarshal(infoW.Body.Bytes(), &urlInfo)
 	assert.NoError(suite.T(), err)
 	
@@ -236,6 +241,168 @@ func (suite *URLShortenerIntegrationTestSuite) TestInvalidURL() {
 	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
 }
 
+// shorten creates a short URL through the API and returns its code and management token
+func (suite *URLShortenerIntegrationTestSuite) shorten(originalURL string) (string, string) {
+	body, _ := json.Marshal(map[string]interface{}{"url": originalURL})
+	req := httptest.NewRequest("POST", "/api/v1/shorten", strings.NewReader(string(body)))
+	req.Header.Set("Content-Type", "application/json")
+	
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	suite.Require().Equal(http.StatusCreated, w.Code)
+	
+	var resp domain.CreateURLResponse
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &resp))
+	return resp.ShortCode, resp.ManagementToken
+}
+
+func (suite *URLShortenerIntegrationTestSuite) TestDeleteThenReshortenInvalidatesCache() {
+	originalURL := "https://example.com/reshorten"
+	
+	// Shorten and resolve once so the old code is definitely cached
+	oldCode, token := suite.shorten(originalURL)
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, httptest.NewRequest("GET", "/"+oldCode, nil))
+	assert.Equal(suite.T(), http.StatusMovedPermanently, w.Code)
+	
+	w = httptest.NewRecorder()
+	req := httptest.NewRequest("DELETE", "/api/v1/urls/"+oldCode, nil)
+	req.Header.Set("X-Management-Token", token)
+	suite.router.ServeHTTP(w, req)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	
+	// Dedup skips the inactive row, so a new code is issued
+	newCode, _ := suite.shorten(originalURL)
+	assert.NotEqual(suite.T(), oldCode, newCode)
+	
+	// The old code is gone everywhere: info and redirect agree
+	w = httptest.NewRecorder()
+	suite.router.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/urls/"+oldCode, nil))
+	assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+	
+	w = httptest.NewRecorder()
+	suite.router.ServeHTTP(w, httptest.NewRequest("GET", "/"+oldCode, nil))
+	assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+	
+	// The new code redirects to the same destination
+	w = httptest.NewRecorder()
+	suite.router.ServeHTTP(w, httptest.NewRequest("GET", "/"+newCode, nil))
+	assert.Equal(suite.T(), http.StatusMovedPermanently, w.Code)
+	assert.Equal(suite.T(), originalURL, w.Header().Get("Location"))
+}
+
+func (suite *URLShortenerIntegrationTestSuite) TestDuplicateReturnsExistingWith200() {
+	code, _ := suite.shorten("https://example.com/dedup")
+	
+	body, _ := json.Marshal(map[string]interface{}{"url": "https://example.com/dedup"})
+	req := httptest.NewRequest("POST", "/api/v1/shorten", strings.NewReader(string(body)))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	
+	assert.Equal(suite.T(), http.StatusOK, w.Code, "nothing new was created")
+	var resp domain.CreateURLResponse
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(suite.T(), code, resp.ShortCode)
+	assert.True(suite.T(), resp.Deduplicated)
+	assert.Equal(suite.T(), fmt.Sprintf("<%s>; rel=\"canonical\"", resp.ShortURL), w.Header().Get("Link"))
+}
+
+func (suite *URLShortenerIntegrationTestSuite) TestRollupDayIsIdempotent() {
+	ctx := context.Background()
+	suite.db.Exec("DELETE FROM click_events")
+	suite.db.Exec("DELETE FROM url_stats_daily")
+	
+	day := time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)
+	events := []domain.ClickEvent{
+		{ShortCode: "roll01", Target: "default", ClickedAt: day.Add(time.Hour), IP: "203.0.113.1", Referrer: "news.example"},
+		{ShortCode: "roll01", Target: "default", ClickedAt: day.Add(2 * time.Hour), IP: "203.0.113.1", Referrer: "news.example"},
+		{ShortCode: "roll01", Target: "default", ClickedAt: day.Add(3 * time.Hour), IP: "203.0.113.2", Referrer: "blog.example"},
+		{ShortCode: "roll01", Target: "default", ClickedAt: day.AddDate(0, 0, 1), IP: "203.0.113.3"}, // Next day
+	}
+	suite.Require().NoError(suite.db.Create(&events).Error)
+	
+	clicks := postgresRepo.NewClickRepository(suite.db)
+	for i := 0; i < 2; i++ {
+		_, err := clicks.RollupDay(ctx, day.Add(12*time.Hour))
+		suite.Require().NoError(err)
+	}
+	
+	series, err := clicks.GetDailySeries(ctx, "roll01", day, day.AddDate(0, 0, 2))
+	suite.Require().NoError(err)
+	suite.Require().Len(series, 1, "only the rolled-up day is returned")
+	assert.Equal(suite.T(), "2024-03-10", series[0].Date)
+	assert.Equal(suite.T(), int64(3), series[0].Clicks, "re-running the rollup must not double-count")
+	assert.Equal(suite.T(), int64(2), series[0].UniqueIPs)
+	assert.Equal(suite.T(), "news.example", series[0].TopReferrer)
+	
+	partial, err := clicks.AggregateDay(ctx, "roll01", day.AddDate(0, 0, 1))
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), int64(1), partial.Clicks)
+}
+
+func (suite *URLShortenerIntegrationTestSuite) TestClickBucketsGroupByHourAndWeek() {
+	ctx := context.Background()
+	suite.db.Exec("DELETE FROM click_events")
+	suite.db.Exec("DELETE FROM url_stats_daily")
+	
+	// Sunday 2024-03-10 and the following Monday fall into different ISO weeks
+	sunday := time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)
+	events := []domain.ClickEvent{
+		{ShortCode: "tser01", Target: "default", ClickedAt: sunday.Add(9*time.Hour + 5*time.Minute)},
+		{ShortCode: "tser01", Target: "default", ClickedAt: sunday.Add(9*time.Hour + 55*time.Minute)},
+		{ShortCode: "tser01", Target: "default", ClickedAt: sunday.Add(11 * time.Hour)},
+		{ShortCode: "tser01", Target: "default", ClickedAt: sunday.AddDate(0, 0, 1)},
+	}
+	suite.Require().NoError(suite.db.Create(&events).Error)
+	
+	clicks := postgresRepo.NewClickRepository(suite.db)
+	hourly, err := clicks.CountByBucket(ctx, "tser01", domain.GranularityHour, sunday, sunday.AddDate(0, 0, 1))
+	suite.Require().NoError(err)
+	suite.Require().Len(hourly, 2, "empty hours are left to the service")
+	assert.Equal(suite.T(), sunday.Add(9*time.Hour), hourly[0].BucketStart)
+	assert.Equal(suite.T(), int64(2), hourly[0].Clicks)
+	
+	for _, day := range []time.Time{sunday, sunday.AddDate(0, 0, 1)} {
+		_, err := clicks.RollupDay(ctx, day)
+		suite.Require().NoError(err)
+	}
+	weekly, err := clicks.SumDailyByBucket(ctx, "tser01", domain.GranularityWeek, sunday.AddDate(0, 0, -6), sunday.AddDate(0, 0, 8))
+	suite.Require().NoError(err)
+	suite.Require().Len(weekly, 2)
+	assert.Equal(suite.T(), sunday.AddDate(0, 0, -6), weekly[0].BucketStart, "weeks start on Monday")
+	assert.Equal(suite.T(), int64(3), weekly[0].Clicks)
+	assert.Equal(suite.T(), int64(1), weekly[1].Clicks)
+}
+
+func (suite *URLShortenerIntegrationTestSuite) TestClicksGroupByWeekdayHourInTimeZone() {
+	ctx := context.Background()
+	suite.db.Exec("DELETE FROM click_events")
+	
+	// 23:30 UTC on Saturday 2024-03-09 is 08:30 on Sunday in Tokyo
+	saturday := time.Date(2024, 3, 9, 23, 30, 0, 0, time.UTC)
+	events := []domain.ClickEvent{
+		{ShortCode: "heat01", Target: "default", ClickedAt: saturday},
+		{ShortCode: "heat01", Target: "default", ClickedAt: saturday.Add(10 * time.Minute)},
+		{ShortCode: "heat01", Target: "default", ClickedAt: saturday.Add(2 * time.Hour)},
+	}
+	suite.Require().NoError(suite.db.Create(&events).Error)
+	
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	suite.Require().NoError(err)
+	clicks := postgresRepo.NewClickRepository(suite.db)
+	cells, err := clicks.CountByWeekdayHour(ctx, "heat01", saturday.AddDate(0, 0, -1), saturday.AddDate(0, 0, 1), tokyo)
+	suite.Require().NoError(err)
+	assert.ElementsMatch(suite.T(), []domain.HeatmapCell{
+		{Weekday: 0, Hour: 8, Clicks: 2},
+		{Weekday: 0, Hour: 10, Clicks: 1},
+	}, cells)
+	
+	cells, err = clicks.CountByWeekdayHour(ctx, "heat01", saturday.AddDate(0, 0, -1), saturday.AddDate(0, 0, 1), time.UTC)
+	suite.Require().NoError(err)
+	assert.Contains(suite.T(), cells, domain.HeatmapCell{Weekday: 6, Hour: 23, Clicks: 2})
+}
+
 func (suite *URLShortenerIntegrationTestSuite) TestHealthCheck() {
 	req := httptest.NewRequest("GET", "/health", nil)
 	w := httptest.NewRecorder()
@@ -249,4 +416,95 @@ func (suite *URLShortenerIntegrationTestSuite) TestHealthCheck() {
 	assert.NoError(suite.T(), err)
 	
 	assert.Equal(suite.T(), "healthy", healthResp["status"])
-}
\ No newline at end of file
+}
+
+// assertRedirectMatchesInfo checks the redirect answers the way the link's metadata says it should
+// Unknown and inactive links are 404 on both, expired ones 410 with the same error body
+func (suite *URLShortenerIntegrationTestSuite) assertRedirectMatchesInfo(code, state string) {
+	info := httptest.NewRecorder()
+	suite.router.ServeHTTP(info, httptest.NewRequest("GET", "/api/v1/urls/"+code, nil))
+	redirect := httptest.NewRecorder()
+	suite.router.ServeHTTP(redirect, httptest.NewRequest("GET", "/"+code, nil))
+	
+	if info.Code == http.StatusNotFound {
+		assert.Equal(suite.T(), http.StatusNotFound, redirect.Code, state)
+		assert.JSONEq(suite.T(), info.Body.String(), redirect.Body.String(), state)
+		return
+	}
+	
+	suite.Require().Equal(http.StatusOK, info.Code, state)
+	var urlInfo domain.URLInfoResponse
+	suite.Require().NoError(json.Unmarshal(info.Body.Bytes(), &urlInfo))
+	if urlInfo.IsExpired {
+		assert.Equal(suite.T(), http.StatusGone, redirect.Code, state)
+		assert.Contains(suite.T(), redirect.Body.String(), "url_expired", state)
+		return
+	}
+	assert.Equal(suite.T(), http.StatusMovedPermanently, redirect.Code, state)
+	assert.Equal(suite.T(), urlInfo.OriginalURL, redirect.Header().Get("Location"), state)
+}
+
+// setActive flips a link through the admin API
+func (suite *URLShortenerIntegrationTestSuite) setActive(code string, active bool) {
+	action := "deactivate"
+	if active {
+		action = "activate"
+	}
+	req := httptest.NewRequest("PUT", "/api/v1/urls/"+code+"/"+action, nil)
+	req.Header.Set("X-API-Key", suite.config.AdminAPIKey)
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+}
+
+func (suite *URLShortenerIntegrationTestSuite) TestRedirectMatchesInfoWithWarmCache() {
+	if suite.cache == nil {
+		suite.T().Skip("Redis not available")
+	}
+	
+	expiresAt := time.Now().Add(2 * time.Second).UTC()
+	body, _ := json.Marshal(map[string]interface{}{"url": "https://example.com/states", "expires_at": expiresAt})
+	req := httptest.NewRequest("POST", "/api/v1/shorten", strings.NewReader(string(body)))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	suite.Require().Equal(http.StatusCreated, w.Code, w.Body.String())
+	var resp domain.CreateURLResponse
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &resp))
+	code := resp.ShortCode
+	
+	// Each state is checked with the cache warm, then again once the entry is gone
+	check := func(state string) {
+		suite.assertRedirectMatchesInfo(code, state+" (warm cache)")
+		suite.Require().NoError(suite.cache.Delete(context.Background(), cache.LinkKey(code)))
+		suite.assertRedirectMatchesInfo(code, state+" (cold cache)")
+	}
+	
+	check("active")
+	
+	suite.setActive(code, false)
+	check("deactivated")
+	
+	suite.setActive(code, true)
+	check("reactivated")
+	
+	// Expiry isn't an event: the cached entry outlives neither expires_at nor its own copy of it
+	w = httptest.NewRecorder()
+	suite.router.ServeHTTP(w, httptest.NewRequest("GET", "/"+code, nil))
+	suite.Require().Equal(http.StatusMovedPermanently, w.Code)
+	time.Sleep(time.Until(expiresAt) + 100*time.Millisecond)
+	check("expired")
+}
+
+func (suite *URLShortenerIntegrationTestSuite) TestRedirectMatchesInfoAfterDeleteWithWarmCache() {
+	code, token := suite.shorten("https://example.com/deleted-state")
+	suite.assertRedirectMatchesInfo(code, "active")
+	
+	req := httptest.NewRequest("DELETE", "/api/v1/urls/"+code, nil)
+	req.Header.Set("X-Management-Token", token)
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	suite.Require().Equal(http.StatusOK, w.Code)
+	
+	suite.assertRedirectMatchesInfo(code, "deleted")
+}
@@ -0,0 +1,154 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/handler"
+	"url-shortener/internal/service"
+)
+
+// MockAuditRepository is a mock implementation of AuditRepository
+type MockAuditRepository struct {
+	mock.Mock
+}
+
+func (m *MockAuditRepository) Record(ctx context.Context, entry *domain.AuditEntry) error {
+	args := m.Called(ctx, entry)
+	return args.Error(0)
+}
+
+func (m *MockAuditRepository) ListByShortCode(ctx context.Context, shortCode string) ([]domain.AuditEntry, error) {
+	args := m.Called(ctx, shortCode)
+	return args.Get(0).([]domain.AuditEntry), args.Error(1)
+}
+
+// setupAdminRouter wires the admin routes to a service that records into audit
+func setupAdminRouter(suite *URLServiceTestSuite, audit *MockAuditRepository) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	suite.cfg.AdminAPIKey = "admin-secret"
+	suite.cfg.NegativeCacheTTL = time.Minute
+	suite.service = service.NewURLService(suite.repo, suite.cache, suite.cfg, suite.logger, service.WithAuditRepository(audit))
+	h := handler.NewURLHandler(suite.service, suite.cfg, suite.logger)
+
+	router := gin.New()
+	router.PUT("/api/v1/urls/:shortCode/deactivate", handler.AdminAuthMiddleware(suite.cfg), h.DeactivateURL)
+	router.PUT("/api/v1/urls/:shortCode/activate", handler.AdminAuthMiddleware(suite.cfg), h.ActivateURL)
+	return router
+}
+
+func adminRequest(target string) *http.Request {
+	req := httptest.NewRequest("PUT", target, nil)
+	req.Header.Set("X-API-Key", "admin-secret")
+	req.RemoteAddr = "10.0.0.7:1234"
+	return req
+}
+
+func TestDeactivateURL_PurgesCacheAndAudits(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	audit := new(MockAuditRepository)
+	router := setupAdminRouter(suite, audit)
+
+	suite.repo.On("SetActive", mock.Anything, "abc123", false).
+		Return(&domain.URL{ShortCode: "abc123", OriginalURL: "https://example.com", IsActive: false}, nil)
+	suite.cache.On("Delete", mock.Anything, "abc123").Return(nil)
+	suite.cache.On("Set", mock.Anything, "inactive:abc123", "1", suite.cfg.NegativeCacheTTL).Return(nil)
+	audit.On("Record", mock.Anything, mock.AnythingOfType("*domain.AuditEntry")).Return(nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, adminRequest("/api/v1/urls/abc123/deactivate"))
+
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var url domain.URL
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &url))
+	assert.Equal(t, "abc123", url.ShortCode)
+	assert.False(t, url.IsActive)
+
+	suite.cache.AssertExpectations(t)
+	entry := audit.Calls[0].Arguments.Get(1).(*domain.AuditEntry)
+	assert.Equal(t, domain.AuditActionDeactivate, entry.Action)
+	assert.Equal(t, "abc123", entry.ShortCode)
+	assert.Contains(t, entry.ActorID, "admin:")
+	assert.NotContains(t, entry.ActorID, "admin-secret")
+	assert.Equal(t, "10.0.0.7", entry.ActorIP)
+}
+
+func TestActivateURL_ClearsNegativeCache(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	audit := new(MockAuditRepository)
+	router := setupAdminRouter(suite, audit)
+
+	suite.repo.On("SetActive", mock.Anything, "abc123", true).
+		Return(&domain.URL{ShortCode: "abc123", OriginalURL: "https://example.com", IsActive: true}, nil)
+	suite.cache.On("Delete", mock.Anything, "inactive:abc123").Return(nil)
+	audit.On("Record", mock.Anything, mock.MatchedBy(func(e *domain.AuditEntry) bool {
+		return e.Action == domain.AuditActionActivate
+	})).Return(nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, adminRequest("/api/v1/urls/abc123/activate"))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	suite.cache.AssertExpectations(t)
+	audit.AssertExpectations(t)
+}
+
+func TestDeactivateURL_NotFound(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	audit := new(MockAuditRepository)
+	router := setupAdminRouter(suite, audit)
+
+	suite.repo.On("SetActive", mock.Anything, "missing", false).Return(nil, domain.ErrURLNotFound)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, adminRequest("/api/v1/urls/missing/deactivate"))
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	audit.AssertNotCalled(t, "Record", mock.Anything, mock.Anything)
+}
+
+func TestAdminAuthMiddleware(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	router := setupAdminRouter(suite, new(MockAuditRepository))
+
+	// Wrong key
+	req := httptest.NewRequest("PUT", "/api/v1/urls/abc123/deactivate", nil)
+	req.Header.Set("X-API-Key", "wrong")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// Admin API disabled when no key is configured
+	suite.cfg.AdminAPIKey = ""
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, adminRequest("/api/v1/urls/abc123/deactivate"))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	suite.repo.AssertNotCalled(t, "SetActive", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestGetOriginalURL_NegativeCacheHit(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	ctx := context.Background()
+
+	// Replace the default negative-cache miss with a hit
+	suite.cache.ExpectedCalls = nil
+	suite.cache.On("Get", ctx, "abc123").Return("", nil)
+	suite.cache.On("Exists", ctx, "inactive:abc123").Return(true, nil)
+
+	_, err := suite.service.GetOriginalURL(ctx, "abc123", domain.Visitor{})
+
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+	suite.repo.AssertNotCalled(t, "FindByShortCode", mock.Anything, mock.Anything)
+}
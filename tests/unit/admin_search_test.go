@@ -0,0 +1,145 @@
+package unit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/handler"
+	"url-shortener/pkg/validator"
+)
+
+func TestParseHostPattern(t *testing.T) {
+	tests := []struct {
+		raw        string
+		host       string
+		subdomains bool
+	}{
+		{"spam.example", "spam.example", false},
+		{" Spam.Example. ", "spam.example", false},
+		{"*.spam.example", "spam.example", true},
+		{"spam.example:8080", "spam.example", false},
+		{"[::1]:8080", "::1", false},
+		{"2001:db8::1", "2001:db8::1", false},
+		{"*.bücher.example", "xn--bcher-kva.example", true},
+	}
+	for _, tt := range tests {
+		host, subdomains, err := validator.ParseHostPattern(tt.raw)
+		require.NoError(t, err, tt.raw)
+		assert.Equal(t, tt.host, host, tt.raw)
+		assert.Equal(t, tt.subdomains, subdomains, tt.raw)
+	}
+
+	for _, raw := range []string{"", "*.", "https://spam.example", "spam.example/path", "user@spam.example", "*.*.spam.example", "spam example"} {
+		_, _, err := validator.ParseHostPattern(raw)
+		assert.Error(t, err, raw)
+	}
+}
+
+func TestDestinationHost(t *testing.T) {
+	assert.Equal(t, "spam.example", validator.DestinationHost("https://user:pw@Spam.Example:8443/a?b=1"))
+	assert.Equal(t, "::1", validator.DestinationHost("http://[::1]:8080/"))
+	assert.Empty(t, validator.DestinationHost("mailto:abuse@spam.example"))
+}
+
+func TestSearchByDestination_Pages(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	ctx := context.Background()
+	want := domain.URLFilter{DestinationHost: "badsite.example", IncludeSubdomains: true, PathPrefix: "/scam"}
+	links := []domain.URL{
+		{ShortCode: "spam03", OriginalURL: "https://login.badsite.example/scam/3", IsActive: true},
+		{ShortCode: "spam02", OriginalURL: "https://badsite.example/scam/2", IsActive: true},
+		{ShortCode: "spam01", OriginalURL: "https://badsite.example/scam/1", IsActive: true},
+	}
+	suite.repo.On("CountActiveMatching", ctx, want).Return(int64(3), nil)
+	suite.repo.On("FindActiveMatching", ctx, want, 3, 0).Return(links, nil)
+
+	page, err := suite.service.SearchByDestination(ctx, "*.BadSite.example", "/scam", 2, 0)
+
+	require.NoError(t, err)
+	assert.Equal(t, "badsite.example", page.Host)
+	assert.True(t, page.Subdomains)
+	assert.Equal(t, int64(3), page.Total)
+	assert.Equal(t, []string{"spam03", "spam02"}, page.ShortCodes)
+	require.Len(t, page.URLs, 2)
+	assert.Equal(t, "https://login.badsite.example/scam/3", page.URLs[0].OriginalURL)
+	require.NotNil(t, page.NextOffset)
+	assert.Equal(t, 2, *page.NextOffset)
+}
+
+func TestSearchByDestination_Validation(t *testing.T) {
+	for name, args := range map[string][2]string{
+		"no host":           {"", ""},
+		"path without host": {" ", "/scam"},
+		"url as host":       {"https://badsite.example", ""},
+		"relative path":     {"badsite.example", "scam"},
+		"path with query":   {"badsite.example", "/scam?id=1"},
+	} {
+		suite := setupURLServiceTest(t)
+
+		_, err := suite.service.SearchByDestination(context.Background(), args[0], args[1], 10, 0)
+
+		var appErr *domain.AppError
+		require.ErrorAs(t, err, &appErr, name)
+		assert.Equal(t, http.StatusBadRequest, appErr.StatusCode, name)
+		suite.repo.AssertNotCalled(t, "CountActiveMatching", mock.Anything, mock.Anything)
+	}
+}
+
+func TestSearchURLs_Handler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	suite := setupURLServiceTest(t)
+	suite.cfg.AdminAPIKey = "admin-secret"
+	h := handler.NewURLHandler(suite.service, suite.cfg, suite.logger)
+	router := gin.New()
+	router.GET("/api/v1/admin/urls/search", handler.AdminAuthMiddleware(suite.cfg), h.SearchURLs)
+
+	want := domain.URLFilter{DestinationHost: "badsite.example"}
+	suite.repo.On("CountActiveMatching", mock.Anything, want).Return(int64(0), nil)
+	suite.repo.On("FindActiveMatching", mock.Anything, want, 101, 0).Return([]domain.URL{}, nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, adminGet("/api/v1/admin/urls/search?host=badsite.example:443"))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "private, no-store", w.Header().Get("Cache-Control"))
+	assert.JSONEq(t, `{"host": "badsite.example", "subdomains": false, "total": 0, "urls": [], "short_codes": [], "limit": 100, "offset": 0}`, w.Body.String())
+
+	for _, target := range []string{"/api/v1/admin/urls/search", "/api/v1/admin/urls/search?host=badsite.example&limit=ten"} {
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, adminGet(target))
+		assert.Equal(t, http.StatusBadRequest, w.Code, target)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/admin/urls/search?host=badsite.example", nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestBulkDeactivate_SubdomainAndPathFilter(t *testing.T) {
+	suite, _, _, svc := setupBulkTest(t)
+	ctx := context.Background()
+	want := domain.URLFilter{DestinationHost: "spam.example", IncludeSubdomains: true, PathPrefix: "/login"}
+	suite.repo.On("CountActiveMatching", ctx, want).Return(int64(7), nil)
+
+	resp, err := svc.BulkDeactivate(ctx, &domain.BulkDeactivateRequest{
+		Filter: &domain.BulkFilter{DestinationHost: "*.spam.example", PathPrefix: "/login"},
+		DryRun: true,
+	}, domain.Actor{ID: "admin:1a2b"})
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), resp.Affected)
+
+	_, err = svc.BulkDeactivate(ctx, &domain.BulkDeactivateRequest{
+		Filter: &domain.BulkFilter{CreatorIP: "203.0.113.9", PathPrefix: "/login"},
+	}, domain.Actor{ID: "admin:1a2b"})
+	var appErr *domain.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Contains(t, appErr.Message, "filter.destination_host")
+}
@@ -0,0 +1,86 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/config"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/shortener"
+)
+
+func TestValidate_MaxCustomAliasLength(t *testing.T) {
+	cfg, err := config.LoadFrom(nil)
+	require.NoError(t, err)
+	assert.Equal(t, 64, cfg.MaxCustomAliasLength)
+
+	cfg.MaxCustomAliasLength = 20
+	assert.NoError(t, cfg.Validate())
+
+	cfg.MaxCustomAliasLength = 65
+	assert.ErrorContains(t, cfg.Validate(), "MAX_CUSTOM_ALIAS_LENGTH must be between 2 and 64")
+
+	cfg.MaxCustomAliasLength = 1
+	assert.ErrorContains(t, cfg.Validate(), "MAX_CUSTOM_ALIAS_LENGTH")
+}
+
+func TestShortenURL_AliasLengthLimit(t *testing.T) {
+	tests := []struct {
+		name    string
+		alias   string
+		wantErr string
+	}{
+		{"at the limit", strings.Repeat("a", 20), ""},
+		{"over the limit", strings.Repeat("a", 21), "at most 20 characters, got 21"},
+		{"over the limit with segments", "promo/" + strings.Repeat("a", 15), "at most 20 characters, got 21"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			suite := setupURLServiceTest(t)
+			suite.cfg.CacheTTL = 0
+			suite.cfg.MaxCustomAliasLength = 20
+			suite.repo.On("FindByOriginalURL", mock.Anything, mock.Anything).Return(nil, domain.ErrURLNotFound).Maybe()
+			suite.repo.On("Create", mock.Anything, mock.AnythingOfType("*domain.URL")).Return(nil).Maybe()
+
+			resp, err := suite.service.ShortenURL(context.Background(), &domain.CreateURLRequest{URL: "https://example.com", CustomAlias: tt.alias}, domain.CreatorContext{})
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				assert.Equal(t, tt.alias, resp.ShortCode)
+				return
+			}
+
+			assert.ErrorContains(t, err, tt.wantErr)
+			var appErr *domain.AppError
+			require.True(t, errors.As(err, &appErr))
+			assert.Equal(t, 400, appErr.StatusCode)
+			require.Len(t, appErr.Fields, 1)
+			assert.Equal(t, domain.FieldError{Field: "custom_alias", Rule: "max", Message: appErr.Message}, appErr.Fields[0])
+			suite.repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestShortenURL_AliasSegmentLimit(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	suite.repo.On("FindByOriginalURL", mock.Anything, mock.Anything).Return(nil, domain.ErrURLNotFound)
+
+	_, err := suite.service.ShortenURL(context.Background(), &domain.CreateURLRequest{URL: "https://example.com", CustomAlias: strings.Repeat("a", 51)}, domain.CreatorContext{})
+
+	assert.ErrorContains(t, err, "at most 50 characters")
+}
+
+func TestCodeGenerator_IsValidAcceptsLongerCodes(t *testing.T) {
+	generator := shortener.NewCodeGenerator(6)
+
+	assert.True(t, generator.IsValid(generator.Generate()))
+	assert.True(t, generator.IsValid("summerCampaign2024"), "custom aliases are longer than generated codes")
+	assert.False(t, generator.IsValid(""))
+	assert.False(t, generator.IsValid("summer!"))
+}
@@ -0,0 +1,162 @@
+package unit
+
+import (
+	"context"
+	"html"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/bootstrap"
+	"url-shortener/internal/cache/cachetest"
+	"url-shortener/internal/config"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/handler"
+	"url-shortener/internal/service"
+	"url-shortener/pkg/logger"
+	"url-shortener/pkg/validator"
+)
+
+func TestValidateShortCodePath(t *testing.T) {
+	for _, code := range []string{"abc123", "promo/summer-2024", "docs/setup/linux_x64"} {
+		assert.True(t, validator.ValidateShortCodePath(code), code)
+	}
+	for _, code := range []string{
+		"",
+		"/promo",
+		"promo/",
+		"promo//summer",
+		"promo/s",           // every segment follows the short code rules, two characters at least
+		"promo/summer 2024", // no spaces
+		"promo/summer%2D24", // codes are stored decoded
+		"promo/../admin",
+		"docs/" + strings.Repeat("a", 60), // 65 characters in total
+	} {
+		assert.False(t, validator.ValidateShortCodePath(code), code)
+	}
+
+	// Single segments keep their limit of 50
+	assert.False(t, validator.ValidateShortCodePath(strings.Repeat("a", 51)))
+}
+
+func TestIsReservedAlias_Paths(t *testing.T) {
+	for _, code := range []string{"api/links", "Health/check", "metrics/x", "admin/promo", "promo/continue", "promo/summer/Continue"} {
+		assert.True(t, validator.IsReservedAlias(code), code)
+	}
+	for _, code := range []string{"promo/summer", "apis/links", "promo/api", "continued/promo"} {
+		assert.False(t, validator.IsReservedAlias(code), code)
+	}
+}
+
+func TestShortenURL_MultiSegmentAlias(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	suite.cfg.CacheTTL = 0
+	ctx := context.Background()
+
+	var stored string
+	suite.repo.On("FindByOriginalURL", mock.Anything, mock.Anything).Return(nil, domain.ErrURLNotFound)
+	suite.repo.On("Create", mock.Anything, mock.AnythingOfType("*domain.URL")).Run(func(args mock.Arguments) {
+		stored = args.Get(1).(*domain.URL).ShortCode
+	}).Return(nil)
+
+	resp, err := suite.service.ShortenURL(ctx, &domain.CreateURLRequest{URL: "https://example.com/summer", CustomAlias: "promo/summer-2024"}, domain.CreatorContext{})
+
+	require.NoError(t, err)
+	assert.Equal(t, "promo/summer-2024", stored, "the full path is the code")
+	assert.Equal(t, "https://short.url/promo/summer-2024", resp.ShortURL)
+
+	for _, alias := range []string{"api/promo", "promo/continue", "promo//summer"} {
+		_, err := suite.service.ShortenURL(ctx, &domain.CreateURLRequest{URL: "https://example.com/" + alias, CustomAlias: alias}, domain.CreatorContext{})
+		assert.ErrorIs(t, err, domain.ErrInvalidURL, alias)
+	}
+}
+
+// setupAliasPathRouter serves the redirects like cmd/server, from an in-memory cache
+func setupAliasPathRouter(t *testing.T, links ...*domain.URL) (*gin.Engine, *URLServiceTestSuite) {
+	suite := setupURLServiceTest(t)
+	suite.service = service.NewURLService(suite.repo, cachetest.NewMemoryCache(), suite.cfg, suite.logger)
+	for _, link := range links {
+		suite.repo.On("FindByShortCode", mock.Anything, link.ShortCode).Return(link, nil)
+	}
+	suite.repo.On("FindByShortCode", mock.Anything, mock.Anything).Return(nil, domain.ErrURLNotFound)
+	suite.repo.On("IncrementClickCount", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+	return setupRedirectRouter(suite), suite
+}
+
+func TestRedirect_NestedAliasesDontConflict(t *testing.T) {
+	router, suite := setupAliasPathRouter(t,
+		&domain.URL{ShortCode: "promo", OriginalURL: "https://example.com/promo", IsActive: true},
+		&domain.URL{ShortCode: "promo/summer", OriginalURL: "https://example.com/summer", IsActive: true},
+	)
+
+	for path, want := range map[string]string{
+		"/promo":          "https://example.com/promo",
+		"/promo/summer":   "https://example.com/summer",
+		"/promo/summer/":  "https://example.com/summer",
+		"/promo/%73ummer": "https://example.com/summer", // escaped segments are decoded before the lookup
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		assert.Equal(t, http.StatusMovedPermanently, w.Code, path)
+		assert.Equal(t, want, w.Header().Get("Location"), path)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/promo/winter", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code, "a prefix doesn't match the longer path")
+	suite.repo.AssertCalled(t, "FindByShortCode", mock.Anything, "promo/winter")
+}
+
+func TestRedirect_NestedPathsBelowRoutesAreNotLinks(t *testing.T) {
+	router, suite := setupAliasPathRouter(t)
+
+	for _, path := range []string{"/api/unknown", "/health/x", "/metrics/x"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		assert.Equal(t, http.StatusNotFound, w.Code, path)
+		assert.Contains(t, w.Body.String(), "endpoint not found", path)
+	}
+	suite.repo.AssertNotCalled(t, "FindByShortCode", mock.Anything, mock.Anything)
+}
+
+func TestRedirect_NestedAliasInterstitial(t *testing.T) {
+	router, suite := setupAliasPathRouter(t,
+		&domain.URL{ShortCode: "docs/setup", OriginalURL: "https://example.com/setup", IsActive: true, RequiresInterstitial: true},
+	)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/docs/setup", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	// Segments are escaped one by one, the slashes between them stay
+	match := continueLink.FindStringSubmatch(w.Body.String())
+	require.Len(t, match, 2)
+	assert.True(t, strings.HasPrefix(match[1], "/docs/setup/continue?token="), match[1])
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", html.UnescapeString(match[1]), nil))
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "https://example.com/setup", w.Header().Get("Location"))
+	suite.repo.AssertCalled(t, "IncrementClickCount", mock.Anything, "docs/setup", mock.Anything)
+}
+
+func TestRouter_EscapedSlashesAddressNestedCodes(t *testing.T) {
+	cfg := &config.Config{}
+	router := bootstrap.NewRouter("test", cfg, handler.NewQuietPaths(nil), logger.NewLogger(logger.Options{}))
+	var code string
+	router.GET("/api/v1/urls/:shortCode/stats", func(c *gin.Context) {
+		code = c.Param("shortCode")
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/urls/promo%2Fsummer-2024/stats", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "promo/summer-2024", code)
+}
@@ -0,0 +1,119 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/handler"
+	"url-shortener/pkg/validator"
+)
+
+func TestSuggestAliases_SkipsTakenCandidates(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	ctx := context.Background()
+
+	var checked []string
+	suite.repo.On("ExistsManyByShortCode", ctx, mock.AnythingOfType("[]string")).
+		Run(func(args mock.Arguments) { checked = args.Get(1).([]string) }).
+		Return(map[string]bool{"promo2": true, "promo-2": true}, nil).Once()
+
+	suggestions, err := suite.service.SuggestAliases(ctx, "promo")
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"promo3", "promo-3", "promo4", "promo-4", "promo5"}, suggestions)
+	assert.NotContains(t, checked, "promo")
+	suite.repo.AssertExpectations(t)
+}
+
+func TestSuggestAliases_ValidAndWithinColumnSize(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	ctx := context.Background()
+
+	suite.repo.On("ExistsManyByShortCode", ctx, mock.AnythingOfType("[]string")).
+		Return(map[string]bool{}, nil)
+
+	suggestions, err := suite.service.SuggestAliases(ctx, "summer-sale-2024")
+
+	require.NoError(t, err)
+	require.Len(t, suggestions, 5)
+	for _, suggestion := range suggestions {
+		assert.True(t, validator.ValidateShortCode(suggestion), suggestion)
+		assert.LessOrEqual(t, len(suggestion), 12, suggestion)
+		// Truncation keeps the leading keyword rather than cutting mid-word
+		assert.True(t, strings.HasPrefix(suggestion, "summer"), suggestion)
+	}
+	assert.Contains(t, suggestions, "summer-sale")
+}
+
+func TestSuggestAliases_NeverSuggestsReservedWords(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	ctx := context.Background()
+
+	suite.repo.On("ExistsManyByShortCode", ctx, mock.AnythingOfType("[]string")).
+		Return(map[string]bool{}, nil)
+
+	suggestions, err := suite.service.SuggestAliases(ctx, "health-check")
+
+	require.NoError(t, err)
+	assert.NotContains(t, suggestions, "health")
+}
+
+func TestShortenURL_ReservedAliasRejected(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	ctx := context.Background()
+
+	suite.repo.On("FindByOriginalURL", ctx, "https://example.com/reserved").
+		Return((*domain.URL)(nil), domain.ErrURLNotFound)
+
+	_, err := suite.service.ShortenURL(ctx, &domain.CreateURLRequest{URL: "https://example.com/reserved", CustomAlias: "Metrics"}, domain.CreatorContext{IP: "192.168.1.1"})
+
+	assert.ErrorIs(t, err, domain.ErrInvalidURL)
+	suite.repo.AssertNotCalled(t, "ExistsByShortCode", mock.Anything, mock.Anything)
+}
+
+func TestShortenURLHandler_ConflictIncludesSuggestions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	suite := setupURLServiceTest(t)
+
+	suite.repo.On("FindByOriginalURL", mock.Anything, "https://example.com/taken").
+		Return((*domain.URL)(nil), domain.ErrURLNotFound)
+	suite.repo.On("Create", mock.Anything, withShortCode("taken")).
+		Return(domain.ErrShortCodeTaken)
+	suite.repo.On("ExistsManyByShortCode", mock.Anything, mock.AnythingOfType("[]string")).
+		Return(map[string]bool{"taken2": true}, nil).Once()
+
+	router := gin.New()
+	router.POST("/api/v1/shorten", handler.NewURLHandler(suite.service, suite.cfg, suite.logger).ShortenURL)
+
+	shorten := func(query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/v1/shorten"+query, strings.NewReader(`{"url":"https://example.com/taken","custom_alias":"taken"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := shorten("")
+	require.Equal(t, http.StatusConflict, w.Code)
+
+	var body domain.ErrorResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "short_code_taken", body.Error)
+	assert.Equal(t, []string{"taken-2", "taken3", "taken-3", "taken4", "taken-4"}, body.Suggestions)
+
+	// Opting out skips the batched lookup entirely
+	w = shorten("?suggestions=false")
+	require.Equal(t, http.StatusConflict, w.Code)
+	assert.NotContains(t, w.Body.String(), "suggestions")
+	suite.repo.AssertNumberOfCalls(t, "ExistsManyByShortCode", 1)
+}
@@ -0,0 +1,266 @@
+package unit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/apikey"
+	"url-shortener/internal/config"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/handler"
+	"url-shortener/pkg/logger"
+)
+
+// MockAPIKeyRepository is a mock implementation of the APIKeyRepository interface
+type MockAPIKeyRepository struct {
+	mock.Mock
+}
+
+func (m *MockAPIKeyRepository) Create(ctx context.Context, key *domain.APIKey) error {
+	args := m.Called(ctx, key)
+	if args.Error(0) == nil {
+		key.ID = 1
+	}
+	return args.Error(0)
+}
+
+func (m *MockAPIKeyRepository) Revoke(ctx context.Context, id uint) (*domain.APIKey, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.APIKey), args.Error(1)
+}
+
+func (m *MockAPIKeyRepository) List(ctx context.Context) ([]domain.APIKey, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]domain.APIKey), args.Error(1)
+}
+
+func (m *MockAPIKeyRepository) ListActive(ctx context.Context) ([]domain.APIKey, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.APIKey), args.Error(1)
+}
+
+func (m *MockAPIKeyRepository) Restore(ctx context.Context, keys []domain.APIKey) error {
+	args := m.Called(ctx, keys)
+	return args.Error(0)
+}
+
+// issuedKey builds the stored row for a secret
+func issuedKey(id uint, secret string, tier int) domain.APIKey {
+	return domain.APIKey{ID: id, KeyHash: apikey.Hash(secret), Fingerprint: apikey.Fingerprint(secret), Label: "test", Tier: tier}
+}
+
+func TestAPIKeyStore_CreateStoresOnlyTheHash(t *testing.T) {
+	repo := new(MockAPIKeyRepository)
+	repo.On("ListActive", mock.Anything).Return([]domain.APIKey{}, nil)
+	var stored *domain.APIKey
+	repo.On("Create", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		stored = args.Get(1).(*domain.APIKey)
+	}).Return(nil)
+	store := apikey.NewStore(repo, time.Minute, logger.NewLogger(logger.Options{}))
+	require.NoError(t, store.Refresh(context.Background()))
+
+	resp, err := store.Create(context.Background(), &domain.CreateAPIKeyRequest{Label: " ci ", Tier: 600})
+
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(resp.Key, "usk_"))
+	assert.Equal(t, apikey.Hash(resp.Key), stored.KeyHash)
+	assert.NotContains(t, stored.KeyHash, resp.Key)
+	assert.Equal(t, "ci", stored.Label)
+	assert.Equal(t, fingerprint(resp.Key), resp.Fingerprint)
+
+	key, ok := store.Validate(context.Background(), resp.Key)
+	require.True(t, ok)
+	assert.Equal(t, 600, key.Tier)
+}
+
+func TestAPIKeyStore_CreateRejectsInvalidTier(t *testing.T) {
+	store := apikey.NewStore(new(MockAPIKeyRepository), time.Minute, logger.NewLogger(logger.Options{}))
+
+	_, err := store.Create(context.Background(), &domain.CreateAPIKeyRequest{Label: "ci", Tier: -5})
+
+	var appErr *domain.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, http.StatusBadRequest, appErr.StatusCode)
+}
+
+func TestAPIKeyStore_RevokeTakesEffectImmediatelyLocally(t *testing.T) {
+	repo := new(MockAPIKeyRepository)
+	key := issuedKey(7, "usk_local", 0)
+	repo.On("ListActive", mock.Anything).Return([]domain.APIKey{key}, nil).Once()
+	repo.On("Revoke", mock.Anything, uint(7)).Return(&key, nil)
+	store := apikey.NewStore(repo, time.Minute, logger.NewLogger(logger.Options{}))
+
+	_, ok := store.Validate(context.Background(), "usk_local")
+	require.True(t, ok)
+
+	_, err := store.Revoke(context.Background(), 7)
+	require.NoError(t, err)
+
+	_, ok = store.Validate(context.Background(), "usk_local")
+	assert.False(t, ok)
+}
+
+func TestAPIKeyStore_RevocationElsewhereExpiresWithCacheTTL(t *testing.T) {
+	repo := new(MockAPIKeyRepository)
+	repo.On("ListActive", mock.Anything).Return([]domain.APIKey{issuedKey(7, "usk_remote", 0)}, nil).Once()
+	repo.On("ListActive", mock.Anything).Return([]domain.APIKey{}, nil)
+	store := apikey.NewStore(repo, 50*time.Millisecond, logger.NewLogger(logger.Options{}))
+
+	_, ok := store.Validate(context.Background(), "usk_remote")
+	require.True(t, ok)
+	_, ok = store.Validate(context.Background(), "usk_remote")
+	assert.True(t, ok, "cached copy is used within the TTL")
+
+	time.Sleep(60 * time.Millisecond)
+	_, ok = store.Validate(context.Background(), "usk_remote")
+	assert.False(t, ok)
+	repo.AssertNumberOfCalls(t, "ListActive", 2)
+}
+
+func TestAPIKeyStore_FailedReloadKeepsPreviousKeys(t *testing.T) {
+	repo := new(MockAPIKeyRepository)
+	repo.On("ListActive", mock.Anything).Return([]domain.APIKey{issuedKey(7, "usk_kept", 0)}, nil).Once()
+	repo.On("ListActive", mock.Anything).Return(nil, domain.NewInternalError(assert.AnError))
+	store := apikey.NewStore(repo, 10*time.Millisecond, logger.NewLogger(logger.Options{}))
+	require.NoError(t, store.Refresh(context.Background()))
+
+	time.Sleep(20 * time.Millisecond)
+	_, ok := store.Validate(context.Background(), "usk_kept")
+	assert.True(t, ok)
+}
+
+// setupAuthRouter protects /ping with AuthMiddleware
+func setupAuthRouter(cfg *config.Config, keys *apikey.Store) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/ping", handler.AuthMiddleware(cfg, keys), func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString("actor"))
+	})
+	return router
+}
+
+func TestAuthMiddleware_AcceptsBootstrapAndIssuedKeys(t *testing.T) {
+	repo := new(MockAPIKeyRepository)
+	repo.On("ListActive", mock.Anything).Return([]domain.APIKey{issuedKey(1, "usk_issued", 0)}, nil)
+	cfg := &config.Config{EnableAuthentication: true, APIKey: "bootstrap"}
+	router := setupAuthRouter(cfg, apikey.NewStore(repo, time.Minute, logger.NewLogger(logger.Options{})))
+
+	tests := []struct {
+		key    string
+		status int
+		actor  string
+	}{
+		{"bootstrap", http.StatusOK, "api_key:" + fingerprint("bootstrap")},
+		{"usk_issued", http.StatusOK, "api_key:" + fingerprint("usk_issued")},
+		{"usk_unknown", http.StatusUnauthorized, ""},
+		{"", http.StatusUnauthorized, ""},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/ping", nil)
+		req.Header.Set("X-API-Key", tt.key)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, tt.status, w.Code, tt.key)
+		if tt.actor != "" {
+			assert.Equal(t, tt.actor, w.Body.String())
+		}
+	}
+}
+
+func TestAuthMiddleware_EmptyKeyRejectedWithoutBootstrapKey(t *testing.T) {
+	cfg := &config.Config{EnableAuthentication: true, AdminAPIKey: "admin"}
+	router := setupAuthRouter(cfg, nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/ping", nil))
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestRateLimit_IssuedKeyUsesItsTier(t *testing.T) {
+	repo := new(MockAPIKeyRepository)
+	repo.On("ListActive", mock.Anything).Return([]domain.APIKey{
+		issuedKey(1, "usk_small", 2),
+		issuedKey(2, "usk_unlimited", config.RateLimitUnlimited),
+	}, nil)
+	keys := apikey.NewStore(repo, time.Minute, logger.NewLogger(logger.Options{}))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(handler.APIKeyIdentityMiddleware(&config.Config{}, keys))
+	router.Use(handler.RateLimitMiddleware(5, nil))
+	router.GET("/ping", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	assert.Equal(t, 2, sendRequests(router, 5, "usk_small"))
+	assert.Equal(t, 20, sendRequests(router, 20, "usk_unlimited"))
+}
+
+// setupAPIKeyAdminRouter registers the key management endpoints without admin auth
+func setupAPIKeyAdminRouter(repo *MockAPIKeyRepository) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	h := handler.NewAPIKeyHandler(apikey.NewStore(repo, time.Minute, logger.NewLogger(logger.Options{})), logger.NewLogger(logger.Options{}))
+
+	router := gin.New()
+	router.POST("/api/v1/admin/api-keys", h.CreateKey)
+	router.DELETE("/api/v1/admin/api-keys/:id", h.RevokeKey)
+	return router
+}
+
+func TestAPIKeyHandler_CreateReturnsSecretOnce(t *testing.T) {
+	repo := new(MockAPIKeyRepository)
+	repo.On("Create", mock.Anything, mock.Anything).Return(nil)
+	router := setupAPIKeyAdminRouter(repo)
+
+	body, _ := json.Marshal(map[string]interface{}{"label": "deploy bot"})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/api/v1/admin/api-keys", bytes.NewReader(body)))
+
+	require.Equal(t, http.StatusCreated, w.Code)
+	var fields map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &fields))
+	assert.NotEmpty(t, fields["key"])
+	assert.Equal(t, "deploy bot", fields["label"])
+	assert.NotContains(t, fields, "key_hash")
+}
+
+func TestAPIKeyHandler_Revoke(t *testing.T) {
+	repo := new(MockAPIKeyRepository)
+	revokedAt := time.Now()
+	repo.On("Revoke", mock.Anything, uint(3)).Return(&domain.APIKey{ID: 3, RevokedAt: &revokedAt}, nil)
+	repo.On("Revoke", mock.Anything, uint(4)).Return(nil, domain.ErrAPIKeyNotFound)
+	router := setupAPIKeyAdminRouter(repo)
+
+	tests := []struct {
+		path   string
+		status int
+	}{
+		{"/api/v1/admin/api-keys/3", http.StatusOK},
+		{"/api/v1/admin/api-keys/4", http.StatusNotFound},
+		{"/api/v1/admin/api-keys/abc", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("DELETE", tt.path, nil))
+		assert.Equal(t, tt.status, w.Code, tt.path)
+	}
+}
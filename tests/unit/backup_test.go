@@ -0,0 +1,251 @@
+package unit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/backup"
+	"url-shortener/internal/config"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/repository"
+)
+
+const testBackupKey = "0123456789abcdef0123456789abcdef"
+
+// backupKeys is what the api_keys table holds when a backup is taken
+func backupKeys() []domain.APIKey {
+	revoked := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	return []domain.APIKey{
+		{ID: 3, KeyHash: "hash-ci", Fingerprint: "usk_1a2b", Label: "ci", Tier: 1, CreatedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{ID: 9, KeyHash: "hash-acme", Fingerprint: "usk_3c4d", Label: "acme", TenantID: "acme", DefaultExpiryDays: 30, MaxExpiryDays: 90,
+			CreatedAt: time.Date(2024, 2, 2, 3, 4, 5, 0, time.UTC), RevokedAt: &revoked},
+	}
+}
+
+// backupSettings is the configuration a backup takes its policy from
+func backupSettings(t *testing.T) func() *config.Config {
+	cfg, err := config.LoadFrom(strings.NewReader(`
+shortener_domains: [bit.ly, tinyurl.com]
+hook_allowed_hosts: [example.com]
+tenant_scoped_codes: true
+tenant_domains: {globex: go.globex.test}
+rate_limit_tiers: {usk_1a2b: 600, usk_3c4d: unlimited}
+max_urls_per_day_per_tenant: 500
+`))
+	require.NoError(t, err)
+	return func() *config.Config { return cfg }
+}
+
+func checksumOf(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestBackups_RoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	store, err := backup.NewFileStore(dir)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	source := new(MockAPIKeyRepository)
+	source.On("List", mock.Anything).Return(backupKeys(), nil)
+	manifest, err := backup.New(store, source, backupSettings(t), testBackupKey, 5).Create(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"", "acme", "globex"}, manifest.Tenants, "tenants with only a domain are listed too")
+	require.Len(t, manifest.Tables, 1)
+	assert.Equal(t, "api_keys", manifest.Tables[0].Name)
+	assert.Equal(t, 2, manifest.Tables[0].Rows)
+
+	// The hashes are archived encrypted, never in the clear
+	archived, err := os.ReadFile(filepath.Join(dir, manifest.Name+".bak"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(archived), "hash-acme")
+
+	// A fresh database is restored from the newest backup, hashes included
+	target := new(MockAPIKeyRepository)
+	target.On("Restore", mock.Anything, backupKeys()).Return(nil)
+	restored, _, err := backup.New(store, target, nil, testBackupKey, 5).Restore(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, manifest.Name, restored.Name)
+	target.AssertExpectations(t)
+}
+
+func TestBackups_RestoresPolicyAsConfigFile(t *testing.T) {
+	store, err := backup.NewFileStore(t.TempDir())
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	source := new(MockAPIKeyRepository)
+	source.On("List", mock.Anything).Return(backupKeys(), nil)
+	manifest, err := backup.New(store, source, backupSettings(t), testBackupKey, 5).Create(ctx)
+	require.NoError(t, err)
+	assert.NotEmpty(t, manifest.PolicySHA256)
+
+	target := new(MockAPIKeyRepository)
+	target.On("Restore", mock.Anything, mock.Anything).Return(nil)
+	_, policy, err := backup.New(store, target, nil, testBackupKey, 5).Restore(ctx, "")
+	require.NoError(t, err)
+
+	// The written file loads as CONFIG_FILE and brings the drill up with the archived rules
+	var file strings.Builder
+	require.NoError(t, policy.WriteYAML(&file, manifest.Name))
+	restored, err := config.LoadFrom(strings.NewReader(file.String()))
+	require.NoError(t, err, file.String())
+
+	original := backupSettings(t)()
+	assert.Equal(t, original.ShortenerDomains, restored.ShortenerDomains)
+	assert.Equal(t, original.HookAllowedHosts, restored.HookAllowedHosts)
+	assert.Equal(t, original.AllowedURLSchemes, restored.AllowedURLSchemes)
+	assert.True(t, restored.TenantScopedCodes)
+	assert.Equal(t, map[string]string{"globex": "go.globex.test"}, restored.TenantDomains)
+	assert.Equal(t, map[string]int{"usk_1a2b": 600, "usk_3c4d": config.RateLimitUnlimited}, restored.RateLimitTiers)
+	assert.Equal(t, 500, restored.MaxURLsPerDayPerTenant)
+	assert.Empty(t, restored.Hooks)
+}
+
+func TestBackups_RestoreRefusesNonEmptyDatabase(t *testing.T) {
+	store, err := backup.NewFileStore(t.TempDir())
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	source := new(MockAPIKeyRepository)
+	source.On("List", mock.Anything).Return(backupKeys(), nil)
+	_, err = backup.New(store, source, backupSettings(t), testBackupKey, 5).Create(ctx)
+	require.NoError(t, err)
+
+	target := new(MockAPIKeyRepository)
+	target.On("Restore", mock.Anything, mock.Anything).Return(repository.ErrRestoreTargetNotEmpty)
+	_, _, err = backup.New(store, target, nil, testBackupKey, 5).Restore(ctx, "")
+	assert.ErrorIs(t, err, repository.ErrRestoreTargetNotEmpty)
+}
+
+func TestBackups_VerifyDetectsWrongKeyAndTampering(t *testing.T) {
+	dir := t.TempDir()
+	store, err := backup.NewFileStore(dir)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	source := new(MockAPIKeyRepository)
+	source.On("List", mock.Anything).Return(backupKeys(), nil)
+	backups := backup.New(store, source, backupSettings(t), testBackupKey, 5)
+	manifest, err := backups.Create(ctx)
+	require.NoError(t, err)
+
+	verified, err := backups.Verify(ctx, manifest.Name)
+	require.NoError(t, err)
+	assert.Equal(t, manifest.ArchiveSHA256, verified.ArchiveSHA256)
+
+	// A wrong key is caught before anything is restored
+	target := new(MockAPIKeyRepository)
+	_, _, err = backup.New(store, target, nil, strings.Repeat("x", 32), 5).Restore(ctx, manifest.Name)
+	assert.ErrorIs(t, err, backup.ErrCorrupt)
+	target.AssertNotCalled(t, "Restore", mock.Anything, mock.Anything)
+
+	// So is a changed archive, and a manifest that no longer matches it
+	archivePath := filepath.Join(dir, manifest.Name+".bak")
+	archived, err := os.ReadFile(archivePath)
+	require.NoError(t, err)
+	archived[len(archived)-1] ^= 0xff
+	require.NoError(t, os.WriteFile(archivePath, archived, 0o600))
+	_, err = backups.Verify(ctx, manifest.Name)
+	assert.ErrorIs(t, err, backup.ErrCorrupt)
+
+	manifestPath := filepath.Join(dir, manifest.Name+".manifest.json")
+	encoded, err := os.ReadFile(manifestPath)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(manifestPath, []byte(strings.Replace(string(encoded), manifest.ArchiveSHA256, checksumOf(archived), 1)), 0o600))
+	_, err = backups.Verify(ctx, manifest.Name)
+	assert.ErrorIs(t, err, backup.ErrCorrupt, "a matching checksum doesn't make a changed archive decrypt")
+
+	_, err = backups.Verify(ctx, "critical-19700101T000000.000Z")
+	assert.ErrorIs(t, err, backup.ErrNotFound)
+}
+
+func TestBackups_RetentionKeepsNewest(t *testing.T) {
+	store, err := backup.NewFileStore(t.TempDir())
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	source := new(MockAPIKeyRepository)
+	source.On("List", mock.Anything).Return(backupKeys(), nil)
+	backups := backup.New(store, source, backupSettings(t), testBackupKey, 2)
+
+	var created []string
+	for i := 0; i < 4; i++ {
+		manifest, err := backups.Create(ctx)
+		require.NoError(t, err)
+		created = append(created, manifest.Name)
+		time.Sleep(2 * time.Millisecond) // Names have millisecond resolution
+	}
+
+	names, err := backups.List(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{created[3], created[2]}, names)
+
+	files, err := store.List(ctx)
+	require.NoError(t, err)
+	assert.Len(t, files, 4, "an archive and a manifest per kept backup")
+}
+
+func TestBackups_S3Store(t *testing.T) {
+	fake := &fakeS3{objects: map[string]fakeObject{}, pageSize: 1}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	store, err := backup.NewS3Store(backup.S3Config{
+		Endpoint:  server.URL,
+		Bucket:    "bucket",
+		AccessKey: "key",
+		SecretKey: "secret",
+		Prefix:    "backups/",
+		PathStyle: true,
+	}, server.Client())
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	source := new(MockAPIKeyRepository)
+	source.On("List", mock.Anything).Return(backupKeys(), nil)
+	manifest, err := backup.New(store, source, backupSettings(t), testBackupKey, 5).Create(ctx)
+	require.NoError(t, err)
+	assert.Contains(t, fake.objects, "backups/"+manifest.Name+".bak")
+	assert.Contains(t, fake.objects, "backups/"+manifest.Name+".manifest.json")
+
+	target := new(MockAPIKeyRepository)
+	target.On("Restore", mock.Anything, backupKeys()).Return(nil)
+	_, _, err = backup.New(store, target, nil, testBackupKey, 5).Restore(ctx, "")
+	require.NoError(t, err)
+	target.AssertExpectations(t)
+}
+
+func TestValidate_BackupSettings(t *testing.T) {
+	cfg, err := config.LoadFrom(nil)
+	require.NoError(t, err)
+	assert.Equal(t, config.BackupStoreNone, cfg.BackupStore)
+
+	cfg.BackupStore = config.BackupStoreFilesystem
+	assert.ErrorContains(t, cfg.Validate(), "BACKUP_KEY")
+
+	cfg.BackupKey = testBackupKey
+	assert.NoError(t, cfg.Validate())
+
+	cfg.BackupRetention = 0
+	assert.ErrorContains(t, cfg.Validate(), "BACKUP_RETENTION")
+	cfg.BackupRetention = 14
+
+	cfg.BackupStore = config.BackupStoreS3
+	assert.ErrorContains(t, cfg.Validate(), "BACKUP_S3_ENDPOINT")
+
+	cfg.BackupStore = "tape"
+	assert.ErrorContains(t, cfg.Validate(), "BACKUP_STORE")
+}
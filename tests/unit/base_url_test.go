@@ -0,0 +1,119 @@
+package unit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/config"
+	"url-shortener/internal/handler"
+)
+
+// setupBaseURLRouter serves the info endpoint behind the base URL middleware in request mode
+func setupBaseURLRouter(suite *URLServiceTestSuite) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	suite.cfg.BaseURLMode = config.BaseURLModeRequest
+	suite.cfg.AllowedDomains = []string{"sho.rt", "staging.sho.rt"}
+	suite.cfg.TrustedProxies = []string{"10.0.0.0/8"}
+	h := handler.NewURLHandler(suite.service, suite.cfg, suite.logger)
+
+	router := gin.New()
+	router.Use(handler.BaseURLMiddleware(suite.cfg))
+	router.GET("/api/v1/urls/:shortCode", h.GetURLInfo)
+	return router
+}
+
+func TestBaseURLMiddleware_RequestMode(t *testing.T) {
+	tests := []struct {
+		name     string
+		host     string
+		peer     string
+		headers  map[string]string
+		expected string
+	}{
+		{"allowed host", "staging.sho.rt", "192.0.2.1:4000", nil, "http://staging.sho.rt/abc123"},
+		{"host with port", "sho.rt:8443", "192.0.2.1:4000", nil, "http://sho.rt:8443/abc123"},
+		{"host in other case", "Sho.RT", "192.0.2.1:4000", nil, "http://sho.rt/abc123"},
+		{"host not allowed", "evil.example", "192.0.2.1:4000", nil, "https://short.url/abc123"},
+		{"subdomain not listed", "x.sho.rt", "192.0.2.1:4000", nil, "https://short.url/abc123"},
+		{"forwarded by trusted proxy", "internal:8080", "10.1.2.3:4000",
+			map[string]string{"X-Forwarded-Proto": "https", "X-Forwarded-Host": "sho.rt, internal"}, "https://sho.rt/abc123"},
+		{"forwarded host not allowed", "sho.rt", "10.1.2.3:4000",
+			map[string]string{"X-Forwarded-Host": "evil.example"}, "https://short.url/abc123"},
+		{"forwarded headers from untrusted peer", "sho.rt", "192.0.2.1:4000",
+			map[string]string{"X-Forwarded-Proto": "https", "X-Forwarded-Host": "staging.sho.rt"}, "http://sho.rt/abc123"},
+		{"unknown forwarded scheme", "sho.rt", "10.1.2.3:4000",
+			map[string]string{"X-Forwarded-Proto": "javascript"}, "http://sho.rt/abc123"},
+		{"credentials in host", "user@sho.rt", "192.0.2.1:4000", nil, "https://short.url/abc123"},
+		{"bad port", "sho.rt:0", "192.0.2.1:4000", nil, "https://short.url/abc123"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			suite := setupURLServiceTest(t)
+			suite.repo.On("FindByShortCode", mock.Anything, "abc123").Return(infoTestURL(), nil)
+			router := setupBaseURLRouter(suite)
+
+			req := httptest.NewRequest("GET", "/api/v1/urls/abc123", nil)
+			req.Host = tt.host
+			req.RemoteAddr = tt.peer
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			require.Equal(t, http.StatusOK, w.Code)
+			var body struct {
+				ShortURL string `json:"short_url"`
+			}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			assert.Equal(t, tt.expected, body.ShortURL)
+		})
+	}
+}
+
+func TestBaseURLMiddleware_StaticModeIgnoresHost(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	suite.repo.On("FindByShortCode", mock.Anything, "abc123").Return(infoTestURL(), nil)
+	h := handler.NewURLHandler(suite.service, suite.cfg, suite.logger)
+	router := gin.New()
+	router.Use(handler.BaseURLMiddleware(suite.cfg))
+	router.GET("/api/v1/urls/:shortCode", h.GetURLInfo)
+
+	req := httptest.NewRequest("GET", "/api/v1/urls/abc123", nil)
+	req.Host = "sho.rt"
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"short_url":"https://short.url/abc123"`)
+}
+
+func TestValidate_BaseURLMode(t *testing.T) {
+	cfg, err := config.LoadFrom(nil)
+	require.NoError(t, err)
+
+	cfg.BaseURLMode = config.BaseURLModeRequest
+	assert.ErrorContains(t, cfg.Validate(), "ALLOWED_DOMAINS is required")
+
+	cfg.AllowedDomains = []string{"sho.rt"}
+	assert.NoError(t, cfg.Validate())
+
+	cfg.AllowedDomains = []string{"https://sho.rt"}
+	assert.ErrorContains(t, cfg.Validate(), "bare host names")
+
+	cfg.AllowedDomains = []string{"sho.rt"}
+	cfg.TrustedProxies = []string{"10.0.0.0/8", "192.0.2.7", "proxy.internal"}
+	assert.ErrorContains(t, cfg.Validate(), "proxy.internal")
+
+	cfg.TrustedProxies = nil
+	cfg.BaseURLMode = "host"
+	assert.ErrorContains(t, cfg.Validate(), "BASE_URL_MODE")
+}
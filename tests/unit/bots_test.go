@@ -0,0 +1,140 @@
+package unit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/config"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/handler"
+	"url-shortener/internal/redirect"
+	"url-shortener/pkg/validator"
+)
+
+const googlebotUA = "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)"
+
+func TestIsBot(t *testing.T) {
+	patterns := []string{"bot", "facebookexternalhit"}
+
+	assert.True(t, redirect.IsBot(googlebotUA, patterns))
+	assert.True(t, redirect.IsBot("facebookexternalhit/1.1", patterns))
+	assert.False(t, redirect.IsBot("Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X)", patterns))
+	assert.False(t, redirect.IsBot("", patterns))
+	assert.False(t, redirect.IsBot(googlebotUA, nil), "an empty list disables detection")
+}
+
+func TestIsReservedAlias_WellKnownFiles(t *testing.T) {
+	assert.True(t, validator.IsReservedAlias("robots.txt"))
+	assert.True(t, validator.IsReservedAlias("Favicon.ico"))
+}
+
+// setupStaticRouter registers the well-known files next to the short code catch-all, as setupRouter does
+func setupStaticRouter(suite *URLServiceTestSuite) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	static := handler.NewStaticHandler(suite.cfg, suite.logger)
+	router.GET("/robots.txt", static.RobotsTxt)
+	router.GET("/favicon.ico", static.Favicon)
+	router.GET("/:shortCode", handler.NewURLHandler(suite.service, suite.cfg, suite.logger).RedirectURL)
+	return router
+}
+
+func TestStaticHandler_Defaults(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	router := setupStaticRouter(suite)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/robots.txt", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/plain; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Body.String(), "Disallow: /")
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/favicon.ico", nil))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Cache-Control"))
+
+	// Neither request was looked up as a short code
+	suite.cache.AssertNotCalled(t, "Get", mock.Anything, mock.Anything)
+	suite.repo.AssertNotCalled(t, "FindByShortCode", mock.Anything, mock.Anything)
+}
+
+func TestStaticHandler_ConfiguredFiles(t *testing.T) {
+	dir := t.TempDir()
+	robots := filepath.Join(dir, "robots.txt")
+	favicon := filepath.Join(dir, "favicon.ico")
+	require.NoError(t, os.WriteFile(robots, []byte("User-agent: *\nAllow: /\n"), 0o644))
+	require.NoError(t, os.WriteFile(favicon, []byte{0x00, 0x00, 0x01, 0x00, 0x01, 0x00}, 0o644))
+
+	suite := setupURLServiceTest(t)
+	suite.cfg.RobotsTxtFile = robots
+	suite.cfg.FaviconFile = favicon
+	router := setupStaticRouter(suite)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/robots.txt", nil))
+	assert.Equal(t, "User-agent: *\nAllow: /\n", w.Body.String())
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/favicon.ico", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "image/x-icon", w.Header().Get("Content-Type"))
+}
+
+func TestRecordClick_BotsCountedSeparately(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	suite.cfg.BotUserAgents = []string{"bot"}
+	suite.cfg.BotClicks = config.BotClicksSeparate
+	ctx := context.Background()
+
+	suite.cache.On("Get", ctx, "abc123").Return("https://example.com", nil)
+	suite.repo.On("IncrementBotClickCount", mock.Anything, "abc123").Return(nil).Once()
+
+	_, err := suite.service.GetOriginalURL(ctx, "abc123", domain.Visitor{UserAgent: googlebotUA})
+	require.NoError(t, err)
+	require.NoError(t, suite.service.Close(ctx))
+
+	suite.repo.AssertExpectations(t)
+	suite.repo.AssertNotCalled(t, "IncrementClickCount", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRecordClick_BotsIgnored(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	suite.cfg.BotUserAgents = []string{"bot"}
+	suite.cfg.BotClicks = config.BotClicksIgnore
+	ctx := context.Background()
+
+	suite.cache.On("Get", ctx, "abc123").Return("https://example.com", nil)
+
+	_, err := suite.service.GetOriginalURL(ctx, "abc123", domain.Visitor{UserAgent: googlebotUA})
+	require.NoError(t, err)
+	require.NoError(t, suite.service.Close(ctx))
+
+	suite.repo.AssertNotCalled(t, "IncrementClickCount", mock.Anything, mock.Anything, mock.Anything)
+	suite.repo.AssertNotCalled(t, "IncrementBotClickCount", mock.Anything, mock.Anything)
+}
+
+func TestRecordClick_VisitorsStillCounted(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	suite.cfg.BotUserAgents = []string{"bot"}
+	ctx := context.Background()
+
+	suite.cache.On("Get", ctx, "abc123").Return("https://example.com", nil)
+	suite.repo.On("IncrementClickCount", mock.Anything, "abc123", mock.Anything).Return(nil).Once()
+
+	_, err := suite.service.GetOriginalURL(ctx, "abc123", domain.Visitor{UserAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64)"})
+	require.NoError(t, err)
+	require.NoError(t, suite.service.Close(ctx))
+
+	suite.repo.AssertExpectations(t)
+	suite.repo.AssertNotCalled(t, "IncrementBotClickCount", mock.Anything, mock.Anything)
+}
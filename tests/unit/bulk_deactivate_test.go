@@ -0,0 +1,193 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/cache"
+	"url-shortener/internal/cache/cachetest"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/handler"
+	"url-shortener/internal/service"
+)
+
+// batchMapCache is a MemoryCache that also deletes in batches, recording each batch
+type batchMapCache struct {
+	*cachetest.MemoryCache
+	batches [][]string
+}
+
+func (c *batchMapCache) DeleteMultiple(ctx context.Context, keys []string) error {
+	c.batches = append(c.batches, append([]string(nil), keys...))
+	for _, key := range keys {
+		_ = c.Delete(ctx, key)
+	}
+	return nil
+}
+
+// setupBulkTest returns a service over a batch-deleting cache with the dedup cache on
+func setupBulkTest(t *testing.T) (*URLServiceTestSuite, *batchMapCache, *MockAuditRepository, service.URLService) {
+	suite := setupURLServiceTest(t)
+	suite.cfg.DedupCacheTTL = time.Hour
+	store := &batchMapCache{MemoryCache: cachetest.NewMemoryCache()}
+	audit := new(MockAuditRepository)
+	svc := service.NewURLService(suite.repo, store, suite.cfg, suite.logger, service.WithAuditRepository(audit))
+	return suite, store, audit, svc
+}
+
+func TestBulkDeactivate_ShortCodes(t *testing.T) {
+	suite, store, audit, svc := setupBulkTest(t)
+	ctx := context.Background()
+	spam := []domain.URL{
+		{ShortCode: "spam01", OriginalURL: "https://spam.example/a"},
+		{ShortCode: "spam02", OriginalURL: "https://spam.example/b"},
+	}
+	for _, url := range spam {
+		require.NoError(t, store.Set(ctx, cache.LinkKey(url.ShortCode), "cached", time.Hour))
+	}
+	require.NoError(t, store.Set(ctx, "unrelated", "kept", time.Hour))
+
+	codes := []string{"spam01", "spam02", "spam01", "gone01"}
+	want := domain.URLFilter{ShortCodes: []string{"spam01", "spam02", "gone01"}}
+	suite.repo.On("ExistsManyByShortCode", ctx, want.ShortCodes).Return(map[string]bool{"spam01": true, "spam02": true}, nil)
+	suite.repo.On("DeactivateMatching", ctx, want).Return(spam, nil)
+	audit.On("Record", ctx, mock.MatchedBy(func(e *domain.AuditEntry) bool {
+		return e.Action == domain.AuditActionBulkDeactivate && strings.Contains(e.Details, "affected=2")
+	})).Return(nil)
+
+	resp, err := svc.BulkDeactivate(ctx, &domain.BulkDeactivateRequest{ShortCodes: codes}, domain.Actor{ID: "admin:1a2b", IP: "10.0.0.7"})
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), resp.Affected)
+	assert.Equal(t, []string{"gone01"}, resp.NotFound)
+	assert.False(t, resp.DryRun)
+	require.Len(t, store.batches, 1, "the cache is invalidated in one pipelined delete")
+	assert.Contains(t, store.batches[0], cache.LinkKey("spam01"))
+	assert.Contains(t, store.batches[0], cache.DedupRefKey("spam02"))
+	assert.Len(t, store.batches[0], 6, "link, dedup reference and dedup entry for each link")
+	assert.Equal(t, []string{"unrelated"}, store.Keys(""))
+	audit.AssertExpectations(t)
+}
+
+func TestBulkDeactivate_DryRunCountsOnly(t *testing.T) {
+	suite, store, audit, svc := setupBulkTest(t)
+	ctx := context.Background()
+	from := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(6 * time.Hour)
+	want := domain.URLFilter{CreatorIP: "203.0.113.9", DestinationHost: "spam.example", CreatedFrom: &from, CreatedTo: &to}
+	suite.repo.On("CountActiveMatching", ctx, want).Return(int64(512), nil)
+
+	resp, err := svc.BulkDeactivate(ctx, &domain.BulkDeactivateRequest{
+		Filter: &domain.BulkFilter{CreatorIP: "203.0.113.9", DestinationHost: " Spam.Example ", CreatedFrom: &from, CreatedTo: &to},
+		DryRun: true,
+	}, domain.Actor{ID: "admin:1a2b"})
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(512), resp.Affected)
+	assert.True(t, resp.DryRun)
+	assert.Empty(t, resp.NotFound)
+	suite.repo.AssertNotCalled(t, "DeactivateMatching", mock.Anything, mock.Anything)
+	audit.AssertNotCalled(t, "Record", mock.Anything, mock.Anything)
+	assert.Empty(t, store.batches)
+}
+
+func TestBulkDeactivate_Validation(t *testing.T) {
+	from := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	tooMany := make([]string, service.MaxBulkShortCodes+1)
+	for i := range tooMany {
+		tooMany[i] = "c" + strings.Repeat("x", i%5)
+	}
+
+	tests := []struct {
+		name string
+		req  domain.BulkDeactivateRequest
+		want string
+	}{
+		{"nothing selected", domain.BulkDeactivateRequest{}, "required"},
+		{"empty filter", domain.BulkDeactivateRequest{Filter: &domain.BulkFilter{}}, "required"},
+		{"blank filter fields", domain.BulkDeactivateRequest{Filter: &domain.BulkFilter{CreatorIP: " ", DestinationHost: " "}}, "required"},
+		{"codes and filter", domain.BulkDeactivateRequest{ShortCodes: []string{"abc123"}, Filter: &domain.BulkFilter{CreatorIP: "203.0.113.9"}}, "mutually exclusive"},
+		{"too many codes", domain.BulkDeactivateRequest{ShortCodes: tooMany}, "short_codes"},
+		{"empty code", domain.BulkDeactivateRequest{ShortCodes: []string{"abc123", " "}}, "empty"},
+		{"bad ip", domain.BulkDeactivateRequest{Filter: &domain.BulkFilter{CreatorIP: "203.0.113"}}, "creator_ip"},
+		{"host with scheme", domain.BulkDeactivateRequest{Filter: &domain.BulkFilter{DestinationHost: "https://spam.example"}}, "destination_host"},
+		{"range backwards", domain.BulkDeactivateRequest{Filter: &domain.BulkFilter{CreatedFrom: &from, CreatedTo: &from}}, "created_from"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			suite, _, _, svc := setupBulkTest(t)
+
+			_, err := svc.BulkDeactivate(context.Background(), &tt.req, domain.Actor{ID: "admin:1a2b"})
+
+			var appErr *domain.AppError
+			require.ErrorAs(t, err, &appErr)
+			assert.Equal(t, http.StatusBadRequest, appErr.StatusCode)
+			assert.Contains(t, appErr.Message, tt.want)
+			suite.repo.AssertNotCalled(t, "DeactivateMatching", mock.Anything, mock.Anything)
+			suite.repo.AssertNotCalled(t, "CountActiveMatching", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestBulkDeactivate_FallsBackToSingleDeletes(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	ctx := context.Background()
+	store := cachetest.NewMemoryCache()
+	require.NoError(t, store.Set(ctx, cache.LinkKey("spam01"), "cached", time.Hour))
+	svc := service.NewURLService(suite.repo, store, suite.cfg, suite.logger)
+	want := domain.URLFilter{CreatorIP: "203.0.113.9"}
+	suite.repo.On("DeactivateMatching", ctx, want).Return([]domain.URL{{ShortCode: "spam01"}}, nil)
+
+	resp, err := svc.BulkDeactivate(ctx, &domain.BulkDeactivateRequest{Filter: &domain.BulkFilter{CreatorIP: "203.0.113.9"}}, domain.Actor{ID: "admin:1a2b"})
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), resp.Affected)
+	assert.Empty(t, store.Keys(""), "caches without batch deletes are invalidated key by key")
+}
+
+func TestBulkDeactivateHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	suite, _, _, svc := setupBulkTest(t)
+	suite.cfg.AdminAPIKey = "admin-secret"
+	suite.repo.On("ExistsManyByShortCode", mock.Anything, []string{"spam01"}).Return(map[string]bool{"spam01": true}, nil)
+	suite.repo.On("CountActiveMatching", mock.Anything, domain.URLFilter{ShortCodes: []string{"spam01"}}).Return(int64(1), nil)
+
+	router := gin.New()
+	router.POST("/api/v1/urls/bulk-delete", handler.AdminAuthMiddleware(suite.cfg), handler.NewURLHandler(svc, suite.cfg, suite.logger).BulkDeactivate)
+
+	post := func(body, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/v1/urls/bulk-delete", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-API-Key", key)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := post(`{"short_codes":["spam01"],"dry_run":true}`, "admin-secret")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resp domain.BulkDeactivateResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, int64(1), resp.Affected)
+	assert.True(t, resp.DryRun)
+
+	// A misspelt filter field must not leave an empty, match-everything filter behind
+	w = post(`{"filter":{"creator_ipp":"203.0.113.9"}}`, "admin-secret")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "creator_ipp")
+
+	w = post(`{"short_codes":["spam01"]}`, "wrong")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	suite.repo.AssertNotCalled(t, "DeactivateMatching", mock.Anything, mock.Anything)
+}
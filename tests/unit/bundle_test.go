@@ -0,0 +1,117 @@
+package unit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/cache"
+	"url-shortener/internal/domain"
+)
+
+func TestShortenURL_BundleCreatesMemberLinks(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	ctx := context.Background()
+
+	var bundle *domain.URL
+	var members []*domain.URL
+	suite.repo.On("CreateBundle", ctx, mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			bundle = args.Get(1).(*domain.URL)
+			members = args.Get(2).([]*domain.URL)
+		}).
+		Return(nil)
+
+	resp, err := suite.service.ShortenURL(ctx, &domain.CreateURLRequest{
+		Bundle: []domain.BundleItem{
+			{Title: "Pricing", URL: "https://example.com/pricing"},
+			{URL: "https://example.com/docs"},
+		},
+		UTM: &domain.UTMParams{Source: "sales"},
+	}, domain.CreatorContext{IP: "10.0.0.1"})
+
+	require.NoError(t, err)
+	require.Len(t, resp.Bundle, 2)
+	assert.Equal(t, "https://short.url/"+resp.ShortCode, bundle.OriginalURL)
+
+	// Every member is an ordinary link sharing the bundle's UTM parameters
+	require.Len(t, members, 2)
+	assert.Equal(t, "https://example.com/pricing", members[0].OriginalURL)
+	assert.Equal(t, "sales", members[1].UTM.Source)
+	assert.Equal(t, members[0].ShortCode, bundle.Bundle[0].ShortCode)
+	assert.NotEqual(t, members[0].ShortCode, members[1].ShortCode)
+	assert.NotEqual(t, resp.ShortCode, members[0].ShortCode)
+
+	// Missing titles fall back to the URL
+	assert.Equal(t, "https://example.com/docs", resp.Bundle[1].Title)
+}
+
+func TestShortenURL_BundleValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		req  *domain.CreateURLRequest
+	}{
+		{name: "invalid member URL", req: &domain.CreateURLRequest{Bundle: []domain.BundleItem{{URL: "javascript:alert(1)"}}}},
+		{name: "bundle with url", req: &domain.CreateURLRequest{URL: "https://example.com", Bundle: []domain.BundleItem{{URL: "https://example.com/a"}}}},
+		{name: "too many members", req: &domain.CreateURLRequest{Bundle: make([]domain.BundleItem, domain.MaxBundleItems+1)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			suite := setupURLServiceTest(t)
+
+			_, err := suite.service.ShortenURL(context.Background(), tt.req, domain.CreatorContext{IP: "10.0.0.1"})
+
+			assert.ErrorIs(t, err, domain.ErrInvalidURL)
+			suite.repo.AssertNotCalled(t, "CreateBundle", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestRedirect_BundleRendersLandingPage(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	router := setupRedirectRouter(suite)
+
+	bundle := &domain.URL{
+		ShortCode:   "kit",
+		OriginalURL: "https://short.url/kit",
+		IsActive:    true,
+		Bundle: domain.BundleItems{
+			{Title: "Pricing <2025>", URL: "https://example.com/pricing", ShortCode: "m1"},
+			{Title: "Docs", URL: "https://example.com/docs", ShortCode: "m2"},
+		},
+	}
+	var cached string
+	suite.cache.On("Get", mock.Anything, "kit").Return("", nil).Once()
+	suite.repo.On("FindByShortCode", mock.Anything, "kit").Return(bundle, nil)
+	suite.repo.On("IncrementClickCount", mock.Anything, "kit", mock.Anything).Return(nil)
+	suite.cache.On("Set", mock.Anything, "kit", mock.AnythingOfType("string"), mock.Anything).
+		Run(func(args mock.Arguments) { cached = args.String(2) }).
+		Return(nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/kit", nil))
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/html")
+	assert.Contains(t, w.Body.String(), `href="https://short.url/m1"`)
+	assert.Contains(t, w.Body.String(), "Pricing &lt;2025&gt;")
+	drainCacheWrites(t, suite)
+
+	// The cached entry keeps the members, so a cache hit renders the same page instead of redirecting
+	entry, ok := cache.DecodeLinkEntry(cached)
+	require.True(t, ok)
+	assert.Len(t, entry.Bundle, 2)
+
+	suite.cache.On("Get", mock.Anything, "kit").Return(cached, nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/kit", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `href="https://short.url/m2"`)
+}
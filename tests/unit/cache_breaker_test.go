@@ -0,0 +1,203 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/cache"
+)
+
+// flakyCache is a fake backend that fails on demand and counts calls
+type flakyCache struct {
+	mu      sync.Mutex
+	failing bool
+	calls   int
+	data    map[string]string
+}
+
+func newFlakyCache() *flakyCache {
+	return &flakyCache{data: make(map[string]string)}
+}
+
+func (f *flakyCache) call() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	if f.failing {
+		return errors.New("i/o timeout")
+	}
+	return nil
+}
+
+func (f *flakyCache) setFailing(failing bool) {
+	f.mu.Lock()
+	f.failing = failing
+	f.mu.Unlock()
+}
+
+func (f *flakyCache) callCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls
+}
+
+func (f *flakyCache) Set(ctx context.Context, key string, value string, ttl time.Duration) error {
+	if err := f.call(); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	f.data[key] = value
+	f.mu.Unlock()
+	return nil
+}
+
+func (f *flakyCache) Get(ctx context.Context, key string) (string, error) {
+	if err := f.call(); err != nil {
+		return "", err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.data[key], nil
+}
+
+func (f *flakyCache) Delete(ctx context.Context, key string) error {
+	if err := f.call(); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	delete(f.data, key)
+	f.mu.Unlock()
+	return nil
+}
+
+func (f *flakyCache) Exists(ctx context.Context, key string) (bool, error) {
+	return false, f.call()
+}
+
+func (f *flakyCache) Close() error {
+	return nil
+}
+
+func TestCircuitBreaker_OpensAndSkipsBackend(t *testing.T) {
+	ctx := context.Background()
+	backend := newFlakyCache()
+	now := time.Now()
+
+	var transitions []cache.BreakerState
+	breaker := cache.NewCircuitBreaker(backend, cache.BreakerConfig{
+		FailureThreshold: 3,
+		Cooldown:         30 * time.Second,
+		Now:              func() time.Time { return now },
+		OnStateChange:    func(_, to cache.BreakerState) { transitions = append(transitions, to) },
+	})
+
+	require.NoError(t, breaker.Set(ctx, "abc123", "https://example.com", time.Hour))
+
+	backend.setFailing(true)
+	for i := 0; i < 3; i++ {
+		_, err := breaker.Get(ctx, "abc123")
+		assert.Error(t, err)
+	}
+	assert.Equal(t, []cache.BreakerState{cache.StateOpen}, transitions)
+
+	// Once open, calls fail fast without touching the backend
+	calls := backend.callCount()
+	_, err := breaker.Get(ctx, "abc123")
+	assert.ErrorIs(t, err, cache.ErrCircuitOpen)
+	assert.NoError(t, breaker.Set(ctx, "abc123", "https://example.com", time.Hour), "writes are skipped silently")
+	assert.Equal(t, calls, backend.callCount())
+
+	// After the cooldown a failing probe re-opens the breaker
+	now = now.Add(31 * time.Second)
+	_, err = breaker.Get(ctx, "abc123")
+	assert.NotErrorIs(t, err, cache.ErrCircuitOpen)
+	assert.Equal(t, []cache.BreakerState{cache.StateOpen, cache.StateHalfOpen, cache.StateOpen}, transitions)
+
+	// A successful probe closes it again
+	backend.setFailing(false)
+	now = now.Add(31 * time.Second)
+	value, err := breaker.Get(ctx, "abc123")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com", value)
+	assert.Equal(t, cache.StateClosed, transitions[len(transitions)-1])
+}
+
+func TestCircuitBreaker_ServiceFallsBackToDatabase(t *testing.T) {
+	ctx := context.Background()
+	backend := newFlakyCache()
+	backend.setFailing(true)
+
+	breaker := cache.NewCircuitBreaker(backend, cache.BreakerConfig{FailureThreshold: 1, Cooldown: time.Minute})
+	_, _ = breaker.Get(ctx, "warmup") // Trip the breaker
+
+	calls := backend.callCount()
+	_, err := breaker.Get(ctx, "abc123")
+	assert.ErrorIs(t, err, cache.ErrCircuitOpen)
+	_, err = breaker.Exists(ctx, "inactive:abc123")
+	assert.ErrorIs(t, err, cache.ErrCircuitOpen)
+	assert.Equal(t, calls, backend.callCount())
+}
+
+func TestCircuitBreaker_ReplaysMissedDeletes(t *testing.T) {
+	ctx := context.Background()
+	backend := newFlakyCache()
+	now := time.Now()
+	breaker := cache.NewCircuitBreaker(backend, cache.BreakerConfig{
+		FailureThreshold: 1,
+		Cooldown:         30 * time.Second,
+		Now:              func() time.Time { return now },
+	})
+	require.NoError(t, breaker.Set(ctx, "abc123", "https://old.example", time.Hour))
+	require.NoError(t, breaker.Set(ctx, "other1", "https://other.example", time.Hour))
+
+	backend.setFailing(true)
+	_, _ = breaker.Get(ctx, "warmup") // Trip the breaker
+	assert.ErrorIs(t, breaker.Delete(ctx, "abc123"), cache.ErrCircuitOpen, "the caller still learns the delete is late")
+
+	// A probe that fails replaying keeps the delete queued
+	now = now.Add(31 * time.Second)
+	_, err := breaker.Get(ctx, "abc123")
+	assert.ErrorIs(t, err, cache.ErrCircuitOpen)
+
+	backend.setFailing(false)
+	now = now.Add(31 * time.Second)
+	value, err := breaker.Get(ctx, "abc123")
+	require.NoError(t, err)
+	assert.Empty(t, value, "the link changed during the outage must miss")
+
+	value, err = breaker.Get(ctx, "other1")
+	require.NoError(t, err)
+	assert.Equal(t, "https://other.example", value, "only the missed keys are deleted")
+}
+
+func TestCircuitBreaker_CanceledCallsDontCount(t *testing.T) {
+	ctx := context.Background()
+	backend := &cancelingCache{flakyCache: newFlakyCache(), canceled: true}
+
+	breaker := cache.NewCircuitBreaker(backend, cache.BreakerConfig{FailureThreshold: 1, Cooldown: time.Minute})
+	_, err := breaker.Get(ctx, "abc123")
+	assert.ErrorIs(t, err, context.Canceled)
+
+	backend.canceled = false
+	_, err = breaker.Get(ctx, "abc123")
+	assert.NoError(t, err)
+}
+
+// cancelingCache simulates a caller whose context was canceled
+type cancelingCache struct {
+	*flakyCache
+	canceled bool
+}
+
+func (c *cancelingCache) Get(ctx context.Context, key string) (string, error) {
+	if c.canceled {
+		return "", context.Canceled
+	}
+	return c.flakyCache.Get(ctx, key)
+}
@@ -0,0 +1,14 @@
+package unit
+
+import (
+	"testing"
+
+	"url-shortener/internal/cache"
+	"url-shortener/internal/cache/cachetest"
+)
+
+func TestMemoryCacheContract(t *testing.T) {
+	cachetest.RunCache(t, func(t *testing.T) cache.Cache {
+		return cachetest.NewMemoryCache()
+	})
+}
@@ -0,0 +1,166 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/cache"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/handler"
+	"url-shortener/internal/service"
+)
+
+// MockFlushableCache is a MockCache that also supports namespace flushes
+type MockFlushableCache struct {
+	MockCache
+}
+
+func (m *MockFlushableCache) FlushNamespace(ctx context.Context, keysPerSecond int) (int64, error) {
+	args := m.Called(ctx, keysPerSecond)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func TestKeyPrefix_Versioned(t *testing.T) {
+	assert.Equal(t, "urlshortener:v2:", cache.KeyPrefix(""))
+	assert.Equal(t, "staging:v2:", cache.KeyPrefix("staging"))
+	assert.Equal(t, "inactive:abc123", cache.InactiveKey("abc123"))
+	assert.Equal(t, "dedup-ref:abc123", cache.DedupRefKey("abc123"))
+}
+
+func TestDedupKey_BoundedLength(t *testing.T) {
+	long := cache.DedupKey("https://example.com/?q=" + strings.Repeat("x", 4096))
+
+	assert.True(t, strings.HasPrefix(long, "dedup:"))
+	assert.Len(t, long, len("dedup:")+32)
+	assert.NotEqual(t, cache.DedupKey("https://example.com/a"), cache.DedupKey("https://example.com/b"))
+}
+
+func TestLinkEntry_RoundTrip(t *testing.T) {
+	plain := cache.NewLinkEntry(&domain.URL{OriginalURL: "https://example.com", IsActive: true, UTM: domain.UTMParams{Source: "ads"}})
+	assert.Equal(t, "https://example.com?utm_source=ads", plain.Encode(), "plain links stay a bare URL")
+
+	ruled := cache.NewLinkEntry(&domain.URL{
+		OriginalURL: "https://example.com",
+		Targets:     domain.Targets{{Platform: "ios", URL: "https://apps.apple.com/app"}},
+	})
+	decoded, ok := cache.DecodeLinkEntry(ruled.Encode())
+	require.True(t, ok)
+	assert.Equal(t, 2, decoded.Version)
+	assert.True(t, decoded.Conditional())
+	assert.Equal(t, "https://apps.apple.com/app", decoded.Targets[0].URL)
+}
+
+func TestDecodeLinkEntry_ForwardCompatible(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		url   string
+		ok    bool
+	}{
+		{name: "plain URL", value: "https://example.com/page", url: "https://example.com/page", ok: true},
+		{name: "unknown fields from a newer release", value: `{"v":3,"url":"https://example.com","pinned":true}`, url: "https://example.com", ok: true},
+		{name: "JSON without url", value: `{"v":2}`, ok: false},
+		{name: "truncated JSON", value: `{"url":"https://exa`, ok: false},
+		{name: "empty", value: "", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry, ok := cache.DecodeLinkEntry(tt.value)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.url, entry.URL)
+		})
+	}
+}
+
+func TestResolve_CacheTTLCappedAtExpiry(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	ctx := context.Background()
+
+	expires := time.Now().Add(10 * time.Minute)
+	suite.cache.On("Get", ctx, "soon").Return("", nil)
+	suite.repo.On("FindByShortCode", ctx, "soon").
+		Return(&domain.URL{ShortCode: "soon", OriginalURL: "https://example.com", ExpiresAt: &expires, IsActive: true}, nil)
+	suite.repo.On("IncrementClickCount", ctx, "soon", mock.Anything).Return(nil)
+	suite.cache.On("Set", mock.Anything, "soon", mock.MatchedBy(func(value string) bool {
+		entry, ok := cache.DecodeLinkEntry(value)
+		return ok && entry.URL == "https://example.com" && entry.ExpiresAt != nil && entry.ExpiresAt.Equal(expires)
+	}), mock.MatchedBy(func(ttl time.Duration) bool {
+		return ttl > 9*time.Minute && ttl <= 10*time.Minute
+	})).Return(nil)
+
+	_, err := suite.service.GetOriginalURL(ctx, "soon", domain.Visitor{})
+
+	require.NoError(t, err)
+	drainCacheWrites(t, suite)
+	suite.cache.AssertExpectations(t)
+}
+
+func TestFlushCache_Admin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	suite := setupURLServiceTest(t)
+	suite.cfg.AdminAPIKey = "admin-secret"
+	suite.cfg.CacheNamespace = "urlshortener"
+	suite.cfg.CacheFlushRate = 1000
+
+	flushable := new(MockFlushableCache)
+	flushable.On("FlushNamespace", mock.Anything, 1000).Return(int64(1234), nil)
+
+	audit := new(MockAuditRepository)
+	audit.On("Record", mock.Anything, mock.MatchedBy(func(entry *domain.AuditEntry) bool {
+		return entry.Action == domain.AuditActionCacheFlush && entry.Details == "namespace=urlshortener deleted=1234"
+	})).Return(nil)
+
+	svc := service.NewURLService(suite.repo, flushable, suite.cfg, suite.logger, service.WithAuditRepository(audit))
+	router := gin.New()
+	router.POST("/api/v1/admin/cache/flush", handler.AdminAuthMiddleware(suite.cfg), handler.NewURLHandler(svc, suite.cfg, suite.logger).FlushCache)
+
+	req := httptest.NewRequest("POST", "/api/v1/admin/cache/flush", nil)
+	req.Header.Set("X-API-Key", "admin-secret")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var body struct {
+		Namespace string `json:"namespace"`
+		Deleted   int64  `json:"deleted"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, int64(1234), body.Deleted)
+	audit.AssertExpectations(t)
+}
+
+func TestFlushCache_Unsupported(t *testing.T) {
+	suite := setupURLServiceTest(t)
+
+	// The plain mock cache has no FlushNamespace method
+	_, err := suite.service.FlushCache(context.Background(), domain.Actor{ID: "admin:test"})
+
+	var appErr *domain.AppError
+	require.True(t, errors.As(err, &appErr))
+	assert.Equal(t, http.StatusConflict, appErr.StatusCode)
+	assert.ErrorIs(t, err, cache.ErrFlushUnsupported)
+}
+
+func TestCircuitBreaker_ForwardsFlush(t *testing.T) {
+	flushable := new(MockFlushableCache)
+	flushable.On("FlushNamespace", mock.Anything, 500).Return(int64(7), nil)
+
+	deleted, err := cache.NewCircuitBreaker(flushable, cache.BreakerConfig{}).(cache.Flusher).FlushNamespace(context.Background(), 500)
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), deleted)
+
+	_, err = cache.NewCircuitBreaker(newFlakyCache(), cache.BreakerConfig{}).(cache.Flusher).FlushNamespace(context.Background(), 500)
+	assert.ErrorIs(t, err, cache.ErrFlushUnsupported)
+}
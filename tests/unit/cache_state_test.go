@@ -0,0 +1,92 @@
+package unit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/cache"
+	"url-shortener/internal/cache/cachetest"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/handler"
+	"url-shortener/internal/service"
+)
+
+func TestLinkEntry_CarriesState(t *testing.T) {
+	expires := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	entry, ok := cache.DecodeLinkEntry(cache.NewLinkEntry(&domain.URL{OriginalURL: "https://example.com", IsActive: true, ExpiresAt: &expires}).Encode())
+	require.True(t, ok)
+	require.NotNil(t, entry.ExpiresAt, "an expiring link is no longer a bare URL")
+	assert.True(t, expires.Equal(*entry.ExpiresAt))
+	assert.False(t, entry.Expired(expires))
+	assert.True(t, entry.Expired(expires.Add(time.Second)))
+	assert.False(t, entry.Inactive)
+
+	entry, ok = cache.DecodeLinkEntry(cache.NewLinkEntry(&domain.URL{OriginalURL: "https://example.com"}).Encode())
+	require.True(t, ok)
+	assert.True(t, entry.Inactive)
+
+	// Entries written before the state was cached were only ever written for active links
+	entry, ok = cache.DecodeLinkEntry(`{"v":2,"url":"https://example.com","forward_query":true}`)
+	require.True(t, ok)
+	assert.False(t, entry.Inactive)
+	assert.False(t, entry.Expired(time.Now()))
+}
+
+// redirectVia serves one redirect for abc123 from a service over store
+func redirectVia(suite *URLServiceTestSuite, store cache.Cache) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	svc := service.NewURLService(suite.repo, store, suite.cfg, suite.logger)
+	router := gin.New()
+	router.GET("/:shortCode", handler.NewURLHandler(svc, suite.cfg, suite.logger).RedirectURL)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/abc123", nil))
+	return w
+}
+
+func TestRedirect_CacheHitAnswersLikeDatabase(t *testing.T) {
+	expired := time.Now().Add(-time.Minute)
+
+	tests := []struct {
+		name   string
+		url    *domain.URL
+		dbErr  error
+		status int
+	}{
+		{"expired", &domain.URL{ShortCode: "abc123", OriginalURL: "https://example.com", IsActive: true, ExpiresAt: &expired}, nil, http.StatusGone},
+		{"inactive", &domain.URL{ShortCode: "abc123", OriginalURL: "https://example.com"}, domain.ErrURLNotFound, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			suite := setupURLServiceTest(t)
+			if tt.dbErr != nil {
+				suite.repo.On("FindByShortCode", mock.Anything, "abc123").Return(nil, tt.dbErr)
+			} else {
+				suite.repo.On("FindByShortCode", mock.Anything, "abc123").Return(tt.url, nil)
+			}
+
+			// A stale entry the cache kept longer than it should have
+			warm := cachetest.NewMemoryCache()
+			require.NoError(t, warm.Set(context.Background(), cache.LinkKey("abc123"), cache.NewLinkEntry(tt.url).Encode(), time.Hour))
+
+			fromCache := redirectVia(suite, warm)
+			fromDB := redirectVia(suite, cachetest.NewMemoryCache())
+
+			assert.Equal(t, tt.status, fromCache.Code)
+			assert.Equal(t, fromDB.Code, fromCache.Code)
+			assert.JSONEq(t, fromDB.Body.String(), fromCache.Body.String(), "the error body doesn't depend on the cache")
+			suite.repo.AssertNumberOfCalls(t, "FindByShortCode", 1)
+			suite.repo.AssertNotCalled(t, "IncrementClickCount", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
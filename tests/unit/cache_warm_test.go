@@ -0,0 +1,104 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/cache"
+	"url-shortener/internal/cache/cachetest"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/service"
+)
+
+// batchSetCache is a memory cache that also stores keys in batches and records the TTL of each
+type batchSetCache struct {
+	*cachetest.MemoryCache
+	ttls []time.Duration
+}
+
+func (c *batchSetCache) SetMultiple(ctx context.Context, items map[string]string, ttl time.Duration) error {
+	c.ttls = append(c.ttls, ttl)
+	for key, value := range items {
+		_ = c.Set(ctx, key, value, ttl)
+	}
+	return nil
+}
+
+// hotLinks are the most clicked links: two plain ones, one expiring and one behind the interstitial
+func hotLinks() []domain.URL {
+	expires := time.Now().Add(20 * time.Minute)
+	created := time.Now().AddDate(0, 0, -30)
+	return []domain.URL{
+		{ShortCode: "hot001", OriginalURL: "https://example.com/1", IsActive: true, ClickCount: 900, CreatedAt: created},
+		{ShortCode: "hot002", OriginalURL: "https://example.com/2", IsActive: true, ClickCount: 800, CreatedAt: created, ExpiresAt: &expires},
+		{ShortCode: "hot003", OriginalURL: "https://example.com/3", IsActive: true, ClickCount: 700, CreatedAt: created, RequiresInterstitial: true},
+		{ShortCode: "hot004", OriginalURL: "https://example.com/4", IsActive: true, ClickCount: 600, CreatedAt: created},
+	}
+}
+
+func TestWarmCache_BatchesByExpiryCappedTTL(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	store := &batchSetCache{MemoryCache: cachetest.NewMemoryCache()}
+	svc := service.NewURLService(suite.repo, store, suite.cfg, suite.logger)
+	suite.repo.On("TopByClickCount", mock.Anything, 100).Return(hotLinks(), nil)
+
+	warmed, err := svc.WarmCache(context.Background(), 100)
+
+	require.NoError(t, err)
+	assert.Equal(t, 3, warmed, "the interstitial link is left to the database path")
+	assert.Len(t, store.ttls, 2, "one batch for the links without expiry, one for the expiring link")
+	assert.Equal(t, time.Hour, store.TTL(cache.LinkKey("hot001")))
+	assert.InDelta(t, (20 * time.Minute).Seconds(), store.TTL(cache.LinkKey("hot002")).Seconds(), 5, "never cached past the link's expiry")
+	assert.Empty(t, store.Keys(cache.LinkKey("hot003")))
+
+	value, err := store.Get(context.Background(), cache.LinkKey("hot004"))
+	require.NoError(t, err)
+	entry, ok := cache.DecodeLinkEntry(value)
+	require.True(t, ok)
+	assert.Equal(t, "https://example.com/4", entry.URL)
+}
+
+func TestWarmCache_FallsBackToSingleSets(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	suite.cfg.CacheStaleTTL = 30 * time.Minute
+	store := cachetest.NewMemoryCache()
+	svc := service.NewURLService(suite.repo, store, suite.cfg, suite.logger)
+	suite.repo.On("TopByClickCount", mock.Anything, 10).Return(hotLinks()[:1], nil)
+
+	warmed, err := svc.WarmCache(context.Background(), 10)
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, warmed)
+	assert.InDelta(t, (90 * time.Minute).Seconds(), store.TTL(cache.LinkKey("hot001")).Seconds(), 5, "kept for the stale window too")
+}
+
+func TestWarmCache_StopsWithContextAndErrors(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	store := cachetest.NewMemoryCache()
+	svc := service.NewURLService(suite.repo, store, suite.cfg, suite.logger)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	suite.repo.On("TopByClickCount", ctx, 10).Return(hotLinks(), nil)
+	warmed, err := svc.WarmCache(ctx, 10)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Zero(t, warmed)
+	assert.Empty(t, store.Keys(""))
+
+	dbErr := errors.New("connection refused")
+	suite.repo.On("TopByClickCount", context.Background(), 20).Return(nil, dbErr)
+	_, err = svc.WarmCache(context.Background(), 20)
+	assert.ErrorIs(t, err, dbErr)
+
+	// Disabled, nothing is queried
+	warmed, err = svc.WarmCache(context.Background(), 0)
+	require.NoError(t, err)
+	assert.Zero(t, warmed)
+	suite.repo.AssertNumberOfCalls(t, "TopByClickCount", 2)
+}
@@ -0,0 +1,156 @@
+package unit
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/cache"
+	"url-shortener/internal/cache/cachetest"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/metrics"
+	"url-shortener/internal/service"
+)
+
+// gatedCache is an in-memory cache whose writes hang until release is closed, like a slow Redis
+type gatedCache struct {
+	*cachetest.MemoryCache
+	started chan string // Receives the key of every write as it begins
+	release chan struct{}
+
+	mu     sync.Mutex
+	writes map[string]int
+}
+
+func newGatedCache() *gatedCache {
+	return &gatedCache{
+		MemoryCache: cachetest.NewMemoryCache(),
+		started:     make(chan string, 64),
+		release:     make(chan struct{}),
+		writes:      make(map[string]int),
+	}
+}
+
+func (c *gatedCache) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	c.started <- key
+	<-c.release
+
+	c.mu.Lock()
+	c.writes[key]++
+	c.mu.Unlock()
+	return c.MemoryCache.Set(ctx, key, value, ttl)
+}
+
+func (c *gatedCache) writesTo(key string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.writes[key]
+}
+
+// setupCacheWriterTest builds a service on a gated cache; the writer is held up in the write for "held"
+// until the test closes release
+func setupCacheWriterTest(t *testing.T, queueSize int, codes ...string) (*URLServiceTestSuite, *gatedCache) {
+	suite := setupURLServiceTest(t)
+	suite.cfg.CacheWriteQueueSize = queueSize
+	store := newGatedCache()
+	suite.service = service.NewURLService(suite.repo, store, suite.cfg, suite.logger)
+
+	for _, code := range append([]string{"held"}, codes...) {
+		suite.repo.On("FindByShortCode", mock.Anything, code).
+			Return(&domain.URL{ShortCode: code, OriginalURL: "https://example.com/" + code, IsActive: true}, nil)
+		suite.repo.On("IncrementClickCount", mock.Anything, code, mock.Anything).Return(nil)
+	}
+
+	_, err := suite.service.GetOriginalURL(context.Background(), "held", domain.Visitor{})
+	require.NoError(t, err)
+	select {
+	case <-store.started:
+	case <-time.After(time.Second):
+		t.Fatal("the writer never picked up the first entry")
+	}
+	return suite, store
+}
+
+func TestCacheWrites_SlowCacheDoesNotDelayResponses(t *testing.T) {
+	suite, store := setupCacheWriterTest(t, 16, "abc123")
+
+	start := time.Now()
+	destination, err := suite.service.GetOriginalURL(context.Background(), "abc123", domain.Visitor{})
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/abc123", destination)
+	assert.Less(t, time.Since(start), 500*time.Millisecond, "the response doesn't wait for the hanging write")
+
+	close(store.release)
+	drainCacheWrites(t, suite)
+	exists, _ := store.Exists(context.Background(), cache.LinkKey("abc123"))
+	assert.True(t, exists, "queued entries are written before shutdown completes")
+}
+
+func TestCacheWrites_DuplicateKeysCoalesce(t *testing.T) {
+	suite, store := setupCacheWriterTest(t, 16, "abc123")
+
+	for i := 0; i < 5; i++ {
+		_, err := suite.service.GetOriginalURL(context.Background(), "abc123", domain.Visitor{})
+		require.NoError(t, err)
+	}
+
+	close(store.release)
+	drainCacheWrites(t, suite)
+	assert.Equal(t, 1, store.writesTo(cache.LinkKey("abc123")), "five queued writes of one key become one")
+}
+
+func TestCacheWrites_OverflowDropsOldest(t *testing.T) {
+	suite, store := setupCacheWriterTest(t, 2, "first", "second", "third")
+	dropped := testutil.ToFloat64(metrics.CacheWritesDropped)
+
+	for _, code := range []string{"first", "second", "third"} {
+		_, err := suite.service.GetOriginalURL(context.Background(), code, domain.Visitor{})
+		require.NoError(t, err)
+	}
+
+	close(store.release)
+	drainCacheWrites(t, suite)
+	assert.Equal(t, dropped+1, testutil.ToFloat64(metrics.CacheWritesDropped))
+	assert.Zero(t, store.writesTo(cache.LinkKey("first")), "the oldest entry makes room")
+	assert.Equal(t, 1, store.writesTo(cache.LinkKey("second")))
+	assert.Equal(t, 1, store.writesTo(cache.LinkKey("third")))
+}
+
+func TestCacheWrites_InvalidationCancelsQueuedWrite(t *testing.T) {
+	suite, store := setupCacheWriterTest(t, 16, "abc123")
+	suite.repo.On("Delete", mock.Anything, "abc123").Return(nil)
+
+	_, err := suite.service.GetOriginalURL(context.Background(), "abc123", domain.Visitor{})
+	require.NoError(t, err)
+	require.NoError(t, suite.service.DeleteURL(context.Background(), "abc123"))
+
+	close(store.release)
+	drainCacheWrites(t, suite)
+	assert.Zero(t, store.writesTo(cache.LinkKey("abc123")), "a deleted link's entry is never written back")
+}
+
+func TestCacheWrites_InvalidationWaitsForWriteInProgress(t *testing.T) {
+	suite, store := setupCacheWriterTest(t, 16)
+	suite.repo.On("Delete", mock.Anything, "held").Return(nil)
+
+	// The entry of "held" is already inside Set when the link is deleted
+	deleted := make(chan error, 1)
+	go func() { deleted <- suite.service.DeleteURL(context.Background(), "held") }()
+	select {
+	case <-deleted:
+		t.Fatal("the delete didn't wait for the write in progress")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(store.release)
+	require.NoError(t, <-deleted)
+	drainCacheWrites(t, suite)
+	exists, _ := store.Exists(context.Background(), cache.LinkKey("held"))
+	assert.False(t, exists, "the write finished before the invalidation deleted the entry")
+}
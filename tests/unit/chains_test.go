@@ -0,0 +1,177 @@
+package unit
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/service"
+	"url-shortener/internal/unshorten"
+)
+
+// redirectTable is a transport answering each URL with a redirect to its entry, or 200 when absent
+type redirectTable map[string]string
+
+func (t redirectTable) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: io.NopCloser(strings.NewReader("")), Request: req}
+	if location, ok := t[req.URL.String()]; ok {
+		resp.StatusCode = http.StatusMovedPermanently
+		resp.Header.Set("Location", location)
+	}
+	return resp, nil
+}
+
+// stubResolver resolves every URL to a fixed destination or error
+type stubResolver struct {
+	final string
+	err   error
+}
+
+func (r stubResolver) Resolve(context.Context, string) (string, error) {
+	return r.final, r.err
+}
+
+func resolveWith(table redirectTable, rawURL string) (string, error) {
+	return unshorten.NewHTTPResolverWithTransport(table, time.Second).Resolve(context.Background(), rawURL)
+}
+
+func TestHTTPResolver_FollowsChain(t *testing.T) {
+	final, err := resolveWith(redirectTable{
+		"https://bit.ly/abc":                "https://tinyurl.com/xyz",
+		"https://tinyurl.com/xyz":           "/landing?src=x", // Relative to the tinyurl host
+		"https://tinyurl.com/landing?src=x": "https://example.com/page",
+	}, "https://bit.ly/abc")
+
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/page", final)
+}
+
+func TestHTTPResolver_NotARedirect(t *testing.T) {
+	final, err := resolveWith(redirectTable{}, "https://example.com/page")
+
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/page", final)
+}
+
+func TestHTTPResolver_DetectsLoop(t *testing.T) {
+	_, err := resolveWith(redirectTable{
+		"https://bit.ly/a":      "https://tinyurl.com/b",
+		"https://tinyurl.com/b": "https://bit.ly/a",
+	}, "https://bit.ly/a")
+
+	assert.ErrorIs(t, err, unshorten.ErrRedirectLoop)
+}
+
+func TestHTTPResolver_ChainLength(t *testing.T) {
+	// chain builds hop0 -> hop1 -> ... -> hopN, where hopN is the final page
+	chain := func(redirects int) redirectTable {
+		table := redirectTable{}
+		for i := 0; i < redirects; i++ {
+			table[fmt.Sprintf("https://bit.ly/hop%d", i)] = fmt.Sprintf("https://bit.ly/hop%d", i+1)
+		}
+		return table
+	}
+
+	final, err := resolveWith(chain(unshorten.MaxHops), "https://bit.ly/hop0")
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("https://bit.ly/hop%d", unshorten.MaxHops), final)
+
+	_, err = resolveWith(chain(unshorten.MaxHops+1), "https://bit.ly/hop0")
+	assert.ErrorIs(t, err, unshorten.ErrTooManyHops)
+}
+
+func TestHTTPResolver_RejectsNonHTTPRedirect(t *testing.T) {
+	_, err := resolveWith(redirectTable{"https://bit.ly/a": "javascript:alert(1)"}, "https://bit.ly/a")
+
+	assert.Error(t, err)
+}
+
+func TestMatchesDomain(t *testing.T) {
+	domains := []string{"bit.ly", "t.co"}
+
+	assert.True(t, unshorten.MatchesDomain("bit.ly", domains))
+	assert.True(t, unshorten.MatchesDomain("BIT.LY:443", domains))
+	assert.True(t, unshorten.MatchesDomain("j.bit.ly", domains))
+	assert.False(t, unshorten.MatchesDomain("habit.ly", domains))
+	assert.False(t, unshorten.MatchesDomain("t.com", domains))
+}
+
+func TestShortenURL_RefusesShortenerLinks(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	suite.cfg.ShortenerDomains = []string{"bit.ly"}
+
+	for _, link := range []string{"https://bit.ly/abc", "https://short.url/abc123"} {
+		_, err := suite.service.ShortenURL(context.Background(), &domain.CreateURLRequest{URL: link}, domain.CreatorContext{IP: "192.168.1.1"})
+
+		var appErr *domain.AppError
+		require.ErrorAs(t, err, &appErr, link)
+		assert.Equal(t, http.StatusBadRequest, appErr.StatusCode)
+		assert.Contains(t, appErr.Message, "already a short link")
+	}
+	suite.repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+// chainSuite rebuilds the service with resolver and bit.ly as a known shortener
+func chainSuite(t *testing.T, resolver unshorten.Resolver) *URLServiceTestSuite {
+	suite := setupURLServiceTest(t)
+	suite.cfg.ShortenerDomains = []string{"bit.ly"}
+	suite.service = service.NewURLService(suite.repo, suite.cache, suite.cfg, suite.logger, service.WithChainResolver(resolver))
+	return suite
+}
+
+func TestShortenURL_ResolvesChainAndKeepsSubmittedURL(t *testing.T) {
+	suite := chainSuite(t, stubResolver{final: "https://Example.com/final/"})
+	ctx := context.Background()
+
+	suite.repo.On("FindByOriginalURL", ctx, "https://example.com/final").Return((*domain.URL)(nil), domain.ErrURLNotFound)
+	suite.repo.On("Create", ctx, mock.MatchedBy(func(u *domain.URL) bool {
+		return u.OriginalURL == "https://example.com/final" && u.SubmittedURL != nil && *u.SubmittedURL == "https://bit.ly/abc"
+	})).Return(nil).Once()
+	suite.cache.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	resp, err := suite.service.ShortenURL(ctx, &domain.CreateURLRequest{URL: "https://bit.ly/abc"}, domain.CreatorContext{IP: "192.168.1.1"})
+
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/final", resp.OriginalURL)
+	suite.repo.AssertExpectations(t)
+}
+
+func TestShortenURL_ChainFailuresAreValidationErrors(t *testing.T) {
+	for name, resolver := range map[string]stubResolver{
+		"loop":              {err: unshorten.ErrRedirectLoop},
+		"too long":          {err: unshorten.ErrTooManyHops},
+		"ends on shortener": {final: "https://bit.ly/not-found"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			suite := chainSuite(t, resolver)
+
+			_, err := suite.service.ShortenURL(context.Background(), &domain.CreateURLRequest{URL: "https://bit.ly/abc"}, domain.CreatorContext{IP: "192.168.1.1"})
+
+			var appErr *domain.AppError
+			require.ErrorAs(t, err, &appErr)
+			assert.Equal(t, http.StatusBadRequest, appErr.StatusCode)
+			suite.repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestShortenURL_RefusesShortenerBundleMembers(t *testing.T) {
+	suite := chainSuite(t, stubResolver{final: "https://example.com"})
+
+	_, err := suite.service.ShortenURL(context.Background(), &domain.CreateURLRequest{
+		Bundle: []domain.BundleItem{{URL: "https://example.com/a"}, {URL: "https://bit.ly/b"}},
+	}, domain.CreatorContext{IP: "192.168.1.1"})
+
+	var appErr *domain.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Contains(t, appErr.Message, "bundle item 1")
+}
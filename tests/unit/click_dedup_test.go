@@ -0,0 +1,116 @@
+package unit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/cache"
+	"url-shortener/internal/cache/cachetest"
+	"url-shortener/internal/config"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/metrics"
+	"url-shortener/internal/service"
+)
+
+// claimCache is a MemoryCache that can also set keys only when missing
+type claimCache struct {
+	*cachetest.MemoryCache
+}
+
+func (c *claimCache) SetIfAbsent(ctx context.Context, key, value string, ttl time.Duration) (bool, error) {
+	if exists, _ := c.Exists(ctx, key); exists {
+		return false, nil
+	}
+	return true, c.Set(ctx, key, value, ttl)
+}
+
+// setupDedupTest serves one link through store with a 30 second dedup window
+func setupDedupTest(t *testing.T, store cache.Cache) *URLServiceTestSuite {
+	suite := setupURLServiceTest(t)
+	suite.cfg.ClickDedupWindow = 30 * time.Second
+	suite.service = service.NewURLService(suite.repo, store, suite.cfg, suite.logger)
+	suite.repo.On("FindByShortCode", mock.Anything, "spam01").
+		Return(&domain.URL{ShortCode: "spam01", OriginalURL: "https://example.com/offer", IsActive: true}, nil)
+	return suite
+}
+
+func TestClickDedup_RepeatClicksFiltered(t *testing.T) {
+	store := &claimCache{cachetest.NewMemoryCache()}
+	suite := setupDedupTest(t, store)
+	suite.repo.On("IncrementClickCount", mock.Anything, "spam01", mock.Anything).Return(nil).Times(3)
+	suite.repo.On("IncrementFilteredClickCount", mock.Anything, "spam01").Return(nil).Times(2)
+	before := testutil.ToFloat64(metrics.FilteredClicks)
+
+	spammer := domain.Visitor{IP: "203.0.113.7", UserAgent: browserUA}
+	visitors := []domain.Visitor{
+		spammer, spammer, spammer,
+		{IP: "203.0.113.7", UserAgent: "curl/8.4.0"}, // Same IP, other client: counted
+		{IP: "198.51.100.2", UserAgent: browserUA},
+	}
+	for _, visitor := range visitors {
+		decision, err := suite.service.PrepareRedirect(context.Background(), "spam01", visitor)
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com/offer", decision.OriginalURL, "filtered clicks still redirect")
+	}
+	drainCacheWrites(t, suite)
+
+	suite.repo.AssertExpectations(t)
+	assert.Equal(t, float64(2), testutil.ToFloat64(metrics.FilteredClicks)-before)
+	assert.Equal(t, 30*time.Second, store.TTL(cache.ClickDedupKey("spam01", "203.0.113.7", browserUA)))
+}
+
+func TestClickDedup_EveryClickCountsWithoutClaims(t *testing.T) {
+	suite := setupDedupTest(t, cachetest.NewMemoryCache())
+	suite.repo.On("IncrementClickCount", mock.Anything, "spam01", mock.Anything).Return(nil).Times(3)
+
+	for i := 0; i < 3; i++ {
+		_, err := suite.service.PrepareRedirect(context.Background(), "spam01", domain.Visitor{IP: "203.0.113.7", UserAgent: browserUA})
+		require.NoError(t, err)
+	}
+	drainCacheWrites(t, suite)
+
+	suite.repo.AssertExpectations(t)
+	suite.repo.AssertNotCalled(t, "IncrementFilteredClickCount", mock.Anything, mock.Anything)
+}
+
+func TestClickDedupKey_HidesVisitor(t *testing.T) {
+	key := cache.ClickDedupKey("spam01", "203.0.113.7", browserUA)
+	assert.Contains(t, key, "spam01")
+	assert.NotContains(t, key, "203.0.113.7")
+	assert.NotEqual(t, key, cache.ClickDedupKey("spam01", "203.0.113.7", "curl/8.4.0"))
+	assert.NotEqual(t, key, cache.ClickDedupKey("spam02", "203.0.113.7", browserUA))
+}
+
+func TestCircuitBreaker_ForwardsSetIfAbsent(t *testing.T) {
+	ctx := context.Background()
+	breaker := cache.NewCircuitBreaker(&claimCache{cachetest.NewMemoryCache()}, cache.BreakerConfig{})
+	claimer, ok := breaker.(cache.Claimer)
+	require.True(t, ok)
+
+	first, err := claimer.SetIfAbsent(ctx, "k", "1", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, first)
+	first, err = claimer.SetIfAbsent(ctx, "k", "1", time.Minute)
+	require.NoError(t, err)
+	assert.False(t, first)
+
+	_, err = cache.NewCircuitBreaker(cachetest.NewMemoryCache(), cache.BreakerConfig{}).(cache.Claimer).SetIfAbsent(ctx, "k", "1", time.Minute)
+	assert.ErrorIs(t, err, cache.ErrClaimUnsupported)
+}
+
+func TestConfig_ClickDedupWindow(t *testing.T) {
+	t.Setenv("CLICK_DEDUP_WINDOW", "30s")
+	cfg, err := config.LoadFrom(nil)
+	require.NoError(t, err)
+	assert.Equal(t, 30*time.Second, cfg.ClickDedupWindow)
+
+	t.Setenv("CLICK_DEDUP_WINDOW", "-5s")
+	_, err = config.LoadFrom(nil)
+	assert.ErrorContains(t, err, "CLICK_DEDUP_WINDOW")
+}
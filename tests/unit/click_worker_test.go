@@ -0,0 +1,106 @@
+package unit
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/service"
+)
+
+func TestClose_FlushesQueuedClicks(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	ctx := context.Background()
+
+	const hits = 50
+	var persisted int64
+
+	suite.cache.On("Get", ctx, mock.AnythingOfType("string")).
+		Return("https://example.com/cached", nil)
+	// Slow writes keep clicks queued until Close drains them
+	suite.repo.On("IncrementClickCount", mock.Anything, mock.AnythingOfType("string"), mock.Anything).
+		Run(func(mock.Arguments) {
+			time.Sleep(time.Millisecond)
+			atomic.AddInt64(&persisted, 1)
+		}).
+		Return(nil)
+
+	for i := 0; i < hits; i++ {
+		_, err := suite.service.GetOriginalURL(ctx, fmt.Sprintf("code%d", i), domain.Visitor{})
+		assert.NoError(t, err)
+	}
+
+	closeCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	assert.NoError(t, suite.service.Close(closeCtx))
+	assert.Equal(t, int64(hits), atomic.LoadInt64(&persisted))
+}
+
+func TestClose_RecordsInlineAfterShutdown(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	ctx := context.Background()
+
+	assert.NoError(t, suite.service.Close(ctx))
+
+	suite.cache.On("Get", ctx, "abc123").
+		Return("https://example.com/cached", nil)
+	suite.repo.On("IncrementClickCount", mock.Anything, "abc123", mock.Anything).
+		Return(nil).Once()
+
+	_, err := suite.service.GetOriginalURL(ctx, "abc123", domain.Visitor{})
+
+	// The click is written before GetOriginalURL returns since the worker is gone
+	assert.NoError(t, err)
+	suite.repo.AssertExpectations(t)
+}
+
+func TestClose_ReturnsWhenDeadlineExpires(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	ctx := context.Background()
+
+	release := make(chan struct{})
+	defer close(release)
+
+	suite.cache.On("Get", ctx, "abc123").
+		Return("https://example.com/cached", nil)
+	suite.repo.On("IncrementClickCount", mock.Anything, "abc123", mock.Anything).
+		Run(func(mock.Arguments) { <-release }).
+		Return(nil)
+
+	_, err := suite.service.GetOriginalURL(ctx, "abc123", domain.Visitor{})
+	assert.NoError(t, err)
+
+	closeCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+
+	assert.ErrorIs(t, suite.service.Close(closeCtx), context.DeadlineExceeded)
+}
+
+func TestClose_FallsBackWhenQueueFull(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	suite.cfg.ClickQueueSize = 1
+	svc := service.NewURLService(suite.repo, suite.cache, suite.cfg, suite.logger)
+	ctx := context.Background()
+
+	var persisted int64
+	suite.cache.On("Get", ctx, "abc123").
+		Return("https://example.com/cached", nil)
+	suite.repo.On("IncrementClickCount", mock.Anything, "abc123", mock.Anything).
+		Run(func(mock.Arguments) { atomic.AddInt64(&persisted, 1) }).
+		Return(nil)
+
+	for i := 0; i < 10; i++ {
+		_, err := svc.GetOriginalURL(ctx, "abc123", domain.Visitor{})
+		assert.NoError(t, err)
+	}
+
+	assert.NoError(t, svc.Close(ctx))
+	assert.Equal(t, int64(10), atomic.LoadInt64(&persisted))
+}
@@ -0,0 +1,188 @@
+package unit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/bootstrap"
+	"url-shortener/internal/cache"
+	"url-shortener/internal/cache/cachetest"
+	"url-shortener/internal/clientip"
+	"url-shortener/internal/config"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/handler"
+	"url-shortener/pkg/logger"
+)
+
+func TestClientIPCanonical(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"203.0.113.9", "203.0.113.9"},
+		{"::ffff:203.0.113.9", "203.0.113.9"},
+		{"::FFFF:cb00:7109", "203.0.113.9"},
+		{" 2001:DB8:0:0:0:0:0:1 ", "2001:db8::1"},
+		{"fe80::1%eth0", "fe80::1"},
+		{"::1", "::1"},
+		{"not-an-ip", "not-an-ip"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, clientip.Canonical(tt.input), tt.input)
+	}
+}
+
+func TestClientIPNetwork(t *testing.T) {
+	ip := "2001:db8:aaaa:bbbb:cccc:dddd:eeee:ffff"
+	tests := []struct {
+		prefix   int
+		expected string
+	}{
+		{48, "2001:db8:aaaa::"},
+		{56, "2001:db8:aaaa:bb00::"},
+		{64, "2001:db8:aaaa:bbbb::"},
+		{127, "2001:db8:aaaa:bbbb:cccc:dddd:eeee:fffe"},
+		{128, ip},
+		{0, ip},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, clientip.Network(ip, tt.prefix), tt.prefix)
+	}
+
+	// IPv4, mapped or not, is never truncated
+	assert.Equal(t, "203.0.113.9", clientip.Network("203.0.113.9", 64))
+	assert.Equal(t, "203.0.113.9", clientip.Network("::ffff:203.0.113.9", 48))
+	assert.Equal(t, "2001:db8:1:2::", clientip.Network("2001:DB8:1:2::A", 64))
+	assert.Equal(t, "unknown", clientip.Network("unknown", 64))
+}
+
+// countForwardedAllowed sends one request per forwarded address through router and counts the 200s
+func countForwardedAllowed(router *gin.Engine, forwarded ...string) int {
+	allowed := 0
+	for _, ip := range forwarded {
+		req := httptest.NewRequest("GET", "/ping", nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+		req.Header.Set("X-Forwarded-For", ip)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code == http.StatusOK {
+			allowed++
+		}
+	}
+	return allowed
+}
+
+func TestRateLimiter_IPv6ClientsShareTheirPrefix(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(handler.NewRateLimiter(nil).GroupIPv6(64).Middleware("default", 2))
+	router.GET("/ping", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	// Rotating through a /64 doesn't buy fresh tokens
+	assert.Equal(t, 2, countForwardedAllowed(router, "2001:db8:1:2::a", "2001:db8:1:2:ffff::1", "2001:DB8:1:2::B"))
+	assert.Equal(t, 1, countForwardedAllowed(router, "2001:db8:1:3::1"), "the next /64 is another client")
+
+	// A dual-stack proxy's mapped form is the IPv4 client it stands for
+	assert.Equal(t, 2, countForwardedAllowed(router, "::ffff:198.51.100.4", "198.51.100.4", "::ffff:c633:6404"))
+	assert.Equal(t, 1, countForwardedAllowed(router, "198.51.100.5"))
+}
+
+func TestRateLimiter_FullIPv6AddressesWithoutGrouping(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(handler.NewRateLimiter(nil).GroupIPv6(128).Middleware("default", 1))
+	router.GET("/ping", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	assert.Equal(t, 2, countForwardedAllowed(router, "2001:db8:1:2::a", "2001:db8:1:2::b", "2001:db8:1:2:0:0:0:a"))
+}
+
+func TestRouter_ForwardedForOnlyFromTrustedProxies(t *testing.T) {
+	newRouter := func(proxies ...string) *gin.Engine {
+		router := bootstrap.NewRouter("test", &config.Config{TrustedProxies: proxies}, handler.NewQuietPaths(nil), logger.NewLogger(logger.Options{}))
+		router.Use(handler.NewRateLimiter(nil).GroupIPv6(64).Middleware("default", 1))
+		router.GET("/ping", func(c *gin.Context) { c.Status(http.StatusOK) })
+		return router
+	}
+
+	// 10.0.0.1 is just another client until it is listed, so a spoofed header buys nothing
+	assert.Equal(t, 1, countForwardedAllowed(newRouter(), "198.51.100.1", "198.51.100.2", "198.51.100.3"))
+	assert.Equal(t, 1, countForwardedAllowed(newRouter("192.0.2.0/24"), "198.51.100.1", "198.51.100.2"))
+
+	assert.Equal(t, 2, countForwardedAllowed(newRouter("10.0.0.0/8"), "198.51.100.1", "198.51.100.2", "198.51.100.1"))
+}
+
+func TestShortenURL_StoresCanonicalCreatorIP(t *testing.T) {
+	suite, router := setupHooksRouter(t)
+	suite.cfg.RateLimitIPv6Prefix = 64
+
+	for alias, forwarded := range map[string]string{
+		"mapped": "::ffff:203.0.113.9",
+		"longv6": "2001:DB8:0:0:1:0:0:A",
+	} {
+		req := shortenRequest(`{"url":"https://example.com/` + alias + `","custom_alias":"` + alias + `"}`)
+		req.Header.Set("X-Forwarded-For", forwarded)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	}
+	drainCacheWrites(t, suite)
+
+	// The creator keeps its full address; only limits and analytics group by prefix
+	suite.repo.AssertCalled(t, "Create", mock.Anything, mock.MatchedBy(func(url *domain.URL) bool {
+		return url.ShortCode == "mapped" && url.CreatorIP == "203.0.113.9"
+	}))
+	suite.repo.AssertCalled(t, "Create", mock.Anything, mock.MatchedBy(func(url *domain.URL) bool {
+		return url.ShortCode == "longv6" && url.CreatorIP == "2001:db8::1:0:0:a"
+	}))
+}
+
+func TestListByCreatorIP_MatchesEitherSpelling(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	suite.repo.On("FindByCreatorIP", mock.Anything, "203.0.113.9", 11, 0).Return([]domain.URL{}, nil)
+
+	page, err := suite.service.ListByCreatorIP(context.Background(), "::ffff:203.0.113.9", 10, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "203.0.113.9", page.CreatorIP)
+}
+
+func TestClickDedup_IPv6ClientsDedupedByPrefix(t *testing.T) {
+	store := &claimCache{cachetest.NewMemoryCache()}
+	suite := setupDedupTest(t, store)
+	suite.cfg.RateLimitIPv6Prefix = 64
+	suite.repo.On("IncrementClickCount", mock.Anything, "spam01", mock.Anything).Return(nil).Times(2)
+	suite.repo.On("IncrementFilteredClickCount", mock.Anything, "spam01").Return(nil).Times(2)
+
+	for _, ip := range []string{"2001:db8:1:2::a", "2001:db8:1:2::b", "2001:db8:1:2:dead:beef::1", "2001:db8:1:3::a"} {
+		_, err := suite.service.PrepareRedirect(context.Background(), "spam01", domain.Visitor{IP: ip, UserAgent: browserUA})
+		require.NoError(t, err)
+	}
+	drainCacheWrites(t, suite)
+
+	suite.repo.AssertExpectations(t)
+	assert.Equal(t, 30*time.Second, store.TTL(cache.ClickDedupKey("spam01", "2001:db8:1:2::", browserUA)))
+	assert.Equal(t, 30*time.Second, store.TTL(cache.ClickDedupKey("spam01", "2001:db8:1:3::", browserUA)))
+}
+
+func TestConfig_RateLimitIPv6Prefix(t *testing.T) {
+	cfg, err := config.LoadFrom(nil)
+	require.NoError(t, err)
+	assert.Equal(t, 64, cfg.RateLimitIPv6Prefix)
+
+	for _, prefix := range []int{0, 129} {
+		cfg.RateLimitIPv6Prefix = prefix
+		assert.ErrorContains(t, cfg.Validate(), "RATE_LIMIT_IPV6_PREFIX")
+	}
+	cfg.RateLimitIPv6Prefix = 128
+	assert.NoError(t, cfg.Validate())
+}
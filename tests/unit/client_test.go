@@ -0,0 +1,292 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/domain"
+	"url-shortener/pkg/client"
+)
+
+// newTestClient points a client with fast retries at handler
+func newTestClient(t *testing.T, handler http.HandlerFunc, opts ...client.Option) *client.Client {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	opts = append([]client.Option{client.WithRetry(2, time.Millisecond, 2*time.Second)}, opts...)
+	return client.New(server.URL+"/", opts...)
+}
+
+// writeAPIError answers like the server's error responses
+func writeAPIError(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(domain.ErrorResponse{Error: code, Message: "details", Code: status})
+}
+
+func TestClient_Shorten(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/api/v1/shorten", r.URL.Path)
+		assert.Equal(t, "secret", r.Header.Get("X-API-Key"))
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+
+		var req domain.CreateURLRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "https://example.com", req.URL)
+		assert.Equal(t, "launch", req.CustomAlias)
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(domain.CreateURLResponse{ShortCode: "launch", ShortURL: "https://sho.rt/launch"})
+	}, client.WithAPIKey("secret"))
+
+	resp, err := c.Shorten(context.Background(), &domain.CreateURLRequest{URL: "https://example.com", CustomAlias: "launch"})
+
+	require.NoError(t, err)
+	assert.Equal(t, "https://sho.rt/launch", resp.ShortURL)
+}
+
+func TestClient_ResolveGetStatsDelete(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.EscapedPath() {
+		case "GET /api/v1/urls/a%2Fb":
+			json.NewEncoder(w).Encode(domain.URLInfoResponse{ShortCode: "a/b", OriginalURL: "https://example.com"})
+		case "GET /api/v1/urls/abc123/stats":
+			json.NewEncoder(w).Encode(domain.URLStats{ShortCode: "abc123", TotalClicks: 7})
+		case "DELETE /api/v1/urls/abc123":
+			assert.Equal(t, "token", r.Header.Get("X-Management-Token"))
+			json.NewEncoder(w).Encode(map[string]string{"message": "URL deleted successfully"})
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.EscapedPath())
+		}
+	})
+	ctx := context.Background()
+
+	info, err := c.Resolve(ctx, "a/b")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com", info.OriginalURL)
+
+	stats, err := c.GetStats(ctx, "abc123")
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), stats.TotalClicks)
+
+	assert.NoError(t, c.Delete(ctx, "abc123", "token"))
+}
+
+func TestClient_StatusMapping(t *testing.T) {
+	tests := []struct {
+		status   int
+		code     string
+		sentinel error
+	}{
+		{http.StatusBadRequest, "invalid_url", client.ErrValidation},
+		{http.StatusRequestEntityTooLarge, "request_too_large", client.ErrValidation},
+		{http.StatusUnauthorized, "unauthorized", client.ErrUnauthorized},
+		{http.StatusForbidden, "forbidden", client.ErrForbidden},
+		{http.StatusNotFound, "not_found", client.ErrNotFound},
+		{http.StatusConflict, "short_code_taken", client.ErrConflict},
+		{http.StatusGone, "url_expired", client.ErrExpired},
+		{http.StatusTooManyRequests, "rate_limit_exceeded", client.ErrRateLimited},
+		{http.StatusInternalServerError, "internal_error", client.ErrUnavailable},
+		{http.StatusServiceUnavailable, "service_unavailable", client.ErrUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				writeAPIError(w, tt.status, tt.code)
+			})
+
+			_, err := c.Resolve(context.Background(), "abc123")
+
+			require.Error(t, err)
+			assert.ErrorIs(t, err, tt.sentinel)
+			var apiErr *client.APIError
+			require.True(t, errors.As(err, &apiErr))
+			assert.Equal(t, tt.status, apiErr.StatusCode)
+			assert.Equal(t, tt.code, apiErr.Code)
+			assert.Equal(t, "details", apiErr.Message)
+		})
+	}
+}
+
+func TestClient_ConflictCarriesSuggestions(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(domain.ErrorResponse{Error: "short_code_taken", Code: 409, Suggestions: []string{"launch2"}})
+	})
+
+	_, err := c.Shorten(context.Background(), &domain.CreateURLRequest{URL: "https://example.com", CustomAlias: "launch"})
+
+	var apiErr *client.APIError
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, []string{"launch2"}, apiErr.Suggestions)
+}
+
+func TestClient_NonJSONErrorKeepsStatus(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "<html>Bad Gateway</html>", http.StatusBadGateway)
+	}, client.WithRetry(0, time.Millisecond, time.Second))
+
+	_, err := c.GetStats(context.Background(), "abc123")
+
+	assert.ErrorIs(t, err, client.ErrUnavailable)
+	var apiErr *client.APIError
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, "Bad Gateway", apiErr.Message)
+	assert.Empty(t, apiErr.Code)
+}
+
+func TestClient_RetriesUntilSuccess(t *testing.T) {
+	var calls atomic.Int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch calls.Add(1) {
+		case 1:
+			writeAPIError(w, http.StatusTooManyRequests, "rate_limit_exceeded")
+		case 2:
+			writeAPIError(w, http.StatusInternalServerError, "internal_error")
+		default:
+			json.NewEncoder(w).Encode(domain.URLStats{ShortCode: "abc123"})
+		}
+	})
+
+	stats, err := c.GetStats(context.Background(), "abc123")
+
+	require.NoError(t, err)
+	assert.Equal(t, "abc123", stats.ShortCode)
+	assert.Equal(t, int32(3), calls.Load())
+}
+
+func TestClient_GivesUpAfterMaxRetries(t *testing.T) {
+	var calls atomic.Int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		writeAPIError(w, http.StatusServiceUnavailable, "service_unavailable")
+	})
+
+	_, err := c.Resolve(context.Background(), "abc123")
+
+	assert.ErrorIs(t, err, client.ErrUnavailable)
+	assert.Equal(t, int32(3), calls.Load(), "one attempt and two retries")
+}
+
+func TestClient_DoesNotRetryClientErrors(t *testing.T) {
+	var calls atomic.Int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		writeAPIError(w, http.StatusNotFound, "not_found")
+	})
+
+	_, err := c.Resolve(context.Background(), "abc123")
+
+	assert.ErrorIs(t, err, client.ErrNotFound)
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestClient_PostNotRetriedAfterInternalError(t *testing.T) {
+	var calls atomic.Int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		writeAPIError(w, http.StatusInternalServerError, "internal_error")
+	})
+
+	_, err := c.Shorten(context.Background(), &domain.CreateURLRequest{URL: "https://example.com"})
+
+	assert.ErrorIs(t, err, client.ErrUnavailable)
+	assert.Equal(t, int32(1), calls.Load(), "the link may have been stored")
+}
+
+func TestClient_RetryResendsBody(t *testing.T) {
+	var calls atomic.Int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var req domain.CreateURLRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "https://example.com", req.URL)
+		if calls.Add(1) == 1 {
+			writeAPIError(w, http.StatusServiceUnavailable, "service_unavailable")
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(domain.CreateURLResponse{ShortCode: "abc123"})
+	})
+
+	resp, err := c.Shorten(context.Background(), &domain.CreateURLRequest{URL: "https://example.com"})
+
+	require.NoError(t, err)
+	assert.Equal(t, "abc123", resp.ShortCode)
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestClient_RespectsRetryAfter(t *testing.T) {
+	var first time.Time
+	var calls atomic.Int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			first = time.Now()
+			w.Header().Set("Retry-After", "1")
+			writeAPIError(w, http.StatusTooManyRequests, "rate_limit_exceeded")
+			return
+		}
+		assert.GreaterOrEqual(t, time.Since(first), time.Second, "retried before Retry-After")
+		json.NewEncoder(w).Encode(domain.URLInfoResponse{ShortCode: "abc123"})
+	})
+
+	_, err := c.Resolve(context.Background(), "abc123")
+
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestClient_RetryAfterBeyondMaxWaitIsReturned(t *testing.T) {
+	var calls atomic.Int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Retry-After", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+		writeAPIError(w, http.StatusTooManyRequests, "quota_exceeded")
+	})
+
+	_, err := c.Shorten(context.Background(), &domain.CreateURLRequest{URL: "https://example.com"})
+
+	assert.ErrorIs(t, err, client.ErrRateLimited)
+	var apiErr *client.APIError
+	require.True(t, errors.As(err, &apiErr))
+	assert.InDelta(t, time.Hour.Seconds(), apiErr.RetryAfter.Seconds(), 5)
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestClient_ContextCancelStopsRetries(t *testing.T) {
+	var calls atomic.Int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		writeAPIError(w, http.StatusServiceUnavailable, "service_unavailable")
+	}, client.WithRetry(5, time.Second, time.Second))
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := c.Resolve(ctx, "abc123")
+
+	assert.ErrorIs(t, err, client.ErrUnavailable)
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestClient_Timeout(t *testing.T) {
+	release := make(chan struct{})
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}, client.WithTimeout(20*time.Millisecond), client.WithRetry(0, time.Millisecond, time.Second))
+	defer close(release)
+
+	_, err := c.Resolve(context.Background(), "abc123")
+
+	require.Error(t, err)
+	var apiErr *client.APIError
+	assert.False(t, errors.As(err, &apiErr), "transport errors are not API errors")
+}
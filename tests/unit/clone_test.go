@@ -0,0 +1,155 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/handler"
+)
+
+// campaignLink is a link with every copyable setting filled in, created ten days ago with a 30 day lifetime
+func campaignLink() *domain.URL {
+	created := time.Now().AddDate(0, 0, -10)
+	expires := created.AddDate(0, 0, 30)
+	return &domain.URL{
+		ID:                   7,
+		ShortCode:            "spring",
+		OriginalURL:          "https://example.com/sale",
+		CreatedAt:            created,
+		ExpiresAt:            &expires,
+		ClickCount:           420,
+		BotClicks:            12,
+		IsActive:             true,
+		CustomAlias:          true,
+		RequiresInterstitial: true,
+		UTM:                  domain.UTMParams{Source: "newsletter", Campaign: "spring"},
+		Targets:              domain.Targets{{Platform: "ios", URL: "https://apps.apple.com/app/id1"}},
+		ForwardQuery:         true,
+		ReferrerPolicy:       "no-referrer",
+	}
+}
+
+func TestCloneURL_CopiesSettingsWithNewDestination(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	ctx := context.Background()
+	source := campaignLink()
+
+	var stored *domain.URL
+	suite.repo.On("FindAnyByShortCode", ctx, "spring").Return(source, nil)
+	suite.repo.On("Create", ctx, mock.AnythingOfType("*domain.URL")).Run(func(args mock.Arguments) {
+		stored = args.Get(1).(*domain.URL)
+	}).Return(nil)
+
+	resp, err := suite.service.CloneURL(ctx, "spring", &domain.CloneURLRequest{URL: "https://example.com/summer", CustomAlias: "summer"}, domain.CreatorContext{IP: "10.0.0.1"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "summer", resp.ShortCode)
+	assert.Equal(t, "spring", resp.ClonedFrom)
+	assert.NotEmpty(t, resp.ManagementToken)
+
+	assert.Equal(t, "https://example.com/summer", stored.OriginalURL)
+	assert.Equal(t, source.UTM, stored.UTM)
+	assert.Equal(t, source.Targets, stored.Targets)
+	assert.True(t, stored.RequiresInterstitial)
+	assert.True(t, stored.ForwardQuery)
+	assert.Equal(t, source.ReferrerPolicy, stored.ReferrerPolicy)
+	assert.Zero(t, stored.ID)
+	assert.Zero(t, stored.ClickCount)
+	assert.Zero(t, stored.BotClicks)
+	assert.Equal(t, "10.0.0.1", stored.CreatorIP)
+	assert.True(t, stored.CustomAlias)
+}
+
+func TestCloneURL_InactiveExpiredSourceStartsFresh(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	ctx := context.Background()
+	source := campaignLink()
+	source.IsActive = false
+	source.CustomAlias = false
+	source.RequiresInterstitial = false
+	source.CreatedAt = time.Now().AddDate(0, 0, -40)
+	expired := source.CreatedAt.AddDate(0, 0, 30)
+	source.ExpiresAt = &expired
+
+	var stored *domain.URL
+	suite.repo.On("FindAnyByShortCode", ctx, "spring").Return(source, nil)
+	suite.repo.On("Create", ctx, mock.AnythingOfType("*domain.URL")).Run(func(args mock.Arguments) {
+		stored = args.Get(1).(*domain.URL)
+	}).Return(nil)
+	suite.cache.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	resp, err := suite.service.CloneURL(ctx, "spring", &domain.CloneURLRequest{}, domain.CreatorContext{IP: "10.0.0.1"})
+	require.NoError(t, err)
+
+	assert.True(t, stored.IsActive)
+	assert.False(t, stored.CustomAlias)
+	assert.Len(t, resp.ShortCode, suite.cfg.ShortCodeLength)
+	assert.Equal(t, source.OriginalURL, stored.OriginalURL)
+	require.NotNil(t, stored.ExpiresAt)
+	assert.WithinDuration(t, time.Now().AddDate(0, 0, 30), *stored.ExpiresAt, time.Minute, "same lifetime, counted from the clone")
+	drainCacheWrites(t, suite)
+	suite.cache.AssertCalled(t, "Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCloneURL_BundleRejected(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	ctx := context.Background()
+	suite.repo.On("FindAnyByShortCode", ctx, "bndl01").
+		Return(&domain.URL{ShortCode: "bndl01", IsActive: true, Bundle: domain.BundleItems{{URL: "https://example.com", ShortCode: "mem001"}}}, nil)
+
+	_, err := suite.service.CloneURL(ctx, "bndl01", &domain.CloneURLRequest{}, domain.CreatorContext{IP: "10.0.0.1"})
+
+	var appErr *domain.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, http.StatusBadRequest, appErr.StatusCode)
+	suite.repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestCloneURLHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name   string
+		code   string
+		body   string
+		status int
+	}{
+		{"empty body keeps destination", "spring", "", http.StatusCreated},
+		{"missing source", "nope01", `{"url":"https://example.com/summer"}`, http.StatusNotFound},
+		{"unknown field", "spring", `{"destination":"https://example.com"}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			suite := setupURLServiceTest(t)
+			suite.repo.On("FindAnyByShortCode", mock.Anything, "spring").Return(campaignLink(), nil)
+			suite.repo.On("FindAnyByShortCode", mock.Anything, "nope01").Return(nil, domain.ErrURLNotFound)
+			suite.repo.On("Create", mock.Anything, mock.AnythingOfType("*domain.URL")).Return(nil)
+
+			router := gin.New()
+			router.POST("/api/v1/urls/:shortCode/clone", handler.NewURLHandler(suite.service, suite.cfg, suite.logger).CloneURL)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest("POST", "/api/v1/urls/"+tt.code+"/clone", strings.NewReader(tt.body)))
+
+			require.Equal(t, tt.status, w.Code, w.Body.String())
+			if tt.status == http.StatusCreated {
+				var resp domain.CreateURLResponse
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+				assert.Equal(t, "spring", resp.ClonedFrom)
+				assert.Equal(t, "https://example.com/sale", resp.OriginalURL)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package unit
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/service"
+	"url-shortener/internal/shortener"
+)
+
+func TestCodeGenerator_WithoutAmbiguousChars(t *testing.T) {
+	generator := shortener.NewCodeGenerator(8, shortener.WithoutAmbiguousChars())
+
+	for i := 0; i < 2000; i++ {
+		code := generator.Generate()
+		require.Len(t, code, 8)
+		require.False(t, strings.ContainsAny(code, "0O1lI"), "generated %q", code)
+	}
+
+	// Codes issued before the option are still valid and decode as before
+	assert.True(t, generator.IsValid("0Ol1I"))
+	assert.Equal(t, uint(62), generator.Decode("10"))
+	assert.Equal(t, shortener.NewCodeGenerator(8).Decode("aZ09"), generator.Decode("aZ09"))
+}
+
+func TestCodeGenerator_CollisionProbabilityUsesAlphabet(t *testing.T) {
+	full := shortener.NewCodeGenerator(6)
+	narrow := shortener.NewCodeGenerator(6, shortener.WithoutAmbiguousChars())
+
+	assert.InDelta(t, 1e6/(2*math.Pow(62, 6)), full.GetCollisionProbability(1000), 1e-12)
+	assert.InDelta(t, 1e6/(2*math.Pow(57, 6)), narrow.GetCollisionProbability(1000), 1e-12)
+	assert.Greater(t, narrow.GetCollisionProbability(1000), full.GetCollisionProbability(1000))
+}
+
+func TestShortenURL_ExcludeAmbiguousCodes(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	suite.cfg.ShortCodeExcludeAmbiguous = true
+	suite.service = service.NewURLService(suite.repo, suite.cache, suite.cfg, suite.logger)
+	suite.repo.On("FindByOriginalURL", mock.Anything, mock.Anything).Return((*domain.URL)(nil), domain.ErrURLNotFound)
+	suite.repo.On("Create", mock.Anything, mock.AnythingOfType("*domain.URL")).Return(nil)
+	suite.cache.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+
+	for i := 0; i < 50; i++ {
+		resp, err := suite.service.ShortenURL(context.Background(), &domain.CreateURLRequest{URL: "https://example.com/print"}, domain.CreatorContext{})
+		require.NoError(t, err)
+		require.False(t, strings.ContainsAny(resp.ShortCode, "0O1lI"), "generated %q", resp.ShortCode)
+	}
+	drainCacheWrites(t, suite)
+}
+
+func TestResolve_CaseInsensitiveFallback(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	suite.cfg.CaseInsensitiveCodes = true
+	suite.cache.On("Get", mock.Anything, mock.Anything).Return("", nil)
+	suite.cache.On("Exists", mock.Anything, mock.Anything).Return(false, nil)
+	suite.cache.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+	suite.repo.On("FindByShortCode", mock.Anything, "promox7").Return((*domain.URL)(nil), domain.ErrURLNotFound)
+	suite.repo.On("FindByShortCodeFold", mock.Anything, "promox7").
+		Return(&domain.URL{ShortCode: "PromoX7", OriginalURL: "https://example.com/promo", IsActive: true}, nil)
+	suite.repo.On("IncrementClickCount", mock.Anything, "PromoX7", mock.Anything).Return(nil).Once()
+
+	decision, err := suite.service.PrepareRedirect(context.Background(), "promox7", domain.Visitor{})
+	require.NoError(t, err)
+	drainCacheWrites(t, suite)
+
+	assert.Equal(t, "https://example.com/promo", decision.OriginalURL)
+	assert.Equal(t, "PromoX7", decision.ShortCode, "the click is counted under the link's own code")
+	suite.repo.AssertExpectations(t)
+}
+
+func TestResolve_ExactCodeWinsAndFallbackIsOptIn(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	suite.cache.On("Get", mock.Anything, mock.Anything).Return("", nil)
+	suite.cache.On("Exists", mock.Anything, mock.Anything).Return(false, nil)
+	suite.cache.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+	suite.repo.On("FindByShortCode", mock.Anything, "promox7").Return((*domain.URL)(nil), domain.ErrURLNotFound)
+	suite.repo.On("FindByShortCode", mock.Anything, "abcDEF").
+		Return(&domain.URL{ShortCode: "abcDEF", OriginalURL: "https://example.com/exact", IsActive: true}, nil)
+	suite.repo.On("IncrementClickCount", mock.Anything, "abcDEF", mock.Anything).Return(nil)
+
+	_, err := suite.service.PrepareRedirect(context.Background(), "promox7", domain.Visitor{})
+	assert.ErrorIs(t, err, domain.ErrURLNotFound, "without CASE_INSENSITIVE_CODES a wrong case is a 404")
+
+	suite.cfg.CaseInsensitiveCodes = true
+	decision, err := suite.service.PrepareRedirect(context.Background(), "abcDEF", domain.Visitor{})
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/exact", decision.OriginalURL)
+	drainCacheWrites(t, suite)
+
+	suite.repo.AssertNotCalled(t, "FindByShortCodeFold", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,151 @@
+package unit
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/handler"
+)
+
+// largeJSON is a response body well over the 1 KB threshold
+var largeJSON = `{"urls":["` + strings.Repeat("https://example.com/some/long/path", 100) + `"]}`
+
+// setupCompressionRouter registers test handlers under /api/v1 behind the middleware with a 1 KB threshold
+func setupCompressionRouter(t *testing.T) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	api := router.Group("/api/v1", handler.CompressionMiddleware(1024), handler.TimeoutMiddleware(time.Second))
+	api.GET("/large", func(c *gin.Context) { c.Data(http.StatusOK, "application/json; charset=utf-8", []byte(largeJSON)) })
+	api.GET("/small", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"ok": true}) })
+	api.GET("/image", func(c *gin.Context) { c.Data(http.StatusOK, "image/png", bytes.Repeat([]byte{0x89}, 4096)) })
+	api.GET("/moved", func(c *gin.Context) {
+		c.Header("Content-Type", "text/html; charset=utf-8")
+		c.Status(http.StatusMovedPermanently)
+		c.Header("Location", "https://example.com/")
+		c.Writer.WriteString(strings.Repeat("<p>moved</p>", 200))
+	})
+	return router
+}
+
+func gzipGet(router *gin.Engine, target, acceptEncoding string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", target, nil)
+	if acceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func gunzip(t *testing.T, data []byte) string {
+	t.Helper()
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	body, err := io.ReadAll(r)
+	require.NoError(t, err)
+	return string(body)
+}
+
+func TestCompression_LargeResponseIsGzipped(t *testing.T) {
+	router := setupCompressionRouter(t)
+
+	w := gzipGet(router, "/api/v1/large", "br;q=1.0, gzip;q=0.8")
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+	assert.Equal(t, []string{"Accept-Encoding"}, w.Header().Values("Vary"))
+	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Less(t, w.Body.Len(), len(largeJSON))
+	assert.Equal(t, largeJSON, gunzip(t, w.Body.Bytes()))
+}
+
+func TestCompression_LeavesResponsesAlone(t *testing.T) {
+	router := setupCompressionRouter(t)
+
+	tests := []struct {
+		name           string
+		target         string
+		acceptEncoding string
+	}{
+		{"under the threshold", "/api/v1/small", "gzip"},
+		{"no Accept-Encoding", "/api/v1/large", ""},
+		{"gzip refused", "/api/v1/large", "*, gzip;q=0"},
+		{"only brotli", "/api/v1/large", "br"},
+		{"already compressed type", "/api/v1/image", "gzip"},
+		{"redirect", "/api/v1/moved", "gzip"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := gzipGet(router, tt.target, tt.acceptEncoding)
+
+			assert.Empty(t, w.Header().Get("Content-Encoding"))
+			assert.Contains(t, w.Header().Values("Vary"), "Accept-Encoding", "caches must still tell the variants apart")
+		})
+	}
+
+	w := gzipGet(router, "/api/v1/moved", "gzip")
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+	assert.Equal(t, "https://example.com/", w.Header().Get("Location"))
+	assert.Equal(t, strings.Repeat("<p>moved</p>", 200), w.Body.String())
+}
+
+func TestCompression_StreamIsFlushedCompressed(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	w := httptest.NewRecorder()
+	router.GET("/api/v1/export", handler.CompressionMiddleware(1024), func(c *gin.Context) {
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Writer.WriteString("short_code,original_url\n")
+		c.Writer.Flush()
+
+		// The first rows are on the wire, compressed, while the handler is still running
+		assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+		r, err := gzip.NewReader(bytes.NewReader(w.Body.Bytes()))
+		require.NoError(t, err)
+		partial, err := io.ReadAll(io.LimitReader(r, 24))
+		require.NoError(t, err)
+		assert.Equal(t, "short_code,original_url\n", string(partial))
+
+		for i := 0; i < 100; i++ {
+			fmt.Fprintf(c.Writer, "code%d,https://example.com/%d\n", i, i)
+		}
+	})
+
+	req := httptest.NewRequest("GET", "/api/v1/export", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, w.Flushed)
+	body := gunzip(t, w.Body.Bytes())
+	assert.True(t, strings.HasPrefix(body, "short_code,original_url\ncode0,"))
+	assert.Equal(t, 101, strings.Count(body, "\n"))
+}
+
+func TestCompression_RedirectRouteOutsideAPIIsUntouched(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	router := setupRedirectRouter(suite)
+	router.Group("/api/v1", handler.CompressionMiddleware(0)).GET("/ping", func(c *gin.Context) { c.String(http.StatusOK, "pong") })
+
+	suite.cache.On("Get", mock.Anything, "abc123").Return("https://example.com/"+strings.Repeat("a", 2000), nil)
+	suite.repo.On("IncrementClickCount", mock.Anything, "abc123", mock.Anything).Return(nil)
+
+	w := gzipGet(router, "/abc123", "gzip")
+
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+	assert.NotContains(t, w.Header().Values("Vary"), "Accept-Encoding")
+	assert.Equal(t, "gzip", gzipGet(router, "/api/v1/ping", "gzip").Header().Get("Content-Encoding"), "a zero threshold compresses everything on the API")
+}
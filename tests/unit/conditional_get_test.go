@@ -0,0 +1,166 @@
+package unit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/handler"
+)
+
+// setupConditionalRouter serves info and stats behind the timeout middleware, as the server does
+func setupConditionalRouter(suite *URLServiceTestSuite) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	h := handler.NewURLHandler(suite.service, suite.cfg, suite.logger)
+
+	router := gin.New()
+	router.Use(handler.TimeoutMiddleware(time.Second))
+	router.GET("/api/v1/urls/:shortCode", h.GetURLInfo)
+	router.GET("/api/v1/urls/:shortCode/stats", h.GetStats)
+	return router
+}
+
+// conditionalGet sends a GET with the given request headers
+func conditionalGet(router *gin.Engine, path string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", path, nil)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// conditionalURL is an active link last changed a minute ago
+func conditionalURL() *domain.URL {
+	updated := time.Now().Add(-time.Minute)
+	return &domain.URL{
+		ID:          42,
+		ShortCode:   "abc123",
+		OriginalURL: "https://example.com/info",
+		CreatedAt:   updated.Add(-time.Hour),
+		UpdatedAt:   updated,
+		ClickCount:  7,
+		IsActive:    true,
+	}
+}
+
+func TestGetURLInfo_SetsValidators(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	url := conditionalURL()
+	suite.repo.On("FindByShortCode", mock.Anything, "abc123").Return(url, nil)
+
+	w := conditionalGet(setupConditionalRouter(suite), "/api/v1/urls/abc123", nil)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Regexp(t, `^W/"[0-9a-f]{16}"$`, w.Header().Get("ETag"))
+	assert.Equal(t, url.UpdatedAt.UTC().Format(http.TimeFormat), w.Header().Get("Last-Modified"))
+	assert.Equal(t, "no-cache", w.Header().Get("Cache-Control"))
+}
+
+func TestGetURLInfo_IfNoneMatchAnswersNotModified(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	suite.repo.On("FindByShortCode", mock.Anything, "abc123").Return(conditionalURL(), nil)
+	router := setupConditionalRouter(suite)
+
+	first := conditionalGet(router, "/api/v1/urls/abc123", nil)
+	require.Equal(t, http.StatusOK, first.Code)
+	etag := first.Header().Get("ETag")
+
+	w := conditionalGet(router, "/api/v1/urls/abc123", map[string]string{"If-None-Match": etag})
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.String())
+	assert.Equal(t, etag, w.Header().Get("ETag"))
+
+	// The strong form and a list both match, since GET compares weakly
+	list := `"other", ` + etag[2:]
+	w = conditionalGet(router, "/api/v1/urls/abc123", map[string]string{"If-None-Match": list})
+	assert.Equal(t, http.StatusNotModified, w.Code)
+
+	w = conditionalGet(router, "/api/v1/urls/abc123", map[string]string{"If-None-Match": `W/"stale"`})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotEmpty(t, w.Body.String())
+}
+
+func TestGetURLInfo_IfModifiedSince(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	url := conditionalURL()
+	suite.repo.On("FindByShortCode", mock.Anything, "abc123").Return(url, nil)
+	router := setupConditionalRouter(suite)
+
+	since := url.UpdatedAt.UTC().Format(http.TimeFormat)
+	w := conditionalGet(router, "/api/v1/urls/abc123", map[string]string{"If-Modified-Since": since})
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.String())
+
+	earlier := url.UpdatedAt.Add(-time.Hour).UTC().Format(http.TimeFormat)
+	w = conditionalGet(router, "/api/v1/urls/abc123", map[string]string{"If-Modified-Since": earlier})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// If-None-Match takes precedence, so a stale tag is answered in full even when the date matches
+	w = conditionalGet(router, "/api/v1/urls/abc123", map[string]string{
+		"If-None-Match":     `W/"stale"`,
+		"If-Modified-Since": since,
+	})
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestGetURLInfo_ClickChangesETag(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	before := conditionalURL()
+	suite.repo.On("FindByShortCode", mock.Anything, "abc123").Return(before, nil).Once()
+	router := setupConditionalRouter(suite)
+
+	first := conditionalGet(router, "/api/v1/urls/abc123", nil)
+	require.Equal(t, http.StatusOK, first.Code)
+
+	// A click bumps the count and the access time within the same second as the last change
+	after := conditionalURL()
+	after.UpdatedAt = before.UpdatedAt
+	clicked := before.UpdatedAt.Add(time.Millisecond)
+	after.ClickCount++
+	after.LastAccessAt = &clicked
+	suite.repo.On("FindByShortCode", mock.Anything, "abc123").Return(after, nil).Once()
+
+	w := conditionalGet(router, "/api/v1/urls/abc123", map[string]string{
+		"If-None-Match": first.Header().Get("ETag"),
+	})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotEqual(t, first.Header().Get("ETag"), w.Header().Get("ETag"))
+	assert.Contains(t, w.Body.String(), `"click_count":8`)
+}
+
+func TestGetStats_IfNoneMatchAnswersNotModified(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	stats := &domain.URLStats{
+		ShortCode:   "abc123",
+		TotalClicks: 7,
+		UpdatedAt:   time.Now().Add(-time.Minute),
+	}
+	suite.repo.On("GetStats", mock.Anything, "abc123").Return(stats, nil).Once()
+	router := setupConditionalRouter(suite)
+
+	first := conditionalGet(router, "/api/v1/urls/abc123/stats", nil)
+	require.Equal(t, http.StatusOK, first.Code)
+	etag := first.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+
+	suite.repo.On("GetStats", mock.Anything, "abc123").Return(stats, nil).Once()
+	w := conditionalGet(router, "/api/v1/urls/abc123/stats", map[string]string{"If-None-Match": etag})
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.String())
+
+	clicked := *stats
+	clicked.TotalClicks++
+	suite.repo.On("GetStats", mock.Anything, "abc123").Return(&clicked, nil).Once()
+	w = conditionalGet(router, "/api/v1/urls/abc123/stats", map[string]string{"If-None-Match": etag})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotEqual(t, etag, w.Header().Get("ETag"))
+}
@@ -0,0 +1,109 @@
+package unit
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/config"
+)
+
+const sampleConfigYAML = `
+base_url: https://sho.rt
+short_code_length: 8
+cache_ttl: 2h
+shortener_domains:
+  - Bit.ly
+  - t.co
+strip_tracking_params: [utm_*, fbclid]
+rate_limit_tiers:
+  3f2a9c: 600
+  partner: unlimited
+max_request_body_bytes: 131072
+`
+
+func TestLoadFrom_FileWithEnvOverride(t *testing.T) {
+	t.Setenv("SHORT_CODE_LENGTH", "10")
+	t.Setenv("CACHE_TTL_SECONDS", "60")
+
+	cfg, err := config.LoadFrom(strings.NewReader(sampleConfigYAML))
+	require.NoError(t, err)
+
+	assert.Equal(t, "https://sho.rt", cfg.BaseURL)
+	assert.Equal(t, 10, cfg.ShortCodeLength, "the environment wins over the file")
+	assert.Equal(t, time.Minute, cfg.CacheTTL)
+	assert.Equal(t, []string{"bit.ly", "t.co"}, cfg.ShortenerDomains, "lists are lowercased like their variables")
+	assert.Equal(t, []string{"utm_*", "fbclid"}, cfg.StripTrackingParams)
+	assert.Equal(t, map[string]int{"3f2a9c": 600, "partner": config.RateLimitUnlimited}, cfg.RateLimitTiers)
+	assert.Equal(t, int64(128<<10), cfg.MaxRequestBodyBytes)
+	assert.Equal(t, "8081", cfg.ServerPort, "keys missing from the file keep their defaults")
+}
+
+func TestLoadFrom_EnvTiersReplaceFileTiers(t *testing.T) {
+	t.Setenv("RATE_LIMIT_TIERS", "other:30")
+
+	cfg, err := config.LoadFrom(strings.NewReader(sampleConfigYAML))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"other": 30}, cfg.RateLimitTiers)
+}
+
+func TestLoadFrom_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want []string
+	}{
+		{"duration without unit", "cache_ttl: 30", []string{"line 1", "cache_ttl", "duration"}},
+		{"number as text", "short_code_length: eight", []string{"short_code_length", "whole number", `"eight"`}},
+		{"list as scalar", "shortener_domains: bit.ly", []string{"shortener_domains", "list of strings"}},
+		{"unknown key", "server_prot: 8080", []string{"unknown key", "server_prot"}},
+		{"key set twice", "base_url: a\nbase_url: b", []string{"line 2", "base_url"}},
+		{"bad tier", "rate_limit_tiers: {partner: lots}", []string{"rate_limit_tiers", "partner"}},
+		{"tiers as list", "rate_limit_tiers: [a, b]", []string{"rate_limit_tiers", "mapping"}},
+		{"not a mapping", "- base_url", []string{"mapping of settings"}},
+		{"domain with scheme", "shortener_domains: [https://bit.ly]", []string{"SHORTENER_DOMAINS", "https://bit.ly"}},
+		{"wildcard inside param", "strip_tracking_params: ['*_id']", []string{"STRIP_TRACKING_PARAMS"}},
+		{"empty bot entry", "bot_user_agents: ['']", []string{"BOT_USER_AGENTS"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := config.LoadFrom(strings.NewReader(tt.yaml))
+			require.Error(t, err)
+			for _, want := range tt.want {
+				assert.Contains(t, err.Error(), want)
+			}
+		})
+	}
+}
+
+func TestLoadConfig_ConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(sampleConfigYAML), 0o600))
+	t.Setenv("CONFIG_FILE", path)
+
+	cfg, err := config.LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, "https://sho.rt", cfg.BaseURL)
+
+	require.NoError(t, os.WriteFile(path, []byte("cache_ttl: soon"), 0o600))
+	_, err = config.LoadConfig()
+	assert.ErrorContains(t, err, path)
+	assert.ErrorContains(t, err, "cache_ttl")
+}
+
+func TestValidate_RateLimitTiers(t *testing.T) {
+	cfg, err := config.LoadFrom(nil)
+	require.NoError(t, err)
+
+	cfg.RateLimitTiers = map[string]int{"partner": 0}
+	assert.ErrorContains(t, cfg.Validate(), "partner")
+
+	cfg.RateLimitTiers = map[string]int{" ": 10}
+	assert.ErrorContains(t, cfg.Validate(), "empty key ID")
+}
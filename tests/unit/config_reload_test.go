@@ -0,0 +1,250 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/config"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/handler"
+	"url-shortener/internal/service"
+	"url-shortener/pkg/logger"
+)
+
+func loadReloadTestConfig(t *testing.T) *config.Config {
+	cfg, err := config.LoadFrom(nil)
+	require.NoError(t, err)
+	return cfg
+}
+
+func TestRuntime_ApplySwapsReloadableSettings(t *testing.T) {
+	cfg := loadReloadTestConfig(t)
+	rt := config.NewRuntime(cfg)
+	before := rt.Load()
+
+	var published *config.RuntimeConfig
+	rt.OnReload(func(rc *config.RuntimeConfig) { published = rc })
+
+	next := *cfg
+	next.LogLevel = config.LogLevelDebug
+	next.RateLimitRedirects = 5
+	changed, err := rt.Apply(&next)
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"log_level", "rate_limit_redirects"}, changed)
+	assert.Equal(t, 5, rt.Load().RateLimitRedirects)
+	assert.Equal(t, config.LogLevelDebug, rt.Load().LogLevel)
+	assert.Same(t, rt.Load(), published)
+	assert.Equal(t, cfg.RateLimitRedirects, before.RateLimitRedirects, "a value already loaded is never modified")
+
+	again := next
+	changed, err = rt.Apply(&again)
+	require.NoError(t, err)
+	assert.Empty(t, changed)
+}
+
+func TestRuntime_RestartOnlySettingsAreRejected(t *testing.T) {
+	cfg := loadReloadTestConfig(t)
+	rt := config.NewRuntime(cfg)
+
+	next := *cfg
+	next.ServerPort = "9999"
+	next.DBPassword = "new-secret"
+	next.RateLimitRedirects = 5
+	changed, err := rt.Apply(&next)
+
+	require.ErrorIs(t, err, config.ErrRestartRequired)
+	assert.Nil(t, changed)
+	assert.Contains(t, err.Error(), "server_port")
+	assert.Contains(t, err.Error(), "db_password")
+	assert.NotContains(t, err.Error(), "new-secret")
+	assert.Equal(t, cfg.RateLimitRedirects, rt.Load().RateLimitRedirects, "nothing is applied")
+}
+
+func TestRuntime_APILimitsFollowPerMinute(t *testing.T) {
+	cfg := loadReloadTestConfig(t)
+	cfg.RateLimitAPIReads, cfg.RateLimitAPIWrites = 0, 0
+	rt := config.NewRuntime(cfg)
+
+	next := *cfg
+	next.RateLimitPerMinute = 7
+	_, err := rt.Apply(&next)
+
+	require.NoError(t, err)
+	assert.Equal(t, 7, rt.Load().RateLimitAPIReads)
+	assert.Equal(t, 7, rt.Load().RateLimitAPIWrites)
+}
+
+func TestRuntimeRateLimiter_ReloadAppliesToNextRequests(t *testing.T) {
+	cfg := loadReloadTestConfig(t)
+	cfg.RateLimitPerMinute, cfg.RateLimitAPIReads, cfg.RateLimitAPIWrites = 2, 0, 0
+	rt := config.NewRuntime(cfg)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(handler.APIKeyIdentityMiddleware(cfg, nil))
+	router.Use(handler.NewRuntimeRateLimiter(rt).RuntimeMethodMiddleware())
+	router.GET("/ping", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	assert.Equal(t, 2, sendRequests(router, 5, ""))
+
+	next := *cfg
+	next.RateLimitPerMinute = 5
+	_, err := rt.Apply(&next)
+	require.NoError(t, err)
+
+	assert.Equal(t, 5, sendRequests(router, 8, ""), "the raised limit starts from a full bucket")
+}
+
+func TestRuntimeRateLimiter_InFlightRequestsFinishAcrossReload(t *testing.T) {
+	cfg := loadReloadTestConfig(t)
+	cfg.RateLimitRedirects = 3
+	rt := config.NewRuntime(cfg)
+
+	entered, release := make(chan struct{}), make(chan struct{})
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(handler.APIKeyIdentityMiddleware(cfg, nil))
+	router.Use(handler.NewRuntimeRateLimiter(rt).RuntimeRedirectMiddleware())
+	router.GET("/:code", func(c *gin.Context) {
+		close(entered)
+		<-release
+		c.Status(http.StatusOK)
+	})
+
+	done := make(chan int)
+	go func() {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/abc123", nil))
+		done <- w.Code
+	}()
+	<-entered
+
+	next := *cfg
+	next.RateLimitRedirects = 1
+	_, err := rt.Apply(&next)
+	require.NoError(t, err)
+	close(release)
+
+	assert.Equal(t, http.StatusOK, <-done)
+	assert.Equal(t, 1, rt.Load().RateLimitRedirects)
+}
+
+func TestCORSMiddleware_OriginsFollowReload(t *testing.T) {
+	cfg := loadReloadTestConfig(t)
+	cfg.Environment = "production"
+	rt := config.NewRuntime(cfg)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(handler.CORSMiddleware(cfg, rt))
+	router.GET("/ping", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	allowedOrigin := func() string {
+		req := httptest.NewRequest("GET", "/ping", nil)
+		req.Header.Set("Origin", "https://App.example.com")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Header().Get("Access-Control-Allow-Origin")
+	}
+
+	assert.Empty(t, allowedOrigin())
+
+	next := *cfg
+	next.CORSAllowedOrigins = []string{"https://app.example.com"}
+	_, err := rt.Apply(&next)
+	require.NoError(t, err)
+
+	assert.Equal(t, "https://App.example.com", allowedOrigin())
+}
+
+func TestShortenURL_ShortenerDomainsFollowReload(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	rt := config.NewRuntime(suite.cfg)
+	suite.service = service.NewURLService(suite.repo, suite.cache, suite.cfg, suite.logger, service.WithRuntimeConfig(rt))
+
+	next := *suite.cfg
+	next.ShortenerDomains = []string{"bit.ly"}
+	_, err := rt.Apply(&next)
+	require.NoError(t, err)
+
+	_, err = suite.service.ShortenURL(context.Background(), &domain.CreateURLRequest{URL: "https://bit.ly/abc"}, domain.CreatorContext{IP: "192.168.1.1"})
+
+	var appErr *domain.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Contains(t, appErr.Message, "already a short link")
+}
+
+func TestReloadHandler_AppliesConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig := func(content string) {
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	}
+	writeConfig("rate_limit_per_minute: 10\n")
+	t.Setenv("CONFIG_FILE", path)
+
+	cfg, err := config.LoadConfig()
+	require.NoError(t, err)
+	rt := config.NewRuntime(cfg)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/api/v1/admin/reload", handler.NewReloadHandler(rt, logger.NewLogger(logger.Options{})).Reload)
+
+	reload := func() (*httptest.ResponseRecorder, map[string]interface{}) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("POST", "/api/v1/admin/reload", nil))
+		var body map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		return w, body
+	}
+
+	w, body := reload()
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []interface{}{}, body["changed"])
+
+	writeConfig("rate_limit_per_minute: 20\n")
+	w, body = reload()
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []interface{}{"rate_limit_per_minute"}, body["changed"])
+	assert.Equal(t, 20, rt.Load().RateLimitAPIReads)
+
+	writeConfig("rate_limit_per_minute: 30\nserver_port: \"9999\"\n")
+	w, body = reload()
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, "restart_required", body["error"])
+	assert.Contains(t, body["message"], "server_port")
+	assert.Equal(t, 20, rt.Load().RateLimitAPIReads, "a rejected reload applies nothing")
+
+	writeConfig("log_level: verbose\n")
+	w, body = reload()
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, "invalid_config", body["error"])
+	assert.Equal(t, 20, rt.Load().RateLimitAPIReads)
+}
+
+func TestLoadFrom_RejectsInvalidReloadableSettings(t *testing.T) {
+	for _, content := range []string{
+		"log_level: verbose\n",
+		"cors_allowed_origins: [https://app.example.com/path]\n",
+		"cors_allowed_origins: [\"*\"]\n",
+	} {
+		_, err := config.LoadFrom(strings.NewReader(content))
+		assert.Error(t, err, content)
+	}
+
+	cfg, err := config.LoadFrom(strings.NewReader("log_level: WARN\ncors_allowed_origins: [https://App.example.com]\n"))
+	require.NoError(t, err)
+	assert.Equal(t, config.LogLevelWarn, cfg.LogLevel)
+	assert.Equal(t, []string{"https://app.example.com"}, cfg.CORSAllowedOrigins)
+}
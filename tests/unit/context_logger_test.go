@@ -0,0 +1,89 @@
+package unit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/handler"
+	"url-shortener/internal/service"
+	"url-shortener/pkg/logger"
+)
+
+func TestLoggerContext_RoundTrip(t *testing.T) {
+	log, _ := observedLogger(zapcore.InfoLevel)
+
+	assert.Nil(t, logger.FromContext(context.Background()))
+	assert.Same(t, log, logger.FromContext(logger.WithContext(context.Background(), log)))
+}
+
+func TestServiceLogs_CarryRequestFields(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	log, logs := observedLogger(zapcore.InfoLevel)
+	suite := setupURLServiceTest(t)
+	suite.repo.On("FindByOriginalURL", mock.Anything, "https://example.com").Return((*domain.URL)(nil), domain.ErrURLNotFound)
+	suite.repo.On("Create", mock.Anything, mock.AnythingOfType("*domain.URL")).Return(nil)
+	suite.cache.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	svc := service.NewURLService(suite.repo, suite.cache, suite.cfg, log)
+
+	router := gin.New()
+	router.Use(handler.RequestIDMiddleware(), handler.LoggerMiddleware(log, nil))
+	router.POST("/api/v1/shorten", handler.NewURLHandler(svc, suite.cfg, log).ShortenURL)
+
+	req := httptest.NewRequest("POST", "/api/v1/shorten", strings.NewReader(`{"url":"https://example.com"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Request-ID", "req-42")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	created := logs.FilterMessageSnippet("URL shortened successfully").All()
+	require.Len(t, created, 1)
+	fields := created[0].ContextMap()
+	assert.Equal(t, "req-42", fields["request_id"])
+	assert.Equal(t, "/api/v1/shorten", fields["route"])
+	assert.NotEmpty(t, fields["ip"])
+}
+
+func TestServiceLogs_FallBackOutsideRequests(t *testing.T) {
+	log, logs := observedLogger(zapcore.InfoLevel)
+	suite := setupURLServiceTest(t)
+	suite.repo.On("Delete", mock.Anything, "abc123").Return(nil)
+	suite.cache.On("Delete", mock.Anything, mock.Anything).Return(nil)
+	svc := service.NewURLService(suite.repo, suite.cache, suite.cfg, log)
+
+	require.NoError(t, svc.DeleteURL(context.Background(), "abc123"))
+
+	deleted := logs.FilterMessageSnippet("URL deleted").All()
+	require.Len(t, deleted, 1)
+	assert.NotContains(t, deleted[0].ContextMap(), "request_id")
+}
+
+func TestLoggerMiddleware_QuietPathsOnlyAtDebug(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	log, logs := observedLogger(zapcore.DebugLevel)
+	router := gin.New()
+	router.Use(handler.LoggerMiddleware(log, handler.NewQuietPaths([]string{"/health", "/metrics"})))
+	router.GET("/health", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.GET("/metrics", func(c *gin.Context) { c.Status(http.StatusServiceUnavailable) })
+	router.GET("/api/v1/errors", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	for _, path := range []string{"/health", "/metrics", "/api/v1/errors"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	entries := logs.FilterMessageSnippet("HTTP request").All()
+	require.Len(t, entries, 3)
+	assert.Equal(t, zapcore.DebugLevel, entries[0].Level, "a healthy probe stays out of the info log")
+	assert.Equal(t, zapcore.InfoLevel, entries[1].Level, "a failing probe is still logged")
+	assert.Equal(t, zapcore.InfoLevel, entries[2].Level)
+}
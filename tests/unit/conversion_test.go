@@ -0,0 +1,159 @@
+package unit
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/cache"
+	"url-shortener/internal/cache/cachetest"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/handler"
+	"url-shortener/internal/service"
+)
+
+// setupConversionTest serves one link with click IDs attached through store, with one hour receipts
+func setupConversionTest(t *testing.T, store cache.Cache) (*URLServiceTestSuite, *gin.Engine) {
+	suite := setupURLServiceTest(t)
+	suite.cfg.ClickIDSecret = "conversion-secret"
+	suite.cfg.ClickIDTTL = time.Hour
+	suite.cfg.ClickIDMaxSkew = time.Minute
+	suite.service = service.NewURLService(suite.repo, store, suite.cfg, suite.logger)
+	suite.repo.On("FindByShortCode", mock.Anything, "shop01").
+		Return(&domain.URL{ShortCode: "shop01", OriginalURL: "https://shop.example.com/cart?item=7", IsActive: true, AttachClickID: true}, nil)
+	suite.repo.On("IncrementClickCount", mock.Anything, "shop01", mock.Anything).Return(nil)
+
+	gin.SetMode(gin.TestMode)
+	h := handler.NewURLHandler(suite.service, suite.cfg, suite.logger)
+	router := gin.New()
+	router.GET("/:shortCode", h.RedirectURL)
+	router.POST("/api/v1/conversions", h.RecordConversion)
+	return suite, router
+}
+
+// redirectClickID follows the link once and returns the receipt appended to its destination
+func redirectClickID(t *testing.T, router *gin.Engine) string {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/shop01", nil))
+	require.Equal(t, http.StatusFound, w.Code, "each redirect carries its own receipt, so none may be cached")
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+
+	location, err := url.Parse(w.Header().Get("Location"))
+	require.NoError(t, err)
+	assert.Equal(t, "7", location.Query().Get("item"))
+	clickID := location.Query().Get(domain.ClickIDParam)
+	require.NotEmpty(t, clickID)
+	return clickID
+}
+
+func postConversion(router *gin.Engine, clickID string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/api/v1/conversions", bytes.NewBufferString(`{"click_id":"`+clickID+`"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestConversion_RecordedOncePerClick(t *testing.T) {
+	suite, router := setupConversionTest(t, &claimCache{cachetest.NewMemoryCache()})
+	suite.repo.On("IncrementConversionCount", mock.Anything, "shop01").Return(nil).Times(2)
+
+	first, second := redirectClickID(t, router), redirectClickID(t, router)
+	assert.NotEqual(t, first, second)
+
+	w := postConversion(router, first)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"short_code":"shop01"`)
+
+	w = postConversion(router, first)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "click_id_used")
+
+	assert.Equal(t, http.StatusOK, postConversion(router, second).Code)
+	suite.repo.AssertExpectations(t)
+}
+
+func TestConversion_RefusesForgedAndExpiredClickIDs(t *testing.T) {
+	suite, router := setupConversionTest(t, &claimCache{cachetest.NewMemoryCache()})
+	clickID := redirectClickID(t, router)
+
+	payload, signed, _ := strings.Cut(clickID, ".")
+	forged := []string{
+		"",
+		"not-a-receipt",
+		payload + "x." + signed,
+		payload + "." + strings.Replace(signed, ".", "9.", 1), // Expiry pushed out
+	}
+	for _, id := range forged {
+		w := postConversion(router, id)
+		assert.Equal(t, http.StatusBadRequest, w.Code, id)
+	}
+
+	// Issued by an instance with an older clock or ages ago: past its expiry plus the skew
+	suite.cfg.ClickIDTTL = -2 * time.Minute
+	expired := redirectClickID(t, router)
+	w := postConversion(router, expired)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid_click_id")
+
+	suite.repo.AssertNotCalled(t, "IncrementConversionCount", mock.Anything, mock.Anything)
+}
+
+func TestConversion_UnavailableWithoutClaims(t *testing.T) {
+	suite, router := setupConversionTest(t, cachetest.NewMemoryCache())
+
+	w := postConversion(router, redirectClickID(t, router))
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code, "replays couldn't be refused")
+	suite.repo.AssertNotCalled(t, "IncrementConversionCount", mock.Anything, mock.Anything)
+}
+
+func TestConversion_ReleasedWhenNotCounted(t *testing.T) {
+	suite, router := setupConversionTest(t, &claimCache{cachetest.NewMemoryCache()})
+	suite.repo.On("IncrementConversionCount", mock.Anything, "shop01").Return(assert.AnError).Once()
+	suite.repo.On("IncrementConversionCount", mock.Anything, "shop01").Return(nil).Once()
+	clickID := redirectClickID(t, router)
+
+	assert.Equal(t, http.StatusInternalServerError, postConversion(router, clickID).Code)
+	assert.Equal(t, http.StatusOK, postConversion(router, clickID).Code, "the destination may retry")
+}
+
+func TestRedirectURL_NoClickIDWithoutFlag(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	router := setupRedirectRouter(suite)
+	suite.cache.On("Get", mock.Anything, "abc123").Return("https://example.com/landing", nil)
+	suite.repo.On("IncrementClickCount", mock.Anything, "abc123", mock.Anything).Return(nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/abc123", nil))
+
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+	assert.Equal(t, "https://example.com/landing", w.Header().Get("Location"))
+}
+
+func TestGetStats_ConversionRate(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	suite.service = service.NewURLService(suite.repo, cachetest.NewMemoryCache(), suite.cfg, suite.logger)
+	suite.repo.On("GetStats", mock.Anything, "shop01").
+		Return(&domain.URLStats{ShortCode: "shop01", TotalClicks: 40, Conversions: 10}, nil).Once()
+	suite.repo.On("GetStats", mock.Anything, "new001").
+		Return(&domain.URLStats{ShortCode: "new001", Conversions: 1}, nil).Once()
+
+	stats, err := suite.service.GetStats(context.Background(), "shop01")
+	require.NoError(t, err)
+	assert.InDelta(t, 0.25, stats.ConversionRate, 1e-9)
+
+	stats, err = suite.service.GetStats(context.Background(), "new001")
+	require.NoError(t, err)
+	assert.Zero(t, stats.ConversionRate, "no clicks counted yet")
+}
@@ -0,0 +1,171 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/handler"
+)
+
+// setupCreatorRouter registers shorten, the public info endpoint and the admin variants
+func setupCreatorRouter(suite *URLServiceTestSuite) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	suite.cfg.AdminAPIKey = "admin-secret"
+	h := handler.NewURLHandler(suite.service, suite.cfg, suite.logger)
+
+	router := gin.New()
+	router.POST("/api/v1/shorten", h.ShortenURL)
+	router.GET("/api/v1/urls/:shortCode", h.GetURLInfo)
+	router.GET("/api/v1/admin/urls", handler.AdminAuthMiddleware(suite.cfg), h.ListByCreatorIP)
+	router.GET("/api/v1/admin/urls/:shortCode", handler.AdminAuthMiddleware(suite.cfg), h.GetAdminURLInfo)
+	return router
+}
+
+func adminGet(target string) *http.Request {
+	req := httptest.NewRequest("GET", target, nil)
+	req.Header.Set("X-API-Key", "admin-secret")
+	return req
+}
+
+func creatorTestURL() *domain.URL {
+	url := infoTestURL()
+	url.CreatorIP = "203.0.113.9"
+	url.CreatorUserAgent = "curl/8.4.0"
+	url.CreatorOrigin = "https://spam.example"
+	return url
+}
+
+func TestShorten_RecordsCreatorMetadata(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	router := setupCreatorRouter(suite)
+
+	var stored *domain.URL
+	suite.repo.On("FindByOriginalURL", mock.Anything, "https://example.com").Return((*domain.URL)(nil), domain.ErrURLNotFound)
+	suite.repo.On("Create", mock.Anything, mock.AnythingOfType("*domain.URL")).Run(func(args mock.Arguments) {
+		stored = args.Get(1).(*domain.URL)
+	}).Return(nil)
+	suite.cache.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	req := httptest.NewRequest("POST", "/api/v1/shorten", strings.NewReader(`{"url": "https://example.com"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "bot\x00/"+strings.Repeat("x", domain.MaxCreatorUserAgentLength))
+	req.Header.Set("Referer", "https://forum.example/thread/42?session=secret")
+	req.RemoteAddr = "203.0.113.9:4321"
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusCreated, w.Code)
+	require.NotNil(t, stored)
+	assert.Equal(t, "203.0.113.9", stored.CreatorIP)
+	assert.Len(t, stored.CreatorUserAgent, domain.MaxCreatorUserAgentLength)
+	assert.True(t, strings.HasPrefix(stored.CreatorUserAgent, "bot/x"), "control characters are dropped")
+	assert.Equal(t, "https://forum.example", stored.CreatorOrigin, "only the origin of the Referer is kept")
+	assert.NotContains(t, w.Body.String(), "forum.example")
+}
+
+func TestShorten_PrefersOriginHeader(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	router := setupCreatorRouter(suite)
+
+	var stored *domain.URL
+	suite.repo.On("FindByOriginalURL", mock.Anything, "https://example.com").Return((*domain.URL)(nil), domain.ErrURLNotFound)
+	suite.repo.On("Create", mock.Anything, mock.AnythingOfType("*domain.URL")).Run(func(args mock.Arguments) {
+		stored = args.Get(1).(*domain.URL)
+	}).Return(nil)
+	suite.cache.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	req := httptest.NewRequest("POST", "/api/v1/shorten", strings.NewReader(`{"url": "https://example.com"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Origin", "https://app.example")
+	req.Header.Set("Referer", "https://forum.example/thread/42")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "https://app.example", stored.CreatorOrigin)
+}
+
+func TestGetURLInfo_NeverExposesCreator(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	router := setupCreatorRouter(suite)
+	suite.repo.On("FindByShortCode", mock.Anything, "abc123").Return(creatorTestURL(), nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/urls/abc123", nil))
+
+	require.Equal(t, http.StatusOK, w.Code)
+	for _, leaked := range []string{"203.0.113.9", "curl/8.4.0", "spam.example", "creator"} {
+		assert.NotContains(t, w.Body.String(), leaked)
+	}
+}
+
+func TestGetAdminURLInfo_IncludesCreator(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	router := setupCreatorRouter(suite)
+	url := creatorTestURL()
+	url.IsActive = false
+	suite.repo.On("FindAnyByShortCode", mock.Anything, "abc123").Return(url, nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/admin/urls/abc123", nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code, "admin key required")
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, adminGet("/api/v1/admin/urls/abc123"))
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var info domain.AdminURLInfoResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &info))
+	assert.Equal(t, "abc123", info.ShortCode)
+	assert.False(t, info.IsActive, "deactivated links are found")
+	assert.Equal(t, domain.CreatorInfo{IP: "203.0.113.9", UserAgent: "curl/8.4.0", Origin: "https://spam.example"}, info.Creator)
+	assert.Equal(t, "private, no-store", w.Header().Get("Cache-Control"))
+}
+
+func TestListByCreatorIP_Pages(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	links := []domain.URL{*creatorTestURL(), *creatorTestURL(), *creatorTestURL()}
+	links[0].ShortCode, links[1].ShortCode, links[2].ShortCode = "third", "second", "first"
+	suite.repo.On("FindByCreatorIP", mock.Anything, "203.0.113.9", 3, 0).Return(links, nil)
+	suite.repo.On("FindByCreatorIP", mock.Anything, "203.0.113.9", 3, 2).Return(links[2:], nil)
+
+	page, err := suite.service.ListByCreatorIP(context.Background(), " 203.0.113.9 ", 2, 0)
+	require.NoError(t, err)
+	require.Len(t, page.URLs, 2)
+	assert.Equal(t, "third", page.URLs[0].ShortCode)
+	assert.Equal(t, "curl/8.4.0", page.URLs[0].Creator.UserAgent)
+	require.NotNil(t, page.NextOffset)
+	assert.Equal(t, 2, *page.NextOffset)
+
+	page, err = suite.service.ListByCreatorIP(context.Background(), "203.0.113.9", 2, 2)
+	require.NoError(t, err)
+	require.Len(t, page.URLs, 1)
+	assert.Nil(t, page.NextOffset, "the last page has no next offset")
+}
+
+func TestListByCreatorIP_Handler(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	router := setupCreatorRouter(suite)
+	suite.repo.On("FindByCreatorIP", mock.Anything, "2001:db8::1", 101, 0).Return([]domain.URL{}, nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, adminGet("/api/v1/admin/urls?creator_ip=2001:db8::1"))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"creator_ip": "2001:db8::1", "urls": [], "limit": 100, "offset": 0}`, w.Body.String())
+
+	for _, target := range []string{"/api/v1/admin/urls?creator_ip=spam", "/api/v1/admin/urls", "/api/v1/admin/urls?creator_ip=203.0.113.9&limit=ten", "/api/v1/admin/urls?creator_ip=203.0.113.9&offset=-1"} {
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, adminGet(target))
+		assert.Equal(t, http.StatusBadRequest, w.Code, target)
+	}
+}
@@ -0,0 +1,114 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/scheduler"
+	"url-shortener/internal/service"
+	"url-shortener/pkg/logger"
+)
+
+// expectEmptyDailySeries stubs the daily series for GetStats tests that don't look at it
+func expectEmptyDailySeries(clicks *MockClickRepository, shortCode string) {
+	clicks.On("GetDailySeries", mock.Anything, shortCode, mock.Anything, mock.Anything).
+		Return([]domain.DailyClickStats{}, nil)
+	clicks.On("AggregateDay", mock.Anything, shortCode, mock.Anything).
+		Return(&domain.DailyClickStats{Date: time.Now().UTC().Format("2006-01-02")}, nil)
+}
+
+func TestGetStats_DailySeriesCombinesRollupAndToday(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	clicks := new(MockClickRepository)
+	svc := service.NewURLService(suite.repo, suite.cache, suite.cfg, suite.logger, service.WithClickRepository(clicks))
+	ctx := context.Background()
+
+	now := time.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	twoDaysAgo := today.AddDate(0, 0, -2).Format("2006-01-02")
+
+	suite.repo.On("GetStats", ctx, "abc123").Return(&domain.URLStats{ShortCode: "abc123"}, nil)
+	clicks.On("CountByTarget", ctx, "abc123").Return(map[string]int64{}, nil)
+	clicks.On("GetDailySeries", ctx, "abc123", today.AddDate(0, 0, -29), today).
+		Return([]domain.DailyClickStats{{Date: twoDaysAgo, Clicks: 7, UniqueIPs: 3, TopReferrer: "news.example"}}, nil)
+	clicks.On("AggregateDay", ctx, "abc123", today).
+		Return(&domain.DailyClickStats{Date: today.Format("2006-01-02"), Clicks: 2, UniqueIPs: 2}, nil)
+
+	stats, err := svc.GetStats(ctx, "abc123")
+
+	require.NoError(t, err)
+	require.Len(t, stats.Daily, 30)
+	assert.Equal(t, today.AddDate(0, 0, -29).Format("2006-01-02"), stats.Daily[0].Date)
+	assert.Equal(t, int64(7), stats.Daily[27].Clicks)
+	assert.Equal(t, "news.example", stats.Daily[27].TopReferrer)
+	// Yesterday has no rollup row and is zero-filled
+	assert.Equal(t, int64(0), stats.Daily[28].Clicks)
+	assert.Equal(t, int64(2), stats.Daily[29].Clicks)
+}
+
+func TestRecordClick_StoresVisitorIPAndReferrerHost(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	clicks := new(MockClickRepository)
+	svc := service.NewURLService(suite.repo, suite.cache, suite.cfg, suite.logger, service.WithClickRepository(clicks))
+	ctx := context.Background()
+
+	suite.cache.On("Get", ctx, "abc123").Return("", nil)
+	suite.repo.On("FindByShortCode", ctx, "abc123").
+		Return(&domain.URL{ShortCode: "abc123", OriginalURL: "https://example.com", IsActive: true}, nil)
+	suite.repo.On("IncrementClickCount", ctx, "abc123", mock.Anything).Return(nil)
+	suite.cache.On("Set", mock.Anything, "abc123", mock.Anything, time.Hour).Return(nil)
+
+	var recorded []*domain.ClickEvent
+	clicks.On("Record", ctx, mock.AnythingOfType("*domain.ClickEvent")).
+		Run(func(args mock.Arguments) { recorded = append(recorded, args.Get(1).(*domain.ClickEvent)) }).
+		Return(nil)
+
+	_, err := svc.GetOriginalURL(ctx, "abc123", domain.Visitor{IP: "203.0.113.7", Referrer: "https://News.Example/story?id=1"})
+	require.NoError(t, err)
+	// Our own interstitial page is not reported as a referrer
+	_, err = svc.GetOriginalURL(ctx, "abc123", domain.Visitor{IP: "203.0.113.8", Referrer: "https://short.url/abc123"})
+	require.NoError(t, err)
+
+	require.Len(t, recorded, 2)
+	assert.Equal(t, "203.0.113.7", recorded[0].IP)
+	assert.Equal(t, "news.example", recorded[0].Referrer)
+	assert.Empty(t, recorded[1].Referrer)
+}
+
+func TestScheduler_RunsJobsUntilCancelled(t *testing.T) {
+	jobs := scheduler.New(logger.NewLogger(logger.Options{}))
+
+	var runs, failures int64
+	jobs.Add(scheduler.Job{Name: "count", Interval: 5 * time.Millisecond, Run: func(ctx context.Context) error {
+		atomic.AddInt64(&runs, 1)
+		return nil
+	}})
+	jobs.Add(scheduler.Job{Name: "failing", Interval: 5 * time.Millisecond, Run: func(ctx context.Context) error {
+		atomic.AddInt64(&failures, 1)
+		return errors.New("boom")
+	}})
+	jobs.Add(scheduler.Job{Name: "disabled", Interval: 0, Run: func(ctx context.Context) error {
+		t.Error("disabled job must not run")
+		return nil
+	}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	jobs.Start(ctx)
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt64(&runs) >= 3 && atomic.LoadInt64(&failures) >= 3
+	}, time.Second, time.Millisecond)
+
+	cancel()
+	jobs.Wait()
+	stopped := atomic.LoadInt64(&runs)
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, stopped, atomic.LoadInt64(&runs))
+}
@@ -0,0 +1,33 @@
+package unit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/config"
+)
+
+func TestValidate_DBDriver(t *testing.T) {
+	cfg, err := config.LoadFrom(nil)
+	require.NoError(t, err)
+	assert.Equal(t, config.DBDriverPostgres, cfg.DBDriver)
+
+	cfg.DBDriver = "mysql"
+	assert.ErrorContains(t, cfg.Validate(), "DB_DRIVER")
+
+	cfg.DBDriver = config.DBDriverMongo
+	assert.ErrorContains(t, cfg.Validate(), "MONGO_URI")
+
+	cfg.MongoURI = "mongodb://localhost:27017"
+	assert.NoError(t, cfg.Validate())
+
+	// Both work on the Postgres urls table
+	cfg.DBReplicaDSNs = []string{"host=replica-1"}
+	assert.ErrorContains(t, cfg.Validate(), "DB_REPLICA_DSNS")
+	cfg.DBReplicaDSNs = nil
+
+	cfg.EventsDriver = config.EventsDriverNATS
+	assert.ErrorContains(t, cfg.Validate(), "EVENTS_DRIVER")
+}
@@ -0,0 +1,128 @@
+package unit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"url-shortener/internal/config"
+	"url-shortener/internal/handler"
+	"url-shortener/internal/repository/postgres"
+	"url-shortener/pkg/logger"
+)
+
+// observedLogger returns a logger whose entries at level and above can be inspected
+func observedLogger(level zapcore.Level) (*logger.Logger, *observer.ObservedLogs) {
+	core, logs := observer.New(level)
+	return &logger.Logger{SugaredLogger: zap.New(core).Sugar()}, logs
+}
+
+func TestStatementDigest_StripsLiterals(t *testing.T) {
+	statement, digest := postgres.StatementDigest(`SELECT * FROM "urls" WHERE short_code = 'abc123' AND is_active = true
+		AND id IN (1, 2, 3) AND note = 'it''s' LIMIT 1`)
+
+	assert.Equal(t, `SELECT * FROM "urls" WHERE short_code = ? AND is_active = true AND id IN (?) AND note = ? LIMIT ?`, statement)
+	assert.Len(t, digest, 16)
+
+	_, other := postgres.StatementDigest(`SELECT * FROM "urls" WHERE short_code = 'zzz999' AND is_active = true AND id IN (7) AND note = 'x' LIMIT 1`)
+	assert.Equal(t, digest, other, "statements differing only in values share a digest")
+
+	statement, _ = postgres.StatementDigest(`UPDATE "urls" SET "utm_source"='a' WHERE idx_2 = 5`)
+	assert.Equal(t, `UPDATE "urls" SET "utm_source"=? WHERE idx_2 = ?`, statement, "digits inside identifiers are kept")
+}
+
+func TestQueryLogger_SlowQueryLoggedWithRequestID(t *testing.T) {
+	log, logs := observedLogger(zapcore.DebugLevel)
+	queryLogger := postgres.NewQueryLogger(log, 100*time.Millisecond)
+	ctx := logger.ContextWithRequestID(context.Background(), "req-42")
+	sql := func() (string, int64) { return `SELECT * FROM "urls" WHERE original_url = 'https://secret.example/path'`, 1 }
+
+	queryLogger.Trace(ctx, time.Now().Add(-10*time.Millisecond), sql, nil)
+	assert.Zero(t, logs.Len(), "fast queries aren't logged")
+
+	queryLogger.Trace(ctx, time.Now().Add(-time.Second), sql, nil)
+	require.Equal(t, 1, logs.Len())
+
+	entry := logs.All()[0]
+	assert.Equal(t, zapcore.WarnLevel, entry.Level)
+	assert.Equal(t, "Slow query", entry.Message)
+	fields := entry.ContextMap()
+	assert.Equal(t, "req-42", fields["request_id"])
+	assert.NotEmpty(t, fields["digest"])
+	assert.NotContains(t, fields["statement"], "secret.example")
+}
+
+func TestQueryLogger_ZeroThresholdDisablesSlowLog(t *testing.T) {
+	log, logs := observedLogger(zapcore.DebugLevel)
+	queryLogger := postgres.NewQueryLogger(log, 0)
+
+	queryLogger.Trace(context.Background(), time.Now().Add(-time.Hour), func() (string, int64) { return "SELECT 1", 1 }, nil)
+
+	assert.Zero(t, logs.Len())
+}
+
+func TestRequestIDMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(handler.RequestIDMiddleware())
+	router.GET("/", func(c *gin.Context) {
+		c.String(http.StatusOK, logger.RequestIDFromContext(c.Request.Context()))
+	})
+
+	tests := []struct {
+		name     string
+		incoming string
+		kept     bool
+	}{
+		{"generated when missing", "", false},
+		{"proxy ID kept", "edge-7f3a.1", true},
+		{"unsafe ID replaced", "bad id\n", false},
+		{"overlong ID replaced", strings.Repeat("a", 65), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			if tt.incoming != "" {
+				req.Header.Set("X-Request-ID", tt.incoming)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			id := w.Header().Get("X-Request-ID")
+			assert.Equal(t, id, w.Body.String(), "the context carries the echoed ID")
+			if tt.kept {
+				assert.Equal(t, tt.incoming, id)
+			} else {
+				assert.Len(t, id, 16)
+			}
+		})
+	}
+}
+
+func TestLoadConfig_DBPoolValidation(t *testing.T) {
+	t.Setenv("DB_MAX_OPEN_CONNS", "20")
+	t.Setenv("DB_MAX_IDLE_CONNS", "50")
+	_, err := config.LoadConfig()
+	assert.ErrorContains(t, err, "DB_MAX_IDLE_CONNS")
+
+	t.Setenv("DB_MAX_IDLE_CONNS", "5")
+	t.Setenv("DB_CONN_MAX_LIFETIME", "5m")
+	t.Setenv("DB_CONN_MAX_IDLE_TIME", "90")
+	t.Setenv("DB_SLOW_QUERY_MS", "250")
+	cfg, err := config.LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, 5*time.Minute, cfg.DBConnMaxLifetime)
+	assert.Equal(t, 90*time.Second, cfg.DBConnMaxIdleTime, "bare numbers are seconds")
+	assert.Equal(t, 250*time.Millisecond, cfg.DBSlowQueryThreshold)
+}
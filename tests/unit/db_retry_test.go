@@ -0,0 +1,205 @@
+package unit
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	gormpostgres "gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"url-shortener/internal/config"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/metrics"
+	"url-shortener/internal/repository"
+	"url-shortener/internal/repository/postgres"
+	"url-shortener/pkg/logger"
+)
+
+// flakyDriver is a database/sql driver whose next statements fail with the queued errors, one each
+// Once they are used up every query returns a single link row and every exec affects one row
+type flakyDriver struct {
+	mu         sync.Mutex
+	failures   []error
+	statements int
+}
+
+func (d *flakyDriver) Connect(context.Context) (driver.Conn, error) { return flakyConn{d}, nil }
+func (d *flakyDriver) Driver() driver.Driver                        { return nil }
+
+// next counts a statement and returns the error it fails with, nil when none is queued
+func (d *flakyDriver) next() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.statements++
+	if len(d.failures) == 0 {
+		return nil
+	}
+	err := d.failures[0]
+	d.failures = d.failures[1:]
+	return err
+}
+
+func (d *flakyDriver) count() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.statements
+}
+
+type flakyConn struct{ d *flakyDriver }
+
+func (c flakyConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("prepared statements are not supported")
+}
+func (c flakyConn) Close() error              { return nil }
+func (c flakyConn) Begin() (driver.Tx, error) { return flakyTx{}, nil }
+
+func (c flakyConn) QueryContext(context.Context, string, []driver.NamedValue) (driver.Rows, error) {
+	if err := c.d.next(); err != nil {
+		return nil, err
+	}
+	return &linkRows{}, nil
+}
+
+func (c flakyConn) ExecContext(context.Context, string, []driver.NamedValue) (driver.Result, error) {
+	if err := c.d.next(); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(1), nil
+}
+
+type flakyTx struct{}
+
+func (flakyTx) Commit() error   { return nil }
+func (flakyTx) Rollback() error { return nil }
+
+// linkRows is the single row of an active link abc123
+type linkRows struct{ done bool }
+
+func (r *linkRows) Columns() []string { return []string{"id", "short_code", "original_url", "is_active"} }
+func (r *linkRows) Close() error      { return nil }
+
+func (r *linkRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0], dest[1], dest[2], dest[3] = int64(1), "abc123", "https://example.com", true
+	return nil
+}
+
+// setupRetryingRepo opens GORM on a flakyDriver failing with failures and wraps its repository for two retries
+func setupRetryingRepo(t *testing.T, failures ...error) (repository.URLRepository, *flakyDriver, *gorm.DB) {
+	d := &flakyDriver{failures: failures}
+	db, err := gorm.Open(gormpostgres.New(gormpostgres.Config{Conn: sql.OpenDB(d)}), &gorm.Config{SkipDefaultTransaction: true})
+	require.NoError(t, err)
+	policy := postgres.RetryPolicy{Attempts: 2, Backoff: time.Millisecond}
+	return postgres.NewRetryingURLRepository(postgres.NewURLRepository(db), policy, logger.NewLogger(logger.Options{})), d, db
+}
+
+func TestRetryingRepository_ReadSucceedsAfterTransientFailure(t *testing.T) {
+	failures := map[string]error{
+		"serialization failure": &pgconn.PgError{Code: "40001"},
+		"connection reset":      io.ErrUnexpectedEOF,
+		"admin shutdown":        &pgconn.PgError{Code: "57P01"},
+		"server starting up":    &pgconn.PgError{Code: "57P03"},
+	}
+	for name, failure := range failures {
+		t.Run(name, func(t *testing.T) {
+			repo, d, _ := setupRetryingRepo(t, failure)
+			before := testutil.ToFloat64(metrics.DBRetries.WithLabelValues("FindByShortCode"))
+
+			url, err := repo.FindByShortCode(context.Background(), "abc123")
+
+			require.NoError(t, err)
+			assert.Equal(t, "https://example.com", url.OriginalURL)
+			assert.Equal(t, 2, d.count())
+			assert.Equal(t, float64(1), testutil.ToFloat64(metrics.DBRetries.WithLabelValues("FindByShortCode"))-before)
+		})
+	}
+}
+
+func TestRetryingRepository_GivesUpAfterTheAttempts(t *testing.T) {
+	outage := &pgconn.PgError{Code: "08006"}
+	repo, d, _ := setupRetryingRepo(t, outage, outage, outage, outage)
+
+	_, err := repo.FindByShortCode(context.Background(), "abc123")
+
+	assert.ErrorIs(t, err, domain.ErrDependencyUnavailable)
+	assert.Equal(t, 3, d.count(), "the first try and two retries")
+}
+
+func TestRetryingRepository_PermanentErrorsAreNotRetried(t *testing.T) {
+	for _, failure := range []error{&pgconn.PgError{Code: "42601"}, errors.New("boom"), context.DeadlineExceeded} {
+		repo, d, _ := setupRetryingRepo(t, failure)
+
+		_, err := repo.FindByShortCode(context.Background(), "abc123")
+
+		require.Error(t, err)
+		assert.Equal(t, 1, d.count(), failure.Error())
+	}
+}
+
+func TestRetryingRepository_WritesOnlyRetriedBeforeExecution(t *testing.T) {
+	// The server refused the connection, so the insert never ran
+	repo, d, _ := setupRetryingRepo(t, &pgconn.PgError{Code: "57P03"})
+	require.NoError(t, repo.Create(context.Background(), &domain.URL{ShortCode: "abc123", OriginalURL: "https://example.com"}))
+	assert.Equal(t, 2, d.count())
+
+	// The connection dropped after the insert was sent; it may have been applied
+	repo, d, _ = setupRetryingRepo(t, io.ErrUnexpectedEOF)
+	assert.Error(t, repo.Create(context.Background(), &domain.URL{ShortCode: "abc123", OriginalURL: "https://example.com"}))
+	assert.Equal(t, 1, d.count())
+
+	repo, d, _ = setupRetryingRepo(t, &pgconn.PgError{Code: "40001"})
+	assert.Error(t, repo.Update(context.Background(), &domain.URL{ID: 1, ShortCode: "abc123", OriginalURL: "https://example.com"}))
+	assert.Equal(t, 1, d.count())
+}
+
+func TestRetryingRepository_NoRetryInsideTransaction(t *testing.T) {
+	repo, d, db := setupRetryingRepo(t, &pgconn.PgError{Code: "40001"})
+
+	err := postgres.NewTransactor(db).WithinTx(context.Background(), func(ctx context.Context) error {
+		_, err := repo.FindByShortCode(ctx, "abc123")
+		return err
+	})
+
+	assert.Error(t, err, "the whole transaction has to be run again, not the statement")
+	assert.Equal(t, 1, d.count())
+}
+
+func TestRetryingRepository_StopsWhenTheCallerGivesUp(t *testing.T) {
+	d := &flakyDriver{failures: []error{&pgconn.PgError{Code: "40001"}}}
+	db, err := gorm.Open(gormpostgres.New(gormpostgres.Config{Conn: sql.OpenDB(d)}), &gorm.Config{})
+	require.NoError(t, err)
+	repo := postgres.NewRetryingURLRepository(postgres.NewURLRepository(db), postgres.RetryPolicy{Attempts: 2, Backoff: time.Hour}, logger.NewLogger(logger.Options{}))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err = repo.FindByShortCode(ctx, "abc123")
+
+	assert.Error(t, err)
+	assert.Equal(t, 1, d.count(), "no retry once the deadline passed during the backoff")
+}
+
+func TestValidate_DBRetries(t *testing.T) {
+	cfg, err := config.LoadFrom(nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, cfg.DBRetries)
+
+	cfg.DBRetries = 6
+	assert.ErrorContains(t, cfg.Validate(), "DB_RETRIES")
+
+	cfg.DBRetries = 1
+	cfg.DBRetryBackoff = 0
+	assert.ErrorContains(t, cfg.Validate(), "DB_RETRY_BACKOFF_MS")
+}
@@ -0,0 +1,173 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/cache/cachetest"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/service"
+)
+
+// setupDedupCacheTest builds a service on an in-memory cache with the dedup cache enabled
+// Create stamps CreatedAt the way the database default would; cache writes are not queued,
+// so a repeated create sees the entry of the one before
+func setupDedupCacheTest(t *testing.T) (*URLServiceTestSuite, *cachetest.MemoryCache) {
+	suite := setupURLServiceTest(t)
+	suite.cfg.DedupCacheTTL = time.Hour
+
+	store := cachetest.NewMemoryCache()
+	suite.service = service.NewURLService(suite.repo, store, suite.cfg, suite.logger)
+	drainCacheWrites(t, suite)
+
+	suite.repo.On("Create", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		args.Get(1).(*domain.URL).CreatedAt = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	}).Return(nil)
+	return suite, store
+}
+
+func TestShortenURL_RepeatCreateServedFromDedupCache(t *testing.T) {
+	suite, _ := setupDedupCacheTest(t)
+	ctx := context.Background()
+	suite.repo.On("FindByOriginalURL", ctx, "https://example.com/import").Return((*domain.URL)(nil), domain.ErrURLNotFound)
+
+	first, err := suite.service.ShortenURL(ctx, &domain.CreateURLRequest{URL: "https://example.com/import"}, domain.CreatorContext{IP: "192.168.1.1"})
+	require.NoError(t, err)
+
+	again, err := suite.service.ShortenURL(ctx, &domain.CreateURLRequest{URL: "https://Example.com/import/"}, domain.CreatorContext{IP: "192.168.1.1"})
+	require.NoError(t, err)
+
+	assert.Equal(t, first.ShortCode, again.ShortCode)
+	assert.Equal(t, first.ShortURL, again.ShortURL)
+	assert.Equal(t, "https://example.com/import", again.OriginalURL)
+	assert.True(t, first.CreatedAt.Equal(again.CreatedAt))
+	require.NotNil(t, again.ExpiresAt)
+	assert.True(t, first.ExpiresAt.Equal(*again.ExpiresAt))
+
+	suite.repo.AssertNumberOfCalls(t, "FindByOriginalURL", 1)
+	suite.repo.AssertNumberOfCalls(t, "Create", 1)
+}
+
+func TestShortenURL_DatabaseDuplicateIsCached(t *testing.T) {
+	suite, _ := setupDedupCacheTest(t)
+	ctx := context.Background()
+	suite.repo.On("FindByOriginalURL", ctx, "https://example.com").
+		Return(&domain.URL{ShortCode: "exist1", OriginalURL: "https://example.com", IsActive: true}, nil)
+
+	for i := 0; i < 3; i++ {
+		resp, err := suite.service.ShortenURL(ctx, &domain.CreateURLRequest{URL: "https://example.com"}, domain.CreatorContext{IP: "192.168.1.1"})
+		require.NoError(t, err)
+		assert.Equal(t, "exist1", resp.ShortCode)
+	}
+
+	suite.repo.AssertNumberOfCalls(t, "FindByOriginalURL", 1)
+	suite.repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestShortenURL_DedupCacheKeepsSettingsApart(t *testing.T) {
+	suite, _ := setupDedupCacheTest(t)
+	ctx := context.Background()
+	suite.repo.On("FindByOriginalURL", ctx, "https://example.com").Return((*domain.URL)(nil), domain.ErrURLNotFound)
+
+	plain, err := suite.service.ShortenURL(ctx, &domain.CreateURLRequest{URL: "https://example.com"}, domain.CreatorContext{IP: "192.168.1.1"})
+	require.NoError(t, err)
+
+	tagged, err := suite.service.ShortenURL(ctx, &domain.CreateURLRequest{
+		URL: "https://example.com",
+		UTM: &domain.UTMParams{Source: "newsletter"},
+	}, domain.CreatorContext{IP: "192.168.1.1"})
+	require.NoError(t, err)
+
+	assert.NotEqual(t, plain.ShortCode, tagged.ShortCode)
+	suite.repo.AssertNumberOfCalls(t, "Create", 2)
+}
+
+func TestShortenURL_DedupCacheDroppedWithLink(t *testing.T) {
+	suite, store := setupDedupCacheTest(t)
+	ctx := context.Background()
+	suite.repo.On("FindByOriginalURL", ctx, "https://example.com").Return((*domain.URL)(nil), domain.ErrURLNotFound)
+
+	first, err := suite.service.ShortenURL(ctx, &domain.CreateURLRequest{URL: "https://example.com"}, domain.CreatorContext{IP: "192.168.1.1"})
+	require.NoError(t, err)
+	require.Len(t, store.Keys("dedup:"), 1)
+
+	suite.repo.On("Delete", ctx, first.ShortCode).Return(nil)
+	require.NoError(t, suite.service.DeleteURL(ctx, first.ShortCode))
+	assert.Empty(t, store.Keys("dedup:"))
+	assert.Empty(t, store.Keys("dedup-ref:"))
+
+	_, err = suite.service.ShortenURL(ctx, &domain.CreateURLRequest{URL: "https://example.com"}, domain.CreatorContext{IP: "192.168.1.1"})
+	require.NoError(t, err)
+	suite.repo.AssertNumberOfCalls(t, "FindByOriginalURL", 2)
+}
+
+func TestShortenURL_DedupCacheNeverOutlivesLink(t *testing.T) {
+	suite, store := setupDedupCacheTest(t)
+	suite.cfg.DedupCacheTTL = 90 * 24 * time.Hour // Longer than the 30-day link lifetime
+	ctx := context.Background()
+	suite.repo.On("FindByOriginalURL", ctx, "https://example.com").Return((*domain.URL)(nil), domain.ErrURLNotFound)
+
+	_, err := suite.service.ShortenURL(ctx, &domain.CreateURLRequest{URL: "https://example.com"}, domain.CreatorContext{IP: "192.168.1.1"})
+	require.NoError(t, err)
+
+	keys := store.Keys("dedup:")
+	require.Len(t, keys, 1)
+	assert.LessOrEqual(t, store.TTL(keys[0]), 30*24*time.Hour)
+}
+
+func TestShortenURL_DedupCacheDisabled(t *testing.T) {
+	suite, store := setupDedupCacheTest(t)
+	suite.cfg.DedupCacheTTL = 0
+	ctx := context.Background()
+	suite.repo.On("FindByOriginalURL", ctx, "https://example.com").Return((*domain.URL)(nil), domain.ErrURLNotFound)
+
+	for i := 0; i < 2; i++ {
+		_, err := suite.service.ShortenURL(ctx, &domain.CreateURLRequest{URL: "https://example.com"}, domain.CreatorContext{IP: "192.168.1.1"})
+		require.NoError(t, err)
+	}
+
+	assert.Empty(t, store.Keys("dedup"))
+	suite.repo.AssertNumberOfCalls(t, "FindByOriginalURL", 2)
+}
+
+func TestShortenHandler_DuplicateAnswers200WithCanonicalLink(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	suite.repo.On("FindByOriginalURL", mock.Anything, "https://example.com/new").Return(nil, domain.ErrURLNotFound)
+	suite.repo.On("FindByOriginalURL", mock.Anything, "https://example.com/old").
+		Return(&domain.URL{ShortCode: "old123", OriginalURL: "https://example.com/old", IsActive: true}, nil)
+	suite.repo.On("Create", mock.Anything, mock.AnythingOfType("*domain.URL")).Return(nil)
+	suite.cache.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	router := setupShortenRouter(suite, 1024)
+
+	tests := []struct {
+		url          string
+		status       int
+		deduplicated bool
+	}{
+		{"https://example.com/new", http.StatusCreated, false},
+		{"https://example.com/old", http.StatusOK, true},
+	}
+
+	for _, tt := range tests {
+		body := `{"url": "` + tt.url + `"}`
+		w, _ := postShorten(t, router, strings.NewReader(body), int64(len(body)))
+		require.Equal(t, tt.status, w.Code, w.Body.String())
+
+		var resp domain.CreateURLResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, tt.deduplicated, resp.Deduplicated)
+		if tt.deduplicated {
+			assert.Equal(t, `<https://short.url/old123>; rel="canonical"`, w.Header().Get("Link"))
+		} else {
+			assert.Empty(t, w.Header().Get("Link"))
+		}
+	}
+}
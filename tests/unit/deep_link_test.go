@@ -0,0 +1,225 @@
+package unit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/cache"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/redirect"
+	"url-shortener/internal/service"
+	"url-shortener/pkg/validator"
+)
+
+// appURL is a link that opens the app with a custom scheme, and the iOS App Store when it's missing
+var appURL = &domain.URL{
+	ShortCode:      "shop",
+	OriginalURL:    "https://example.com/product/42",
+	IsActive:       true,
+	DeepLink:       "myapp://product/42",
+	IOSFallbackURL: "https://apps.apple.com/app/id123",
+}
+
+func TestValidateDeepLink(t *testing.T) {
+	for _, link := range []string{"myapp://product/42", "fb://profile?id=1", "https://example.com/app/42", "com.example.app:/open"} {
+		assert.NoError(t, validator.ValidateDeepLink(link), link)
+	}
+	for _, link := range []string{"", "myapp://", "javascript://alert(1)", "JavaScript:alert(1)", "data:text/html,x",
+		"file:///etc/passwd", "http://example.com", "intent://x#Intent;end", "myapp://a b", "1app://x", "https://"} {
+		assert.Error(t, validator.ValidateDeepLink(link), link)
+	}
+}
+
+func TestLaunchApp(t *testing.T) {
+	link := domain.AppLink{DeepLink: "myapp://product/42", IOSFallback: "https://apps.apple.com/app/id123"}
+	web := redirect.Result{Destination: "https://example.com/product/42", Target: redirect.DefaultTarget}
+
+	result, app := redirect.LaunchApp(link, web, desktopUA)
+	assert.Equal(t, web, result)
+	assert.Nil(t, app)
+
+	result, app = redirect.LaunchApp(link, web, iPhoneUA)
+	assert.Equal(t, "https://apps.apple.com/app/id123", result.Destination)
+	assert.Equal(t, "app:ios", result.Target)
+	assert.Equal(t, &domain.AppLaunch{DeepLink: "myapp://product/42"}, app)
+
+	// Without an Android fallback the link's own destination is used
+	result, app = redirect.LaunchApp(link, web, androidUA)
+	assert.Equal(t, "https://example.com/product/42", result.Destination)
+	assert.Equal(t, "app:android", result.Target)
+	assert.True(t, app.Android)
+
+	// Universal links are redirected to directly
+	result, app = redirect.LaunchApp(domain.AppLink{DeepLink: "https://app.example.com/p/42"}, web, iPhoneUA)
+	assert.Equal(t, "https://app.example.com/p/42", result.Destination)
+	assert.Nil(t, app)
+}
+
+func TestIntentURL(t *testing.T) {
+	assert.Equal(t,
+		"intent://product/42?ref=sms#Intent;scheme=myapp;S.browser_fallback_url=https%3A%2F%2Fexample.com%2Fp%3Fa%3D1;end",
+		redirect.IntentURL("myapp://product/42?ref=sms#top", "https://example.com/p?a=1"))
+}
+
+func TestShortenURL_DeepLinkValidation(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	ctx := context.Background()
+
+	cases := map[string]*domain.CreateURLRequest{
+		"custom scheme destination": {URL: "myapp://product/42"},
+		"script deep link":          {URL: "https://example.com", DeepLink: "javascript:alert(1)"},
+		"custom scheme fallback":    {URL: "https://example.com", DeepLink: "myapp://x", AndroidFallbackURL: "market://details?id=x"},
+		"fallback alone":            {URL: "https://example.com", IOSFallbackURL: "https://apps.apple.com/app/id123"},
+		"bundle":                    {Bundle: []domain.BundleItem{{URL: "https://example.com"}}, DeepLink: "myapp://x"},
+	}
+	for name, req := range cases {
+		_, err := suite.service.ShortenURL(ctx, req, domain.CreatorContext{IP: "192.168.1.1"})
+		var appErr *domain.AppError
+		require.ErrorAs(t, err, &appErr, name)
+		assert.Equal(t, http.StatusBadRequest, appErr.StatusCode, name)
+	}
+	suite.repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestShortenURL_DeepLinksAreNotDeduplicated(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	ctx := context.Background()
+
+	suite.repo.On("FindByOriginalURL", ctx, "https://example.com/product/42").
+		Return(&domain.URL{ShortCode: "plain01", OriginalURL: "https://example.com/product/42", IsActive: true}, nil)
+	suite.repo.On("Create", ctx, mock.MatchedBy(func(u *domain.URL) bool {
+		return u.DeepLink == "myapp://product/42" && u.AndroidFallbackURL == "https://play.google.com/store/apps/details?id=app"
+	})).Return(nil).Once()
+	suite.cache.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	resp, err := suite.service.ShortenURL(ctx, &domain.CreateURLRequest{
+		URL:                "https://example.com/product/42",
+		DeepLink:           " myapp://product/42 ",
+		AndroidFallbackURL: "https://play.google.com/store/apps/details?id=app",
+	}, domain.CreatorContext{IP: "192.168.1.1"})
+
+	require.NoError(t, err)
+	assert.NotEqual(t, "plain01", resp.ShortCode)
+	suite.repo.AssertExpectations(t)
+}
+
+func TestRedirectURL_AppPageForMobileVisitors(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	clicks := new(MockClickRepository)
+	suite.service = service.NewURLService(suite.repo, suite.cache, suite.cfg, suite.logger, service.WithClickRepository(clicks))
+	router := setupRedirectRouter(suite)
+
+	suite.cache.On("Get", mock.Anything, "shop").Return("", nil)
+	suite.repo.On("FindByShortCode", mock.Anything, "shop").Return(appURL, nil)
+	suite.repo.On("IncrementClickCount", mock.Anything, "shop", mock.Anything).Return(nil).Once()
+	suite.cache.On("Set", mock.Anything, "shop", mock.Anything, time.Hour).Return(nil)
+	clicks.On("Record", mock.Anything, mock.MatchedBy(func(e *domain.ClickEvent) bool {
+		return e.Target == "app:ios"
+	})).Return(nil).Once()
+
+	req := httptest.NewRequest("GET", "/shop", nil)
+	req.Header.Set("User-Agent", iPhoneUA)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	drainCacheWrites(t, suite)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("Location"))
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+	assert.Contains(t, w.Header().Get("Content-Security-Policy"), "script-src 'nonce-")
+	body := w.Body.String()
+	assert.Contains(t, body, `href="myapp://product/42"`)
+	assert.Contains(t, body, `window.location.href = "myapp://product/42"`)
+	assert.Contains(t, body, `var fallback = "https://apps.apple.com/app/id123"`)
+	suite.repo.AssertNumberOfCalls(t, "IncrementClickCount", 1)
+	clicks.AssertExpectations(t)
+}
+
+func TestRedirectURL_DesktopVisitorsAreRedirected(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	router := setupRedirectRouter(suite)
+
+	suite.cache.On("Get", mock.Anything, "shop").Return(cache.NewLinkEntry(appURL).Encode(), nil)
+	suite.repo.On("IncrementClickCount", mock.Anything, "shop", mock.Anything).Return(nil)
+
+	req := httptest.NewRequest("GET", "/shop", nil)
+	req.Header.Set("User-Agent", desktopUA)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "https://example.com/product/42", w.Header().Get("Location"))
+	assert.Equal(t, "private, no-cache", w.Header().Get("Cache-Control"))
+	assert.Contains(t, w.Header().Get("Vary"), "User-Agent")
+}
+
+func TestRedirectURL_AndroidGetsIntentFromCache(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	router := setupRedirectRouter(suite)
+
+	link := *appURL
+	link.UTM = domain.UTMParams{Source: "sms"}
+	link.AndroidFallbackURL = "https://play.google.com/store/apps/details?id=app"
+	suite.cache.On("Get", mock.Anything, "shop").Return(cache.NewLinkEntry(&link).Encode(), nil)
+	suite.repo.On("IncrementClickCount", mock.Anything, "shop", mock.Anything).Return(nil)
+
+	req := httptest.NewRequest("GET", "/shop", nil)
+	req.Header.Set("User-Agent", androidUA)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(),
+		`href="intent://product/42#Intent;scheme=myapp;S.browser_fallback_url=https%3A%2F%2Fplay.google.com%2Fstore%2Fapps%2Fdetails%3Fid%3Dapp%26utm_source%3Dsms;end"`)
+}
+
+func TestUpdateURL_DeepLink(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	ctx := context.Background()
+	link := *appURL
+	suite.repo.On("FindByShortCode", ctx, "shop").Return(&link, nil)
+	suite.repo.On("Update", ctx, mock.Anything).Return(nil)
+	suite.cache.On("Delete", mock.Anything, mock.Anything).Return(nil)
+
+	fallback := "https://play.google.com/store/apps/details?id=app"
+	updated, err := suite.service.UpdateURL(ctx, "shop", &domain.UpdateURLRequest{AndroidFallbackURL: &fallback})
+	require.NoError(t, err)
+	assert.Equal(t, "myapp://product/42", updated.DeepLink)
+	assert.Equal(t, fallback, updated.AndroidFallbackURL)
+
+	removed := ""
+	updated, err = suite.service.UpdateURL(ctx, "shop", &domain.UpdateURLRequest{DeepLink: &removed})
+	require.NoError(t, err)
+	assert.Empty(t, updated.DeepLink)
+	assert.Empty(t, updated.IOSFallbackURL)
+	assert.Empty(t, updated.AndroidFallbackURL)
+
+	script := "javascript:alert(1)"
+	_, err = suite.service.UpdateURL(ctx, "shop", &domain.UpdateURLRequest{DeepLink: &script})
+	assert.Error(t, err)
+}
+
+func TestRedirectURL_AppPageEscapesValues(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	router := setupRedirectRouter(suite)
+
+	link := *appURL
+	link.IOSFallbackURL = `https://example.com/?q="</script><script>alert(1)</script>`
+	suite.cache.On("Get", mock.Anything, "shop").Return(cache.NewLinkEntry(&link).Encode(), nil)
+	suite.repo.On("IncrementClickCount", mock.Anything, "shop", mock.Anything).Return(nil)
+
+	req := httptest.NewRequest("GET", "/shop", nil)
+	req.Header.Set("User-Agent", iPhoneUA)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "<script>alert(1)")
+}
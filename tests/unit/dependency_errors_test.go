@@ -0,0 +1,144 @@
+package unit
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"syscall"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	gormpostgres "gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/repository/postgres"
+)
+
+// failingPool is a gorm connection pool whose every statement fails with err
+// A nil err fails with the context's error instead, as a real driver does once the caller gives up
+type failingPool struct {
+	err error
+}
+
+func (p failingPool) fail(ctx context.Context) error {
+	if p.err == nil {
+		return ctx.Err()
+	}
+	return p.err
+}
+
+func (p failingPool) PrepareContext(ctx context.Context, _ string) (*sql.Stmt, error) {
+	return nil, p.fail(ctx)
+}
+
+func (p failingPool) ExecContext(ctx context.Context, _ string, _ ...interface{}) (sql.Result, error) {
+	return nil, p.fail(ctx)
+}
+
+func (p failingPool) QueryContext(ctx context.Context, _ string, _ ...interface{}) (*sql.Rows, error) {
+	return nil, p.fail(ctx)
+}
+
+func (p failingPool) QueryRowContext(context.Context, string, ...interface{}) *sql.Row {
+	return nil
+}
+
+// failingDB opens GORM with the Postgres dialector on top of a failingPool
+func failingDB(t *testing.T, err error) *gorm.DB {
+	db, openErr := gorm.Open(gormpostgres.New(gormpostgres.Config{Conn: failingPool{err: err}}), &gorm.Config{})
+	require.NoError(t, openErr)
+	return db
+}
+
+func TestURLRepository_ClassifiesDriverErrors(t *testing.T) {
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}
+
+	tests := []struct {
+		name        string
+		err         error
+		unavailable bool
+	}{
+		{"connection refused", refused, true},
+		{"server starting up", &pgconn.PgError{Code: "57P03"}, true},
+		{"connection exception", &pgconn.PgError{Code: "08006"}, true},
+		{"too many connections", &pgconn.PgError{Code: "53300"}, true},
+		{"statement timeout", context.DeadlineExceeded, true},
+		{"bad connection", driver.ErrBadConn, true},
+		{"syntax error", &pgconn.PgError{Code: "42601"}, false},
+		{"unknown failure", errors.New("boom"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := postgres.NewURLRepository(failingDB(t, tt.err))
+
+			_, err := repo.FindByShortCode(context.Background(), "abc123")
+
+			require.Error(t, err)
+			assert.Equal(t, tt.unavailable, errors.Is(err, domain.ErrDependencyUnavailable))
+			if !tt.unavailable {
+				var appErr *domain.AppError
+				require.ErrorAs(t, err, &appErr)
+				assert.True(t, appErr.Internal)
+			}
+		})
+	}
+}
+
+func TestURLRepository_KeepsCancellation(t *testing.T) {
+	repo := postgres.NewURLRepository(failingDB(t, nil))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := repo.FindByShortCode(ctx, "abc123")
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.NotErrorIs(t, err, domain.ErrDependencyUnavailable)
+}
+
+func TestGetURLInfo_DependencyUnavailableIs503(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	outage := domain.NewDependencyError(&pgconn.PgError{Code: "57P03"})
+	suite.repo.On("FindByShortCode", mock.Anything, "abc123").Return((*domain.URL)(nil), outage)
+
+	w := httptest.NewRecorder()
+	setupURLInfoRouter(suite).ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/urls/abc123", nil))
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "5", w.Header().Get("Retry-After"))
+	assert.Contains(t, w.Body.String(), "service_unavailable")
+}
+
+func TestGetURLInfo_CanceledRequestIsNotAnError(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	suite.repo.On("FindByShortCode", mock.Anything, "abc123").Return((*domain.URL)(nil), context.Canceled)
+
+	w := httptest.NewRecorder()
+	setupURLInfoRouter(suite).ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/urls/abc123", nil))
+
+	assert.Equal(t, 499, w.Code)
+	assert.Empty(t, w.Body.String())
+}
+
+func TestShortenURL_OutageWrappedAsInternalStillIs503(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	outage := domain.NewDependencyError(context.DeadlineExceeded)
+	suite.repo.On("FindByOriginalURL", mock.Anything, "https://example.com/down").Return((*domain.URL)(nil), domain.ErrURLNotFound)
+	suite.repo.On("Create", mock.Anything, withShortCode("down01")).Return(domain.NewInternalError(outage))
+
+	_, err := suite.service.ShortenURL(context.Background(), &domain.CreateURLRequest{
+		URL:         "https://example.com/down",
+		CustomAlias: "down01",
+	}, domain.CreatorContext{IP: "192.168.1.1"})
+
+	// The failed insert arrives wrapped in an internal error, the outage must still be visible
+	assert.ErrorIs(t, err, domain.ErrDependencyUnavailable)
+}
@@ -0,0 +1,110 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/domain"
+)
+
+func TestShortenURL_DryRunNormalizesWithoutWriting(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	ctx := context.Background()
+	suite.repo.On("FindByOriginalURL", ctx, "https://example.com/launch").Return((*domain.URL)(nil), domain.ErrURLNotFound)
+
+	resp, err := suite.service.ShortenURL(ctx, &domain.CreateURLRequest{URL: "https://Example.com/launch/", DryRun: true}, domain.CreatorContext{IP: "192.168.1.1"})
+
+	require.NoError(t, err)
+	assert.True(t, resp.DryRun)
+	assert.False(t, resp.Deduplicated)
+	assert.Equal(t, "https://example.com/launch", resp.OriginalURL)
+	assert.Empty(t, resp.ShortCode, "random codes aren't reserved by a dry run")
+	assert.Empty(t, resp.ManagementToken)
+	suite.repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	suite.cache.AssertNotCalled(t, "Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestShortenURL_DryRunCustomAlias(t *testing.T) {
+	tests := []struct {
+		name  string
+		taken bool
+	}{
+		{"available", false},
+		{"taken", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			suite := setupURLServiceTest(t)
+			ctx := context.Background()
+			suite.repo.On("FindByOriginalURL", ctx, "https://example.com/launch").Return((*domain.URL)(nil), domain.ErrURLNotFound)
+			suite.repo.On("ExistsManyByShortCode", ctx, []string{"launch"}).Return(map[string]bool{"launch": tt.taken}, nil)
+
+			resp, err := suite.service.ShortenURL(ctx, &domain.CreateURLRequest{URL: "https://example.com/launch", CustomAlias: "launch", DryRun: true}, domain.CreatorContext{IP: "192.168.1.1"})
+
+			if tt.taken {
+				assert.ErrorIs(t, err, domain.ErrShortCodeTaken)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, "launch", resp.ShortCode)
+				assert.Equal(t, "https://short.url/launch", resp.ShortURL)
+			}
+			suite.repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestShortenURL_DryRunDuplicateNotRemembered(t *testing.T) {
+	suite, store := setupDedupCacheTest(t)
+	ctx := context.Background()
+	existing := &domain.URL{ShortCode: "abc123", OriginalURL: "https://example.com/import", IsActive: true, CreatedAt: time.Now()}
+	suite.repo.On("FindByOriginalURL", ctx, "https://example.com/import").Return(existing, nil)
+
+	resp, err := suite.service.ShortenURL(ctx, &domain.CreateURLRequest{URL: "https://example.com/import", DryRun: true}, domain.CreatorContext{IP: "192.168.1.1"})
+
+	require.NoError(t, err)
+	assert.True(t, resp.DryRun)
+	assert.True(t, resp.Deduplicated)
+	assert.Equal(t, "abc123", resp.ShortCode)
+	assert.Empty(t, store.Keys(""), "a dry run leaves the dedup cache alone")
+	suite.repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestShortenURL_DryRunBundle(t *testing.T) {
+	suite := setupURLServiceTest(t)
+
+	resp, err := suite.service.ShortenURL(context.Background(), &domain.CreateURLRequest{
+		Bundle: []domain.BundleItem{{URL: "https://example.com/a"}, {URL: "https://example.com/b"}},
+		DryRun: true,
+	}, domain.CreatorContext{IP: "192.168.1.1"})
+
+	require.NoError(t, err)
+	assert.True(t, resp.DryRun)
+	assert.Len(t, resp.Bundle, 2)
+	suite.repo.AssertNotCalled(t, "CreateBundle", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestShortenHandler_DryRunAnswers200(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	suite.repo.On("FindByOriginalURL", mock.Anything, "https://example.com/launch").Return((*domain.URL)(nil), domain.ErrURLNotFound)
+	router := setupShortenRouter(suite, 1<<20)
+
+	body := `{"url":"https://Example.com/launch/","dry_run":true}`
+	w, _ := postShorten(t, router, strings.NewReader(body), int64(len(body)))
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resp domain.CreateURLResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.True(t, resp.DryRun)
+	assert.Equal(t, "https://example.com/launch", resp.OriginalURL)
+	assert.Empty(t, w.Header().Get("X-Quota-Limit"))
+	suite.repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,110 @@
+package unit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/handler"
+)
+
+// browserAccept is the Accept header sent by a typical desktop browser for navigation
+const browserAccept = "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8"
+
+func setupErrorPageRouter(suite *URLServiceTestSuite) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	h := handler.NewURLHandler(suite.service, suite.cfg, suite.logger)
+
+	router := gin.New()
+	router.GET("/:shortCode", h.RedirectURL)
+	router.NoRoute(h.NoRoute)
+	return router
+}
+
+func requestWithAccept(router *gin.Engine, path, accept string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", path, nil)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestRedirect_ErrorPagesForBrowsers(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	router := setupErrorPageRouter(suite)
+
+	past := time.Now().Add(-time.Hour)
+	suite.cache.On("Get", mock.Anything, mock.Anything).Return("", nil)
+	suite.repo.On("FindByShortCode", mock.Anything, "missing").Return(nil, domain.ErrURLNotFound)
+	suite.repo.On("FindByShortCode", mock.Anything, "old").
+		Return(&domain.URL{ShortCode: "old", OriginalURL: "https://example.com", ExpiresAt: &past, IsActive: true}, nil)
+
+	tests := []struct {
+		name   string
+		path   string
+		accept string
+		code   int
+		html   bool
+		text   string
+	}{
+		{name: "unknown link in browser", path: "/missing", accept: browserAccept, code: http.StatusNotFound, html: true, text: "missing"},
+		{name: "expired link in browser", path: "/old", accept: browserAccept, code: http.StatusGone, html: true, text: "expired"},
+		{name: "unknown link via API", path: "/missing", accept: "application/json", code: http.StatusNotFound},
+		{name: "expired link without Accept", path: "/old", code: http.StatusGone},
+		{name: "expired link with wildcard Accept", path: "/old", accept: "*/*", code: http.StatusGone},
+		{name: "unknown route in browser", path: "/a/b/c", accept: browserAccept, code: http.StatusNotFound, html: true, text: "does not exist"},
+		{name: "unknown route via API", path: "/a/b/c", code: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := requestWithAccept(router, tt.path, tt.accept)
+			assert.Equal(t, tt.code, w.Code)
+
+			if tt.html {
+				assert.Contains(t, w.Header().Get("Content-Type"), "text/html")
+				assert.Contains(t, w.Body.String(), tt.text)
+				return
+			}
+
+			assert.Contains(t, w.Header().Get("Content-Type"), "application/json")
+			assert.True(t, json.Valid(w.Body.Bytes()))
+		})
+	}
+}
+
+func TestRedirect_TemplateDirOverridesPages(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "not_found.html"), []byte(`<p>Acme: no link {{.ShortCode}}</p>`), 0o600))
+
+	suite := setupURLServiceTest(t)
+	suite.cfg.TemplateDir = dir
+	router := setupErrorPageRouter(suite)
+
+	past := time.Now().Add(-time.Hour)
+	suite.cache.On("Get", mock.Anything, mock.Anything).Return("", nil)
+	suite.repo.On("FindByShortCode", mock.Anything, "missing").Return(nil, domain.ErrURLNotFound)
+	suite.repo.On("FindByShortCode", mock.Anything, "old").
+		Return(&domain.URL{ShortCode: "old", OriginalURL: "https://example.com", ExpiresAt: &past, IsActive: true}, nil)
+
+	w := requestWithAccept(router, "/missing", browserAccept)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "<p>Acme: no link missing</p>", w.Body.String())
+
+	// Pages not present in the directory fall back to the built-in version
+	w = requestWithAccept(router, "/old", browserAccept)
+	assert.Equal(t, http.StatusGone, w.Code)
+	assert.Contains(t, w.Body.String(), "This link has expired")
+}
@@ -0,0 +1,147 @@
+package unit
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	gormpostgres "gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/repository/postgres"
+)
+
+// codesConnector is a database/sql driver that answers short_code lookups from a fixed set
+// Every query records how many codes it was asked about
+type codesConnector struct {
+	taken   map[string]bool
+	queries []int
+}
+
+func (c *codesConnector) Connect(context.Context) (driver.Conn, error) { return codesConn{c}, nil }
+func (c *codesConnector) Driver() driver.Driver                        { return nil }
+
+type codesConn struct{ c *codesConnector }
+
+func (codesConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("prepare not supported")
+}
+func (codesConn) Close() error              { return nil }
+func (codesConn) Begin() (driver.Tx, error) { return nil, errors.New("transactions not supported") }
+
+func (conn codesConn) QueryContext(_ context.Context, _ string, args []driver.NamedValue) (driver.Rows, error) {
+	conn.c.queries = append(conn.c.queries, len(args))
+	rows := &codesRows{}
+	for _, arg := range args {
+		if code, ok := arg.Value.(string); ok && conn.c.taken[code] {
+			rows.codes = append(rows.codes, code)
+		}
+	}
+	return rows, nil
+}
+
+type codesRows struct{ codes []string }
+
+func (r *codesRows) Columns() []string { return []string{"short_code"} }
+func (r *codesRows) Close() error      { return nil }
+
+func (r *codesRows) Next(dest []driver.Value) error {
+	if len(r.codes) == 0 {
+		return io.EOF
+	}
+	dest[0], r.codes = r.codes[0], r.codes[1:]
+	return nil
+}
+
+// codesDB opens GORM with the Postgres dialector on top of connector
+func codesDB(t *testing.T, connector *codesConnector) *gorm.DB {
+	db, err := gorm.Open(gormpostgres.New(gormpostgres.Config{Conn: sql.OpenDB(connector)}), &gorm.Config{})
+	require.NoError(t, err)
+	return db
+}
+
+func TestExistsManyByShortCode_EmptyInputSkipsTheQuery(t *testing.T) {
+	connector := &codesConnector{}
+	repo := postgres.NewURLRepository(codesDB(t, connector))
+
+	taken, err := repo.ExistsManyByShortCode(context.Background(), nil)
+
+	require.NoError(t, err)
+	assert.Empty(t, taken)
+	assert.Empty(t, connector.queries)
+}
+
+func TestExistsManyByShortCode_DuplicatesQueriedOnce(t *testing.T) {
+	connector := &codesConnector{taken: map[string]bool{"abc123": true}}
+	repo := postgres.NewURLRepository(codesDB(t, connector))
+
+	taken, err := repo.ExistsManyByShortCode(context.Background(), []string{"abc123", "free01", "abc123", "free01"})
+
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{"abc123": true}, taken)
+	assert.Equal(t, []int{2}, connector.queries)
+}
+
+func TestExistsManyByShortCode_ChunksLargeInput(t *testing.T) {
+	codes := make([]string, 2500)
+	for i := range codes {
+		codes[i] = fmt.Sprintf("c%05d", i)
+	}
+	connector := &codesConnector{taken: map[string]bool{codes[0]: true, codes[1500]: true, codes[2499]: true}}
+	repo := postgres.NewURLRepository(codesDB(t, connector))
+
+	taken, err := repo.ExistsManyByShortCode(context.Background(), codes)
+
+	require.NoError(t, err)
+	assert.Len(t, taken, 3)
+	assert.True(t, taken[codes[2499]])
+	assert.Equal(t, []int{1000, 1000, 500}, connector.queries)
+}
+
+func TestShortenURL_BundleConflictReplacesOnlyTakenCodes(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	ctx := context.Background()
+
+	var attempts [][]string
+	record := func(args mock.Arguments) {
+		codes := []string{args.Get(1).(*domain.URL).ShortCode}
+		for _, member := range args.Get(2).([]*domain.URL) {
+			codes = append(codes, member.ShortCode)
+		}
+		attempts = append(attempts, codes)
+	}
+	suite.repo.On("CreateBundle", ctx, mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			record(args)
+			// The second member's code is the one that collided
+			codes := attempts[0]
+			suite.repo.On("ExistsManyByShortCode", ctx, codes).Return(map[string]bool{codes[2]: true}, nil).Once()
+		}).
+		Return(domain.ErrShortCodeTaken).Once()
+	suite.repo.On("CreateBundle", ctx, mock.Anything, mock.Anything).Run(record).Return(nil).Once()
+
+	resp, err := suite.service.ShortenURL(ctx, &domain.CreateURLRequest{
+		CustomAlias: "launch",
+		Bundle: []domain.BundleItem{
+			{URL: "https://example.com/pricing"},
+			{URL: "https://example.com/docs"},
+		},
+	}, domain.CreatorContext{IP: "10.0.0.1"})
+
+	require.NoError(t, err)
+	require.Len(t, attempts, 2)
+	first, second := attempts[0], attempts[1]
+	assert.Equal(t, []string{"launch", "launch"}, []string{first[0], second[0]})
+	assert.Equal(t, first[1], second[1], "free codes are kept")
+	assert.NotEqual(t, first[2], second[2], "the taken code is replaced")
+	assert.Equal(t, second[2], resp.Bundle[1].ShortCode)
+	suite.repo.AssertNumberOfCalls(t, "ExistsManyByShortCode", 1)
+}
@@ -0,0 +1,85 @@
+package unit
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/cache"
+	"url-shortener/internal/cache/cachetest"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/service"
+)
+
+func TestShortenURL_ExpiresAtSubDay(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	store := cachetest.NewMemoryCache()
+	svc := service.NewURLService(suite.repo, store, suite.cfg, suite.logger)
+	ctx := context.Background()
+	suite.repo.On("FindByOriginalURL", ctx, "https://example.com/event").Return((*domain.URL)(nil), domain.ErrURLNotFound)
+	suite.repo.On("Create", ctx, mock.AnythingOfType("*domain.URL")).Return(nil)
+
+	at := time.Now().Add(20 * time.Minute).Truncate(time.Second)
+	resp, err := svc.ShortenURL(ctx, &domain.CreateURLRequest{URL: "https://example.com/event", ExpiresAt: &at}, domain.CreatorContext{IP: "192.168.1.1"})
+
+	require.NoError(t, err)
+	require.NotNil(t, resp.ExpiresAt)
+	assert.True(t, at.Equal(*resp.ExpiresAt), "the timestamp is kept to the second")
+
+	// The cached redirect is gone when the link is
+	require.NoError(t, svc.Close(ctx))
+	ttl := store.TTL(cache.LinkKey(resp.ShortCode))
+	assert.InDelta(t, (20 * time.Minute).Seconds(), ttl.Seconds(), 5)
+}
+
+func TestShortenURL_ExpiryValidation(t *testing.T) {
+	past := time.Now().Add(-time.Minute)
+	soon := time.Now().Add(time.Hour)
+	far := time.Now().AddDate(0, 0, 400)
+
+	tests := []struct {
+		name string
+		req  domain.CreateURLRequest
+		want string
+	}{
+		{"in the past", domain.CreateURLRequest{ExpiresAt: &past}, "expires_at must be in the future"},
+		{"both fields", domain.CreateURLRequest{ExpiresAt: &soon, ExpiryDays: 3}, "mutually exclusive"},
+		{"beyond the horizon", domain.CreateURLRequest{ExpiresAt: &far}, "expires_at must be at most 365 days ahead"},
+		{"days beyond the horizon", domain.CreateURLRequest{ExpiryDays: 400}, "expiry_days must be at most 365"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			suite := setupURLServiceTest(t)
+			suite.cfg.MaxExpiryDays = 365
+			tt.req.URL = "https://example.com/event"
+
+			_, err := suite.service.ShortenURL(context.Background(), &tt.req, domain.CreatorContext{IP: "192.168.1.1"})
+
+			var appErr *domain.AppError
+			require.ErrorAs(t, err, &appErr)
+			assert.Equal(t, http.StatusBadRequest, appErr.StatusCode)
+			assert.Contains(t, appErr.Message, tt.want)
+			// Checked before deduplication, so an existing link can't answer an invalid request
+			suite.repo.AssertNotCalled(t, "FindByOriginalURL", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestShortenHandler_MalformedExpiresAt(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	router := setupShortenRouter(suite, 1<<20)
+
+	body := `{"url":"https://example.com","expires_at":"June 3rd 18:00"}`
+	w, _ := postShorten(t, router, strings.NewReader(body), int64(len(body)))
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "RFC3339")
+	assert.Contains(t, w.Body.String(), "June 3rd 18:00")
+}
@@ -0,0 +1,135 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/handler"
+	"url-shortener/internal/notify"
+)
+
+// heldLocker is a Locker whose lock is always held by someone else
+type heldLocker struct{}
+
+func (heldLocker) TryLock(ctx context.Context, key string, ttl time.Duration) (func(), bool, error) {
+	return nil, false, nil
+}
+
+// expiryWebhook records the notices it receives and rejects the short codes in fail
+func expiryWebhook(t *testing.T, fail ...string) (*httptest.Server, func() []notify.ExpiryNotice) {
+	var mu sync.Mutex
+	var received []notify.ExpiryNotice
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var notice notify.ExpiryNotice
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&notice))
+		for _, code := range fail {
+			if notice.ShortCode == code {
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+		}
+		mu.Lock()
+		received = append(received, notice)
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, func() []notify.ExpiryNotice {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]notify.ExpiryNotice(nil), received...)
+	}
+}
+
+func TestExpiryJob_NotifiesOnceAndRetriesFailures(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	server, received := expiryWebhook(t, "fails1")
+	soon := time.Now().Add(48 * time.Hour)
+	notified := time.Now().Add(-time.Hour)
+
+	suite.repo.On("FindExpiringBetween", mock.Anything, mock.Anything, mock.Anything, 0).Return([]domain.URL{
+		{ShortCode: "abc123", OriginalURL: "https://example.com/a", ExpiresAt: &soon},
+		{ShortCode: "done01", OriginalURL: "https://example.com/b", ExpiresAt: &soon, ExpiryNotifiedAt: &notified},
+		{ShortCode: "fails1", OriginalURL: "https://example.com/c", ExpiresAt: &soon},
+	}, nil)
+	suite.repo.On("MarkExpiryNotified", mock.Anything, "abc123", mock.Anything).Return(nil).Once()
+
+	job := notify.NewExpiryJob(suite.repo, notify.NewWebhookNotifier(server.URL, time.Second), nil, "https://short.url", 7*24*time.Hour, suite.logger)
+	err := job.Run(context.Background())
+
+	// The failed notice is reported so the scheduler logs it, and stays unmarked for the next run
+	assert.ErrorContains(t, err, "1 expiry notices failed")
+	notices := received()
+	require.Len(t, notices, 1)
+	assert.Equal(t, domain.EventLinkExpiring, notices[0].Type)
+	assert.Equal(t, "https://short.url/abc123", notices[0].ShortURL)
+	assert.True(t, soon.Equal(notices[0].ExpiresAt))
+	suite.repo.AssertNotCalled(t, "MarkExpiryNotified", mock.Anything, "fails1", mock.Anything)
+	suite.repo.AssertNotCalled(t, "MarkExpiryNotified", mock.Anything, "done01", mock.Anything)
+
+	// The window handed to the repository starts now and spans the configured days
+	call := suite.repo.Calls[0]
+	from, to := call.Arguments.Get(1).(time.Time), call.Arguments.Get(2).(time.Time)
+	assert.WithinDuration(t, time.Now(), from, time.Minute)
+	assert.Equal(t, 7*24*time.Hour, to.Sub(from))
+}
+
+func TestExpiryJob_SkipsWhileAnotherInstanceHoldsTheLock(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	server, received := expiryWebhook(t)
+
+	job := notify.NewExpiryJob(suite.repo, notify.NewWebhookNotifier(server.URL, time.Second), heldLocker{}, "https://short.url", 7*24*time.Hour, suite.logger)
+
+	require.NoError(t, job.Run(context.Background()))
+	assert.Empty(t, received())
+	suite.repo.AssertNotCalled(t, "FindExpiringBetween", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestListExpiringHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	suite := setupURLServiceTest(t)
+	soon := time.Now().Add(24 * time.Hour)
+	suite.repo.On("FindExpiringBetween", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return([]domain.URL{{ShortCode: "abc123", OriginalURL: "https://example.com", ExpiresAt: &soon, IsActive: true}}, nil)
+	suite.repo.On("FindByShortCode", mock.Anything, "abc123").Return(&domain.URL{ShortCode: "abc123", OriginalURL: "https://example.com"}, nil)
+
+	h := handler.NewURLHandler(suite.service, suite.cfg, suite.logger)
+	router := gin.New()
+	router.GET("/api/v1/urls/expiring", h.ListExpiring)
+	router.GET("/api/v1/urls/:shortCode", h.GetURLInfo)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/urls/expiring?days=3", nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var urls []domain.URLInfoResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &urls))
+	require.Len(t, urls, 1)
+	assert.Equal(t, "https://short.url/abc123", urls[0].ShortURL)
+
+	call := suite.repo.Calls[0]
+	from, to := call.Arguments.Get(1).(time.Time), call.Arguments.Get(2).(time.Time)
+	assert.Equal(t, 3*24*time.Hour, to.Sub(from).Round(time.Hour))
+
+	// The static segment doesn't shadow other links' info
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/urls/abc123", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	for _, days := range []string{"0", "91", "soon"} {
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/urls/expiring?days="+days, nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code, days)
+	}
+}
@@ -0,0 +1,177 @@
+package unit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/apikey"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/handler"
+	"url-shortener/pkg/logger"
+)
+
+func TestNewExpiryPolicy_KeyOverridesGlobal(t *testing.T) {
+	tests := []struct {
+		name                     string
+		globalDefault, globalMax int
+		key                      domain.KeyExpiry
+		want                     domain.ExpiryPolicy
+	}{
+		{"no key", 30, 365, domain.KeyExpiry{}, domain.ExpiryPolicy{DefaultDays: 30, MaxDays: 365}},
+		{"key default", 30, 365, domain.KeyExpiry{DefaultDays: 7}, domain.ExpiryPolicy{DefaultDays: 7, MaxDays: 365}},
+		{"key never", 30, 365, domain.KeyExpiry{DefaultDays: domain.ExpiryNever, MaxDays: domain.ExpiryNever}, domain.ExpiryPolicy{}},
+		{"key cap", 0, 0, domain.KeyExpiry{MaxDays: 90}, domain.ExpiryPolicy{MaxDays: 90}},
+		{"negative globals mean off", -5, -1, domain.KeyExpiry{}, domain.ExpiryPolicy{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, domain.NewExpiryPolicy(tt.globalDefault, tt.globalMax, tt.key))
+		})
+	}
+}
+
+func TestExpiryPolicy_Expiry(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	at := now.Add(48 * time.Hour)
+	far := now.AddDate(0, 0, 400)
+	days := func(n int) *time.Time {
+		expiry := now.AddDate(0, 0, n)
+		return &expiry
+	}
+
+	tests := []struct {
+		name       string
+		policy     domain.ExpiryPolicy
+		expiryDays int
+		expiresAt  *time.Time
+		want       *time.Time
+		rule       string // Field error rule expected instead of an expiry
+	}{
+		// Request value, with and without a default and a cap
+		{"days", domain.ExpiryPolicy{}, 10, nil, days(10), ""},
+		{"days over the default", domain.ExpiryPolicy{DefaultDays: 30}, 10, nil, days(10), ""},
+		{"days at the cap", domain.ExpiryPolicy{MaxDays: 10}, 10, nil, days(10), ""},
+		{"days over the cap", domain.ExpiryPolicy{MaxDays: 10}, 11, nil, nil, "max"},
+		{"exact time", domain.ExpiryPolicy{DefaultDays: 30, MaxDays: 10}, 0, &at, &at, ""},
+		{"exact time over the cap", domain.ExpiryPolicy{MaxDays: 365}, 0, &far, nil, "max"},
+		{"exact time in the past", domain.ExpiryPolicy{}, 0, &now, nil, "future"},
+		{"both fields", domain.ExpiryPolicy{}, 3, &at, nil, "excluded_with"},
+
+		// Explicit never
+		{"never uncapped", domain.ExpiryPolicy{DefaultDays: 30}, domain.ExpiryNever, nil, nil, ""},
+		{"never capped", domain.ExpiryPolicy{DefaultDays: 30, MaxDays: 365}, domain.ExpiryNever, nil, nil, "max"},
+		{"other negative days", domain.ExpiryPolicy{}, -2, nil, nil, "min"},
+
+		// Defaults apply to requests without an expiry and aren't held to the cap
+		{"no default", domain.ExpiryPolicy{MaxDays: 365}, 0, nil, nil, ""},
+		{"default", domain.ExpiryPolicy{DefaultDays: 30}, 0, nil, days(30), ""},
+		{"default over the cap", domain.ExpiryPolicy{DefaultDays: 30, MaxDays: 10}, 0, nil, days(30), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.policy.Expiry(now, tt.expiryDays, tt.expiresAt)
+
+			if tt.rule != "" {
+				var appErr *domain.AppError
+				require.ErrorAs(t, err, &appErr)
+				assert.Equal(t, http.StatusBadRequest, appErr.StatusCode)
+				require.Len(t, appErr.Fields, 1)
+				assert.Equal(t, tt.rule, appErr.Fields[0].Rule)
+				return
+			}
+			require.NoError(t, err)
+			if tt.want == nil {
+				assert.Nil(t, got)
+			} else {
+				require.NotNil(t, got)
+				assert.True(t, tt.want.Equal(*got), "got %s", got)
+			}
+		})
+	}
+}
+
+func TestAPIKeyStore_CreateValidatesExpiry(t *testing.T) {
+	repo := new(MockAPIKeyRepository)
+	repo.On("ListActive", mock.Anything).Return([]domain.APIKey{}, nil)
+	repo.On("Create", mock.Anything, mock.Anything).Return(nil)
+	store := apikey.NewStore(repo, time.Minute, logger.NewLogger(logger.Options{}))
+	require.NoError(t, store.Refresh(context.Background()))
+
+	for name, req := range map[string]domain.CreateAPIKeyRequest{
+		"default below never": {Label: "ci", DefaultExpiryDays: -2},
+		"cap below no limit":  {Label: "ci", MaxExpiryDays: -3},
+		"never beyond cap":    {Label: "ci", DefaultExpiryDays: domain.ExpiryNever, MaxExpiryDays: 30},
+		"default beyond cap":  {Label: "ci", DefaultExpiryDays: 60, MaxExpiryDays: 30},
+	} {
+		_, err := store.Create(context.Background(), &req)
+		var appErr *domain.AppError
+		require.ErrorAs(t, err, &appErr, name)
+		assert.Equal(t, http.StatusBadRequest, appErr.StatusCode, name)
+	}
+
+	resp, err := store.Create(context.Background(), &domain.CreateAPIKeyRequest{Label: "archive", DefaultExpiryDays: domain.ExpiryNever, MaxExpiryDays: domain.ExpiryNever})
+	require.NoError(t, err)
+	assert.Equal(t, domain.KeyExpiry{DefaultDays: domain.ExpiryNever, MaxDays: domain.ExpiryNever}, resp.Expiry())
+}
+
+func TestShortenURL_KeyExpiryDefault(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	suite := setupURLServiceTest(t)
+	suite.cfg.URLExpirationDays = 30
+	suite.cfg.MaxExpiryDays = 365
+
+	forever := domain.APIKey{ID: 1, KeyHash: apikey.Hash("usk_archive"), Fingerprint: apikey.Fingerprint("usk_archive"), Label: "archive",
+		DefaultExpiryDays: domain.ExpiryNever, MaxExpiryDays: domain.ExpiryNever}
+	repo := new(MockAPIKeyRepository)
+	repo.On("ListActive", mock.Anything).Return([]domain.APIKey{forever}, nil)
+	keys := apikey.NewStore(repo, time.Minute, suite.logger)
+	require.NoError(t, keys.Refresh(context.Background()))
+
+	var saved []*domain.URL
+	suite.repo.On("FindByOriginalURL", mock.Anything, mock.Anything).Return(nil, domain.ErrURLNotFound)
+	suite.repo.On("Create", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		saved = append(saved, args.Get(1).(*domain.URL))
+	}).Return(nil)
+	suite.cache.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+
+	router := gin.New()
+	router.POST("/api/v1/shorten", handler.APIKeyIdentityMiddleware(suite.cfg, keys), handler.NewURLHandler(suite.service, suite.cfg, suite.logger).ShortenURL)
+	shorten := func(body map[string]interface{}, apiKey string) *httptest.ResponseRecorder {
+		data, _ := json.Marshal(body)
+		req := httptest.NewRequest("POST", "/api/v1/shorten", bytes.NewReader(data))
+		req.Header.Set("Content-Type", "application/json")
+		if apiKey != "" {
+			req.Header.Set("X-API-Key", apiKey)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Anonymous links get the global default, and may not ask for never under the global cap
+	require.Equal(t, http.StatusCreated, shorten(map[string]interface{}{"url": "https://example.com/a"}, "").Code)
+	assert.Equal(t, http.StatusBadRequest, shorten(map[string]interface{}{"url": "https://example.com/b", "expiry_days": -1}, "").Code)
+
+	// The key's links never expire, by default or when asked, and may go past the global cap
+	require.Equal(t, http.StatusCreated, shorten(map[string]interface{}{"url": "https://example.com/c"}, "usk_archive").Code)
+	require.Equal(t, http.StatusCreated, shorten(map[string]interface{}{"url": "https://example.com/d", "expiry_days": -1}, "usk_archive").Code)
+	require.Equal(t, http.StatusCreated, shorten(map[string]interface{}{"url": "https://example.com/e", "expiry_days": 1000}, "usk_archive").Code)
+
+	require.Len(t, saved, 4)
+	require.NotNil(t, saved[0].ExpiresAt)
+	assert.WithinDuration(t, time.Now().AddDate(0, 0, 30), *saved[0].ExpiresAt, time.Minute)
+	assert.Nil(t, saved[1].ExpiresAt)
+	assert.Nil(t, saved[2].ExpiresAt)
+	require.NotNil(t, saved[3].ExpiresAt)
+	assert.WithinDuration(t, time.Now().AddDate(0, 0, 1000), *saved[3].ExpiresAt, time.Minute)
+}
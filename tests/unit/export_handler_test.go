@@ -0,0 +1,107 @@
+package unit
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/handler"
+)
+
+func setupExportRouter(t *testing.T, urls []*domain.URL) (*gin.Engine, *MockURLRepository) {
+	gin.SetMode(gin.TestMode)
+	suite := setupURLServiceTest(t)
+
+	suite.repo.On("ForEach", mock.Anything, mock.AnythingOfType("domain.URLFilter"), mock.AnythingOfType("int"), mock.Anything).
+		Return(urls, nil)
+
+	router := gin.New()
+	router.GET("/api/v1/export", handler.NewURLHandler(suite.service, suite.cfg, suite.logger).ExportURLs)
+	return router, suite.repo
+}
+
+func TestExportURLs_CSVEscaping(t *testing.T) {
+	created := time.Date(2025, 10, 20, 20, 26, 21, 0, time.UTC)
+	router, _ := setupExportRouter(t, []*domain.URL{
+		{ShortCode: "abc123", OriginalURL: `https://example.com/?q=a,b&t="quoted"`, CreatedAt: created, ClickCount: 3, IsActive: true},
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/export?format=csv", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "attachment;")
+	assert.Contains(t, w.Body.String(), `"https://example.com/?q=a,b&t=""quoted"""`)
+
+	records, err := csv.NewReader(strings.NewReader(w.Body.String())).ReadAll()
+	assert.NoError(t, err)
+	assert.Len(t, records, 2)
+	assert.Equal(t, "short_code", records[0][0])
+	assert.Equal(t, `https://example.com/?q=a,b&t="quoted"`, records[1][1])
+	assert.Equal(t, "2025-10-20T20:26:21Z", records[1][2])
+	assert.Equal(t, "", records[1][3])
+	assert.Equal(t, "3", records[1][4])
+}
+
+func TestExportURLs_JSON(t *testing.T) {
+	router, _ := setupExportRouter(t, []*domain.URL{
+		{ShortCode: "one", OriginalURL: "https://example.com/1", IsActive: true},
+		{ShortCode: "two", OriginalURL: "https://example.com/2", IsActive: false},
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/export?format=json", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var records []domain.ExportRecord
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &records))
+	assert.Len(t, records, 2)
+	assert.Equal(t, "two", records[1].ShortCode)
+	assert.False(t, records[1].IsActive)
+}
+
+func TestExportURLs_EmptyJSON(t *testing.T) {
+	router, _ := setupExportRouter(t, nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/export?format=json", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "[]", w.Body.String())
+}
+
+func TestExportURLs_DateFilter(t *testing.T) {
+	router, repo := setupExportRouter(t, nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/export?from=2025-10-01&to=2025-10-31", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	filter := repo.Calls[0].Arguments.Get(1).(domain.URLFilter)
+	assert.Equal(t, time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC), *filter.CreatedFrom)
+	assert.Equal(t, time.Date(2025, 11, 1, 0, 0, 0, 0, time.UTC), *filter.CreatedTo)
+}
+
+func TestExportURLs_InvalidParams(t *testing.T) {
+	router, _ := setupExportRouter(t, nil)
+
+	for _, target := range []string{
+		"/api/v1/export?format=xml",
+		"/api/v1/export?from=yesterday",
+		"/api/v1/export?from=2025-10-31&to=2025-10-01",
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", target, nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code, target)
+	}
+}
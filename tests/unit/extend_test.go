@@ -0,0 +1,148 @@
+package unit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/cache"
+	"url-shortener/internal/cache/cachetest"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/service"
+)
+
+// setupExtendTest returns a service on an in-memory cache that stores whatever Update is given
+func setupExtendTest(t *testing.T, link *domain.URL) (*URLServiceTestSuite, *cachetest.MemoryCache, service.URLService) {
+	suite := setupURLServiceTest(t)
+	store := cachetest.NewMemoryCache()
+	svc := service.NewURLService(suite.repo, store, suite.cfg, suite.logger)
+	suite.repo.On("FindAnyByShortCode", mock.Anything, link.ShortCode).Return(link, nil)
+	suite.repo.On("Update", mock.Anything, mock.AnythingOfType("*domain.URL")).Return(nil)
+	// Not queued, so the rewritten entry can be inspected as soon as ExtendURL returns
+	require.NoError(t, svc.Close(context.Background()))
+	return suite, store, svc
+}
+
+func TestExtendURL_FromExpiryOrNow(t *testing.T) {
+	tests := []struct {
+		name string
+		from string
+		want time.Duration // from now
+	}{
+		{"default counts from the expiry", "", 12 * 24 * time.Hour},
+		{"from now", domain.ExtendFromNow, 10 * 24 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expiry := time.Now().Add(48 * time.Hour)
+			notified := time.Now()
+			link := &domain.URL{ShortCode: "abc123", OriginalURL: "https://example.com", IsActive: true, ExpiresAt: &expiry, ExpiryNotifiedAt: &notified}
+			_, store, svc := setupExtendTest(t, link)
+
+			url, err := svc.ExtendURL(context.Background(), "abc123", &domain.ExtendURLRequest{Days: 10, From: tt.from})
+
+			require.NoError(t, err)
+			assert.WithinDuration(t, time.Now().Add(tt.want), *url.ExpiresAt, time.Minute)
+			assert.Nil(t, url.ExpiryNotifiedAt, "the new expiry gets its own notice")
+
+			// The rewritten entry lives for CacheTTL, no longer capped by the old expiry
+			exists, _ := store.Exists(context.Background(), cache.LinkKey("abc123"))
+			assert.True(t, exists)
+			assert.Equal(t, time.Hour, store.TTL(cache.LinkKey("abc123")))
+		})
+	}
+}
+
+func TestExtendURL_CacheTTLCappedAtNewExpiry(t *testing.T) {
+	soon := time.Now().Add(10 * time.Minute)
+	link := &domain.URL{ShortCode: "soon01", OriginalURL: "https://example.com", IsActive: true, ExpiresAt: &soon}
+	suite, store, svc := setupExtendTest(t, link)
+	suite.cfg.CacheTTL = 30 * 24 * time.Hour
+
+	_, err := svc.ExtendURL(context.Background(), "soon01", &domain.ExtendURLRequest{Days: 1})
+
+	require.NoError(t, err)
+	assert.InDelta(t, (24*time.Hour + 10*time.Minute).Seconds(), store.TTL(cache.LinkKey("soon01")).Seconds(), 60,
+		"the entry must not outlive the link")
+}
+
+func TestExtendURL_ExpiredNeedsAllowRevive(t *testing.T) {
+	expiry := time.Now().Add(-time.Hour)
+	link := &domain.URL{ShortCode: "old123", OriginalURL: "https://example.com", IsActive: false, ExpiresAt: &expiry}
+	suite, store, svc := setupExtendTest(t, link)
+	require.NoError(t, store.Set(context.Background(), cache.InactiveKey("old123"), "1", 0))
+
+	_, err := svc.ExtendURL(context.Background(), "old123", &domain.ExtendURLRequest{Days: 7})
+	var appErr *domain.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, http.StatusGone, appErr.StatusCode)
+	suite.repo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+
+	url, err := svc.ExtendURL(context.Background(), "old123", &domain.ExtendURLRequest{Days: 7, AllowRevive: true})
+	require.NoError(t, err)
+	assert.True(t, url.IsActive)
+	assert.WithinDuration(t, time.Now().AddDate(0, 0, 7), *url.ExpiresAt, time.Minute, "revived links count from now")
+	inactive, _ := store.Get(context.Background(), cache.InactiveKey("old123"))
+	assert.Empty(t, inactive)
+}
+
+func TestExtendURL_Rejected(t *testing.T) {
+	future := time.Now().Add(30 * 24 * time.Hour)
+	tests := []struct {
+		name   string
+		link   *domain.URL
+		req    domain.ExtendURLRequest
+		status int
+	}{
+		{"never expires", &domain.URL{ShortCode: "abc123", IsActive: true}, domain.ExtendURLRequest{Days: 7}, http.StatusBadRequest},
+		{"zero days", &domain.URL{ShortCode: "abc123", IsActive: true, ExpiresAt: &future}, domain.ExtendURLRequest{Days: 0}, http.StatusBadRequest},
+		{"unknown from", &domain.URL{ShortCode: "abc123", IsActive: true, ExpiresAt: &future}, domain.ExtendURLRequest{Days: 7, From: "creation"}, http.StatusBadRequest},
+		{"would shorten", &domain.URL{ShortCode: "abc123", IsActive: true, ExpiresAt: &future}, domain.ExtendURLRequest{Days: 7, From: domain.ExtendFromNow}, http.StatusBadRequest},
+		{"deactivated by admin", &domain.URL{ShortCode: "abc123", IsActive: false, ExpiresAt: &future}, domain.ExtendURLRequest{Days: 7, AllowRevive: true}, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			suite, _, svc := setupExtendTest(t, tt.link)
+
+			_, err := svc.ExtendURL(context.Background(), "abc123", &tt.req)
+
+			if tt.status == http.StatusNotFound {
+				assert.ErrorIs(t, err, domain.ErrURLNotFound)
+			} else {
+				var appErr *domain.AppError
+				require.ErrorAs(t, err, &appErr)
+				assert.Equal(t, tt.status, appErr.StatusCode)
+			}
+			suite.repo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestExtendHandler_RevivesWithManagementToken(t *testing.T) {
+	suite, router := setupManagementRouter(t)
+	expiry := time.Now().Add(-time.Hour)
+	suite.repo.On("FindAnyByShortCode", mock.Anything, "old123").
+		Return(&domain.URL{ShortCode: "old123", OriginalURL: "https://example.com", ExpiresAt: &expiry, ManagementTokenHash: managementTokenHash(testManagementToken)}, nil)
+	suite.repo.On("Update", mock.Anything, mock.AnythingOfType("*domain.URL")).Return(nil)
+
+	extend := func(token string) int {
+		req := httptest.NewRequest("POST", "/api/v1/urls/old123/extend", strings.NewReader(`{"days":30,"allow_revive":true}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Management-Token", token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusForbidden, extend("guess"))
+	assert.Equal(t, http.StatusOK, extend(testManagementToken), "owners can revive links the cleanup job deactivated")
+}
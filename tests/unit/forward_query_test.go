@@ -0,0 +1,123 @@
+package unit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/cache"
+	"url-shortener/internal/config"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/redirect"
+)
+
+func TestMergeQuery(t *testing.T) {
+	tests := []struct {
+		name         string
+		destination  string
+		incoming     string
+		incomingWins bool
+		expected     string
+	}{
+		{"no incoming query", "https://example.com/a?x=1", "", false, "https://example.com/a?x=1"},
+		{"appends to empty query", "https://example.com/a", "src=email", false, "https://example.com/a?src=email"},
+		{"keeps fragment", "https://example.com/docs#install", "src=email", false, "https://example.com/docs?src=email#install"},
+		{"repeated parameters survive", "https://example.com/a?tag=x", "tag2=a&tag2=b", false, "https://example.com/a?tag=x&tag2=a&tag2=b"},
+		{"destination wins on conflict", "https://example.com/a?src=site&id=1", "src=email&src=sms&ref=2", false, "https://example.com/a?src=site&id=1&ref=2"},
+		{"incoming wins on conflict", "https://example.com/a?src=site&src=ads&id=1", "src=email&src=sms", true, "https://example.com/a?id=1&src=email&src=sms"},
+		{"encoded keys conflict", "https://example.com/a?a%20b=1", "a+b=2", false, "https://example.com/a?a%20b=1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, redirect.MergeQuery(tt.destination, tt.incoming, tt.incomingWins))
+		})
+	}
+}
+
+func TestLinkEntry_ForwardQueryRoundTrip(t *testing.T) {
+	encoded := cache.NewLinkEntry(&domain.URL{OriginalURL: "https://example.com", ForwardQuery: true}).Encode()
+
+	entry, ok := cache.DecodeLinkEntry(encoded)
+
+	require.True(t, ok)
+	assert.True(t, entry.ForwardQuery)
+	assert.Equal(t, "https://example.com", entry.URL)
+}
+
+func TestRedirectURL_ForwardsQueryFromCache(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	router := setupRedirectRouter(suite)
+
+	entry := cache.NewLinkEntry(&domain.URL{OriginalURL: "https://example.com/landing?campaign=spring#top", ForwardQuery: true, IsActive: true})
+	suite.cache.On("Get", mock.Anything, "abc123").Return(entry.Encode(), nil)
+	suite.repo.On("IncrementClickCount", mock.Anything, "abc123", mock.Anything).Return(nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/abc123?src=email&campaign=other", nil))
+
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+	assert.Equal(t, "https://example.com/landing?campaign=spring&src=email#top", w.Header().Get("Location"))
+}
+
+func TestRedirectURL_DropsQueryWithoutFlag(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	router := setupRedirectRouter(suite)
+
+	suite.cache.On("Get", mock.Anything, "abc123").Return("https://example.com/landing", nil)
+	suite.repo.On("IncrementClickCount", mock.Anything, "abc123", mock.Anything).Return(nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/abc123?src=email", nil))
+
+	assert.Equal(t, "https://example.com/landing", w.Header().Get("Location"))
+}
+
+func TestRedirectURL_IncomingPrecedenceFromDatabase(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	suite.cfg.ForwardQueryPrecedence = config.ForwardQueryIncomingWins
+	router := setupRedirectRouter(suite)
+
+	suite.cache.On("Get", mock.Anything, "abc123").Return("", nil)
+	suite.repo.On("FindByShortCode", mock.Anything, "abc123").
+		Return(&domain.URL{ShortCode: "abc123", OriginalURL: "https://example.com/?src=site", IsActive: true, ForwardQuery: true}, nil)
+	suite.repo.On("IncrementClickCount", mock.Anything, "abc123", mock.Anything).Return(nil)
+	suite.cache.On("Set", mock.Anything, "abc123", mock.MatchedBy(func(v string) bool {
+		entry, ok := cache.DecodeLinkEntry(v)
+		return ok && entry.ForwardQuery
+	}), time.Hour).Return(nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/abc123?src=email", nil))
+
+	assert.Equal(t, "https://example.com/?src=email", w.Header().Get("Location"))
+	drainCacheWrites(t, suite)
+	suite.cache.AssertExpectations(t)
+}
+
+func TestShortenURL_ForwardQueryDefaultFromConfig(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	suite.cfg.ForwardQueryDefault = true
+	ctx := context.Background()
+
+	suite.repo.On("FindByOriginalURL", ctx, "https://example.com/fq").
+		Return((*domain.URL)(nil), domain.ErrURLNotFound)
+	suite.repo.On("Create", ctx, mock.MatchedBy(func(u *domain.URL) bool { return u.ForwardQuery })).Return(nil).Once()
+	suite.cache.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	_, err := suite.service.ShortenURL(ctx, &domain.CreateURLRequest{URL: "https://example.com/fq"}, domain.CreatorContext{IP: "192.168.1.1"})
+	require.NoError(t, err)
+
+	off := false
+	suite.repo.On("Create", ctx, mock.MatchedBy(func(u *domain.URL) bool { return !u.ForwardQuery })).Return(nil).Once()
+	_, err = suite.service.ShortenURL(ctx, &domain.CreateURLRequest{URL: "https://example.com/fq", ForwardQuery: &off}, domain.CreatorContext{IP: "192.168.1.1"})
+	require.NoError(t, err)
+
+	suite.repo.AssertExpectations(t)
+}
@@ -0,0 +1,157 @@
+package unit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/geo"
+	"url-shortener/internal/redirect"
+	"url-shortener/internal/service"
+)
+
+const geoTable = `
+# network,country
+81.2.69.0/24,DE
+2001:db8::/32,FR
+8.8.8.0/24,US
+`
+
+var gdprTargets = domain.Targets{
+	{Platform: redirect.PlatformIOS, URL: "https://apps.apple.com/app/id1"},
+	{Country: "US", URL: "https://example.com/us"},
+	{Country: geo.RegionEU, URL: "https://example.com/gdpr"},
+	{Country: "FR", URL: "https://example.com/fr"},
+}
+
+func TestCIDRResolver(t *testing.T) {
+	resolver, err := geo.ParseCIDRTable(strings.NewReader(geoTable))
+	require.NoError(t, err)
+
+	assert.Equal(t, "DE", resolver.Country("81.2.69.160"))
+	assert.Equal(t, "FR", resolver.Country("2001:db8::1"))
+	assert.Equal(t, "US", resolver.Country("8.8.8.8"))
+	assert.Equal(t, "", resolver.Country("1.1.1.1"))
+	assert.Equal(t, "", resolver.Country("not-an-ip"))
+
+	_, err = geo.ParseCIDRTable(strings.NewReader("10.0.0.0/8,Germany"))
+	assert.Error(t, err)
+}
+
+func TestEvaluate_Precedence(t *testing.T) {
+	tests := []struct {
+		name     string
+		visitor  domain.Visitor
+		expected string
+		target   string
+	}{
+		{"exact country beats region", domain.Visitor{Country: "FR", UserAgent: iPhoneUA}, "https://example.com/fr", "country:FR"},
+		{"region beats platform", domain.Visitor{Country: "DE", UserAgent: iPhoneUA}, "https://example.com/gdpr", "country:EU"},
+		{"country beats platform", domain.Visitor{Country: "US", UserAgent: iPhoneUA}, "https://example.com/us", "country:US"},
+		{"platform without geo data", domain.Visitor{UserAgent: iPhoneUA}, "https://apps.apple.com/app/id1", "ios"},
+		{"unmatched country falls to platform", domain.Visitor{Country: "JP", UserAgent: iPhoneUA}, "https://apps.apple.com/app/id1", "ios"},
+		{"default", domain.Visitor{Country: "JP", UserAgent: desktopUA}, "https://example.com", redirect.DefaultTarget},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := redirect.Evaluate(gdprTargets, "https://example.com", tt.visitor)
+			assert.Equal(t, tt.expected, result.Destination)
+			assert.Equal(t, tt.target, result.Target)
+		})
+	}
+}
+
+func TestValidateTargets_RejectsUnreachableRules(t *testing.T) {
+	assert.NoError(t, redirect.ValidateTargets(gdprTargets))
+
+	for _, targets := range []domain.Targets{
+		{{Country: "DE", URL: "https://a.example"}, {Country: "DE", URL: "https://b.example"}},
+		{{Country: "DE", Platform: "ios", URL: "https://a.example"}},
+		{{URL: "https://a.example"}},
+		{{Country: "Germany", URL: "https://a.example"}},
+	} {
+		assert.Error(t, redirect.ValidateTargets(targets), targets)
+	}
+}
+
+func TestRedirect_CountryFromGeoIP(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	resolver, err := geo.ParseCIDRTable(strings.NewReader(geoTable))
+	require.NoError(t, err)
+	suite.service = service.NewURLService(suite.repo, suite.cache, suite.cfg, suite.logger, service.WithGeoResolver(resolver))
+	router := setupRedirectRouter(suite)
+
+	suite.cache.On("Get", mock.Anything, "gdpr").Return("", nil)
+	suite.repo.On("FindByShortCode", mock.Anything, "gdpr").
+		Return(&domain.URL{ShortCode: "gdpr", OriginalURL: "https://example.com", IsActive: true, Targets: gdprTargets}, nil)
+	suite.repo.On("IncrementClickCount", mock.Anything, "gdpr", mock.Anything).Return(nil)
+	suite.cache.On("Set", mock.Anything, "gdpr", mock.Anything, time.Hour).Return(nil)
+
+	for ip, expected := range map[string]string{
+		"81.2.69.160": "https://example.com/gdpr",
+		"8.8.8.8":     "https://example.com/us",
+		"1.1.1.1":     "https://example.com",
+	} {
+		req := httptest.NewRequest("GET", "/gdpr", nil)
+		req.RemoteAddr = ip + ":4321"
+		req.Header.Set("User-Agent", desktopUA)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusFound, w.Code, ip)
+		assert.Equal(t, expected, w.Header().Get("Location"), ip)
+	}
+}
+
+func TestRedirect_CountryFromTrustedHeader(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	suite.cfg.GeoIPCountryHeader = "CF-IPCountry"
+	router := setupRedirectRouter(suite)
+
+	suite.cache.On("Get", mock.Anything, "gdpr").Return("", nil)
+	suite.repo.On("FindByShortCode", mock.Anything, "gdpr").
+		Return(&domain.URL{ShortCode: "gdpr", OriginalURL: "https://example.com", IsActive: true, Targets: gdprTargets}, nil)
+	suite.repo.On("IncrementClickCount", mock.Anything, "gdpr", mock.Anything).Return(nil)
+	suite.cache.On("Set", mock.Anything, "gdpr", mock.Anything, time.Hour).Return(nil)
+
+	for header, expected := range map[string]string{
+		"it": "https://example.com/gdpr",
+		"XX": "https://example.com", // Unknown marker falls back
+	} {
+		req := httptest.NewRequest("GET", "/gdpr", nil)
+		req.Header.Set("CF-IPCountry", header)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, expected, w.Header().Get("Location"), header)
+	}
+}
+
+func TestUpdateURL_ReplacesTargets(t *testing.T) {
+	suite := setupURLServiceTest(t)
+
+	url := &domain.URL{ShortCode: "abc123", OriginalURL: "https://example.com", IsActive: true}
+	suite.repo.On("FindByShortCode", mock.Anything, "abc123").Return(url, nil)
+	suite.repo.On("Update", mock.Anything, url).Return(nil)
+	suite.cache.On("Delete", mock.Anything, "abc123").Return(nil)
+
+	targets := []domain.Target{{Country: "de", URL: "https://example.com/de"}}
+	updated, err := suite.service.UpdateURL(context.Background(), "abc123", &domain.UpdateURLRequest{Targets: &targets})
+
+	require.NoError(t, err)
+	require.Len(t, updated.Targets, 1)
+	assert.Equal(t, "DE", updated.Targets[0].Country)
+
+	invalid := []domain.Target{{Country: "DE", URL: "https://a.example"}, {Country: "DE", URL: "https://b.example"}}
+	_, err = suite.service.UpdateURL(context.Background(), "abc123", &domain.UpdateURLRequest{Targets: &invalid})
+	assert.Error(t, err)
+}
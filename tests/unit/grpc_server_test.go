@@ -0,0 +1,100 @@
+package unit
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	pb "url-shortener/api/urlshortener/v1"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/grpcserver"
+)
+
+// startGRPCTest serves the URL service over an in-memory listener and returns a connected client
+func startGRPCTest(t *testing.T, suite *URLServiceTestSuite) pb.URLShortenerClient {
+	lis := bufconn.Listen(1 << 20)
+	srv := grpcserver.NewGRPCServer(suite.service, nil, suite.cfg, suite.logger)
+	go grpcserver.Serve(srv, lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return pb.NewURLShortenerClient(conn)
+}
+
+func TestGRPC_ShortenAndResolve(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	client := startGRPCTest(t, suite)
+
+	suite.repo.On("FindByOriginalURL", mock.Anything, "https://example.com/grpc").
+		Return((*domain.URL)(nil), domain.ErrURLNotFound)
+	suite.repo.On("Create", mock.Anything, mock.AnythingOfType("*domain.URL")).Return(nil)
+	suite.cache.On("Set", mock.Anything, "grpc-alias", cachedDestination("https://example.com/grpc"), mock.Anything).Return(nil)
+	suite.cache.On("Get", mock.Anything, "grpc-alias").Return("https://example.com/grpc", nil)
+	suite.repo.On("IncrementClickCount", mock.Anything, "grpc-alias", mock.Anything).Return(nil)
+
+	resp, err := client.Shorten(context.Background(), &pb.ShortenRequest{Url: "https://example.com/grpc", CustomAlias: "grpc-alias"})
+	require.NoError(t, err)
+	assert.Equal(t, "grpc-alias", resp.ShortCode)
+	assert.Equal(t, "https://short.url/grpc-alias", resp.ShortUrl)
+
+	resolved, err := client.Resolve(context.Background(), &pb.ResolveRequest{ShortCode: "grpc-alias"})
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/grpc", resolved.OriginalUrl)
+}
+
+func TestGRPC_ErrorCodes(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	client := startGRPCTest(t, suite)
+	ctx := context.Background()
+
+	suite.repo.On("Delete", mock.Anything, "missing").Return(domain.ErrURLNotFound)
+	_, err := client.Delete(ctx, &pb.DeleteRequest{ShortCode: "missing"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	suite.repo.On("FindByOriginalURL", mock.Anything, "https://example.com/taken").
+		Return((*domain.URL)(nil), domain.ErrURLNotFound)
+	suite.repo.On("Create", mock.Anything, withShortCode("taken")).Return(domain.ErrShortCodeTaken)
+	_, err = client.Shorten(ctx, &pb.ShortenRequest{Url: "https://example.com/taken", CustomAlias: "taken"})
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+
+	_, err = client.Shorten(ctx, &pb.ShortenRequest{Url: "not a url"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	suite.repo.On("GetStats", mock.Anything, "boom").Return(nil, domain.NewInternalError(assert.AnError))
+	_, err = client.GetStats(ctx, &pb.GetStatsRequest{ShortCode: "boom"})
+	assert.Equal(t, codes.Internal, status.Code(err))
+	assert.NotContains(t, err.Error(), assert.AnError.Error())
+}
+
+func TestGRPC_AuthInterceptor(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	suite.cfg.EnableAuthentication = true
+	suite.cfg.APIKey = "secret"
+	client := startGRPCTest(t, suite)
+
+	suite.repo.On("Delete", mock.Anything, "abc123").Return(nil)
+	suite.cache.On("Delete", mock.Anything, "abc123").Return(nil)
+
+	_, err := client.Delete(context.Background(), &pb.DeleteRequest{ShortCode: "abc123"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-api-key", "secret")
+	_, err = client.Delete(ctx, &pb.DeleteRequest{ShortCode: "abc123"})
+	assert.NoError(t, err)
+}
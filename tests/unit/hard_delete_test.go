@@ -0,0 +1,216 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/archive"
+	"url-shortener/internal/cache"
+	"url-shortener/internal/cache/cachetest"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/handler"
+	"url-shortener/internal/service"
+)
+
+// setupHardDeleteRouter wires DELETE as in main, on a service with an in-memory cache, snapshots and audit
+func setupHardDeleteRouter(t *testing.T, adminKey string) (*URLServiceTestSuite, *gin.Engine, *cachetest.MemoryCache, *memorySnapshotStore, *MockAuditRepository) {
+	gin.SetMode(gin.TestMode)
+	suite := setupURLServiceTest(t)
+	suite.cfg.AdminAPIKey = adminKey
+	store := cachetest.NewMemoryCache()
+	snapshots := newMemorySnapshotStore()
+	audit := new(MockAuditRepository)
+	suite.service = service.NewURLService(suite.repo, store, suite.cfg, suite.logger,
+		service.WithAuditRepository(audit),
+		service.WithSnapshots(stubCapturer{}, snapshots),
+	)
+	h := handler.NewURLHandler(suite.service, suite.cfg, suite.logger)
+
+	router := gin.New()
+	router.DELETE("/api/v1/urls/:shortCode", h.DeleteURL)
+	return suite, router, store, snapshots, audit
+}
+
+func TestHardDelete_ErasesLinkCacheAndSnapshot(t *testing.T) {
+	suite, router, store, snapshots, audit := setupHardDeleteRouter(t, "admin-secret")
+	ctx := context.Background()
+	require.NoError(t, store.Set(ctx, cache.LinkKey("abc123"), cache.NewLinkEntry(&domain.URL{ShortCode: "abc123", OriginalURL: "https://example.com"}).Encode(), time.Hour))
+	require.NoError(t, store.Set(ctx, cache.InactiveKey("abc123"), "1", time.Hour))
+	require.NoError(t, snapshots.Save(ctx, testSnapshot("abc123", time.Now())))
+	suite.repo.On("HardDelete", mock.Anything, "abc123").Return(nil)
+	audit.On("Record", mock.Anything, mock.AnythingOfType("*domain.AuditEntry")).Return(nil)
+
+	req := deleteRequest("abc123?hard=true", map[string]string{"X-API-Key": "admin-secret"})
+	req.RemoteAddr = "10.0.0.7:1234"
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusNoContent, w.Code)
+	assert.Empty(t, w.Body.String())
+	suite.repo.AssertExpectations(t)
+	suite.repo.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+
+	for _, key := range []string{cache.LinkKey("abc123"), cache.InactiveKey("abc123")} {
+		exists, _ := store.Exists(ctx, key)
+		assert.False(t, exists, key)
+	}
+	_, err := snapshots.Get(ctx, "abc123")
+	assert.ErrorIs(t, err, archive.ErrSnapshotNotFound, "the snapshot is erased with the link")
+
+	entry := audit.Calls[0].Arguments.Get(1).(*domain.AuditEntry)
+	assert.Equal(t, domain.AuditActionErase, entry.Action)
+	assert.Equal(t, "abc123", entry.ShortCode)
+	assert.Contains(t, entry.ActorID, "admin:")
+	assert.NotContains(t, entry.ActorID, "admin-secret")
+	assert.Equal(t, "10.0.0.7", entry.ActorIP)
+}
+
+func TestHardDelete_RequiresAdminKey(t *testing.T) {
+	tests := []struct {
+		name     string
+		adminKey string
+		headers  map[string]string
+		status   int
+		errValue string
+	}{
+		{"management token", "admin-secret", map[string]string{"X-Management-Token": testManagementToken}, http.StatusUnauthorized, "unauthorized"},
+		{"wrong admin key", "admin-secret", map[string]string{"X-API-Key": "guess"}, http.StatusUnauthorized, "unauthorized"},
+		{"admin disabled", "", map[string]string{"X-API-Key": "anything"}, http.StatusForbidden, "admin_disabled"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			suite, router, _, _, _ := setupHardDeleteRouter(t, tt.adminKey)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, deleteRequest("abc123?hard=1", tt.headers))
+
+			require.Equal(t, tt.status, w.Code)
+			var body domain.ErrorResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			assert.Equal(t, tt.errValue, body.Error)
+			suite.repo.AssertNotCalled(t, "HardDelete", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestHardDelete_InvalidFlag(t *testing.T) {
+	suite, router, _, _, _ := setupHardDeleteRouter(t, "admin-secret")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, deleteRequest("abc123?hard=maybe", map[string]string{"X-API-Key": "admin-secret"}))
+
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid_request")
+	suite.repo.AssertNotCalled(t, "HardDelete", mock.Anything, mock.Anything)
+	suite.repo.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+}
+
+func TestHardDelete_FalseKeepsSoftDelete(t *testing.T) {
+	suite, router, _, _, _ := setupHardDeleteRouter(t, "admin-secret")
+	suite.repo.On("Delete", mock.Anything, "abc123").Return(nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, deleteRequest("abc123?hard=false", map[string]string{"X-API-Key": "admin-secret"}))
+
+	require.Equal(t, http.StatusOK, w.Code)
+	suite.repo.AssertNotCalled(t, "HardDelete", mock.Anything, mock.Anything)
+}
+
+func TestHardDelete_NotFound(t *testing.T) {
+	suite, router, _, _, audit := setupHardDeleteRouter(t, "admin-secret")
+	suite.repo.On("HardDelete", mock.Anything, "nope00").Return(domain.ErrURLNotFound)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, deleteRequest("nope00?hard=true", map[string]string{"X-API-Key": "admin-secret"}))
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	audit.AssertNotCalled(t, "Record", mock.Anything, mock.Anything)
+}
+
+func TestPurgeDeleted_ErasesInBatches(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	store := cachetest.NewMemoryCache()
+	suite.service = service.NewURLService(suite.repo, store, suite.cfg, suite.logger)
+	ctx := context.Background()
+
+	first := make([]domain.URL, 500)
+	for i := range first {
+		first[i] = domain.URL{ShortCode: fmt.Sprintf("old%03d", i)}
+	}
+	require.NoError(t, store.Set(ctx, cache.LinkKey("old000"), "stale", time.Hour))
+	cutoff := time.Now().Add(-30 * 24 * time.Hour)
+	suite.repo.On("HardDeleteInactive", mock.Anything, cutoff, 500).Return(first, nil).Once()
+	suite.repo.On("HardDeleteInactive", mock.Anything, cutoff, 500).Return([]domain.URL{{ShortCode: "last00"}}, nil).Once()
+
+	erased, err := suite.service.PurgeDeleted(ctx, cutoff)
+	require.NoError(t, err)
+	assert.Equal(t, 501, erased, "a full batch is followed by another until one comes back short")
+	suite.repo.AssertNumberOfCalls(t, "HardDeleteInactive", 2)
+
+	exists, _ := store.Exists(ctx, cache.LinkKey("old000"))
+	assert.False(t, exists, "erased links leave the cache")
+}
+
+func TestRecordClick_SkipsEventForErasedLink(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	clicks := new(MockClickRepository)
+	suite.service = service.NewURLService(suite.repo, cachetest.NewMemoryCache(), suite.cfg, suite.logger, service.WithClickRepository(clicks))
+
+	suite.repo.On("FindByShortCode", mock.Anything, "abc123").
+		Return(&domain.URL{ShortCode: "abc123", OriginalURL: "https://example.com", IsActive: true}, nil)
+	// The link is erased between the redirect and the queued click
+	suite.repo.On("IncrementClickCount", mock.Anything, "abc123", mock.Anything).Return(domain.ErrURLNotFound)
+
+	_, err := suite.service.GetOriginalURL(context.Background(), "abc123", domain.Visitor{IP: "203.0.113.9"})
+	require.NoError(t, err)
+	drainCacheWrites(t, suite)
+
+	clicks.AssertNotCalled(t, "Record", mock.Anything, mock.Anything)
+}
+
+func TestHardDelete_NewLinkDoesNotInheritCachedStats(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	clicks := new(MockClickRepository)
+	suite.service = service.NewURLService(suite.repo, cachetest.NewMemoryCache(), suite.cfg, suite.logger, service.WithClickRepository(clicks))
+	ctx := context.Background()
+	from := time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)
+	query := domain.TimeSeriesQuery{Granularity: domain.GranularityHour, From: from, To: from.Add(2 * time.Hour)}
+	heatmapQuery := domain.HeatmapQuery{From: from, To: query.To}
+
+	suite.repo.On("FindAnyByShortCode", mock.Anything, "abc123").Return(&domain.URL{ID: 1, ShortCode: "abc123"}, nil).Twice()
+	clicks.On("CountByBucket", ctx, "abc123", domain.GranularityHour, from, query.To).
+		Return([]domain.ClickBucket{{BucketStart: from, Clicks: 7}}, nil).Once()
+	series, err := suite.service.GetClickTimeSeries(ctx, "abc123", query)
+	require.NoError(t, err)
+	require.Equal(t, int64(7), series[0].Clicks)
+	clicks.On("CountByWeekdayHour", ctx, "abc123", from, query.To, time.UTC).
+		Return([]domain.HeatmapCell{{Weekday: 1, Hour: 0, Clicks: 7}}, nil).Once()
+	heatmap, err := suite.service.GetClickHeatmap(ctx, "abc123", heatmapQuery)
+	require.NoError(t, err)
+	require.Equal(t, int64(7), heatmap.Total)
+
+	suite.repo.On("HardDelete", mock.Anything, "abc123").Return(nil)
+	require.NoError(t, suite.service.HardDeleteURL(ctx, "abc123", domain.Actor{ID: "admin:test"}))
+
+	// Someone takes the code again
+	suite.repo.On("FindAnyByShortCode", mock.Anything, "abc123").Return(&domain.URL{ID: 2, ShortCode: "abc123"}, nil)
+	clicks.On("CountByBucket", ctx, "abc123", domain.GranularityHour, from, query.To).Return([]domain.ClickBucket{}, nil).Once()
+	series, err = suite.service.GetClickTimeSeries(ctx, "abc123", query)
+	require.NoError(t, err)
+	assert.Zero(t, series[0].Clicks, "the erased link's series is not served for the new one")
+	clicks.On("CountByWeekdayHour", ctx, "abc123", from, query.To, time.UTC).Return([]domain.HeatmapCell{}, nil).Once()
+	heatmap, err = suite.service.GetClickHeatmap(ctx, "abc123", heatmapQuery)
+	require.NoError(t, err)
+	assert.Zero(t, heatmap.Total, "nor is the heat map")
+}
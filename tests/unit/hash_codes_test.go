@@ -0,0 +1,114 @@
+package unit
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/config"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/service"
+	"url-shortener/internal/shortener"
+)
+
+const hashedURL = "https://example.com/machine/generated"
+
+// setupHashService switches the suite's service to hash-derived codes
+func setupHashService(t *testing.T) (*URLServiceTestSuite, service.URLService) {
+	suite := setupURLServiceTest(t)
+	suite.cfg.ShortCodeStrategy = config.ShortCodeStrategyHash
+	svc := service.NewURLService(suite.repo, suite.cache, suite.cfg, suite.logger)
+
+	suite.repo.On("FindByOriginalURL", mock.Anything, hashedURL).
+		Return((*domain.URL)(nil), domain.ErrURLNotFound)
+	suite.cache.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+	return suite, svc
+}
+
+func TestGenerateFromContent_DeterministicPrefixes(t *testing.T) {
+	generator := shortener.NewCodeGenerator(6)
+
+	short := generator.GenerateFromContent(hashedURL, 6)
+	long := generator.GenerateFromContent(hashedURL, 7)
+
+	assert.Len(t, short, 6)
+	assert.Equal(t, short, generator.GenerateFromContent(hashedURL, 6))
+	assert.True(t, strings.HasPrefix(long, short))
+	assert.NotEqual(t, short, generator.GenerateFromContent(hashedURL+"?x=1", 6))
+}
+
+func TestShortenURL_HashStrategySkipsExistenceCheck(t *testing.T) {
+	suite, svc := setupHashService(t)
+	ctx := context.Background()
+	expected := shortener.NewCodeGenerator(6).GenerateFromContent(hashedURL, 6)
+
+	suite.repo.On("Create", ctx, mock.MatchedBy(func(u *domain.URL) bool { return u.ShortCode == expected })).
+		Return(nil).Once()
+
+	resp, err := svc.ShortenURL(ctx, &domain.CreateURLRequest{URL: hashedURL}, domain.CreatorContext{IP: "192.168.1.1"})
+
+	require.NoError(t, err)
+	assert.Equal(t, expected, resp.ShortCode)
+	suite.repo.AssertNotCalled(t, "ExistsByShortCode", mock.Anything, mock.Anything)
+}
+
+func TestShortenURL_HashConflictWithSameDestinationReturnsExisting(t *testing.T) {
+	suite, svc := setupHashService(t)
+	ctx := context.Background()
+	code := shortener.NewCodeGenerator(6).GenerateFromContent(hashedURL, 6)
+
+	// A replica inserted the same link between our dedup lookup and insert
+	suite.repo.On("Create", ctx, mock.AnythingOfType("*domain.URL")).Return(domain.ErrShortCodeTaken).Once()
+	suite.repo.On("FindByShortCode", ctx, code).
+		Return(&domain.URL{ShortCode: code, OriginalURL: hashedURL, IsActive: true}, nil)
+
+	resp, err := svc.ShortenURL(ctx, &domain.CreateURLRequest{URL: hashedURL}, domain.CreatorContext{IP: "192.168.1.1"})
+
+	require.NoError(t, err)
+	assert.Equal(t, code, resp.ShortCode)
+	suite.repo.AssertNumberOfCalls(t, "Create", 1)
+}
+
+func TestShortenURL_HashCollisionExtendsCode(t *testing.T) {
+	suite, svc := setupHashService(t)
+	ctx := context.Background()
+	generator := shortener.NewCodeGenerator(6)
+	code6 := generator.GenerateFromContent(hashedURL, 6)
+	code7 := generator.GenerateFromContent(hashedURL, 7)
+	code8 := generator.GenerateFromContent(hashedURL, 8)
+
+	var attempts []string
+	suite.repo.On("Create", ctx, mock.AnythingOfType("*domain.URL")).
+		Run(func(args mock.Arguments) { attempts = append(attempts, args.Get(1).(*domain.URL).ShortCode) }).
+		Return(domain.ErrShortCodeTaken).Twice()
+	suite.repo.On("Create", ctx, mock.AnythingOfType("*domain.URL")).
+		Run(func(args mock.Arguments) { attempts = append(attempts, args.Get(1).(*domain.URL).ShortCode) }).
+		Return(nil).Once()
+	// code6 belongs to another destination; code7 is held by a deactivated row
+	suite.repo.On("FindByShortCode", ctx, code6).
+		Return(&domain.URL{ShortCode: code6, OriginalURL: "https://other.example", IsActive: true}, nil)
+	suite.repo.On("FindByShortCode", ctx, code7).
+		Return((*domain.URL)(nil), domain.ErrURLNotFound)
+
+	resp, err := svc.ShortenURL(ctx, &domain.CreateURLRequest{URL: hashedURL}, domain.CreatorContext{IP: "192.168.1.1"})
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{code6, code7, code8}, attempts)
+	assert.Equal(t, code8, resp.ShortCode)
+}
+
+func TestShortenURL_HashStrategyKeepsCustomAlias(t *testing.T) {
+	suite, svc := setupHashService(t)
+	ctx := context.Background()
+
+	suite.repo.On("Create", ctx, mock.MatchedBy(func(u *domain.URL) bool { return u.ShortCode == "mine" })).Return(nil)
+
+	resp, err := svc.ShortenURL(ctx, &domain.CreateURLRequest{URL: hashedURL, CustomAlias: "mine"}, domain.CreatorContext{IP: "192.168.1.1"})
+
+	require.NoError(t, err)
+	assert.Equal(t, "mine", resp.ShortCode)
+}
@@ -0,0 +1,149 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/handler"
+)
+
+func TestLoadTimeZone(t *testing.T) {
+	loc, err := domain.LoadTimeZone("")
+	require.NoError(t, err)
+	assert.Equal(t, time.UTC, loc)
+
+	loc, err = domain.LoadTimeZone("America/New_York")
+	require.NoError(t, err)
+	assert.Equal(t, "America/New_York", loc.String())
+
+	for _, name := range []string{"Local", "Mars/Olympus_Mons", "../etc/passwd", "+02:00"} {
+		_, err := domain.LoadTimeZone(name)
+		var appErr *domain.AppError
+		require.ErrorAs(t, err, &appErr, name)
+		assert.Equal(t, http.StatusBadRequest, appErr.StatusCode, name)
+	}
+}
+
+func TestGetClickHeatmap_GapsFilledAndEndedRangeCached(t *testing.T) {
+	_, clicks, svc := setupTimeSeriesTest(t)
+	ctx := context.Background()
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+	from := time.Date(2026, 3, 2, 0, 0, 0, 0, berlin)
+	to := from.AddDate(0, 0, 28)
+
+	clicks.On("CountByWeekdayHour", ctx, "abc123", from, to, berlin).Return([]domain.HeatmapCell{
+		{Weekday: 1, Hour: 9, Clicks: 4},
+		{Weekday: 0, Hour: 23, Clicks: 2},
+	}, nil).Once()
+
+	for i := 0; i < 2; i++ {
+		heatmap, err := svc.GetClickHeatmap(ctx, "abc123", domain.HeatmapQuery{Location: berlin, From: from, To: to})
+		require.NoError(t, err)
+		assert.Equal(t, "Europe/Berlin", heatmap.TimeZone)
+		assert.True(t, from.Equal(heatmap.From))
+		assert.Equal(t, int64(6), heatmap.Total)
+		assert.Equal(t, int64(4), heatmap.Clicks[time.Monday][9])
+		assert.Equal(t, int64(2), heatmap.Clicks[time.Sunday][23])
+		assert.Equal(t, int64(0), heatmap.Clicks[time.Saturday][12])
+	}
+
+	// The second request is answered from the cache
+	clicks.AssertNumberOfCalls(t, "CountByWeekdayHour", 1)
+}
+
+func TestGetClickHeatmap_OpenRangeIsNotCached(t *testing.T) {
+	_, clicks, svc := setupTimeSeriesTest(t)
+	ctx := context.Background()
+	clicks.On("CountByWeekdayHour", ctx, "abc123", mock.Anything, mock.Anything, time.UTC).
+		Return([]domain.HeatmapCell{}, nil)
+
+	for i := 0; i < 2; i++ {
+		heatmap, err := svc.GetClickHeatmap(ctx, "abc123", domain.HeatmapQuery{})
+		require.NoError(t, err)
+		assert.Equal(t, "UTC", heatmap.TimeZone)
+		assert.Equal(t, 28*24*time.Hour, heatmap.To.Sub(heatmap.From), "the default range is four weeks")
+	}
+	clicks.AssertNumberOfCalls(t, "CountByWeekdayHour", 2)
+}
+
+func TestGetClickHeatmap_Validation(t *testing.T) {
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		code   string
+		query  domain.HeatmapQuery
+		status int
+	}{
+		{"over 366 days", "abc123", domain.HeatmapQuery{From: from, To: from.AddDate(0, 0, 367)}, http.StatusBadRequest},
+		{"from after to", "abc123", domain.HeatmapQuery{From: from, To: from.Add(-time.Hour)}, http.StatusBadRequest},
+		{"unknown link", "nope01", domain.HeatmapQuery{From: from, To: from.AddDate(0, 0, 1)}, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, clicks, svc := setupTimeSeriesTest(t)
+
+			_, err := svc.GetClickHeatmap(context.Background(), tt.code, tt.query)
+
+			var appErr *domain.AppError
+			if tt.status == http.StatusNotFound {
+				assert.ErrorIs(t, err, domain.ErrURLNotFound)
+			} else {
+				require.ErrorAs(t, err, &appErr)
+				assert.Equal(t, tt.status, appErr.StatusCode)
+			}
+			clicks.AssertNotCalled(t, "CountByWeekdayHour", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestGetClickHeatmapHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	suite, clicks, svc := setupTimeSeriesTest(t)
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	require.NoError(t, err)
+	from := time.Date(2026, 1, 5, 0, 0, 0, 0, tokyo)
+	clicks.On("CountByWeekdayHour", mock.Anything, "abc123", mock.MatchedBy(from.Equal), mock.MatchedBy(from.AddDate(0, 0, 7).Equal), mock.MatchedBy(func(loc *time.Location) bool {
+		return loc.String() == "Asia/Tokyo"
+	})).Return([]domain.HeatmapCell{{Weekday: 1, Hour: 0, Clicks: 3}}, nil)
+
+	router := gin.New()
+	router.GET("/api/v1/urls/:shortCode/stats/heatmap", handler.NewURLHandler(svc, suite.cfg, suite.logger).GetClickHeatmap)
+
+	// Plain dates are midnights in tz, and "to" is inclusive
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/urls/abc123/stats/heatmap?tz=Asia/Tokyo&from=2026-01-05&to=2026-01-11", nil))
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var body struct {
+		TimeZone string    `json:"tz"`
+		From     string    `json:"from"`
+		Clicks   [][]int64 `json:"clicks"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "Asia/Tokyo", body.TimeZone)
+	assert.Equal(t, "2026-01-05T00:00:00+09:00", body.From)
+	require.Len(t, body.Clicks, 7)
+	for _, day := range body.Clicks {
+		assert.Len(t, day, 24)
+	}
+	assert.Equal(t, int64(3), body.Clicks[1][0])
+
+	for _, target := range []string{"/api/v1/urls/abc123/stats/heatmap?tz=Nowhere/Land", "/api/v1/urls/abc123/stats/heatmap?from=yesterday"} {
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", target, nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code, target)
+	}
+}
@@ -0,0 +1,104 @@
+package unit
+
+import (
+	"bytes"
+	"context"
+	"image/gif"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/config"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/handler"
+)
+
+// setupHitRouter serves the hit and pixel endpoints for abc123 (cached), expired and missing codes
+func setupHitRouter(t *testing.T) (*URLServiceTestSuite, *gin.Engine) {
+	gin.SetMode(gin.TestMode)
+	suite := setupURLServiceTest(t)
+	suite.cfg.BotUserAgents = []string{"bot"}
+	suite.cfg.BotClicks = config.BotClicksSeparate
+
+	expiry := time.Now().Add(-time.Hour)
+	suite.cache.On("Get", mock.Anything, "abc123").Return("https://example.com", nil)
+	suite.cache.On("Get", mock.Anything, mock.Anything).Return("", nil)
+	suite.cache.On("Exists", mock.Anything, mock.Anything).Return(false, nil)
+	suite.repo.On("FindByShortCode", mock.Anything, "expired").
+		Return(&domain.URL{ShortCode: "expired", OriginalURL: "https://example.com", IsActive: true, ExpiresAt: &expiry}, nil)
+	suite.repo.On("FindByShortCode", mock.Anything, "nope01").Return(nil, domain.ErrURLNotFound)
+
+	h := handler.NewURLHandler(suite.service, suite.cfg, suite.logger)
+	router := gin.New()
+	router.POST("/api/v1/urls/:shortCode/hit", h.RegisterHit)
+	router.GET("/api/v1/urls/:shortCode/pixel.gif", h.TrackingPixel)
+	return suite, router
+}
+
+func TestRegisterHit_CountsWithoutRedirect(t *testing.T) {
+	suite, router := setupHitRouter(t)
+	suite.repo.On("IncrementClickCount", mock.Anything, "abc123", mock.Anything).Return(nil).Once()
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/api/v1/urls/abc123/hit", nil))
+	require.NoError(t, suite.service.Close(context.Background()))
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Empty(t, w.Header().Get("Location"))
+	suite.repo.AssertNumberOfCalls(t, "IncrementClickCount", 1)
+}
+
+func TestTrackingPixel_ServesUncachedGIF(t *testing.T) {
+	suite, router := setupHitRouter(t)
+	suite.repo.On("IncrementBotClickCount", mock.Anything, "abc123").Return(nil).Once()
+
+	req := httptest.NewRequest("GET", "/api/v1/urls/abc123/pixel.gif", nil)
+	req.Header.Set("User-Agent", googlebotUA)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.NoError(t, suite.service.Close(context.Background()))
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "image/gif", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Header().Get("Cache-Control"), "no-store")
+	img, err := gif.Decode(bytes.NewReader(w.Body.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, 1, img.Bounds().Dx())
+	assert.Equal(t, 1, img.Bounds().Dy())
+
+	// Bots are counted apart, as on redirects
+	suite.repo.AssertNumberOfCalls(t, "IncrementBotClickCount", 1)
+	suite.repo.AssertNotCalled(t, "IncrementClickCount", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestHitEndpoints_MissingAndExpired(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		status int
+	}{
+		{"hit missing", "POST", "/api/v1/urls/nope01/hit", http.StatusNotFound},
+		{"hit expired", "POST", "/api/v1/urls/expired/hit", http.StatusGone},
+		{"pixel missing", "GET", "/api/v1/urls/nope01/pixel.gif", http.StatusNotFound},
+		{"pixel expired", "GET", "/api/v1/urls/expired/pixel.gif", http.StatusGone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			suite, router := setupHitRouter(t)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+
+			assert.Equal(t, tt.status, w.Code)
+			suite.repo.AssertNotCalled(t, "IncrementClickCount", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
@@ -0,0 +1,265 @@
+package unit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/cache/cachetest"
+	"url-shortener/internal/config"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/handler"
+	"url-shortener/internal/hooks"
+	"url-shortener/internal/service"
+)
+
+// setupHooksRouter serves shorten, redirect and delete as in main, on a service built with opts
+func setupHooksRouter(t *testing.T, opts ...service.Option) (*URLServiceTestSuite, *gin.Engine) {
+	gin.SetMode(gin.TestMode)
+	suite := setupURLServiceTest(t)
+	suite.cfg.AdminAPIKey = "admin-secret"
+	suite.cfg.URLExpirationDays = 0 // Expiry headers would differ by the second between runs
+	suite.service = service.NewURLService(suite.repo, cachetest.NewMemoryCache(), suite.cfg, suite.logger, opts...)
+
+	suite.repo.On("FindByOriginalURL", mock.Anything, mock.Anything).Return(nil, domain.ErrURLNotFound).Maybe()
+	suite.repo.On("Create", mock.Anything, mock.Anything).Return(nil).Maybe()
+	suite.repo.On("FindByShortCode", mock.Anything, "abc123").
+		Return(&domain.URL{ShortCode: "abc123", OriginalURL: "https://example.com", IsActive: true}, nil).Maybe()
+	suite.repo.On("FindByShortCode", mock.Anything, mock.Anything).Return(nil, domain.ErrURLNotFound).Maybe()
+	suite.repo.On("IncrementClickCount", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+	suite.repo.On("Delete", mock.Anything, "hooked").Return(nil).Maybe()
+
+	h := handler.NewURLHandler(suite.service, suite.cfg, suite.logger)
+	router := gin.New()
+	router.POST("/api/v1/shorten", h.ShortenURL)
+	router.DELETE("/api/v1/urls/:shortCode", h.DeleteURL)
+	router.GET("/:shortCode", h.RedirectURL)
+	return suite, router
+}
+
+func shortenRequest(body string) *http.Request {
+	req := httptest.NewRequest("POST", "/api/v1/shorten", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
+
+// runHooksScript creates, follows and deletes a link and returns every response in full
+// Only the random management token and the creation time are blanked.
+func runHooksScript(t *testing.T, opts ...service.Option) []string {
+	suite, router := setupHooksRouter(t, opts...)
+	requests := []func() *http.Request{
+		func() *http.Request { return shortenRequest(`{"url":"https://example.com/a","custom_alias":"hooked"}`) },
+		func() *http.Request { return shortenRequest(`{"url":"https://example.com/b","dry_run":true}`) },
+		func() *http.Request { return shortenRequest(`{"url":"ftp://example.com/c"}`) },
+		func() *http.Request { return httptest.NewRequest("GET", "/hooked?src=email", nil) },
+		func() *http.Request { return httptest.NewRequest("GET", "/missing", nil) },
+		func() *http.Request { return deleteRequest("hooked", map[string]string{"X-API-Key": "admin-secret"}) },
+		func() *http.Request { return httptest.NewRequest("GET", "/hooked", nil) },
+	}
+
+	var transcript []string
+	for _, next := range requests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, next())
+		drainCacheWrites(t, suite)
+
+		body := w.Body.Bytes()
+		var fields map[string]interface{}
+		if json.Unmarshal(body, &fields) == nil {
+			for _, random := range []string{"management_token", "created_at"} {
+				if _, ok := fields[random]; ok {
+					fields[random] = "-"
+				}
+			}
+			body, _ = json.Marshal(fields)
+		}
+
+		headers := make([]string, 0, len(w.Header()))
+		for name, values := range w.Header() {
+			headers = append(headers, name+": "+strings.Join(values, ", "))
+		}
+		sort.Strings(headers)
+		transcript = append(transcript, fmt.Sprintf("%d\n%s\n%s", w.Code, strings.Join(headers, "\n"), body))
+	}
+	return transcript
+}
+
+func TestHooks_UnsetHooksChangeNothing(t *testing.T) {
+	want := runHooksScript(t)
+	require.Len(t, want, 7)
+	assert.True(t, strings.HasPrefix(want[0], "201"), want[0])
+	assert.True(t, strings.HasPrefix(want[3], "301"), want[3])
+
+	for name, opt := range map[string]service.Option{
+		"zero hooks":  service.WithHooks(service.Hooks{}),
+		"empty chain": service.WithHooks(hooks.Chain()),
+		"built from an empty HOOKS": func() service.Option {
+			built, err := hooks.Build(&config.Config{})
+			require.NoError(t, err)
+			return service.WithHooks(built)
+		}(),
+	} {
+		assert.Equal(t, want, runHooksScript(t, opt), name)
+	}
+}
+
+func TestHooks_RunAroundOperations(t *testing.T) {
+	var calls []string
+	recorder := service.Hooks{
+		BeforeCreate: func(ctx context.Context, url *domain.URL) error {
+			calls = append(calls, "before create "+url.OriginalURL)
+			url.Title = "Set by a hook"
+			return nil
+		},
+		AfterCreate: func(ctx context.Context, url *domain.URL) error {
+			calls = append(calls, "after create "+url.ShortCode)
+			return errors.New("CRM unreachable")
+		},
+		BeforeRedirect: func(ctx context.Context, decision *domain.RedirectDecision) error {
+			calls = append(calls, "before redirect "+decision.OriginalURL)
+			return nil
+		},
+		AfterDelete: func(ctx context.Context, shortCode string) error {
+			calls = append(calls, "after delete "+shortCode)
+			return nil
+		},
+	}
+	suite, router := setupHooksRouter(t, service.WithHooks(recorder))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, shortenRequest(`{"url":"https://example.com/a","custom_alias":"hooked"}`))
+	require.Equal(t, http.StatusCreated, w.Code, "a failing after hook is only logged")
+	drainCacheWrites(t, suite)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/hooked", nil))
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, deleteRequest("hooked", map[string]string{"X-API-Key": "admin-secret"}))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	assert.Equal(t, []string{
+		"before create https://example.com/a",
+		"after create hooked",
+		"before redirect https://example.com/a",
+		"after delete hooked",
+	}, calls)
+	suite.repo.AssertCalled(t, "Create", mock.Anything, mock.MatchedBy(func(url *domain.URL) bool {
+		return url.Title == "Set by a hook"
+	}))
+}
+
+func TestHooks_BeforeHooksVeto(t *testing.T) {
+	vetoes := service.Hooks{
+		BeforeCreate: func(ctx context.Context, url *domain.URL) error {
+			return domain.NewFieldError("url", "blocked", "Destination is blocked")
+		},
+		BeforeRedirect: func(ctx context.Context, decision *domain.RedirectDecision) error {
+			return domain.ErrURLNotFound
+		},
+	}
+	suite, router := setupHooksRouter(t, service.WithHooks(vetoes))
+
+	for _, body := range []string{`{"url":"https://example.com/a"}`, `{"url":"https://example.com/a","dry_run":true}`} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, shortenRequest(body))
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+		assert.Contains(t, w.Body.String(), `"rule":"blocked"`)
+	}
+	suite.repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/abc123", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Empty(t, w.Header().Get("Location"))
+	suite.repo.AssertCalled(t, "IncrementClickCount", mock.Anything, "abc123", mock.Anything)
+}
+
+func TestHooksChain_OrderAndErrors(t *testing.T) {
+	var calls []string
+	step := func(name string, err error) service.Hooks {
+		return service.Hooks{
+			BeforeCreate: func(ctx context.Context, url *domain.URL) error {
+				calls = append(calls, name)
+				return err
+			},
+			AfterDelete: func(ctx context.Context, shortCode string) error {
+				calls = append(calls, name)
+				return err
+			},
+		}
+	}
+	first, second := errors.New("first"), errors.New("second")
+	chained := hooks.Chain(step("a", nil), step("b", first), service.Hooks{}, step("c", second))
+
+	assert.ErrorIs(t, chained.BeforeCreate(context.Background(), &domain.URL{}), first)
+	assert.Equal(t, []string{"a", "b"}, calls, "a veto stops the chain")
+
+	calls = nil
+	err := chained.AfterDelete(context.Background(), "abc123")
+	assert.Equal(t, []string{"a", "b", "c"}, calls, "after hooks all run")
+	assert.ErrorIs(t, err, first)
+	assert.ErrorIs(t, err, second)
+
+	assert.Nil(t, chained.AfterCreate, "callbacks no hook sets stay unset")
+	assert.Nil(t, chained.BeforeRedirect)
+}
+
+func TestHooksBuild(t *testing.T) {
+	assert.Contains(t, hooks.Registered(), hooks.HostAllowlist)
+	assert.Panics(t, func() {
+		hooks.Register(hooks.HostAllowlist, func(*config.Config) (service.Hooks, error) { return service.Hooks{}, nil })
+	}, "a second hook of the same name")
+
+	_, err := hooks.Build(&config.Config{Hooks: []string{"notify_crm"}})
+	assert.ErrorContains(t, err, "notify_crm")
+
+	_, err = hooks.Build(&config.Config{Hooks: []string{hooks.HostAllowlist}})
+	assert.ErrorContains(t, err, "HOOK_ALLOWED_HOSTS")
+
+	cfg, err := config.LoadFrom(nil)
+	require.NoError(t, err)
+	cfg.HookAllowedHosts = []string{"https://example.com"}
+	assert.ErrorContains(t, cfg.Validate(), "HOOK_ALLOWED_HOSTS")
+}
+
+func TestHostAllowlist(t *testing.T) {
+	h, err := hooks.Build(&config.Config{Hooks: []string{hooks.HostAllowlist}, HookAllowedHosts: []string{"example.com"}})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	for _, allowed := range []*domain.URL{
+		{OriginalURL: "https://example.com/a"},
+		{OriginalURL: "https://shop.EXAMPLE.com:8443/a"},
+		{OriginalURL: "https://example.com", Variants: domain.Variants{{URL: "https://www.example.com/b", Weight: 100}}},
+		{OriginalURL: "https://short.url/abc123", Bundle: domain.BundleItems{{URL: "https://example.com"}}},
+	} {
+		assert.NoError(t, h.BeforeCreate(ctx, allowed), allowed.OriginalURL)
+	}
+
+	for field, refused := range map[string]*domain.URL{
+		"url":              {OriginalURL: "https://notexample.com/a"},
+		"targets.url":      {OriginalURL: "https://example.com", Targets: domain.Targets{{Platform: "ios", URL: "https://evil.test"}}},
+		"ios_fallback_url": {OriginalURL: "https://example.com", DeepLink: "myapp://a", IOSFallbackURL: "https://evil.test"},
+	} {
+		err := h.BeforeCreate(ctx, refused)
+		var appErr *domain.AppError
+		require.ErrorAs(t, err, &appErr, field)
+		require.Len(t, appErr.Fields, 1)
+		assert.Equal(t, field, appErr.Fields[0].Field)
+		assert.Equal(t, "host_not_allowed", appErr.Fields[0].Rule)
+	}
+}
@@ -0,0 +1,159 @@
+package unit
+
+import (
+	"context"
+	"html"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/handler"
+	"url-shortener/pkg/signer"
+)
+
+func setupRedirectRouter(suite *URLServiceTestSuite) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	h := handler.NewURLHandler(suite.service, suite.cfg, suite.logger)
+
+	router := gin.New()
+	router.GET("/:shortCode", h.RedirectURL)
+	router.GET("/:shortCode/*path", h.RedirectPath)
+	return router
+}
+
+var continueLink = regexp.MustCompile(`href="(/[^"]+/continue\?token=[^"]+)"`)
+
+func TestSigner_VerifyRoundTrip(t *testing.T) {
+	s := signer.New([]byte("secret"))
+	now := time.Now()
+	token := s.Sign("abc123", now.Add(time.Minute))
+
+	assert.NoError(t, s.Verify(token, "abc123", now))
+	assert.ErrorIs(t, s.Verify(token, "other", now), signer.ErrInvalidToken)
+	assert.ErrorIs(t, s.Verify(token, "abc123", now.Add(2*time.Minute)), signer.ErrTokenExpired)
+	assert.ErrorIs(t, s.Verify(token+"x", "abc123", now), signer.ErrInvalidToken)
+	assert.ErrorIs(t, s.Verify("garbage", "abc123", now), signer.ErrInvalidToken)
+	assert.ErrorIs(t, signer.New([]byte("other-key")).Verify(token, "abc123", now), signer.ErrInvalidToken)
+}
+
+func TestRedirect_InterstitialFlowCountsOnce(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	router := setupRedirectRouter(suite)
+
+	flagged := &domain.URL{
+		ShortCode:            "flagged",
+		OriginalURL:          "https://example.com/?a=<b>",
+		IsActive:             true,
+		RequiresInterstitial: true,
+	}
+	suite.cache.On("Get", mock.Anything, "flagged").Return("", nil)
+	suite.repo.On("FindByShortCode", mock.Anything, "flagged").Return(flagged, nil)
+
+	// First hop: warning page, no click recorded
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/flagged", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "https://example.com/?a=&lt;b&gt;")
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+	suite.repo.AssertNotCalled(t, "IncrementClickCount", mock.Anything, mock.Anything, mock.Anything)
+
+	match := continueLink.FindStringSubmatch(w.Body.String())
+	require.Len(t, match, 2)
+
+	// Second hop: valid token redirects and counts the click
+	suite.repo.On("IncrementClickCount", mock.Anything, "flagged", mock.Anything).Return(nil).Once()
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", html.UnescapeString(match[1]), nil))
+
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "https://example.com/?a=<b>", w.Header().Get("Location"))
+	suite.repo.AssertNumberOfCalls(t, "IncrementClickCount", 1)
+	suite.cache.AssertNotCalled(t, "Set", mock.Anything, "flagged", mock.Anything, mock.Anything)
+}
+
+func TestRedirect_ContinueRejectsBadToken(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	router := setupRedirectRouter(suite)
+
+	// A token issued for another code must not work for this one
+	other := signer.New([]byte("unknown-key")).Sign("flagged", time.Now().Add(time.Minute))
+
+	for _, target := range []string{
+		"/flagged/continue",
+		"/flagged/continue?token=" + other,
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", target, nil))
+		assert.Equal(t, http.StatusForbidden, w.Code, target)
+	}
+
+	suite.repo.AssertNotCalled(t, "FindByShortCode", mock.Anything, mock.Anything)
+	suite.repo.AssertNotCalled(t, "IncrementClickCount", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRedirect_GlobalInterstitialBypassesCache(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	suite.cfg.InterstitialAll = true
+	router := setupRedirectRouter(suite)
+
+	suite.repo.On("FindByShortCode", mock.Anything, "abc123").
+		Return(&domain.URL{ShortCode: "abc123", OriginalURL: "https://example.com", IsActive: true}, nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/abc123", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "/abc123/continue?token=")
+	suite.cache.AssertNotCalled(t, "Get", mock.Anything, mock.Anything)
+	suite.repo.AssertNotCalled(t, "IncrementClickCount", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRedirect_NewLinkWindow(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	suite.cfg.InterstitialNewLinkMinutes = 60
+	router := setupRedirectRouter(suite)
+
+	suite.cache.On("Get", mock.Anything, "young").Return("", nil)
+	suite.repo.On("FindByShortCode", mock.Anything, "young").
+		Return(&domain.URL{ShortCode: "young", OriginalURL: "https://example.com", IsActive: true, CreatedAt: time.Now().Add(-time.Minute)}, nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/young", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	suite.cache.On("Get", mock.Anything, "old").Return("", nil)
+	suite.repo.On("FindByShortCode", mock.Anything, "old").
+		Return(&domain.URL{ShortCode: "old", OriginalURL: "https://example.com/old", IsActive: true, CreatedAt: time.Now().Add(-2 * time.Hour)}, nil)
+	suite.repo.On("IncrementClickCount", mock.Anything, "old", mock.Anything).Return(nil)
+	suite.cache.On("Set", mock.Anything, "old", "https://example.com/old", time.Hour).Return(nil)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/old", nil))
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+}
+
+func TestUpdateURL_TogglesInterstitialAndInvalidatesCache(t *testing.T) {
+	suite := setupURLServiceTest(t)
+
+	url := &domain.URL{ShortCode: "abc123", OriginalURL: "https://example.com", IsActive: true}
+	suite.repo.On("FindByShortCode", mock.Anything, "abc123").Return(url, nil)
+	suite.repo.On("Update", mock.Anything, url).Return(nil)
+	suite.cache.On("Delete", mock.Anything, "abc123").Return(nil)
+
+	enabled := true
+	updated, err := suite.service.UpdateURL(context.Background(), "abc123", &domain.UpdateURLRequest{RequiresInterstitial: &enabled})
+
+	require.NoError(t, err)
+	assert.True(t, updated.RequiresInterstitial)
+	suite.cache.AssertCalled(t, "Delete", mock.Anything, "abc123")
+}
@@ -0,0 +1,258 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/config"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/handler"
+	"url-shortener/internal/linkcheck"
+	"url-shortener/internal/notify"
+)
+
+// fakeChecker answers every destination from a table and counts the requests per destination
+type fakeChecker struct {
+	mu      sync.Mutex
+	answers map[string]linkcheck.Result
+	errs    map[string]error
+	calls   map[string]int
+}
+
+func (f *fakeChecker) Check(ctx context.Context, rawURL string) (linkcheck.Result, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.calls == nil {
+		f.calls = map[string]int{}
+	}
+	f.calls[rawURL]++
+	if err := f.errs[rawURL]; err != nil {
+		return linkcheck.Result{}, err
+	}
+	return f.answers[rawURL], nil
+}
+
+// brokenWebhook records the broken link notices it receives
+func brokenWebhook(t *testing.T) (*httptest.Server, func() []notify.BrokenNotice) {
+	var mu sync.Mutex
+	var received []notify.BrokenNotice
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var notice notify.BrokenNotice
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&notice))
+		mu.Lock()
+		received = append(received, notice)
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, func() []notify.BrokenNotice {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]notify.BrokenNotice(nil), received...)
+	}
+}
+
+// recordedCheck matches a RecordLinkCheck with the given status and verdict
+func recordedCheck(status *int, broken bool) interface{} {
+	return mock.MatchedBy(func(check domain.LinkCheck) bool {
+		if (status == nil) != (check.StatusCode == nil) || (status != nil && *status != *check.StatusCode) {
+			return false
+		}
+		return check.Broken == broken && !check.CheckedAt.IsZero()
+	})
+}
+
+func intPtr(n int) *int {
+	return &n
+}
+
+func TestLinkCheck_RecordsAndNotifiesTransitions(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	server, received := brokenWebhook(t)
+
+	links := []domain.URL{
+		{ID: 1, ShortCode: "gone01", OriginalURL: "https://a.example/gone", IsActive: true},
+		{ID: 2, ShortCode: "still1", OriginalURL: "https://b.example/gone", IsActive: true, IsBroken: true},
+		{ID: 3, ShortCode: "fine01", OriginalURL: "https://c.example/", IsActive: true},
+		{ID: 4, ShortCode: "nohost", OriginalURL: "https://d.example/", IsActive: true},
+		{ID: 5, ShortCode: "flaky1", OriginalURL: "https://e.example/", IsActive: true, IsBroken: true},
+	}
+	checker := &fakeChecker{
+		answers: map[string]linkcheck.Result{
+			"https://a.example/gone": {StatusCode: 404},
+			"https://b.example/gone": {StatusCode: 410},
+			"https://c.example/":     {StatusCode: 200},
+		},
+		errs: map[string]error{
+			"https://d.example/": &net.DNSError{Err: "no such host", Name: "d.example", IsNotFound: true},
+			"https://e.example/": errors.New("i/o timeout"),
+		},
+	}
+	suite.repo.On("FindDueForCheck", mock.Anything, mock.Anything, uint(0), 10).Return(links, nil).Once()
+	suite.repo.On("RecordLinkCheck", mock.Anything, "gone01", recordedCheck(intPtr(404), true)).Return(nil).Once()
+	suite.repo.On("RecordLinkCheck", mock.Anything, "still1", recordedCheck(intPtr(410), true)).Return(nil).Once()
+	suite.repo.On("RecordLinkCheck", mock.Anything, "fine01", recordedCheck(intPtr(200), false)).Return(nil).Once()
+	suite.repo.On("RecordLinkCheck", mock.Anything, "nohost", recordedCheck(nil, true)).Return(nil).Once()
+	suite.repo.On("RecordLinkCheck", mock.Anything, "flaky1", recordedCheck(nil, true)).Return(nil).Once()
+
+	monitor := linkcheck.NewMonitor(suite.repo, checker, notify.NewWebhookNotifier(server.URL, time.Second), nil, linkcheck.Config{
+		RecheckAfter: 24 * time.Hour,
+		Concurrency:  3,
+		BatchSize:    10,
+		HostRate:     100,
+		BaseURL:      "https://short.url",
+	}, suite.logger)
+
+	require.NoError(t, monitor.Run(context.Background()))
+	suite.repo.AssertExpectations(t)
+
+	// Only links turning broken are notified; still1 was broken before, flaky1 only timed out
+	notices := received()
+	require.Len(t, notices, 2)
+	codes := []string{notices[0].ShortCode, notices[1].ShortCode}
+	assert.ElementsMatch(t, []string{"gone01", "nohost"}, codes)
+	for _, notice := range notices {
+		assert.Equal(t, domain.EventURLBroken, notice.Type)
+		assert.Equal(t, "https://short.url/"+notice.ShortCode, notice.ShortURL)
+		if notice.ShortCode == "gone01" {
+			require.NotNil(t, notice.StatusCode)
+			assert.Equal(t, 404, *notice.StatusCode)
+		} else {
+			assert.Nil(t, notice.StatusCode)
+		}
+	}
+
+	// Links checked within RECHECK_AFTER are not asked for
+	checkedBefore := suite.repo.Calls[0].Arguments.Get(1).(time.Time)
+	assert.WithinDuration(t, time.Now().Add(-24*time.Hour), checkedBefore, time.Minute)
+}
+
+func TestLinkCheck_BacksOffRateLimitedHosts(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	links := []domain.URL{
+		{ID: 1, ShortCode: "limit1", OriginalURL: "https://busy.example/1", IsActive: true},
+		{ID: 2, ShortCode: "limit2", OriginalURL: "https://busy.example/2", IsActive: true},
+		{ID: 3, ShortCode: "other1", OriginalURL: "https://quiet.example/", IsActive: true},
+	}
+	checker := &fakeChecker{answers: map[string]linkcheck.Result{
+		"https://busy.example/1": {StatusCode: 429, RetryAfter: time.Hour},
+		"https://busy.example/2": {StatusCode: 200},
+		"https://quiet.example/": {StatusCode: 200},
+	}}
+	suite.repo.On("FindDueForCheck", mock.Anything, mock.Anything, uint(0), 10).Return(links, nil)
+	suite.repo.On("RecordLinkCheck", mock.Anything, "other1", mock.Anything).Return(nil)
+
+	monitor := linkcheck.NewMonitor(suite.repo, checker, nil, nil, linkcheck.Config{Concurrency: 1, BatchSize: 10, HostRate: 100}, suite.logger)
+	require.NoError(t, monitor.Run(context.Background()))
+	require.NoError(t, monitor.Run(context.Background()))
+
+	// The paused host isn't asked again, in this run or the next, and its links stay due
+	assert.Equal(t, 1, checker.calls["https://busy.example/1"])
+	assert.Zero(t, checker.calls["https://busy.example/2"])
+	assert.Equal(t, 2, checker.calls["https://quiet.example/"])
+	suite.repo.AssertNotCalled(t, "RecordLinkCheck", mock.Anything, "limit1", mock.Anything)
+	suite.repo.AssertNotCalled(t, "RecordLinkCheck", mock.Anything, "limit2", mock.Anything)
+}
+
+func TestLinkCheck_PagesAndSkipsBundlesAndExpiredLinks(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	expired := time.Now().Add(-time.Hour)
+	checker := &fakeChecker{answers: map[string]linkcheck.Result{}}
+	suite.repo.On("FindDueForCheck", mock.Anything, mock.Anything, uint(0), 2).Return([]domain.URL{
+		{ID: 7, ShortCode: "bundle", OriginalURL: "https://x.example/", IsActive: true, Bundle: domain.BundleItems{{URL: "https://x.example/a"}}},
+		{ID: 9, ShortCode: "old001", OriginalURL: "https://y.example/", IsActive: true, ExpiresAt: &expired},
+	}, nil).Once()
+	suite.repo.On("FindDueForCheck", mock.Anything, mock.Anything, uint(9), 2).Return([]domain.URL{
+		{ID: 12, ShortCode: "last01", OriginalURL: "https://z.example/", IsActive: true},
+	}, nil).Once()
+	suite.repo.On("RecordLinkCheck", mock.Anything, "last01", mock.Anything).Return(nil).Once()
+
+	monitor := linkcheck.NewMonitor(suite.repo, checker, nil, nil, linkcheck.Config{Concurrency: 2, BatchSize: 2, HostRate: 100}, suite.logger)
+	require.NoError(t, monitor.Run(context.Background()))
+
+	suite.repo.AssertExpectations(t)
+	assert.Equal(t, map[string]int{"https://z.example/": 1}, checker.calls)
+}
+
+func TestLinkCheck_SkipsWhileAnotherInstanceHoldsTheLock(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	monitor := linkcheck.NewMonitor(suite.repo, &fakeChecker{}, nil, heldLocker{}, linkcheck.Config{HostRate: 1}, suite.logger)
+
+	require.NoError(t, monitor.Run(context.Background()))
+	suite.repo.AssertNotCalled(t, "FindDueForCheck", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestLinkCheck_IsBroken(t *testing.T) {
+	for status, broken := range map[int]bool{200: false, 301: false, 403: false, 404: true, 410: true, 429: false, 500: false, 503: false} {
+		assert.Equal(t, broken, linkcheck.IsBroken(status), status)
+	}
+	assert.True(t, linkcheck.HostGone(&net.DNSError{Err: "no such host", IsNotFound: true}))
+	assert.False(t, linkcheck.HostGone(&net.DNSError{Err: "server misbehaving", IsTemporary: true}))
+	assert.False(t, linkcheck.HostGone(errors.New("connection refused")))
+}
+
+func TestListURLsHandler_BrokenFilter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	suite := setupURLServiceTest(t)
+	broken := true
+	suite.repo.On("FindActiveMatching", mock.Anything, domain.URLFilter{Broken: &broken}, 3, 0).Return([]domain.URL{
+		{ShortCode: "gone01", OriginalURL: "https://a.example/gone", IsActive: true, IsBroken: true, LastStatusCode: intPtr(404)},
+		{ShortCode: "gone02", OriginalURL: "https://b.example/gone", IsActive: true, IsBroken: true},
+		{ShortCode: "gone03", OriginalURL: "https://c.example/gone", IsActive: true, IsBroken: true},
+	}, nil)
+
+	router := gin.New()
+	router.GET("/api/v1/urls", handler.NewURLHandler(suite.service, suite.cfg, suite.logger).ListURLs)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/urls?broken=true&limit=2", nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var page domain.URLPage
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+	require.Len(t, page.URLs, 2)
+	assert.True(t, page.URLs[0].IsBroken)
+	require.NotNil(t, page.URLs[0].LastStatusCode)
+	assert.Equal(t, 404, *page.URLs[0].LastStatusCode)
+	require.NotNil(t, page.NextOffset)
+	assert.Equal(t, 2, *page.NextOffset)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/urls?broken=maybe", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid_request")
+}
+
+func TestConfig_LinkCheck(t *testing.T) {
+	t.Setenv("ENABLE_LINK_CHECK", "true")
+	t.Setenv("LINK_CHECK_INTERVAL", "30m")
+	t.Setenv("LINK_CHECK_CONCURRENCY", "8")
+	cfg, err := config.LoadFrom(nil)
+	require.NoError(t, err)
+	assert.True(t, cfg.EnableLinkCheck)
+	assert.Equal(t, 30*time.Minute, cfg.LinkCheckInterval)
+	assert.Equal(t, 8, cfg.LinkCheckConcurrency)
+	assert.Equal(t, 24*time.Hour, cfg.LinkCheckRecheckAfter)
+
+	t.Setenv("LINK_CHECK_CONCURRENCY", "0")
+	_, err = config.LoadFrom(nil)
+	assert.ErrorContains(t, err, "LINK_CHECK_CONCURRENCY")
+
+	t.Setenv("LINK_CHECK_CONCURRENCY", "4")
+	t.Setenv("LINK_CHECK_WEBHOOK_URL", "ftp://hooks.example")
+	_, err = config.LoadFrom(nil)
+	assert.ErrorContains(t, err, "LINK_CHECK_WEBHOOK_URL")
+}
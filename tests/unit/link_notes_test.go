@@ -0,0 +1,164 @@
+package unit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/domain"
+	"url-shortener/pkg/validator"
+)
+
+func TestStripHTML(t *testing.T) {
+	tests := []struct {
+		input     string
+		multiline bool
+		expected  string
+	}{
+		{"<b>Launch</b> plan", false, "Launch plan"},
+		{"  spring \t\n campaign  ", false, "spring campaign"},
+		{"<<b>script>alert(1)<</b>/script>", false, "alert(1)"},
+		{`<img src=x onerror="alert(1)">Docs`, false, "Docs"},
+		{"<!-- hidden -->Visible", false, "Visible"},
+		{"Q3 <script", false, "Q3"},
+		{"a < b && c > d", false, "a < b && c > d"},
+		{"&lt;b&gt;kept&lt;/b&gt;", false, "&lt;b&gt;kept&lt;/b&gt;"},
+		{"bell\a and\x00nul", false, "bell and nul"},
+		{"first\r\nsecond<br>\n\tthird\x1b", true, "first\nsecond\n third"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, validator.StripHTML(tt.input, tt.multiline), tt.input)
+	}
+}
+
+func TestShortenURL_StoresSanitizedNotes(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	ctx := context.Background()
+
+	var stored *domain.URL
+	suite.repo.On("FindByOriginalURL", ctx, "https://example.com").
+		Return(&domain.URL{ShortCode: "old123", OriginalURL: "https://example.com", IsActive: true}, nil)
+	suite.repo.On("Create", ctx, mock.AnythingOfType("*domain.URL")).Run(func(args mock.Arguments) {
+		stored = args.Get(1).(*domain.URL)
+	}).Return(nil)
+	suite.cache.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	resp, err := suite.service.ShortenURL(ctx, &domain.CreateURLRequest{
+		URL:         "https://example.com",
+		Title:       "<b>Launch</b>\n plan",
+		Description: "<script>alert(1)</script>Owner: <i>growth</i>\r\nReview in May",
+	}, domain.CreatorContext{IP: "192.168.1.1"})
+
+	require.NoError(t, err)
+	assert.False(t, resp.Deduplicated, "a link with notes is never answered with an existing one")
+	require.NotNil(t, stored)
+	assert.Equal(t, "Launch plan", stored.Title)
+	assert.Equal(t, "alert(1)Owner: growth\nReview in May", stored.Description)
+}
+
+func TestShortenURL_RejectsLongNotes(t *testing.T) {
+	tests := []struct {
+		name string
+		req  *domain.CreateURLRequest
+	}{
+		{"title", &domain.CreateURLRequest{URL: "https://example.com", Title: strings.Repeat("é", domain.MaxTitleLength+1)}},
+		{"description", &domain.CreateURLRequest{URL: "https://example.com", Description: strings.Repeat("x", domain.MaxDescriptionLength+1)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			suite := setupURLServiceTest(t)
+
+			_, err := suite.service.ShortenURL(context.Background(), tt.req, domain.CreatorContext{IP: "192.168.1.1"})
+
+			var appErr *domain.AppError
+			require.ErrorAs(t, err, &appErr)
+			assert.Equal(t, http.StatusBadRequest, appErr.StatusCode)
+			assert.Contains(t, appErr.Message, tt.name)
+			suite.repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestShortenURL_TagsDontCountTowardsLimit(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	ctx := context.Background()
+
+	suite.repo.On("FindByOriginalURL", ctx, "https://example.com").Return((*domain.URL)(nil), domain.ErrURLNotFound)
+	suite.repo.On("Create", ctx, mock.AnythingOfType("*domain.URL")).Return(nil)
+	suite.cache.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	title := "<span>" + strings.Repeat("x", domain.MaxTitleLength) + "</span>"
+	_, err := suite.service.ShortenURL(ctx, &domain.CreateURLRequest{URL: "https://example.com", Title: title}, domain.CreatorContext{IP: "192.168.1.1"})
+
+	assert.NoError(t, err)
+}
+
+func TestUpdateURL_SetsAndClearsNotes(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	ctx := context.Background()
+
+	url := &domain.URL{ShortCode: "abc123", OriginalURL: "https://example.com", IsActive: true, Title: "Old", Description: "Keep me"}
+	suite.repo.On("FindByShortCode", ctx, "abc123").Return(url, nil)
+	suite.repo.On("Update", ctx, url).Return(nil)
+	suite.cache.On("Delete", mock.Anything, "abc123").Return(nil)
+
+	title := `New <a href="javascript:alert(1)">title</a>`
+	updated, err := suite.service.UpdateURL(ctx, "abc123", &domain.UpdateURLRequest{Title: &title})
+	require.NoError(t, err)
+	assert.Equal(t, "New title", updated.Title)
+	assert.Equal(t, "Keep me", updated.Description, "omitted fields are left unchanged")
+
+	empty := ""
+	updated, err = suite.service.UpdateURL(ctx, "abc123", &domain.UpdateURLRequest{Description: &empty})
+	require.NoError(t, err)
+	assert.Equal(t, "New title", updated.Title)
+	assert.Empty(t, updated.Description)
+
+	long := strings.Repeat("x", domain.MaxTitleLength+1)
+	_, err = suite.service.UpdateURL(ctx, "abc123", &domain.UpdateURLRequest{Title: &long})
+	var appErr *domain.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, http.StatusBadRequest, appErr.StatusCode)
+}
+
+func TestGetURLInfo_ReturnsNotes(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	url := infoTestURL()
+	url.Title = "Launch plan"
+	url.Description = "Owner: growth"
+	suite.repo.On("FindByShortCode", mock.Anything, "abc123").Return(url, nil)
+
+	info, err := suite.service.GetURLInfo(context.Background(), "abc123")
+
+	require.NoError(t, err)
+	assert.Equal(t, "Launch plan", info.Title)
+	assert.Equal(t, "Owner: growth", info.Description)
+}
+
+func TestExportURLs_SearchFilter(t *testing.T) {
+	router, repo := setupExportRouter(t, []*domain.URL{
+		{ShortCode: "abc123", OriginalURL: "https://example.com", Title: "Launch plan", Description: "Owner: growth", IsActive: true},
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/export?format=csv&q=+launch+", nil))
+
+	require.Equal(t, http.StatusOK, w.Code)
+	filter := repo.Calls[0].Arguments.Get(1).(domain.URLFilter)
+	assert.Equal(t, "launch", filter.Search)
+	assert.False(t, filter.IsEmpty())
+	assert.Contains(t, w.Body.String(), "title,description\n")
+	assert.Contains(t, w.Body.String(), ",Launch plan,Owner: growth\n")
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/export?q="+strings.Repeat("x", 201), nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,103 @@
+package unit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/cache"
+	"url-shortener/internal/cache/cachetest"
+	"url-shortener/internal/config"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/service"
+)
+
+func TestLinkStateHeaders_DefaultOn(t *testing.T) {
+	cfg, err := config.LoadFrom(nil)
+	require.NoError(t, err)
+	assert.True(t, cfg.LinkStateHeaders)
+
+	t.Setenv("LINK_STATE_HEADERS", "false")
+	cfg, err = config.LoadFrom(nil)
+	require.NoError(t, err)
+	assert.False(t, cfg.LinkStateHeaders)
+}
+
+// setupLinkStateTest serves redirects from an in-memory cache with the link state headers on
+func setupLinkStateTest(t *testing.T) (*URLServiceTestSuite, *cachetest.MemoryCache) {
+	suite := setupURLServiceTest(t)
+	suite.cfg.LinkStateHeaders = true
+	store := cachetest.NewMemoryCache()
+	suite.service = service.NewURLService(suite.repo, store, suite.cfg, suite.logger)
+	suite.repo.On("IncrementClickCount", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+	return suite, store
+}
+
+func TestRedirect_LinkStateHeadersFromCache(t *testing.T) {
+	suite, store := setupLinkStateTest(t)
+	expiresAt := time.Date(2031, 6, 1, 12, 0, 0, 0, time.UTC)
+	link := &domain.URL{ShortCode: "abc123", OriginalURL: "https://example.com", IsActive: true, ExpiresAt: &expiresAt}
+	require.NoError(t, store.Set(context.Background(), cache.LinkKey("abc123"), cache.NewLinkEntry(link).Encode(), time.Hour))
+
+	w := httptest.NewRecorder()
+	setupRedirectRouter(suite).ServeHTTP(w, httptest.NewRequest("GET", "/abc123", nil))
+
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+	assert.Equal(t, "2031-06-01T12:00:00Z", w.Header().Get("X-URL-Expires-At"))
+	assert.Equal(t, "true", w.Header().Get("X-URL-Active"))
+	suite.repo.AssertNotCalled(t, "FindByShortCode", mock.Anything, mock.Anything)
+}
+
+func TestRedirect_LinkStateHeadersFromDatabase(t *testing.T) {
+	suite, _ := setupLinkStateTest(t)
+	expiresAt := time.Date(2031, 6, 1, 12, 0, 0, 0, time.FixedZone("CEST", 2*3600))
+	suite.repo.On("FindByShortCode", mock.Anything, "abc123").
+		Return(&domain.URL{ShortCode: "abc123", OriginalURL: "https://example.com", IsActive: true, ExpiresAt: &expiresAt}, nil)
+	suite.repo.On("FindByShortCode", mock.Anything, "forever").
+		Return(&domain.URL{ShortCode: "forever", OriginalURL: "https://example.com", IsActive: true}, nil)
+	router := setupRedirectRouter(suite)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/abc123", nil))
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+	assert.Equal(t, "2031-06-01T10:00:00Z", w.Header().Get("X-URL-Expires-At"))
+	assert.Equal(t, "true", w.Header().Get("X-URL-Active"))
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/forever", nil))
+	assert.Empty(t, w.Header().Values("X-URL-Expires-At"), "links that never expire have no expiry header")
+	assert.Equal(t, "true", w.Header().Get("X-URL-Active"))
+}
+
+func TestGetURLInfo_LinkStateHeaders(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	suite.cfg.LinkStateHeaders = true
+	link := infoTestURL()
+	suite.repo.On("FindByShortCode", mock.Anything, "abc123").Return(link, nil)
+
+	w, _ := getInfoFields(t, setupURLInfoRouter(suite))
+
+	assert.Equal(t, link.ExpiresAt.UTC().Format(time.RFC3339), w.Header().Get("X-URL-Expires-At"))
+	assert.Equal(t, "true", w.Header().Get("X-URL-Active"))
+}
+
+func TestLinkStateHeaders_Disabled(t *testing.T) {
+	suite, store := setupLinkStateTest(t)
+	suite.cfg.LinkStateHeaders = false
+	expiresAt := time.Now().Add(time.Hour)
+	link := &domain.URL{ShortCode: "abc123", OriginalURL: "https://example.com", IsActive: true, ExpiresAt: &expiresAt}
+	require.NoError(t, store.Set(context.Background(), cache.LinkKey("abc123"), cache.NewLinkEntry(link).Encode(), time.Hour))
+
+	w := httptest.NewRecorder()
+	setupRedirectRouter(suite).ServeHTTP(w, httptest.NewRequest("GET", "/abc123", nil))
+
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+	assert.Empty(t, w.Header().Values("X-URL-Expires-At"))
+	assert.Empty(t, w.Header().Values("X-URL-Active"))
+}
@@ -0,0 +1,114 @@
+package unit
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/config"
+	"url-shortener/pkg/logger"
+)
+
+// logDir lists the names in dir, sorted
+func logDir(t *testing.T, dir string) []string {
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestRotatingFile_RotatesPrunesAndCompresses(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "logs", "app.log")
+	file, err := logger.OpenRotatingFile(logger.FileOptions{Path: path, MaxSizeMB: 1, MaxBackups: 2, Compress: true})
+	require.NoError(t, err)
+
+	chunk := bytes.Repeat([]byte("x"), 600*1024)
+	for i := 0; i < 4; i++ {
+		_, err := file.Write(chunk)
+		require.NoError(t, err)
+	}
+	require.NoError(t, file.Close())
+
+	names := logDir(t, filepath.Join(dir, "logs"))
+	require.Len(t, names, 3, "the live file and two backups out of three rotations: %v", names)
+	assert.Equal(t, "app.log", names[len(names)-1])
+	for _, name := range names[:2] {
+		assert.True(t, strings.HasPrefix(name, "app-") && strings.HasSuffix(name, ".log.gz"), name)
+	}
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(chunk)), info.Size(), "each write that didn't fit started a new file")
+}
+
+func TestRotatingFile_AppendsAndKeepsBackupsByDefault(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	require.NoError(t, os.WriteFile(path, bytes.Repeat([]byte("o"), 900*1024), 0644))
+
+	file, err := logger.OpenRotatingFile(logger.FileOptions{Path: path, MaxSizeMB: 1})
+	require.NoError(t, err)
+	_, err = file.Write(bytes.Repeat([]byte("n"), 200*1024))
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	names := logDir(t, filepath.Dir(path))
+	require.Len(t, names, 2, "the existing content counts towards the limit")
+	assert.True(t, strings.HasSuffix(names[0], ".log"), "not compressed unless asked to")
+
+	_, err = file.Write([]byte("late"))
+	assert.ErrorIs(t, err, os.ErrClosed)
+}
+
+func TestNewLogger_FileAndFormat(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	log := logger.NewLogger(logger.Options{Level: "warn", File: logger.FileOptions{Path: path}})
+	log.Info("dropped below the level")
+	log.Warnw("kept", "short_code", "abc123")
+	log.Sync()
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 1)
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &entry), "JSON unless console is asked for")
+	assert.Equal(t, "kept", entry["message"])
+	assert.Equal(t, "abc123", entry["short_code"])
+
+	path = filepath.Join(t.TempDir(), "console.log")
+	log = logger.NewLogger(logger.Options{Format: logger.FormatConsole, File: logger.FileOptions{Path: path}})
+	log.Infow("readable", "short_code", "abc123")
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "INFO\treadable\t{\"short_code\": \"abc123\"}")
+}
+
+func TestValidate_LogSettings(t *testing.T) {
+	cfg, err := config.LoadFrom(nil)
+	require.NoError(t, err)
+	assert.Equal(t, config.LogFormatJSON, cfg.LogFormat)
+	assert.Empty(t, cfg.LogFile, "stdout only unless a file is configured")
+
+	cfg.LogFormat = "text"
+	assert.ErrorContains(t, cfg.Validate(), "LOG_FORMAT")
+
+	cfg.LogFormat = config.LogFormatConsole
+	cfg.LogMaxSizeMB = 0
+	assert.ErrorContains(t, cfg.Validate(), "LOG_MAX_SIZE_MB")
+
+	cfg.LogMaxSizeMB = 10
+	cfg.LogMaxBackups = -1
+	assert.ErrorContains(t, cfg.Validate(), "LOG_MAX_BACKUPS")
+}
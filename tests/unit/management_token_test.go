@@ -0,0 +1,221 @@
+package unit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/apikey"
+	"url-shortener/internal/cache/cachetest"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/handler"
+	"url-shortener/internal/service"
+)
+
+const testManagementToken = "creator-token"
+
+func managementTokenHash(token string) *string {
+	sum := sha256.Sum256([]byte(token))
+	hash := hex.EncodeToString(sum[:])
+	return &hash
+}
+
+// setupManagementRouter wires PATCH, DELETE and extend as in main, on a service with an in-memory cache
+func setupManagementRouter(t *testing.T) (*URLServiceTestSuite, *gin.Engine) {
+	gin.SetMode(gin.TestMode)
+	suite := setupURLServiceTest(t)
+	suite.cfg.AdminAPIKey = "admin-secret"
+	suite.service = service.NewURLService(suite.repo, cachetest.NewMemoryCache(), suite.cfg, suite.logger)
+	h := handler.NewURLHandler(suite.service, suite.cfg, suite.logger)
+	keys := apikey.NewStore(new(MockAPIKeyRepository), 0, suite.logger)
+
+	router := gin.New()
+	router.PATCH("/api/v1/urls/:shortCode", handler.ManagementAuthMiddleware(suite.cfg, keys), h.UpdateURL)
+	router.DELETE("/api/v1/urls/:shortCode", handler.ManagementAuthMiddleware(suite.cfg, keys), h.DeleteURL)
+	router.POST("/api/v1/urls/:shortCode/extend", handler.ManagementAuthMiddleware(suite.cfg, keys), h.ExtendURL)
+	return suite, router
+}
+
+func deleteRequest(code string, headers map[string]string) *http.Request {
+	req := httptest.NewRequest("DELETE", "/api/v1/urls/"+code, nil)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	return req
+}
+
+func TestShortenURL_ReturnsManagementTokenStoredHashed(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	ctx := context.Background()
+
+	var stored *domain.URL
+	suite.repo.On("FindByOriginalURL", ctx, "https://example.com").Return((*domain.URL)(nil), domain.ErrURLNotFound)
+	suite.repo.On("Create", ctx, mock.AnythingOfType("*domain.URL")).Run(func(args mock.Arguments) {
+		stored = args.Get(1).(*domain.URL)
+	}).Return(nil)
+	suite.cache.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	resp, err := suite.service.ShortenURL(ctx, &domain.CreateURLRequest{URL: "https://example.com"}, domain.CreatorContext{IP: "192.168.1.1"})
+	require.NoError(t, err)
+
+	assert.Len(t, resp.ManagementToken, 43, "32 random bytes, base64url without padding")
+	require.NotNil(t, stored.ManagementTokenHash)
+	assert.Equal(t, *managementTokenHash(resp.ManagementToken), *stored.ManagementTokenHash)
+}
+
+func TestShortenURL_DuplicateGetsNoManagementToken(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	ctx := context.Background()
+	suite.repo.On("FindByOriginalURL", ctx, "https://example.com").
+		Return(&domain.URL{ShortCode: "exist1", OriginalURL: "https://example.com", IsActive: true, ManagementTokenHash: managementTokenHash("first")}, nil)
+
+	resp, err := suite.service.ShortenURL(ctx, &domain.CreateURLRequest{URL: "https://example.com"}, domain.CreatorContext{IP: "192.168.1.1"})
+
+	require.NoError(t, err)
+	assert.Empty(t, resp.ManagementToken, "someone else's link must not hand out its token")
+}
+
+func TestDeleteURL_ManagementToken(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+		status  int
+	}{
+		{"matching token", map[string]string{"X-Management-Token": testManagementToken}, http.StatusOK},
+		{"admin key", map[string]string{"X-API-Key": "admin-secret"}, http.StatusOK},
+		{"wrong token", map[string]string{"X-Management-Token": "guess"}, http.StatusForbidden},
+		{"no token", nil, http.StatusUnauthorized},
+		{"wrong admin key", map[string]string{"X-API-Key": "admin-guess"}, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			suite, router := setupManagementRouter(t)
+			suite.repo.On("FindAnyByShortCode", mock.Anything, "abc123").
+				Return(&domain.URL{ShortCode: "abc123", OriginalURL: "https://example.com", IsActive: true, ManagementTokenHash: managementTokenHash(testManagementToken)}, nil)
+			suite.repo.On("Delete", mock.Anything, "abc123").Return(nil)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, deleteRequest("abc123", tt.headers))
+
+			assert.Equal(t, tt.status, w.Code, w.Body.String())
+			if tt.status != http.StatusOK {
+				suite.repo.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestDeleteURL_LinkWithoutTokenFollowsConfig(t *testing.T) {
+	for _, required := range []bool{false, true} {
+		suite, router := setupManagementRouter(t)
+		suite.cfg.RequireManagementToken = required
+		suite.repo.On("FindAnyByShortCode", mock.Anything, "old123").
+			Return(&domain.URL{ShortCode: "old123", OriginalURL: "https://example.com", IsActive: true}, nil)
+		suite.repo.On("Delete", mock.Anything, "old123").Return(nil)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, deleteRequest("old123", map[string]string{"X-Management-Token": "anything"}))
+
+		if required {
+			assert.Equal(t, http.StatusForbidden, w.Code)
+		} else {
+			assert.Equal(t, http.StatusOK, w.Code)
+		}
+
+		// Admins can always clean up
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, deleteRequest("old123", map[string]string{"X-API-Key": "admin-secret"}))
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+}
+
+func TestDeleteURL_LinkWithoutTokenNeedsAPIKeyWhenAuthEnabled(t *testing.T) {
+	suite, router := setupManagementRouter(t)
+	suite.cfg.EnableAuthentication = true
+	suite.cfg.APIKey = "api-secret"
+	suite.repo.On("FindAnyByShortCode", mock.Anything, "legacy").
+		Return(&domain.URL{ShortCode: "legacy", OriginalURL: "https://example.com", IsActive: true}, nil)
+	suite.repo.On("Delete", mock.Anything, "legacy").Return(nil)
+
+	send := func(headers map[string]string) int {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, deleteRequest("legacy", headers))
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, send(nil))
+	assert.Equal(t, http.StatusUnauthorized, send(map[string]string{"X-Management-Token": "x"}), "a token header can't stand in for the API key")
+	suite.repo.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+
+	assert.Equal(t, http.StatusOK, send(map[string]string{"X-API-Key": "api-secret"}))
+}
+
+func TestUpdateURL_ManagementTokenBypassesAPIKey(t *testing.T) {
+	suite, router := setupManagementRouter(t)
+	suite.cfg.EnableAuthentication = true
+	suite.cfg.APIKey = "api-secret"
+	link := &domain.URL{ShortCode: "abc123", OriginalURL: "https://example.com", IsActive: true, ManagementTokenHash: managementTokenHash(testManagementToken)}
+	suite.repo.On("FindAnyByShortCode", mock.Anything, "abc123").Return(link, nil)
+	suite.repo.On("FindByShortCode", mock.Anything, "abc123").Return(link, nil)
+	suite.repo.On("Update", mock.Anything, mock.AnythingOfType("*domain.URL")).Return(nil)
+
+	patch := func(headers map[string]string) int {
+		req := httptest.NewRequest("PATCH", "/api/v1/urls/abc123", strings.NewReader(`{"requires_interstitial":true}`))
+		req.Header.Set("Content-Type", "application/json")
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, patch(map[string]string{"X-Management-Token": testManagementToken}))
+	assert.Equal(t, http.StatusForbidden, patch(map[string]string{"X-Management-Token": "guess"}))
+	assert.Equal(t, http.StatusUnauthorized, patch(nil), "no credentials at all still fails the API key check")
+	assert.Equal(t, http.StatusUnauthorized, patch(map[string]string{"X-API-Key": "api-secret"}), "an API key alone doesn't own the link")
+}
+
+func TestUpdateURL_TokenHeaderDoesNotBypassAuthOnLinkWithoutToken(t *testing.T) {
+	suite, router := setupManagementRouter(t)
+	suite.cfg.EnableAuthentication = true
+	suite.cfg.APIKey = "api-secret"
+	link := &domain.URL{ShortCode: "legacy", OriginalURL: "https://example.com", IsActive: true}
+	suite.repo.On("FindAnyByShortCode", mock.Anything, "legacy").Return(link, nil)
+	suite.repo.On("FindByShortCode", mock.Anything, "legacy").Return(link, nil)
+	suite.repo.On("Update", mock.Anything, mock.AnythingOfType("*domain.URL")).Return(nil)
+
+	send := func(method, path string, headers map[string]string) int {
+		req := httptest.NewRequest(method, path, strings.NewReader(`{"requires_interstitial":true}`))
+		req.Header.Set("Content-Type", "application/json")
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// There is no token to verify a bogus one against, so the API key check still applies
+	for _, path := range []string{"/api/v1/urls/legacy", "/api/v1/urls/legacy/extend"} {
+		method := "PATCH"
+		if strings.HasSuffix(path, "/extend") {
+			method = "POST"
+		}
+		assert.Equal(t, http.StatusUnauthorized, send(method, path, map[string]string{"X-Management-Token": "x"}), path)
+	}
+	suite.repo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+
+	assert.Equal(t, http.StatusOK, send("PATCH", "/api/v1/urls/legacy", map[string]string{"X-Management-Token": "x", "X-API-Key": "api-secret"}))
+	assert.Equal(t, http.StatusOK, send("PATCH", "/api/v1/urls/legacy", map[string]string{"X-API-Key": "api-secret"}))
+}
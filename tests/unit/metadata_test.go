@@ -0,0 +1,117 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/metadata"
+	"url-shortener/internal/service"
+)
+
+// stubFetcher returns canned metadata without network access
+type stubFetcher struct {
+	meta *metadata.Metadata
+	err  error
+}
+
+func (f stubFetcher) Fetch(ctx context.Context, rawURL string) (*metadata.Metadata, error) {
+	return f.meta, f.err
+}
+
+func strPtr(s string) *string { return &s }
+
+func TestParseMetadata_TitleAndIcon(t *testing.T) {
+	base, _ := url.Parse("https://example.com/blog/post")
+	page := `<html><head>
+		<title>  Hello &amp;
+		World </title>
+		<link rel="shortcut icon" href="/static/icon.png">
+	</head><body><title>ignored</title></body></html>`
+
+	meta := metadata.Parse(strings.NewReader(page), base)
+
+	assert.Equal(t, "Hello & World", meta.Title)
+	assert.Equal(t, "https://example.com/static/icon.png", meta.FaviconURL)
+}
+
+func TestParseMetadata_DefaultFavicon(t *testing.T) {
+	base, _ := url.Parse("https://example.com/page")
+
+	meta := metadata.Parse(strings.NewReader(`<html><head><link rel="icon" href="javascript:alert(1)"></head></html>`), base)
+
+	assert.Empty(t, meta.Title)
+	assert.Equal(t, "https://example.com/favicon.ico", meta.FaviconURL)
+}
+
+func TestHTTPFetcher_BlocksLoopback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("fetcher must not reach a loopback address")
+	}))
+	defer server.Close()
+
+	_, err := metadata.NewHTTPFetcher(time.Second).Fetch(context.Background(), server.URL)
+
+	assert.ErrorIs(t, err, metadata.ErrBlockedAddress)
+}
+
+func TestShortenURL_EnrichesMetadataInBackground(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	svc := service.NewURLService(suite.repo, suite.cache, suite.cfg, suite.logger,
+		service.WithMetadataFetcher(stubFetcher{meta: &metadata.Metadata{Title: "Example", FaviconURL: "https://example.com/favicon.ico"}}))
+	ctx := context.Background()
+
+	suite.repo.On("FindByOriginalURL", ctx, "https://example.com/meta").
+		Return((*domain.URL)(nil), domain.ErrURLNotFound)
+	suite.repo.On("Create", ctx, mock.AnythingOfType("*domain.URL")).Return(nil)
+	suite.cache.On("Set", mock.Anything, "meta", cachedDestination("https://example.com/meta"), mock.Anything).Return(nil)
+	suite.repo.On("UpdateMetadata", mock.Anything, "meta", strPtr("Example"), strPtr("https://example.com/favicon.ico")).
+		Return(nil).Once()
+
+	_, err := svc.ShortenURL(ctx, &domain.CreateURLRequest{URL: "https://example.com/meta", CustomAlias: "meta"}, domain.CreatorContext{IP: "192.168.1.1"})
+	require.NoError(t, err)
+
+	// Close waits for the background fetch
+	require.NoError(t, svc.Close(ctx))
+	suite.repo.AssertExpectations(t)
+}
+
+func TestRefreshMetadata_FailureStoresNull(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	svc := service.NewURLService(suite.repo, suite.cache, suite.cfg, suite.logger,
+		service.WithMetadataFetcher(stubFetcher{err: errors.New("connection refused")}))
+	ctx := context.Background()
+
+	suite.repo.On("FindByShortCode", ctx, "abc123").
+		Return(&domain.URL{ShortCode: "abc123", OriginalURL: "https://example.com/down", IsActive: true, PageTitle: strPtr("Old")}, nil)
+	suite.repo.On("UpdateMetadata", ctx, "abc123", (*string)(nil), (*string)(nil)).Return(nil).Once()
+
+	url, err := svc.RefreshMetadata(ctx, "abc123")
+
+	require.NoError(t, err)
+	assert.Nil(t, url.PageTitle)
+	assert.Nil(t, url.FaviconURL)
+	assert.NotNil(t, url.MetadataFetchedAt)
+	suite.repo.AssertExpectations(t)
+}
+
+func TestRefreshMetadata_DisabledReturnsConflict(t *testing.T) {
+	suite := setupURLServiceTest(t)
+
+	_, err := suite.service.RefreshMetadata(context.Background(), "abc123")
+
+	var appErr *domain.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, http.StatusConflict, appErr.StatusCode)
+	suite.repo.AssertNotCalled(t, "FindByShortCode", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,61 @@
+package unit
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/config"
+	"url-shortener/internal/migrations"
+)
+
+func TestEmbeddedMigrations_AreNumberedInOrder(t *testing.T) {
+	all, err := migrations.Embedded()
+	require.NoError(t, err)
+	require.NotEmpty(t, all)
+
+	for i, migration := range all {
+		assert.Equal(t, int64(i+1), migration.Version, "versions have no gaps")
+	}
+	assert.Equal(t, "initial_schema", all[0].Name)
+	assert.Equal(t, "add_url_hash", all[1].Name)
+}
+
+func TestParseMigrations(t *testing.T) {
+	sql := &fstest.MapFile{Data: []byte("SELECT 1;")}
+
+	all, err := migrations.Parse(fstest.MapFS{
+		"0002_second.up.sql":   sql,
+		"0002_second.down.sql": sql,
+		"0001_first.up.sql":    sql,
+		"0001_first.down.sql":  sql,
+		"README.md":            sql,
+	})
+	require.NoError(t, err)
+	require.Len(t, all, 2)
+	assert.Equal(t, int64(1), all[0].Version)
+	assert.Equal(t, "second", all[1].Name)
+
+	for name, fsys := range map[string]fstest.MapFS{
+		"missing down":      {"0001_first.up.sql": sql},
+		"duplicate version": {"0001_first.up.sql": sql, "0001_first.down.sql": sql, "0001_other.up.sql": sql, "0001_other.down.sql": sql},
+		"bad name":          {"1_first.up.sql": sql, "1_first.down.sql": sql},
+		"version zero":      {"0000_first.up.sql": sql, "0000_first.down.sql": sql},
+	} {
+		_, err := migrations.Parse(fsys)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestLoadFrom_AutoMigrate(t *testing.T) {
+	cfg, err := config.LoadFrom(nil)
+	require.NoError(t, err)
+	assert.True(t, cfg.AutoMigrate, "on by default so a fresh database works")
+
+	t.Setenv("AUTO_MIGRATE", "false")
+	cfg, err = config.LoadFrom(nil)
+	require.NoError(t, err)
+	assert.False(t, cfg.AutoMigrate)
+}
@@ -0,0 +1,144 @@
+package unit
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/domain"
+	"url-shortener/pkg/validator"
+)
+
+func TestNormalizeURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"adds scheme", "example.com/a", "https://example.com/a"},
+		{"lowercases scheme and host", "HTTPS://Example.COM/Path", "https://example.com/Path"},
+		{"trims trailing slash", "https://example.com/a/", "https://example.com/a"},
+		{"drops default https port", "https://example.com:443/a", "https://example.com/a"},
+		{"drops default http port", "http://example.com:80/a", "http://example.com/a"},
+		{"keeps other ports", "https://example.com:8443/a", "https://example.com:8443/a"},
+		{"keeps 443 on http", "http://example.com:443/a", "http://example.com:443/a"},
+		{"decodes unreserved escapes", "https://example.com/%7Euser/%41bc", "https://example.com/~user/Abc"},
+		{"uppercases reserved escapes", "https://example.com/a%2fb?q=%3d", "https://example.com/a%2Fb?q=%3D"},
+		{"decodes unreserved in query", "https://example.com/?q=%61%2D1", "https://example.com?q=a-1"},
+		{"keeps invalid escapes", "https://example.com/?q=%zz", "https://example.com?q=%zz"},
+		{"converts IDN host to punycode", "https://пример.рф/путь", "https://xn--e1afmkfd.xn--p1ai/%D0%BF%D1%83%D1%82%D1%8C"},
+		{"punycode host unchanged", "https://xn--e1afmkfd.xn--p1ai/a", "https://xn--e1afmkfd.xn--p1ai/a"},
+		{"IPv6 with default port", "http://[2001:DB8::1]:80/a", "http://[2001:db8::1]/a"},
+		{"sorts parameters", "https://example.com/?b=2&a=1&c=3", "https://example.com?a=1&b=2&c=3"},
+		{"keeps order of repeated parameters", "https://example.com/?tag=2&a=1&tag=1", "https://example.com?a=1&tag=2&tag=1"},
+		{"keeps fragment", "https://example.com/a#Top", "https://example.com/a#Top"},
+		{"keeps tracking parameters by default", "https://example.com/?utm_source=x", "https://example.com?utm_source=x"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, validator.NormalizeURL(tt.input))
+		})
+	}
+}
+
+func TestNormalizeURL_EquivalentSpellingsMatch(t *testing.T) {
+	spellings := []string{
+		"https://example.com:443/a",
+		"HTTPS://EXAMPLE.com/a/",
+		"https://example.com/%61",
+	}
+
+	for _, spelling := range spellings {
+		assert.Equal(t, "https://example.com/a", validator.NormalizeURL(spelling), spelling)
+	}
+	assert.Equal(t, validator.NormalizeURL("https://xn--e1afmkfd.xn--p1ai"), validator.NormalizeURL("https://ПРИМЕР.рф"))
+}
+
+func TestNormalizeURLWith_StripParams(t *testing.T) {
+	opts := validator.NormalizeOptions{StripParams: []string{"utm_*", "fbclid", "gclid"}}
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"strips prefix match", "https://example.com/a?utm_source=x&utm_medium=y&id=1", "https://example.com/a?id=1"},
+		{"strips exact names", "https://example.com/a?fbclid=abc&gclid=def", "https://example.com/a"},
+		{"matches case-insensitively", "https://example.com/a?FBCLID=abc&id=2", "https://example.com/a?id=2"},
+		{"matches escaped names", "https://example.com/a?utm%5Fsource=x&id=3", "https://example.com/a?id=3"},
+		{"keeps similar names", "https://example.com/a?fbclid2=1&my_utm=2", "https://example.com/a?fbclid2=1&my_utm=2"},
+		{"keeps repeated params", "https://example.com/a?tag=1&utm_id=9&tag=2", "https://example.com/a?tag=1&tag=2"},
+		{"sorts what is left", "https://example.com/a?z=1&utm_source=x&b=2", "https://example.com/a?b=2&z=1"},
+		{"keeps fragment", "https://example.com/a?gclid=1#frag", "https://example.com/a#frag"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, validator.NormalizeURLWith(tt.input, opts))
+		})
+	}
+}
+
+func TestNormalizeURLWith_Legacy(t *testing.T) {
+	opts := validator.NormalizeOptions{Legacy: true, StripParams: []string{"utm_*"}}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"https://Example.com:443/a/", "https://example.com:443/a"},
+		{"https://example.com/%7Euser", "https://example.com/%7Euser"},
+		{"https://example.com/a?utm_source=x", "https://example.com/a?utm_source=x"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, validator.NormalizeURLWith(tt.input, opts), tt.input)
+	}
+}
+
+func TestDisplayURL(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"https://xn--e1afmkfd.xn--p1ai/a", "https://пример.рф/a"},
+		{"https://xn--e1afmkfd.xn--p1ai:8443/a", "https://пример.рф:8443/a"},
+		{"https://example.com/a", "https://example.com/a"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, validator.DisplayURL(tt.input), tt.input)
+	}
+}
+
+func TestShortenURL_DedupesEquivalentSpellings(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	suite.cfg.StripTrackingParams = []string{"utm_*"}
+	ctx := context.Background()
+
+	existing := &domain.URL{ShortCode: "abc123", OriginalURL: "https://example.com/a", IsActive: true}
+	suite.repo.On("FindByOriginalURL", ctx, "https://example.com/a").Return(existing, nil)
+
+	resp, err := suite.service.ShortenURL(ctx, &domain.CreateURLRequest{URL: "https://EXAMPLE.com:443/a/?utm_source=news"}, domain.CreatorContext{IP: "192.168.1.1"})
+
+	require.NoError(t, err)
+	assert.Equal(t, "abc123", resp.ShortCode)
+	suite.repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestGetURLInfo_DisplayURL(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	ctx := context.Background()
+	suite.repo.On("FindByShortCode", ctx, "abc123").
+		Return(&domain.URL{ShortCode: "abc123", OriginalURL: "https://xn--e1afmkfd.xn--p1ai/a", IsActive: true}, nil)
+
+	info, err := suite.service.GetURLInfo(ctx, "abc123")
+
+	require.NoError(t, err)
+	assert.Equal(t, "https://xn--e1afmkfd.xn--p1ai/a", info.OriginalURL)
+	assert.Equal(t, "https://пример.рф/a", info.DisplayURL)
+}
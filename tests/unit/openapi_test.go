@@ -0,0 +1,134 @@
+package unit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/handler"
+)
+
+// emittedError matches the error values written by the handlers
+var emittedError = regexp.MustCompile(`(?:Error:|"error":)\s+"([^"]+)"`)
+
+func TestErrorCatalog_CoversHandlerErrors(t *testing.T) {
+	files, err := filepath.Glob("../../internal/handler/*.go")
+	require.NoError(t, err)
+
+	emitted := map[string]bool{}
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") || strings.HasSuffix(file, "error_catalog.go") {
+			continue
+		}
+		source, err := os.ReadFile(file)
+		require.NoError(t, err)
+		for _, match := range emittedError.FindAllStringSubmatch(string(source), -1) {
+			emitted[match[1]] = true
+		}
+	}
+	require.NotEmpty(t, emitted)
+
+	catalog := map[string]bool{}
+	for _, code := range handler.ErrorCatalog {
+		assert.False(t, catalog[code.Error], "%s is listed twice", code.Error)
+		assert.NotEmpty(t, code.Status, code.Error)
+		catalog[code.Error] = true
+	}
+	for code := range emitted {
+		assert.True(t, catalog[code], "%s is sent but missing from handler.ErrorCatalog", code)
+	}
+	for code := range catalog {
+		assert.True(t, emitted[code], "%s is in handler.ErrorCatalog but never sent", code)
+	}
+}
+
+func setupDocsRouter(t *testing.T) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	suite := setupURLServiceTest(t)
+	docs := handler.NewDocsHandler(suite.cfg, suite.logger)
+
+	router := gin.New()
+	router.GET("/api/v1/openapi.json", docs.OpenAPISpec)
+	router.GET("/api/v1/errors", docs.ErrorCatalog)
+	router.GET("/api/v1/docs", docs.SwaggerUI)
+	return router
+}
+
+func TestOpenAPISpec_ErrorEnumAndServer(t *testing.T) {
+	router := setupDocsRouter(t)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/openapi.json", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var spec struct {
+		OpenAPI string `json:"openapi"`
+		Servers []struct {
+			URL string `json:"url"`
+		} `json:"servers"`
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]struct {
+					Enum []string `json:"enum"`
+				} `json:"properties"`
+			} `json:"schemas"`
+		} `json:"components"`
+		Catalog []handler.ErrorCode `json:"x-error-catalog"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &spec))
+
+	assert.True(t, strings.HasPrefix(spec.OpenAPI, "3."))
+	require.Len(t, spec.Servers, 1)
+	assert.Equal(t, "https://short.url", spec.Servers[0].URL)
+	for _, name := range []string{"CreateURLRequest", "CreateURLResponse", "URLStats", "ErrorResponse"} {
+		assert.Contains(t, spec.Components.Schemas, name)
+	}
+	enum := spec.Components.Schemas["ErrorResponse"].Properties["error"].Enum
+	require.Len(t, enum, len(handler.ErrorCatalog))
+	assert.Contains(t, enum, "short_code_taken")
+	assert.Equal(t, handler.ErrorCatalog, spec.Catalog)
+}
+
+func TestErrorCatalogEndpoint(t *testing.T) {
+	router := setupDocsRouter(t)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/errors", nil))
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var body struct {
+		Errors []handler.ErrorCode `json:"errors"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, handler.ErrorCatalog, body.Errors)
+}
+
+func TestSwaggerUI_ScriptAllowedByNonceOnly(t *testing.T) {
+	router := setupDocsRouter(t)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/docs", nil))
+
+	require.Equal(t, http.StatusOK, w.Code)
+	csp := w.Header().Get("Content-Security-Policy")
+	nonce := regexp.MustCompile(`'nonce-([^']+)'`).FindStringSubmatch(csp)
+	require.Len(t, nonce, 2, csp)
+	assert.Contains(t, w.Body.String(), `<script nonce="`+nonce[1]+`">`)
+	scriptSrc := regexp.MustCompile(`script-src [^;]*`).FindString(csp)
+	assert.NotContains(t, scriptSrc, "unsafe-inline", "inline scripts only run with the nonce")
+	assert.Contains(t, w.Body.String(), `url: "openapi.json"`)
+
+	// Every request gets a fresh nonce
+	again := httptest.NewRecorder()
+	router.ServeHTTP(again, httptest.NewRequest("GET", "/api/v1/docs", nil))
+	assert.NotEqual(t, csp, again.Header().Get("Content-Security-Policy"))
+}
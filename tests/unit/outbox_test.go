@@ -0,0 +1,414 @@
+package unit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/outbox"
+	"url-shortener/internal/service"
+)
+
+// MockOutboxRepository is a mock implementation of the OutboxRepository interface
+type MockOutboxRepository struct {
+	mock.Mock
+}
+
+func (m *MockOutboxRepository) Append(ctx context.Context, events ...*domain.OutboxEvent) error {
+	args := m.Called(ctx, events)
+	return args.Error(0)
+}
+
+func (m *MockOutboxRepository) PublishPending(ctx context.Context, limit int, publish func([]domain.OutboxEvent) error) (int, error) {
+	args := m.Called(ctx, limit)
+	batch := args.Get(0).([]domain.OutboxEvent)
+	if len(batch) == 0 {
+		return 0, args.Error(1)
+	}
+	if err := publish(batch); err != nil {
+		return 0, err
+	}
+	return len(batch), args.Error(1)
+}
+
+func (m *MockOutboxRepository) Lag(ctx context.Context) (int64, *time.Time, error) {
+	return 0, nil, nil
+}
+
+func (m *MockOutboxRepository) DeletePublished(ctx context.Context, before time.Time) (int64, error) {
+	args := m.Called(ctx, before)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+// fakeTransactor runs fn directly and remembers how many transactions were opened
+type fakeTransactor struct {
+	calls int
+}
+
+func (f *fakeTransactor) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	f.calls++
+	return fn(ctx)
+}
+
+// fakePublisher records published batches, failing while err is set
+type fakePublisher struct {
+	batches [][]domain.OutboxEvent
+	err     error
+}
+
+func (f *fakePublisher) Publish(ctx context.Context, events []domain.OutboxEvent) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.batches = append(f.batches, events)
+	return nil
+}
+
+func (f *fakePublisher) Close() error { return nil }
+
+// setupOutboxService rebuilds the suite's service with the outbox enabled
+func setupOutboxService(suite *URLServiceTestSuite) (*MockOutboxRepository, *fakeTransactor) {
+	repo := new(MockOutboxRepository)
+	tx := &fakeTransactor{}
+	suite.service = service.NewURLService(suite.repo, suite.cache, suite.cfg, suite.logger, service.WithOutbox(repo, tx))
+	return repo, tx
+}
+
+// eventData decodes the payload of an outbox event
+func eventData(t *testing.T, event *domain.OutboxEvent) domain.LinkEventData {
+	var data domain.LinkEventData
+	require.NoError(t, json.Unmarshal([]byte(event.Payload), &data))
+	return data
+}
+
+func TestOutbox_CreateWritesEventInSameTransaction(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	outboxRepo, tx := setupOutboxService(suite)
+	ctx := context.Background()
+
+	suite.repo.On("FindByOriginalURL", ctx, "https://example.com/events").Return((*domain.URL)(nil), domain.ErrURLNotFound)
+	suite.repo.On("Create", mock.Anything, mock.Anything).Return(nil)
+	suite.cache.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	var appended []*domain.OutboxEvent
+	outboxRepo.On("Append", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		appended = args.Get(1).([]*domain.OutboxEvent)
+	}).Return(nil)
+
+	resp, err := suite.service.ShortenURL(ctx, &domain.CreateURLRequest{URL: "https://example.com/events"}, domain.CreatorContext{IP: "192.168.1.1"})
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, tx.calls)
+	require.Len(t, appended, 1)
+	assert.Equal(t, domain.EventLinkCreated, appended[0].Type)
+	assert.Equal(t, resp.ShortCode, appended[0].ShortCode)
+	assert.Equal(t, "https://example.com/events", eventData(t, appended[0]).OriginalURL)
+}
+
+func TestOutbox_FailedDeleteWritesNoEvent(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	outboxRepo, _ := setupOutboxService(suite)
+	ctx := context.Background()
+
+	suite.repo.On("Delete", mock.Anything, "gone").Return(domain.ErrURLNotFound)
+
+	err := suite.service.DeleteURL(ctx, "gone")
+
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+	outboxRepo.AssertNotCalled(t, "Append", mock.Anything, mock.Anything)
+}
+
+func TestOutbox_DeleteWritesEvent(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	outboxRepo, _ := setupOutboxService(suite)
+	ctx := context.Background()
+
+	suite.repo.On("Delete", mock.Anything, "abc123").Return(nil)
+	suite.cache.On("Delete", ctx, "abc123").Return(nil)
+	outboxRepo.On("Append", mock.Anything, mock.MatchedBy(func(events []*domain.OutboxEvent) bool {
+		return len(events) == 1 && events[0].Type == domain.EventLinkDeleted && events[0].ShortCode == "abc123"
+	})).Return(nil).Once()
+
+	require.NoError(t, suite.service.DeleteURL(ctx, "abc123"))
+	outboxRepo.AssertExpectations(t)
+}
+
+func TestOutbox_ClickSampling(t *testing.T) {
+	tests := []struct {
+		name     string
+		sampling int
+		events   int
+	}{
+		{"every click", 1, 1},
+		{"click events disabled", 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			suite := setupURLServiceTest(t)
+			suite.cfg.EventsClickSampling = tt.sampling
+			outboxRepo, _ := setupOutboxService(suite)
+			ctx := context.Background()
+
+			suite.cache.On("Get", ctx, "abc123").Return("https://example.com/cached", nil)
+			suite.repo.On("IncrementClickCount", mock.Anything, "abc123", mock.Anything).Return(nil)
+			outboxRepo.On("Append", mock.Anything, mock.Anything).Return(nil)
+
+			_, err := suite.service.GetOriginalURL(ctx, "abc123", domain.Visitor{Country: "DE"})
+			require.NoError(t, err)
+			require.NoError(t, suite.service.Close(ctx))
+
+			outboxRepo.AssertNumberOfCalls(t, "Append", tt.events)
+			if tt.events > 0 {
+				events := outboxRepo.Calls[0].Arguments.Get(1).([]*domain.OutboxEvent)
+				data := eventData(t, events[0])
+				assert.Equal(t, domain.EventLinkClicked, events[0].Type)
+				assert.Equal(t, 1, data.Weight)
+				assert.Equal(t, "DE", data.Country)
+			}
+		})
+	}
+}
+
+func TestOutbox_SampledClicksCarryWeight(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	suite.cfg.EventsClickSampling = 4
+	outboxRepo, _ := setupOutboxService(suite)
+	ctx := context.Background()
+
+	suite.cache.On("Get", ctx, "abc123").Return("https://example.com/cached", nil)
+	suite.repo.On("IncrementClickCount", mock.Anything, "abc123", mock.Anything).Return(nil)
+	outboxRepo.On("Append", mock.Anything, mock.Anything).Return(nil)
+
+	const clicks = 400
+	for i := 0; i < clicks; i++ {
+		_, err := suite.service.GetOriginalURL(ctx, "abc123", domain.Visitor{})
+		require.NoError(t, err)
+	}
+	require.NoError(t, suite.service.Close(ctx))
+
+	suite.repo.AssertNumberOfCalls(t, "IncrementClickCount", clicks)
+	sampled := len(outboxRepo.Calls)
+	assert.Greater(t, sampled, 0)
+	assert.Less(t, sampled, clicks/2)
+	events := outboxRepo.Calls[0].Arguments.Get(1).([]*domain.OutboxEvent)
+	assert.Equal(t, 4, eventData(t, events[0]).Weight)
+}
+
+func TestRelay_DrainsPendingBatches(t *testing.T) {
+	repo := new(MockOutboxRepository)
+	full := []domain.OutboxEvent{{ID: 1}, {ID: 2}}
+	repo.On("PublishPending", mock.Anything, 2).Return(full, nil).Once()
+	repo.On("PublishPending", mock.Anything, 2).Return([]domain.OutboxEvent{{ID: 3}}, nil).Once()
+	publisher := &fakePublisher{}
+	relay := outbox.NewRelay(repo, publisher, 2, setupURLServiceTest(t).logger)
+
+	require.NoError(t, relay.Run(context.Background()))
+
+	assert.Len(t, publisher.batches, 2)
+	repo.AssertNumberOfCalls(t, "PublishPending", 2)
+}
+
+func TestRelay_PublishFailureLeavesBatchPending(t *testing.T) {
+	repo := new(MockOutboxRepository)
+	repo.On("PublishPending", mock.Anything, 10).Return([]domain.OutboxEvent{{ID: 1}}, nil)
+	publisher := &fakePublisher{err: assert.AnError}
+	relay := outbox.NewRelay(repo, publisher, 10, setupURLServiceTest(t).logger)
+
+	err := relay.Run(context.Background())
+
+	assert.ErrorIs(t, err, assert.AnError)
+	repo.AssertNumberOfCalls(t, "PublishPending", 1)
+}
+
+func TestKafkaRESTPublisher_ProducesKeyedRecords(t *testing.T) {
+	var body map[string][]map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/topics/link-events", r.URL.Path)
+		assert.Equal(t, "application/vnd.kafka.json.v2+json", r.Header.Get("Content-Type"))
+		raw, _ := io.ReadAll(r.Body)
+		require.NoError(t, json.Unmarshal(raw, &body))
+		w.Write([]byte(`{"offsets":[{"partition":0,"offset":41}]}`))
+	}))
+	defer server.Close()
+
+	publisher := outbox.NewKafkaRESTPublisher(server.URL, "link-events", time.Second)
+	err := publisher.Publish(context.Background(), []domain.OutboxEvent{
+		{ID: 9, Type: domain.EventLinkCreated, ShortCode: "abc123", Payload: `{"original_url":"https://example.com"}`},
+	})
+
+	require.NoError(t, err)
+	require.Len(t, body["records"], 1)
+	assert.Equal(t, "abc123", body["records"][0]["key"])
+	value := body["records"][0]["value"].(map[string]interface{})
+	assert.Equal(t, float64(9), value["id"])
+	assert.Equal(t, "https://example.com", value["data"].(map[string]interface{})["original_url"])
+}
+
+func TestKafkaRESTPublisher_RejectedRecordFailsBatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"offsets":[{"error_code":50001,"error":"broker unavailable"}]}`))
+	}))
+	defer server.Close()
+
+	publisher := outbox.NewKafkaRESTPublisher(server.URL, "link-events", time.Second)
+	err := publisher.Publish(context.Background(), []domain.OutboxEvent{{ID: 1, Payload: `{}`}})
+
+	assert.ErrorContains(t, err, "broker unavailable")
+}
+
+// natsMessage is an HPUB the fake JetStream server received
+type natsMessage struct {
+	Subject string
+	MsgID   string
+	Payload string
+}
+
+// fakeJetStreamServer accepts one connection and answers the nth HPUB with reply(n), written as is
+// A reply of "" sends nothing back. Every message received is passed on.
+func fakeJetStreamServer(t *testing.T, reply func(n int, inbox string) string) (string, <-chan natsMessage) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { lis.Close() })
+
+	received := make(chan natsMessage, 16)
+	go func() {
+		conn, err := lis.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		fmt.Fprint(conn, "INFO {\"server_id\":\"test\",\"headers\":true}\r\n")
+		reader := bufio.NewReader(conn)
+		for n := 0; ; {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			fields := strings.Fields(line)
+			if len(fields) != 5 || fields[0] != "HPUB" {
+				continue
+			}
+
+			headerSize, _ := strconv.Atoi(fields[3])
+			total, _ := strconv.Atoi(fields[4])
+			body := make([]byte, total+2)
+			if _, err := io.ReadFull(reader, body); err != nil {
+				return
+			}
+			message := natsMessage{Subject: fields[1], Payload: string(body[headerSize:total])}
+			for _, header := range strings.Split(string(body[:headerSize]), "\r\n") {
+				if value, ok := strings.CutPrefix(header, "Nats-Msg-Id: "); ok {
+					message.MsgID = value
+				}
+			}
+			received <- message
+
+			fmt.Fprint(conn, reply(n, fields[2]))
+			n++
+		}
+	}()
+	return "nats://" + lis.Addr().String(), received
+}
+
+// jetStreamAck is the MSG a stream answers a stored message with
+func jetStreamAck(inbox, ack string) string {
+	return fmt.Sprintf("MSG %s 1 %d\r\n%s\r\n", inbox, len(ack), ack)
+}
+
+func TestNATSPublisher_WaitsForPubAcks(t *testing.T) {
+	addr, received := fakeJetStreamServer(t, func(n int, inbox string) string {
+		return jetStreamAck(inbox, fmt.Sprintf(`{"stream":"LINKS","seq":%d}`, n+1))
+	})
+	publisher, err := outbox.NewNATSPublisher(addr, "links.events", time.Second)
+	require.NoError(t, err)
+	defer publisher.Close()
+
+	err = publisher.Publish(context.Background(), []domain.OutboxEvent{
+		{ID: 1, Type: domain.EventLinkCreated, ShortCode: "abc123", Payload: `{}`},
+		{ID: 2, Type: domain.EventLinkDeleted, ShortCode: "abc123", Payload: `{}`},
+	})
+
+	require.NoError(t, err)
+	first, second := <-received, <-received
+	assert.Equal(t, "links.events", first.Subject)
+	assert.Equal(t, "1", first.MsgID, "the event ID lets the stream drop resends")
+	assert.Equal(t, "2", second.MsgID)
+	var envelope outbox.Envelope
+	require.NoError(t, json.Unmarshal([]byte(second.Payload), &envelope))
+	assert.Equal(t, uint64(2), envelope.ID)
+	assert.Equal(t, domain.EventLinkDeleted, envelope.Type)
+}
+
+func TestNATSPublisher_NegativeAckFailsBatch(t *testing.T) {
+	addr, _ := fakeJetStreamServer(t, func(n int, inbox string) string {
+		if n == 1 {
+			return jetStreamAck(inbox, `{"error":{"code":503,"err_code":10077,"description":"maximum messages exceeded"}}`)
+		}
+		return jetStreamAck(inbox, `{"stream":"LINKS","seq":1}`)
+	})
+	publisher, err := outbox.NewNATSPublisher(addr, "links.events", time.Second)
+	require.NoError(t, err)
+	defer publisher.Close()
+
+	err = publisher.Publish(context.Background(), []domain.OutboxEvent{{ID: 1, Payload: `{}`}, {ID: 2, Payload: `{}`}})
+
+	assert.ErrorContains(t, err, "event 2")
+	assert.ErrorContains(t, err, "maximum messages exceeded")
+}
+
+func TestNATSPublisher_SubjectWithoutStreamFailsBatch(t *testing.T) {
+	// Core NATS answers a request nobody listens to with a 503 status when no_responders is set
+	addr, _ := fakeJetStreamServer(t, func(n int, inbox string) string {
+		return fmt.Sprintf("HMSG %s 1 16 16\r\nNATS/1.0 503\r\n\r\n\r\n", inbox)
+	})
+	publisher, err := outbox.NewNATSPublisher(addr, "links.events", time.Second)
+	require.NoError(t, err)
+	defer publisher.Close()
+
+	err = publisher.Publish(context.Background(), []domain.OutboxEvent{{ID: 1, Payload: `{}`}})
+
+	assert.ErrorContains(t, err, "no JetStream stream")
+}
+
+func TestNATSPublisher_MissingAckTimesOut(t *testing.T) {
+	addr, _ := fakeJetStreamServer(t, func(n int, inbox string) string { return "" })
+	publisher, err := outbox.NewNATSPublisher(addr, "links.events", 100*time.Millisecond)
+	require.NoError(t, err)
+	defer publisher.Close()
+
+	err = publisher.Publish(context.Background(), []domain.OutboxEvent{{ID: 1, Payload: `{}`}})
+
+	assert.Error(t, err, "a message the server took but no stream stored isn't delivered")
+}
+
+func TestNATSPublisher_ServerErrorFailsBatch(t *testing.T) {
+	addr, _ := fakeJetStreamServer(t, func(n int, inbox string) string { return "-ERR 'Authorization Violation'\r\n" })
+	publisher, err := outbox.NewNATSPublisher(addr, "links.events", time.Second)
+	require.NoError(t, err)
+	defer publisher.Close()
+
+	err = publisher.Publish(context.Background(), []domain.OutboxEvent{{ID: 1, Payload: `{}`}})
+
+	assert.ErrorContains(t, err, "Authorization Violation")
+}
+
+func TestNewNATSPublisher_RejectsInvalidURL(t *testing.T) {
+	_, err := outbox.NewNATSPublisher("http://localhost:4222", "links", time.Second)
+	assert.Error(t, err)
+}
@@ -0,0 +1,136 @@
+package unit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/cache"
+	"url-shortener/internal/cache/cachetest"
+	"url-shortener/internal/config"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/redirect"
+	"url-shortener/internal/service"
+)
+
+const (
+	slackbotUA = "Slackbot-LinkExpanding 1.0 (+https://api.slack.com/robots)"
+	browserUA  = "Mozilla/5.0 (Macintosh; Intel Mac OS X 14_0) AppleWebKit/605.1.15 Safari/605.1.15"
+)
+
+func TestUserAgentMatcher(t *testing.T) {
+	matcher := redirect.NewUserAgentMatcher([]string{"slackbot", "WhatsApp", "facebookexternalhit", "a.b"})
+
+	assert.True(t, matcher.Match(slackbotUA))
+	assert.True(t, matcher.Match("WhatsApp/2.23.20.0 A"))
+	assert.True(t, matcher.Match("facebookexternalhit/1.1"))
+	assert.True(t, matcher.Match("FACEBOOKEXTERNALHIT/1.1"), "matching ignores case")
+	assert.False(t, matcher.Match(browserUA))
+	assert.False(t, matcher.Match("axb"), "patterns are literal substrings")
+	assert.False(t, matcher.Match(""))
+
+	assert.False(t, redirect.NewUserAgentMatcher(nil).Match(slackbotUA), "an empty list matches nothing")
+	assert.False(t, redirect.NewUserAgentMatcher([]string{""}).Match(slackbotUA))
+}
+
+// setupPrefetchTest serves a confirm_before_redirect link from the database through an in-memory cache
+func setupPrefetchTest(t *testing.T, confirm bool) (*URLServiceTestSuite, *cachetest.MemoryCache) {
+	suite := setupURLServiceTest(t)
+	suite.cfg.BotUserAgents = []string{"bot"}
+	suite.cfg.BotClicks = config.BotClicksSeparate
+	suite.cfg.PrefetchUserAgents = []string{"slackbot", "whatsapp"}
+	store := cachetest.NewMemoryCache()
+	suite.service = service.NewURLService(suite.repo, store, suite.cfg, suite.logger)
+
+	suite.repo.On("FindByShortCode", mock.Anything, "unsub1").
+		Return(&domain.URL{ShortCode: "unsub1", OriginalURL: "https://example.com/unsubscribe?u=42", IsActive: true, ConfirmBeforeRedirect: confirm}, nil)
+	return suite, store
+}
+
+// getAs requests the short link with the given User-Agent
+func getAs(suite *URLServiceTestSuite, userAgent string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", "/unsub1", nil)
+	req.Header.Set("User-Agent", userAgent)
+	w := httptest.NewRecorder()
+	setupRedirectRouter(suite).ServeHTTP(w, req)
+	return w
+}
+
+func TestRedirect_PrefetcherGetsConfirmPage(t *testing.T) {
+	suite, _ := setupPrefetchTest(t, true)
+	suite.repo.On("IncrementPrefetchCount", mock.Anything, "unsub1").Return(nil).Once()
+
+	w := getAs(suite, slackbotUA)
+	drainCacheWrites(t, suite)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("Location"))
+	assert.Contains(t, w.Body.String(), "/unsub1/continue?token=")
+	suite.repo.AssertExpectations(t)
+	suite.repo.AssertNotCalled(t, "IncrementClickCount", mock.Anything, mock.Anything, mock.Anything)
+	suite.repo.AssertNotCalled(t, "IncrementBotClickCount", mock.Anything, mock.Anything)
+}
+
+func TestRedirect_BrowserSkipsConfirmPage(t *testing.T) {
+	suite, store := setupPrefetchTest(t, true)
+	suite.repo.On("IncrementClickCount", mock.Anything, "unsub1", mock.Anything).Return(nil).Once()
+
+	w := getAs(suite, browserUA)
+	drainCacheWrites(t, suite)
+
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+	assert.Equal(t, "https://example.com/unsubscribe?u=42", w.Header().Get("Location"))
+	suite.repo.AssertExpectations(t)
+	suite.repo.AssertNotCalled(t, "IncrementPrefetchCount", mock.Anything, mock.Anything)
+
+	cached, err := store.Get(context.Background(), cache.LinkKey("unsub1"))
+	require.NoError(t, err)
+	entry, ok := cache.DecodeLinkEntry(cached)
+	require.True(t, ok)
+	assert.True(t, entry.ConfirmPrefetch, "the flag survives caching")
+}
+
+func TestRedirect_CachedLinkStillConfirmsPrefetch(t *testing.T) {
+	suite, store := setupPrefetchTest(t, true)
+	link := &domain.URL{ShortCode: "unsub1", OriginalURL: "https://example.com/unsubscribe?u=42", IsActive: true, ConfirmBeforeRedirect: true}
+	require.NoError(t, store.Set(context.Background(), cache.LinkKey("unsub1"), cache.NewLinkEntry(link).Encode(), time.Hour))
+	suite.repo.On("IncrementPrefetchCount", mock.Anything, "unsub1").Return(nil).Once()
+
+	w := getAs(suite, "WhatsApp/2.23.20.0 A")
+	drainCacheWrites(t, suite)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "/unsub1/continue?token=")
+	suite.repo.AssertNotCalled(t, "FindByShortCode", mock.Anything, mock.Anything)
+	suite.repo.AssertCalled(t, "IncrementPrefetchCount", mock.Anything, "unsub1")
+	suite.repo.AssertNotCalled(t, "IncrementClickCount", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRedirect_PrefetchOnlyConfirmedWhenLinkOptsIn(t *testing.T) {
+	suite, _ := setupPrefetchTest(t, false)
+	suite.repo.On("IncrementBotClickCount", mock.Anything, "unsub1").Return(nil).Once()
+
+	w := getAs(suite, slackbotUA)
+	drainCacheWrites(t, suite)
+
+	assert.Equal(t, http.StatusMovedPermanently, w.Code, "other links keep redirecting prefetchers")
+	suite.repo.AssertExpectations(t)
+	suite.repo.AssertNotCalled(t, "IncrementPrefetchCount", mock.Anything, mock.Anything)
+}
+
+func TestConfig_PrefetchUserAgents(t *testing.T) {
+	cfg, err := config.LoadFrom(nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"slackbot", "whatsapp", "facebookexternalhit"}, cfg.PrefetchUserAgents)
+
+	t.Setenv("PREFETCH_USER_AGENTS", "Discordbot, TelegramBot")
+	cfg, err = config.LoadFrom(nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"discordbot", "telegrambot"}, cfg.PrefetchUserAgents)
+}
@@ -0,0 +1,160 @@
+package unit
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/handler"
+)
+
+// exportedDomainErrors returns the message of every exported Err* = errors.New(...) in the domain package, by name
+func exportedDomainErrors(t *testing.T) map[string]string {
+	t.Helper()
+	pkgs, err := parser.ParseDir(token.NewFileSet(), "../../internal/domain", func(info fs.FileInfo) bool {
+		return !strings.HasSuffix(info.Name(), "_test.go")
+	}, 0)
+	require.NoError(t, err)
+
+	found := make(map[string]string)
+	for _, file := range pkgs["domain"].Files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			spec, ok := n.(*ast.ValueSpec)
+			if !ok {
+				return true
+			}
+			for i, name := range spec.Names {
+				if !name.IsExported() || !strings.HasPrefix(name.Name, "Err") || i >= len(spec.Values) {
+					continue
+				}
+				call, ok := spec.Values[i].(*ast.CallExpr)
+				if !ok || len(call.Args) != 1 {
+					continue
+				}
+				if lit, ok := call.Args[0].(*ast.BasicLit); ok {
+					message, err := strconv.Unquote(lit.Value)
+					require.NoError(t, err)
+					found[name.Name] = message
+				}
+			}
+			return true
+		})
+	}
+	return found
+}
+
+func TestDomainErrors_CoverEveryExportedError(t *testing.T) {
+	exported := exportedDomainErrors(t)
+	require.NotEmpty(t, exported)
+
+	catalog := make(map[string]bool)
+	for _, entry := range handler.ErrorCatalog {
+		catalog[entry.Error] = true
+	}
+
+	for name, message := range exported {
+		var mapped *handler.DomainError
+		for i := range handler.DomainErrors {
+			if handler.DomainErrors[i].Err.Error() == message {
+				mapped = &handler.DomainErrors[i]
+				break
+			}
+		}
+		if assert.NotNil(t, mapped, "domain.%s has no entry in handler.DomainErrors", name) {
+			assert.True(t, catalog[mapped.Error], "domain.%s answers with %q, which is not in the error catalog", name, mapped.Error)
+			assert.NotEmpty(t, mapped.Message, name)
+		}
+	}
+}
+
+// setupProblemRouter serves link info behind the error format middleware, as setupRouter does
+func setupProblemRouter(suite *URLServiceTestSuite) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	h := handler.NewURLHandler(suite.service, suite.cfg, suite.logger)
+
+	router := gin.New()
+	router.Use(handler.RequestIDMiddleware(), handler.ErrorFormatMiddleware(suite.cfg))
+	router.GET("/api/v1/urls/:shortCode", h.GetURLInfo)
+	router.GET("/slow", handler.TimeoutMiddleware(10*time.Millisecond), func(c *gin.Context) {
+		<-c.Request.Context().Done()
+	})
+	return router
+}
+
+// getWithAccept requests path with the Accept header and request ID set
+func getWithAccept(router *gin.Engine, path, accept string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", path, nil)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	req.Header.Set("X-Request-ID", "req-42")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestProblemDetails_Negotiated(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	suite.cfg.BaseURL = "https://sho.rt"
+	suite.repo.On("FindByShortCode", mock.Anything, "nope00").Return((*domain.URL)(nil), domain.ErrURLNotFound)
+	router := setupProblemRouter(suite)
+
+	w := getWithAccept(router, "/api/v1/urls/nope00", "application/problem+json")
+
+	require.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+	var problem domain.ProblemDetails
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+	assert.Equal(t, "https://sho.rt/api/v1/errors#not_found", problem.Type)
+	assert.NotEmpty(t, problem.Title)
+	assert.Equal(t, http.StatusNotFound, problem.Status)
+	assert.Equal(t, "The requested URL was not found", problem.Detail)
+	assert.Equal(t, "/api/v1/urls/nope00#req-42", problem.Instance)
+	assert.Equal(t, "not_found", problem.Error)
+}
+
+func TestProblemDetails_DefaultShapeKept(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	suite.repo.On("FindByShortCode", mock.Anything, "nope00").Return((*domain.URL)(nil), domain.ErrURLNotFound)
+	router := setupProblemRouter(suite)
+
+	for _, accept := range []string{"", "application/json", "*/*", "application/problem+json;q=0"} {
+		w := getWithAccept(router, "/api/v1/urls/nope00", accept)
+
+		require.Equal(t, http.StatusNotFound, w.Code, accept)
+		assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"), accept)
+		var body domain.ErrorResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		assert.Equal(t, "not_found", body.Error)
+		assert.Equal(t, http.StatusNotFound, body.Code)
+	}
+}
+
+func TestProblemDetails_ConfigForcesFormat(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	suite.cfg.ProblemDetails = true
+	router := setupProblemRouter(suite)
+
+	w := getWithAccept(router, "/slow", "application/json")
+
+	require.Equal(t, http.StatusGatewayTimeout, w.Code)
+	assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"), "the 504 written at the deadline follows the format too")
+	var problem domain.ProblemDetails
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+	assert.Equal(t, "request_timeout", problem.Error)
+	assert.True(t, strings.HasSuffix(problem.Type, "/api/v1/errors#request_timeout"))
+}
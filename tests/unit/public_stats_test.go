@@ -0,0 +1,162 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/service"
+	"url-shortener/pkg/validator"
+)
+
+// setupPublicStatsTest serves the redirect routes over a service with a click repository
+// Each link is returned by FindByShortCode under its own code.
+func setupPublicStatsTest(t *testing.T, links ...*domain.URL) (*URLServiceTestSuite, *MockClickRepository) {
+	suite := setupURLServiceTest(t)
+	clicks := new(MockClickRepository)
+	suite.service = service.NewURLService(suite.repo, suite.cache, suite.cfg, suite.logger, service.WithClickRepository(clicks))
+	for _, link := range links {
+		suite.repo.On("FindByShortCode", mock.Anything, link.ShortCode).Return(link, nil)
+	}
+	suite.repo.On("FindByShortCode", mock.Anything, mock.Anything).Return(nil, domain.ErrURLNotFound)
+	return suite, clicks
+}
+
+func publicStatsRequest(path, accept string) *http.Request {
+	req := httptest.NewRequest("GET", path, nil)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	return req
+}
+
+func TestPublicStats_ServesJSONAndHTML(t *testing.T) {
+	created := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	suite, clicks := setupPublicStatsTest(t, &domain.URL{
+		ShortCode: "shared", OriginalURL: "https://secret.example/offer?coupon=VIP", IsActive: true,
+		PublicStats: true, ClickCount: 42, CreatedAt: created, CreatorIP: "203.0.113.9",
+	})
+	today := time.Now().UTC().Format("2006-01-02")
+	clicks.On("GetDailySeries", mock.Anything, "shared", mock.Anything, mock.Anything).Return([]domain.DailyClickStats{}, nil)
+	clicks.On("AggregateDay", mock.Anything, "shared", mock.Anything).
+		Return(&domain.DailyClickStats{Date: today, Clicks: 5, UniqueIPs: 4, TopReferrer: "news.example"}, nil)
+	router := setupRedirectRouter(suite)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, publicStatsRequest("/shared/stats", ""))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "public, max-age=60", w.Header().Get("Cache-Control"))
+	assert.Contains(t, w.Header().Get("Vary"), "Accept")
+
+	var stats domain.PublicStats
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &stats))
+	assert.Equal(t, "shared", stats.ShortCode)
+	assert.Equal(t, int64(42), stats.TotalClicks)
+	assert.True(t, created.Equal(stats.CreatedAt))
+	require.Len(t, stats.Daily, 30)
+	assert.Equal(t, domain.PublicDailyClicks{Date: today, Clicks: 5}, stats.Daily[29])
+
+	// Only the counts are public
+	for _, private := range []string{"secret.example", "203.0.113.9", "news.example", "unique_ips", "original_url"} {
+		assert.NotContains(t, w.Body.String(), private)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, publicStatsRequest("/shared/stats", "text/html"))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/html")
+	assert.Equal(t, "public, max-age=60", w.Header().Get("Cache-Control"))
+	body := w.Body.String()
+	assert.Contains(t, body, "<code>/shared</code>")
+	assert.Contains(t, body, ">42<")
+	assert.Contains(t, body, "since 2024-01-15")
+	assert.Contains(t, body, `style="width: 100%"`, "the busiest day gets the full bar")
+	assert.NotContains(t, body, "secret.example")
+	suite.repo.AssertNotCalled(t, "IncrementClickCount", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestPublicStats_PrivateLinksLookUnknown(t *testing.T) {
+	suite, _ := setupPublicStatsTest(t,
+		&domain.URL{ShortCode: "private", OriginalURL: "https://example.com", IsActive: true},
+		&domain.URL{ShortCode: "disabled", OriginalURL: "https://example.com", PublicStats: true},
+	)
+	router := setupRedirectRouter(suite)
+
+	for _, accept := range []string{"", "text/html"} {
+		unknown := httptest.NewRecorder()
+		router.ServeHTTP(unknown, publicStatsRequest("/missing/stats", accept))
+		require.Equal(t, http.StatusNotFound, unknown.Code)
+
+		for _, code := range []string{"private", "disabled"} {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, publicStatsRequest("/"+code+"/stats", accept))
+			assert.Equal(t, http.StatusNotFound, w.Code, code)
+			assert.Equal(t, unknown.Body.String(), w.Body.String(), "%s answers like an unknown code", code)
+			assert.Empty(t, w.Header().Get("Cache-Control"))
+		}
+	}
+}
+
+func TestPublicStats_MultiSegmentAlias(t *testing.T) {
+	suite, clicks := setupPublicStatsTest(t, &domain.URL{
+		ShortCode: "promo/summer", OriginalURL: "https://example.com", IsActive: true, PublicStats: true, ClickCount: 3,
+	})
+	expectEmptyDailySeries(clicks, "promo/summer")
+	router := setupRedirectRouter(suite)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, publicStatsRequest("/promo/summer/stats", ""))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"total_clicks":3`)
+
+	assert.True(t, validator.IsReservedAlias("promo/stats"))
+	assert.True(t, validator.IsReservedAlias("promo/summer/Stats"))
+	assert.False(t, validator.IsReservedAlias("stats"), "a plain code named stats still works")
+}
+
+func TestUpdateURL_TogglesPublicStats(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	ctx := context.Background()
+
+	url := &domain.URL{ShortCode: "abc123", OriginalURL: "https://example.com", IsActive: true}
+	suite.repo.On("FindByShortCode", ctx, "abc123").Return(url, nil)
+	suite.repo.On("Update", ctx, url).Return(nil)
+	suite.cache.On("Delete", mock.Anything, "abc123").Return(nil)
+
+	on, off := true, false
+	updated, err := suite.service.UpdateURL(ctx, "abc123", &domain.UpdateURLRequest{PublicStats: &on})
+	require.NoError(t, err)
+	assert.True(t, updated.PublicStats)
+
+	info, err := suite.service.GetURLInfo(ctx, "abc123")
+	require.NoError(t, err)
+	assert.True(t, info.PublicStats)
+
+	updated, err = suite.service.UpdateURL(ctx, "abc123", &domain.UpdateURLRequest{PublicStats: &off})
+	require.NoError(t, err)
+	assert.False(t, updated.PublicStats)
+}
+
+func TestShortenURL_PublicStatsLinksAreNotDeduplicated(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	ctx := context.Background()
+	existing := &domain.URL{ShortCode: "plain1", OriginalURL: "https://example.com", IsActive: true}
+	suite.repo.On("FindByOriginalURL", ctx, "https://example.com").Return(existing, nil)
+	suite.repo.On("Create", ctx, mock.Anything).Return(nil)
+	suite.cache.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+
+	response, err := suite.service.ShortenURL(ctx, &domain.CreateURLRequest{URL: "https://example.com", PublicStats: true}, domain.CreatorContext{})
+	require.NoError(t, err)
+	drainCacheWrites(t, suite)
+
+	assert.NotEqual(t, "plain1", response.ShortCode)
+	suite.repo.AssertCalled(t, "Create", ctx, mock.MatchedBy(func(url *domain.URL) bool { return url.PublicStats }))
+}
@@ -0,0 +1,170 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/handler"
+	"url-shortener/internal/service"
+)
+
+// countingCache is a MockCache with real in-memory atomic counters
+type countingCache struct {
+	MockCache
+	mu       sync.Mutex
+	counters map[string]int64
+}
+
+func newCountingCache() *countingCache {
+	c := &countingCache{counters: make(map[string]int64)}
+	c.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+	return c
+}
+
+func (c *countingCache) IncrBy(ctx context.Context, key string, delta int64, expireAt time.Time) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.counters[key] += delta
+	return c.counters[key], nil
+}
+
+func (c *countingCache) total(prefix string) int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var sum int64
+	for key, value := range c.counters {
+		if strings.HasPrefix(key, prefix) {
+			sum += value
+		}
+	}
+	return sum
+}
+
+// setupQuotaService builds a service whose creates always succeed unless createErr is set
+func setupQuotaService(t *testing.T, perIP, perKey int, createErr error) (*URLServiceTestSuite, *countingCache) {
+	suite := setupURLServiceTest(t)
+	suite.cfg.MaxURLsPerDayPerIP = perIP
+	suite.cfg.MaxURLsPerDayPerKey = perKey
+
+	counters := newCountingCache()
+	suite.service = service.NewURLService(suite.repo, counters, suite.cfg, suite.logger)
+
+	suite.repo.On("FindByOriginalURL", mock.Anything, mock.Anything).Return(nil, domain.ErrURLNotFound)
+	suite.repo.On("Create", mock.Anything, mock.Anything).Return(createErr)
+	return suite, counters
+}
+
+func TestShortenURL_PerIPQuota(t *testing.T) {
+	suite, _ := setupQuotaService(t, 2, 0, nil)
+	ctx := context.Background()
+
+	first, err := suite.service.ShortenURL(ctx, &domain.CreateURLRequest{URL: "https://example.com/1"}, domain.CreatorContext{IP: "10.0.0.1"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), first.Quota.Remaining)
+
+	_, err = suite.service.ShortenURL(ctx, &domain.CreateURLRequest{URL: "https://example.com/2"}, domain.CreatorContext{IP: "10.0.0.1"})
+	require.NoError(t, err)
+
+	_, err = suite.service.ShortenURL(ctx, &domain.CreateURLRequest{URL: "https://example.com/3"}, domain.CreatorContext{IP: "10.0.0.1"})
+	assert.ErrorIs(t, err, domain.ErrQuotaExceeded)
+
+	// Other IPs have their own quota
+	_, err = suite.service.ShortenURL(ctx, &domain.CreateURLRequest{URL: "https://example.com/4"}, domain.CreatorContext{IP: "10.0.0.2"})
+	assert.NoError(t, err)
+}
+
+func TestShortenURL_QuotaIsAtomicUnderConcurrency(t *testing.T) {
+	suite, counters := setupQuotaService(t, 5, 0, nil)
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		succeeded int
+	)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := suite.service.ShortenURL(context.Background(), &domain.CreateURLRequest{URL: "https://example.com/" + strconv.Itoa(i)}, domain.CreatorContext{IP: "10.0.0.1"})
+			if err == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, 5, succeeded)
+	// Rejected attempts give their reservation back
+	assert.Equal(t, int64(5), counters.total("quota:ip:10.0.0.1:"))
+}
+
+func TestShortenURL_QuotaReleasedWhenCreateFails(t *testing.T) {
+	suite, counters := setupQuotaService(t, 5, 0, errors.New("connection reset"))
+
+	_, err := suite.service.ShortenURL(context.Background(), &domain.CreateURLRequest{URL: "https://example.com"}, domain.CreatorContext{IP: "10.0.0.1"})
+
+	assert.Error(t, err)
+	assert.Equal(t, int64(0), counters.total("quota:ip:"))
+}
+
+func TestShortenURL_PerKeyQuota(t *testing.T) {
+	suite, _ := setupQuotaService(t, 0, 1, nil)
+	ctx := service.ContextWithAPIKey(context.Background(), "api_key:1a2b3c4d")
+
+	_, err := suite.service.ShortenURL(ctx, &domain.CreateURLRequest{URL: "https://example.com/1"}, domain.CreatorContext{IP: "10.0.0.1"})
+	require.NoError(t, err)
+
+	// The key quota follows the key across IPs
+	_, err = suite.service.ShortenURL(ctx, &domain.CreateURLRequest{URL: "https://example.com/2"}, domain.CreatorContext{IP: "10.0.0.2"})
+	assert.ErrorIs(t, err, domain.ErrQuotaExceeded)
+
+	// Anonymous callers are only subject to the (disabled) per-IP quota
+	_, err = suite.service.ShortenURL(context.Background(), &domain.CreateURLRequest{URL: "https://example.com/3"}, domain.CreatorContext{IP: "10.0.0.1"})
+	assert.NoError(t, err)
+}
+
+func TestShortenURLHandler_QuotaHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	suite, _ := setupQuotaService(t, 1, 0, nil)
+
+	router := gin.New()
+	router.POST("/api/v1/shorten", handler.APIKeyIdentityMiddleware(suite.cfg, nil), handler.NewURLHandler(suite.service, suite.cfg, suite.logger).ShortenURL)
+
+	shorten := func(url string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/v1/shorten", strings.NewReader(`{"url":"`+url+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.RemoteAddr = "10.0.0.9:1234"
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := shorten("https://example.com/1")
+	require.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "1", w.Header().Get("X-Quota-Limit"))
+	assert.Equal(t, "0", w.Header().Get("X-Quota-Remaining"))
+	assert.NotEmpty(t, w.Header().Get("X-Quota-Reset"))
+
+	w = shorten("https://example.com/2")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Contains(t, w.Body.String(), "quota_exceeded")
+
+	retryAfter, err := strconv.Atoi(w.Header().Get("Retry-After"))
+	require.NoError(t, err)
+	assert.True(t, retryAfter > 0 && retryAfter <= 86401)
+}
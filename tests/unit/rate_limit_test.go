@@ -0,0 +1,145 @@
+package unit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/config"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/handler"
+)
+
+// fingerprint mirrors how key ids appear in audit logs and RATE_LIMIT_TIERS
+func fingerprint(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:4])
+}
+
+func setupRateLimitRouter(cfg *config.Config, perMinute int, tiers map[string]int) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(handler.APIKeyIdentityMiddleware(cfg, nil))
+	router.Use(handler.RateLimitMiddleware(perMinute, tiers))
+	router.GET("/ping", func(c *gin.Context) { c.Status(http.StatusOK) })
+	return router
+}
+
+// sendRequests returns how many of n requests from the same IP got through
+func sendRequests(router *gin.Engine, n int, apiKey string) int {
+	allowed := 0
+	for i := 0; i < n; i++ {
+		req := httptest.NewRequest("GET", "/ping", nil)
+		req.RemoteAddr = "10.1.1.1:1234"
+		if apiKey != "" {
+			req.Header.Set("X-API-Key", apiKey)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code == http.StatusOK {
+			allowed++
+		}
+	}
+	return allowed
+}
+
+func TestRateLimit_KeyHasOwnBucketBehindSharedIP(t *testing.T) {
+	cfg := &config.Config{APIKey: "service-key"}
+	router := setupRateLimitRouter(cfg, 3, nil)
+
+	assert.Equal(t, 3, sendRequests(router, 5, ""))
+	// Same IP, but the identified key is limited separately
+	assert.Equal(t, 3, sendRequests(router, 5, "service-key"))
+}
+
+func TestRateLimit_TierOverridesDefault(t *testing.T) {
+	cfg := &config.Config{APIKey: "service-key"}
+	router := setupRateLimitRouter(cfg, 2, map[string]int{fingerprint("service-key"): 10})
+
+	assert.Equal(t, 10, sendRequests(router, 15, "service-key"))
+}
+
+func TestRateLimit_UnlimitedTier(t *testing.T) {
+	cfg := &config.Config{APIKey: "service-key", AdminAPIKey: "admin-key"}
+	router := setupRateLimitRouter(cfg, 2, map[string]int{fingerprint("admin-key"): config.RateLimitUnlimited})
+
+	assert.Equal(t, 100, sendRequests(router, 100, "admin-key"))
+	// Keys without a tier use the default
+	assert.Equal(t, 2, sendRequests(router, 5, "service-key"))
+}
+
+func TestRateLimit_InvalidKeyFallsBackToIP(t *testing.T) {
+	cfg := &config.Config{APIKey: "service-key"}
+	router := setupRateLimitRouter(cfg, 2, nil)
+
+	// Made-up keys must not earn a fresh bucket each
+	assert.Equal(t, 1, sendRequests(router, 1, "guess-1"))
+	assert.Equal(t, 1, sendRequests(router, 1, "guess-2"))
+	assert.Equal(t, 0, sendRequests(router, 1, "guess-3"))
+}
+
+// setupBucketRouter mirrors setupRouter's groups: API reads and writes, and redirects, on one limiter
+func setupBucketRouter(reads, writes, redirects int) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	limiter := handler.NewRateLimiter(nil)
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+
+	router := gin.New()
+	api := router.Group("/api/v1", limiter.MethodMiddleware(reads, writes))
+	api.POST("/shorten", ok)
+	api.GET("/urls/:shortCode", ok)
+	router.Group("/", limiter.Middleware(handler.RateLimitBucketRedirects, redirects)).GET("/:shortCode", ok)
+	return router
+}
+
+// countAllowed returns how many of n requests from the same IP got through, and the last response
+func countAllowed(router *gin.Engine, n int, method, path string) (int, *httptest.ResponseRecorder) {
+	allowed := 0
+	var w *httptest.ResponseRecorder
+	for i := 0; i < n; i++ {
+		req := httptest.NewRequest(method, path, nil)
+		req.RemoteAddr = "10.1.1.1:1234"
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code == http.StatusOK {
+			allowed++
+		}
+	}
+	return allowed, w
+}
+
+func TestRateLimiter_ExhaustedWritesDontBlockRedirects(t *testing.T) {
+	router := setupBucketRouter(4, 2, 5)
+
+	allowed, w := countAllowed(router, 3, "POST", "/api/v1/shorten")
+	assert.Equal(t, 2, allowed)
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, handler.RateLimitBucketAPIWrites, w.Header().Get("X-RateLimit-Bucket"))
+	var resp domain.ErrorResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Contains(t, resp.Message, handler.RateLimitBucketAPIWrites)
+
+	// Same IP, other buckets
+	allowed, _ = countAllowed(router, 5, "GET", "/abc123")
+	assert.Equal(t, 5, allowed)
+	allowed, _ = countAllowed(router, 4, "GET", "/api/v1/urls/abc123")
+	assert.Equal(t, 4, allowed)
+
+	allowed, w = countAllowed(router, 1, "GET", "/abc123")
+	assert.Zero(t, allowed)
+	assert.Equal(t, handler.RateLimitBucketRedirects, w.Header().Get("X-RateLimit-Bucket"))
+}
+
+func TestConfig_APILimitsFallBackToPerMinute(t *testing.T) {
+	cfg := &config.Config{RateLimitPerMinute: 60, RateLimitAPIWrites: 10}
+
+	assert.Equal(t, 60, cfg.APIReadLimit())
+	assert.Equal(t, 10, cfg.APIWriteLimit())
+}
@@ -0,0 +1,65 @@
+package unit
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/bootstrap"
+	"url-shortener/internal/config"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/service"
+)
+
+func TestRedirectorRole(t *testing.T) {
+	cfg, err := config.LoadFrom(nil)
+	require.NoError(t, err)
+	cfg.DBUser, cfg.DBPassword = "owner", "owner-secret"
+
+	role := bootstrap.RedirectorRole(cfg)
+	assert.Equal(t, bootstrap.ServerRole(cfg), role, "without REDIRECTOR_DB_USER the server's role is used")
+	assert.NotContains(t, role.DSN(cfg), "read_only")
+
+	t.Setenv("REDIRECTOR_DB_USER", "reader")
+	t.Setenv("REDIRECTOR_DB_PASSWORD", "reader-secret")
+	t.Setenv("REDIRECTOR_READ_ONLY", "true")
+	cfg, err = config.LoadFrom(nil)
+	require.NoError(t, err)
+
+	role = bootstrap.RedirectorRole(cfg)
+	assert.Equal(t, bootstrap.Role{User: "reader", Password: "reader-secret", ReadOnly: true}, role)
+	assert.Contains(t, role.DSN(cfg), "user=reader password=reader-secret ")
+	assert.Contains(t, role.DSN(cfg), "default_transaction_read_only=on")
+}
+
+func TestConfig_RedirectorPasswordNeedsUser(t *testing.T) {
+	t.Setenv("REDIRECTOR_DB_PASSWORD", "reader-secret")
+	_, err := config.LoadFrom(nil)
+	assert.ErrorContains(t, err, "REDIRECTOR_DB_USER")
+}
+
+func TestRedirect_WithoutClickCounting(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	suite.cfg.BotUserAgents = []string{"bot"}
+	suite.cfg.PrefetchUserAgents = []string{"slackbot"}
+	suite.service = service.NewURLService(suite.repo, suite.cache, suite.cfg, suite.logger, service.WithoutClickCounting())
+	suite.cache.On("Get", mock.Anything, mock.Anything).Return("", nil)
+	suite.cache.On("Exists", mock.Anything, mock.Anything).Return(false, nil)
+	suite.cache.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+	suite.repo.On("FindByShortCode", mock.Anything, "ro0001").
+		Return(&domain.URL{ShortCode: "ro0001", OriginalURL: "https://example.com/read-only", IsActive: true, ConfirmBeforeRedirect: true}, nil)
+
+	for _, userAgent := range []string{browserUA, "Googlebot/2.1", slackbotUA} {
+		_, err := suite.service.PrepareRedirect(context.Background(), "ro0001", domain.Visitor{UserAgent: userAgent})
+		require.NoError(t, err, userAgent)
+	}
+	drainCacheWrites(t, suite)
+
+	// The mock panics on any write it wasn't told about; these make the intent explicit
+	suite.repo.AssertNotCalled(t, "IncrementClickCount", mock.Anything, mock.Anything, mock.Anything)
+	suite.repo.AssertNotCalled(t, "IncrementBotClickCount", mock.Anything, mock.Anything)
+	suite.repo.AssertNotCalled(t, "IncrementPrefetchCount", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,105 @@
+package unit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/cache"
+	"url-shortener/internal/config"
+)
+
+func TestRedisMode_FromEnv(t *testing.T) {
+	cfg, err := config.LoadFrom(nil)
+	require.NoError(t, err)
+	assert.Equal(t, config.RedisModeSingle, cfg.RedisMode)
+
+	t.Setenv("REDIS_MODE", "Sentinel")
+	t.Setenv("REDIS_SENTINEL_ADDRS", "sentinel-1:26379, sentinel-2:26379")
+	t.Setenv("REDIS_MASTER_NAME", "mymaster")
+	cfg, err = config.LoadFrom(nil)
+	require.NoError(t, err)
+	assert.Equal(t, config.RedisModeSentinel, cfg.RedisMode)
+	assert.Equal(t, []string{"sentinel-1:26379", "sentinel-2:26379"}, cfg.RedisSentinelAddrs)
+	assert.Equal(t, "mymaster", cfg.RedisMasterName)
+
+	t.Setenv("REDIS_MODE", "cluster")
+	t.Setenv("REDIS_CLUSTER_ADDRS", "redis-1:6379,redis-2:6379,redis-3:6379")
+	cfg, err = config.LoadFrom(nil)
+	require.NoError(t, err)
+	assert.Len(t, cfg.RedisClusterAddrs, 3)
+}
+
+func TestValidate_RedisMode(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(*config.Config)
+		wantErr string
+	}{
+		{"single needs nothing else", func(c *config.Config) {}, ""},
+		{"sentinel without addresses", func(c *config.Config) {
+			c.RedisMode = config.RedisModeSentinel
+			c.RedisMasterName = "mymaster"
+		}, "REDIS_SENTINEL_ADDRS and REDIS_MASTER_NAME"},
+		{"sentinel without master name", func(c *config.Config) {
+			c.RedisMode = config.RedisModeSentinel
+			c.RedisSentinelAddrs = []string{"sentinel-1:26379"}
+		}, "REDIS_SENTINEL_ADDRS and REDIS_MASTER_NAME"},
+		{"cluster without addresses", func(c *config.Config) {
+			c.RedisMode = config.RedisModeCluster
+		}, "REDIS_CLUSTER_ADDRS is required"},
+		{"cluster with a database", func(c *config.Config) {
+			c.RedisMode = config.RedisModeCluster
+			c.RedisClusterAddrs = []string{"redis-1:6379"}
+			c.RedisDB = 2
+		}, "REDIS_DB must be 0"},
+		{"unknown mode", func(c *config.Config) {
+			c.RedisMode = "replicated"
+		}, `REDIS_MODE must be "single", "sentinel" or "cluster", got "replicated"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := config.LoadFrom(nil)
+			require.NoError(t, err)
+			tt.modify(cfg)
+
+			err = cfg.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestNewRedisCacheWith_RejectsIncompleteOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    cache.RedisOptions
+		wantErr string
+	}{
+		{"single without address", cache.RedisOptions{Mode: cache.RedisModeSingle}, "needs an address"},
+		{"sentinel without addresses", cache.RedisOptions{Mode: cache.RedisModeSentinel, MasterName: "mymaster"}, "at least one sentinel address"},
+		{"sentinel without master name", cache.RedisOptions{Mode: cache.RedisModeSentinel, SentinelAddrs: []string{"sentinel-1:26379"}}, "needs the master name"},
+		{"cluster without addresses", cache.RedisOptions{Mode: cache.RedisModeCluster}, "at least one node address"},
+		{"cluster with a database", cache.RedisOptions{Mode: cache.RedisModeCluster, ClusterAddrs: []string{"redis-1:6379"}, DB: 1}, "only supports database 0"},
+		{"unknown mode", cache.RedisOptions{Mode: "replicated", Addr: "localhost:6379"}, `unknown redis mode "replicated"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := cache.NewRedisCacheWith(tt.opts, "test")
+			assert.Nil(t, c)
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestNewRedisCacheWith_UnreachableServer(t *testing.T) {
+	// Nothing listens on port 1, so the connection is refused right away
+	_, err := cache.NewRedisCacheWith(cache.RedisOptions{Addr: "127.0.0.1:1"}, "test")
+	assert.ErrorContains(t, err, "failed to connect to Redis")
+}
@@ -0,0 +1,69 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/domain"
+)
+
+func TestRecordClick_PassesReferrerHostToClickCount(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	ctx := context.Background()
+
+	suite.cache.On("Get", ctx, "abc123").Return("", nil)
+	suite.repo.On("FindByShortCode", ctx, "abc123").
+		Return(&domain.URL{ShortCode: "abc123", OriginalURL: "https://example.com", IsActive: true}, nil)
+	suite.repo.On("IncrementClickCount", ctx, "abc123", "news.example").Return(nil).Once()
+	suite.repo.On("IncrementClickCount", ctx, "abc123", "").Return(nil).Twice()
+	suite.cache.On("Set", mock.Anything, "abc123", mock.Anything, time.Hour).Return(nil)
+
+	for _, referrer := range []string{"https://News.Example/story?id=1", "", "https://short.url/abc123"} {
+		_, err := suite.service.GetOriginalURL(ctx, "abc123", domain.Visitor{Referrer: referrer})
+		require.NoError(t, err)
+	}
+
+	// Direct visits and our own pages are counted without a referrer
+	suite.repo.AssertExpectations(t)
+}
+
+func TestGetStats_ExposesReferrers(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	suite.repo.On("GetStats", mock.Anything, "abc123").Return(&domain.URLStats{
+		ShortCode:      "abc123",
+		TotalClicks:    5,
+		LastReferrer:   "news.example",
+		ReferrerCounts: domain.ReferrerCounts{"news.example": 3, domain.OtherReferrers: 2},
+	}, nil)
+	router := setupConditionalRouter(suite)
+
+	w := conditionalGet(router, "/api/v1/urls/abc123/stats", nil)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var body map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.JSONEq(t, `"news.example"`, string(body["last_referrer"]))
+	assert.JSONEq(t, `{"news.example": 3, "(other)": 2}`, string(body["referrer_counts"]))
+}
+
+func TestReferrerCounts_ScanValueRoundTrip(t *testing.T) {
+	counts := domain.ReferrerCounts{"news.example": 3}
+
+	value, err := counts.Value()
+	require.NoError(t, err)
+
+	var scanned domain.ReferrerCounts
+	require.NoError(t, scanned.Scan([]byte(value.(string))))
+	assert.Equal(t, counts, scanned)
+
+	empty, err := domain.ReferrerCounts{}.Value()
+	require.NoError(t, err)
+	assert.Nil(t, empty, "links without referrers store NULL")
+}
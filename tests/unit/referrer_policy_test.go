@@ -0,0 +1,136 @@
+package unit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/cache"
+	"url-shortener/internal/domain"
+)
+
+func TestReferrerPolicy_Validate(t *testing.T) {
+	for _, policy := range []domain.ReferrerPolicy{"", "no-referrer", "origin", "strict-origin-when-cross-origin", "bounce"} {
+		assert.NoError(t, policy.Validate(), policy)
+	}
+	for _, policy := range []domain.ReferrerPolicy{"none", "No-Referrer", "origin; unsafe-url"} {
+		assert.Error(t, policy.Validate(), policy)
+	}
+}
+
+func TestLinkEntry_ReferrerPolicyRoundTrip(t *testing.T) {
+	encoded := cache.NewLinkEntry(&domain.URL{OriginalURL: "https://example.com", ReferrerPolicy: domain.ReferrerPolicyOrigin}).Encode()
+
+	entry, ok := cache.DecodeLinkEntry(encoded)
+
+	require.True(t, ok)
+	assert.Equal(t, domain.ReferrerPolicyOrigin, entry.ReferrerPolicy)
+}
+
+func TestRedirectURL_SendsReferrerPolicyHeader(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	router := setupRedirectRouter(suite)
+
+	entry := cache.NewLinkEntry(&domain.URL{OriginalURL: "https://partner.example", IsActive: true, ReferrerPolicy: domain.ReferrerPolicyNoReferrer})
+	suite.cache.On("Get", mock.Anything, "abc123").Return(entry.Encode(), nil)
+	suite.repo.On("IncrementClickCount", mock.Anything, "abc123", mock.Anything).Return(nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/abc123", nil))
+
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+	assert.Equal(t, "no-referrer", w.Header().Get("Referrer-Policy"))
+}
+
+func TestRedirectURL_NoReferrerPolicyByDefault(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	router := setupRedirectRouter(suite)
+
+	suite.cache.On("Get", mock.Anything, "abc123").Return("https://example.com", nil)
+	suite.repo.On("IncrementClickCount", mock.Anything, "abc123", mock.Anything).Return(nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/abc123", nil))
+
+	assert.Empty(t, w.Header().Get("Referrer-Policy"))
+}
+
+func TestRedirectURL_BouncePageCountsOnce(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	router := setupRedirectRouter(suite)
+
+	suite.cache.On("Get", mock.Anything, "abc123").Return("", nil)
+	suite.repo.On("FindByShortCode", mock.Anything, "abc123").Return(&domain.URL{
+		ShortCode:      "abc123",
+		OriginalURL:    "https://partner.example/?a=1&b=2",
+		IsActive:       true,
+		ReferrerPolicy: domain.ReferrerPolicyBounce,
+	}, nil)
+	suite.repo.On("IncrementClickCount", mock.Anything, "abc123", mock.Anything).Return(nil).Once()
+	suite.cache.On("Set", mock.Anything, "abc123", mock.Anything, time.Hour).Return(nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/abc123", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("Location"))
+	assert.Equal(t, "no-referrer", w.Header().Get("Referrer-Policy"))
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+	assert.Contains(t, w.Body.String(), `<meta name="referrer" content="no-referrer">`)
+	assert.Contains(t, w.Body.String(), `content="0;url=https://partner.example/?a=1&amp;b=2"`)
+	suite.repo.AssertNumberOfCalls(t, "IncrementClickCount", 1)
+}
+
+func TestShortenURL_RejectsUnknownReferrerPolicy(t *testing.T) {
+	suite := setupURLServiceTest(t)
+
+	_, err := suite.service.ShortenURL(context.Background(), &domain.CreateURLRequest{
+		URL:            "https://example.com",
+		ReferrerPolicy: "hide-everything",
+	}, domain.CreatorContext{IP: "192.168.1.1"})
+
+	var appErr *domain.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, http.StatusBadRequest, appErr.StatusCode)
+	suite.repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestShortenURL_ReferrerPolicySeparatesDedup(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	ctx := context.Background()
+
+	suite.repo.On("FindByOriginalURL", ctx, "https://example.com/partner").
+		Return(&domain.URL{ShortCode: "plain01", OriginalURL: "https://example.com/partner", IsActive: true}, nil)
+	suite.repo.On("Create", ctx, mock.MatchedBy(func(u *domain.URL) bool {
+		return u.ReferrerPolicy == domain.ReferrerPolicyBounce
+	})).Return(nil).Once()
+	suite.cache.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	resp, err := suite.service.ShortenURL(ctx, &domain.CreateURLRequest{
+		URL:            "https://example.com/partner",
+		ReferrerPolicy: domain.ReferrerPolicyBounce,
+	}, domain.CreatorContext{IP: "192.168.1.1"})
+
+	require.NoError(t, err)
+	assert.NotEqual(t, "plain01", resp.ShortCode)
+	suite.repo.AssertExpectations(t)
+}
+
+func TestUpdateURL_RejectsUnknownReferrerPolicy(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	ctx := context.Background()
+	suite.repo.On("FindByShortCode", ctx, "abc123").
+		Return(&domain.URL{ShortCode: "abc123", OriginalURL: "https://example.com", IsActive: true}, nil)
+
+	policy := domain.ReferrerPolicy("sometimes")
+	_, err := suite.service.UpdateURL(ctx, "abc123", &domain.UpdateURLRequest{ReferrerPolicy: &policy})
+
+	require.Error(t, err)
+	suite.repo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+}
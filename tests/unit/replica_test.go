@@ -0,0 +1,185 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+
+	"url-shortener/internal/cache"
+	"url-shortener/internal/cache/cachetest"
+	"url-shortener/internal/config"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/repository"
+	"url-shortener/internal/repository/postgres"
+)
+
+// failingReplica answers every read with err, like a replica that is down or unreachable
+type failingReplica struct {
+	repository.URLRepository
+	err   error
+	reads int
+}
+
+func (f *failingReplica) FindByShortCode(context.Context, string) (*domain.URL, error) {
+	f.reads++
+	return nil, f.err
+}
+
+func (f *failingReplica) FindByOriginalURL(context.Context, string) (*domain.URL, error) {
+	f.reads++
+	return nil, f.err
+}
+
+func (f *failingReplica) GetStats(context.Context, string) (*domain.URLStats, error) {
+	f.reads++
+	return nil, f.err
+}
+
+func (f *failingReplica) ExistsByShortCode(context.Context, string) (bool, error) {
+	f.reads++
+	return false, f.err
+}
+
+func TestReplicaRepository_ReadsGoToReplicasInTurn(t *testing.T) {
+	ctx := context.Background()
+	primary, first, second := new(MockURLRepository), new(MockURLRepository), new(MockURLRepository)
+	link := &domain.URL{ShortCode: "abc123", OriginalURL: "https://example.com"}
+	first.On("FindByShortCode", ctx, "abc123").Return(link, nil)
+	second.On("FindByShortCode", ctx, "abc123").Return(link, nil)
+	first.On("GetStats", ctx, "abc123").Return(&domain.URLStats{ShortCode: "abc123"}, nil)
+	primary.On("IncrementClickCount", ctx, "abc123", mock.Anything).Return(nil)
+	primary.On("Delete", ctx, "abc123").Return(nil)
+	log, _ := observedLogger(zapcore.WarnLevel)
+	repo := postgres.NewReplicaURLRepository(primary, []repository.URLRepository{first, second}, cachetest.NewMemoryCache(), 10*time.Second, log)
+
+	for i := 0; i < 2; i++ {
+		got, err := repo.FindByShortCode(ctx, "abc123")
+		require.NoError(t, err)
+		assert.Equal(t, link, got)
+	}
+	_, err := repo.GetStats(ctx, "abc123")
+	require.NoError(t, err)
+	require.NoError(t, repo.IncrementClickCount(ctx, "abc123", ""))
+	require.NoError(t, repo.Delete(ctx, "abc123"))
+
+	first.AssertNumberOfCalls(t, "FindByShortCode", 1)
+	second.AssertNumberOfCalls(t, "FindByShortCode", 1)
+	primary.AssertNotCalled(t, "FindByShortCode", mock.Anything, mock.Anything)
+	primary.AssertExpectations(t)
+}
+
+func TestReplicaRepository_FailingReplicaFallsBackToPrimary(t *testing.T) {
+	ctx := context.Background()
+	primary := new(MockURLRepository)
+	replica := &failingReplica{err: errors.New("dial tcp 10.0.0.7:5432: connection refused")}
+	link := &domain.URL{ShortCode: "abc123", OriginalURL: "https://example.com"}
+	primary.On("FindByShortCode", ctx, "abc123").Return(link, nil)
+	primary.On("FindByOriginalURL", ctx, "https://example.com").Return(link, nil)
+	primary.On("GetStats", ctx, "abc123").Return(&domain.URLStats{ShortCode: "abc123"}, nil)
+	primary.On("ExistsByShortCode", ctx, "abc123").Return(true, nil)
+	log, logs := observedLogger(zapcore.WarnLevel)
+	repo := postgres.NewReplicaURLRepository(primary, []repository.URLRepository{replica}, cachetest.NewMemoryCache(), 10*time.Second, log)
+
+	got, err := repo.FindByShortCode(ctx, "abc123")
+	require.NoError(t, err)
+	assert.Equal(t, link, got)
+	got, err = repo.FindByOriginalURL(ctx, "https://example.com")
+	require.NoError(t, err)
+	assert.Equal(t, link, got)
+	_, err = repo.GetStats(ctx, "abc123")
+	require.NoError(t, err)
+	exists, err := repo.ExistsByShortCode(ctx, "abc123")
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	assert.Equal(t, 4, replica.reads)
+	primary.AssertExpectations(t)
+	assert.Equal(t, 4, logs.FilterMessageSnippet("Replica read failed").Len())
+}
+
+func TestReplicaRepository_LaggingReplicaWithinWindow(t *testing.T) {
+	ctx := context.Background()
+	primary := new(MockURLRepository)
+	replica := &failingReplica{err: domain.ErrURLNotFound}
+	link := &domain.URL{ShortCode: "fresh1", OriginalURL: "https://example.com/new"}
+	primary.On("Create", ctx, link).Return(nil)
+	primary.On("FindByShortCode", ctx, "fresh1").Return(link, nil)
+	primary.On("FindByOriginalURL", ctx, "https://example.com/new").Return(link, nil)
+	primary.On("ExistsByShortCode", ctx, "fresh1").Return(true, nil)
+	log, logs := observedLogger(zapcore.WarnLevel)
+	recent := cachetest.NewMemoryCache()
+	repo := postgres.NewReplicaURLRepository(primary, []repository.URLRepository{replica}, recent, 10*time.Second, log)
+
+	require.NoError(t, repo.Create(ctx, link))
+	assert.Equal(t, 10*time.Second, recent.TTL(cache.RecentWriteKey("fresh1")), "the mark lasts for the fallback window")
+
+	// The replica hasn't caught up, so the primary answers for the new link
+	got, err := repo.FindByShortCode(ctx, "fresh1")
+	require.NoError(t, err)
+	assert.Equal(t, link, got)
+	got, err = repo.FindByOriginalURL(ctx, "https://example.com/new")
+	require.NoError(t, err)
+	assert.Equal(t, link, got)
+	exists, err := repo.ExistsByShortCode(ctx, "fresh1")
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	// Codes nobody wrote recently are answered by the replica alone
+	_, err = repo.FindByShortCode(ctx, "nope01")
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+	exists, err = repo.ExistsByShortCode(ctx, "nope01")
+	require.NoError(t, err)
+	assert.False(t, exists)
+	primary.AssertNotCalled(t, "FindByShortCode", ctx, "nope01")
+	primary.AssertNotCalled(t, "ExistsByShortCode", ctx, "nope01")
+	assert.Zero(t, logs.Len(), "lag is expected and not worth a warning")
+}
+
+func TestReplicaRepository_NoCacheChecksPrimaryOnNotFound(t *testing.T) {
+	ctx := context.Background()
+	primary := new(MockURLRepository)
+	primary.On("FindByShortCode", ctx, "nope01").Return(nil, domain.ErrURLNotFound)
+	log, _ := observedLogger(zapcore.WarnLevel)
+	repo := postgres.NewReplicaURLRepository(primary, []repository.URLRepository{&failingReplica{err: domain.ErrURLNotFound}}, nil, 10*time.Second, log)
+
+	_, err := repo.FindByShortCode(ctx, "nope01")
+
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+	primary.AssertExpectations(t)
+}
+
+func TestReplicaRepository_NoReplicasReturnsPrimary(t *testing.T) {
+	primary := new(MockURLRepository)
+	log, _ := observedLogger(zapcore.WarnLevel)
+
+	assert.Same(t, primary, postgres.NewReplicaURLRepository(primary, nil, cachetest.NewMemoryCache(), time.Second, log))
+}
+
+func TestLoadFrom_ReplicaDSNsKeepTheirCase(t *testing.T) {
+	cfg, err := config.LoadFrom(strings.NewReader("db_replica_dsns: [host=file password=MixedCase]"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"host=file password=MixedCase"}, cfg.DBReplicaDSNs)
+
+	t.Setenv("DB_REPLICA_DSNS", "host=replica-a password=S3cret , host=replica-b password=Other")
+	cfg, err = config.LoadFrom(strings.NewReader("db_replica_dsns: [host=file password=MixedCase]"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"host=replica-a password=S3cret", "host=replica-b password=Other"}, cfg.DBReplicaDSNs)
+	assert.Equal(t, 10*time.Second, cfg.DBReplicaFallbackWindow)
+
+	t.Setenv("DB_REPLICA_DSNS", "")
+	cfg, err = config.LoadFrom(strings.NewReader("db_replica_dsns: [host=file password=MixedCase]"))
+	require.NoError(t, err)
+	assert.Empty(t, cfg.DBReplicaDSNs, "an empty variable clears the file's replicas")
+
+	t.Setenv("DB_REPLICA_DSNS", "host=replica-a")
+	t.Setenv("DB_REPLICA_FALLBACK_SECONDS", "0")
+	_, err = config.LoadFrom(nil)
+	assert.ErrorContains(t, err, "DB_REPLICA_FALLBACK_SECONDS")
+}
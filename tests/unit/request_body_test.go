@@ -0,0 +1,118 @@
+package unit
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/handler"
+)
+
+// setupShortenRouter registers the shorten endpoint behind a body limit of maxBytes
+func setupShortenRouter(suite *URLServiceTestSuite, maxBytes int64) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	h := handler.NewURLHandler(suite.service, suite.cfg, suite.logger)
+
+	router := gin.New()
+	v1 := router.Group("/api/v1")
+	v1.Use(handler.BodyLimitMiddleware(maxBytes))
+	v1.POST("/shorten", h.ShortenURL)
+	return router
+}
+
+// postShorten sends body to the shorten endpoint and decodes the error response, if any
+func postShorten(t *testing.T, router *gin.Engine, body io.Reader, contentLength int64) (*httptest.ResponseRecorder, domain.ErrorResponse) {
+	req := httptest.NewRequest("POST", "/api/v1/shorten", body)
+	req.Header.Set("Content-Type", "application/json")
+	req.ContentLength = contentLength
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var resp domain.ErrorResponse
+	if w.Code >= 400 {
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	}
+	return w, resp
+}
+
+func TestShorten_OversizedBodyDeclared(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	body := `{"url": "https://example.com/` + strings.Repeat("a", 200) + `"}`
+
+	w, resp := postShorten(t, setupShortenRouter(suite, 64), strings.NewReader(body), int64(len(body)))
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Equal(t, "request_too_large", resp.Error)
+	suite.repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestShorten_OversizedBodyChunked(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	body := `{"url": "https://example.com/` + strings.Repeat("a", 200) + `"}`
+
+	// Unknown length, so the limit only trips while the decoder reads
+	w, resp := postShorten(t, setupShortenRouter(suite, 64), io.MultiReader(strings.NewReader(body)), -1)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Equal(t, "request_too_large", resp.Error)
+}
+
+func TestShorten_TruncatedJSON(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	body := `{"url": "https://exa`
+
+	w, resp := postShorten(t, setupShortenRouter(suite, 1024), strings.NewReader(body), int64(len(body)))
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "invalid_request", resp.Error)
+	assert.Contains(t, resp.Message, "truncated")
+}
+
+func TestShorten_UnknownFieldNamed(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	body := `{"url": "https://example.com", "custom_alais": "golang"}`
+
+	w, resp := postShorten(t, setupShortenRouter(suite, 1024), strings.NewReader(body), int64(len(body)))
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, resp.Message, `unknown field "custom_alais"`)
+	suite.repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestShorten_UnknownNestedField(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	body := `{"url": "https://example.com", "utm": {"sorce": "newsletter"}}`
+
+	w, resp := postShorten(t, setupShortenRouter(suite, 1024), strings.NewReader(body), int64(len(body)))
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, resp.Message, `unknown field "sorce"`)
+}
+
+func TestShorten_TrailingDataRejected(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	body := `{"url": "https://example.com"} {"url": "https://other.example"}`
+
+	w, _ := postShorten(t, setupShortenRouter(suite, 1024), strings.NewReader(body), int64(len(body)))
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestShorten_StrictDecodingStillValidates(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	body := `{"custom_alias": "golang"}`
+
+	w, resp := postShorten(t, setupShortenRouter(suite, 1024), strings.NewReader(body), int64(len(body)))
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "invalid_request", resp.Error)
+}
@@ -0,0 +1,95 @@
+package unit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/handler"
+)
+
+// setupValidationRouter registers the endpoints taking a link body
+func setupValidationRouter(suite *URLServiceTestSuite) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	suite.cfg.AdminAPIKey = "admin-secret"
+	suite.cfg.MaxExpiryDays = 365
+	h := handler.NewURLHandler(suite.service, suite.cfg, suite.logger)
+
+	router := gin.New()
+	router.POST("/api/v1/shorten", h.ShortenURL)
+	router.PATCH("/api/v1/urls/:shortCode", h.UpdateURL)
+	return router
+}
+
+func TestRequestValidation_FieldErrors(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	suite.repo.On("FindByOriginalURL", mock.Anything, mock.Anything).Return(nil, domain.ErrURLNotFound).Maybe()
+	suite.repo.On("FindByShortCode", mock.Anything, "abc123").
+		Return(&domain.URL{ShortCode: "abc123", OriginalURL: "https://example.com", IsActive: true}, nil).Maybe()
+	router := setupValidationRouter(suite)
+
+	tests := []struct {
+		name   string
+		method string
+		body   string
+		field  string
+		rule   string
+	}{
+		{"missing url", "POST", `{}`, "url", "required_without"},
+		{"malformed url", "POST", `{"url": "not a url"}`, "url", "format"},
+		{"nested binding rule", "POST", `{"url": "https://example.com", "utm": {"source": "` + strings.Repeat("s", 256) + `"}}`, "utm.source", "max"},
+		{"alias charset", "POST", `{"url": "https://example.com", "custom_alias": "my alias!"}`, "custom_alias", "format"},
+		{"alias length", "POST", `{"url": "https://example.com", "custom_alias": "` + strings.Repeat("a", 65) + `"}`, "custom_alias", "max"},
+		{"reserved alias", "POST", `{"url": "https://example.com", "custom_alias": "health"}`, "custom_alias", "reserved"},
+		{"expiry_days range", "POST", `{"url": "https://example.com", "expiry_days": 9999}`, "expiry_days", "max"},
+		{"expiry_days type", "POST", `{"url": "https://example.com", "expiry_days": "ten"}`, "expiry_days", "type"},
+		{"unknown field", "POST", `{"url": "https://example.com", "custom_alais": "x"}`, "custom_alais", "unknown"},
+		{"patch title", "PATCH", `{"title": "` + strings.Repeat("t", domain.MaxTitleLength+1) + `"}`, "title", "max"},
+		{"patch referrer policy", "PATCH", `{"referrer_policy": "bogus"}`, "referrer_policy", "oneof"},
+		{"patch nested type", "PATCH", `{"utm": {"source": 5}}`, "utm.source", "type"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := "/api/v1/shorten"
+			if tt.method == "PATCH" {
+				target = "/api/v1/urls/abc123"
+			}
+			req := httptest.NewRequest(tt.method, target, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("X-API-Key", "admin-secret")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			require.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+			var body domain.ErrorResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			require.Len(t, body.Errors, 1, w.Body.String())
+			assert.Equal(t, tt.field, body.Errors[0].Field, "fields are named by their JSON tag")
+			assert.Equal(t, tt.rule, body.Errors[0].Rule)
+			assert.NotEmpty(t, body.Errors[0].Message)
+			assert.NotEmpty(t, body.Message)
+		})
+	}
+}
+
+func TestRequestValidation_OtherErrorsHaveNoFields(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	router := setupValidationRouter(suite)
+
+	req := httptest.NewRequest("POST", "/api/v1/shorten", strings.NewReader(`{"url": "https://example.com"`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	assert.NotContains(t, w.Body.String(), `"errors"`, "a truncated body isn't about one field")
+}
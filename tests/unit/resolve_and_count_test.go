@@ -0,0 +1,193 @@
+package unit
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/cache"
+	"url-shortener/internal/cache/cachetest"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/service"
+)
+
+// resolvingCache is a MemoryCache with an in-memory cache.Resolver that records which calls reached it
+type resolvingCache struct {
+	*cachetest.MemoryCache
+	mu       sync.Mutex
+	calls    []string
+	visitors map[string]map[string]bool
+}
+
+func newResolvingCache() *resolvingCache {
+	return &resolvingCache{MemoryCache: cachetest.NewMemoryCache(), visitors: map[string]map[string]bool{}}
+}
+
+func (c *resolvingCache) record(call string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls = append(c.calls, call)
+}
+
+// redirectCalls returns the reads and counter calls made so far
+func (c *resolvingCache) redirectCalls() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.calls...)
+}
+
+func (c *resolvingCache) Get(ctx context.Context, key string) (string, error) {
+	c.record("Get")
+	return c.MemoryCache.Get(ctx, key)
+}
+
+func (c *resolvingCache) Exists(ctx context.Context, key string) (bool, error) {
+	c.record("Exists")
+	return c.MemoryCache.Exists(ctx, key)
+}
+
+func (c *resolvingCache) ResolveAndCount(ctx context.Context, req cache.ResolveRequest) (cache.Resolution, error) {
+	c.record("ResolveAndCount")
+	value, _ := c.MemoryCache.Get(ctx, req.Key)
+	if value == "" {
+		inactive, _ := c.MemoryCache.Exists(ctx, req.InactiveKey)
+		return cache.Resolution{Inactive: inactive}, nil
+	}
+	if entry, ok := cache.DecodeLinkEntry(value); ok && entry.Inactive {
+		return cache.Resolution{Value: value}, nil
+	}
+	visits := c.count(ctx, req.Counters)
+	return cache.Resolution{Value: value, Visits: visits}, nil
+}
+
+func (c *resolvingCache) CountVisit(ctx context.Context, counters cache.VisitCounters) error {
+	c.record("CountVisit")
+	c.count(ctx, counters)
+	return nil
+}
+
+func (c *resolvingCache) VisitCounts(ctx context.Context, counters cache.VisitCounters) (int64, int64, error) {
+	value, _ := c.MemoryCache.Get(ctx, counters.VisitsKey)
+	visits, _ := strconv.ParseInt(value, 10, 64)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return visits, int64(len(c.visitors[counters.VisitorsKey])), nil
+}
+
+func (c *resolvingCache) count(ctx context.Context, counters cache.VisitCounters) int64 {
+	value, _ := c.MemoryCache.Get(ctx, counters.VisitsKey)
+	visits, _ := strconv.ParseInt(value, 10, 64)
+	visits++
+	c.MemoryCache.Set(ctx, counters.VisitsKey, strconv.FormatInt(visits, 10), time.Until(counters.ExpireAt))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.visitors[counters.VisitorsKey] == nil {
+		c.visitors[counters.VisitorsKey] = map[string]bool{}
+	}
+	c.visitors[counters.VisitorsKey][counters.Visitor] = true
+	return visits
+}
+
+func setupResolvingTest(t *testing.T) (*URLServiceTestSuite, *resolvingCache) {
+	suite := setupURLServiceTest(t)
+	store := newResolvingCache()
+	suite.service = service.NewURLService(suite.repo, store, suite.cfg, suite.logger)
+	suite.repo.On("IncrementClickCount", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+	return suite, store
+}
+
+func TestResolve_CacheHitIsOneRoundTrip(t *testing.T) {
+	suite, store := setupResolvingTest(t)
+	ctx := context.Background()
+	require.NoError(t, store.MemoryCache.Set(ctx, cache.LinkKey("hot001"), "https://example.com", time.Hour))
+
+	destination, err := suite.service.GetOriginalURL(ctx, "hot001", domain.Visitor{IP: "192.0.2.1", UserAgent: "Mozilla/5.0"})
+
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com", destination)
+	assert.Equal(t, []string{"ResolveAndCount"}, store.redirectCalls(), "no separate Get, Exists or counter call")
+	suite.repo.AssertNotCalled(t, "FindByShortCode", mock.Anything, mock.Anything)
+}
+
+func TestResolve_NegativeCacheReadWithEntry(t *testing.T) {
+	suite, store := setupResolvingTest(t)
+	ctx := context.Background()
+	require.NoError(t, store.MemoryCache.Set(ctx, cache.InactiveKey("gone01"), "1", time.Minute))
+
+	_, err := suite.service.GetOriginalURL(ctx, "gone01", domain.Visitor{})
+
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+	assert.Equal(t, []string{"ResolveAndCount"}, store.redirectCalls())
+	suite.repo.AssertNotCalled(t, "FindByShortCode", mock.Anything, mock.Anything)
+}
+
+func TestResolve_CacheMissCountsVisitSeparately(t *testing.T) {
+	suite, store := setupResolvingTest(t)
+	ctx := context.Background()
+	suite.repo.On("FindByShortCode", mock.Anything, "cold01").
+		Return(&domain.URL{ShortCode: "cold01", OriginalURL: "https://example.com", IsActive: true}, nil)
+
+	_, err := suite.service.GetOriginalURL(ctx, "cold01", domain.Visitor{IP: "192.0.2.1"})
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ResolveAndCount", "CountVisit"}, store.redirectCalls())
+}
+
+func TestGetStats_VisitsToday(t *testing.T) {
+	suite, store := setupResolvingTest(t)
+	ctx := context.Background()
+	require.NoError(t, store.MemoryCache.Set(ctx, cache.LinkKey("hot001"), "https://example.com", time.Hour))
+
+	for _, ip := range []string{"192.0.2.1", "192.0.2.1", "192.0.2.2"} {
+		_, err := suite.service.GetOriginalURL(ctx, "hot001", domain.Visitor{IP: ip})
+		require.NoError(t, err)
+	}
+	suite.repo.On("GetStats", mock.Anything, "hot001").Return(&domain.URLStats{ShortCode: "hot001", IsActive: true}, nil)
+
+	stats, err := suite.service.GetStats(ctx, "hot001")
+
+	require.NoError(t, err)
+	require.NotNil(t, stats.Today)
+	assert.Equal(t, domain.VisitStats{Visits: 3, Visitors: 2}, *stats.Today)
+
+	keys := store.Keys("visits:hot001:")
+	require.Len(t, keys, 1)
+	assert.True(t, strings.HasSuffix(keys[0], time.Now().UTC().Format("20060102")))
+}
+
+func TestGetStats_NoVisitsWithoutResolver(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	suite.repo.On("GetStats", mock.Anything, "abc123").Return(&domain.URLStats{ShortCode: "abc123"}, nil)
+
+	stats, err := suite.service.GetStats(context.Background(), "abc123")
+
+	require.NoError(t, err)
+	assert.Nil(t, stats.Today)
+}
+
+func TestCircuitBreaker_ResolveUnsupported(t *testing.T) {
+	wrapped := cache.NewCircuitBreaker(cachetest.NewMemoryCache(), cache.BreakerConfig{})
+	resolver, ok := wrapped.(cache.Resolver)
+	require.True(t, ok)
+
+	_, err := resolver.ResolveAndCount(context.Background(), cache.ResolveRequest{Key: "abc123"})
+	assert.ErrorIs(t, err, cache.ErrResolveUnsupported)
+}
+
+func TestVisitorID_StableAndOpaque(t *testing.T) {
+	id := cache.VisitorID("192.0.2.1", "Mozilla/5.0")
+
+	assert.Len(t, id, 32)
+	assert.Equal(t, id, cache.VisitorID("192.0.2.1", "Mozilla/5.0"))
+	assert.NotEqual(t, id, cache.VisitorID("192.0.2.2", "Mozilla/5.0"))
+	assert.NotContains(t, id, "192")
+	assert.Equal(t, "click-dedup:abc123:"+id, cache.ClickDedupKey("abc123", "192.0.2.1", "Mozilla/5.0"))
+}
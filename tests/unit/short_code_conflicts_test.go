@@ -0,0 +1,124 @@
+package unit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/repository/postgres"
+	"url-shortener/internal/service"
+)
+
+// withShortCode matches the *domain.URL argument of Create by its code
+func withShortCode(code string) interface{} {
+	return mock.MatchedBy(func(u *domain.URL) bool { return u.ShortCode == code })
+}
+
+// uniqueURLRepository enforces a unique short_code on Create like the database index does
+// The first failFirst inserts report a conflict, as if another request had just taken the code
+type uniqueURLRepository struct {
+	*MockURLRepository
+	mu        sync.Mutex
+	codes     map[string]bool
+	attempts  []string
+	failFirst int
+}
+
+func newUniqueURLRepository(mockRepo *MockURLRepository) *uniqueURLRepository {
+	return &uniqueURLRepository{MockURLRepository: mockRepo, codes: map[string]bool{}}
+}
+
+func (r *uniqueURLRepository) Create(_ context.Context, url *domain.URL) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.attempts = append(r.attempts, url.ShortCode)
+	if len(r.attempts) <= r.failFirst || r.codes[url.ShortCode] {
+		return domain.ErrShortCodeTaken
+	}
+	r.codes[url.ShortCode] = true
+	return nil
+}
+
+// setupConflictTest builds a service on a repository with a unique short code, no dedup hits and a lenient cache
+func setupConflictTest(t *testing.T) (*URLServiceTestSuite, *uniqueURLRepository) {
+	suite := setupURLServiceTest(t)
+	repo := newUniqueURLRepository(suite.repo)
+	suite.service = service.NewURLService(repo, suite.cache, suite.cfg, suite.logger)
+
+	suite.repo.On("FindByOriginalURL", mock.Anything, mock.Anything).Return((*domain.URL)(nil), domain.ErrURLNotFound)
+	suite.cache.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	return suite, repo
+}
+
+func TestShortenURL_ConcurrentCustomAliasOneWins(t *testing.T) {
+	suite, _ := setupConflictTest(t)
+	const requests = 10
+
+	var wg sync.WaitGroup
+	errs := make([]error, requests)
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = suite.service.ShortenURL(context.Background(), &domain.CreateURLRequest{
+				URL:         fmt.Sprintf("https://example.com/%d", i),
+				CustomAlias: "race01",
+			}, domain.CreatorContext{IP: "192.168.1.1"})
+		}(i)
+	}
+	wg.Wait()
+
+	created, taken := 0, 0
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			created++
+		case assert.ErrorIs(t, err, domain.ErrShortCodeTaken):
+			taken++
+		}
+	}
+	assert.Equal(t, 1, created)
+	assert.Equal(t, requests-1, taken)
+	suite.repo.AssertNotCalled(t, "ExistsByShortCode", mock.Anything, mock.Anything)
+}
+
+func TestShortenURL_GeneratedCodeRetriedOnConflict(t *testing.T) {
+	suite, repo := setupConflictTest(t)
+	repo.failFirst = 2
+
+	resp, err := suite.service.ShortenURL(context.Background(), &domain.CreateURLRequest{URL: "https://example.com/raced"}, domain.CreatorContext{IP: "192.168.1.1"})
+
+	require.NoError(t, err)
+	require.Len(t, repo.attempts, 3)
+	assert.Equal(t, repo.attempts[2], resp.ShortCode, "the response carries the code that was stored")
+	assert.NotEqual(t, repo.attempts[0], repo.attempts[2])
+	suite.repo.AssertNotCalled(t, "ExistsByShortCode", mock.Anything, mock.Anything)
+}
+
+func TestShortenURL_GeneratedCodeGivesUpAfterRetries(t *testing.T) {
+	suite, repo := setupConflictTest(t)
+	repo.failFirst = 100
+
+	_, err := suite.service.ShortenURL(context.Background(), &domain.CreateURLRequest{URL: "https://example.com/raced"}, domain.CreatorContext{IP: "192.168.1.1"})
+
+	var appErr *domain.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, http.StatusInternalServerError, appErr.StatusCode)
+	assert.Len(t, repo.attempts, 5)
+}
+
+func TestURLRepositoryCreate_UniqueViolationIsShortCodeTaken(t *testing.T) {
+	repo := postgres.NewURLRepository(failingDB(t, &pgconn.PgError{Code: "23505", ConstraintName: "idx_urls_short_code"}))
+
+	err := repo.Create(context.Background(), &domain.URL{ShortCode: "abc123", OriginalURL: "https://example.com"})
+	assert.ErrorIs(t, err, domain.ErrShortCodeTaken)
+}
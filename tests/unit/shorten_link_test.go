@@ -0,0 +1,172 @@
+package unit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/apikey"
+	"url-shortener/internal/config"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/handler"
+	"url-shortener/pkg/signer"
+)
+
+const shortenLinkSecret = "bookmarklet-secret"
+
+// setupShortenLinkRouter wires GET /api/v1/shorten as in main, on a service that creates every link it's given
+func setupShortenLinkRouter(t *testing.T) (*gin.Engine, *URLServiceTestSuite) {
+	router, suite := setupWebFormRouter(t)
+	suite.cfg.APIKey = "bootstrap-key"
+	suite.cfg.ShortenLinkSecret = shortenLinkSecret
+	suite.cfg.ShortenLinkMaxSkew = 5 * time.Minute
+	suite.cfg.EnableWebUI = true
+
+	h := handler.NewURLHandler(suite.service, suite.cfg, suite.logger)
+	repo := new(MockAPIKeyRepository)
+	repo.On("ListActive", mock.Anything).Return([]domain.APIKey{issuedKey(3, "usk_extension", 0)}, nil)
+	keys := apikey.NewStore(repo, time.Minute, suite.logger)
+	router.GET("/api/v1/shorten", handler.ShortenLinkAuthMiddleware(suite.cfg, keys), h.ShortenLink)
+	return router, suite
+}
+
+// signedShortenLink builds the query of a link signed at ts
+func signedShortenLink(destination string, ts time.Time) url.Values {
+	return url.Values{
+		"url": {destination},
+		"ts":  {strconv.FormatInt(ts.Unix(), 10)},
+		"sig": {signer.New([]byte(shortenLinkSecret)).SignTimestamp(destination, ts)},
+	}
+}
+
+func getShortenLink(router *gin.Engine, query url.Values) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/shorten?"+query.Encode(), nil))
+	return w
+}
+
+func TestSigner_VerifyTimestamp(t *testing.T) {
+	s := signer.New([]byte("secret"))
+	now := time.Unix(1767225600, 0)
+	sig := s.SignTimestamp("https://example.com", now)
+	ts := strconv.FormatInt(now.Unix(), 10)
+
+	assert.NoError(t, s.VerifyTimestamp(sig, "https://example.com", ts, now.Add(time.Minute), 5*time.Minute))
+	assert.NoError(t, s.VerifyTimestamp(sig, "https://example.com", ts, now.Add(-time.Minute), 5*time.Minute), "slightly behind clocks are fine")
+	assert.ErrorIs(t, s.VerifyTimestamp(sig, "https://example.com", ts, now.Add(6*time.Minute), 5*time.Minute), signer.ErrTokenExpired)
+	assert.ErrorIs(t, s.VerifyTimestamp(sig, "https://example.com", ts, now.Add(-6*time.Minute), 5*time.Minute), signer.ErrTokenExpired)
+	assert.ErrorIs(t, s.VerifyTimestamp(sig, "https://example.org", ts, now, 5*time.Minute), signer.ErrInvalidToken)
+	assert.ErrorIs(t, s.VerifyTimestamp(sig, "https://example.com", "1767225601", now, 5*time.Minute), signer.ErrInvalidToken)
+	assert.ErrorIs(t, s.VerifyTimestamp(sig, "https://example.com", "soon", now, 5*time.Minute), signer.ErrInvalidToken)
+	assert.ErrorIs(t, s.VerifyTimestamp("", "https://example.com", ts, now, 5*time.Minute), signer.ErrInvalidToken)
+}
+
+func TestShortenLink_SignedLinkCreates(t *testing.T) {
+	router, suite := setupShortenLinkRouter(t)
+
+	w := getShortenLink(router, signedShortenLink("https://example.com/shared?a=1&b=2", time.Now()))
+	drainCacheWrites(t, suite)
+
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+	var body domain.CreateURLResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "https://example.com/shared?a=1&b=2", body.OriginalURL)
+}
+
+func TestShortenLink_RefusesBadOrReplayedSignatures(t *testing.T) {
+	router, suite := setupShortenLinkRouter(t)
+
+	tampered := signedShortenLink("https://example.com", time.Now())
+	tampered.Set("url", "https://attacker.example")
+	cases := map[string]url.Values{
+		"tampered url": tampered,
+		"old link":     signedShortenLink("https://example.com", time.Now().Add(-10*time.Minute)),
+		"future link":  signedShortenLink("https://example.com", time.Now().Add(10*time.Minute)),
+	}
+	for name, query := range cases {
+		w := getShortenLink(router, query)
+		assert.Equal(t, http.StatusUnauthorized, w.Code, name)
+		assert.Contains(t, w.Body.String(), "invalid_signature", name)
+		assert.Equal(t, "no-store", w.Header().Get("Cache-Control"), name)
+	}
+
+	w := getShortenLink(router, url.Values{"url": {"https://example.com"}})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "unauthorized")
+	suite.repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestShortenLink_APIKey(t *testing.T) {
+	router, suite := setupShortenLinkRouter(t)
+
+	w := getShortenLink(router, url.Values{"url": {"https://example.com/k"}, "key": {"bootstrap-key"}})
+	drainCacheWrites(t, suite)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	w = getShortenLink(router, url.Values{"url": {"https://example.com/i"}, "key": {"usk_extension"}})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	w = getShortenLink(router, url.Values{"url": {"https://example.com/k"}, "key": {"wrong"}})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestShortenLink_SignaturesNeedTheSecret(t *testing.T) {
+	router, suite := setupShortenLinkRouter(t)
+	suite.cfg.ShortenLinkSecret = ""
+	router.GET("/unsigned", handler.ShortenLinkAuthMiddleware(suite.cfg, nil), func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/unsigned?"+signedShortenLink("https://example.com", time.Now()).Encode(), nil))
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "?key=")
+}
+
+func TestShortenLink_RedirectsToResultPage(t *testing.T) {
+	router, suite := setupShortenLinkRouter(t)
+
+	query := signedShortenLink("https://example.com/page", time.Now())
+	query.Set("redirect", "true")
+	w := getShortenLink(router, query)
+	drainCacheWrites(t, suite)
+
+	require.Equal(t, http.StatusSeeOther, w.Code, w.Body.String())
+	location, err := url.Parse(w.Header().Get("Location"))
+	require.NoError(t, err)
+	assert.Equal(t, "/", location.Path)
+	code := location.Query().Get("created")
+	require.NotEmpty(t, code)
+
+	suite.repo.On("FindByShortCode", mock.Anything, code).
+		Return(&domain.URL{ShortCode: code, OriginalURL: "https://example.com/page", IsActive: true}, nil)
+	page := httptest.NewRecorder()
+	router.ServeHTTP(page, httptest.NewRequest(http.MethodGet, w.Header().Get("Location"), nil))
+	assert.Contains(t, page.Body.String(), "https://example.com/page")
+	assert.Contains(t, page.Body.String(), code)
+
+	query.Set("redirect", "maybe")
+	assert.Equal(t, http.StatusBadRequest, getShortenLink(router, query).Code)
+
+	suite.cfg.EnableWebUI = false
+	query.Set("redirect", "true")
+	assert.Equal(t, http.StatusBadRequest, getShortenLink(router, query).Code)
+}
+
+func TestValidate_ShortenLinkMaxSkew(t *testing.T) {
+	cfg, err := config.LoadFrom(nil)
+	require.NoError(t, err)
+	assert.Equal(t, 5*time.Minute, cfg.ShortenLinkMaxSkew)
+
+	cfg.ShortenLinkMaxSkew = 0
+	assert.ErrorContains(t, cfg.Validate(), "SHORTEN_LINK_MAX_SKEW_SECONDS")
+}
@@ -0,0 +1,125 @@
+package unit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/handler"
+	"url-shortener/pkg/validator"
+)
+
+func TestEscapeMarkdown(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"Spring sale", "Spring sale"},
+		{"50% off *today*", `50% off \*today\*`},
+		{"[x](javascript:alert(1))", `\[x\]\(javascript:alert\(1\)\)`},
+		{"<img src=x onerror=alert(1)>", `\<img src=x onerror=alert\(1\)\>`},
+		{"![pixel](https://evil.test/p.gif)", `\!\[pixel\]\(https://evil.test/p.gif\)`},
+		{`back\slash _under_ ` + "`code`", `back\\slash \_under\_ ` + "\\`code\\`"},
+		{"&lt;script&gt; #1 | ~x~", `\&lt;script\&gt; \#1 \| \~x\~`},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, validator.EscapeMarkdown(tt.input), tt.input)
+	}
+}
+
+func TestLinkLabel(t *testing.T) {
+	page := "Example Domain"
+	blank := "  "
+
+	assert.Equal(t, "Spring sale", domain.LinkLabel(" Spring sale ", &page, "https://example.com/a"))
+	assert.Equal(t, "Example Domain", domain.LinkLabel("", &page, "https://example.com/a"))
+	assert.Equal(t, "shop.example.com", domain.LinkLabel("", &blank, "https://shop.example.com:8443/a?b=c"))
+	assert.Equal(t, "mailto:team@example.com", domain.LinkLabel("", nil, "mailto:team@example.com"))
+}
+
+// setupSnippetsRouter serves shorten and link details as in main
+func setupSnippetsRouter(t *testing.T) (*URLServiceTestSuite, *gin.Engine) {
+	gin.SetMode(gin.TestMode)
+	suite := setupURLServiceTest(t)
+	suite.repo.On("FindByOriginalURL", mock.Anything, mock.Anything).Return(nil, domain.ErrURLNotFound).Maybe()
+	suite.repo.On("Create", mock.Anything, mock.Anything).Return(nil).Maybe()
+	suite.cache.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+
+	h := handler.NewURLHandler(suite.service, suite.cfg, suite.logger)
+	router := gin.New()
+	router.POST("/api/v1/shorten", h.ShortenURL)
+	router.GET("/api/v1/urls/:shortCode", h.GetURLInfo)
+	return suite, router
+}
+
+func decodeSnippets(t *testing.T, w *httptest.ResponseRecorder) *domain.Snippets {
+	var body struct {
+		Snippets *domain.Snippets `json:"snippets"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body), w.Body.String())
+	return body.Snippets
+}
+
+func TestShortenURL_Snippets(t *testing.T) {
+	_, router := setupSnippetsRouter(t)
+
+	// The stored title is plain text already, but still has to be escaped for each format
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, shortenRequest(`{"url":"https://example.com/sale","custom_alias":"sale01","include_snippets":true,`+
+		`"title":"Sale \"50%\" & <b>more</b> [x](javascript:alert(1))"}`))
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	snippets := decodeSnippets(t, w)
+	require.NotNil(t, snippets)
+	assert.Equal(t, `[Sale "50%" \& more \[x\]\(javascript:alert\(1\)\)](https://short.url/sale01)`, snippets.Markdown)
+	assert.Equal(t, `<a href="https://short.url/sale01">Sale &#34;50%&#34; &amp; more [x](javascript:alert(1))</a>`, snippets.HTML)
+	assert.Equal(t, `Sale "50%" & more [x](javascript:alert(1)): https://short.url/sale01`, snippets.Text)
+
+	// Without a title the destination host stands in; the query parameter works like the body flag
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, shortenRequest(`{"url":"https://docs.example.com/guide","custom_alias":"guide1"}`))
+	assert.Nil(t, decodeSnippets(t, w), "only when asked for")
+	assert.NotContains(t, w.Body.String(), "snippets")
+
+	w = httptest.NewRecorder()
+	req := shortenRequest(`{"url":"https://docs.example.com/guide","custom_alias":"guide2"}`)
+	req.URL.RawQuery = "include_snippets=true"
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "[docs.example.com](https://short.url/guide2)", decodeSnippets(t, w).Markdown)
+
+	w = httptest.NewRecorder()
+	req = shortenRequest(`{"url":"https://docs.example.com/guide"}`)
+	req.URL.RawQuery = "include_snippets=maybe"
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestGetURLInfo_SnippetsFromPageTitle(t *testing.T) {
+	suite, router := setupSnippetsRouter(t)
+	pageTitle := `Docs </title><script>alert("x")</script> - Example`
+	suite.repo.On("FindByShortCode", mock.Anything, "docs01").
+		Return(&domain.URL{ShortCode: "docs01", OriginalURL: "https://docs.example.com", IsActive: true, PageTitle: &pageTitle}, nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/urls/docs01?include_snippets=1", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	snippets := decodeSnippets(t, w)
+	require.NotNil(t, snippets)
+
+	// The fetched title is untrusted: tags are dropped and what is left is escaped
+	assert.Equal(t, `<a href="https://short.url/docs01">Docs alert(&#34;x&#34;) - Example</a>`, snippets.HTML)
+	assert.Equal(t, `[Docs alert\("x"\) - Example](https://short.url/docs01)`, snippets.Markdown)
+	assert.Equal(t, `Docs alert("x") - Example: https://short.url/docs01`, snippets.Text)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/urls/docs01", nil))
+	assert.Nil(t, decodeSnippets(t, w))
+}
@@ -0,0 +1,160 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/cache"
+	"url-shortener/internal/cache/cachetest"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/service"
+)
+
+// setupStaleCacheTest returns a service with a 30 minute stale window over a memory cache holding a stale abc123
+func setupStaleCacheTest(t *testing.T, url *domain.URL) (*URLServiceTestSuite, *cachetest.MemoryCache, service.URLService) {
+	suite := setupURLServiceTest(t)
+	suite.cfg.CacheStaleTTL = 30 * time.Minute
+	suite.repo.On("IncrementClickCount", mock.Anything, "abc123", mock.Anything).Return(nil).Maybe()
+
+	entry := cache.NewLinkEntry(url)
+	freshUntil := time.Now().Add(-time.Minute)
+	entry.FreshUntil = &freshUntil
+	store := cachetest.NewMemoryCache()
+	require.NoError(t, store.Set(context.Background(), cache.LinkKey("abc123"), entry.Encode(), 29*time.Minute))
+
+	return suite, store, service.NewURLService(suite.repo, store, suite.cfg, suite.logger)
+}
+
+func TestLinkEntry_Stale(t *testing.T) {
+	freshUntil := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	entry := cache.NewLinkEntry(&domain.URL{OriginalURL: "https://example.com", IsActive: true})
+	entry.FreshUntil = &freshUntil
+
+	decoded, ok := cache.DecodeLinkEntry(entry.Encode())
+	require.True(t, ok)
+	require.NotNil(t, decoded.FreshUntil, "a soft TTL makes a plain link JSON")
+	assert.False(t, decoded.Stale(freshUntil))
+	assert.True(t, decoded.Stale(freshUntil.Add(time.Second)))
+
+	// Bare URLs and entries written without a soft TTL never go stale
+	decoded, ok = cache.DecodeLinkEntry("https://example.com")
+	require.True(t, ok)
+	assert.False(t, decoded.Stale(time.Now()))
+}
+
+func TestCacheLink_SoftTTLInsideHardTTL(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	suite.cfg.CacheStaleTTL = 30 * time.Minute
+	store := cachetest.NewMemoryCache()
+	svc := service.NewURLService(suite.repo, store, suite.cfg, suite.logger)
+	suite.repo.On("FindByShortCode", mock.Anything, "abc123").Return(&domain.URL{ShortCode: "abc123", OriginalURL: "https://example.com", IsActive: true}, nil)
+	suite.repo.On("IncrementClickCount", mock.Anything, "abc123", mock.Anything).Return(nil)
+
+	_, err := svc.GetOriginalURL(context.Background(), "abc123", domain.Visitor{})
+	require.NoError(t, err)
+	require.NoError(t, svc.Close(context.Background()))
+
+	assert.InDelta(t, (90 * time.Minute).Seconds(), store.TTL(cache.LinkKey("abc123")).Seconds(), 5, "Redis keeps the entry for CACHE_TTL plus the stale window")
+	value, _ := store.Get(context.Background(), cache.LinkKey("abc123"))
+	entry, ok := cache.DecodeLinkEntry(value)
+	require.True(t, ok)
+	require.NotNil(t, entry.FreshUntil)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), *entry.FreshUntil, 5*time.Second)
+}
+
+func TestResolve_StaleEntryServedWhileOneRefreshRuns(t *testing.T) {
+	suite, store, svc := setupStaleCacheTest(t, &domain.URL{ShortCode: "abc123", OriginalURL: "https://old.example.com", IsActive: true})
+	release := make(chan time.Time)
+	suite.repo.On("FindByShortCode", mock.Anything, "abc123").
+		Return(&domain.URL{ShortCode: "abc123", OriginalURL: "https://new.example.com", IsActive: true}, nil).
+		WaitUntil(release)
+
+	// Every hit is answered from the stale entry while the database read is held up
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			original, err := svc.GetOriginalURL(context.Background(), "abc123", domain.Visitor{})
+			assert.NoError(t, err)
+			assert.Equal(t, "https://old.example.com", original)
+		}()
+	}
+	wg.Wait()
+
+	close(release)
+	require.NoError(t, svc.Close(context.Background()))
+
+	suite.repo.AssertNumberOfCalls(t, "FindByShortCode", 1)
+	value, _ := store.Get(context.Background(), cache.LinkKey("abc123"))
+	entry, ok := cache.DecodeLinkEntry(value)
+	require.True(t, ok)
+	assert.Equal(t, "https://new.example.com", entry.URL)
+	assert.False(t, entry.Stale(time.Now()))
+}
+
+func TestResolve_StaleEntryKeptWhenRefreshFails(t *testing.T) {
+	suite, store, svc := setupStaleCacheTest(t, &domain.URL{ShortCode: "abc123", OriginalURL: "https://old.example.com", IsActive: true})
+	suite.repo.On("FindByShortCode", mock.Anything, "abc123").Return(nil, domain.NewInternalError(errors.New("connection refused")))
+
+	for i := 0; i < 2; i++ {
+		original, err := svc.GetOriginalURL(context.Background(), "abc123", domain.Visitor{})
+		require.NoError(t, err, "the database being down doesn't fail a redirect the cache can still answer")
+		assert.Equal(t, "https://old.example.com", original)
+
+		// Close waits for the refresh; the service still redirects afterwards, so the next hit tries again
+		require.NoError(t, svc.Close(context.Background()))
+		suite.repo.AssertNumberOfCalls(t, "FindByShortCode", i+1)
+	}
+
+	value, _ := store.Get(context.Background(), cache.LinkKey("abc123"))
+	entry, ok := cache.DecodeLinkEntry(value)
+	require.True(t, ok, "the stale entry stays until Redis expires it")
+	assert.Equal(t, "https://old.example.com", entry.URL)
+	assert.True(t, entry.Stale(time.Now()))
+	assert.Greater(t, store.TTL(cache.LinkKey("abc123")), 28*time.Minute, "a failed refresh doesn't extend the hard TTL")
+}
+
+func TestResolve_StaleRefreshDropsRemovedLink(t *testing.T) {
+	suite, store, svc := setupStaleCacheTest(t, &domain.URL{ShortCode: "abc123", OriginalURL: "https://old.example.com", IsActive: true})
+	suite.repo.On("FindByShortCode", mock.Anything, "abc123").Return(nil, domain.ErrURLNotFound)
+
+	_, err := svc.GetOriginalURL(context.Background(), "abc123", domain.Visitor{})
+	require.NoError(t, err)
+	require.NoError(t, svc.Close(context.Background()))
+
+	exists, err := store.Exists(context.Background(), cache.LinkKey("abc123"))
+	require.NoError(t, err)
+	assert.False(t, exists, "the next hit goes to the database and gets the 404")
+}
+
+func TestResolve_StaleInactiveOrExpiredEntryBypassesRefresh(t *testing.T) {
+	expired := time.Now().Add(-time.Second)
+
+	tests := []struct {
+		name string
+		url  *domain.URL
+		err  error
+	}{
+		{"inactive", &domain.URL{ShortCode: "abc123", OriginalURL: "https://example.com"}, domain.ErrURLNotFound},
+		{"expired", &domain.URL{ShortCode: "abc123", OriginalURL: "https://example.com", IsActive: true, ExpiresAt: &expired}, domain.ErrURLExpired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			suite, _, svc := setupStaleCacheTest(t, tt.url)
+
+			_, err := svc.GetOriginalURL(context.Background(), "abc123", domain.Visitor{})
+			require.NoError(t, svc.Close(context.Background()))
+
+			assert.ErrorIs(t, err, tt.err)
+			suite.repo.AssertNotCalled(t, "FindByShortCode", mock.Anything, mock.Anything)
+		})
+	}
+}
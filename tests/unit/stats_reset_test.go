@@ -0,0 +1,171 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/apikey"
+	"url-shortener/internal/cache"
+	"url-shortener/internal/cache/cachetest"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/handler"
+	"url-shortener/internal/service"
+)
+
+// setupStatsResetRouter wires the reset endpoint as in main, on a service with a resolving cache and an audit trail
+func setupStatsResetRouter(t *testing.T) (*URLServiceTestSuite, *resolvingCache, *MockAuditRepository, *gin.Engine) {
+	gin.SetMode(gin.TestMode)
+	suite := setupURLServiceTest(t)
+	suite.cfg.AdminAPIKey = "admin-secret"
+	store := newResolvingCache()
+	audit := new(MockAuditRepository)
+	suite.service = service.NewURLService(suite.repo, store, suite.cfg, suite.logger, service.WithAuditRepository(audit))
+	h := handler.NewURLHandler(suite.service, suite.cfg, suite.logger)
+	keys := apikey.NewStore(new(MockAPIKeyRepository), 0, suite.logger)
+
+	router := gin.New()
+	router.POST("/api/v1/urls/:shortCode/stats/reset", handler.APIKeyIdentityMiddleware(suite.cfg, keys),
+		handler.ManagementAuthMiddleware(suite.cfg, keys), h.ResetStats)
+	return suite, store, audit, router
+}
+
+func resetRequest(headers map[string]string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/urls/abc123/stats/reset", nil)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	return req
+}
+
+func TestResetStats_ZeroesAndAuditsPriorTotals(t *testing.T) {
+	suite, store, audit, router := setupStatsResetRouter(t)
+	ctx := context.Background()
+	link := &domain.URL{ShortCode: "abc123", OriginalURL: "https://example.com", IsActive: true, ManagementTokenHash: managementTokenHash(testManagementToken)}
+	suite.repo.On("FindAnyByShortCode", mock.Anything, "abc123").Return(link, nil)
+	suite.repo.On("ResetStats", mock.Anything, "abc123").
+		Return(&domain.URL{ShortCode: "abc123", ClickCount: 120, BotClicks: 4, PrefetchHits: 2, FilteredClicks: 9}, nil)
+	suite.repo.On("GetStats", mock.Anything, "abc123").Return(&domain.URLStats{ShortCode: "abc123", IsActive: true}, nil)
+	audit.On("Record", mock.Anything, mock.AnythingOfType("*domain.AuditEntry")).Return(nil)
+
+	// Today's visit counter exists; yesterday's doesn't, which must not fail the reset
+	counters := cache.VisitCounters{
+		VisitsKey:   cache.VisitsKey("abc123", time.Now()),
+		VisitorsKey: cache.VisitorsKey("abc123", time.Now()),
+		Visitor:     "v1",
+		ExpireAt:    time.Now().Add(time.Hour),
+	}
+	require.NoError(t, store.CountVisit(ctx, counters))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, resetRequest(map[string]string{"X-Management-Token": testManagementToken}))
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var stats domain.URLStats
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &stats))
+	assert.Zero(t, stats.TotalClicks)
+	require.NotNil(t, stats.Today)
+	assert.Zero(t, stats.Today.Visits, "the Redis counter is gone")
+	assert.Empty(t, store.Keys("visits:abc123:"))
+
+	entry := audit.Calls[0].Arguments.Get(1).(*domain.AuditEntry)
+	assert.Equal(t, domain.AuditActionStatsReset, entry.Action)
+	assert.Equal(t, "management_token", entry.ActorID)
+	assert.Equal(t, "click_count=120 bot_clicks=4 prefetch_hits=2 filtered_clicks=9", entry.Details)
+}
+
+func TestResetStats_Authorization(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+		status  int
+		actor   string
+	}{
+		{"matching token", map[string]string{"X-Management-Token": testManagementToken}, http.StatusOK, "management_token"},
+		{"admin key", map[string]string{"X-API-Key": "admin-secret"}, http.StatusOK, "admin:"},
+		{"wrong token", map[string]string{"X-Management-Token": "guess"}, http.StatusForbidden, ""},
+		{"no token", nil, http.StatusUnauthorized, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			suite, _, audit, router := setupStatsResetRouter(t)
+			suite.repo.On("FindAnyByShortCode", mock.Anything, "abc123").
+				Return(&domain.URL{ShortCode: "abc123", IsActive: true, ManagementTokenHash: managementTokenHash(testManagementToken)}, nil)
+			suite.repo.On("ResetStats", mock.Anything, "abc123").Return(&domain.URL{ShortCode: "abc123"}, nil)
+			suite.repo.On("GetStats", mock.Anything, "abc123").Return(&domain.URLStats{ShortCode: "abc123"}, nil)
+			audit.On("Record", mock.Anything, mock.AnythingOfType("*domain.AuditEntry")).Return(nil)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, resetRequest(tt.headers))
+
+			assert.Equal(t, tt.status, w.Code, w.Body.String())
+			if tt.status != http.StatusOK {
+				suite.repo.AssertNotCalled(t, "ResetStats", mock.Anything, mock.Anything)
+				return
+			}
+			entry := audit.Calls[0].Arguments.Get(1).(*domain.AuditEntry)
+			assert.Contains(t, entry.ActorID, tt.actor)
+		})
+	}
+}
+
+func TestResetStats_UnknownLink(t *testing.T) {
+	suite, _, _, router := setupStatsResetRouter(t)
+	suite.repo.On("ResetStats", mock.Anything, "abc123").Return(nil, domain.ErrURLNotFound)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, resetRequest(map[string]string{"X-API-Key": "admin-secret"}))
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	suite.repo.AssertNotCalled(t, "GetStats", mock.Anything, mock.Anything)
+}
+
+func TestResetStats_CachedClickStatsAreNotServedAfterwards(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	clicks := new(MockClickRepository)
+	svc := service.NewURLService(suite.repo, cachetest.NewMemoryCache(), suite.cfg, suite.logger, service.WithClickRepository(clicks))
+	ctx := context.Background()
+	from := time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)
+	query := domain.TimeSeriesQuery{Granularity: domain.GranularityHour, From: from, To: from.Add(2 * time.Hour)}
+	heatmapQuery := domain.HeatmapQuery{From: from, To: query.To}
+
+	suite.repo.On("FindAnyByShortCode", mock.Anything, "abc123").Return(&domain.URL{ID: 1, ShortCode: "abc123"}, nil).Twice()
+	clicks.On("CountByBucket", ctx, "abc123", domain.GranularityHour, from, query.To).
+		Return([]domain.ClickBucket{{BucketStart: from, Clicks: 7}}, nil).Once()
+	series, err := svc.GetClickTimeSeries(ctx, "abc123", query)
+	require.NoError(t, err)
+	require.Equal(t, int64(7), series[0].Clicks)
+	clicks.On("CountByWeekdayHour", ctx, "abc123", from, query.To, time.UTC).
+		Return([]domain.HeatmapCell{{Weekday: 1, Hour: 0, Clicks: 7}}, nil).Once()
+	heatmap, err := svc.GetClickHeatmap(ctx, "abc123", heatmapQuery)
+	require.NoError(t, err)
+	require.Equal(t, int64(7), heatmap.Total)
+
+	suite.repo.On("ResetStats", mock.Anything, "abc123").Return(&domain.URL{ID: 1, ShortCode: "abc123", ClickCount: 7}, nil)
+	suite.repo.On("GetStats", mock.Anything, "abc123").Return(&domain.URLStats{ShortCode: "abc123"}, nil)
+	clicks.On("CountByTarget", mock.Anything, "abc123").Return(map[string]int64{}, nil)
+	expectEmptyDailySeries(clicks, "abc123")
+	_, err = svc.ResetStats(ctx, "abc123", domain.Actor{ID: "admin:test"})
+	require.NoError(t, err)
+
+	resetAt := time.Now()
+	suite.repo.On("FindAnyByShortCode", mock.Anything, "abc123").Return(&domain.URL{ID: 1, ShortCode: "abc123", StatsResetAt: &resetAt}, nil)
+	clicks.On("CountByBucket", ctx, "abc123", domain.GranularityHour, from, query.To).Return([]domain.ClickBucket{}, nil).Once()
+	series, err = svc.GetClickTimeSeries(ctx, "abc123", query)
+	require.NoError(t, err)
+	assert.Zero(t, series[0].Clicks, "the series cached before the reset is not read")
+	clicks.On("CountByWeekdayHour", ctx, "abc123", from, query.To, time.UTC).Return([]domain.HeatmapCell{}, nil).Once()
+	heatmap, err = svc.GetClickHeatmap(ctx, "abc123", heatmapQuery)
+	require.NoError(t, err)
+	assert.Zero(t, heatmap.Total, "nor is the heat map")
+	clicks.AssertExpectations(t)
+}
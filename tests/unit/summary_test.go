@@ -0,0 +1,114 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/handler"
+)
+
+// mockSummaryQueries registers the aggregate queries with a link created today
+func mockSummaryQueries(suite *URLServiceTestSuite) {
+	today := time.Now().UTC().Format("2006-01-02")
+
+	suite.repo.On("CountURLs", mock.Anything).Return(int64(42), nil)
+	suite.repo.On("SumClicks", mock.Anything).Return(int64(1000), nil)
+	suite.repo.On("TopByClicks", mock.Anything, mock.AnythingOfType("time.Time"), 10).
+		Return([]domain.TopLink{{ShortCode: "abc123", OriginalURL: "https://example.com", Clicks: 77}}, nil)
+	suite.repo.On("CreatedBetween", mock.Anything, mock.AnythingOfType("time.Time"), mock.AnythingOfType("time.Time")).
+		Return([]domain.DailyCount{{Date: today, Count: 3}}, nil)
+}
+
+func TestGetSummary_FillsDailySeries(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	mockSummaryQueries(suite)
+
+	summary, err := suite.service.GetSummary(context.Background(), domain.SummaryWindow{Days: 7, Limit: 10})
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(42), summary.TotalURLs)
+	assert.Equal(t, int64(1000), summary.TotalClicks)
+	assert.Equal(t, int64(3), summary.CreatedToday)
+	assert.Len(t, summary.TopLinks, 1)
+
+	// One entry per day, oldest first, ending today
+	require.Len(t, summary.CreatedByDay, 7)
+	assert.Equal(t, summary.Since.Format("2006-01-02"), summary.CreatedByDay[0].Date)
+	assert.Equal(t, int64(0), summary.CreatedByDay[0].Count)
+	assert.Equal(t, int64(3), summary.CreatedByDay[6].Count)
+
+	// The top-links window starts at midnight UTC six days ago
+	since := suite.repo.Calls[2].Arguments.Get(1).(time.Time)
+	assert.Equal(t, summary.Since, since)
+	assert.Equal(t, 0, since.Hour())
+}
+
+func TestGetSummary_ServedFromCache(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	suite.cfg.SummaryCacheTTL = time.Minute
+	ctx := context.Background()
+
+	// First call computes and caches the aggregates
+	mockSummaryQueries(suite)
+	suite.cache.On("Get", ctx, "stats:summary:7:10").Return("", nil).Once()
+	suite.cache.On("Set", mock.Anything, "stats:summary:7:10", mock.AnythingOfType("string"), time.Minute).Return(nil).Once()
+
+	first, err := suite.service.GetSummary(ctx, domain.SummaryWindow{Days: 7, Limit: 10})
+	require.NoError(t, err)
+
+	// Second call is answered from the cached JSON without touching the database
+	cached := suite.cache.Calls[len(suite.cache.Calls)-1].Arguments.String(2)
+	suite.cache.On("Get", ctx, "stats:summary:7:10").Return(cached, nil).Once()
+
+	second, err := suite.service.GetSummary(ctx, domain.SummaryWindow{Days: 7, Limit: 10})
+	require.NoError(t, err)
+
+	assert.Equal(t, first.TotalURLs, second.TotalURLs)
+	assert.Equal(t, first.CreatedByDay, second.CreatedByDay)
+	suite.repo.AssertNumberOfCalls(t, "CountURLs", 1)
+	suite.cache.AssertExpectations(t)
+}
+
+func TestGetSummaryHandler_Validation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	suite := setupURLServiceTest(t)
+	mockSummaryQueries(suite)
+
+	router := gin.New()
+	router.GET("/api/v1/stats/summary", handler.NewURLHandler(suite.service, suite.cfg, suite.logger).GetSummary)
+
+	tests := []struct {
+		name  string
+		query string
+		code  int
+	}{
+		{name: "defaults", query: "", code: http.StatusOK},
+		{name: "non-numeric days", query: "?days=week", code: http.StatusBadRequest},
+		{name: "days out of range", query: "?days=365", code: http.StatusBadRequest},
+		{name: "zero limit", query: "?limit=0", code: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/stats/summary"+tt.query, nil))
+			assert.Equal(t, tt.code, w.Code)
+
+			if tt.code == http.StatusOK {
+				var summary domain.SummaryStats
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &summary))
+				assert.Len(t, summary.CreatedByDay, 7)
+			}
+		})
+	}
+}
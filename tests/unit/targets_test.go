@@ -0,0 +1,245 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/handler"
+	"url-shortener/internal/redirect"
+	"url-shortener/internal/service"
+)
+
+const (
+	iPhoneUA  = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 Mobile/15E148"
+	androidUA = "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 Chrome/120.0 Mobile Safari/537.36"
+	desktopUA = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 Chrome/120.0 Safari/537.36"
+)
+
+// MockClickRepository is a mock implementation of ClickRepository
+type MockClickRepository struct {
+	mock.Mock
+}
+
+func (m *MockClickRepository) Record(ctx context.Context, event *domain.ClickEvent) error {
+	args := m.Called(ctx, event)
+	return args.Error(0)
+}
+
+func (m *MockClickRepository) CountByTarget(ctx context.Context, shortCode string) (map[string]int64, error) {
+	args := m.Called(ctx, shortCode)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[string]int64), args.Error(1)
+}
+
+func (m *MockClickRepository) CountByVariant(ctx context.Context, shortCode string) (map[int]int64, error) {
+	args := m.Called(ctx, shortCode)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[int]int64), args.Error(1)
+}
+
+func (m *MockClickRepository) RollupDay(ctx context.Context, day time.Time) (int64, error) {
+	args := m.Called(ctx, day)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockClickRepository) GetDailySeries(ctx context.Context, shortCode string, from, to time.Time) ([]domain.DailyClickStats, error) {
+	args := m.Called(ctx, shortCode, from, to)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.DailyClickStats), args.Error(1)
+}
+
+func (m *MockClickRepository) AggregateDay(ctx context.Context, shortCode string, day time.Time) (*domain.DailyClickStats, error) {
+	args := m.Called(ctx, shortCode, day)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.DailyClickStats), args.Error(1)
+}
+
+func (m *MockClickRepository) CountByBucket(ctx context.Context, shortCode string, granularity domain.Granularity, from, to time.Time) ([]domain.ClickBucket, error) {
+	args := m.Called(ctx, shortCode, granularity, from, to)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.ClickBucket), args.Error(1)
+}
+
+func (m *MockClickRepository) SumDailyByBucket(ctx context.Context, shortCode string, granularity domain.Granularity, from, to time.Time) ([]domain.ClickBucket, error) {
+	args := m.Called(ctx, shortCode, granularity, from, to)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.ClickBucket), args.Error(1)
+}
+
+func (m *MockClickRepository) CountByWeekdayHour(ctx context.Context, shortCode string, from, to time.Time, loc *time.Location) ([]domain.HeatmapCell, error) {
+	args := m.Called(ctx, shortCode, from, to, loc)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.HeatmapCell), args.Error(1)
+}
+
+var appTargets = domain.Targets{
+	{Platform: redirect.PlatformIOS, URL: "https://apps.apple.com/app/id1"},
+	{Platform: redirect.PlatformAndroid, URL: "https://play.google.com/store/apps/details?id=app"},
+}
+
+func TestDetectPlatform(t *testing.T) {
+	tests := map[string]string{
+		iPhoneUA:  redirect.PlatformIOS,
+		androidUA: redirect.PlatformAndroid,
+		desktopUA: redirect.PlatformWindows,
+		"Mozilla/5.0 (Macintosh; Intel Mac OS X 14_0) AppleWebKit/605.1.15": redirect.PlatformMacOS,
+		"Mozilla/5.0 (X11; Linux x86_64) Gecko/20100101 Firefox/120.0":      redirect.PlatformLinux,
+		"curl/8.0": "",
+		"":         "",
+	}
+
+	for ua, expected := range tests {
+		assert.Equal(t, expected, redirect.DetectPlatform(ua), ua)
+	}
+}
+
+func TestEvaluate_FallsBackToDefault(t *testing.T) {
+	result := redirect.Evaluate(appTargets, "https://example.com", domain.Visitor{UserAgent: iPhoneUA})
+	assert.Equal(t, "https://apps.apple.com/app/id1", result.Destination)
+	assert.Equal(t, redirect.PlatformIOS, result.Target)
+
+	result = redirect.Evaluate(appTargets, "https://example.com", domain.Visitor{UserAgent: desktopUA})
+	assert.Equal(t, "https://example.com", result.Destination)
+	assert.Equal(t, redirect.DefaultTarget, result.Target)
+}
+
+func TestShortenURL_InvalidTargets(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	ctx := context.Background()
+
+	for _, targets := range [][]domain.Target{
+		{{Platform: "blackberry", URL: "https://example.com/bb"}},
+		{{Platform: "ios", URL: "not a url"}},
+		{{Platform: "ios", URL: "https://a.example"}, {Platform: "ios", URL: "https://b.example"}},
+	} {
+		_, err := suite.service.ShortenURL(ctx, &domain.CreateURLRequest{URL: "https://example.com", Targets: targets}, domain.CreatorContext{IP: "127.0.0.1"})
+
+		var appErr *domain.AppError
+		require.ErrorAs(t, err, &appErr)
+		assert.Equal(t, http.StatusBadRequest, appErr.StatusCode)
+	}
+
+	suite.repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestGetOriginalURL_TargetsSurviveCache(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	ctx := context.Background()
+
+	url := &domain.URL{ShortCode: "app", OriginalURL: "https://example.com", IsActive: true, Targets: appTargets}
+
+	// First lookup misses the cache and stores the rule set
+	var cached string
+	suite.cache.On("Get", ctx, "app").Return("", nil).Once()
+	suite.repo.On("FindByShortCode", ctx, "app").Return(url, nil).Once()
+	suite.repo.On("IncrementClickCount", mock.Anything, "app", mock.Anything).Return(nil)
+	suite.cache.On("Set", mock.Anything, "app", mock.AnythingOfType("string"), time.Hour).
+		Run(func(args mock.Arguments) { cached = args.String(2) }).
+		Return(nil)
+
+	destination, err := suite.service.GetOriginalURL(ctx, "app", domain.Visitor{UserAgent: androidUA})
+	require.NoError(t, err)
+	assert.Equal(t, "https://play.google.com/store/apps/details?id=app", destination)
+
+	// Cache hits still branch per visitor
+	drainCacheWrites(t, suite)
+	suite.cache.On("Get", ctx, "app").Return(cached, nil)
+
+	destination, err = suite.service.GetOriginalURL(ctx, "app", domain.Visitor{UserAgent: iPhoneUA})
+	require.NoError(t, err)
+	assert.Equal(t, "https://apps.apple.com/app/id1", destination)
+
+	destination, err = suite.service.GetOriginalURL(ctx, "app", domain.Visitor{UserAgent: desktopUA})
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com", destination)
+
+	suite.repo.AssertNumberOfCalls(t, "FindByShortCode", 1)
+}
+
+func TestRedirect_ConditionalLinkIsNotCacheable(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	router := setupRedirectRouter(suite)
+
+	suite.cache.On("Get", mock.Anything, "app").Return("", nil)
+	suite.repo.On("FindByShortCode", mock.Anything, "app").
+		Return(&domain.URL{ShortCode: "app", OriginalURL: "https://example.com", IsActive: true, Targets: appTargets}, nil)
+	suite.repo.On("IncrementClickCount", mock.Anything, "app", mock.Anything).Return(nil)
+	suite.cache.On("Set", mock.Anything, "app", mock.Anything, time.Hour).Return(nil)
+
+	req := httptest.NewRequest("GET", "/app", nil)
+	req.Header.Set("User-Agent", iPhoneUA)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "https://apps.apple.com/app/id1", w.Header().Get("Location"))
+	assert.Equal(t, "User-Agent", w.Header().Get("Vary"))
+}
+
+func TestGetStats_ClicksByTarget(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	suite := setupURLServiceTest(t)
+	clicks := new(MockClickRepository)
+	suite.service = service.NewURLService(suite.repo, suite.cache, suite.cfg, suite.logger, service.WithClickRepository(clicks))
+
+	suite.repo.On("GetStats", mock.Anything, "app").Return(&domain.URLStats{ShortCode: "app", TotalClicks: 5}, nil)
+	clicks.On("CountByTarget", mock.Anything, "app").Return(map[string]int64{"ios": 3, "default": 2}, nil)
+	expectEmptyDailySeries(clicks, "app")
+
+	router := gin.New()
+	router.GET("/api/v1/urls/:shortCode/stats", handler.NewURLHandler(suite.service, suite.cfg, suite.logger).GetStats)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/urls/app/stats", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var stats domain.URLStats
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &stats))
+	assert.Equal(t, int64(3), stats.ClicksByTarget["ios"])
+	assert.Equal(t, int64(2), stats.ClicksByTarget["default"])
+}
+
+func TestGetOriginalURL_RecordsMatchedTarget(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	clicks := new(MockClickRepository)
+	suite.service = service.NewURLService(suite.repo, suite.cache, suite.cfg, suite.logger, service.WithClickRepository(clicks))
+	ctx := context.Background()
+
+	suite.cache.On("Get", ctx, "app").Return("", nil)
+	suite.repo.On("FindByShortCode", ctx, "app").
+		Return(&domain.URL{ShortCode: "app", OriginalURL: "https://example.com", IsActive: true, Targets: appTargets}, nil)
+	suite.repo.On("IncrementClickCount", ctx, "app", mock.Anything).Return(nil)
+	suite.cache.On("Set", mock.Anything, "app", mock.Anything, time.Hour).Return(nil)
+	clicks.On("Record", ctx, mock.MatchedBy(func(e *domain.ClickEvent) bool {
+		return e.ShortCode == "app" && e.Target == redirect.PlatformAndroid
+	})).Return(nil).Once()
+
+	_, err := suite.service.GetOriginalURL(ctx, "app", domain.Visitor{UserAgent: androidUA})
+
+	require.NoError(t, err)
+	clicks.AssertExpectations(t)
+}
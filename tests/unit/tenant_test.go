@@ -0,0 +1,222 @@
+package unit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/cache"
+	"url-shortener/internal/config"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/handler"
+	"url-shortener/internal/service"
+)
+
+func TestTenantKey_DefaultTenantKeepsPlainKeys(t *testing.T) {
+	assert.Equal(t, "abc123", cache.TenantKey(domain.DefaultTenant, cache.LinkKey("abc123")))
+	assert.Equal(t, "tenant:acme:abc123", cache.TenantKey("acme", cache.LinkKey("abc123")))
+	assert.Equal(t, "tenant:acme:inactive:abc123", cache.TenantKey("acme", cache.InactiveKey("abc123")))
+}
+
+func TestTenantFromContext(t *testing.T) {
+	_, ok := domain.TenantFromContext(context.Background())
+	assert.False(t, ok, "background jobs see every tenant")
+
+	ctx := domain.ContextWithTenant(context.Background(), "acme")
+	tenant, ok := domain.TenantFromContext(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, "acme", tenant)
+
+	_, ok = domain.TenantFromContext(domain.ContextWithoutTenant(ctx))
+	assert.False(t, ok)
+
+	copied, ok := domain.TenantFromContext(domain.CopyTenant(context.Background(), ctx))
+	assert.True(t, ok)
+	assert.Equal(t, "acme", copied)
+}
+
+func TestValidate_TenantDomains(t *testing.T) {
+	cfg, err := config.LoadFrom(nil)
+	require.NoError(t, err)
+
+	cfg.TenantDomains = map[string]string{"acme": "links.acme.com", "globex": "go.globex.com"}
+	assert.NoError(t, cfg.Validate())
+	assert.Equal(t, "acme", cfg.TenantForHost("LINKS.acme.com"))
+	assert.Equal(t, domain.DefaultTenant, cfg.TenantForHost("short.url"))
+
+	cfg.TenantDomains = map[string]string{"Acme": "links.acme.com"}
+	assert.ErrorContains(t, cfg.Validate(), "tenant IDs")
+
+	cfg.TenantDomains = map[string]string{"acme": "https://links.acme.com"}
+	assert.ErrorContains(t, cfg.Validate(), "bare host names")
+
+	cfg.TenantDomains = map[string]string{"acme": "links.acme.com", "globex": "Links.Acme.com"}
+	assert.ErrorContains(t, cfg.Validate(), "to both")
+
+	cfg.TenantDomains = nil
+	cfg.MaxURLsPerDayPerTenant = -1
+	assert.ErrorContains(t, cfg.Validate(), "MAX_URLS_PER_DAY_PER_TENANT")
+}
+
+func TestTenantDomains_FromEnv(t *testing.T) {
+	t.Setenv("TENANT_DOMAINS", "acme:links.acme.com, globex:go.globex.com")
+	cfg, err := config.LoadFrom(nil)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"acme": "links.acme.com", "globex": "go.globex.com"}, cfg.TenantDomains)
+
+	t.Setenv("TENANT_DOMAINS", "acme")
+	_, err = config.LoadFrom(nil)
+	assert.ErrorContains(t, err, "TENANT_DOMAINS")
+}
+
+// tenantOfRequest runs TenantMiddleware and returns the tenant it put on the request context
+func tenantOfRequest(t *testing.T, cfg *config.Config, req *http.Request) (string, bool, *httptest.ResponseRecorder) {
+	var tenant string
+	var scoped bool
+	router := gin.New()
+	router.GET("/api/v1/urls", handler.APIKeyIdentityMiddleware(cfg, nil), handler.TenantMiddleware(cfg), func(c *gin.Context) {
+		tenant, scoped = domain.TenantFromContext(c.Request.Context())
+		c.Status(http.StatusNoContent)
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return tenant, scoped, w
+}
+
+func TestTenantMiddleware_AdminsPickTheTenant(t *testing.T) {
+	cfg := &config.Config{BaseURL: "https://short.url", AdminAPIKey: "admin-secret"}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/urls", nil)
+	tenant, scoped, w := tenantOfRequest(t, cfg, req)
+	require.Equal(t, http.StatusNoContent, w.Code)
+	assert.True(t, scoped, "anonymous callers only see the default tenant")
+	assert.Equal(t, domain.DefaultTenant, tenant)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/urls", nil)
+	req.Header.Set("X-API-Key", "admin-secret")
+	req.Header.Set("X-Tenant-ID", "acme")
+	tenant, _, w = tenantOfRequest(t, cfg, req)
+	require.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "acme", tenant)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/urls", nil)
+	req.Header.Set("X-API-Key", "admin-secret")
+	req.Header.Set("X-Tenant-ID", "../acme")
+	_, _, w = tenantOfRequest(t, cfg, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid_tenant")
+
+	// Only admins can pick; anyone else's header is ignored
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/urls", nil)
+	req.Header.Set("X-Tenant-ID", "acme")
+	tenant, _, _ = tenantOfRequest(t, cfg, req)
+	assert.Equal(t, domain.DefaultTenant, tenant)
+}
+
+func TestRedirectTenantMiddleware(t *testing.T) {
+	tenantOf := func(cfg *config.Config, host string) (string, bool) {
+		var tenant string
+		var scoped bool
+		router := gin.New()
+		router.GET("/:shortCode", handler.RedirectTenantMiddleware(cfg), func(c *gin.Context) {
+			tenant, scoped = domain.TenantFromContext(c.Request.Context())
+		})
+		req := httptest.NewRequest(http.MethodGet, "/abc123", nil)
+		req.Host = host
+		router.ServeHTTP(httptest.NewRecorder(), req)
+		return tenant, scoped
+	}
+
+	cfg := &config.Config{TenantDomains: map[string]string{"acme": "links.acme.com"}}
+	_, scoped := tenantOf(cfg, "links.acme.com")
+	assert.False(t, scoped, "globally unique codes are looked up across tenants")
+
+	cfg.TenantScopedCodes = true
+	tenant, scoped := tenantOf(cfg, "links.acme.com:443")
+	assert.True(t, scoped)
+	assert.Equal(t, "acme", tenant)
+
+	tenant, _ = tenantOf(cfg, "short.url")
+	assert.Equal(t, domain.DefaultTenant, tenant)
+}
+
+func TestResolve_CountsClickForLinkOwner(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	suite.cfg.CacheTTL = 0
+	ctx := context.Background()
+
+	// Without TENANT_SCOPED_CODES the redirect finds the link in any tenant and records for its owner
+	suite.cache.On("Get", ctx, "abc123").Return("", nil)
+	suite.repo.On("FindByShortCode", ctx, "abc123").
+		Return(&domain.URL{ShortCode: "abc123", TenantID: "acme", OriginalURL: "https://example.com", IsActive: true}, nil)
+	suite.repo.On("IncrementClickCount", mock.MatchedBy(func(ctx context.Context) bool {
+		tenant, ok := domain.TenantFromContext(ctx)
+		return ok && tenant == "acme"
+	}), "abc123", mock.Anything).Return(nil)
+
+	_, err := suite.service.GetOriginalURL(ctx, "abc123", domain.Visitor{})
+
+	require.NoError(t, err)
+	suite.repo.AssertExpectations(t)
+}
+
+func TestResolve_TenantScopedCacheKeys(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	suite.cfg.TenantScopedCodes = true
+	ctx := domain.ContextWithTenant(context.Background(), "acme")
+
+	suite.cache.On("Get", ctx, "tenant:acme:abc123").Return("https://example.com", nil)
+	suite.cache.On("Exists", mock.Anything, "tenant:acme:inactive:abc123").Return(false, nil).Maybe()
+	suite.repo.On("IncrementClickCount", mock.Anything, "abc123", mock.Anything).Return(nil).Maybe()
+
+	decision, err := suite.service.PrepareRedirect(ctx, "abc123", domain.Visitor{})
+
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com", decision.OriginalURL)
+	suite.cache.AssertExpectations(t)
+}
+
+func TestShortenURL_TenantScopedCodes(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	suite.cfg.CacheTTL = 0
+	ctx := domain.ContextWithTenant(context.Background(), "acme")
+
+	var scope string
+	suite.repo.On("FindByOriginalURL", mock.Anything, mock.Anything).Return(nil, domain.ErrURLNotFound)
+	suite.repo.On("Create", mock.Anything, mock.AnythingOfType("*domain.URL")).Run(func(args mock.Arguments) {
+		scope = args.Get(1).(*domain.URL).CodeScope
+	}).Return(nil)
+
+	_, err := suite.service.ShortenURL(ctx, &domain.CreateURLRequest{URL: "https://example.com"}, domain.CreatorContext{})
+	require.NoError(t, err)
+	assert.Empty(t, scope, "codes are unique across tenants by default")
+
+	suite.cfg.TenantScopedCodes = true
+	_, err = suite.service.ShortenURL(ctx, &domain.CreateURLRequest{URL: "https://example.com/2"}, domain.CreatorContext{})
+	require.NoError(t, err)
+	assert.Equal(t, "acme", scope)
+}
+
+func TestShortenURL_PerTenantQuota(t *testing.T) {
+	suite, counters := setupQuotaService(t, 0, 0, nil)
+	suite.cfg.MaxURLsPerDayPerTenant = 1
+
+	acme := domain.ContextWithTenant(context.Background(), "acme")
+	_, err := suite.service.ShortenURL(service.ContextWithAPIKey(acme, "api_key:1a2b3c4d"), &domain.CreateURLRequest{URL: "https://example.com/1"}, domain.CreatorContext{IP: "10.0.0.1"})
+	require.NoError(t, err)
+
+	// The quota is shared by every key of the tenant
+	_, err = suite.service.ShortenURL(service.ContextWithAPIKey(acme, "api_key:5e6f7a8b"), &domain.CreateURLRequest{URL: "https://example.com/2"}, domain.CreatorContext{IP: "10.0.0.2"})
+	assert.ErrorIs(t, err, domain.ErrQuotaExceeded)
+	assert.Equal(t, int64(1), counters.total("quota:tenant:acme:"))
+
+	_, err = suite.service.ShortenURL(domain.ContextWithTenant(context.Background(), domain.DefaultTenant), &domain.CreateURLRequest{URL: "https://example.com/3"}, domain.CreatorContext{IP: "10.0.0.1"})
+	assert.NoError(t, err, "the default tenant has no tenant quota")
+}
@@ -0,0 +1,167 @@
+package unit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/handler"
+)
+
+// strictRecorder is a ResponseRecorder that panics on a superfluous WriteHeader, as net/http would warn
+type strictRecorder struct {
+	*httptest.ResponseRecorder
+	mu           sync.Mutex
+	headerWrites int
+}
+
+func newStrictRecorder() *strictRecorder {
+	return &strictRecorder{ResponseRecorder: httptest.NewRecorder()}
+}
+
+func (r *strictRecorder) WriteHeader(code int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.headerWrites++
+	if r.headerWrites > 1 {
+		panic("http: superfluous response.WriteHeader call")
+	}
+	r.ResponseRecorder.WriteHeader(code)
+}
+
+func (r *strictRecorder) Write(data []byte) (int, error) {
+	r.mu.Lock()
+	if r.headerWrites == 0 {
+		r.mu.Unlock()
+		r.WriteHeader(http.StatusOK)
+	} else {
+		r.mu.Unlock()
+	}
+	return r.ResponseRecorder.Write(data)
+}
+
+// setupTimeoutRouter serves h at /slow behind a deadline of timeout
+// Panics are not recovered, so a double write fails the test instead of turning into a 500
+func setupTimeoutRouter(timeout time.Duration, h gin.HandlerFunc) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(handler.SecurityHeadersMiddleware())
+	router.GET("/slow", handler.TimeoutMiddleware(timeout), h)
+	return router
+}
+
+func TestTimeoutMiddleware_SlowHandlerGets504Once(t *testing.T) {
+	handlerCanceled := make(chan error, 1)
+	writeErr := make(chan error, 1)
+
+	router := setupTimeoutRouter(20*time.Millisecond, func(c *gin.Context) {
+		// Stands in for a DB call that honors its context
+		<-c.Request.Context().Done()
+		handlerCanceled <- c.Request.Context().Err()
+
+		c.Header("X-Late", "1")
+		c.JSON(http.StatusOK, gin.H{"late": true})
+		_, err := c.Writer.Write([]byte("more"))
+		writeErr <- err
+	})
+
+	w := newStrictRecorder()
+	require.NotPanics(t, func() {
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/slow", nil))
+	})
+
+	assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+	assert.Equal(t, 1, w.headerWrites)
+	assert.Error(t, <-handlerCanceled)
+	assert.ErrorIs(t, <-writeErr, http.ErrHandlerTimeout)
+
+	var resp domain.ErrorResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "request_timeout", resp.Error)
+	assert.Equal(t, http.StatusGatewayTimeout, resp.Code)
+	assert.Empty(t, w.Header().Get("X-Late"))
+	assert.Equal(t, "nosniff", w.Header().Get("X-Content-Type-Options"), "headers set before the deadline are kept")
+}
+
+func TestTimeoutMiddleware_HandlerIgnoringContext(t *testing.T) {
+	router := setupTimeoutRouter(10*time.Millisecond, func(c *gin.Context) {
+		time.Sleep(40 * time.Millisecond)
+		c.JSON(http.StatusCreated, gin.H{"late": true})
+	})
+
+	w := newStrictRecorder()
+	require.NotPanics(t, func() {
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/slow", nil))
+	})
+
+	assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+	assert.Equal(t, 1, w.headerWrites)
+	assert.NotContains(t, w.Body.String(), "late")
+}
+
+func TestTimeoutMiddleware_FastHandlerUnaffected(t *testing.T) {
+	router := setupTimeoutRouter(time.Second, func(c *gin.Context) {
+		c.Header("X-Handler", "1")
+		c.JSON(http.StatusCreated, gin.H{"ok": true})
+	})
+
+	w := newStrictRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/slow", nil))
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "1", w.Header().Get("X-Handler"))
+	assert.JSONEq(t, `{"ok":true}`, w.Body.String())
+}
+
+func TestTimeoutMiddleware_HeadersWithoutBody(t *testing.T) {
+	router := setupTimeoutRouter(time.Second, func(c *gin.Context) {
+		c.Header("Location", "https://example.com")
+		c.Status(http.StatusFound)
+	})
+
+	w := newStrictRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/slow", nil))
+
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "https://example.com", w.Header().Get("Location"))
+}
+
+func TestTimeoutMiddleware_CommittedResponseIsKept(t *testing.T) {
+	router := setupTimeoutRouter(10*time.Millisecond, func(c *gin.Context) {
+		c.Status(http.StatusOK)
+		c.Writer.WriteString("partial ")
+		c.Writer.Flush()
+
+		<-c.Request.Context().Done()
+		c.Writer.WriteString("rest")
+	})
+
+	w := newStrictRecorder()
+	require.NotPanics(t, func() {
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/slow", nil))
+	})
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "partial rest", w.Body.String())
+}
+
+func TestTimeoutMiddleware_ZeroDisables(t *testing.T) {
+	router := setupTimeoutRouter(0, func(c *gin.Context) {
+		_, hasDeadline := c.Request.Context().Deadline()
+		c.JSON(http.StatusOK, gin.H{"deadline": hasDeadline})
+	})
+
+	w := newStrictRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/slow", nil))
+
+	assert.JSONEq(t, `{"deadline":false}`, w.Body.String())
+}
@@ -0,0 +1,147 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/cache/cachetest"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/handler"
+	"url-shortener/internal/service"
+)
+
+// setupTimeSeriesTest returns a service with click events, an in-memory cache and an existing link abc123
+func setupTimeSeriesTest(t *testing.T) (*URLServiceTestSuite, *MockClickRepository, service.URLService) {
+	suite := setupURLServiceTest(t)
+	clicks := new(MockClickRepository)
+	svc := service.NewURLService(suite.repo, cachetest.NewMemoryCache(), suite.cfg, suite.logger, service.WithClickRepository(clicks))
+	suite.repo.On("FindAnyByShortCode", mock.Anything, "abc123").Return(&domain.URL{ShortCode: "abc123"}, nil)
+	suite.repo.On("FindAnyByShortCode", mock.Anything, "nope01").Return(nil, domain.ErrURLNotFound)
+	return suite, clicks, svc
+}
+
+func TestGranularity_TruncateWeekStartsMonday(t *testing.T) {
+	sunday := time.Date(2024, 3, 10, 18, 30, 0, 0, time.UTC)
+	monday := time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, monday, domain.GranularityWeek.Truncate(sunday))
+	assert.Equal(t, monday, domain.GranularityWeek.Truncate(monday))
+	assert.Equal(t, time.Date(2024, 3, 10, 18, 0, 0, 0, time.UTC), domain.GranularityHour.Truncate(sunday))
+}
+
+func TestGetClickTimeSeries_HourlyGapsFilledAndSettledRangeCached(t *testing.T) {
+	_, clicks, svc := setupTimeSeriesTest(t)
+	ctx := context.Background()
+	from := time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)
+	to := from.Add(6 * time.Hour)
+
+	clicks.On("CountByBucket", ctx, "abc123", domain.GranularityHour, from, to).Return([]domain.ClickBucket{
+		{BucketStart: from.Add(time.Hour), Clicks: 4},
+		{BucketStart: from.Add(4 * time.Hour), Clicks: 1},
+	}, nil).Once()
+
+	for i := 0; i < 2; i++ {
+		series, err := svc.GetClickTimeSeries(ctx, "abc123", domain.TimeSeriesQuery{Granularity: domain.GranularityHour, From: from, To: to})
+		require.NoError(t, err)
+		require.Len(t, series, 6)
+		assert.Equal(t, from, series[0].BucketStart)
+		assert.Equal(t, []int64{0, 4, 0, 0, 1, 0}, []int64{series[0].Clicks, series[1].Clicks, series[2].Clicks, series[3].Clicks, series[4].Clicks, series[5].Clicks})
+	}
+
+	// The second request is answered from the cache
+	clicks.AssertNumberOfCalls(t, "CountByBucket", 1)
+}
+
+func TestGetClickTimeSeries_DailyCombinesRollupAndToday(t *testing.T) {
+	_, clicks, svc := setupTimeSeriesTest(t)
+	ctx := context.Background()
+	today := domain.GranularityDay.Truncate(time.Now())
+	from := today.AddDate(0, 0, -29)
+	settled := today.AddDate(0, 0, -2)
+
+	clicks.On("SumDailyByBucket", ctx, "abc123", domain.GranularityDay, from, settled).
+		Return([]domain.ClickBucket{{BucketStart: from, Clicks: 3}}, nil)
+	clicks.On("SumDailyByBucket", ctx, "abc123", domain.GranularityDay, settled, today).
+		Return([]domain.ClickBucket{{BucketStart: today.AddDate(0, 0, -1), Clicks: 5}}, nil)
+	clicks.On("CountByBucket", ctx, "abc123", domain.GranularityDay, today, today.AddDate(0, 0, 1)).
+		Return([]domain.ClickBucket{{BucketStart: today, Clicks: 2}}, nil)
+
+	series, err := svc.GetClickTimeSeries(ctx, "abc123", domain.TimeSeriesQuery{})
+
+	require.NoError(t, err)
+	require.Len(t, series, 30, "days is the default granularity, 30 buckets up to today")
+	assert.Equal(t, int64(3), series[0].Clicks)
+	assert.Equal(t, int64(0), series[27].Clicks)
+	assert.Equal(t, int64(5), series[28].Clicks)
+	assert.Equal(t, int64(2), series[29].Clicks)
+	clicks.AssertExpectations(t)
+}
+
+func TestGetClickTimeSeries_Validation(t *testing.T) {
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		code   string
+		query  domain.TimeSeriesQuery
+		status int
+	}{
+		{"unknown granularity", "abc123", domain.TimeSeriesQuery{Granularity: "minute"}, http.StatusBadRequest},
+		{"hourly over 14 days", "abc123", domain.TimeSeriesQuery{Granularity: domain.GranularityHour, From: from, To: from.AddDate(0, 0, 15)}, http.StatusBadRequest},
+		{"daily over 366 days", "abc123", domain.TimeSeriesQuery{Granularity: domain.GranularityDay, From: from, To: from.AddDate(0, 0, 367)}, http.StatusBadRequest},
+		{"from after to", "abc123", domain.TimeSeriesQuery{From: from, To: from.AddDate(0, 0, -1)}, http.StatusBadRequest},
+		{"unknown link", "nope01", domain.TimeSeriesQuery{From: from, To: from.AddDate(0, 0, 1)}, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, clicks, svc := setupTimeSeriesTest(t)
+
+			_, err := svc.GetClickTimeSeries(context.Background(), tt.code, tt.query)
+
+			var appErr *domain.AppError
+			if tt.status == http.StatusNotFound {
+				assert.ErrorIs(t, err, domain.ErrURLNotFound)
+			} else {
+				require.ErrorAs(t, err, &appErr)
+				assert.Equal(t, tt.status, appErr.StatusCode)
+			}
+			clicks.AssertNotCalled(t, "CountByBucket", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestGetClickTimeSeriesHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	suite, clicks, svc := setupTimeSeriesTest(t)
+	monday := time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)
+	clicks.On("SumDailyByBucket", mock.Anything, "abc123", domain.GranularityWeek, monday, monday.AddDate(0, 0, 14)).
+		Return([]domain.ClickBucket{{BucketStart: monday.AddDate(0, 0, 7), Clicks: 9}}, nil)
+
+	router := gin.New()
+	router.GET("/api/v1/urls/:shortCode/stats/timeseries", handler.NewURLHandler(svc, suite.cfg, suite.logger).GetClickTimeSeries)
+
+	// A plain "to" date is inclusive, and both ends widen to whole weeks
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/urls/abc123/stats/timeseries?granularity=week&from=2026-01-07&to=2026-01-18", nil))
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var series []domain.ClickBucket
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &series))
+	require.Len(t, series, 2)
+	assert.True(t, monday.Equal(series[0].BucketStart))
+	assert.Equal(t, int64(9), series[1].Clicks)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/urls/abc123/stats/timeseries?from=yesterday", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,126 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/handler"
+)
+
+// setupURLInfoRouter registers the info endpoint on a bare router
+func setupURLInfoRouter(suite *URLServiceTestSuite) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	h := handler.NewURLHandler(suite.service, suite.cfg, suite.logger)
+
+	router := gin.New()
+	router.GET("/api/v1/urls/:shortCode", h.GetURLInfo)
+	return router
+}
+
+// infoTestURL is a stored link with every internal column populated
+func infoTestURL() *domain.URL {
+	expired := time.Now().Add(-time.Hour)
+	return &domain.URL{
+		ID:          42,
+		ShortCode:   "abc123",
+		OriginalURL: "https://example.com/info",
+		CreatedAt:   time.Now().Add(-48 * time.Hour),
+		UpdatedAt:   time.Now(),
+		ExpiresAt:   &expired,
+		ClickCount:  7,
+		CreatorIP:   "203.0.113.9",
+		IsActive:    true,
+	}
+}
+
+// getInfoFields requests the info endpoint and returns the decoded JSON object
+func getInfoFields(t *testing.T, router *gin.Engine, accept ...string) (*httptest.ResponseRecorder, map[string]interface{}) {
+	req := httptest.NewRequest("GET", "/api/v1/urls/abc123", nil)
+	for _, value := range accept {
+		req.Header.Add("Accept", value)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var fields map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &fields))
+	return w, fields
+}
+
+func TestGetURLInfo_MapsComputedFields(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	ctx := context.Background()
+	suite.repo.On("FindByShortCode", ctx, "abc123").Return(infoTestURL(), nil)
+
+	info, err := suite.service.GetURLInfo(ctx, "abc123")
+
+	require.NoError(t, err)
+	assert.Equal(t, "https://short.url/abc123", info.ShortURL)
+	assert.True(t, info.IsExpired)
+	assert.Equal(t, int64(7), info.ClickCount)
+}
+
+func TestGetURLInfo_ResponseShape(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	suite.repo.On("FindByShortCode", mock.Anything, "abc123").Return(infoTestURL(), nil)
+
+	w, fields := getInfoFields(t, setupURLInfoRouter(suite))
+
+	assert.Empty(t, w.Header().Get("Deprecation"))
+	for _, hidden := range []string{"id", "updated_at", "creator_ip", "metadata_fetched_at"} {
+		assert.NotContains(t, fields, hidden)
+	}
+	assert.Equal(t, "https://short.url/abc123", fields["short_url"])
+	assert.Equal(t, true, fields["is_expired"])
+	assert.Equal(t, "abc123", fields["short_code"])
+}
+
+func TestGetURLInfo_LegacyShapeBehindFlag(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	suite.cfg.LegacyURLInfo = true
+	suite.repo.On("FindByShortCode", mock.Anything, "abc123").Return(infoTestURL(), nil)
+
+	w, fields := getInfoFields(t, setupURLInfoRouter(suite), "application/json")
+
+	assert.Equal(t, "true", w.Header().Get("Deprecation"))
+	assert.Equal(t, float64(42), fields["id"])
+	assert.Contains(t, fields, "updated_at")
+	assert.NotContains(t, fields, "short_url")
+	assert.NotContains(t, fields, "creator_ip")
+}
+
+func TestGetURLInfo_AcceptOptsOutOfLegacyShape(t *testing.T) {
+	tests := []struct {
+		name   string
+		accept []string
+	}{
+		{"single value", []string{handler.URLInfoMediaType}},
+		{"listed after json", []string{"application/json, " + handler.URLInfoMediaType + ";q=0.9"}},
+		{"separate header line", []string{"text/plain", handler.URLInfoMediaType}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			suite := setupURLServiceTest(t)
+			suite.cfg.LegacyURLInfo = true
+			suite.repo.On("FindByShortCode", mock.Anything, "abc123").Return(infoTestURL(), nil)
+
+			w, fields := getInfoFields(t, setupURLInfoRouter(suite), tt.accept...)
+
+			assert.Empty(t, w.Header().Get("Deprecation"))
+			assert.NotContains(t, fields, "id")
+			assert.Contains(t, fields, "short_url")
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package unit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/cache"
+	"url-shortener/internal/config"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/service"
+	"url-shortener/pkg/validator"
+)
+
+func TestValidator_DefaultSchemes(t *testing.T) {
+	v := validator.New(nil)
+
+	assert.NoError(t, v.ValidateURL("https://example.com/a"))
+	assert.NoError(t, v.ValidateURL("HTTP://example.com"))
+	for _, rawURL := range []string{"ftp://files.example.com/x", "mailto:team@example.com", "tel:+1-555-0100", "javascript:alert(1)"} {
+		assert.Error(t, v.ValidateURL(rawURL), rawURL)
+	}
+}
+
+func TestValidator_OptInSchemes(t *testing.T) {
+	v := validator.New([]string{"https", "ftp", "mailto", "tel"})
+
+	valid := []string{
+		"ftp://files.example.com/x",
+		"mailto:team@example.com",
+		"mailto:a@example.com,b@example.com?subject=Hi%20there",
+		"tel:+1-555-0100",
+		"tel:+44(20)7946.0958;ext=12",
+	}
+	for _, rawURL := range valid {
+		assert.NoError(t, v.ValidateURL(rawURL), rawURL)
+	}
+
+	invalid := []string{"mailto:team", "mailto:", "tel:call-me", "tel:+1 555", "http://example.com"}
+	for _, rawURL := range invalid {
+		assert.Error(t, v.ValidateURL(rawURL), rawURL)
+	}
+}
+
+func TestNormalizeURL_SchemeDetection(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"example.com/a", "https://example.com/a"},
+		{"localhost:8080/a", "https://localhost:8080/a"},
+		{"example.com:8443", "https://example.com:8443"},
+		{"MAILTO:Team@Example.com?subject=Hi", "mailto:Team@Example.com?subject=Hi"},
+		{"tel:911", "tel:911"},
+		{"sms:+15550100", "sms:+15550100"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, validator.NormalizeURL(tt.input), tt.input)
+	}
+}
+
+func TestShortenURL_RejectsSchemeNotAllowed(t *testing.T) {
+	suite := setupURLServiceTest(t)
+
+	_, err := suite.service.ShortenURL(context.Background(), &domain.CreateURLRequest{URL: "ftp://files.example.com/x"}, domain.CreatorContext{IP: "192.168.1.1"})
+
+	var appErr *domain.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, http.StatusBadRequest, appErr.StatusCode)
+	suite.repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestShortenURL_WithValidatorOverridesConfig(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	svc := service.NewURLService(suite.repo, suite.cache, suite.cfg, suite.logger,
+		service.WithValidator(validator.New([]string{"mailto"})))
+
+	_, err := svc.ShortenURL(context.Background(), &domain.CreateURLRequest{URL: "https://example.com"}, domain.CreatorContext{IP: "192.168.1.1"})
+
+	var appErr *domain.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, http.StatusBadRequest, appErr.StatusCode, "https is not in the injected validator's schemes")
+}
+
+func TestRedirectURL_NonWebSchemeRendersLink(t *testing.T) {
+	// html/template writes + as &#43;, which browsers decode back
+	links := map[string]string{"mailto:team@example.com": "mailto:team@example.com", "tel:+1-555-0100": "tel:&#43;1-555-0100"}
+	for destination, href := range links {
+		t.Run(destination, func(t *testing.T) {
+			suite := setupURLServiceTest(t)
+			router := setupRedirectRouter(suite)
+
+			entry := cache.NewLinkEntry(&domain.URL{OriginalURL: destination, IsActive: true})
+			suite.cache.On("Get", mock.Anything, "abc123").Return(entry.Encode(), nil)
+			suite.repo.On("IncrementClickCount", mock.Anything, "abc123", mock.Anything).Return(nil)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest("GET", "/abc123", nil))
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Empty(t, w.Header().Get("Location"))
+			assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+			assert.Contains(t, w.Body.String(), `href="`+href+`"`)
+		})
+	}
+}
+
+func TestValidate_AllowedURLSchemes(t *testing.T) {
+	cfg, err := config.LoadFrom(nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"http", "https"}, cfg.AllowedURLSchemes)
+
+	cfg.AllowedURLSchemes = []string{"https", "mailto", "tel"}
+	assert.NoError(t, cfg.Validate())
+
+	cfg.AllowedURLSchemes = []string{"https", "JavaScript"}
+	assert.ErrorContains(t, cfg.Validate(), `"JavaScript"`, "matched regardless of case")
+
+	cfg.AllowedURLSchemes = []string{"mailto:"}
+	assert.ErrorContains(t, cfg.Validate(), "ALLOWED_URL_SCHEMES")
+
+	cfg.AllowedURLSchemes = nil
+	assert.ErrorContains(t, cfg.Validate(), "cannot be empty")
+}
@@ -3,12 +3,15 @@ package unit
 import (
 	"context"
 	"errors"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 
+	"url-shortener/internal/cache"
 	"url-shortener/internal/config"
 	"url-shortener/internal/domain"
 	"url-shortener/internal/service"
@@ -25,6 +28,11 @@ func (m *MockURLRepository) Create(ctx context.Context, url *domain.URL) error {
 	return args.Error(0)
 }
 
+func (m *MockURLRepository) CreateBundle(ctx context.Context, bundle *domain.URL, members []*domain.URL) error {
+	args := m.Called(ctx, bundle, members)
+	return args.Error(0)
+}
+
 func (m *MockURLRepository) FindByShortCode(ctx context.Context, shortCode string) (*domain.URL, error) {
 	args := m.Called(ctx, shortCode)
 	if args.Get(0) == nil {
@@ -33,6 +41,14 @@ func (m *MockURLRepository) FindByShortCode(ctx context.Context, shortCode strin
 	return args.Get(0).(*domain.URL), args.Error(1)
 }
 
+func (m *MockURLRepository) FindAnyByShortCode(ctx context.Context, shortCode string) (*domain.URL, error) {
+	args := m.Called(ctx, shortCode)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.URL), args.Error(1)
+}
+
 func (m *MockURLRepository) FindByOriginalURL(ctx context.Context, originalURL string) (*domain.URL, error) {
 	args := m.Called(ctx, originalURL)
 	if args.Get(0) == nil {
@@ -46,12 +62,69 @@ func (m *MockURLRepository) Update(ctx context.Context, url *domain.URL) error {
 	return args.Error(0)
 }
 
+func (m *MockURLRepository) SetActive(ctx context.Context, shortCode string, active bool) (*domain.URL, error) {
+	args := m.Called(ctx, shortCode, active)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.URL), args.Error(1)
+}
+
 func (m *MockURLRepository) Delete(ctx context.Context, shortCode string) error {
 	args := m.Called(ctx, shortCode)
 	return args.Error(0)
 }
 
-func (m *MockURLRepository) IncrementClickCount(ctx context.Context, shortCode string) error {
+func (m *MockURLRepository) ResetStats(ctx context.Context, shortCode string) (*domain.URL, error) {
+	args := m.Called(ctx, shortCode)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.URL), args.Error(1)
+}
+
+func (m *MockURLRepository) HardDelete(ctx context.Context, shortCode string) error {
+	args := m.Called(ctx, shortCode)
+	return args.Error(0)
+}
+
+func (m *MockURLRepository) HardDeleteInactive(ctx context.Context, before time.Time, limit int) ([]domain.URL, error) {
+	args := m.Called(ctx, before, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.URL), args.Error(1)
+}
+
+func (m *MockURLRepository) IncrementClickCount(ctx context.Context, shortCode, referrer string) error {
+	args := m.Called(ctx, shortCode, referrer)
+	return args.Error(0)
+}
+
+func (m *MockURLRepository) IncrementBotClickCount(ctx context.Context, shortCode string) error {
+	args := m.Called(ctx, shortCode)
+	return args.Error(0)
+}
+
+func (m *MockURLRepository) FindByShortCodeFold(ctx context.Context, shortCode string) (*domain.URL, error) {
+	args := m.Called(ctx, shortCode)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.URL), args.Error(1)
+}
+
+func (m *MockURLRepository) IncrementPrefetchCount(ctx context.Context, shortCode string) error {
+	args := m.Called(ctx, shortCode)
+	return args.Error(0)
+}
+
+func (m *MockURLRepository) IncrementFilteredClickCount(ctx context.Context, shortCode string) error {
+	args := m.Called(ctx, shortCode)
+	return args.Error(0)
+}
+
+func (m *MockURLRepository) IncrementConversionCount(ctx context.Context, shortCode string) error {
 	args := m.Called(ctx, shortCode)
 	return args.Error(0)
 }
@@ -74,11 +147,134 @@ func (m *MockURLRepository) ExistsByShortCode(ctx context.Context, shortCode str
 	return args.Bool(0), args.Error(1)
 }
 
+func (m *MockURLRepository) UpdateMetadata(ctx context.Context, shortCode string, pageTitle, faviconURL *string) error {
+	args := m.Called(ctx, shortCode, pageTitle, faviconURL)
+	return args.Error(0)
+}
+
+func (m *MockURLRepository) ExistsManyByShortCode(ctx context.Context, shortCodes []string) (map[string]bool, error) {
+	args := m.Called(ctx, shortCodes)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[string]bool), args.Error(1)
+}
+
+func (m *MockURLRepository) ForEach(ctx context.Context, filter domain.URLFilter, batchSize int, fn func(*domain.URL) error) error {
+	args := m.Called(ctx, filter, batchSize, fn)
+	// Feed any URLs configured on the mock through the callback
+	if urls, ok := args.Get(0).([]*domain.URL); ok {
+		for _, url := range urls {
+			if err := fn(url); err != nil {
+				return err
+			}
+		}
+	}
+	return args.Error(1)
+}
+
+func (m *MockURLRepository) CountURLs(ctx context.Context) (int64, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockURLRepository) SumClicks(ctx context.Context) (int64, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockURLRepository) TopByClickCount(ctx context.Context, limit int) ([]domain.URL, error) {
+	args := m.Called(ctx, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.URL), args.Error(1)
+}
+
+func (m *MockURLRepository) TopByClicks(ctx context.Context, since time.Time, limit int) ([]domain.TopLink, error) {
+	args := m.Called(ctx, since, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.TopLink), args.Error(1)
+}
+
+func (m *MockURLRepository) CreatedBetween(ctx context.Context, from, to time.Time) ([]domain.DailyCount, error) {
+	args := m.Called(ctx, from, to)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.DailyCount), args.Error(1)
+}
+
+func (m *MockURLRepository) FindByCreatorIP(ctx context.Context, ip string, limit, offset int) ([]domain.URL, error) {
+	args := m.Called(ctx, ip, limit, offset)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.URL), args.Error(1)
+}
+
+func (m *MockURLRepository) FindExpiringBetween(ctx context.Context, from, to time.Time, limit int) ([]domain.URL, error) {
+	args := m.Called(ctx, from, to, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.URL), args.Error(1)
+}
+
+func (m *MockURLRepository) DeactivateMatching(ctx context.Context, filter domain.URLFilter) ([]domain.URL, error) {
+	args := m.Called(ctx, filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.URL), args.Error(1)
+}
+
+func (m *MockURLRepository) CountActiveMatching(ctx context.Context, filter domain.URLFilter) (int64, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockURLRepository) MarkExpiryNotified(ctx context.Context, shortCode string, at time.Time) error {
+	args := m.Called(ctx, shortCode, at)
+	return args.Error(0)
+}
+
+func (m *MockURLRepository) FindDueForCheck(ctx context.Context, checkedBefore time.Time, afterID uint, limit int) ([]domain.URL, error) {
+	args := m.Called(ctx, checkedBefore, afterID, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.URL), args.Error(1)
+}
+
+func (m *MockURLRepository) RecordLinkCheck(ctx context.Context, shortCode string, check domain.LinkCheck) error {
+	args := m.Called(ctx, shortCode, check)
+	return args.Error(0)
+}
+
+func (m *MockURLRepository) FindActiveMatching(ctx context.Context, filter domain.URLFilter, limit, offset int) ([]domain.URL, error) {
+	args := m.Called(ctx, filter, limit, offset)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.URL), args.Error(1)
+}
+
 // MockCache is a mock implementation of Cache
 type MockCache struct {
 	mock.Mock
 }
 
+// cachedDestination matches a cached link entry redirecting to destination, whatever else it carries
+func cachedDestination(destination string) interface{} {
+	return mock.MatchedBy(func(value string) bool {
+		entry, ok := cache.DecodeLinkEntry(value)
+		return ok && entry.URL == destination
+	})
+}
+
 func (m *MockCache) Set(ctx context.Context, key string, value string, ttl time.Duration) error {
 	args := m.Called(ctx, key, value, ttl)
 	return args.Error(0)
@@ -124,7 +320,12 @@ func setupURLServiceTest(t *testing.T) *URLServiceTestSuite {
 		EnableAuthentication: false,
 	}
 	
-	logger := logger.NewLogger()
+	// Cache misses consult the negative cache for deactivated links
+	cache.On("Exists", mock.Anything, mock.MatchedBy(func(key string) bool {
+		return strings.HasPrefix(key, "inactive:")
+	})).Return(false, nil).Maybe()
+	
+	logger := logger.NewLogger(logger.Options{})
 	service := service.NewURLService(repo, cache, cfg, logger)
 	
 	return &URLServiceTestSuite{
@@ -136,6 +337,13 @@ func setupURLServiceTest(t *testing.T) *URLServiceTestSuite {
 	}
 }
 
+// drainCacheWrites waits for the cache entries the service queued in the background
+// Later writes go straight to the cache, so a test can inspect them as soon as the call returns
+func drainCacheWrites(t *testing.T, suite *URLServiceTestSuite) {
+	t.Helper()
+	require.NoError(t, suite.service.Close(context.Background()))
+}
+
 func TestShortenURL_Success(t *testing.T) {
 	suite := setupURLServiceTest(t)
 	ctx := context.Background()
@@ -147,14 +355,12 @@ func TestShortenURL_Success(t *testing.T) {
 	// Mock repository calls
 	suite.repo.On("FindByOriginalURL", ctx, "https://example.com/very/long/url").
 		Return((*domain.URL)(nil), domain.ErrURLNotFound)
-	suite.repo.On("ExistsByShortCode", ctx, mock.AnythingOfType("string")).
-		Return(false, nil)
 	suite.repo.On("Create", ctx, mock.AnythingOfType("*domain.URL")).
 		Return(nil)
-	suite.cache.On("Set", ctx, mock.AnythingOfType("string"), "https://example.com/very/long/url", time.Hour).
+	suite.cache.On("Set", mock.Anything, mock.AnythingOfType("string"), cachedDestination("https://example.com/very/long/url"), time.Hour).
 		Return(nil)
 	
-	resp, err := suite.service.ShortenURL(ctx, req, "192.168.1.1")
+	resp, err := suite.service.ShortenURL(ctx, req, domain.CreatorContext{IP: "192.168.1.1"})
 	
 	assert.NoError(t, err)
 	assert.NotNil(t, resp)
@@ -162,6 +368,7 @@ func TestShortenURL_Success(t *testing.T) {
 	assert.Contains(t, resp.ShortURL, "https://short.url/")
 	
 	suite.repo.AssertExpectations(t)
+	drainCacheWrites(t, suite)
 	suite.cache.AssertExpectations(t)
 }
 
@@ -184,11 +391,12 @@ func TestShortenURL_DuplicateURL(t *testing.T) {
 	suite.repo.On("FindByOriginalURL", ctx, "https://example.com/duplicate").
 		Return(existingURL, nil)
 	
-	resp, err := suite.service.ShortenURL(ctx, req, "192.168.1.1")
+	resp, err := suite.service.ShortenURL(ctx, req, domain.CreatorContext{IP: "192.168.1.1"})
 	
 	assert.NoError(t, err)
 	assert.NotNil(t, resp)
 	assert.Equal(t, "abc123", resp.ShortCode)
+	assert.True(t, resp.Deduplicated)
 	
 	suite.repo.AssertExpectations(t)
 }
@@ -204,19 +412,18 @@ func TestShortenURL_CustomAlias(t *testing.T) {
 	
 	suite.repo.On("FindByOriginalURL", ctx, "https://example.com/custom").
 		Return((*domain.URL)(nil), domain.ErrURLNotFound)
-	suite.repo.On("ExistsByShortCode", ctx, "myalias").
-		Return(false, nil)
 	suite.repo.On("Create", ctx, mock.AnythingOfType("*domain.URL")).
 		Return(nil)
-	suite.cache.On("Set", ctx, "myalias", "https://example.com/custom", time.Hour).
+	suite.cache.On("Set", mock.Anything, "myalias", cachedDestination("https://example.com/custom"), time.Hour).
 		Return(nil)
 	
-	resp, err := suite.service.ShortenURL(ctx, req, "192.168.1.1")
+	resp, err := suite.service.ShortenURL(ctx, req, domain.CreatorContext{IP: "192.168.1.1"})
 	
 	assert.NoError(t, err)
 	assert.Equal(t, "myalias", resp.ShortCode)
 	
 	suite.repo.AssertExpectations(t)
+	drainCacheWrites(t, suite)
 	suite.cache.AssertExpectations(t)
 }
 
@@ -226,10 +433,10 @@ func TestGetOriginalURL_CacheHit(t *testing.T) {
 	
 	suite.cache.On("Get", ctx, "abc123").
 		Return("https://example.com/cached", nil)
-	suite.repo.On("IncrementClickCount", mock.Anything, "abc123").
+	suite.repo.On("IncrementClickCount", mock.Anything, "abc123", mock.Anything).
 		Return(nil)
 	
-	originalURL, err := suite.service.GetOriginalURL(ctx, "abc123")
+	originalURL, err := suite.service.GetOriginalURL(ctx, "abc123", domain.Visitor{})
 	
 	assert.NoError(t, err)
 	assert.Equal(t, "https://example.com/cached", originalURL)
@@ -253,17 +460,18 @@ func TestGetOriginalURL_CacheMiss(t *testing.T) {
 		Return("", nil) // Cache miss
 	suite.repo.On("FindByShortCode", ctx, "abc123").
 		Return(url, nil)
-	suite.repo.On("IncrementClickCount", ctx, "abc123").
+	suite.repo.On("IncrementClickCount", ctx, "abc123", mock.Anything).
 		Return(nil)
-	suite.cache.On("Set", ctx, "abc123", "https://example.com/notcached", time.Hour).
+	suite.cache.On("Set", mock.Anything, "abc123", "https://example.com/notcached", time.Hour).
 		Return(nil)
 	
-	originalURL, err := suite.service.GetOriginalURL(ctx, "abc123")
+	originalURL, err := suite.service.GetOriginalURL(ctx, "abc123", domain.Visitor{})
 	
 	assert.NoError(t, err)
 	assert.Equal(t, "https://example.com/notcached", originalURL)
 	
 	suite.repo.AssertExpectations(t)
+	drainCacheWrites(t, suite)
 	suite.cache.AssertExpectations(t)
 }
 
@@ -282,8 +490,32 @@ func TestGetOriginalURL_Expired(t *testing.T) {
 	suite.cache.On("Get", ctx, "expired").Return("", nil)
 	suite.repo.On("FindByShortCode", ctx, "expired").Return(url, nil)
 	
-	_, err := suite.service.GetOriginalURL(ctx, "expired")
+	_, err := suite.service.GetOriginalURL(ctx, "expired", domain.Visitor{})
 	
 	assert.Error(t, err)
 	assert.True(t, errors.Is(err, domain.ErrURLExpired))
-}
\ No newline at end of file
+}
+
+func TestDeleteURL_InvalidatesCache(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	suite.cfg.NegativeCacheTTL = time.Minute
+	ctx := context.Background()
+	
+	suite.repo.On("Delete", ctx, "abc123").Return(nil)
+	suite.cache.On("Delete", ctx, "abc123").Return(nil)
+	suite.cache.On("Set", mock.Anything, "inactive:abc123", "1", time.Minute).Return(nil)
+	
+	err := suite.service.DeleteURL(ctx, "abc123")
+	
+	assert.NoError(t, err)
+	suite.cache.AssertExpectations(t)
+	
+	// A redirect right after the delete is answered from the negative cache
+	suite.cache.ExpectedCalls = nil
+	suite.cache.On("Get", ctx, "abc123").Return("", nil)
+	suite.cache.On("Exists", ctx, "inactive:abc123").Return(true, nil)
+	
+	_, err = suite.service.GetOriginalURL(ctx, "abc123", domain.Visitor{})
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+	suite.repo.AssertNotCalled(t, "FindByShortCode", mock.Anything, mock.Anything)
+}
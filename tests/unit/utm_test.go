@@ -0,0 +1,85 @@
+package unit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/domain"
+)
+
+func TestUTMParams_AppendTo(t *testing.T) {
+	utm := domain.UTMParams{Source: "newsletter", Campaign: "spring sale"}
+
+	tests := []struct {
+		name        string
+		destination string
+		expected    string
+	}{
+		{"no query", "https://example.com/page", "https://example.com/page?utm_source=newsletter&utm_campaign=spring+sale"},
+		{"existing query kept verbatim", "https://example.com/?b=2&a=%2F&flag", "https://example.com/?b=2&a=%2F&flag&utm_source=newsletter&utm_campaign=spring+sale"},
+		{"override existing utm", "https://example.com/?utm_source=old&x=1", "https://example.com/?x=1&utm_source=newsletter&utm_campaign=spring+sale"},
+		{"fragment stays last", "https://example.com/docs#install", "https://example.com/docs?utm_source=newsletter&utm_campaign=spring+sale#install"},
+		{"query and fragment", "https://example.com/?q=go#top", "https://example.com/?q=go&utm_source=newsletter&utm_campaign=spring+sale#top"},
+		{"trailing question mark", "https://example.com/?", "https://example.com/?utm_source=newsletter&utm_campaign=spring+sale"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, utm.AppendTo(tt.destination))
+		})
+	}
+
+	// No parameters leaves the destination untouched
+	assert.Equal(t, "https://example.com/?a=1#x", domain.UTMParams{}.AppendTo("https://example.com/?a=1#x"))
+}
+
+func TestGetOriginalURL_AppliesUTMAndCachesComposedURL(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	ctx := context.Background()
+
+	url := &domain.URL{
+		ShortCode:   "abc123",
+		OriginalURL: "https://example.com/?ref=x",
+		IsActive:    true,
+		UTM:         domain.UTMParams{Medium: "email"},
+	}
+	composed := "https://example.com/?ref=x&utm_medium=email"
+
+	suite.cache.On("Get", ctx, "abc123").Return("", nil)
+	suite.repo.On("FindByShortCode", ctx, "abc123").Return(url, nil)
+	suite.repo.On("IncrementClickCount", ctx, "abc123", mock.Anything).Return(nil)
+	suite.cache.On("Set", mock.Anything, "abc123", composed, time.Hour).Return(nil)
+
+	destination, err := suite.service.GetOriginalURL(ctx, "abc123", domain.Visitor{})
+
+	require.NoError(t, err)
+	assert.Equal(t, composed, destination)
+	drainCacheWrites(t, suite)
+	suite.cache.AssertExpectations(t)
+}
+
+func TestShortenURL_DifferentUTMIsNotDeduplicated(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	ctx := context.Background()
+
+	existing := &domain.URL{ShortCode: "old123", OriginalURL: "https://example.com", IsActive: true}
+	suite.repo.On("FindByOriginalURL", ctx, "https://example.com").Return(existing, nil)
+	suite.repo.On("Create", ctx, mock.AnythingOfType("*domain.URL")).Return(nil)
+	suite.cache.On("Set", mock.Anything, mock.AnythingOfType("string"), cachedDestination("https://example.com?utm_source=ads"), time.Hour).Return(nil)
+
+	resp, err := suite.service.ShortenURL(ctx, &domain.CreateURLRequest{
+		URL: "https://example.com",
+		UTM: &domain.UTMParams{Source: "ads"},
+	}, domain.CreatorContext{IP: "192.168.1.1"})
+
+	require.NoError(t, err)
+	assert.NotEqual(t, "old123", resp.ShortCode)
+	suite.repo.AssertCalled(t, "Create", ctx, mock.MatchedBy(func(u *domain.URL) bool {
+		return u.UTM.Source == "ads"
+	}))
+}
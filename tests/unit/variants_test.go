@@ -0,0 +1,133 @@
+package unit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/redirect"
+	"url-shortener/internal/service"
+)
+
+var splitVariants = domain.Variants{
+	{URL: "https://example.com/a", Weight: 50},
+	{URL: "https://example.com/b", Weight: 50},
+}
+
+func TestNormalizeWeights(t *testing.T) {
+	normalized, err := redirect.NormalizeWeights([]domain.Variant{{URL: "a", Weight: 1}, {URL: "b", Weight: 1}, {URL: "c", Weight: 1}})
+	require.NoError(t, err)
+	assert.Equal(t, []int{34, 33, 33}, []int{normalized[0].Weight, normalized[1].Weight, normalized[2].Weight})
+
+	normalized, err = redirect.NormalizeWeights([]domain.Variant{{URL: "a", Weight: 3}, {URL: "b", Weight: 1}})
+	require.NoError(t, err)
+	assert.Equal(t, 75, normalized[0].Weight)
+	assert.Equal(t, 25, normalized[1].Weight)
+
+	for _, invalid := range [][]domain.Variant{
+		{{URL: "a", Weight: 100}},
+		{{URL: "a", Weight: 0}, {URL: "b", Weight: 100}},
+		{{URL: "a", Weight: 1000}, {URL: "b", Weight: 1}},
+	} {
+		_, err := redirect.NormalizeWeights(invalid)
+		assert.Error(t, err)
+	}
+}
+
+func TestPickVariant_Distribution(t *testing.T) {
+	weighted := []domain.Variant{{URL: "a", Weight: 70}, {URL: "b", Weight: 30}}
+
+	assert.Equal(t, 0, redirect.PickVariant(weighted, 0))
+	assert.Equal(t, 0, redirect.PickVariant(weighted, 69))
+	assert.Equal(t, 1, redirect.PickVariant(weighted, 70))
+	assert.Equal(t, 1, redirect.PickVariant(weighted, 99))
+
+	counts := make([]int, 2)
+	for i := 0; i < 10000; i++ {
+		counts[redirect.PickVariant(weighted, redirect.RandomRoll())]++
+	}
+	assert.InDelta(t, 7000, counts[0], 400)
+}
+
+func TestApplySplit_StickyAndTargets(t *testing.T) {
+	visitor := domain.Visitor{IP: "203.0.113.9", UserAgent: desktopUA}
+	fallback := redirect.Result{Destination: "https://example.com", Target: redirect.DefaultTarget}
+
+	first := redirect.ApplySplit(fallback, splitVariants, true, visitor)
+	require.NotNil(t, first.Variant)
+	for i := 0; i < 20; i++ {
+		again := redirect.ApplySplit(fallback, splitVariants, true, visitor)
+		assert.Equal(t, *first.Variant, *again.Variant)
+	}
+
+	// Targeted visitors are not split
+	targeted := redirect.Result{Destination: "https://apps.apple.com/app/id1", Target: redirect.PlatformIOS}
+	assert.Equal(t, targeted, redirect.ApplySplit(targeted, splitVariants, false, visitor))
+}
+
+func TestGetOriginalURL_RecordsVariant(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	clicks := new(MockClickRepository)
+	suite.service = service.NewURLService(suite.repo, suite.cache, suite.cfg, suite.logger, service.WithClickRepository(clicks))
+	ctx := context.Background()
+
+	suite.cache.On("Get", ctx, "split").Return("", nil)
+	suite.repo.On("FindByShortCode", ctx, "split").
+		Return(&domain.URL{ShortCode: "split", OriginalURL: "https://example.com", IsActive: true, Variants: splitVariants}, nil)
+	suite.repo.On("IncrementClickCount", ctx, "split", mock.Anything).Return(nil)
+	suite.cache.On("Set", mock.Anything, "split", mock.Anything, time.Hour).Return(nil)
+
+	var recorded *domain.ClickEvent
+	clicks.On("Record", ctx, mock.AnythingOfType("*domain.ClickEvent")).
+		Run(func(args mock.Arguments) { recorded = args.Get(1).(*domain.ClickEvent) }).
+		Return(nil)
+
+	destination, err := suite.service.GetOriginalURL(ctx, "split", domain.Visitor{UserAgent: desktopUA})
+
+	require.NoError(t, err)
+	require.NotNil(t, recorded.Variant)
+	assert.Equal(t, splitVariants[*recorded.Variant].URL, destination)
+}
+
+func TestGetStats_VariantRatios(t *testing.T) {
+	suite := setupURLServiceTest(t)
+	clicks := new(MockClickRepository)
+	suite.service = service.NewURLService(suite.repo, suite.cache, suite.cfg, suite.logger, service.WithClickRepository(clicks))
+	ctx := context.Background()
+
+	suite.repo.On("GetStats", ctx, "split").Return(&domain.URLStats{
+		ShortCode: "split",
+		Variants: []domain.VariantStats{
+			{Index: 0, URL: "https://example.com/a", Weight: 50},
+			{Index: 1, URL: "https://example.com/b", Weight: 50},
+		},
+	}, nil)
+	clicks.On("CountByTarget", ctx, "split").Return(map[string]int64{"default": 4}, nil)
+	clicks.On("CountByVariant", ctx, "split").Return(map[int]int64{0: 3, 1: 1}, nil)
+	expectEmptyDailySeries(clicks, "split")
+
+	stats, err := suite.service.GetStats(ctx, "split")
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), stats.Variants[0].Clicks)
+	assert.InDelta(t, 0.75, stats.Variants[0].Ratio, 1e-9)
+	assert.InDelta(t, 0.25, stats.Variants[1].Ratio, 1e-9)
+}
+
+func TestShortenURL_InvalidVariants(t *testing.T) {
+	suite := setupURLServiceTest(t)
+
+	_, err := suite.service.ShortenURL(context.Background(), &domain.CreateURLRequest{
+		URL:      "https://example.com",
+		Variants: []domain.Variant{{URL: "https://example.com/a", Weight: 50}, {URL: "nope", Weight: 50}},
+	}, domain.CreatorContext{IP: "127.0.0.1"})
+
+	var appErr *domain.AppError
+	assert.ErrorAs(t, err, &appErr)
+	suite.repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
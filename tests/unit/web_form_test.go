@@ -0,0 +1,148 @@
+package unit
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/handler"
+)
+
+// setupWebFormRouter registers shorten and the form page on a service that creates every link it's given
+func setupWebFormRouter(t *testing.T) (*gin.Engine, *URLServiceTestSuite) {
+	gin.SetMode(gin.TestMode)
+	suite := setupURLServiceTest(t)
+	suite.repo.On("FindByOriginalURL", mock.Anything, mock.Anything).Return((*domain.URL)(nil), domain.ErrURLNotFound).Maybe()
+	suite.repo.On("Create", mock.Anything, mock.AnythingOfType("*domain.URL")).Return(nil).Maybe()
+	suite.cache.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+
+	h := handler.NewURLHandler(suite.service, suite.cfg, suite.logger)
+	router := gin.New()
+	router.GET("/", h.ShortenForm)
+	router.POST("/api/v1/shorten", h.ShortenURL)
+	router.GET("/:shortCode", h.RedirectURL)
+	return router, suite
+}
+
+func postShortenForm(router *gin.Engine, form url.Values, accept string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", "/api/v1/shorten", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestShortenForm_APIClientsGetJSON(t *testing.T) {
+	router, suite := setupWebFormRouter(t)
+
+	w := postShortenForm(router, url.Values{"url": {"https://example.com/form"}, "expiry_days": {"7"}}, "")
+	drainCacheWrites(t, suite)
+
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var body domain.CreateURLResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "https://example.com/form", body.OriginalURL)
+	assert.NotNil(t, body.ExpiresAt)
+}
+
+func TestShortenForm_BrowsersGetThePageBack(t *testing.T) {
+	router, suite := setupWebFormRouter(t)
+
+	w := postShortenForm(router, url.Values{"url": {"https://example.com/browser"}}, "text/html,application/xhtml+xml,*/*;q=0.8")
+	drainCacheWrites(t, suite)
+
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/html")
+	assert.Contains(t, w.Body.String(), `<p class="result">`)
+	assert.Contains(t, w.Body.String(), "https://example.com/browser")
+}
+
+func TestShortenForm_EscapesTheSubmittedURL(t *testing.T) {
+	router, _ := setupWebFormRouter(t)
+
+	w := postShortenForm(router, url.Values{"url": {`"><script>alert(1)</script>`}}, "text/html")
+
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/html")
+	assert.Contains(t, w.Body.String(), `<p class="error">`)
+	assert.NotContains(t, w.Body.String(), "<script>")
+	assert.Contains(t, w.Body.String(), "&lt;script&gt;")
+}
+
+func TestShortenForm_Multipart(t *testing.T) {
+	router, suite := setupWebFormRouter(t)
+
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	require.NoError(t, mw.WriteField("url", "https://example.com/multipart"))
+	require.NoError(t, mw.WriteField("custom_alias", "multi"))
+	require.NoError(t, mw.Close())
+
+	req := httptest.NewRequest("POST", "/api/v1/shorten", &buf)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	drainCacheWrites(t, suite)
+
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var body domain.CreateURLResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "multi", body.ShortCode)
+}
+
+func TestShortenForm_RejectsUnknownFieldsAndBadExpiry(t *testing.T) {
+	router, _ := setupWebFormRouter(t)
+
+	tests := []struct {
+		name    string
+		form    url.Values
+		message string
+	}{
+		{"unknown field", url.Values{"url": {"https://example.com"}, "custom_alais": {"x"}}, `unknown field "custom_alais"`},
+		{"non-numeric expiry", url.Values{"url": {"https://example.com"}, "expiry_days": {"soon"}}, "expiry_days must be a whole number"},
+		{"missing url", url.Values{"custom_alias": {"nourl"}}, "Invalid request body"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := postShortenForm(router, tt.form, "")
+
+			require.Equal(t, http.StatusBadRequest, w.Code)
+			var body domain.ErrorResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			assert.Equal(t, "invalid_request", body.Error)
+			assert.Contains(t, body.Message, tt.message)
+		})
+	}
+}
+
+func TestShortenForm_PageAtRoot(t *testing.T) {
+	router, suite := setupWebFormRouter(t)
+	suite.cache.On("Get", mock.Anything, mock.Anything).Return("", nil).Maybe()
+	suite.cache.On("Exists", mock.Anything, mock.Anything).Return(false, nil).Maybe()
+	suite.repo.On("FindByShortCode", mock.Anything, "abc123").Return((*domain.URL)(nil), domain.ErrURLNotFound).Maybe()
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `action="/api/v1/shorten"`)
+
+	// The short code catch-all still answers everything below the root
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/abc123", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}